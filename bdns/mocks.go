@@ -28,6 +28,11 @@ func (mock *MockClient) LookupTXT(_ context.Context, hostname string) ([]string,
 		// expected token + test account jwk thumbprint
 		return []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}, nil
 	}
+	if hostname == "_6g727n6x5dk6qex5._acme-challenge.good-dns-account01.com" {
+		// The same digest, at the dns-account-01 label of the account
+		// "http://boulder.service.consul:4000/acme/reg/1".
+		return []string{"LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"}, nil
+	}
 	if hostname == "_acme-challenge.wrong-dns01.com" {
 		return []string{"a"}, nil
 	}
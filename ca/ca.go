@@ -134,6 +134,15 @@ func NewCertificateAuthorityImpl(
 		return nil, errors.New("must have at least one issuer")
 	}
 
+	// Check now that each issuer's profile allows the validity period, rather
+	// than failing every issuance.
+	for _, issuer := range boulderIssuers {
+		if certExpiry > issuer.Profile.MaxValidity() {
+			return nil, fmt.Errorf("certificate validity period %s exceeds the %s allowed by the profile of issuer %q",
+				certExpiry, issuer.Profile.MaxValidity(), issuer.Name())
+		}
+	}
+
 	issuers := makeIssuerMaps(boulderIssuers)
 
 	lintErrorCount := prometheus.NewCounter(
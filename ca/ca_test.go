@@ -317,6 +317,27 @@ func TestSerialPrefix(t *testing.T) {
 	test.AssertError(t, err, "CA should have failed with too-large SerialPrefix")
 }
 
+func TestValidityPeriodExceedsProfile(t *testing.T) {
+	testCtx := setup(t)
+	_, err := NewCertificateAuthorityImpl(
+		nil,
+		nil,
+		testCtx.boulderIssuers,
+		nil,
+		testCtx.certExpiry+time.Hour,
+		testCtx.certBackdate,
+		testCtx.serialPrefix,
+		testCtx.maxNames,
+		testCtx.keyPolicy,
+		testCtx.logger,
+		testCtx.stats,
+		nil,
+		nil,
+		testCtx.fc)
+	test.AssertError(t, err, "CA should have failed with a validity period longer than its profile allows")
+	test.AssertContains(t, err.Error(), "exceeds the 8760h0m0s allowed")
+}
+
 type TestCertificateIssuance struct {
 	ca      *certificateAuthorityImpl
 	sa      *mockSA
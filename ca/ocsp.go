@@ -104,6 +104,12 @@ func (oi *ocspImpl) GenerateOCSP(ctx context.Context, req *capb.GenerateOCSPRequ
 	if !ok {
 		return nil, fmt.Errorf("unrecognized issuer ID %d", req.IssuerID)
 	}
+	if issuer.Profile.OmitsOCSP() {
+		// Certificates from this issuer carry no OCSP URL, so nothing will
+		// ever ask for their status. Don't sign, or let the caller store,
+		// responses for them.
+		return nil, berrors.NotFoundError("issuer ID %d does not provide OCSP", req.IssuerID)
+	}
 
 	now := oi.clk.Now().Truncate(time.Minute)
 	tbsResponse := ocsp.Response{
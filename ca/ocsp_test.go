@@ -8,10 +8,14 @@ import (
 	"time"
 
 	capb "github.com/letsencrypt/boulder/ca/proto"
+	"github.com/letsencrypt/boulder/config"
 	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/issuance"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/test"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/crypto/ocsp"
 )
 
@@ -109,6 +113,43 @@ func TestOCSP(t *testing.T) {
 	test.AssertNotError(t, err, "GenerateOCSP failed with fake-but-valid Serial")
 }
 
+func TestOCSPOmittedByProfile(t *testing.T) {
+	testCtx := setup(t)
+	profile, err := issuance.NewProfile(
+		issuance.ProfileConfig{
+			MaxValidityPeriod: config.Duration{Duration: issuance.MaxShortLivedValidity},
+			OmitOCSP:          true,
+		},
+		issuance.IssuerConfig{
+			UseForRSALeaves: true,
+			IssuerURL:       "http://not-example.com/issuer-url",
+			OCSPURL:         "http://not-example.com/ocsp",
+		},
+	)
+	test.AssertNotError(t, err, "Failed to create short-lived profile")
+	issuer := &issuance.Issuer{Cert: caCert, Signer: caKey, Profile: profile, Linter: caLinter, Clk: testCtx.fc}
+	ocspi, err := NewOCSPImpl(
+		[]*issuance.Issuer{issuer},
+		24*time.Hour,
+		0,
+		time.Second,
+		blog.NewMock(),
+		metrics.NoopRegisterer,
+		testCtx.signatureCount,
+		testCtx.signErrorCount,
+		testCtx.fc,
+	)
+	test.AssertNotError(t, err, "Failed to create ocsp impl")
+
+	_, err = ocspi.GenerateOCSP(context.Background(), &capb.GenerateOCSPRequest{
+		Serial:   "03DEADBEEFBADDECAFFADEFACECAFE30",
+		IssuerID: int64(issuer.NameID()),
+		Status:   string(core.OCSPStatusGood),
+	})
+	test.AssertErrorIs(t, err, berrors.NotFound)
+	test.AssertMetricWithLabelsEquals(t, testCtx.signatureCount, prometheus.Labels{"purpose": "ocsp"}, 0)
+}
+
 // Set up an ocspLogQueue with a very long period and a large maxLen,
 // to ensure any buffered entries get flushed on `.stop()`.
 func TestOcspLogFlushOnExit(t *testing.T) {
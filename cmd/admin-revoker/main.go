@@ -10,9 +10,11 @@ import (
 	"io"
 	"os"
 	"os/user"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/cmd"
@@ -21,13 +23,16 @@ import (
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/features"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/identifier"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/privatekey"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/ratelimits"
 	"github.com/letsencrypt/boulder/revocation"
 	"github.com/letsencrypt/boulder/sa"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -42,6 +47,13 @@ usage:
   private-key-block      -config <path> -comment="<string>" -dry-run=<bool>    <priv-key-path>
   private-key-revoke     -config <path> -comment="<string>" -dry-run=<bool>    <priv-key-path>
   clear-email            -config <path> <email-address>
+  pause-identifier       -config <path> <registration-id>  <dns-name>...
+  unpause-account        -config <path> <registration-id>
+  request-override       -config <path> -comment="<string>" <limit-name> <bucket-id> <burst> <count> <period> <expires-in>
+  approve-override       -config <path> <request-id>
+  deny-override          -config <path> <request-id>
+  expire-override        -config <path> <request-id>
+  write-overrides        -config <path> <base-overrides-file-path> <overrides-file-path>
 
 
 descriptions:
@@ -62,16 +74,33 @@ descriptions:
                          table. <priv-key-path> is expected to be the path to a PEM
                          formatted file containing an RSA or ECDSA private key.
   clear-email            Delete all instances of a given email from all accounts (slow).
+  pause-identifier       Prevent a registration ID from validating the provided DNS names,
+                         without deactivating the account.
+  unpause-account        Allow a registration ID to validate every DNS name paused for it.
+  request-override       File a pending request to override the default limit named by
+                         <limit-name> for <bucket-id>, e.g. NewOrdersPerAccount 12345678
+                         600 600 3h 2160h. <period> and <expires-in> are Go durations.
+  approve-override       Approve the pending override request with the provided ID.
+  deny-override          Deny the pending override request with the provided ID.
+  expire-override        Stop honoring the approved override request with the provided ID.
+  write-overrides        Replace the file at <overrides-file-path> with an overrides file
+                         containing the hand-maintained overrides at
+                         <base-overrides-file-path> and every approved, unexpired override
+                         request. Fails, leaving the file unchanged, if any two are for
+                         the same bucket.
 
 flags:
   all:
     -config              File path to the configuration file for this service (required)
 
+  private-key-block | private-key-revoke | request-override:
+    -comment             Comment to include in the blocked keys table entry, or the
+                         justification for the override request. (default: "")
+
   private-key-block | private-key-revoke:
     -dry-run             true (default): only queries for affected certificates. false: will
                          perform the requested block or revoke action. Only implemented for
                          private-key-block and private-key-revoke.
-    -comment             Comment to include in the blocked keys table entry. (default: "")
 `
 
 type Config struct {
@@ -247,6 +276,111 @@ func (r *revoker) clearEmailAddress(ctx context.Context, email string) error {
 	return nil
 }
 
+func (r *revoker) pauseIdentifiers(ctx context.Context, regID int64, names []string) error {
+	var idents []*sapb.Identifier
+	for _, name := range names {
+		idents = append(idents, &sapb.Identifier{Type: string(identifier.DNS), Value: name})
+	}
+	resp, err := r.sac.PauseIdentifiers(ctx, &sapb.PauseRequest{
+		RegistrationID: regID,
+		Identifiers:    idents,
+	})
+	if err != nil {
+		return err
+	}
+	r.log.AuditInfof("paused %d and repaused %d of %d identifiers for registration ID %d: %q",
+		resp.Paused, resp.Repaused, len(names), regID, names)
+	return nil
+}
+
+func (r *revoker) unpauseAccount(ctx context.Context, regID int64) error {
+	count, err := r.sac.UnpauseAccount(ctx, &sapb.RegistrationID{Id: regID})
+	if err != nil {
+		return err
+	}
+	r.log.AuditInfof("unpaused %d identifiers for registration ID %d", count.Count, regID)
+	return nil
+}
+
+func (r *revoker) requestOverride(ctx context.Context, req ratelimits.OverrideRequest) error {
+	u, err := user.Current()
+	if err != nil {
+		return err
+	}
+	req.Requester = u.Username
+
+	// Validate before sending, so that mistakes are reported without a round
+	// trip to the SA, which validates again.
+	err = ratelimits.ValidateOverrideRequest(req)
+	if err != nil {
+		return err
+	}
+	resp, err := r.sac.AddOverrideRequest(ctx, ratelimits.OverrideRequestToPB(req))
+	if err != nil {
+		return err
+	}
+	r.log.AuditInfof("filed override request %d for %s %q: burst %d, count %d, period %s, expires %s",
+		resp.Id, req.LimitName, req.BucketId, req.Burst, req.Count, req.Period, req.ExpiresAt.UTC().Format(time.RFC3339))
+	return nil
+}
+
+func (r *revoker) updateOverrideRequestState(ctx context.Context, id int64, from, to ratelimits.OverrideRequestState) error {
+	_, err := r.sac.UpdateOverrideRequestState(ctx, &sapb.UpdateOverrideRequestStateRequest{
+		Id:   id,
+		From: string(from),
+		To:   string(to),
+	})
+	if err != nil {
+		return err
+	}
+	r.log.AuditInfof("moved override request %d from %s to %s", id, from, to)
+	return nil
+}
+
+// writeOverrides replaces the file at the provided path with an overrides
+// file containing the overrides file at basePath followed by every approved,
+// unexpired override request, since NewTransactionBuilder loads only a single
+// overrides file. The file is written in full and then renamed into place, so
+// that it's never read while partially written.
+func (r *revoker) writeOverrides(ctx context.Context, basePath, path string) error {
+	stream, err := r.sac.GetApprovedOverrideRequests(ctx, &emptypb.Empty{})
+	if err != nil {
+		return fmt.Errorf("setting up stream of approved override requests: %s", err)
+	}
+	var reqs []ratelimits.OverrideRequest
+	for {
+		pb, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("streaming approved override requests: %s", err)
+		}
+		reqs = append(reqs, ratelimits.OverrideRequestFromPB(pb))
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	err = ratelimits.WriteOverrideRequests(basePath, reqs, f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return err
+	}
+	r.log.AuditInfof("wrote %q and %d approved override requests to %q", basePath, len(reqs), path)
+	return nil
+}
+
 func (r *revoker) revokeIncidentTableSerials(ctx context.Context, tableName string, reasonCode revocation.Reason, parallelism int) error {
 	wg := new(sync.WaitGroup)
 	work := make(chan string, parallelism)
@@ -660,6 +794,64 @@ func main() {
 		err := r.clearEmailAddress(ctx, email)
 		cmd.FailOnError(err, "Clearing email address")
 
+	case command == "pause-identifier" && len(args) >= 2:
+		// 1: registration ID, 2+: DNS names
+		regID, err := strconv.ParseInt(args[0], 10, 64)
+		cmd.FailOnError(err, "Registration ID argument must be an integer")
+
+		err = r.pauseIdentifiers(ctx, regID, args[1:])
+		cmd.FailOnError(err, "Couldn't pause identifiers")
+
+	case command == "unpause-account" && len(args) == 1:
+		// 1: registration ID
+		regID, err := strconv.ParseInt(args[0], 10, 64)
+		cmd.FailOnError(err, "Registration ID argument must be an integer")
+
+		err = r.unpauseAccount(ctx, regID)
+		cmd.FailOnError(err, "Couldn't unpause account")
+
+	case command == "request-override" && len(args) == 6:
+		// 1: limit name, 2: bucket ID, 3: burst, 4: count, 5: period, 6: expires in
+		burst, err := strconv.ParseInt(args[2], 10, 64)
+		cmd.FailOnError(err, "Burst argument must be an integer")
+		count, err := strconv.ParseInt(args[3], 10, 64)
+		cmd.FailOnError(err, "Count argument must be an integer")
+		period, err := time.ParseDuration(args[4])
+		cmd.FailOnError(err, "Period argument must be a duration")
+		expiresIn, err := time.ParseDuration(args[5])
+		cmd.FailOnError(err, "Expires in argument must be a duration")
+
+		err = r.requestOverride(ctx, ratelimits.OverrideRequest{
+			LimitName: args[0],
+			BucketId:  args[1],
+			Burst:     burst,
+			Count:     count,
+			Period:    period,
+			Comment:   *comment,
+			ExpiresAt: r.clk.Now().Add(expiresIn),
+		})
+		cmd.FailOnError(err, "Couldn't file override request")
+
+	case (command == "approve-override" || command == "deny-override" || command == "expire-override") && len(args) == 1:
+		// 1: request ID
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		cmd.FailOnError(err, "Request ID argument must be an integer")
+
+		from, to := ratelimits.OverrideRequestPending, ratelimits.OverrideRequestApproved
+		switch command {
+		case "deny-override":
+			to = ratelimits.OverrideRequestDenied
+		case "expire-override":
+			from, to = ratelimits.OverrideRequestApproved, ratelimits.OverrideRequestExpired
+		}
+		err = r.updateOverrideRequestState(ctx, id, from, to)
+		cmd.FailOnError(err, "Couldn't update override request")
+
+	case command == "write-overrides" && len(args) == 2:
+		// 1: base overrides file path, 2: overrides file path
+		err := r.writeOverrides(ctx, args[0], args[1])
+		cmd.FailOnError(err, "Couldn't write overrides file")
+
 	default:
 		fmt.Fprintf(os.Stderr, "unrecognized subcommand %q\n\n", command)
 		usage()
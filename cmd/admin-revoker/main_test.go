@@ -9,10 +9,12 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"math/big"
 	"net"
 	"os"
 	"os/user"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -22,12 +24,14 @@ import (
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
 	"github.com/letsencrypt/boulder/db"
+	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/goodkey"
 	"github.com/letsencrypt/boulder/issuance"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/mocks"
 	"github.com/letsencrypt/boulder/ra"
+	"github.com/letsencrypt/boulder/ratelimits"
 	"github.com/letsencrypt/boulder/sa"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 	"github.com/letsencrypt/boulder/test"
@@ -53,6 +57,149 @@ func (mp *mockPurger) Purge(context.Context, *akamaipb.PurgeRequest, ...grpc.Cal
 	return &emptypb.Empty{}, nil
 }
 
+// mockSAPause is a mock SA which records the identifiers it's asked to pause.
+type mockSAPause struct {
+	mocks.StorageAuthority
+	paused []*sapb.Identifier
+}
+
+func (msa *mockSAPause) PauseIdentifiers(_ context.Context, req *sapb.PauseRequest, _ ...grpc.CallOption) (*sapb.PauseIdentifiersResponse, error) {
+	msa.paused = append(msa.paused, req.Identifiers...)
+	return &sapb.PauseIdentifiersResponse{Paused: int64(len(req.Identifiers))}, nil
+}
+
+func (msa *mockSAPause) UnpauseAccount(_ context.Context, req *sapb.RegistrationID, _ ...grpc.CallOption) (*sapb.Count, error) {
+	count := &sapb.Count{Count: int64(len(msa.paused))}
+	msa.paused = nil
+	return count, nil
+}
+
+func TestPauseAndUnpause(t *testing.T) {
+	log := blog.NewMock()
+	msa := &mockSAPause{}
+	r := revoker{sac: msa, log: log}
+
+	err := r.pauseIdentifiers(context.Background(), 1, []string{"example.com", "example.net"})
+	test.AssertNotError(t, err, "pausing identifiers")
+	test.AssertEquals(t, len(msa.paused), 2)
+	test.AssertEquals(t, msa.paused[1].Type, "dns")
+	test.AssertEquals(t, msa.paused[1].Value, "example.net")
+	test.AssertEquals(t, len(log.GetAllMatching(`paused 2 and repaused 0 of 2 identifiers for registration ID 1`)), 1)
+
+	err = r.unpauseAccount(context.Background(), 1)
+	test.AssertNotError(t, err, "unpausing account")
+	test.AssertEquals(t, len(log.GetAllMatching(`unpaused 2 identifiers for registration ID 1`)), 1)
+}
+
+// mockSAOverrides is a mock SA which stores the override requests it's asked
+// to add, and approves them all.
+type mockSAOverrides struct {
+	mocks.StorageAuthority
+	reqs []*sapb.OverrideRequest
+}
+
+func (msa *mockSAOverrides) AddOverrideRequest(_ context.Context, req *sapb.OverrideRequest, _ ...grpc.CallOption) (*sapb.OverrideRequest, error) {
+	req.Id = int64(len(msa.reqs) + 1)
+	req.State = string(ratelimits.OverrideRequestPending)
+	msa.reqs = append(msa.reqs, req)
+	return req, nil
+}
+
+func (msa *mockSAOverrides) UpdateOverrideRequestState(_ context.Context, req *sapb.UpdateOverrideRequestStateRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	for _, or := range msa.reqs {
+		if or.Id == req.Id && or.State == req.From {
+			or.State = req.To
+			return &emptypb.Empty{}, nil
+		}
+	}
+	return nil, berrors.NotFoundError("no override request with id %d in state %s", req.Id, req.From)
+}
+
+type mockApprovedOverridesStream struct {
+	grpc.ClientStream
+	reqs []*sapb.OverrideRequest
+}
+
+func (s *mockApprovedOverridesStream) Recv() (*sapb.OverrideRequest, error) {
+	for len(s.reqs) > 0 {
+		req := s.reqs[0]
+		s.reqs = s.reqs[1:]
+		if req.State == string(ratelimits.OverrideRequestApproved) {
+			return req, nil
+		}
+	}
+	return nil, io.EOF
+}
+
+func (msa *mockSAOverrides) GetApprovedOverrideRequests(_ context.Context, _ *emptypb.Empty, _ ...grpc.CallOption) (sapb.StorageAuthority_GetApprovedOverrideRequestsClient, error) {
+	return &mockApprovedOverridesStream{reqs: msa.reqs}, nil
+}
+
+func TestOverrideRequests(t *testing.T) {
+	log := blog.NewMock()
+	fc := clock.NewFake()
+	msa := &mockSAOverrides{}
+	r := revoker{sac: msa, log: log, clk: fc}
+
+	req := ratelimits.OverrideRequest{
+		LimitName: ratelimits.NewOrdersPerAccount.String(),
+		BucketId:  "12345678",
+		Burst:     600,
+		Count:     600,
+		Period:    3 * time.Hour,
+		Comment:   "large hosting provider",
+		ExpiresAt: fc.Now().Add(90 * 24 * time.Hour),
+	}
+	err := r.requestOverride(context.Background(), req)
+	test.AssertNotError(t, err, "filing override request")
+	test.AssertEquals(t, len(log.GetAllMatching(`filed override request 1 for NewOrdersPerAccount "12345678"`)), 1)
+
+	other := req
+	other.BucketId = "87654321"
+	err = r.requestOverride(context.Background(), other)
+	test.AssertNotError(t, err, "filing override request")
+
+	invalid := req
+	invalid.BucketId = "lol"
+	err = r.requestOverride(context.Background(), invalid)
+	test.AssertError(t, err, "filing invalid override request")
+	test.AssertEquals(t, len(msa.reqs), 2)
+
+	err = r.updateOverrideRequestState(context.Background(), 1, ratelimits.OverrideRequestPending, ratelimits.OverrideRequestApproved)
+	test.AssertNotError(t, err, "approving override request")
+	test.AssertEquals(t, len(log.GetAllMatching(`moved override request 1 from pending to approved`)), 1)
+	err = r.updateOverrideRequestState(context.Background(), 1, ratelimits.OverrideRequestPending, ratelimits.OverrideRequestDenied)
+	test.AssertError(t, err, "denying approved override request")
+
+	// The hand-maintained overrides and only the approved request are
+	// written, and can be loaded together.
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "base.yaml")
+	err = os.WriteFile(basePath, []byte("- NewRegistrationsPerIPAddress:\n    burst: 40\n    count: 40\n    period: 1s\n    ids: [10.0.0.2]\n"), 0600)
+	test.AssertNotError(t, err, "writing base overrides file")
+	path := filepath.Join(dir, "overrides.yaml")
+	err = r.writeOverrides(context.Background(), basePath, path)
+	test.AssertNotError(t, err, "writing overrides file")
+	contents, err := os.ReadFile(path)
+	test.AssertNotError(t, err, "reading overrides file")
+	test.AssertContains(t, string(contents), "10.0.0.2")
+	test.AssertContains(t, string(contents), "12345678")
+	test.AssertNotContains(t, string(contents), "87654321")
+	test.AssertEquals(t, len(log.GetAllMatching(`and 1 approved override requests`)), 1)
+	_, err = ratelimits.NewTransactionBuilder("../../test/config-next/wfe2-ratelimit-defaults.yml", "", path, "")
+	test.AssertNotError(t, err, "loading merged overrides file")
+
+	// An approved request for a bucket which is also overridden by hand fails,
+	// leaving the previous file in place.
+	err = os.WriteFile(basePath, []byte("- NewOrdersPerAccount:\n    burst: 40\n    count: 40\n    period: 1s\n    ids: [12345678]\n"), 0600)
+	test.AssertNotError(t, err, "writing base overrides file")
+	err = r.writeOverrides(context.Background(), basePath, path)
+	test.AssertError(t, err, "writing overrides file with a duplicate bucket")
+	unchanged, err := os.ReadFile(path)
+	test.AssertNotError(t, err, "reading overrides file")
+	test.AssertEquals(t, string(unchanged), string(contents))
+}
+
 func TestRevokeSerialBatchFile(t *testing.T) {
 	testCtx := setup(t)
 	defer testCtx.cleanUp()
@@ -486,6 +633,8 @@ func setup(t *testing.T) testCtx {
 		nil,
 		&mockPurger{},
 		[]*issuance.Certificate{issuer},
+		nil,
+		nil,
 	)
 	ra.SA = isa.SA{Impl: ssa}
 	ra.OCSP = &mockOCSPA{}
@@ -5,12 +5,13 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"math"
+	"net/url"
 	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/protobuf/types/known/emptypb"
 
@@ -20,6 +21,8 @@ import (
 	"github.com/letsencrypt/boulder/config"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/ratelimits"
+	bredis "github.com/letsencrypt/boulder/redis"
 )
 
 const (
@@ -37,8 +40,9 @@ const (
 	// defaultEntriesPerBatch is the default value for 'queueEntriesPerBatch'.
 	defaultEntriesPerBatch = 2
 
-	// defaultPurgeBatchInterval is the default value for 'purgeBatchInterval'.
-	defaultPurgeBatchInterval = time.Millisecond * 32
+	// defaultURLsPerSecond is the default value for 'urlsPerSecond'. It leaves
+	// headroom below 'akamaiURLsPerSecondLimit' for a full batch of burst.
+	defaultURLsPerSecond = 180
 
 	// defaultQueueSize is the default value for 'maxQueueSize'. A queue size of
 	// 1.25M cached OCSP responses, assuming 3 URLs per request, is about 6
@@ -58,6 +62,16 @@ const (
 	// akamaiURLsPerSecondLimit is the limit of URLs, sent per second, that
 	// we're allowed to make to the Fast-Purge API.
 	akamaiURLsPerSecondLimit = 200
+
+	// minURLsPerRequest is the least number of URLs each purge request is
+	// charged against the AkamaiPurgeURLsPerEndpoint limit, however few it
+	// contains. Staying under 'akamaiURLsPerSecondLimit' then also keeps us
+	// under 'akamaiAPIReqPerSecondLimit'.
+	minURLsPerRequest = akamaiURLsPerSecondLimit / akamaiAPIReqPerSecondLimit
+
+	// limiterErrorBackoff is how long the purger waits before trying again
+	// when the Limiter fails to decide whether a purge request may be sent.
+	limiterErrorBackoff = time.Second
 )
 
 // Throughput is a container for all throuput related akamai-purger
@@ -69,9 +83,14 @@ type Throughput struct {
 	// 'defaultQueueEntriesPerBatch'.
 	QueueEntriesPerBatch int
 
-	// PurgeBatchInterval is the duration waited between dispatching an Akamai
-	// purge request containing 'QueueEntriesPerBatch' * 3 URLs. If this value
-	// isn't provided it will default to 'defaultPurgeBatchInterval'.
+	// URLsPerSecond is the steady rate at which URLs are submitted to the
+	// Fast-Purge API endpoint, paced by the AkamaiPurgeURLsPerEndpoint limit.
+	// Up to a full batch of URLs may be submitted in a burst beyond it. If
+	// this value isn't provided it will default to 'defaultURLsPerSecond'.
+	URLsPerSecond int64
+
+	// DEPRECATED: Purge requests are paced by 'URLsPerSecond'. This config
+	// value is no longer used.
 	PurgeBatchInterval config.Duration `validate:"-"`
 }
 
@@ -79,18 +98,25 @@ func (t *Throughput) useOptimizedDefaults() {
 	if t.QueueEntriesPerBatch == 0 {
 		t.QueueEntriesPerBatch = defaultEntriesPerBatch
 	}
-	if t.PurgeBatchInterval.Duration == 0 {
-		t.PurgeBatchInterval.Duration = defaultPurgeBatchInterval
+	if t.URLsPerSecond == 0 {
+		t.URLsPerSecond = defaultURLsPerSecond
 	}
 }
 
+// burst returns the number of URLs which may be submitted at once, beyond the
+// steady rate of 'URLsPerSecond': a full batch, or one request of
+// 'minURLsPerRequest', whichever is larger.
+func (t *Throughput) burst() int64 {
+	return max(int64(t.QueueEntriesPerBatch*urlsPerQueueEntry), minURLsPerRequest)
+}
+
 // validate ensures that the provided throughput configuration will not violate
 // the Akamai Fast-Purge API limits. For more information see the official
 // documentation:
 // https://techdocs.akamai.com/purge-cache/reference/rate-limiting
 func (t *Throughput) validate() error {
-	if t.PurgeBatchInterval.Duration == 0 {
-		return errors.New("'purgeBatchInterval' must be > 0")
+	if t.URLsPerSecond <= 0 {
+		return errors.New("'urlsPerSecond' must be > 0")
 	}
 	if t.QueueEntriesPerBatch <= 0 {
 		return errors.New("'queueEntriesPerBatch' must be > 0")
@@ -103,15 +129,11 @@ func (t *Throughput) validate() error {
 			akamaiBytesPerReqLimit, bytesPerRequest-akamaiBytesPerReqLimit)
 	}
 
-	// Send no more than the 50 API requests we’re allotted each second.
-	requestsPerSecond := int(math.Ceil(float64(time.Second) / float64(t.PurgeBatchInterval.Duration)))
-	if requestsPerSecond > akamaiAPIReqPerSecondLimit {
-		return fmt.Errorf("config exceeds Akamai's requests per second limit (%d requests) by %d",
-			akamaiAPIReqPerSecondLimit, requestsPerSecond-akamaiAPIReqPerSecondLimit)
-	}
-
-	// Purge no more than the 200 URLs we’re allotted each second.
-	urlsPurgedPerSecond := requestsPerSecond * (t.QueueEntriesPerBatch * urlsPerQueueEntry)
+	// Purge no more than the 200 URLs we’re allotted each second, even in the
+	// second in which a full burst is spent. Since each request is charged at
+	// least 'minURLsPerRequest' URLs, this also means we send no more than the
+	// 50 API requests we’re allotted each second.
+	urlsPurgedPerSecond := t.burst() + t.URLsPerSecond
 	if urlsPurgedPerSecond > akamaiURLsPerSecondLimit {
 		return fmt.Errorf("config exceeds Akamai's URLs per second limit (%d URLs) by %d",
 			akamaiURLsPerSecondLimit, urlsPurgedPerSecond-akamaiURLsPerSecondLimit)
@@ -145,6 +167,12 @@ type Config struct {
 		// attempting to purge a batch of URLs which previously failed to be
 		// purged.
 		PurgeRetryBackoff config.Duration `validate:"-"`
+
+		// Redis optionally contains the configuration necessary to connect to
+		// the Redis which stores the AkamaiPurgeURLsPerEndpoint bucket, so that
+		// every akamai-purger purging the same endpoint shares its pace. If
+		// this field is not set, the bucket is stored in memory.
+		Redis *bredis.Config
 	}
 	Syslog        cmd.SyslogConfig
 	OpenTelemetry cmd.OpenTelemetryConfig
@@ -170,6 +198,18 @@ type akamaiPurger struct {
 	entriesPerBatch int
 	client          cachePurgeClient
 	log             blog.Logger
+
+	// pending is signalled, without blocking, each time an entry is added to
+	// the stack, so that an idle purger can wake up.
+	pending chan struct{}
+
+	// limiter and limit pace the URLs submitted to endpoint, the host of the
+	// Fast-Purge API.
+	limiter  *ratelimits.Limiter
+	limit    *ratelimits.InternalLimit
+	burst    int64
+	endpoint string
+	clk      clock.Clock
 }
 
 func (ap *akamaiPurger) len() int {
@@ -193,6 +233,47 @@ func (ap *akamaiPurger) purgeBatch(batch [][]string) error {
 	return nil
 }
 
+// reserve spends the capacity for a purge request of the provided number of
+// URLs from the endpoint's bucket, returning zero if the request may be sent
+// now, or otherwise how long to wait before trying again. Each request is
+// charged at least 'minURLsPerRequest', and at most a full burst, of URLs.
+func (ap *akamaiPurger) reserve(ctx context.Context, urls int) (time.Duration, error) {
+	cost := min(max(int64(urls), minURLsPerRequest), ap.burst)
+	txn, err := ap.limit.Transaction(ap.endpoint, cost)
+	if err != nil {
+		return 0, err
+	}
+	d, err := ap.limiter.Spend(ctx, txn)
+	if err != nil {
+		return 0, err
+	}
+	if d.Allowed {
+		return 0, nil
+	}
+	return d.RetryIn, nil
+}
+
+// throttle blocks until a purge request for the provided batch may be sent. If
+// the Limiter fails, it waits 'limiterErrorBackoff' and tries again, rather
+// than sending the request unpaced.
+func (ap *akamaiPurger) throttle(ctx context.Context, batch [][]string) {
+	var urls int
+	for _, entry := range batch {
+		urls += len(entry)
+	}
+	for {
+		wait, err := ap.reserve(ctx, urls)
+		if err != nil {
+			ap.log.Errf("Failed to reserve capacity to purge %d URLs: %s", urls, err)
+			wait = limiterErrorBackoff
+		}
+		if wait == 0 {
+			return
+		}
+		ap.clk.Sleep(wait)
+	}
+}
+
 func (ap *akamaiPurger) takeBatch() [][]string {
 	ap.Lock()
 	defer ap.Unlock()
@@ -228,6 +309,10 @@ func (ap *akamaiPurger) Purge(ctx context.Context, req *akamaipb.PurgeRequest) (
 	}
 	// Add the entry from the new request to the top of the stack.
 	ap.toPurge = append(ap.toPurge, req.Urls)
+	select {
+	case ap.pending <- struct{}{}:
+	default:
+	}
 	return &emptypb.Empty{}, nil
 }
 
@@ -297,16 +382,35 @@ func main() {
 	defer oTelShutdown(context.Background())
 	logger.Info(cmd.VersionString())
 
-	// Unless otherwise specified, use optimized throughput settings.
-	if (apc.Throughput == Throughput{}) {
-		apc.Throughput.useOptimizedDefaults()
-	}
+	// Use optimized throughput settings for any not otherwise specified.
+	apc.Throughput.useOptimizedDefaults()
 	cmd.FailOnError(apc.Throughput.validate(), "")
 
 	if apc.MaxQueueSize == 0 {
 		apc.MaxQueueSize = defaultQueueSize
 	}
 
+	clk := cmd.Clock()
+	baseURL, err := url.Parse(apc.BaseURL)
+	cmd.FailOnError(err, "Failed to parse 'baseURL'")
+	limit, err := ratelimits.NewInternalLimit(ratelimits.AkamaiPurgeURLsPerEndpoint,
+		apc.Throughput.burst(), apc.Throughput.URLsPerSecond, time.Second)
+	cmd.FailOnError(err, "Failed to create Akamai purge limit")
+	_, err = limit.Transaction(baseURL.Host, minURLsPerRequest)
+	cmd.FailOnError(err, "Invalid 'baseURL' for Akamai purge limit")
+
+	var limiter *ratelimits.Limiter
+	if apc.Redis != nil {
+		limiterRedis, err := bredis.NewRingFromConfig(*apc.Redis, scope, logger)
+		cmd.FailOnError(err, "Failed to create Redis ring")
+		defer limiterRedis.StopLookups()
+		limiter, err = ratelimits.NewLimiter(clk, ratelimits.NewRedisSource(limiterRedis.Ring, clk, scope), scope)
+		cmd.FailOnError(err, "Failed to create rate limiter")
+	} else {
+		limiter, err = ratelimits.NewLimiter(clk, ratelimits.NewInmemSource(), scope)
+		cmd.FailOnError(err, "Failed to create rate limiter")
+	}
+
 	ccu, err := akamai.NewCachePurgeClient(
 		apc.BaseURL,
 		apc.ClientToken,
@@ -325,6 +429,12 @@ func main() {
 		entriesPerBatch: apc.Throughput.QueueEntriesPerBatch,
 		client:          ccu,
 		log:             logger,
+		pending:         make(chan struct{}, 1),
+		limiter:         limiter,
+		limit:           limit,
+		burst:           apc.Throughput.burst(),
+		endpoint:        baseURL.Host,
+		clk:             clk,
 	}
 
 	var gaugePurgeQueueLength = prometheus.NewGaugeFunc(
@@ -339,7 +449,7 @@ func main() {
 	if manualMode {
 		manualPurge(ccu, *tag, *tagFile)
 	} else {
-		daemon(c, ap, logger, scope)
+		daemon(c, ap, logger, scope, clk)
 	}
 }
 
@@ -362,36 +472,41 @@ func manualPurge(purgeClient *akamai.CachePurgeClient, tag, tagFile string) {
 }
 
 // daemon initializes the akamai-purger gRPC service.
-func daemon(c Config, ap *akamaiPurger, logger blog.Logger, scope prometheus.Registerer) {
-	clk := cmd.Clock()
-
+func daemon(c Config, ap *akamaiPurger, logger blog.Logger, scope prometheus.Registerer, clk clock.Clock) {
 	tlsConfig, err := c.AkamaiPurger.TLS.Load(scope)
 	cmd.FailOnError(err, "tlsConfig config")
 
 	stop, stopped := make(chan bool, 1), make(chan bool, 1)
-	ticker := time.NewTicker(c.AkamaiPurger.Throughput.PurgeBatchInterval.Duration)
 	go func() {
 	loop:
 		for {
 			select {
-			case <-ticker.C:
-				batch := ap.takeBatch()
-				if batch == nil {
-					continue
-				}
-				_ = ap.purgeBatch(batch)
 			case <-stop:
 				break loop
+			default:
+			}
+			batch := ap.takeBatch()
+			if batch == nil {
+				// Wait for an entry to be added to the stack.
+				select {
+				case <-ap.pending:
+					continue
+				case <-stop:
+					break loop
+				}
 			}
+			ap.throttle(context.Background(), batch)
+			_ = ap.purgeBatch(batch)
 		}
 
-		// As we may have missed a tick by calling ticker.Stop() and
-		// writing to the stop channel call ap.purge one last time just
-		// in case there is anything that still needs to be purged.
+		// As we may have stopped waiting between an entry being added to the
+		// stack and the purger waking up, call ap.purge one last time just in
+		// case there is anything that still needs to be purged.
 		stackLen := ap.len()
 		if stackLen > 0 {
 			logger.Infof("Shutting down; purging OCSP responses for %d certificates before exit.", stackLen)
 			batch := ap.takeBatch()
+			ap.throttle(context.Background(), batch)
 			err := ap.purgeBatch(batch)
 			cmd.FailOnError(err, fmt.Sprintf("Shutting down; failed to purge OCSP responses for %d certificates before exit", stackLen))
 			logger.Infof("Shutting down; finished purging OCSP responses for %d certificates.", stackLen)
@@ -401,13 +516,12 @@ func daemon(c Config, ap *akamaiPurger, logger blog.Logger, scope prometheus.Reg
 		stopped <- true
 	}()
 
-	// When the gRPC server finally exits, run a clean-up routine that stops the
-	// ticker and waits for the goroutine above to finish purging the stack.
+	// When the gRPC server finally exits, run a clean-up routine that waits for
+	// the goroutine above to finish purging the stack.
 	defer func() {
-		// Stop the ticker and signal that we want to shutdown by writing to the
-		// stop channel. We wait 15 seconds for any remaining URLs to be emptied
-		// from the current stack, if we pass that deadline we exit early.
-		ticker.Stop()
+		// Signal that we want to shutdown by writing to the stop channel. We
+		// wait 15 seconds for any remaining URLs to be emptied from the current
+		// stack, if we pass that deadline we exit early.
 		stop <- true
 		select {
 		case <-time.After(time.Second * 15):
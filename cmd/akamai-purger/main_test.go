@@ -7,8 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jmhodges/clock"
+
 	akamaipb "github.com/letsencrypt/boulder/akamai/proto"
 	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/ratelimits"
 	"github.com/letsencrypt/boulder/test"
 )
 
@@ -19,7 +23,7 @@ func TestImplementation(t *testing.T) {
 func TestThroughput_validate(t *testing.T) {
 	type fields struct {
 		QueueEntriesPerBatch int
-		PurgeBatchInterval   time.Duration
+		URLsPerSecond        int64
 	}
 	tests := []struct {
 		name    string
@@ -29,31 +33,31 @@ func TestThroughput_validate(t *testing.T) {
 		{"optimized defaults, should succeed",
 			fields{
 				QueueEntriesPerBatch: defaultEntriesPerBatch,
-				PurgeBatchInterval:   defaultPurgeBatchInterval},
+				URLsPerSecond:        defaultURLsPerSecond},
 			false,
 		},
-		{"2ms faster than optimized defaults, should succeed",
+		{"a full burst and 194 URLs per second, should succeed",
 			fields{
 				QueueEntriesPerBatch: defaultEntriesPerBatch,
-				PurgeBatchInterval:   defaultPurgeBatchInterval + 2*time.Millisecond},
+				URLsPerSecond:        194},
 			false,
 		},
 		{"exceeds URLs per second by 4 URLs",
 			fields{
 				QueueEntriesPerBatch: defaultEntriesPerBatch,
-				PurgeBatchInterval:   29 * time.Millisecond},
+				URLsPerSecond:        198},
 			true,
 		},
 		{"exceeds bytes per second by 20 bytes",
 			fields{
 				QueueEntriesPerBatch: 125,
-				PurgeBatchInterval:   1 * time.Second},
+				URLsPerSecond:        1},
 			true,
 		},
-		{"exceeds requests per second by 1 request",
+		{"no URLs per second",
 			fields{
-				QueueEntriesPerBatch: 1,
-				PurgeBatchInterval:   19999 * time.Microsecond},
+				QueueEntriesPerBatch: defaultEntriesPerBatch,
+				URLsPerSecond:        0},
 			true,
 		},
 	}
@@ -61,8 +65,8 @@ func TestThroughput_validate(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tr := &Throughput{
 				QueueEntriesPerBatch: tt.fields.QueueEntriesPerBatch,
+				URLsPerSecond:        tt.fields.URLsPerSecond,
 			}
-			tr.PurgeBatchInterval.Duration = tt.fields.PurgeBatchInterval
 			if err := tr.validate(); (err != nil) != tt.wantErr {
 				t.Errorf("Throughput.validate() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -149,3 +153,46 @@ func TestAkamaiPurgerQueueWithOneEntry(t *testing.T) {
 	// Verify that the stack no longer contains our entry.
 	test.AssertEquals(t, len(ap.toPurge), 0)
 }
+
+func TestAkamaiPurgerThrottle(t *testing.T) {
+	clk := clock.NewFake()
+	limiter, err := ratelimits.NewLimiter(clk, ratelimits.NewInmemSource(), metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "Failed to create limiter")
+	tp := Throughput{QueueEntriesPerBatch: 2, URLsPerSecond: 60}
+	limit, err := ratelimits.NewInternalLimit(ratelimits.AkamaiPurgeURLsPerEndpoint, tp.burst(), tp.URLsPerSecond, time.Second)
+	test.AssertNotError(t, err, "Failed to create limit")
+	ap := &akamaiPurger{
+		log:      blog.NewMock(),
+		limiter:  limiter,
+		limit:    limit,
+		burst:    tp.burst(),
+		endpoint: "localhost:6789",
+		clk:      clk,
+	}
+	ctx := context.Background()
+
+	// A full batch of 6 URLs spends the whole burst.
+	wait, err := ap.reserve(ctx, 6)
+	test.AssertNotError(t, err, "reserve failed")
+	test.AssertEquals(t, wait, time.Duration(0))
+
+	// A batch of a single URL is charged 'minURLsPerRequest' URLs, which refill
+	// at one URL per 1/60th of a second.
+	wait, err = ap.reserve(ctx, 1)
+	test.AssertNotError(t, err, "reserve failed")
+	test.AssertEquals(t, wait, minURLsPerRequest*(time.Second/60))
+
+	// throttle sleeps until the batch may be sent.
+	start := clk.Now()
+	ap.throttle(ctx, [][]string{{"http://test.com/0"}})
+	test.AssertEquals(t, clk.Now().Sub(start), minURLsPerRequest*(time.Second/60))
+	wait, err = ap.reserve(ctx, 1)
+	test.AssertNotError(t, err, "reserve failed")
+	test.Assert(t, wait > 0, "capacity should have been spent by throttle")
+
+	// Batches of more URLs than the burst are charged a full burst.
+	clk.Add(time.Second)
+	wait, err = ap.reserve(ctx, 100)
+	test.AssertNotError(t, err, "reserve failed")
+	test.AssertEquals(t, wait, time.Duration(0))
+}
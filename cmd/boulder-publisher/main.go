@@ -10,6 +10,7 @@ import (
 	ct "github.com/google/certificate-transparency-go"
 
 	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/ctpolicy/ctconfig"
 	"github.com/letsencrypt/boulder/features"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/issuance"
@@ -32,6 +33,12 @@ type Config struct {
 		// a chain, starting with the issuing intermediate, followed by one or
 		// more additional certificates, up to and including a root.
 		Chains [][]string `validate:"min=1,dive,min=2,dive,required"`
+
+		// LogLimits optionally paces and caps the submissions made to CT logs,
+		// keyed by the submission URL of the log as it appears in the log list.
+		// Submissions to logs without an entry are neither paced nor capped.
+		// Each publisher paces its own submissions.
+		LogLimits map[string]ctconfig.LogLimit `validate:"omitempty,dive"`
 	}
 
 	Syslog        cmd.SyslogConfig
@@ -90,7 +97,8 @@ func main() {
 
 	clk := cmd.Clock()
 
-	pubi := publisher.New(bundles, c.Publisher.UserAgent, logger, scope)
+	pubi, err := publisher.New(bundles, c.Publisher.UserAgent, c.Publisher.LogLimits, clk, logger, scope)
+	cmd.FailOnError(err, "Failed to create Publisher")
 
 	start, err := bgrpc.NewServer(c.Publisher.GRPC, logger).Add(
 		&pubpb.Publisher_ServiceDesc, pubi).Build(tlsConfig, scope, clk)
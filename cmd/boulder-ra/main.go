@@ -22,6 +22,9 @@ import (
 	pubpb "github.com/letsencrypt/boulder/publisher/proto"
 	"github.com/letsencrypt/boulder/ra"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/ratelimits"
+	rlpb "github.com/letsencrypt/boulder/ratelimits/proto"
+	bredis "github.com/letsencrypt/boulder/redis"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 	vapb "github.com/letsencrypt/boulder/va/proto"
 )
@@ -95,6 +98,22 @@ type Config struct {
 		// generate OCSP URLs to purge during revocation.
 		IssuerCerts []string `validate:"min=1,dive,required"`
 
+		// Limiter configures the key-value rate limiter which evaluates the
+		// NewOrdersPerAccount and CertificatesPerDomain limits, in shadow mode
+		// unless the UseKvLimitsForNewOrder feature is enabled. If it is not
+		// configured, only the legacy rate limits are evaluated.
+		Limiter struct {
+			// Redis, RateLimitD, Defaults, Environment, Overrides, and
+			// Exemptions are as described for the WFE's Limiter
+			// configuration. See: ratelimits/README.md for details.
+			Redis       *bredis.Config `validate:"excluded_with=RateLimitD"`
+			RateLimitD  *cmd.GRPCClientConfig
+			Defaults    string `validate:"required_with=Redis,excluded_with=RateLimitD"`
+			Environment string `validate:"excluded_with=RateLimitD"`
+			Overrides   string `validate:"excluded_with=RateLimitD"`
+			Exemptions  string `validate:"excluded_with=RateLimitD"`
+		}
+
 		Features features.Config
 	}
 
@@ -232,6 +251,34 @@ func main() {
 		cmd.Fail("Error in RA config: MaxNames must not be 0")
 	}
 
+	// Only assign a configured *ratelimits.Limiter or *ratelimits.Client to
+	// the TransactionLimiter passed to the RA, which would otherwise hold a
+	// non-nil interface wrapping a nil pointer when rate limiting is disabled.
+	var limiter ratelimits.TransactionLimiter
+	var txnBuilder *ratelimits.TransactionBuilder
+	if c.RA.Limiter.RateLimitD != nil {
+		// The limits, overrides, and exemptions of ratelimitd apply.
+		rlConn, err := bgrpc.ClientSetup(c.RA.Limiter.RateLimitD, tlsConfig, scope, clk)
+		cmd.FailOnError(err, "Failed to load credentials and create gRPC connection to ratelimitd")
+		limiter = ratelimits.NewClient(rlpb.NewRateLimitsClient(rlConn))
+		txnBuilder = ratelimits.NewRemoteTransactionBuilder()
+	} else if c.RA.Limiter.Defaults != "" {
+		if c.RA.Limiter.Redis == nil {
+			cmd.Fail("'redis' is required when 'defaults' is set")
+		}
+		limiterRedis, err := bredis.NewRingFromConfig(*c.RA.Limiter.Redis, scope, logger)
+		cmd.FailOnError(err, "Failed to create Redis ring")
+		defer limiterRedis.StopLookups()
+
+		l, err := ratelimits.NewLimiter(clk, ratelimits.NewRedisSource(limiterRedis.Ring, clk, scope), scope)
+		cmd.FailOnError(err, "Failed to create rate limiter")
+		limiter = l
+		txnBuilder, err = ratelimits.NewTransactionBuilder(c.RA.Limiter.Defaults, c.RA.Limiter.Environment, c.RA.Limiter.Overrides, c.RA.Limiter.Exemptions)
+		cmd.FailOnError(err, "Failed to create rate limits transaction builder")
+	} else if features.Get().UseKvLimitsForNewOrder {
+		cmd.Fail("UseKvLimitsForNewOrder requires the Limiter to be configured")
+	}
+
 	rai := ra.NewRegistrationAuthorityImpl(
 		clk,
 		logger,
@@ -248,6 +295,8 @@ func main() {
 		ctp,
 		apc,
 		issuerCerts,
+		limiter,
+		txnBuilder,
 	)
 	defer rai.DrainFinalize()
 
@@ -64,13 +64,13 @@ func main() {
 	cmd.FailOnError(err, "While initializing dbMap")
 
 	dbReadOnlyMap := dbMap
-	if c.SA.ReadOnlyDB != (cmd.DBConfig{}) {
+	if c.SA.ReadOnlyDB.DBConnectFile != "" {
 		dbReadOnlyMap, err = sa.InitWrappedDb(c.SA.ReadOnlyDB, scope, logger)
 		cmd.FailOnError(err, "While initializing dbReadOnlyMap")
 	}
 
 	dbIncidentsMap := dbMap
-	if c.SA.IncidentsDB != (cmd.DBConfig{}) {
+	if c.SA.IncidentsDB.DBConnectFile != "" {
 		dbIncidentsMap, err = sa.InitWrappedDb(c.SA.IncidentsDB, scope, logger)
 		cmd.FailOnError(err, "While initializing dbIncidentsMap")
 	}
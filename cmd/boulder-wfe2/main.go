@@ -26,6 +26,7 @@ import (
 	"github.com/letsencrypt/boulder/nonce"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
 	"github.com/letsencrypt/boulder/ratelimits"
+	rlpb "github.com/letsencrypt/boulder/ratelimits/proto"
 	bredis "github.com/letsencrypt/boulder/redis"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 	"github.com/letsencrypt/boulder/wfe2"
@@ -84,6 +85,12 @@ type Config struct {
 		// local and remote nonce-service instances.
 		RedeemNonceService *cmd.GRPCClientConfig `validate:"required_without=RedeemNonceServices"`
 
+		// SharedNonceStorage indicates that the nonce-service instances store
+		// nonces in a shared Redis ring, so that any of them can redeem any
+		// nonce. Redemption RPCs then needn't be routed to the instance which
+		// issued the nonce, and RedeemNonceService may use any SRVResolver.
+		SharedNonceStorage bool
+
 		// NoncePrefixKey is a secret used for deriving the prefix of each nonce
 		// instance. It should contain 256 bits of random data to be suitable as
 		// an HMAC-SHA256 key (e.g. the output of `openssl rand -hex 32`). In a
@@ -142,21 +149,103 @@ type Config struct {
 
 		Limiter struct {
 			// Redis contains the configuration necessary to connect to Redis
-			// for rate limiting. This field is required to enable rate
-			// limiting.
-			Redis *bredis.Config `validate:"required_with=Defaults"`
+			// for rate limiting. Either this field or RateLimitD is required
+			// to enable rate limiting.
+			Redis *bredis.Config `validate:"excluded_with=RateLimitD"`
+
+			// RateLimitD, if set, is the configuration of the gRPC client of
+			// ratelimitd, whose buckets are spent from in place of those in
+			// Redis, and whose limits, overrides, and exemptions apply. The
+			// remaining fields, other than LogDenials and RateLimitHeaders,
+			// configure the Limiter which uses Redis, and must not be set:
+			// ratelimitd is configured in their place.
+			RateLimitD *cmd.GRPCClientConfig
 
 			// Defaults is a path to a YAML file containing default rate limits.
 			// See: ratelimits/README.md for details. This field is required to
-			// enable rate limiting. If any individual rate limit is not set,
-			// that limit will be disabled.
-			Defaults string `validate:"required_with=Redis"`
+			// enable rate limiting with Redis. If any individual rate limit is
+			// not set, that limit will be disabled.
+			Defaults string `validate:"required_with=Redis,excluded_with=RateLimitD"`
+
+			// Environment selects the 'Environment:<name>' section of the
+			// Defaults file which applies in addition to its top-level limits.
+			// See: ratelimits/README.md for details. If this field is not set,
+			// only the top-level limits apply.
+			Environment string `validate:"excluded_with=RateLimitD"`
 
 			// Overrides is a path to a YAML file containing overrides for the
 			// default rate limits. See: ratelimits/README.md for details. If
 			// this field is not set, all requesters will be subject to the
 			// default rate limits.
-			Overrides string
+			Overrides string `validate:"excluded_with=RateLimitD"`
+
+			// Exemptions is a path to a YAML file containing ACME registration
+			// IDs which are exempt from specific rate limits. See:
+			// ratelimits/README.md for details. If this field is not set, no
+			// requesters are exempt.
+			Exemptions string `validate:"excluded_with=RateLimitD"`
+
+			// LogDenials, if true, logs every rate limit denial, including the
+			// limit name, bucket key, cost, remaining capacity, and retry
+			// duration, as a structured log line.
+			LogDenials bool
+
+			// RateLimitHeaders, if true, adds RateLimit-Limit,
+			// RateLimit-Remaining, and RateLimit-Reset headers to new-account
+			// responses, describing the requester's
+			// NewRegistrationsPerIPAddress bucket as of the spend of the
+			// request. New-order responses which the RA denies for a rate
+			// limit carry RateLimit-Remaining and RateLimit-Reset.
+			//
+			// The new-account spend is otherwise made asynchronously, but is
+			// made synchronously when this is set, so that its Decision can be
+			// described: every new-account request then waits for a round
+			// trip to Redis, or to ratelimitd. Since the new-account limits
+			// aren't yet enforced, Retry-After is never added to new-account
+			// responses, even if the spend was denied.
+			RateLimitHeaders bool
+
+			// ActiveBucketsInterval is how often the number of buckets stored
+			// in Redis for each limit is estimated and exported as a gauge. If
+			// this field is not set, no estimates are made.
+			ActiveBucketsInterval config.Duration `validate:"-"`
+
+			// OverrideAlertThreshold, if set, logs an alert when an override
+			// has been utilized at or above this proportion of its burst, a
+			// value between 0 and 1, for at least OverrideAlertDuration.
+			OverrideAlertThreshold float64 `validate:"omitempty,gt=0,lte=1"`
+
+			// OverrideAlertDuration is how long an override must be utilized
+			// at or above OverrideAlertThreshold before an alert is logged.
+			OverrideAlertDuration config.Duration `validate:"-"`
+
+			// ClockSkewInterval is how often the local clock is compared with
+			// the clock of each Redis shard. If this field is not set, clock
+			// skew is not monitored.
+			ClockSkewInterval config.Duration `validate:"-"`
+
+			// ClockSkewThreshold is the difference between the local clock and
+			// the clock of a Redis shard above which a warning is logged.
+			// Defaults to 1s.
+			ClockSkewThreshold config.Duration `validate:"-"`
+
+			// SourceTimeout is the deadline applied to each Redis call which
+			// reads or writes a single bucket, or pings Redis, and
+			// BatchSourceTimeout is the deadline applied to each Redis call
+			// which reads or writes many buckets, or estimates their number,
+			// and to each round trip of scans. If either field is not set, no
+			// deadline is applied to those calls beyond the Redis client's own
+			// timeouts.
+			SourceTimeout      config.Duration `validate:"-"`
+			BatchSourceTimeout config.Duration `validate:"-"`
+
+			// UtilizationReportFile, if set, is a path to which a JSON report
+			// of override utilization, the most denied buckets, and per-limit
+			// spend rates is written every UtilizationReportInterval. The same
+			// report is always served by the debug server at
+			// /debug/ratelimits/report.
+			UtilizationReportFile     string
+			UtilizationReportInterval config.Duration `validate:"-"`
 		}
 	}
 
@@ -233,7 +322,7 @@ func setupWFE(c Config, scope prometheus.Registerer, clk clock.Clock) (rapb.Regi
 	var npm map[string]nonce.Redeemer
 	if c.WFE.RedeemNonceService != nil {
 		// Dispatch nonce redemption RPCs dynamically.
-		if c.WFE.RedeemNonceService.SRVResolver != noncebalancer.SRVResolverScheme {
+		if c.WFE.RedeemNonceService.SRVResolver != noncebalancer.SRVResolverScheme && !c.WFE.SharedNonceStorage {
 			cmd.Fail(fmt.Sprintf(
 				"'redeemNonceService.SRVResolver' must be set to %q", noncebalancer.SRVResolverScheme),
 			)
@@ -351,16 +440,65 @@ func main() {
 	var limiter *ratelimits.Limiter
 	var txnBuilder *ratelimits.TransactionBuilder
 	var limiterRedis *bredis.Ring
-	if c.WFE.Limiter.Defaults != "" {
+	var limiterClient *ratelimits.Client
+	if c.WFE.Limiter.RateLimitD != nil {
+		// Setup rate limiting using the buckets and limits of ratelimitd.
+		tlsConfig, err := c.WFE.TLS.Load(stats)
+		cmd.FailOnError(err, "TLS config")
+		rlConn, err := bgrpc.ClientSetup(c.WFE.Limiter.RateLimitD, tlsConfig, stats, clk)
+		cmd.FailOnError(err, "Failed to load credentials and create gRPC connection to ratelimitd")
+		limiterClient = ratelimits.NewClient(rlpb.NewRateLimitsClient(rlConn))
+		txnBuilder = ratelimits.NewRemoteTransactionBuilder()
+	} else if c.WFE.Limiter.Defaults != "" {
 		// Setup rate limiting.
+		if c.WFE.Limiter.Redis == nil {
+			cmd.Fail("'redis' is required when 'defaults' is set")
+		}
 		limiterRedis, err = bredis.NewRingFromConfig(*c.WFE.Limiter.Redis, stats, logger)
 		cmd.FailOnError(err, "Failed to create Redis ring")
 
 		source := ratelimits.NewRedisSource(limiterRedis.Ring, clk, stats)
+		if c.WFE.Limiter.ClockSkewInterval.Duration > 0 {
+			threshold := c.WFE.Limiter.ClockSkewThreshold.Duration
+			if threshold == 0 {
+				threshold = time.Second
+			}
+			go source.MonitorClockSkew(context.Background(), c.WFE.Limiter.ClockSkewInterval.Duration, threshold, logger)
+		}
 		limiter, err = ratelimits.NewLimiter(clk, source, stats)
 		cmd.FailOnError(err, "Failed to create rate limiter")
-		txnBuilder, err = ratelimits.NewTransactionBuilder(c.WFE.Limiter.Defaults, c.WFE.Limiter.Overrides)
+		limiter.SetSourceTimeouts(c.WFE.Limiter.SourceTimeout.Duration, c.WFE.Limiter.BatchSourceTimeout.Duration)
+		if c.WFE.Limiter.LogDenials {
+			limiter.SetDenialHook(ratelimits.LogDenials(logger))
+		}
+		if c.WFE.Limiter.OverrideAlertThreshold > 0 {
+			err = limiter.SetOverrideAlertHook(c.WFE.Limiter.OverrideAlertThreshold, c.WFE.Limiter.OverrideAlertDuration.Duration, ratelimits.LogOverrideAlerts(logger))
+			cmd.FailOnError(err, "Failed to configure rate limit override alerts")
+		}
+		if c.WFE.Limiter.ActiveBucketsInterval.Duration > 0 {
+			go limiter.ReportActiveBuckets(context.Background(), c.WFE.Limiter.ActiveBucketsInterval.Duration, logger)
+		}
+		txnBuilder, err = ratelimits.NewTransactionBuilder(c.WFE.Limiter.Defaults, c.WFE.Limiter.Environment, c.WFE.Limiter.Overrides, c.WFE.Limiter.Exemptions)
 		cmd.FailOnError(err, "Failed to create rate limits transaction builder")
+		cmd.HandleDebug("/debug/ratelimits", ratelimits.NewDebugHandler(limiter, txnBuilder))
+		cmd.HandleDebug("/debug/ratelimits/top", ratelimits.NewTopDeniedHandler(limiter))
+		cmd.HandleDebug("/debug/ratelimits/report", ratelimits.NewUtilizationReportHandler(limiter))
+		if c.WFE.Limiter.UtilizationReportFile != "" {
+			if c.WFE.Limiter.UtilizationReportInterval.Duration <= 0 {
+				cmd.Fail("utilizationReportInterval must be set when utilizationReportFile is set")
+			}
+			go limiter.WriteUtilizationReports(context.Background(), c.WFE.Limiter.UtilizationReportInterval.Duration, c.WFE.Limiter.UtilizationReportFile, logger)
+		}
+	}
+
+	// Only assign a configured *ratelimits.Limiter or *ratelimits.Client to
+	// the TransactionLimiter passed to the WFE, which would otherwise hold a
+	// non-nil interface wrapping a nil pointer when rate limiting is disabled.
+	var txnLimiter ratelimits.TransactionLimiter
+	if limiter != nil {
+		txnLimiter = limiter
+	} else if limiterClient != nil {
+		txnLimiter = limiterClient
 	}
 
 	var accountGetter wfe2.AccountGetter
@@ -391,7 +529,7 @@ func main() {
 		rnc,
 		npKey,
 		accountGetter,
-		limiter,
+		txnLimiter,
 		txnBuilder,
 	)
 	cmd.FailOnError(err, "Unable to create WFE")
@@ -401,6 +539,7 @@ func main() {
 	wfe.DirectoryCAAIdentity = c.WFE.DirectoryCAAIdentity
 	wfe.DirectoryWebsite = c.WFE.DirectoryWebsite
 	wfe.LegacyKeyIDPrefix = c.WFE.LegacyKeyIDPrefix
+	wfe.RateLimitHeaders = c.WFE.Limiter.RateLimitHeaders
 
 	logger.Infof("WFE using key policy: %#v", kp)
 
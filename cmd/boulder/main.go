@@ -21,12 +21,19 @@ import (
 	_ "github.com/letsencrypt/boulder/cmd/crl-checker"
 	_ "github.com/letsencrypt/boulder/cmd/crl-storer"
 	_ "github.com/letsencrypt/boulder/cmd/crl-updater"
+	_ "github.com/letsencrypt/boulder/cmd/db-migrate"
 	_ "github.com/letsencrypt/boulder/cmd/expiration-mailer"
 	_ "github.com/letsencrypt/boulder/cmd/id-exporter"
 	_ "github.com/letsencrypt/boulder/cmd/log-validator"
 	_ "github.com/letsencrypt/boulder/cmd/nonce-service"
 	_ "github.com/letsencrypt/boulder/cmd/notify-mailer"
 	_ "github.com/letsencrypt/boulder/cmd/ocsp-responder"
+	_ "github.com/letsencrypt/boulder/cmd/ratelimitd"
+	_ "github.com/letsencrypt/boulder/cmd/ratelimits-analyzer"
+	_ "github.com/letsencrypt/boulder/cmd/ratelimits-envoy"
+	_ "github.com/letsencrypt/boulder/cmd/ratelimits-notifier"
+	_ "github.com/letsencrypt/boulder/cmd/ratelimits-tool"
+	_ "github.com/letsencrypt/boulder/cmd/ratelimits-validator"
 	_ "github.com/letsencrypt/boulder/cmd/reversed-hostname-checker"
 	_ "github.com/letsencrypt/boulder/cmd/rocsp-tool"
 	"github.com/letsencrypt/boulder/core"
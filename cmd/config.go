@@ -55,6 +55,62 @@ type DBConfig struct {
 	// A file containing a connect URL for the DB.
 	DBConnectFile string `validate:"required"`
 
+	// FailoverDBConnectFiles are files containing connect URLs for other hosts
+	// serving the same DB, in order of preference. New connections are made to
+	// them when the hosts before them are unreachable.
+	FailoverDBConnectFiles []string `validate:"omitempty,dive,required"`
+
+	// FailbackInterval is how often the hosts preferred to the one currently
+	// connected to are probed, so that new connections move back to them once
+	// they are reachable again. If zero, they are not probed. ConnMaxLifetime
+	// should also be set, so that existing connections are eventually replaced.
+	FailbackInterval config.Duration `validate:"-"`
+
+	// TLS, if set, is the client certificate and key presented to the DB, and
+	// the CA used to verify its certificate. It overrides any "tls" parameter
+	// of the connect URLs.
+	TLS *TLSConfig `validate:"omitempty"`
+
+	// TransactionIsolation, if set, is the isolation level of the DB's
+	// sessions, unless the connect URL sets transaction_isolation.
+	TransactionIsolation string `validate:"omitempty,oneof=READ-UNCOMMITTED READ-COMMITTED REPEATABLE-READ SERIALIZABLE"`
+
+	// QueryComments, if true, appends a comment naming the component and the
+	// trace which executed each statement, so that they can be attributed in
+	// the DB's processlist and slow query log.
+	QueryComments bool
+
+	// MaxTransactionDuration, if non-zero, is the longest a transaction may
+	// remain open. Transactions are rolled back, and their connections
+	// returned to the pool, once it elapses or their context is canceled.
+	MaxTransactionDuration config.Duration `validate:"-"`
+
+	// MaxExecutionTimeHints, if true, adds a MAX_EXECUTION_TIME optimizer hint
+	// to each SELECT with a deadline, so that the DB aborts it once the
+	// deadline passes. It has no effect on MariaDB.
+	MaxExecutionTimeHints bool
+
+	// CircuitBreakerThreshold, if non-zero, is the number of consecutive
+	// operations which must fail because the DB is unreachable, or time out,
+	// for operations to fail fast rather than wait on the DB, until one
+	// succeeds. While failing fast a single operation is attempted every
+	// CircuitBreakerCooldown, which defaults to 5 seconds.
+	CircuitBreakerThreshold int             `validate:"min=0"`
+	CircuitBreakerCooldown  config.Duration `validate:"-"`
+
+	// MaxConcurrentQueries, if non-zero, is the most statements, counting
+	// each transaction as one, which may be executed at once. Others wait,
+	// within their timeout, for one to finish.
+	MaxConcurrentQueries int `validate:"min=0"`
+
+	// RequiredSQLModes, RequiredCharset, and MinServerVersion, if set, are
+	// checked against the DB at startup, which fails if its sessions lack any
+	// of the SQL modes, its default character set differs, or its version,
+	// e.g. "10.5", is older.
+	RequiredSQLModes []string `validate:"omitempty,dive,required"`
+	RequiredCharset  string
+	MinServerVersion string
+
 	// MaxOpenConns sets the maximum number of open connections to the
 	// database. If MaxIdleConns is greater than 0 and MaxOpenConns is
 	// less than MaxIdleConns, then MaxIdleConns will be reduced to
@@ -78,6 +134,14 @@ type DBConfig struct {
 	// If d < 0, connections are not closed due to a connection's idle
 	// time.
 	ConnMaxIdleTime config.Duration `validate:"-"`
+
+	// ReadTimeout, WriteTimeout, and LongRunningTimeout are the deadlines
+	// applied to reads, writes, and operations marked as long-running,
+	// respectively, whose context has no deadline. If zero, no deadline is
+	// applied.
+	ReadTimeout        config.Duration `validate:"-"`
+	WriteTimeout       config.Duration `validate:"-"`
+	LongRunningTimeout config.Duration `validate:"-"`
 }
 
 // URL returns the DBConnect URL represented by this DBConfig object, loading it
@@ -87,6 +151,21 @@ func (d *DBConfig) URL() (string, error) {
 	return strings.TrimSpace(string(url)), err
 }
 
+// FailoverURLs returns the DBConnect URLs of the failover hosts represented by
+// this DBConfig object, in order, loading them from the files on disk. Leading
+// and trailing whitespace is stripped.
+func (d *DBConfig) FailoverURLs() ([]string, error) {
+	var urls []string
+	for _, file := range d.FailoverDBConnectFiles {
+		url, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, strings.TrimSpace(string(url)))
+	}
+	return urls, nil
+}
+
 type SMTPConfig struct {
 	PasswordConfig
 	Server   string `validate:"required"`
@@ -464,6 +543,23 @@ type GRPCServiceConfig struct {
 	// which do not appear in this list, and the server interceptor will reject
 	// RPC calls for this service from clients which are not listed here.
 	ClientNames []string `json:"clientNames" validate:"min=1,dive,hostname,required"`
+
+	// ClientRateLimits optionally limits the rate at which each client may make
+	// RPCs to this service, keyed by a client certificate SAN which must also
+	// appear in ClientNames. RPCs from a client beyond its limit are rejected
+	// with a rate limit error, protecting the service from runaway callers,
+	// e.g. during retry storms. Each server enforces the limits separately.
+	// Clients without a limit are not limited.
+	ClientRateLimits map[string]GRPCClientRateLimit `json:"clientRateLimits" validate:"omitempty,dive"`
+}
+
+// GRPCClientRateLimit configures the rate at which a single client may make
+// RPCs to a gRPC service. Count RPCs are allowed per Period, and up to Burst
+// RPCs at once.
+type GRPCClientRateLimit struct {
+	Burst  int64           `json:"burst" validate:"required,min=1"`
+	Count  int64           `json:"count" validate:"required,min=1"`
+	Period config.Duration `json:"period" validate:"required"`
 }
 
 // OpenTelemetryConfig configures tracing via OpenTelemetry.
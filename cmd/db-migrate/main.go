@@ -0,0 +1,144 @@
+package notmain
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/db/migrations"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/sa"
+)
+
+const usageIntro = `
+Introduction:
+
+The db-migrate tool applies and rolls back the schema migrations in a
+directory, such as sa/db/boulder_sa, recording those applied in the
+gorp_migrations table of the database.
+
+Usage:
+
+  db-migrate -config db-migrate.json [flags] <action>
+
+Actions:
+
+  status
+    Print each migration, and when it was applied if it has been.
+
+  up
+    Apply every pending migration, in order.
+
+  down
+    Roll back the -count most recently applied migrations.
+
+With -dry-run, up and down print the migrations they would apply or roll back
+without changing the database.
+`
+
+type Config struct {
+	DBMigrate struct {
+		DB cmd.DBConfig
+
+		// MigrationsDir is the directory of .sql migration files to manage,
+		// e.g. sa/db/boulder_sa.
+		MigrationsDir string `validate:"required"`
+	}
+}
+
+// printMigrations prints the ID of each migration, prefixed by verb.
+func printMigrations(verb string, ms []migrations.Migration) {
+	for _, migration := range ms {
+		fmt.Printf("%s %s\n", verb, migration.ID)
+	}
+}
+
+func main() {
+	configFile := flag.String("config", "", "File containing a JSON config.")
+	dryRun := flag.Bool("dry-run", false, "Print the migrations which would be applied or rolled back, without doing so.")
+	count := flag.Int("count", 1, "Number of migrations to roll back with the down action.")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "%s\n\n", usageIntro)
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+	if *configFile == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var c Config
+	err := cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+
+	logger := cmd.NewLogger(cmd.SyslogConfig{StdoutLevel: 6})
+	logger.Info(cmd.VersionString())
+
+	loaded, err := migrations.Load(os.DirFS(c.DBMigrate.MigrationsDir), ".")
+	cmd.FailOnError(err, "Loading migrations")
+
+	dbMap, err := sa.InitWrappedDb(c.DBMigrate.DB, nil, logger)
+	cmd.FailOnError(err, "While initializing dbMap")
+	defer dbMap.Close()
+
+	run(context.Background(), migrations.New(dbMap, loaded, cmd.Clock()), flag.Arg(0), *dryRun, *count, logger)
+}
+
+func run(ctx context.Context, m *migrations.Migrator, action string, dryRun bool, count int, logger blog.Logger) {
+	switch action {
+	case "status":
+		statuses, err := m.Status(ctx)
+		cmd.FailOnError(err, "Getting migration status")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if !status.AppliedAt.IsZero() {
+				appliedAt = status.AppliedAt.String()
+			}
+			fmt.Printf("%s\t%s\n", status.ID, appliedAt)
+		}
+
+	case "up":
+		var applied []migrations.Migration
+		var err error
+		if dryRun {
+			applied, err = m.PlanUp(ctx)
+		} else {
+			applied, err = m.Up(ctx)
+		}
+		verb := "Applied"
+		if dryRun {
+			verb = "Would apply"
+		}
+		printMigrations(verb, applied)
+		cmd.FailOnError(err, "Applying migrations")
+		logger.Infof("Applied %d migrations", len(applied))
+
+	case "down":
+		var rolledBack []migrations.Migration
+		var err error
+		if dryRun {
+			rolledBack, err = m.PlanDown(ctx, count)
+		} else {
+			rolledBack, err = m.Down(ctx, count)
+		}
+		verb := "Rolled back"
+		if dryRun {
+			verb = "Would roll back"
+		}
+		printMigrations(verb, rolledBack)
+		cmd.FailOnError(err, "Rolling back migrations")
+		logger.Infof("Rolled back %d migrations", len(rolledBack))
+
+	default:
+		cmd.Fail(fmt.Sprintf("Unknown action %q: must be status, up, or down", action))
+	}
+}
+
+func init() {
+	cmd.RegisterCommand("db-migrate", main, &cmd.ConfigValidator{Config: &Config{}})
+}
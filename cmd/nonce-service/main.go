@@ -8,9 +8,11 @@ import (
 	"os"
 
 	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/config"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/nonce"
 	noncepb "github.com/letsencrypt/boulder/nonce/proto"
+	bredis "github.com/letsencrypt/boulder/redis"
 )
 
 type Config struct {
@@ -42,6 +44,16 @@ type Config struct {
 		// by default.
 		NoncePrefixKey cmd.PasswordConfig `validate:"excluded_with=NoncePrefix,structonly"`
 
+		// Redis, if set, contains the configuration necessary to connect to
+		// the Redis ring in which nonces are stored, instead of in memory.
+		// Every instance sharing the ring can then redeem any nonce stored in
+		// it, regardless of which issued it, and MaxUsed is ignored.
+		Redis *bredis.Config `validate:"omitempty"`
+
+		// NonceTTL is how long a nonce stored in Redis remains redeemable.
+		// It is only used if Redis is set. Defaults to 15 minutes.
+		NonceTTL config.Duration `validate:"-"`
+
 		Syslog        cmd.SyslogConfig
 		OpenTelemetry cmd.OpenTelemetryConfig
 	}
@@ -104,13 +116,24 @@ func main() {
 	defer oTelShutdown(context.Background())
 	logger.Info(cmd.VersionString())
 
-	ns, err := nonce.NewNonceService(scope, c.NonceService.MaxUsed, c.NonceService.NoncePrefix)
-	cmd.FailOnError(err, "Failed to initialize nonce service")
+	var nonceServer *nonce.Server
+	if c.NonceService.Redis != nil {
+		nonceRedis, err := bredis.NewRingFromConfig(*c.NonceService.Redis, scope, logger)
+		cmd.FailOnError(err, "Failed to create Redis ring")
+		defer nonceRedis.StopLookups()
+
+		ns, err := nonce.NewRedisNonceService(nonceRedis.Ring, scope, c.NonceService.NonceTTL.Duration, c.NonceService.NoncePrefix)
+		cmd.FailOnError(err, "Failed to initialize nonce service")
+		nonceServer = nonce.NewRedisServer(ns)
+	} else {
+		ns, err := nonce.NewNonceService(scope, c.NonceService.MaxUsed, c.NonceService.NoncePrefix)
+		cmd.FailOnError(err, "Failed to initialize nonce service")
+		nonceServer = nonce.NewServer(ns)
+	}
 
 	tlsConfig, err := c.NonceService.TLS.Load(scope)
 	cmd.FailOnError(err, "tlsConfig config")
 
-	nonceServer := nonce.NewServer(ns)
 	start, err := bgrpc.NewServer(c.NonceService.GRPC, logger).Add(
 		&noncepb.NonceService_ServiceDesc, nonceServer).Build(tlsConfig, scope, cmd.Clock())
 	cmd.FailOnError(err, "Unable to setup nonce service gRPC server")
@@ -192,7 +192,7 @@ as generated by Boulder's ceremony command.
 		cmd.FailOnError(err, "Could not create redis source")
 
 		var dbMap *db.WrappedMap
-		if c.OCSPResponder.DB != (cmd.DBConfig{}) {
+		if c.OCSPResponder.DB.DBConnectFile != "" {
 			dbMap, err = sa.InitWrappedDb(c.OCSPResponder.DB, scope, logger)
 			cmd.FailOnError(err, "While initializing dbMap")
 		}
@@ -0,0 +1,138 @@
+package notmain
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/letsencrypt/boulder/cmd"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/ratelimits"
+	rlpb "github.com/letsencrypt/boulder/ratelimits/proto"
+	bredis "github.com/letsencrypt/boulder/redis"
+)
+
+type Config struct {
+	RateLimitD struct {
+		cmd.ServiceConfig
+
+		// Redis contains the configuration necessary to connect to the Redis
+		// which stores the buckets of the Limiter.
+		Redis *bredis.Config `validate:"required"`
+
+		// Defaults is a path to a YAML file containing default rate limits.
+		// See: ratelimits/README.md for details. If any individual rate limit
+		// is not set, that limit will be disabled.
+		Defaults string `validate:"required"`
+
+		// Environment selects the 'Environment:<name>' section of the Defaults
+		// file which applies in addition to its top-level limits. If this
+		// field is not set, only the top-level limits apply.
+		Environment string
+
+		// Overrides is a path to a YAML file containing overrides for the
+		// default rate limits. If this field is not set, all requesters will be
+		// subject to the default rate limits.
+		Overrides string
+
+		// Exemptions is a path to a YAML file containing ACME registration IDs
+		// which are exempt from specific rate limits. If this field is not
+		// set, no requesters are exempt.
+		Exemptions string
+
+		// AdminAddr, if set, is the address on which to serve the admin API for
+		// inspecting and resetting buckets. See: ratelimits/README.md for
+		// details. The admin API uses the same TLS configuration as the gRPC
+		// server, so clients must present a certificate issued by CACertFile.
+		AdminAddr string `validate:"omitempty,hostname_port"`
+
+		// AdminToken contains the bearer token which must be presented to the
+		// admin API. It is required if AdminAddr is set.
+		AdminToken cmd.PasswordConfig `validate:"-"`
+
+		// LogDenials, if true, logs every rate limit denial as a structured log
+		// line.
+		LogDenials bool
+
+		Syslog        cmd.SyslogConfig
+		OpenTelemetry cmd.OpenTelemetryConfig
+	}
+}
+
+func main() {
+	grpcAddr := flag.String("addr", "", "gRPC listen address override")
+	debugAddr := flag.String("debug-addr", "", "Debug server address override")
+	configFile := flag.String("config", "", "File path to the configuration file for this service")
+	flag.Parse()
+
+	if *configFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var c Config
+	err := cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+
+	if *grpcAddr != "" {
+		c.RateLimitD.GRPC.Address = *grpcAddr
+	}
+	if *debugAddr != "" {
+		c.RateLimitD.DebugAddr = *debugAddr
+	}
+
+	scope, logger, oTelShutdown := cmd.StatsAndLogging(c.RateLimitD.Syslog, c.RateLimitD.OpenTelemetry, c.RateLimitD.DebugAddr)
+	defer oTelShutdown(context.Background())
+	logger.Info(cmd.VersionString())
+	clk := cmd.Clock()
+
+	limiterRedis, err := bredis.NewRingFromConfig(*c.RateLimitD.Redis, scope, logger)
+	cmd.FailOnError(err, "Failed to create Redis ring")
+	defer limiterRedis.StopLookups()
+
+	limiter, err := ratelimits.NewLimiter(clk, ratelimits.NewRedisSource(limiterRedis.Ring, clk, scope), scope)
+	cmd.FailOnError(err, "Failed to create rate limiter")
+	if c.RateLimitD.LogDenials {
+		limiter.SetDenialHook(ratelimits.LogDenials(logger))
+	}
+	txnBuilder, err := ratelimits.NewTransactionBuilder(c.RateLimitD.Defaults, c.RateLimitD.Environment, c.RateLimitD.Overrides, c.RateLimitD.Exemptions)
+	cmd.FailOnError(err, "Failed to create rate limits transaction builder")
+	cmd.HandleDebug("/debug/ratelimits", ratelimits.NewDebugHandler(limiter, txnBuilder))
+	cmd.HandleDebug("/debug/ratelimits/top", ratelimits.NewTopDeniedHandler(limiter))
+	cmd.HandleDebug("/debug/ratelimits/report", ratelimits.NewUtilizationReportHandler(limiter))
+
+	tlsConfig, err := c.RateLimitD.TLS.Load(scope)
+	cmd.FailOnError(err, "TLS config")
+
+	if c.RateLimitD.AdminAddr != "" {
+		if c.RateLimitD.AdminToken.PasswordFile == "" {
+			cmd.Fail("'adminToken' is required when 'adminAddr' is set")
+		}
+		token, err := c.RateLimitD.AdminToken.Pass()
+		cmd.FailOnError(err, "Failed to load 'adminToken'")
+		adminHandler, err := ratelimits.NewAdminHandler(limiter, txnBuilder, token, logger)
+		cmd.FailOnError(err, "Failed to create admin handler")
+		adminServer := &http.Server{
+			Addr:              c.RateLimitD.AdminAddr,
+			Handler:           adminHandler,
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			err := adminServer.ListenAndServeTLS("", "")
+			cmd.FailOnError(err, "ratelimitd admin server failed")
+		}()
+	}
+
+	start, err := bgrpc.NewServer(c.RateLimitD.GRPC, logger).WithCheckInterval(c.RateLimitD.HealthCheckInterval.Duration).Add(
+		&rlpb.RateLimits_ServiceDesc, ratelimits.NewServer(limiter, txnBuilder)).Build(tlsConfig, scope, clk)
+	cmd.FailOnError(err, "Unable to setup ratelimitd gRPC server")
+
+	cmd.FailOnError(start(), "ratelimitd gRPC service failed")
+}
+
+func init() {
+	cmd.RegisterCommand("ratelimitd", main, &cmd.ConfigValidator{Config: &Config{}})
+}
@@ -0,0 +1,156 @@
+// Analyze historical request arrivals against key-value rate limits. Arrivals
+// are read from a file, or stdin, containing one RFC 3339 timestamp per line,
+// or, for simulate, one JSON new-order request per line.
+
+package notmain
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/ratelimits"
+)
+
+func init() {
+	cmd.RegisterCommand("ratelimits-analyzer", main, nil)
+}
+
+const usage = `Usage: %s <subcommand> [flags]
+
+Subcommands:
+  suggest-override
+    Recommend an override for a single bucket, printed as a ready-to-merge
+    stanza for the overrides file, which would have allowed the given quantile
+    of historical traffic for that bucket.
+
+  evaluate-defaults
+    Replay historical traffic for many buckets against a list of candidate
+    default limits and report the denial rate each would have produced.
+
+  simulate
+    Replay a log of new-order requests against a candidate limits config and
+    report the requests each limit would have denied.
+
+Use <subcommand> --help to see the flags for a specific subcommand.
+`
+
+func helpExit() {
+	fmt.Fprintf(os.Stderr, usage, "ratelimits-analyzer")
+	os.Exit(1)
+}
+
+// readArrivals parses arrivals from the file at path, or stdin if path is
+// empty.
+func readArrivals(path string) ([]time.Time, error) {
+	var input io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		input = f
+	}
+	arrivals, err := ratelimits.ParseArrivals(input)
+	if err != nil {
+		return nil, fmt.Errorf("reading arrivals: %w", err)
+	}
+	return arrivals, nil
+}
+
+func suggestOverride(args []string) {
+	fs := flag.NewFlagSet("suggest-override", flag.ExitOnError)
+	defaults := fs.String("defaults", "", "Path to YAML file containing default limits (required).")
+	name := fs.String("name", "", "Name of the limit, e.g. NewOrdersPerAccount (required).")
+	id := fs.String("id", "", "Id of the bucket, formatted as it would be in an overrides file (required).")
+	input := fs.String("input", "", "File containing request timestamps, newline separated. Defaults to stdin.")
+	q := fs.Float64("quantile", 0.99, "Quantile of historical traffic, per period, the override should allow.")
+	_ = fs.Parse(args)
+
+	if *defaults == "" || *name == "" || *id == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	arrivals, err := readArrivals(*input)
+	cmd.FailOnError(err, "Failed to read arrivals")
+
+	stanza, err := ratelimits.SuggestOverride(*defaults, *name, *id, arrivals, *q)
+	cmd.FailOnError(err, "Failed to suggest override")
+	fmt.Print(stanza)
+}
+
+func evaluateDefaults(args []string) {
+	fs := flag.NewFlagSet("evaluate-defaults", flag.ExitOnError)
+	candidates := fs.String("candidates", "", "Path to YAML file containing a list of candidate limits (required).")
+	input := fs.String("input", "", "File containing 'id timestamp' pairs, newline separated. Defaults to stdin.")
+	_ = fs.Parse(args)
+
+	if *candidates == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		cmd.FailOnError(err, "Failed to open input")
+		defer f.Close()
+		r = f
+	}
+	arrivals, err := ratelimits.ParseBucketArrivals(r)
+	cmd.FailOnError(err, "Failed to read arrivals")
+
+	err = ratelimits.EvaluateCandidates(*candidates, arrivals, os.Stdout)
+	cmd.FailOnError(err, "Failed to evaluate candidates")
+}
+
+func simulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	defaults := fs.String("defaults", "", "Path to YAML file containing default limits (required).")
+	environment := fs.String("environment", "", "Environment section of the defaults file to apply (optional).")
+	overrides := fs.String("overrides", "", "Path to YAML file containing override limits (optional).")
+	exemptions := fs.String("exemptions", "", "Path to YAML file containing limit exemptions (optional).")
+	input := fs.String("input", "", `File containing JSON requests, e.g. {"timestamp": "2024-01-01T00:00:00Z", "regId": 1, "identifiers": ["example.com"]}, newline separated. Defaults to stdin.`)
+	_ = fs.Parse(args)
+
+	if *defaults == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	builder, err := ratelimits.NewTransactionBuilder(*defaults, *environment, *overrides, *exemptions)
+	cmd.FailOnError(err, "Failed to load limits")
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		cmd.FailOnError(err, "Failed to open input")
+		defer f.Close()
+		r = f
+	}
+	requests, err := ratelimits.ParseRecordedRequests(r)
+	cmd.FailOnError(err, "Failed to read requests")
+
+	report, err := ratelimits.SimulateNewOrders(builder, requests)
+	cmd.FailOnError(err, "Failed to simulate requests")
+	err = ratelimits.WriteSimulationReport(report, os.Stdout)
+	cmd.FailOnError(err, "Failed to write report")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		helpExit()
+	}
+	switch os.Args[1] {
+	case "suggest-override":
+		suggestOverride(os.Args[2:])
+	case "evaluate-defaults":
+		evaluateDefaults(os.Args[2:])
+	case "simulate":
+		simulate(os.Args[2:])
+	default:
+		helpExit()
+	}
+}
@@ -0,0 +1,102 @@
+package notmain
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/letsencrypt/boulder/cmd"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/ratelimits"
+	"github.com/letsencrypt/boulder/ratelimits/envoy"
+	bredis "github.com/letsencrypt/boulder/redis"
+)
+
+type Config struct {
+	RatelimitsEnvoy struct {
+		// ServiceConfig configures the gRPC server on which the Envoy rate
+		// limit service API is served. Clients, such as an edge proxy, must
+		// present a certificate issued by CACertFile with a SAN listed in the
+		// clientNames of the envoy.service.ratelimit.v3.RateLimitService
+		// service.
+		cmd.ServiceConfig
+
+		// Redis contains the configuration necessary to connect to the Redis
+		// which stores the buckets of the Limiter. It should be the same as
+		// the Redis used by the WFE, so that both consult the same buckets.
+		Redis *bredis.Config `validate:"required"`
+
+		// Defaults, Environment, Overrides, and Exemptions are as described
+		// for the WFE's Limiter configuration. See: ratelimits/README.md for
+		// details.
+		Defaults    string `validate:"required"`
+		Environment string
+		Overrides   string
+		Exemptions  string
+
+		// Domain, if set, is the only rate limit domain accepted in requests.
+		Domain string
+
+		// Descriptors maps descriptor entry keys to the names of the limits
+		// they select, e.g. "remote_address" to
+		// "NewRegistrationsPerIPAddress". The value of the entry is the id of
+		// the bucket, formatted as it would be in an overrides file.
+		Descriptors map[string]string `validate:"required,min=1"`
+
+		Syslog        cmd.SyslogConfig
+		OpenTelemetry cmd.OpenTelemetryConfig
+	}
+}
+
+func main() {
+	grpcAddr := flag.String("addr", "", "gRPC listen address override")
+	debugAddr := flag.String("debug-addr", "", "Debug server address override")
+	configFile := flag.String("config", "", "File path to the configuration file for this service")
+	flag.Parse()
+
+	if *configFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var c Config
+	err := cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+
+	if *grpcAddr != "" {
+		c.RatelimitsEnvoy.GRPC.Address = *grpcAddr
+	}
+	if *debugAddr != "" {
+		c.RatelimitsEnvoy.DebugAddr = *debugAddr
+	}
+
+	stats, logger, oTelShutdown := cmd.StatsAndLogging(c.RatelimitsEnvoy.Syslog, c.RatelimitsEnvoy.OpenTelemetry, c.RatelimitsEnvoy.DebugAddr)
+	defer oTelShutdown(context.Background())
+	logger.Info(cmd.VersionString())
+	clk := cmd.Clock()
+
+	limiterRedis, err := bredis.NewRingFromConfig(*c.RatelimitsEnvoy.Redis, stats, logger)
+	cmd.FailOnError(err, "Failed to create Redis ring")
+	defer limiterRedis.StopLookups()
+
+	limiter, err := ratelimits.NewLimiter(clk, ratelimits.NewRedisSource(limiterRedis.Ring, clk, stats), stats)
+	cmd.FailOnError(err, "Failed to create rate limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(c.RatelimitsEnvoy.Defaults, c.RatelimitsEnvoy.Environment, c.RatelimitsEnvoy.Overrides, c.RatelimitsEnvoy.Exemptions)
+	cmd.FailOnError(err, "Failed to create rate limits transaction builder")
+
+	envoyServer, err := envoy.NewServer(limiter, txnBuilder, c.RatelimitsEnvoy.Domain, c.RatelimitsEnvoy.Descriptors)
+	cmd.FailOnError(err, "Failed to create Envoy rate limit service")
+
+	tlsConfig, err := c.RatelimitsEnvoy.TLS.Load(stats)
+	cmd.FailOnError(err, "TLS config")
+
+	start, err := bgrpc.NewServer(c.RatelimitsEnvoy.GRPC, logger).WithCheckInterval(c.RatelimitsEnvoy.HealthCheckInterval.Duration).WithCodec(envoy.Codec{}).Add(
+		&envoy.ServiceDesc, envoyServer).Build(tlsConfig, stats, clk)
+	cmd.FailOnError(err, "Unable to setup Envoy rate limit service gRPC server")
+
+	cmd.FailOnError(start(), "Envoy rate limit service failed")
+}
+
+func init() {
+	cmd.RegisterCommand("ratelimits-envoy", main, &cmd.ConfigValidator{Config: &Config{}})
+}
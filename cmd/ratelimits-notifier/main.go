@@ -0,0 +1,545 @@
+// Periodically find accounts which have used most of the capacity of a key-value
+// rate limit, whether the default or an override, and email their contacts
+// once a day, so that subscribers can adjust their clients, or request an
+// override, before they're denied.
+
+package notmain
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	netmail "net/mail"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/config"
+	"github.com/letsencrypt/boulder/db"
+	blog "github.com/letsencrypt/boulder/log"
+	bmail "github.com/letsencrypt/boulder/mail"
+	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/ratelimits"
+	bredis "github.com/letsencrypt/boulder/redis"
+	"github.com/letsencrypt/boulder/sa"
+)
+
+const (
+	defaultSubject        = "Your ACME account is approaching its rate limits"
+	defaultThreshold      = 0.8
+	defaultSampleInterval = 15 * time.Minute
+	defaultNotifyInterval = 24 * time.Hour
+
+	// stateKey is the Redis key under which the notifierState is stored.
+	stateKey = "ratelimits-notifier:state"
+)
+
+type Config struct {
+	RatelimitsNotifier struct {
+		DebugAddr string `validate:"omitempty,hostname_port"`
+
+		// DB is the database holding the registrations table, from which the
+		// contacts of each account are read.
+		DB cmd.DBConfig
+		cmd.SMTPConfig
+
+		// Redis contains the configuration necessary to connect to the Redis
+		// which stores the buckets. The notifier also stores its own state
+		// there, so that peaks, and when accounts were last notified, survive
+		// restarts.
+		Redis *bredis.Config `validate:"required"`
+
+		// Defaults, Environment, Overrides, and Exemptions are as described
+		// for the WFE's Limiter configuration. See: ratelimits/README.md for
+		// details.
+		Defaults    string `validate:"required"`
+		Environment string
+		Overrides   string
+		Exemptions  string
+
+		// From is an RFC 5322 formatted "From" address for notifications,
+		// e.g. "Example <example@test.org>".
+		From string `validate:"required"`
+
+		// Subject is the Subject line of notifications. Defaults to
+		// "Your ACME account is approaching its rate limits".
+		Subject string
+
+		// EmailTemplate is the path to a text/template email template. See
+		// emailData for the fields it may refer to.
+		EmailTemplate string `validate:"required"`
+
+		// Threshold is the proportion of a limit's burst, between 0 and 1,
+		// which an account must have used for its contacts to be notified.
+		// Defaults to 0.8.
+		Threshold float64 `validate:"omitempty,gt=0,lte=1"`
+
+		// SampleInterval is how often buckets are scanned for utilization
+		// above the threshold. Since utilization falls as buckets refill,
+		// peaks shorter than this may be missed. Defaults to 15m.
+		SampleInterval config.Duration `validate:"-"`
+
+		// NotifyInterval is how often each account whose utilization was
+		// above the threshold since the last notifications is notified.
+		// Whether notifications are due is checked after each sample, so they
+		// may be sent up to SampleInterval late. Defaults to 24h.
+		NotifyInterval config.Duration `validate:"-"`
+
+		// Path to a file containing a list of trusted root certificates for use
+		// during the SMTP connection.
+		SMTPTrustedRootFile string
+	}
+
+	Syslog        cmd.SyslogConfig
+	OpenTelemetry cmd.OpenTelemetryConfig
+}
+
+// peak is the highest utilization of an account's buckets of a single limit
+// since the last notifications.
+type peak struct {
+	Limit       ratelimits.Name
+	Override    bool
+	Burst       int64
+	Utilization float64
+}
+
+// limitData describes a single limit in a notification.
+type limitData struct {
+	// Name is the name of the limit, e.g. NewOrdersPerAccount.
+	Name string
+
+	// Percent is the peak utilization of the limit, as a whole percentage.
+	Percent int
+
+	// Burst is the capacity of the limit, which is overridden if Override is
+	// true.
+	Burst    int64
+	Override bool
+}
+
+// emailData is the data available to the email template.
+type emailData struct {
+	RegID  int64
+	Limits []limitData
+}
+
+// notifierState is the state of the notifier which is persisted, so that it
+// survives restarts.
+type notifierState struct {
+	// LastNotified is when the last notifications were sent.
+	LastNotified time.Time
+
+	// Peaks maps the registration ID of each account with utilization above
+	// the threshold since the last notifications to its peak for each limit.
+	Peaks map[int64]map[ratelimits.Name]peak
+}
+
+// stateStore persists the notifierState.
+type stateStore interface {
+	// load returns the stored state, or nil if none has been stored.
+	load(ctx context.Context) (*notifierState, error)
+	save(ctx context.Context, state notifierState) error
+}
+
+// redisStateStore stores the notifierState as JSON under a single key.
+type redisStateStore struct {
+	client *redis.Ring
+	key    string
+}
+
+func (s redisStateStore) load(ctx context.Context) (*notifierState, error) {
+	b, err := s.client.Get(ctx, s.key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var state notifierState
+	err = json.Unmarshal(b, &state)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (s redisStateStore) save(ctx context.Context, state notifierState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.key, b, 0).Err()
+}
+
+type notifier struct {
+	log             blog.Logger
+	clk             clock.Clock
+	limiter         *ratelimits.Limiter
+	txnBuilder      *ratelimits.TransactionBuilder
+	dbMap           dbSelector
+	mailer          bmail.Mailer
+	subject         string
+	emailTemplate   *template.Template
+	threshold       float64
+	notifyInterval  time.Duration
+	store           stateStore
+	notifications   *prometheus.CounterVec
+	highUtilization prometheus.Gauge
+
+	sync.Mutex
+	// lastNotified is when the last notifications were sent.
+	lastNotified time.Time
+	// peaks maps the registration ID of each account with utilization above
+	// the threshold since the last notifications to its peak for each limit.
+	peaks map[int64]map[ratelimits.Name]peak
+}
+
+func newNotifier(log blog.Logger, clk clock.Clock, stats prometheus.Registerer, limiter *ratelimits.Limiter, txnBuilder *ratelimits.TransactionBuilder, dbMap dbSelector, mailer bmail.Mailer, subject string, emailTemplate *template.Template, threshold float64, notifyInterval time.Duration, store stateStore) *notifier {
+	notifications := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_notifications",
+		Help: "A counter of rate limit utilization notifications, labelled by result",
+	}, []string{"result"})
+	stats.MustRegister(notifications)
+	highUtilization := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "ratelimits_notifier_accounts",
+		Help: "The number of accounts with utilization above the threshold since the last notifications",
+	})
+	stats.MustRegister(highUtilization)
+
+	return &notifier{
+		log:             log,
+		clk:             clk,
+		limiter:         limiter,
+		txnBuilder:      txnBuilder,
+		dbMap:           dbMap,
+		mailer:          mailer,
+		subject:         subject,
+		emailTemplate:   emailTemplate,
+		threshold:       threshold,
+		notifyInterval:  notifyInterval,
+		store:           store,
+		notifications:   notifications,
+		highUtilization: highUtilization,
+		peaks:           make(map[int64]map[ratelimits.Name]peak),
+	}
+}
+
+// restore loads the persisted state of the notifier. If none was persisted,
+// the notifier starts afresh, as if it had just sent notifications.
+func (n *notifier) restore(ctx context.Context) error {
+	state, err := n.store.load(ctx)
+	if err != nil {
+		return fmt.Errorf("loading notifier state: %w", err)
+	}
+	n.Lock()
+	defer n.Unlock()
+	if state == nil {
+		n.lastNotified = n.clk.Now()
+		return nil
+	}
+	n.lastNotified = state.LastNotified
+	if state.Peaks != nil {
+		n.peaks = state.Peaks
+	}
+	n.highUtilization.Set(float64(len(n.peaks)))
+	return nil
+}
+
+// persist saves the current state of the notifier.
+func (n *notifier) persist(ctx context.Context) error {
+	n.Lock()
+	defer n.Unlock()
+	return n.store.save(ctx, notifierState{LastNotified: n.lastNotified, Peaks: n.peaks})
+}
+
+// tick samples utilization, then sends notifications if at least a
+// notifyInterval has passed since the last notifications.
+func (n *notifier) tick(ctx context.Context) error {
+	err := n.sample(ctx)
+	if err != nil {
+		return fmt.Errorf("sampling rate limit utilization: %w", err)
+	}
+	n.Lock()
+	due := n.clk.Since(n.lastNotified) >= n.notifyInterval
+	n.Unlock()
+	if !due {
+		err = n.persist(ctx)
+		if err != nil {
+			return fmt.Errorf("saving notifier state: %w", err)
+		}
+		return nil
+	}
+	err = n.notify(ctx)
+	if err != nil {
+		return fmt.Errorf("sending rate limit utilization notifications: %w", err)
+	}
+	return nil
+}
+
+// sample scans every bucket of each limit keyed by account, recording the
+// utilization of those above the threshold if it's the highest seen for the
+// account and limit since the last notifications.
+func (n *notifier) sample(ctx context.Context) error {
+	return n.limiter.ScanAccountUtilization(ctx, n.txnBuilder, n.threshold, func(u ratelimits.AccountUtilization) {
+		n.Lock()
+		defer n.Unlock()
+		limits, ok := n.peaks[u.RegID]
+		if !ok {
+			limits = make(map[ratelimits.Name]peak)
+			n.peaks[u.RegID] = limits
+		}
+		if u.Utilization > limits[u.Limit].Utilization {
+			limits[u.Limit] = peak{
+				Limit:       u.Limit,
+				Override:    u.Override,
+				Burst:       u.Burst,
+				Utilization: u.Utilization,
+			}
+		}
+		n.highUtilization.Set(float64(len(n.peaks)))
+	})
+}
+
+// notify emails the contacts of each account with utilization above the
+// threshold since the last notifications, and forgets their peaks. Accounts
+// without a valid email contact are skipped. Failing to notify one account
+// doesn't prevent the others from being notified; it isn't retried. The
+// forgotten peaks are persisted before any email is sent, so that an account
+// isn't notified twice about the same peaks if the notifier restarts.
+func (n *notifier) notify(ctx context.Context) error {
+	n.Lock()
+	peaks := n.peaks
+	n.peaks = make(map[int64]map[ratelimits.Name]peak)
+	n.lastNotified = n.clk.Now()
+	n.highUtilization.Set(0)
+	n.Unlock()
+	err := n.persist(ctx)
+	if err != nil {
+		return fmt.Errorf("saving notifier state: %w", err)
+	}
+	if len(peaks) == 0 {
+		return nil
+	}
+
+	regIDs := make([]int64, 0, len(peaks))
+	for regID := range peaks {
+		regIDs = append(regIDs, regID)
+	}
+	sort.Slice(regIDs, func(i, j int) bool { return regIDs[i] < regIDs[j] })
+
+	conn, err := n.mailer.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to the mail server: %w", err)
+	}
+	defer conn.Close()
+
+	for _, regID := range regIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		result, err := n.notifyOne(ctx, conn, regID, peaks[regID])
+		if err != nil {
+			n.log.Errf("notifying registration ID %d: %s", regID, err)
+		}
+		n.notifications.WithLabelValues(result).Inc()
+	}
+	return nil
+}
+
+// notifyOne emails the contacts of a single account about its peaks, returning
+// the result with which to label the notifications metric.
+func (n *notifier) notifyOne(ctx context.Context, conn bmail.Conn, regID int64, peaks map[ratelimits.Name]peak) (string, error) {
+	addresses, err := getAddressesForID(ctx, regID, n.dbMap)
+	if err != nil {
+		return "error", err
+	}
+	if len(addresses) == 0 {
+		return "no_contact", nil
+	}
+
+	data := emailData{RegID: regID}
+	for _, p := range peaks {
+		data.Limits = append(data.Limits, limitData{
+			Name:     p.Limit.String(),
+			Percent:  int(p.Utilization * 100),
+			Burst:    p.Burst,
+			Override: p.Override,
+		})
+	}
+	sort.Slice(data.Limits, func(i, j int) bool { return data.Limits[i].Name < data.Limits[j].Name })
+
+	body := new(bytes.Buffer)
+	err = n.emailTemplate.Execute(body, data)
+	if err != nil {
+		return "error", err
+	}
+	err = conn.SendMail(addresses, n.subject, body.String())
+	if err != nil {
+		return "error", err
+	}
+
+	logItem, err := json.Marshal(data)
+	if err != nil {
+		return "error", err
+	}
+	n.log.Infof("sent rate limit utilization notification JSON=%s", logItem)
+	return "sent", nil
+}
+
+// dbSelector abstracts over a subset of methods from `borp.DbMap` objects to
+// facilitate mocking in unit tests.
+type dbSelector interface {
+	SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error
+}
+
+// getAddressesForID queries the database for the valid email addresses of the
+// provided registration ID.
+func getAddressesForID(ctx context.Context, id int64, dbMap dbSelector) ([]string, error) {
+	var contact []byte
+	err := dbMap.SelectOne(ctx, &contact,
+		`SELECT contact
+		FROM registrations
+		WHERE contact NOT IN ('[]', 'null')
+			AND id = ?`,
+		id)
+	if err != nil {
+		if db.IsNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var contacts []string
+	err = json.Unmarshal(contact, &contacts)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	for _, contact := range contacts {
+		address, ok := strings.CutPrefix(contact, "mailto:")
+		if !ok || policy.ValidEmail(address) != nil {
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+func main() {
+	debugAddr := flag.String("debug-addr", "", "Debug server address override")
+	configFile := flag.String("config", "", "File path to the configuration file for this service")
+	reconnBase := flag.Duration("reconnectBase", 1*time.Second, "Base sleep duration between reconnect attempts")
+	reconnMax := flag.Duration("reconnectMax", 5*60*time.Second, "Max sleep duration between reconnect attempts after exponential backoff")
+	flag.Parse()
+	if *configFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var c Config
+	err := cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+	conf := c.RatelimitsNotifier
+
+	if *debugAddr != "" {
+		conf.DebugAddr = *debugAddr
+	}
+	if conf.Subject == "" {
+		conf.Subject = defaultSubject
+	}
+	if conf.Threshold == 0 {
+		conf.Threshold = defaultThreshold
+	}
+	if conf.SampleInterval.Duration <= 0 {
+		conf.SampleInterval.Duration = defaultSampleInterval
+	}
+	if conf.NotifyInterval.Duration <= 0 {
+		conf.NotifyInterval.Duration = defaultNotifyInterval
+	}
+
+	scope, logger, oTelShutdown := cmd.StatsAndLogging(c.Syslog, c.OpenTelemetry, conf.DebugAddr)
+	defer oTelShutdown(context.Background())
+	logger.Info(cmd.VersionString())
+	clk := cmd.Clock()
+
+	dbMap, err := sa.InitWrappedDb(conf.DB, scope, logger)
+	cmd.FailOnError(err, "While initializing dbMap")
+
+	limiterRedis, err := bredis.NewRingFromConfig(*conf.Redis, scope, logger)
+	cmd.FailOnError(err, "Failed to create Redis ring")
+	defer limiterRedis.StopLookups()
+	limiter, err := ratelimits.NewLimiter(clk, ratelimits.NewRedisSource(limiterRedis.Ring, clk, scope), scope)
+	cmd.FailOnError(err, "Failed to create rate limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(conf.Defaults, conf.Environment, conf.Overrides, conf.Exemptions)
+	cmd.FailOnError(err, "Failed to create rate limits transaction builder")
+
+	emailTmpl, err := os.ReadFile(conf.EmailTemplate)
+	cmd.FailOnError(err, fmt.Sprintf("Could not read email template file [%s]", conf.EmailTemplate))
+	tmpl, err := template.New("ratelimits-email").Parse(string(emailTmpl))
+	cmd.FailOnError(err, "Could not parse email template")
+
+	var smtpRoots *x509.CertPool
+	if conf.SMTPTrustedRootFile != "" {
+		pem, err := os.ReadFile(conf.SMTPTrustedRootFile)
+		cmd.FailOnError(err, "Loading trusted roots file")
+		smtpRoots = x509.NewCertPool()
+		if !smtpRoots.AppendCertsFromPEM(pem) {
+			cmd.Fail("Failed to parse root certs PEM")
+		}
+	}
+	fromAddress, err := netmail.ParseAddress(conf.From)
+	cmd.FailOnError(err, fmt.Sprintf("Could not parse from address: %s", conf.From))
+	smtpPassword, err := conf.PasswordConfig.Pass()
+	cmd.FailOnError(err, "Failed to load SMTP password")
+	mailClient := bmail.New(
+		conf.Server,
+		conf.Port,
+		conf.Username,
+		smtpPassword,
+		smtpRoots,
+		*fromAddress,
+		logger,
+		scope,
+		*reconnBase,
+		*reconnMax)
+
+	store := redisStateStore{client: limiterRedis.Ring, key: stateKey}
+	n := newNotifier(logger, clk, scope, limiter, txnBuilder, dbMap, mailClient, conf.Subject, tmpl, conf.Threshold, conf.NotifyInterval.Duration, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go cmd.CatchSignals(cancel)
+
+	err = n.restore(ctx)
+	cmd.FailOnError(err, "Failed to restore notifier state")
+
+	sampleTicker := time.NewTicker(conf.SampleInterval.Duration)
+	defer sampleTicker.Stop()
+	for {
+		select {
+		case <-sampleTicker.C:
+			err := n.tick(ctx)
+			if err != nil {
+				logger.Errf("%s", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func init() {
+	cmd.RegisterCommand("ratelimits-notifier", main, &cmd.ConfigValidator{Config: &Config{}})
+}
@@ -0,0 +1,189 @@
+package notmain
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/mocks"
+	"github.com/letsencrypt/boulder/ratelimits"
+	"github.com/letsencrypt/boulder/test"
+)
+
+const testDefaults = `NewOrdersPerAccount:
+  burst: 20
+  count: 20
+  period: 1h
+`
+
+// mockContactResolver implements dbSelector, treating the requested
+// registration ID as a key into contacts.
+type mockContactResolver struct {
+	contacts map[int64]string
+}
+
+func (m mockContactResolver) SelectOne(_ context.Context, output interface{}, _ string, args ...interface{}) error {
+	contact, ok := m.contacts[args[0].(int64)]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	*output.(*[]byte) = []byte(contact)
+	return nil
+}
+
+// memStateStore implements stateStore in memory.
+type memStateStore struct {
+	state *notifierState
+}
+
+func (m *memStateStore) load(context.Context) (*notifierState, error) {
+	if m.state == nil {
+		return nil, nil
+	}
+	// Round trip through JSON, like redisStateStore.
+	b, err := json.Marshal(m.state)
+	if err != nil {
+		return nil, err
+	}
+	var state notifierState
+	err = json.Unmarshal(b, &state)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (m *memStateStore) save(_ context.Context, state notifierState) error {
+	m.state = &state
+	return nil
+}
+
+func setup(t *testing.T) (*notifier, *mocks.Mailer) {
+	t.Helper()
+	defaults := filepath.Join(t.TempDir(), "defaults.yml")
+	err := os.WriteFile(defaults, []byte(testDefaults), 0600)
+	test.AssertNotError(t, err, "writing defaults")
+
+	clk := clock.NewFake()
+	limiter, err := ratelimits.NewLimiter(clk, ratelimits.NewInmemSource(), metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "creating limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(defaults, "", "", "")
+	test.AssertNotError(t, err, "creating transaction builder")
+
+	tmpl, err := template.New("email").Parse(`{{range .Limits}}{{.Name}} {{.Percent}}% of {{.Burst}}{{end}}`)
+	test.AssertNotError(t, err, "parsing template")
+
+	mailer := &mocks.Mailer{}
+	dbMap := mockContactResolver{contacts: map[int64]string{
+		1: `["mailto:one@letsencrypt.org", "tel:+15555555555", "mailto:invalid"]`,
+	}}
+	n := newNotifier(blog.NewMock(), clk, metrics.NoopRegisterer, limiter, txnBuilder, dbMap, mailer, "subject", tmpl, 0.8, 24*time.Hour, &memStateStore{})
+	err = n.restore(context.Background())
+	test.AssertNotError(t, err, "restoring state")
+	return n, mailer
+}
+
+func TestNotify(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	n, mailer := setup(t)
+
+	spend := func(regID int64, times int) {
+		t.Helper()
+		txn, err := n.txnBuilder.OrdersPerAccountTransaction(regID)
+		test.AssertNotError(t, err, "txn should be valid")
+		for i := 0; i < times; i++ {
+			_, err = n.limiter.Spend(ctx, txn)
+			test.AssertNotError(t, err, "should not error")
+		}
+	}
+
+	// Account 1 has a valid contact, account 2 has none, and account 3 is
+	// below the threshold.
+	spend(1, 18)
+	spend(2, 20)
+	spend(3, 10)
+
+	err := n.sample(ctx)
+	test.AssertNotError(t, err, "sampling")
+	test.AssertEquals(t, len(n.peaks), 2)
+	test.AssertEquals(t, n.peaks[1][ratelimits.NewOrdersPerAccount].Utilization, 0.9)
+
+	// Peaks are kept as buckets refill.
+	n.clk.(clock.FakeClock).Add(time.Hour)
+	err = n.sample(ctx)
+	test.AssertNotError(t, err, "sampling")
+	test.AssertEquals(t, len(n.peaks), 2)
+
+	err = n.notify(ctx)
+	test.AssertNotError(t, err, "notifying")
+	test.AssertEquals(t, len(mailer.Messages), 1)
+	test.AssertDeepEquals(t, mailer.Messages[0], mocks.MailerMessage{
+		To:      "one@letsencrypt.org",
+		Subject: "subject",
+		Body:    "NewOrdersPerAccount 90% of 20",
+	})
+	test.AssertMetricWithLabelsEquals(t, n.notifications, map[string]string{"result": "sent"}, 1)
+	test.AssertMetricWithLabelsEquals(t, n.notifications, map[string]string{"result": "no_contact"}, 1)
+
+	// Peaks are forgotten once notified.
+	test.AssertEquals(t, len(n.peaks), 0)
+	mailer.Clear()
+	err = n.notify(ctx)
+	test.AssertNotError(t, err, "notifying")
+	test.AssertEquals(t, len(mailer.Messages), 0)
+}
+
+func TestNotifyAfterRestart(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	n, mailer := setup(t)
+
+	txn, err := n.txnBuilder.OrdersPerAccountTransaction(1)
+	test.AssertNotError(t, err, "txn should be valid")
+	for i := 0; i < 18; i++ {
+		_, err = n.limiter.Spend(ctx, txn)
+		test.AssertNotError(t, err, "should not error")
+	}
+
+	// Notifications aren't due until a notifyInterval after the notifier
+	// first started.
+	err = n.tick(ctx)
+	test.AssertNotError(t, err, "ticking")
+	test.AssertEquals(t, len(mailer.Messages), 0)
+
+	// A notifier restarted with the same store remembers the peaks, and when
+	// the last notifications were sent.
+	restarted := newNotifier(blog.NewMock(), n.clk, metrics.NoopRegisterer, n.limiter, n.txnBuilder, n.dbMap, mailer, "subject", n.emailTemplate, 0.8, 24*time.Hour, n.store)
+	err = restarted.restore(ctx)
+	test.AssertNotError(t, err, "restoring state")
+	test.AssertEquals(t, len(restarted.peaks), 1)
+	test.AssertEquals(t, restarted.lastNotified, n.lastNotified)
+
+	n.clk.(clock.FakeClock).Add(23 * time.Hour)
+	err = restarted.tick(ctx)
+	test.AssertNotError(t, err, "ticking")
+	test.AssertEquals(t, len(mailer.Messages), 0)
+
+	// The buckets have refilled, but the peak is still notified once due.
+	n.clk.(clock.FakeClock).Add(time.Hour)
+	err = restarted.tick(ctx)
+	test.AssertNotError(t, err, "ticking")
+	test.AssertEquals(t, len(mailer.Messages), 1)
+	test.AssertEquals(t, mailer.Messages[0].Body, "NewOrdersPerAccount 90% of 20")
+
+	// The forgotten peaks were persisted.
+	state, err := n.store.load(ctx)
+	test.AssertNotError(t, err, "loading state")
+	test.AssertEquals(t, len(state.Peaks), 0)
+	test.AssertEquals(t, state.LastNotified, n.clk.Now())
+}
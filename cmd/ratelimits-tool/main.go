@@ -0,0 +1,333 @@
+// Inspect, test, refund, and reset key-value rate limit buckets using the same
+// limits and Redis configuration as the WFE. Intended for incident response
+// and support workflows.
+
+package notmain
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/ratelimits"
+	bredis "github.com/letsencrypt/boulder/redis"
+	"github.com/letsencrypt/boulder/sa"
+)
+
+type Config struct {
+	RatelimitsTool struct {
+		// Redis contains the configuration necessary to connect to the Redis
+		// which stores the buckets.
+		Redis *bredis.Config `validate:"required"`
+
+		// Defaults, Environment, Overrides, and Exemptions are as described
+		// for the WFE's Limiter configuration. See: ratelimits/README.md for
+		// details.
+		Defaults    string `validate:"required"`
+		Environment string
+		Overrides   string
+		Exemptions  string
+
+		// DB, if set, is the database holding the legacy certificatesPerName
+		// and newOrdersRL tables. It is only required by the backfill
+		// subcommand.
+		DB *cmd.DBConfig
+	}
+	Syslog cmd.SyslogConfig
+}
+
+func init() {
+	cmd.RegisterCommand("ratelimits-tool", main, &cmd.ConfigValidator{Config: &Config{}})
+}
+
+const usage = `Usage: %s <subcommand> -config <path> -name <limit> -id <id> [flags]
+
+Subcommands:
+  inspect
+    Print the effective limit and the current state of the bucket.
+
+  check
+    Print the decision which spending -cost from the bucket would produce,
+    without spending it.
+
+  spend
+    Spend -cost from the bucket and print the decision.
+
+  refund
+    Refund -cost to the bucket and print the decision.
+
+  reset
+    Reset the bucket to full capacity.
+
+  reset-prefix
+    Reset every bucket of the limit whose id is -id, or begins with -id and a
+    ':', to full capacity. If -id is not set, or ends with a ':', every bucket
+    of the limit whose id begins with it is reset. Progress is printed to stderr
+    after each batch.
+
+  export
+    Write a snapshot of every bucket of the limit whose id begins with -id to
+    -file. If -name is not set, every bucket is exported.
+
+  import
+    Store every bucket in the snapshot at -file. Existing buckets with the same
+    keys are overwritten.
+
+  preflight
+    Print the cost and current remaining capacity of every bucket which a new
+    order for the regId -id and the comma-separated -domains would be subject
+    to, without spending from them.
+
+  backfill
+    Record, in each bucket of the -name limit, either CertificatesPerDomain or
+    NewOrdersPerAccount, the usage counted over the last -window by the legacy
+    certificatesPerName or newOrdersRL table, unless the bucket already
+    reflects as much. Intended to be run once the RA's Limiter has run in
+    shadow mode, and before the UseKvLimitsForNewOrder feature is enabled.
+
+  loadtest
+    Drive the -mix of operations from -concurrency workers against the Redis
+    ring, until -requests have been made or -duration has elapsed, and print
+    latency percentiles and error rates for each operation. Buckets are keyed
+    by the enum of the -name limit with synthetic ids, and are reset afterwards.
+
+If -renewal is set, check, spend, and preflight treat the request as a renewal
+of an existing certificate, charging the renewalCost of each limit which
+configures one.
+
+The -id is formatted as it would be in an overrides file, e.g. an IP address
+for NewRegistrationsPerIPAddress or a regId for NewOrdersPerAccount. For
+reset-prefix it is matched against whole ':'-separated segments of the id
+portion of each bucket key, e.g. '1234' matches every per-domain bucket of
+CertificatesPerDomainPerAccount for regId 1234, but none of those for regId
+12345.
+`
+
+func helpExit() {
+	fmt.Fprintf(os.Stderr, usage, "ratelimits-tool")
+	os.Exit(1)
+}
+
+func printJSON(v any) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	cmd.FailOnError(err, "Failed to marshal output")
+	fmt.Println(string(out))
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		helpExit()
+	}
+	subcommand := os.Args[1]
+	switch subcommand {
+	case "inspect", "check", "spend", "refund", "reset", "reset-prefix", "export", "import", "preflight", "backfill", "loadtest":
+	default:
+		helpExit()
+	}
+
+	fs := flag.NewFlagSet(subcommand, flag.ExitOnError)
+	configFile := fs.String("config", "", "File path to the configuration file for this tool (required).")
+	name := fs.String("name", "", "Name of the limit, e.g. NewOrdersPerAccount (required except for export and import).")
+	id := fs.String("id", "", "Id of the bucket, formatted as it would be in an overrides file (required except for reset-prefix, export, and import).")
+	cost := fs.Int64("cost", 1, "Cost to check, spend, or refund.")
+	file := fs.String("file", "", "File path of the snapshot to export or import (required for export and import).")
+	domains := fs.String("domains", "", "Comma-separated domain names of the order (required for preflight).")
+	renewal := fs.Bool("renewal", false, "Treat the request as a renewal, charging the renewalCost of each limit (check, spend, and preflight only).")
+	mix := fs.String("mix", "spend=70,check=20,batch-spend=10", "Comma-separated 'operation=weight' pairs, operations are check, spend, batch-check, and batch-spend (loadtest only).")
+	buckets := fs.Int("buckets", 1000, "Number of distinct buckets to spread requests across (loadtest only).")
+	batchSize := fs.Int("batch-size", 5, "Number of transactions in each batch operation (loadtest only).")
+	concurrency := fs.Int("concurrency", 10, "Number of concurrent workers (loadtest only).")
+	requests := fs.Int64("requests", 0, "Total number of requests to make, 0 for no limit (loadtest only).")
+	duration := fs.Duration("duration", time.Minute, "Maximum duration of the load test (loadtest only).")
+	window := fs.Duration("window", 0, "How far back to read legacy counts, e.g. 168h for CertificatesPerDomain (required for backfill).")
+	_ = fs.Parse(os.Args[2:])
+
+	var missing bool
+	switch subcommand {
+	case "export":
+		missing = *file == "" || (*name == "" && *id != "")
+	case "import":
+		missing = *file == ""
+	case "reset-prefix", "loadtest":
+		missing = *name == ""
+	case "backfill":
+		missing = *name == "" || *window <= 0
+	case "preflight":
+		missing = *id == "" || *domains == ""
+	default:
+		missing = *name == "" || *id == ""
+	}
+	if *configFile == "" || missing {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var c Config
+	err := cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+
+	logger := cmd.NewLogger(c.Syslog)
+	clk := cmd.Clock()
+	limiterRedis, err := bredis.NewRingFromConfig(*c.RatelimitsTool.Redis, metrics.NoopRegisterer, logger)
+	cmd.FailOnError(err, "Failed to create Redis ring")
+	defer limiterRedis.StopLookups()
+
+	limiter, err := ratelimits.NewLimiter(clk, ratelimits.NewRedisSource(limiterRedis.Ring, clk, metrics.NoopRegisterer), metrics.NoopRegisterer)
+	cmd.FailOnError(err, "Failed to create rate limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(c.RatelimitsTool.Defaults, c.RatelimitsTool.Environment, c.RatelimitsTool.Overrides, c.RatelimitsTool.Exemptions)
+	cmd.FailOnError(err, "Failed to create rate limits transaction builder")
+
+	ctx := context.Background()
+	switch subcommand {
+	case "export":
+		var prefix string
+		if *name != "" {
+			limitName, err := ratelimits.NameFromString(*name)
+			cmd.FailOnError(err, "Invalid limit name")
+			prefix, err = ratelimits.BucketPrefix(limitName, *id)
+			cmd.FailOnError(err, "Invalid bucket prefix")
+		}
+		f, err := os.Create(*file)
+		cmd.FailOnError(err, "Failed to create snapshot file")
+		exported, err := limiter.ExportBuckets(ctx, prefix, f)
+		cmd.FailOnError(err, "Failed to export buckets")
+		cmd.FailOnError(f.Close(), "Failed to write snapshot file")
+		fmt.Fprintf(os.Stderr, "Exported %d buckets to %s\n", exported, *file)
+		return
+
+	case "import":
+		f, err := os.Open(*file)
+		cmd.FailOnError(err, "Failed to open snapshot file")
+		defer f.Close()
+		imported, err := limiter.ImportBuckets(ctx, f)
+		logger.AuditInfof("Imported %d rate limit buckets from %s", imported, *file)
+		cmd.FailOnError(err, "Failed to import buckets")
+		return
+
+	case "reset-prefix":
+		limitName, err := ratelimits.NameFromString(*name)
+		cmd.FailOnError(err, "Invalid limit name")
+		prefix, err := ratelimits.BucketPrefix(limitName, *id)
+		cmd.FailOnError(err, "Invalid bucket prefix")
+		reset, err := limiter.ResetPrefix(ctx, prefix, func(n int64) {
+			fmt.Fprintf(os.Stderr, "Reset %d buckets\n", n)
+		})
+		// Buckets reset before an error remain reset, so audit log them either
+		// way.
+		logger.AuditInfof("Reset %d rate limit buckets with prefix %q for %s", reset, prefix, *name)
+		cmd.FailOnError(err, "Failed to reset buckets")
+		return
+
+	case "loadtest":
+		limitName, err := ratelimits.NameFromString(*name)
+		cmd.FailOnError(err, "Invalid limit name")
+		opMix, err := ratelimits.ParseLoadTestMix(*mix)
+		cmd.FailOnError(err, "Invalid mix")
+		ctx, cancel := context.WithTimeout(ctx, *duration)
+		defer cancel()
+		report, err := ratelimits.RunLoadTest(ctx, limiter, ratelimits.LoadTestConfig{
+			Name:        limitName,
+			Buckets:     *buckets,
+			BatchSize:   *batchSize,
+			Concurrency: *concurrency,
+			Requests:    *requests,
+			Mix:         opMix,
+		})
+		if report != nil {
+			cmd.FailOnError(ratelimits.WriteLoadTestReport(report, os.Stdout), "Failed to write report")
+		}
+		cmd.FailOnError(err, "Failed to run load test")
+		return
+
+	case "backfill":
+		if c.RatelimitsTool.DB == nil {
+			cmd.Fail("backfill requires DB to be configured")
+		}
+		limitName, err := ratelimits.NameFromString(*name)
+		cmd.FailOnError(err, "Invalid limit name")
+		dbMap, err := sa.InitWrappedDb(*c.RatelimitsTool.DB, nil, logger)
+		cmd.FailOnError(err, "Failed to connect to database")
+
+		since := clk.Now().Add(-*window)
+		var counts []sa.LegacyRateLimitCount
+		switch limitName {
+		case ratelimits.CertificatesPerDomain:
+			counts, err = sa.LegacyCertificatesPerNameCounts(ctx, dbMap, since)
+		case ratelimits.NewOrdersPerAccount:
+			counts, err = sa.LegacyNewOrdersCounts(ctx, dbMap, since)
+		default:
+			cmd.Fail(fmt.Sprintf("backfill doesn't support %s", limitName))
+		}
+		cmd.FailOnError(err, "Failed to read legacy counts")
+
+		var backfilled int64
+		for _, count := range counts {
+			txn, err := txnBuilder.TransactionForId(limitName, count.ID, 1)
+			if err == nil {
+				var changed bool
+				changed, err = limiter.Backfill(ctx, txn, count.Count)
+				if changed {
+					backfilled++
+				}
+			}
+			if err != nil {
+				// Buckets backfilled before an error remain so, so audit log
+				// them either way.
+				logger.AuditInfof("Backfilled %d of %d %s buckets from legacy counts since %s", backfilled, len(counts), limitName, since)
+				cmd.FailOnError(err, fmt.Sprintf("Failed to backfill bucket for %q", count.ID))
+			}
+		}
+		logger.AuditInfof("Backfilled %d of %d %s buckets from legacy counts since %s", backfilled, len(counts), limitName, since)
+		return
+
+	case "preflight":
+		regId, err := strconv.ParseInt(*id, 10, 64)
+		cmd.FailOnError(err, "Invalid regId")
+		estimates, err := ratelimits.EstimateNewOrderQuota(ctx, limiter, txnBuilder, regId, strings.Split(*domains, ","), *renewal)
+		cmd.FailOnError(err, "Failed to estimate order quota")
+		printJSON(estimates)
+		return
+	}
+
+	state, err := ratelimits.InspectBucket(ctx, limiter, txnBuilder, *name, *id)
+	cmd.FailOnError(err, "Failed to inspect bucket")
+
+	switch subcommand {
+	case "inspect":
+		printJSON(state)
+		return
+
+	case "reset":
+		err = limiter.Reset(ctx, state.BucketKey)
+		cmd.FailOnError(err, "Failed to reset bucket")
+		logger.AuditInfof("Reset rate limit bucket %q for %s %q", state.BucketKey, *name, *id)
+		return
+	}
+
+	limitName, err := ratelimits.NameFromString(*name)
+	cmd.FailOnError(err, "Invalid limit name")
+	txn, err := txnBuilder.TransactionForId(limitName, *id, *cost)
+	cmd.FailOnError(err, "Failed to build transaction")
+	if *renewal && subcommand != "refund" {
+		txn = txn.AsRenewal()
+	}
+
+	var d *ratelimits.Decision
+	switch subcommand {
+	case "check":
+		d, err = limiter.Check(ctx, txn)
+	case "spend":
+		d, err = limiter.Spend(ctx, txn)
+	case "refund":
+		d, err = limiter.Refund(ctx, txn)
+	}
+	cmd.FailOnError(err, fmt.Sprintf("Failed to %s", subcommand))
+	printJSON(d)
+}
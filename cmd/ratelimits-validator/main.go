@@ -0,0 +1,45 @@
+// Load and validate a pair of key-value rate limit defaults and overrides YAML
+// files. Print the normalized effective limits if they are valid, otherwise
+// print the first error encountered and exit non-zero.
+
+package notmain
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/ratelimits"
+)
+
+func init() {
+	cmd.RegisterCommand("ratelimits-validator", main, nil)
+}
+
+func main() {
+	defaults := flag.String("defaults", "", "Path to YAML file containing default limits (required).")
+	environment := flag.String("environment", "", "Environment section of the defaults file to apply (optional).")
+	overrides := flag.String("overrides", "", "Path to YAML file containing override limits (optional).")
+	exemptions := flag.String("exemptions", "", "Path to YAML file containing limit exemptions (optional).")
+	quiet := flag.Bool("quiet", false, "Do not print the effective limits, only validate them.")
+	flag.Parse()
+
+	if *defaults == "" {
+		fmt.Fprintln(os.Stderr, "-defaults is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var out io.Writer = os.Stdout
+	if *quiet {
+		out = io.Discard
+	}
+
+	err := ratelimits.ValidateLimits(*defaults, *environment, *overrides, *exemptions, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid limits: %s\n", err)
+		os.Exit(1)
+	}
+}
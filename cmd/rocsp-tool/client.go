@@ -2,6 +2,7 @@ package notmain
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
@@ -12,6 +13,7 @@ import (
 	capb "github.com/letsencrypt/boulder/ca/proto"
 	"github.com/letsencrypt/boulder/core"
 	"github.com/letsencrypt/boulder/db"
+	berrors "github.com/letsencrypt/boulder/errors"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/rocsp"
 	"github.com/letsencrypt/boulder/sa"
@@ -31,10 +33,12 @@ type client struct {
 
 // processResult represents the result of attempting to sign and store status
 // for a single certificateStatus ID. If `err` is non-nil, it indicates the
-// attempt failed.
+// attempt failed. If `skipped` is true, the certificate's issuer doesn't
+// provide OCSP, so there was nothing to sign or store.
 type processResult struct {
-	id  uint64
-	err error
+	id      uint64
+	err     error
+	skipped bool
 }
 
 func getStartingID(ctx context.Context, clk clock.Clock, db *db.WrappedMap) (int64, error) {
@@ -96,7 +100,7 @@ func (cl *client) loadFromDB(ctx context.Context, speed ProcessingSpeed, startFr
 		go cl.signAndStoreResponses(ctx, statusesToSign, results, &runningSigners)
 	}
 
-	var successCount, errorCount int64
+	var successCount, errorCount, skippedCount int64
 
 	for result := range results {
 		inflightIDs.remove(result.id)
@@ -108,20 +112,22 @@ func (cl *client) loadFromDB(ctx context.Context, speed ProcessingSpeed, startFr
 				(rand.Intn(1000) < 1) {
 				cl.logger.Errf("error: %s", result.err)
 			}
+		} else if result.skipped {
+			skippedCount++
 		} else {
 			successCount++
 		}
 
-		total := successCount + errorCount
+		total := successCount + errorCount + skippedCount
 		if total < 10 ||
 			(total < 1000 && rand.Intn(1000) < 100) ||
 			(total < 100000 && rand.Intn(1000) < 10) ||
 			(rand.Intn(1000) < 1) {
-			cl.logger.Infof("stored %d responses, %d errors", successCount, errorCount)
+			cl.logger.Infof("stored %d responses, %d errors, %d skipped", successCount, errorCount, skippedCount)
 		}
 	}
 
-	cl.logger.Infof("done. processed %d successes and %d errors\n", successCount, errorCount)
+	cl.logger.Infof("done. processed %d successes, %d errors, and %d skipped\n", successCount, errorCount, skippedCount)
 	if inflightIDs.len() != 0 {
 		return fmt.Errorf("inflightIDs non-empty! has %d items, lowest %d", inflightIDs.len(), inflightIDs.min())
 	}
@@ -217,6 +223,11 @@ func (cl *client) signAndStoreResponses(ctx context.Context, input <-chan *sa.Ce
 			RevokedAt: timestamppb.New(status.RevokedDate),
 		}
 		result, err := cl.ocspGenerator.GenerateOCSP(ctx, ocspReq)
+		if errors.Is(err, berrors.NotFound) {
+			// The CA doesn't sign OCSP for issuers whose profile omits it.
+			output <- processResult{id: uint64(status.ID), skipped: true}
+			continue
+		}
 		if err != nil {
 			output <- processResult{id: uint64(status.ID), err: err}
 			continue
@@ -258,6 +258,21 @@ func newVersionCollector() prometheus.Collector {
 	)
 }
 
+// debugMux is the *http.ServeMux used by the debug server. It is created by
+// StatsAndLogging and is nil until then.
+var debugMux *http.ServeMux
+
+// HandleDebug registers the provided handler for the given pattern on the debug
+// server. It must only be called after StatsAndLogging. The debug server is
+// only reachable by operators, handlers registered here MUST NOT be exposed to
+// subscribers.
+func HandleDebug(pattern string, handler http.Handler) {
+	if debugMux == nil {
+		panic("HandleDebug called before StatsAndLogging")
+	}
+	debugMux.Handle(pattern, handler)
+}
+
 func newStatsRegistry(addr string, logger blog.Logger) prometheus.Registerer {
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(collectors.NewGoCollector())
@@ -266,6 +281,7 @@ func newStatsRegistry(addr string, logger blog.Logger) prometheus.Registerer {
 	registry.MustRegister(newVersionCollector())
 
 	mux := http.NewServeMux()
+	debugMux = mux
 	// Register the available pprof handlers. These are all registered on
 	// DefaultServeMux just by importing pprof, but since we eschew
 	// DefaultServeMux, we need to explicitly register them on our own mux.
@@ -284,6 +300,8 @@ func newStatsRegistry(addr string, logger blog.Logger) prometheus.Registerer {
 	mux.Handle("/debug/vars", expvar.Handler())
 	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{
 		ErrorLog: promLogger{logger},
+		// Exemplars are only served in the OpenMetrics format.
+		EnableOpenMetrics: true,
 	}))
 
 	if addr == "" {
@@ -119,3 +119,24 @@ type LogID struct {
 	ID          string
 	SubmitFinal bool
 }
+
+// LogLimit configures how the publisher paces its submissions to a single CT
+// log, so that a log which is slow or which enforces a quota is not flooded
+// with submissions during spikes in issuance.
+type LogLimit struct {
+	// Count submissions are allowed per Period, and up to Burst at once. If
+	// Count is zero, submissions to the log are not paced.
+	Burst  int64           `validate:"omitempty,min=1"`
+	Count  int64           `validate:"omitempty,min=1"`
+	Period config.Duration `validate:"-"`
+
+	// MaxConcurrent is the maximum number of submissions which may be in
+	// flight to the log at once. If zero, in flight submissions are not
+	// limited.
+	MaxConcurrent int `validate:"min=0"`
+
+	// MaxQueued is the maximum number of submissions which may wait for
+	// their turn to be submitted to the log, beyond which submissions fail
+	// immediately. If zero, submissions never wait.
+	MaxQueued int `validate:"min=0"`
+}
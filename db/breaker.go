@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/borp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrCircuitOpen is returned, wrapped in ErrDatabaseOp, by the operations of a
+// map whose circuit breaker is open, without reaching the database. See
+// SetCircuitBreaker.
+var ErrCircuitOpen = errors.New("database circuit breaker is open")
+
+// circuitBreaker counts consecutive operations which fail because the database
+// is unreachable or unresponsive, and once there are threshold of them opens,
+// failing operations fast with ErrCircuitOpen. After cooldown it lets a single
+// trial operation through: if it succeeds the breaker closes, and otherwise it
+// remains open for another cooldown. A nil *circuitBreaker never opens.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	clk       clock.Clock
+
+	open       prometheus.Gauge
+	rejections prometheus.Counter
+
+	mu       sync.Mutex
+	failures int
+	// openedAt is when the breaker last opened, or the zero time if it's
+	// closed.
+	openedAt time.Time
+	// trial is true while the trial operation of an open breaker is in
+	// progress.
+	trial bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, stats prometheus.Registerer) (*circuitBreaker, error) {
+	open := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "db_circuit_breaker_open",
+		Help: "Whether the database circuit breaker is open, failing operations fast",
+	})
+	rejections := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "db_circuit_breaker_rejections_total",
+		Help: "Number of database operations failed fast by an open circuit breaker",
+	})
+	if stats != nil {
+		for _, c := range []prometheus.Collector{open, rejections} {
+			err := stats.Register(c)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &circuitBreaker{
+		threshold:  threshold,
+		cooldown:   cooldown,
+		clk:        clock.New(),
+		open:       open,
+		rejections: rejections,
+	}, nil
+}
+
+// allow returns ErrCircuitOpen if an operation may not proceed. Otherwise the
+// operation's result must be passed to the returned function once it
+// completes.
+func (b *circuitBreaker) allow() (func(error), error) {
+	if b == nil {
+		return func(error) {}, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return func(err error) { b.record(false, err) }, nil
+	}
+	if b.trial || b.clk.Since(b.openedAt) < b.cooldown {
+		b.rejections.Inc()
+		return nil, ErrCircuitOpen
+	}
+	b.trial = true
+	return func(err error) { b.record(true, err) }, nil
+}
+
+// record updates the breaker with the result of an operation, which is its
+// trial operation if trial is true.
+func (b *circuitBreaker) record(trial bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if trial {
+		b.trial = false
+	}
+	if !breaksCircuit(err) {
+		// The database responded, so it's available again.
+		b.failures = 0
+		b.openedAt = time.Time{}
+		b.open.Set(0)
+		return
+	}
+	b.failures++
+	if trial || (b.openedAt.IsZero() && b.failures >= b.threshold) {
+		b.openedAt = b.clk.Now()
+		b.open.Set(1)
+	}
+}
+
+// breaksCircuit returns true if err indicates that the database is
+// unreachable or unresponsive, rather than that the operation itself failed.
+// Other errors, e.g. a duplicate key, show that the database is responding,
+// and timing out waiting under the concurrency limit doesn't involve it.
+func breaksCircuit(err error) bool {
+	if err == nil || errors.Is(err, errConcurrencyWait) {
+		return false
+	}
+	return IsConnectionLost(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// wrap returns executor, with its operations subject to the breaker.
+func (b *circuitBreaker) wrap(executor borp.SqlExecutor) borp.SqlExecutor {
+	if b == nil {
+		return executor
+	}
+	return guardedExecutor{SqlExecutor: executor, guard: func(context.Context) (func(error), error) { return b.allow() }}
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/borp"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewSQLiteMap(ctx, ":memory:", func(dbMap *borp.DbMap) {
+		dbMap.AddTableWithName(sqliteTestModel{}, "widgets").SetKeys(true, "ID")
+	})
+	test.AssertNotError(t, err, "creating SQLite map")
+	fake := NewFakeMap(m)
+	defer func() { _ = fake.Close() }()
+	err = fake.SetCircuitBreaker(3, time.Minute, prometheus.NewRegistry())
+	test.AssertNotError(t, err, "setting circuit breaker")
+	clk := clock.NewFake()
+	fake.breaker.clk = clk
+
+	count := func() error {
+		_, err := fake.SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+		return err
+	}
+
+	// Errors showing that the database is responding don't count, and
+	// successes reset the count.
+	fake.FailNext("select", driver.ErrBadConn)
+	fake.FailNext("select", driver.ErrBadConn)
+	fake.FailNext("select", errors.New("oops"))
+	fake.FailNext("select", driver.ErrBadConn)
+	for i := 0; i < 4; i++ {
+		test.AssertError(t, count(), "scripted failure")
+	}
+	test.AssertNotError(t, count(), "counting widgets")
+	test.AssertMetricWithLabelsEquals(t, fake.breaker.open, prometheus.Labels{}, 0)
+
+	// Consecutive connection errors and timeouts open the breaker.
+	fake.FailNext("select", driver.ErrBadConn)
+	fake.FailNext("select", context.DeadlineExceeded)
+	fake.FailNext("select", driver.ErrBadConn)
+	for i := 0; i < 3; i++ {
+		test.AssertError(t, count(), "scripted failure")
+	}
+	test.AssertMetricWithLabelsEquals(t, fake.breaker.open, prometheus.Labels{}, 1)
+
+	// Once open, operations, including beginning transactions, fail fast.
+	err = count()
+	test.AssertErrorIs(t, err, ErrCircuitOpen)
+	var dbOpErr ErrDatabaseOp
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Op, "select")
+	err = fake.Insert(ctx, &sqliteTestModel{Name: "widget"})
+	test.AssertErrorIs(t, err, ErrCircuitOpen)
+	_, err = fake.BeginTx(ctx)
+	test.AssertErrorIs(t, err, ErrCircuitOpen)
+	test.AssertMetricWithLabelsEquals(t, fake.breaker.rejections, prometheus.Labels{}, 3)
+
+	// After the cooldown a failed trial reopens it for another cooldown.
+	clk.Add(time.Minute)
+	fake.FailNext("select", driver.ErrBadConn)
+	test.AssertErrorIs(t, count(), driver.ErrBadConn)
+	test.AssertErrorIs(t, count(), ErrCircuitOpen)
+	clk.Add(time.Minute - time.Second)
+	test.AssertErrorIs(t, count(), ErrCircuitOpen)
+
+	// A successful trial closes it.
+	clk.Add(time.Second)
+	test.AssertNotError(t, count(), "counting widgets")
+	test.AssertMetricWithLabelsEquals(t, fake.breaker.open, prometheus.Labels{}, 0)
+	err = WithTransaction(ctx, fake, func(tx Executor) error {
+		return tx.Insert(ctx, &sqliteTestModel{Name: "widget"})
+	})
+	test.AssertNotError(t, err, "inserting widget")
+}
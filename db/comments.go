@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying id, which is included in the
+// query comments of the operations performed with it. See SetQueryComments.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// queryCommenter appends a comment attributing each statement to the component
+// and request which executed it, in the format of
+// https://google.github.io/sqlcommenter/spec/, so that they can be identified
+// in the processlist and slow query log. A nil *queryCommenter leaves
+// statements unchanged.
+type queryCommenter struct {
+	component string
+}
+
+// comment returns query with a comment describing ctx appended.
+func (c *queryCommenter) comment(ctx context.Context, query string) string {
+	if c == nil {
+		return query
+	}
+	// The keys are in lexicographic order, as the spec requires.
+	fields := []string{commentField("component", c.component)}
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	if ok && requestID != "" {
+		fields = append(fields, commentField("request_id", requestID))
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	traceparent := carrier.Get("traceparent")
+	if traceparent != "" {
+		fields = append(fields, commentField("traceparent", traceparent))
+	}
+	return query + " /*" + strings.Join(fields, ",") + "*/"
+}
+
+// commentField formats a key-value pair of a query comment. Escaping the value
+// ensures that it can't contain a quote or close the comment.
+func commentField(key, value string) string {
+	return key + "='" + url.PathEscape(value) + "'"
+}
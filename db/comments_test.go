@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// queryRecorder records the queries passed to ExecContext.
+type queryRecorder struct {
+	MockSqlExecutor
+	queries []string
+}
+
+func (r *queryRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.queries = append(r.queries, query)
+	return nil, sql.ErrConnDone
+}
+
+func TestQueryComments(t *testing.T) {
+	ctx := context.Background()
+	const query = "UPDATE widgets SET Value = ?"
+
+	var none *queryCommenter
+	test.AssertEquals(t, none.comment(ctx, query), query)
+
+	c := &queryCommenter{component: "boulder-sa"}
+	test.AssertEquals(t, c.comment(ctx, query), query+" /*component='boulder-sa'*/")
+
+	// Values are escaped so that they can't end the comment.
+	test.AssertEquals(t,
+		c.comment(WithRequestID(ctx, "*/ DROP TABLE widgets; /*'"), query),
+		query+" /*component='boulder-sa',request_id='%2A%2F%20DROP%20TABLE%20widgets%3B%20%2F%2A%27'*/")
+
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	tracedCtx := trace.ContextWithSpanContext(WithRequestID(ctx, "abc123"), spanCtx)
+	test.AssertEquals(t, c.comment(tracedCtx, query),
+		query+" /*component='boulder-sa',request_id='abc123',traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/")
+
+	// The wrappers comment the statements they execute.
+	recorder := &queryRecorder{}
+	we := WrappedExecutor{sqlExecutor: recorder, instrumentation: instrumentation{commenter: c}}
+	_, err := we.ExecContext(tracedCtx, query, []byte{1})
+	test.AssertError(t, err, "expected error from recorder")
+	test.AssertDeepEquals(t, recorder.queries, []string{c.comment(tracedCtx, query)})
+
+	// Commented statements are still valid SQL.
+	dbMap := testSQLiteMap(t)
+	dbMap.SetQueryComments("boulder-sa")
+	_, err = dbMap.ExecContext(tracedCtx, query, []byte{1})
+	test.AssertNotError(t, err, "executing commented statement")
+	_, err = dbMap.SelectNullInt(tracedCtx, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "selecting with commented statement")
+}
@@ -0,0 +1,85 @@
+// Package dbtest provides hermetic, SQLite-backed implementations of the db
+// package's maps for unit tests. It links the cgo SQLite driver, so it must
+// only be imported by tests.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/letsencrypt/borp"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/letsencrypt/boulder/db"
+)
+
+func init() {
+	db.RegisterDriver(&sqlite3.SQLiteDriver{}, "sqlite", ClassifySQLiteError)
+}
+
+// NewSQLiteMap opens the SQLite database named by dsn and returns a
+// *db.WrappedMap over it. The dsn is passed to github.com/mattn/go-sqlite3,
+// e.g. ":memory:" for a private, in-memory database. If setup is non-nil it is
+// called to register tables (and, if needed, a TypeConverter) with the
+// underlying *borp.DbMap, after which any registered tables which don't
+// already exist are created using the SQLite dialect.
+//
+// It is intended for hermetic unit tests of packages that depend on db, which
+// would otherwise require a MySQL container. Queries using MySQL-specific
+// syntax will fail. The driver requires cgo.
+func NewSQLiteMap(ctx context.Context, dsn string, setup func(*borp.DbMap)) (*db.WrappedMap, error) {
+	dbConn, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening SQLite database: %w", err)
+	}
+	// Every connection to ":memory:" opens a distinct database. Use a single
+	// connection so that all queries, including those of transactions, see
+	// the same tables. SQLite serializes writes regardless.
+	dbConn.SetMaxOpenConns(1)
+
+	dbMap := &borp.DbMap{Db: dbConn, Dialect: borp.SqliteDialect{}}
+	if setup != nil {
+		setup(dbMap)
+	}
+	err = dbMap.CreateTablesIfNotExists(ctx)
+	if err != nil {
+		_ = dbConn.Close()
+		return nil, fmt.Errorf("creating SQLite tables: %w", err)
+	}
+	return db.NewWrappedMap(dbMap), nil
+}
+
+// NewFakeMap returns a *db.FakeMap over a private, in-memory SQLite database,
+// whose tables are registered by setup. See NewSQLiteMap.
+func NewFakeMap(ctx context.Context, setup func(*borp.DbMap)) (*db.FakeMap, error) {
+	m, err := NewSQLiteMap(ctx, ":memory:", setup)
+	if err != nil {
+		return nil, err
+	}
+	return db.NewFakeMap(m), nil
+}
+
+// ClassifySQLiteError returns the class of the SQLite error wrapped by err, or
+// db.Unknown if err doesn't wrap one.
+func ClassifySQLiteError(err error) db.ErrorClass {
+	var dbErr sqlite3.Error
+	if !errors.As(err, &dbErr) {
+		return db.Unknown
+	}
+	switch {
+	case dbErr.ExtendedCode == sqlite3.ErrConstraintUnique || dbErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		return db.Duplicate
+	case dbErr.Code == sqlite3.ErrBusy || dbErr.Code == sqlite3.ErrLocked:
+		// SQLite reports a lock held by another connection or transaction as
+		// busy, after waiting for its busy timeout.
+		return db.LockTimeout
+	case dbErr.Code == sqlite3.ErrReadonly:
+		return db.ReadOnly
+	case dbErr.Code == sqlite3.ErrError && strings.Contains(dbErr.Error(), "syntax error"):
+		return db.Syntax
+	}
+	return db.Unknown
+}
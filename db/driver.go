@@ -0,0 +1,61 @@
+package db
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// driverHooks are how a database driver registered with RegisterDriver takes
+// part in error classification and tracing.
+type driverHooks struct {
+	system   attribute.KeyValue
+	classify func(error) ErrorClass
+}
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[reflect.Type]driverHooks)
+)
+
+// RegisterDriver teaches this package about a database driver other than
+// MySQL, without this package importing it. Spans of operations on a *sql.DB
+// whose driver has the same type as drv identify the database as system, an
+// OpenTelemetry db.system value such as "sqlite", and ClassifyError uses
+// classify, which must return Unknown for errors it doesn't recognize. It is
+// intended for drivers used only by tests, like the SQLite driver registered
+// by the db/dbtest package, which shouldn't be linked into every binary that
+// imports this package.
+func RegisterDriver(drv driver.Driver, system string, classify func(error) ErrorClass) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[reflect.TypeOf(drv)] = driverHooks{
+		system:   semconv.DBSystemKey.String(system),
+		classify: classify,
+	}
+}
+
+// registeredDriver returns the hooks registered for drv, if any.
+func registeredDriver(drv driver.Driver) (driverHooks, bool) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	hooks, ok := drivers[reflect.TypeOf(drv)]
+	return hooks, ok
+}
+
+// classifyRegisteredDriverError returns the class of err according to the
+// first registered driver which recognizes it, or Unknown.
+func classifyRegisteredDriverError(err error) ErrorClass {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	for _, hooks := range drivers {
+		class := hooks.classify(err)
+		if class != Unknown {
+			return class
+		}
+	}
+	return Unknown
+}
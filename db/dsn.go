@@ -0,0 +1,120 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// redacted replaces the password of a DSN.
+const redacted = "REDACTED"
+
+// splitPassword returns the password of dsn, a MySQL DSN of the form
+// [user[:password]@][net[(addr)]]/dbname[?params], and the offsets of its
+// start and end, or ok false if it has none. Passwords may contain any
+// character, so like the driver it takes the user info to end at the last @
+// before the last /, and the user name to end at the first :.
+func splitPassword(dsn string) (start, end int, ok bool) {
+	slash := strings.LastIndex(dsn, "/")
+	if slash < 0 {
+		return 0, 0, false
+	}
+	at := strings.LastIndex(dsn[:slash], "@")
+	if at < 0 {
+		return 0, 0, false
+	}
+	colon := strings.Index(dsn[:at], ":")
+	if colon < 0 || colon+1 == at {
+		return 0, 0, false
+	}
+	return colon + 1, at, true
+}
+
+// RedactDSN returns dsn with its password, if any, replaced, so that it can be
+// logged or included in an error. Since it doesn't parse dsn, it can redact
+// DSNs which are invalid, which are those most likely to end up in errors.
+func RedactDSN(dsn string) string {
+	start, end, ok := splitPassword(dsn)
+	if !ok {
+		return dsn
+	}
+	return dsn[:start] + redacted + dsn[end:]
+}
+
+// ParseDSN parses and validates dsn, a MySQL DSN. Unlike those of
+// mysql.ParseDSN, its errors include the DSN, to identify it, but never its
+// password.
+func ParseDSN(dsn string) (*mysql.Config, error) {
+	conf, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		msg := err.Error()
+		start, end, ok := splitPassword(dsn)
+		if ok {
+			msg = strings.ReplaceAll(msg, dsn[start:end], redacted)
+		}
+		return nil, fmt.Errorf("parsing DSN %q: %s", RedactDSN(dsn), msg)
+	}
+	if conf.DBName == "" {
+		return nil, fmt.Errorf("parsing DSN %q: missing the database name", RedactDSN(dsn))
+	}
+	return conf, nil
+}
+
+// DSNConfig is the structured form of a MySQL DSN, for constructing one from
+// configuration rather than storing it whole.
+type DSNConfig struct {
+	User     string
+	Password string
+	// Addr is the host:port of the database, connected to over TCP.
+	Addr   string
+	DBName string
+	// Params are system variables and driver parameters, as in the query
+	// string of a DSN, e.g. readTimeout.
+	Params map[string]string
+}
+
+// DSN returns the DSN c represents, after checking that it is complete.
+func (c DSNConfig) DSN() (string, error) {
+	if c.User == "" || c.Addr == "" || c.DBName == "" {
+		return "", errors.New("DSN config must include a user, address, and database name")
+	}
+	conf := mysql.NewConfig()
+	conf.User = c.User
+	conf.Passwd = c.Password
+	conf.Net = "tcp"
+	conf.Addr = c.Addr
+	conf.DBName = c.DBName
+	// Parameters which the driver parses into fields of its Config must be
+	// round-tripped through a DSN to be parsed.
+	dsn := conf.FormatDSN()
+	if len(c.Params) > 0 {
+		var params []string
+		for k, v := range c.Params {
+			params = append(params, k+"="+url.QueryEscape(v))
+		}
+		sort.Strings(params)
+		dsn += "?" + strings.Join(params, "&")
+	}
+	_, err := ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	return dsn, nil
+}
+
+// String returns the DSN c represents, with its password redacted, so that a
+// DSNConfig can be logged.
+func (c DSNConfig) String() string {
+	if c.Password != "" {
+		c.Password = redacted
+	}
+	dsn, err := c.DSN()
+	if err != nil {
+		return fmt.Sprintf("invalid DSN (%s)", err)
+	}
+	return dsn
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestRedactDSN(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"sa:hunter2@tcp(boulder-proxysql:6033)/boulder_sa?readTimeout=14s": "sa:REDACTED@tcp(boulder-proxysql:6033)/boulder_sa?readTimeout=14s",
+		"sa:p@ss:w/rd@tcp(10.0.0.1:3306)/boulder_sa":                       "sa:REDACTED@tcp(10.0.0.1:3306)/boulder_sa",
+		"sa@tcp(boulder-proxysql:6033)/boulder_sa":                         "sa@tcp(boulder-proxysql:6033)/boulder_sa",
+		"sa:@tcp(boulder-proxysql:6033)/boulder_sa":                        "sa:@tcp(boulder-proxysql:6033)/boulder_sa",
+		"invalid": "invalid",
+		"sa:hunter2@tcp(boulder-proxysql:6033)/%zz": "sa:REDACTED@tcp(boulder-proxysql:6033)/%zz",
+	} {
+		test.AssertEquals(t, RedactDSN(dsn), want)
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	conf, err := ParseDSN("sa:hunter2@tcp(boulder-proxysql:6033)/boulder_sa?readTimeout=14s")
+	test.AssertNotError(t, err, "parsing valid DSN")
+	test.AssertEquals(t, conf.Passwd, "hunter2")
+	test.AssertEquals(t, conf.DBName, "boulder_sa")
+
+	for _, dsn := range []string{
+		"invalid",
+		"sa:hunter2@tcp(boulder-proxysql:6033)/",
+		"sa:hunter2@tcp(boulder-proxysql:6033)/boulder_sa?readTimeout=forever",
+		"sa:hunter2@tcp(boulder-proxysql:6033/boulder_sa",
+	} {
+		_, err := ParseDSN(dsn)
+		test.AssertError(t, err, "expected error parsing "+dsn)
+		test.Assert(t, !strings.Contains(err.Error(), "hunter2"), "error contains password: "+err.Error())
+	}
+}
+
+func TestDSNConfig(t *testing.T) {
+	c := DSNConfig{
+		User:     "sa",
+		Password: "hunter2",
+		Addr:     "boulder-proxysql:6033",
+		DBName:   "boulder_sa",
+		Params:   map[string]string{"readTimeout": "14s", "sql_mode": "'STRICT_ALL_TABLES'"},
+	}
+	dsn, err := c.DSN()
+	test.AssertNotError(t, err, "formatting DSN")
+	test.AssertEquals(t, dsn, "sa:hunter2@tcp(boulder-proxysql:6033)/boulder_sa?readTimeout=14s&sql_mode=%27STRICT_ALL_TABLES%27")
+	conf, err := ParseDSN(dsn)
+	test.AssertNotError(t, err, "parsing formatted DSN")
+	test.AssertEquals(t, conf.Params["sql_mode"], "'STRICT_ALL_TABLES'")
+	test.AssertEquals(t, c.String(), "sa:REDACTED@tcp(boulder-proxysql:6033)/boulder_sa?readTimeout=14s&sql_mode=%27STRICT_ALL_TABLES%27")
+
+	_, err = DSNConfig{User: "sa", Addr: "boulder-proxysql:6033"}.DSN()
+	test.AssertError(t, err, "expected error for missing database name")
+	c.Params = map[string]string{"readTimeout": "forever"}
+	_, err = c.DSN()
+	test.AssertError(t, err, "expected error for invalid parameter")
+	test.Assert(t, !strings.Contains(err.Error(), "hunter2"), "error contains password: "+err.Error())
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrorClass is a coarse category of database error, which callers can act on
+// without matching MySQL error numbers themselves.
+type ErrorClass int
+
+const (
+	// Unknown is the class of errors, including nil, which are not otherwise
+	// classified.
+	Unknown ErrorClass = iota
+	// Duplicate errors are returned when a write would violate a unique key
+	// constraint.
+	Duplicate
+	// Deadlock errors are returned when a transaction was rolled back to
+	// resolve a deadlock with another transaction.
+	Deadlock
+	// LockTimeout errors are returned when a statement timed out waiting for a
+	// lock held by another transaction.
+	LockTimeout
+	// ConnectionLost errors are returned when the connection to the database
+	// failed or was closed by the server.
+	ConnectionLost
+	// ReadOnly errors are returned when a write was attempted against a server
+	// or transaction which is read-only.
+	ReadOnly
+	// Syntax errors are returned when a statement couldn't be parsed.
+	Syntax
+	// Interrupted errors are returned when a statement was killed while it was
+	// executing.
+	Interrupted
+	// LockTableFull errors are returned when a transaction acquired more locks
+	// than fit in the lock table.
+	LockTableFull
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case Duplicate:
+		return "duplicate"
+	case Deadlock:
+		return "deadlock"
+	case LockTimeout:
+		return "lockTimeout"
+	case ConnectionLost:
+		return "connectionLost"
+	case ReadOnly:
+		return "readOnly"
+	case Syntax:
+		return "syntax"
+	case Interrupted:
+		return "interrupted"
+	case LockTableFull:
+		return "lockTableFull"
+	default:
+		return "unknown"
+	}
+}
+
+// Retryable returns true for the classes of error after which retrying the
+// operation, or the transaction it was part of, may succeed: Deadlock,
+// LockTimeout, Interrupted, and ConnectionLost. The others are fatal: retrying
+// would fail the same way, as for Duplicate, Syntax, and LockTableFull, or
+// would likely be sent to the same read-only server, as for ReadOnly.
+func (c ErrorClass) Retryable() bool {
+	switch c {
+	case Deadlock, LockTimeout, Interrupted, ConnectionLost:
+		return true
+	default:
+		return false
+	}
+}
+
+// mysqlErrorClasses maps the MySQL and MariaDB error numbers which are
+// classified to their ErrorClass. See
+// https://mariadb.com/kb/en/mariadb-error-codes/
+var mysqlErrorClasses = map[uint16]ErrorClass{
+	1062: Duplicate,      // ER_DUP_ENTRY
+	1213: Deadlock,       // ER_LOCK_DEADLOCK
+	1205: LockTimeout,    // ER_LOCK_WAIT_TIMEOUT
+	1053: ConnectionLost, // ER_SERVER_SHUTDOWN
+	1927: ConnectionLost, // ER_CONNECTION_KILLED
+	4031: ConnectionLost, // ER_CLIENT_INTERACTION_TIMEOUT
+	1290: ReadOnly,       // ER_OPTION_PREVENTS_STATEMENT, e.g. --read-only
+	1792: ReadOnly,       // ER_CANT_EXECUTE_IN_READ_ONLY_TRANSACTION
+	1836: ReadOnly,       // ER_READ_ONLY_MODE
+	1064: Syntax,         // ER_PARSE_ERROR
+	1317: Interrupted,    // ER_QUERY_INTERRUPTED
+	1206: LockTableFull,  // ER_LOCK_TABLE_FULL
+}
+
+// ClassifyError returns the class of the database error wrapped by err. Errors
+// of drivers registered with RegisterDriver, e.g. those returned by a
+// WrappedMap from dbtest.NewSQLiteMap, are classified like their MySQL
+// equivalents.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return Unknown
+	}
+	var dbErr *mysql.MySQLError
+	if errors.As(err, &dbErr) {
+		return mysqlErrorClasses[dbErr.Number]
+	}
+	class := classifyRegisteredDriverError(err)
+	if class != Unknown {
+		return class
+	}
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.As(err, &netErr) {
+		return ConnectionLost
+	}
+	return Unknown
+}
+
+// IsRetryable returns true if err wraps an error of a Retryable class.
+func IsRetryable(err error) bool {
+	return ClassifyError(err).Retryable()
+}
+
+// IsDeadlock returns true if err wraps a Deadlock error, e.g. MySQL's Error
+// 1213.
+func IsDeadlock(err error) bool {
+	return ClassifyError(err) == Deadlock
+}
+
+// IsLockTimeout returns true if err wraps a LockTimeout error, e.g. MySQL's
+// Error 1205.
+func IsLockTimeout(err error) bool {
+	return ClassifyError(err) == LockTimeout
+}
+
+// IsConnectionLost returns true if err wraps a ConnectionLost error, e.g.
+// driver.ErrBadConn or a network error.
+func IsConnectionLost(err error) bool {
+	return ClassifyError(err) == ConnectionLost
+}
+
+// IsReadOnly returns true if err wraps a ReadOnly error, e.g. MySQL's Error
+// 1290.
+func IsReadOnly(err error) bool {
+	return ClassifyError(err) == ReadOnly
+}
+
+// IsSyntax returns true if err wraps a Syntax error, e.g. MySQL's Error 1064.
+func IsSyntax(err error) bool {
+	return ClassifyError(err) == Syntax
+}
+
+// IsInterrupted returns true if err wraps an Interrupted error, e.g. MySQL's
+// Error 1317.
+func IsInterrupted(err error) bool {
+	return ClassifyError(err) == Interrupted
+}
+
+// IsLockTableFull returns true if err wraps a LockTableFull error, e.g. MySQL's
+// Error 1206.
+func IsLockTableFull(err error) bool {
+	return ClassifyError(err) == LockTableFull
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"nil", nil, Unknown},
+		{"other", errors.New("oops"), Unknown},
+		{"unclassified MySQL error", &mysql.MySQLError{Number: 1234}, Unknown},
+		{"duplicate", &mysql.MySQLError{Number: 1062}, Duplicate},
+		{"deadlock", &mysql.MySQLError{Number: 1213}, Deadlock},
+		{"lock wait timeout", &mysql.MySQLError{Number: 1205}, LockTimeout},
+		{"connection killed", &mysql.MySQLError{Number: 1927}, ConnectionLost},
+		{"bad connection", driver.ErrBadConn, ConnectionLost},
+		{"invalid connection", mysql.ErrInvalidConn, ConnectionLost},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ConnectionLost},
+		{"read-only mode", &mysql.MySQLError{Number: 1836}, ReadOnly},
+		{"read-only transaction", &mysql.MySQLError{Number: 1792}, ReadOnly},
+		{"parse error", &mysql.MySQLError{Number: 1064}, Syntax},
+		{"read-only option", &mysql.MySQLError{Number: 1290}, ReadOnly},
+		{"query interrupted", &mysql.MySQLError{Number: 1317}, Interrupted},
+		{"lock table full", &mysql.MySQLError{Number: 1206}, LockTableFull},
+		{"idle timeout", &mysql.MySQLError{Number: 4031}, ConnectionLost},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			wrapped := ErrDatabaseOp{Op: "test", Table: "widgets", Err: fmt.Errorf("wrapped: %w", tc.err)}
+			test.AssertEquals(t, ClassifyError(tc.err), tc.want)
+			if tc.err != nil {
+				test.AssertEquals(t, ClassifyError(wrapped), tc.want)
+			}
+		})
+	}
+
+	test.Assert(t, IsDeadlock(&mysql.MySQLError{Number: 1213}), "deadlock should be a deadlock")
+	test.Assert(t, IsLockTimeout(&mysql.MySQLError{Number: 1205}), "lock wait timeout should be a lock timeout")
+	test.Assert(t, IsConnectionLost(driver.ErrBadConn), "bad connection should be a lost connection")
+	test.Assert(t, IsReadOnly(&mysql.MySQLError{Number: 1836}), "read-only mode should be read-only")
+	test.Assert(t, IsSyntax(&mysql.MySQLError{Number: 1064}), "parse error should be a syntax error")
+	test.Assert(t, !IsDeadlock(&mysql.MySQLError{Number: 1205}), "lock wait timeout should not be a deadlock")
+	test.Assert(t, IsInterrupted(&mysql.MySQLError{Number: 1317}), "query interrupted should be interrupted")
+	test.Assert(t, IsLockTableFull(&mysql.MySQLError{Number: 1206}), "lock table full should be lock table full")
+	test.AssertEquals(t, Deadlock.String(), "deadlock")
+	test.AssertEquals(t, ErrorClass(100).String(), "unknown")
+}
+
+func TestRetryable(t *testing.T) {
+	for _, class := range []ErrorClass{Deadlock, LockTimeout, Interrupted, ConnectionLost} {
+		test.Assert(t, class.Retryable(), fmt.Sprintf("%s should be retryable", class))
+	}
+	for _, class := range []ErrorClass{Unknown, Duplicate, ReadOnly, Syntax, LockTableFull} {
+		test.Assert(t, !class.Retryable(), fmt.Sprintf("%s should be fatal", class))
+	}
+	test.Assert(t, IsRetryable(fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 4031})), "idle timeout should be retryable")
+	test.Assert(t, !IsRetryable(&mysql.MySQLError{Number: 1290}), "read-only should be fatal")
+	test.Assert(t, !IsRetryable(nil), "nil should not be retryable")
+}
+
+func TestClassifySQLiteError(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+
+	err := dbMap.Insert(ctx, &sqliteTestModel{Name: "widget"})
+	test.AssertNotError(t, err, "inserting widget")
+	err = dbMap.Insert(ctx, &sqliteTestModel{Name: "widget"})
+	test.AssertEquals(t, ClassifyError(err), Duplicate)
+
+	_, err = dbMap.ExecContext(ctx, "SELEKT 1")
+	test.AssertEquals(t, ClassifyError(err), Syntax)
+
+	_, err = dbMap.ExecContext(ctx, "SELECT * FROM nonexistent")
+	test.AssertEquals(t, ClassifyError(err), Unknown)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnexpectedRows is returned by UpdateOne, DeleteOne, and ExecExpectingRows
+// when a write affects a number of rows other than the number expected, e.g.
+// an UPDATE whose WHERE clause matched no rows, or more than one. The write is
+// not undone, so when it is made in a transaction, returning the error from
+// the transaction's function rolls it back.
+type ErrUnexpectedRows struct {
+	Op       string
+	Table    string
+	Expected int64
+	Affected int64
+}
+
+func (e ErrUnexpectedRows) Error() string {
+	return fmt.Sprintf("%s %s affected %d rows, expected %d", e.Op, e.Table, e.Affected, e.Expected)
+}
+
+// IsUnexpectedRows returns true if err wraps an ErrUnexpectedRows.
+func IsUnexpectedRows(err error) bool {
+	return errors.As(err, &ErrUnexpectedRows{})
+}
+
+// UpdateOne updates the row of holder, returning an ErrUnexpectedRows unless
+// exactly one row was updated. Since the SA's connections set clientFoundRows,
+// an update which matches the row but changes nothing counts.
+func UpdateOne(ctx context.Context, updater Updater, holder interface{}) error {
+	n, err := updater.Update(ctx, holder)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrUnexpectedRows{Op: "update", Table: fmt.Sprintf("%T", holder), Expected: 1, Affected: n}
+	}
+	return nil
+}
+
+// DeleteOne deletes the row of holder, returning an ErrUnexpectedRows unless
+// exactly one row was deleted.
+func DeleteOne(ctx context.Context, deleter Deleter, holder interface{}) error {
+	n, err := deleter.Delete(ctx, holder)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrUnexpectedRows{Op: "delete", Table: fmt.Sprintf("%T", holder), Expected: 1, Affected: n}
+	}
+	return nil
+}
+
+// ExecExpectingRows executes query, returning an ErrUnexpectedRows unless it
+// affected exactly expected rows.
+func ExecExpectingRows(ctx context.Context, execer Execer, expected int64, query string, args ...interface{}) error {
+	res, err := execer.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errForQuery(query, "exec", err, args)
+	}
+	if n != expected {
+		table := tableFromQuery(query)
+		if table == "" {
+			table = "unknown table"
+		}
+		return ErrUnexpectedRows{Op: "exec", Table: table, Expected: expected, Affected: n}
+	}
+	return nil
+}
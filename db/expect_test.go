@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestExpectingRows(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	w := &sqliteTestModel{Name: "widget"}
+	err := dbMap.Insert(ctx, w)
+	test.AssertNotError(t, err, "inserting widget")
+	err = dbMap.Insert(ctx, &sqliteTestModel{Name: "gadget"})
+	test.AssertNotError(t, err, "inserting gadget")
+
+	w.Value = []byte{1}
+	err = UpdateOne(ctx, dbMap, w)
+	test.AssertNotError(t, err, "updating widget")
+	err = ExecExpectingRows(ctx, dbMap, 2, "UPDATE widgets SET Value = ?", []byte{2})
+	test.AssertNotError(t, err, "updating all widgets")
+
+	// Writes affecting no rows, or too many, are errors.
+	missing := &sqliteTestModel{ID: 100, Name: "missing"}
+	err = UpdateOne(ctx, dbMap, missing)
+	test.Assert(t, IsUnexpectedRows(err), "expected unexpected rows error")
+	var rowsErr ErrUnexpectedRows
+	test.AssertErrorWraps(t, err, &rowsErr)
+	test.AssertDeepEquals(t, rowsErr, ErrUnexpectedRows{Op: "update", Table: "*db.sqliteTestModel", Expected: 1, Affected: 0})
+	err = DeleteOne(ctx, dbMap, missing)
+	test.Assert(t, IsUnexpectedRows(err), "expected unexpected rows error")
+	err = ExecExpectingRows(ctx, dbMap, 1, "UPDATE widgets SET Value = ?", []byte{3})
+	test.AssertErrorWraps(t, err, &rowsErr)
+	test.AssertDeepEquals(t, rowsErr, ErrUnexpectedRows{Op: "exec", Table: "widgets", Expected: 1, Affected: 2})
+	test.AssertEquals(t, err.Error(), "exec widgets affected 2 rows, expected 1")
+
+	// Failed writes return their own errors.
+	err = ExecExpectingRows(ctx, dbMap, 1, "UPDATE doesNotExist SET Value = ?", []byte{3})
+	test.AssertError(t, err, "expected error for missing table")
+	test.Assert(t, !IsUnexpectedRows(err), "failed write shouldn't be an unexpected rows error")
+
+	err = DeleteOne(ctx, dbMap, w)
+	test.AssertNotError(t, err, "deleting widget")
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(1))
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/letsencrypt/borp"
+)
+
+// QueryPlanProblem describes a SELECT whose query plan reads one of the tables
+// registered with SetExplainChecks inefficiently.
+type QueryPlanProblem struct {
+	Query string
+	Table string
+	// Problem is either "full table scan" or "filesort".
+	Problem string
+}
+
+// queryExplainer runs EXPLAIN for each SELECT before it is executed, and
+// reports those whose plans scan or filesort any of tables. A nil
+// *queryExplainer checks nothing.
+type queryExplainer struct {
+	tables map[string]bool
+	report func(QueryPlanProblem)
+}
+
+var (
+	selectRegexp = regexp.MustCompile(`(?i)^\s*select\s`)
+	// sqliteScanRegexp matches the detail of a SQLite query plan step which
+	// reads a whole table, rather than searching it or scanning an index.
+	sqliteScanRegexp = regexp.MustCompile(`^SCAN (?:TABLE )?(\w+)(?: AS \w+)?$`)
+)
+
+// check explains query, using executor and the dialect of dbMap, and reports
+// any problems with its plan. Queries which can't be explained are ignored:
+// executing them will return the error.
+func (e *queryExplainer) check(ctx context.Context, executor borp.SqlExecutor, dbMap *borp.DbMap, query string, args []interface{}) {
+	if e == nil || dbMap == nil || !selectRegexp.MatchString(query) {
+		return
+	}
+	var problems []QueryPlanProblem
+	var err error
+	switch dbMap.Dialect.(type) {
+	case borp.MySQLDialect:
+		problems, err = explainMySQL(ctx, executor, query, args)
+	case borp.SqliteDialect:
+		problems, err = explainSQLite(ctx, executor, query, args)
+	default:
+		return
+	}
+	if err != nil {
+		return
+	}
+	for _, problem := range problems {
+		if e.tables[problem.Table] {
+			problem.Query = query
+			e.report(problem)
+		}
+	}
+}
+
+// explainRows runs the EXPLAIN statement explain and returns each of the rows
+// of its result as a map from column name to value.
+func explainRows(ctx context.Context, executor borp.SqlExecutor, explain string, args []interface{}) ([]map[string]string, error) {
+	rows, err := executor.QueryContext(ctx, explain, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var results []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[string]string, len(columns))
+		for i, column := range columns {
+			result[strings.ToLower(column)] = values[i].String
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// explainMySQL reports the tables which MySQL or MariaDB plans to read in full
+// because no index could be used, and those it plans to filesort.
+func explainMySQL(ctx context.Context, executor borp.SqlExecutor, query string, args []interface{}) ([]QueryPlanProblem, error) {
+	rows, err := explainRows(ctx, executor, "EXPLAIN "+query, args)
+	if err != nil {
+		return nil, err
+	}
+	var problems []QueryPlanProblem
+	for _, row := range rows {
+		if row["type"] == "ALL" && row["possible_keys"] == "" {
+			problems = append(problems, QueryPlanProblem{Table: row["table"], Problem: "full table scan"})
+		}
+		if strings.Contains(row["extra"], "Using filesort") {
+			problems = append(problems, QueryPlanProblem{Table: row["table"], Problem: "filesort"})
+		}
+	}
+	return problems, nil
+}
+
+// explainSQLite reports the tables which SQLite plans to read in full, and
+// sorting in a temporary b-tree, which it reports without a table and so is
+// attributed to the table of the query.
+func explainSQLite(ctx context.Context, executor borp.SqlExecutor, query string, args []interface{}) ([]QueryPlanProblem, error) {
+	rows, err := explainRows(ctx, executor, "EXPLAIN QUERY PLAN "+query, args)
+	if err != nil {
+		return nil, err
+	}
+	var problems []QueryPlanProblem
+	for _, row := range rows {
+		detail := row["detail"]
+		matches := sqliteScanRegexp.FindStringSubmatch(detail)
+		if matches != nil {
+			problems = append(problems, QueryPlanProblem{Table: matches[1], Problem: "full table scan"})
+		}
+		if strings.HasPrefix(detail, "USE TEMP B-TREE FOR ORDER BY") {
+			problems = append(problems, QueryPlanProblem{Table: tableFromQuery(query), Problem: "filesort"})
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestExplainChecks(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	var problems []QueryPlanProblem
+	dbMap.SetExplainChecks([]string{"widgets"}, func(problem QueryPlanProblem) {
+		problems = append(problems, problem)
+	})
+	err := dbMap.Insert(ctx, &sqliteTestModel{Name: "widget"})
+	test.AssertNotError(t, err, "inserting widget")
+
+	// Lookups by an indexed column are fine.
+	var w sqliteTestModel
+	err = dbMap.SelectOne(ctx, &w, "SELECT * FROM widgets WHERE Name = ?", "widget")
+	test.AssertNotError(t, err, "selecting widget by name")
+	test.AssertEquals(t, len(problems), 0)
+
+	// Lookups by an unindexed column scan the table.
+	const scan = "SELECT COUNT(*) FROM widgets WHERE Value = ?"
+	_, err = dbMap.SelectNullInt(ctx, scan, []byte{1})
+	test.AssertNotError(t, err, "counting widgets by value")
+	test.AssertDeepEquals(t, problems, []QueryPlanProblem{{Query: scan, Table: "widgets", Problem: "full table scan"}})
+
+	// Ordering by an unindexed column sorts, and the checks also apply within
+	// transactions.
+	problems = nil
+	const sort = "SELECT * FROM widgets WHERE Name > ? ORDER BY Value"
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		_, err := tx.Select(ctx, &[]sqliteTestModel{}, sort, "a")
+		return err
+	})
+	test.AssertNotError(t, err, "selecting sorted widgets")
+	test.AssertDeepEquals(t, problems, []QueryPlanProblem{{Query: sort, Table: "widgets", Problem: "filesort"}})
+
+	// Tables which aren't registered aren't checked, and neither are queries
+	// which fail.
+	problems = nil
+	_, err = dbMap.SelectNullInt(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE sql = ?", "")
+	test.AssertNotError(t, err, "counting schema objects")
+	_, err = dbMap.SelectNullInt(ctx, "SELECT COUNT(*) FROM doesNotExist")
+	test.AssertError(t, err, "selecting from missing table")
+	test.AssertEquals(t, len(problems), 0)
+}
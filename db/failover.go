@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FailoverConnector is a driver.Connector over an ordered list of hosts, each
+// represented by its own driver.Connector. New connections are made to the
+// current host and, if it can't be connected to, to each of the others in turn,
+// the first of which to succeed becomes the current host. If a failback
+// interval is provided, hosts earlier in the list than the current host are
+// periodically probed, and the first of them to accept a connection becomes the
+// current host again.
+//
+// Only new connections are affected by a change of host: connections already in
+// the pool are discarded by database/sql once they fail, or once they exceed
+// ConnMaxLifetime, which should be set so that the pool moves back to a
+// preferred host after failback.
+type FailoverConnector struct {
+	connectors []driver.Connector
+
+	mu  sync.Mutex
+	cur int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	probing  sync.WaitGroup
+}
+
+// NewFailoverConnector returns a *FailoverConnector over connectors, in order
+// of preference. If failbackInterval is greater than zero, a goroutine probes
+// the preferred hosts at that interval until the connector is closed, which
+// (*sql.DB).Close does.
+func NewFailoverConnector(connectors []driver.Connector, failbackInterval time.Duration) (*FailoverConnector, error) {
+	if len(connectors) == 0 {
+		return nil, errors.New("no connectors provided")
+	}
+	fc := &FailoverConnector{
+		connectors: connectors,
+		stop:       make(chan struct{}),
+	}
+	if failbackInterval > 0 && len(connectors) > 1 {
+		fc.probing.Add(1)
+		go fc.probeLoop(failbackInterval)
+	}
+	return fc, nil
+}
+
+// Connect implements driver.Connector.
+func (fc *FailoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	start := fc.current()
+	var errs []error
+	for i := 0; i < len(fc.connectors); i++ {
+		idx := (start + i) % len(fc.connectors)
+		conn, err := fc.connectors[idx].Connect(ctx)
+		if err == nil {
+			fc.setCurrent(start, idx)
+			return conn, nil
+		}
+		errs = append(errs, fmt.Errorf("connecting to host %d: %w", idx, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Driver implements driver.Connector.
+func (fc *FailoverConnector) Driver() driver.Driver {
+	return fc.connectors[0].Driver()
+}
+
+// Close stops probing for failback, waiting for any probe in progress. It is
+// called by (*sql.DB).Close.
+func (fc *FailoverConnector) Close() error {
+	fc.stopOnce.Do(func() { close(fc.stop) })
+	fc.probing.Wait()
+	return nil
+}
+
+// current returns the index of the host to which new connections are made.
+func (fc *FailoverConnector) current() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.cur
+}
+
+// setCurrent makes idx the current host, unless the current host has changed
+// from prev in the meantime.
+func (fc *FailoverConnector) setCurrent(prev, idx int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.cur == prev {
+		fc.cur = idx
+	}
+}
+
+func (fc *FailoverConnector) probeLoop(interval time.Duration) {
+	defer fc.probing.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-fc.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			fc.probe(ctx)
+			cancel()
+		}
+	}
+}
+
+// probe attempts to connect to each of the hosts preferred to the current host,
+// in order, and makes the first to succeed the current host.
+func (fc *FailoverConnector) probe(ctx context.Context) {
+	cur := fc.current()
+	for idx := 0; idx < cur; idx++ {
+		conn, err := fc.connectors[idx].Connect(ctx)
+		if err != nil {
+			continue
+		}
+		_ = conn.Close()
+		fc.setCurrent(cur, idx)
+		return
+	}
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+var errHostDown = errors.New("host is down")
+
+// fakeHost is a driver.Connector to a private, in-memory SQLite database which
+// can be marked as down.
+type fakeHost struct {
+	down     atomic.Bool
+	connects atomic.Int64
+}
+
+func (h *fakeHost) Connect(ctx context.Context) (driver.Conn, error) {
+	h.connects.Add(1)
+	if h.down.Load() {
+		return nil, errHostDown
+	}
+	return h.Driver().Open(":memory:")
+}
+
+func (h *fakeHost) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+func TestFailoverConnector(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewFailoverConnector(nil, 0)
+	test.AssertError(t, err, "expected error with no connectors")
+
+	primary, secondary, tertiary := &fakeHost{}, &fakeHost{}, &fakeHost{}
+	fc, err := NewFailoverConnector([]driver.Connector{primary, secondary, tertiary}, 0)
+	test.AssertNotError(t, err, "creating connector")
+
+	conn, err := fc.Connect(ctx)
+	test.AssertNotError(t, err, "connecting to primary")
+	_ = conn.Close()
+	test.AssertEquals(t, fc.current(), 0)
+	test.AssertEquals(t, secondary.connects.Load(), int64(0))
+
+	// When the primary is down the secondary is used, and remains the current
+	// host once the primary is back.
+	primary.down.Store(true)
+	conn, err = fc.Connect(ctx)
+	test.AssertNotError(t, err, "failing over to secondary")
+	_ = conn.Close()
+	test.AssertEquals(t, fc.current(), 1)
+	primary.down.Store(false)
+	conn, err = fc.Connect(ctx)
+	test.AssertNotError(t, err, "connecting to secondary")
+	_ = conn.Close()
+	test.AssertEquals(t, fc.current(), 1)
+	test.AssertEquals(t, primary.connects.Load(), int64(2))
+
+	// Hosts are tried in order after the current host, wrapping around.
+	secondary.down.Store(true)
+	tertiary.down.Store(true)
+	conn, err = fc.Connect(ctx)
+	test.AssertNotError(t, err, "failing over to primary")
+	_ = conn.Close()
+	test.AssertEquals(t, fc.current(), 0)
+	test.AssertEquals(t, tertiary.connects.Load(), int64(1))
+
+	// When all hosts are down, each of their errors is returned.
+	primary.down.Store(true)
+	_, err = fc.Connect(ctx)
+	test.AssertErrorIs(t, err, errHostDown)
+	test.AssertContains(t, err.Error(), "connecting to host 2")
+	test.AssertEquals(t, fc.current(), 0)
+
+	// Probing makes the first reachable preferred host current.
+	fc.setCurrent(0, 2)
+	secondary.down.Store(false)
+	fc.probe(ctx)
+	test.AssertEquals(t, fc.current(), 1)
+	primary.down.Store(false)
+	fc.probe(ctx)
+	test.AssertEquals(t, fc.current(), 0)
+}
+
+func TestFailoverConnectorFailback(t *testing.T) {
+	primary, secondary := &fakeHost{}, &fakeHost{}
+	fc, err := NewFailoverConnector([]driver.Connector{primary, secondary}, time.Millisecond)
+	test.AssertNotError(t, err, "creating connector")
+	db := sql.OpenDB(fc)
+
+	primary.down.Store(true)
+	err = db.Ping()
+	test.AssertNotError(t, err, "pinging with primary down")
+	test.AssertEquals(t, fc.current(), 1)
+
+	primary.down.Store(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for fc.current() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	test.AssertEquals(t, fc.current(), 0)
+
+	// Closing the DB stops probing.
+	err = db.Close()
+	test.AssertNotError(t, err, "closing DB")
+	fc.setCurrent(0, 1)
+	connects := primary.connects.Load()
+	time.Sleep(20 * time.Millisecond)
+	test.AssertEquals(t, primary.connects.Load(), connects)
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/letsencrypt/borp"
+)
+
+// FakeMap is a *WrappedMap, usually over a private, in-memory SQLite database,
+// whose operations can also be scripted to fail. It is intended for unit tests
+// of straightforward CRUD paths, which would otherwise require a MySQL
+// container, and of how they handle database errors. See dbtest.NewFakeMap.
+type FakeMap struct {
+	*WrappedMap
+	faults *faultInjector
+}
+
+// NewFakeMap returns a *FakeMap over m, which must not be used directly
+// afterwards.
+func NewFakeMap(m *WrappedMap) *FakeMap {
+	m.faults = &faultInjector{}
+	return &FakeMap{WrappedMap: m, faults: m.faults}
+}
+
+// FailNext causes the next operation named op, performed by the map or any of
+// the transactions it has begun, to fail with err without reaching the
+// database. The error is wrapped as an error returned by the database would
+// be. The operations are named as in ErrDatabaseOp: "get", "insert",
+// "update", "delete", "select" (Select, SelectNullInt, SelectStr, and
+// QueryContext), "select one", "exec", and "begin transaction". Calls for the
+// same op are queued, so that consecutive operations fail in turn.
+func (f *FakeMap) FailNext(op string, err error) {
+	f.faults.mu.Lock()
+	defer f.faults.mu.Unlock()
+	if f.faults.next == nil {
+		f.faults.next = make(map[string][]error)
+	}
+	f.faults.next[op] = append(f.faults.next[op], err)
+}
+
+// faultInjector holds the errors scripted by FakeMap.FailNext. A nil
+// *faultInjector injects no errors.
+type faultInjector struct {
+	mu   sync.Mutex
+	next map[string][]error
+}
+
+// fail returns the next error scripted for op, if any.
+func (f *faultInjector) fail(op string) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	errs := f.next[op]
+	if len(errs) == 0 {
+		return nil
+	}
+	f.next[op] = errs[1:]
+	return errs[0]
+}
+
+// wrap returns executor, with the scripted errors injected into its
+// operations.
+func (f *faultInjector) wrap(executor borp.SqlExecutor) borp.SqlExecutor {
+	if f == nil {
+		return executor
+	}
+	return faultyExecutor{SqlExecutor: executor, faults: f}
+}
+
+// faultyExecutor is a borp.SqlExecutor whose operations can fail with errors
+// scripted by FakeMap.FailNext.
+type faultyExecutor struct {
+	borp.SqlExecutor
+	faults *faultInjector
+}
+
+func (fe faultyExecutor) Get(ctx context.Context, holder interface{}, keys ...interface{}) (interface{}, error) {
+	err := fe.faults.fail("get")
+	if err != nil {
+		return nil, err
+	}
+	return fe.SqlExecutor.Get(ctx, holder, keys...)
+}
+
+func (fe faultyExecutor) Insert(ctx context.Context, list ...interface{}) error {
+	err := fe.faults.fail("insert")
+	if err != nil {
+		return err
+	}
+	return fe.SqlExecutor.Insert(ctx, list...)
+}
+
+func (fe faultyExecutor) Update(ctx context.Context, list ...interface{}) (int64, error) {
+	err := fe.faults.fail("update")
+	if err != nil {
+		return 0, err
+	}
+	return fe.SqlExecutor.Update(ctx, list...)
+}
+
+func (fe faultyExecutor) Delete(ctx context.Context, list ...interface{}) (int64, error) {
+	err := fe.faults.fail("delete")
+	if err != nil {
+		return 0, err
+	}
+	return fe.SqlExecutor.Delete(ctx, list...)
+}
+
+func (fe faultyExecutor) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	err := fe.faults.fail("select")
+	if err != nil {
+		return nil, err
+	}
+	return fe.SqlExecutor.Select(ctx, holder, query, args...)
+}
+
+func (fe faultyExecutor) SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
+	err := fe.faults.fail("select one")
+	if err != nil {
+		return err
+	}
+	return fe.SqlExecutor.SelectOne(ctx, holder, query, args...)
+}
+
+func (fe faultyExecutor) SelectNullInt(ctx context.Context, query string, args ...interface{}) (sql.NullInt64, error) {
+	err := fe.faults.fail("select")
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	return fe.SqlExecutor.SelectNullInt(ctx, query, args...)
+}
+
+func (fe faultyExecutor) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
+	err := fe.faults.fail("select")
+	if err != nil {
+		return "", err
+	}
+	return fe.SqlExecutor.SelectStr(ctx, query, args...)
+}
+
+func (fe faultyExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	err := fe.faults.fail("exec")
+	if err != nil {
+		return nil, err
+	}
+	return fe.SqlExecutor.ExecContext(ctx, query, args...)
+}
+
+func (fe faultyExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	err := fe.faults.fail("select")
+	if err != nil {
+		return nil, err
+	}
+	return fe.SqlExecutor.QueryContext(ctx, query, args...)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/letsencrypt/borp"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestFakeMap(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewSQLiteMap(ctx, ":memory:", func(dbMap *borp.DbMap) {
+		dbMap.AddTableWithName(sqliteTestModel{}, "widgets").SetKeys(true, "ID")
+	})
+	test.AssertNotError(t, err, "creating SQLite map")
+	fake := NewFakeMap(m)
+	defer func() { _ = fake.Close() }()
+	var _ DatabaseMap = fake
+	var _ Executor = fake
+
+	// Without scripted errors it behaves like a database.
+	w := &sqliteTestModel{Name: "widget"}
+	err = fake.Insert(ctx, w)
+	test.AssertNotError(t, err, "inserting widget")
+	got, err := fake.Get(ctx, sqliteTestModel{}, w.ID)
+	test.AssertNotError(t, err, "getting widget")
+	test.AssertDeepEquals(t, got, w)
+
+	// Scripted errors are returned, wrapped, in the order they were scripted,
+	// by the next operations of their kind only.
+	oops, uhoh := errors.New("oops"), errors.New("uh oh")
+	fake.FailNext("get", oops)
+	fake.FailNext("get", uhoh)
+	err = fake.Insert(ctx, &sqliteTestModel{Name: "gadget"})
+	test.AssertNotError(t, err, "inserting gadget")
+	_, err = fake.Get(ctx, sqliteTestModel{}, w.ID)
+	test.AssertErrorIs(t, err, oops)
+	var dbOpErr ErrDatabaseOp
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Op, "get")
+	_, err = fake.Get(ctx, sqliteTestModel{}, w.ID)
+	test.AssertErrorIs(t, err, uhoh)
+	_, err = fake.Get(ctx, sqliteTestModel{}, w.ID)
+	test.AssertNotError(t, err, "getting widget")
+
+	// Errors can be scripted for operations within transactions, and for
+	// beginning them.
+	fake.FailNext("select", oops)
+	err = WithTransaction(ctx, fake, func(tx Executor) error {
+		err := tx.Insert(ctx, &sqliteTestModel{Name: "rolled back"})
+		if err != nil {
+			return err
+		}
+		_, err = tx.Select(ctx, &[]sqliteTestModel{}, "SELECT * FROM widgets")
+		return err
+	})
+	test.AssertErrorIs(t, err, oops)
+	count, err := fake.SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "counting widgets")
+	test.AssertEquals(t, count.Int64, int64(2))
+
+	fake.FailNext("begin transaction", oops)
+	_, err = fake.BeginTx(ctx)
+	test.AssertErrorIs(t, err, oops)
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/letsencrypt/borp"
+)
+
+type readYourWritesKey struct{}
+
+// readYourWrites holds the GTID set captured after the most recent write made
+// with a context returned by WithReadYourWrites.
+type readYourWrites struct {
+	mu   sync.Mutex
+	gtid string
+	// lost is true if capturing the GTID set of a write failed, so that reads
+	// can't be made to wait for it.
+	lost bool
+}
+
+// WithReadYourWrites returns a copy of ctx in which the operations of a
+// request see its earlier writes, even when reads are routed to a replica.
+// Writes made with it by a map with GTID capture enabled (see SetGTIDCapture)
+// record the primary's executed GTID set, and reads made with it by a map
+// with GTID waits enabled (see SetGTIDWait) first wait for the replica to have
+// executed that set. Contexts derived from it share its GTID set, so it should
+// be called once per request, e.g. at the start of an RPC handler.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, &readYourWrites{})
+}
+
+// gtidCapturer records the primary's executed GTID set, after each write, in
+// the context of the write. A nil *gtidCapturer records nothing.
+type gtidCapturer struct {
+	primary borp.SqlExecutor
+}
+
+// capture records the GTID set, if ctx is from WithReadYourWrites. It is called
+// after each write which isn't part of a transaction, and after each
+// transaction commits. A failure to capture isn't the write's failure, so
+// isn't returned: instead, the reads which should wait for the write fail.
+func (c *gtidCapturer) capture(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	ryw, ok := ctx.Value(readYourWritesKey{}).(*readYourWrites)
+	if !ok {
+		return
+	}
+	// The global set includes the GTIDs of every transaction committed on the
+	// primary, so it's a superset of the write's, whichever pooled connection
+	// made it.
+	gtid, err := c.primary.SelectStr(ctx, "SELECT @@GLOBAL.gtid_executed")
+	ryw.mu.Lock()
+	defer ryw.mu.Unlock()
+	if err != nil {
+		ryw.lost = true
+		return
+	}
+	ryw.gtid = gtid
+}
+
+// errGTIDLost is returned by reads which can't wait for an earlier write of
+// their request, because its GTID set couldn't be captured.
+var errGTIDLost = errors.New("GTID set of an earlier write is unknown")
+
+// gtidWaiter makes each read wait for the replica to execute the GTID set of
+// the latest write in its context. A nil *gtidWaiter doesn't wait.
+type gtidWaiter struct {
+	timeout time.Duration
+}
+
+// wait blocks until executor's database has executed the GTID set recorded in
+// ctx, if any, or the timeout elapses, which is an error.
+func (w *gtidWaiter) wait(ctx context.Context, executor borp.SqlExecutor) error {
+	if w == nil {
+		return nil
+	}
+	ryw, ok := ctx.Value(readYourWritesKey{}).(*readYourWrites)
+	if !ok {
+		return nil
+	}
+	ryw.mu.Lock()
+	gtid, lost := ryw.gtid, ryw.lost
+	ryw.mu.Unlock()
+	if lost {
+		return ErrDatabaseOp{Op: "wait for GTID", Table: "unknown table", Err: errGTIDLost}
+	}
+	if gtid == "" {
+		return nil
+	}
+	// WAIT_FOR_EXECUTED_GTID_SET returns 0 once the set has been executed, and
+	// 1 if the timeout, in seconds, elapses first.
+	result, err := executor.SelectNullInt(ctx, "SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?)", gtid, w.timeout.Seconds())
+	if err == nil && result.Int64 != 0 {
+		err = fmt.Errorf("replica did not execute GTID set %q within %s", gtid, w.timeout)
+	}
+	if err != nil {
+		return ErrDatabaseOp{Op: "wait for GTID", Table: "unknown table", Err: err}
+	}
+	return nil
+}
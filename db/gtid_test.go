@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// fakeGTIDPrimary reports an executed GTID set, which grows with each write.
+type fakeGTIDPrimary struct {
+	MockSqlExecutor
+	writes  int
+	gtidErr error
+}
+
+func (p *fakeGTIDPrimary) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	p.writes++
+	return driver.RowsAffected(1), nil
+}
+
+func (p *fakeGTIDPrimary) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
+	if p.gtidErr != nil {
+		return "", p.gtidErr
+	}
+	return fmt.Sprintf("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-%d", p.writes), nil
+}
+
+// fakeGTIDReplica records the GTID sets waited for, and returns waitResult.
+type fakeGTIDReplica struct {
+	MockSqlExecutor
+	waitedFor  []interface{}
+	waitResult int64
+}
+
+func (r *fakeGTIDReplica) SelectNullInt(ctx context.Context, query string, args ...interface{}) (sql.NullInt64, error) {
+	r.waitedFor = append(r.waitedFor, args[0])
+	return sql.NullInt64{Int64: r.waitResult, Valid: true}, nil
+}
+
+func (r *fakeGTIDReplica) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
+	return "widget", nil
+}
+
+func TestReadYourWrites(t *testing.T) {
+	primary := &fakeGTIDPrimary{}
+	replica := &fakeGTIDReplica{}
+	writer := WrappedExecutor{sqlExecutor: primary, instrumentation: instrumentation{gtidCapture: &gtidCapturer{primary: primary}}}
+	reader := WrappedExecutor{sqlExecutor: replica, instrumentation: instrumentation{gtidWait: &gtidWaiter{timeout: time.Second}}}
+
+	// Without WithReadYourWrites, reads don't wait.
+	_, err := writer.ExecContext(context.Background(), "UPDATE widgets SET Value = ?", []byte{1})
+	test.AssertNotError(t, err, "writing")
+	_, err = reader.SelectStr(context.Background(), "SELECT Name FROM widgets")
+	test.AssertNotError(t, err, "reading")
+	test.AssertEquals(t, len(replica.waitedFor), 0)
+
+	// Nor before the request has written anything.
+	ctx := WithReadYourWrites(context.Background())
+	_, err = reader.SelectStr(ctx, "SELECT Name FROM widgets")
+	test.AssertNotError(t, err, "reading")
+	test.AssertEquals(t, len(replica.waitedFor), 0)
+
+	// Reads wait for the request's latest write.
+	_, err = writer.ExecContext(ctx, "UPDATE widgets SET Value = ?", []byte{2})
+	test.AssertNotError(t, err, "writing")
+	_, err = writer.ExecContext(ctx, "UPDATE widgets SET Value = ?", []byte{3})
+	test.AssertNotError(t, err, "writing")
+	_, err = reader.SelectStr(ctx, "SELECT Name FROM widgets")
+	test.AssertNotError(t, err, "reading")
+	test.AssertDeepEquals(t, replica.waitedFor, []interface{}{"3e11fa47-71ca-11e1-9e33-c80aa9429562:1-3"})
+
+	// Reads fail if the replica doesn't catch up.
+	replica.waitResult = 1
+	_, err = reader.SelectStr(ctx, "SELECT Name FROM widgets")
+	test.AssertError(t, err, "expected error when the wait times out")
+	var dbOpErr ErrDatabaseOp
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Op, "wait for GTID")
+
+	// Or if a write's GTID set couldn't be captured.
+	replica.waitResult = 0
+	primary.gtidErr = errors.New("oops")
+	_, err = writer.ExecContext(ctx, "UPDATE widgets SET Value = ?", []byte{4})
+	test.AssertNotError(t, err, "writes succeed even if capture fails")
+	_, err = reader.SelectStr(ctx, "SELECT Name FROM widgets")
+	test.AssertErrorIs(t, err, errGTIDLost)
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/letsencrypt/borp"
+)
+
+// guard is called before each operation of a guardedExecutor. If it returns an
+// error the operation fails with it, and otherwise the result of the operation
+// is passed to the returned function once it completes.
+type guard func(ctx context.Context) (func(error), error)
+
+// guardedExecutor is a borp.SqlExecutor whose operations are each preceded by
+// its guard, e.g. to fail fast while a circuit breaker is open.
+// QueryRowContext, which can't return an error of its own, isn't guarded.
+type guardedExecutor struct {
+	borp.SqlExecutor
+	guard guard
+}
+
+func (ge guardedExecutor) Get(ctx context.Context, holder interface{}, keys ...interface{}) (interface{}, error) {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ge.SqlExecutor.Get(ctx, holder, keys...)
+	done(err)
+	return res, err
+}
+
+func (ge guardedExecutor) Insert(ctx context.Context, list ...interface{}) error {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return err
+	}
+	err = ge.SqlExecutor.Insert(ctx, list...)
+	done(err)
+	return err
+}
+
+func (ge guardedExecutor) Update(ctx context.Context, list ...interface{}) (int64, error) {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := ge.SqlExecutor.Update(ctx, list...)
+	done(err)
+	return n, err
+}
+
+func (ge guardedExecutor) Delete(ctx context.Context, list ...interface{}) (int64, error) {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := ge.SqlExecutor.Delete(ctx, list...)
+	done(err)
+	return n, err
+}
+
+func (ge guardedExecutor) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ge.SqlExecutor.Select(ctx, holder, query, args...)
+	done(err)
+	return res, err
+}
+
+func (ge guardedExecutor) SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return err
+	}
+	err = ge.SqlExecutor.SelectOne(ctx, holder, query, args...)
+	done(err)
+	return err
+}
+
+func (ge guardedExecutor) SelectNullInt(ctx context.Context, query string, args ...interface{}) (sql.NullInt64, error) {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	res, err := ge.SqlExecutor.SelectNullInt(ctx, query, args...)
+	done(err)
+	return res, err
+}
+
+func (ge guardedExecutor) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return "", err
+	}
+	res, err := ge.SqlExecutor.SelectStr(ctx, query, args...)
+	done(err)
+	return res, err
+}
+
+func (ge guardedExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ge.SqlExecutor.ExecContext(ctx, query, args...)
+	done(err)
+	return res, err
+}
+
+func (ge guardedExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	done, err := ge.guard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ge.SqlExecutor.QueryContext(ctx, query, args...)
+	done(err)
+	return res, err
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// checkedDB is the subset of a map's methods a Checker uses.
+type checkedDB interface {
+	OneSelector
+	Queryer
+}
+
+// Checker periodically checks that a database responds and, if it's a replica,
+// how far it lags behind its primary, so that readiness probes, e.g. the
+// Health method of a gRPC service (see grpc.checker), and read routing can
+// depend on the result without querying the database themselves.
+type Checker struct {
+	db       checkedDB
+	interval time.Duration
+	// maxLag is the most replication lag a healthy replica may have, or zero
+	// if the database isn't a replica.
+	maxLag time.Duration
+	// lagQuery returns the replica's status, whose Seconds_Behind_Source (or,
+	// on MariaDB, Seconds_Behind_Master) column is its lag.
+	lagQuery string
+	clk      clock.Clock
+
+	up  prometheus.Gauge
+	lag prometheus.Gauge
+
+	mu        sync.Mutex
+	err       error
+	lastLag   time.Duration
+	checkedAt time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	checking sync.WaitGroup
+}
+
+// NewChecker returns a *Checker of db, which checks it every interval once
+// started. If maxLag is non-zero db is a replica, which is unhealthy if its
+// replication is stopped or lags by more than maxLag, as reported by SHOW
+// REPLICA STATUS, which requires MySQL 8.0.22 or MariaDB 10.5.1 and the
+// REPLICATION CLIENT privilege. If stats is non-nil, the results of its checks
+// are exported.
+func NewChecker(db checkedDB, interval, maxLag time.Duration, stats prometheus.Registerer) (*Checker, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("health check interval must be positive, got %s", interval)
+	}
+	up := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "db_health_up",
+		Help: "Whether the most recent health check of the database succeeded",
+	})
+	lag := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "db_replication_lag_seconds",
+		Help: "Replication lag of the database, as of its most recent health check",
+	})
+	if stats != nil {
+		for _, c := range []prometheus.Collector{up, lag} {
+			err := stats.Register(c)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Checker{
+		db:       db,
+		interval: interval,
+		maxLag:   maxLag,
+		lagQuery: "SHOW REPLICA STATUS",
+		clk:      clock.New(),
+		up:       up,
+		lag:      lag,
+		err:      errors.New("database has not been checked yet"),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Start checks the database immediately and then, in a goroutine, every
+// interval until Stop is called.
+func (c *Checker) Start() {
+	c.runCheck()
+	c.checking.Add(1)
+	go func() {
+		defer c.checking.Done()
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				c.runCheck()
+			}
+		}
+	}()
+}
+
+// Stop stops checking, waiting for any check in progress.
+func (c *Checker) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.checking.Wait()
+}
+
+// runCheck checks the database, within the interval, and records the result.
+func (c *Checker) runCheck() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.interval)
+	defer cancel()
+	lag, err := c.Check(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+	c.lastLag = lag
+	c.checkedAt = c.clk.Now()
+	if err != nil {
+		c.up.Set(0)
+	} else {
+		c.up.Set(1)
+	}
+	c.lag.Set(lag.Seconds())
+}
+
+// Check checks the database now, returning its replication lag, which is zero
+// if it isn't a replica, and an error if it's unhealthy. It doesn't affect the
+// results reported by Health and Lag.
+func (c *Checker) Check(ctx context.Context) (time.Duration, error) {
+	err := c.db.SelectOne(ctx, new(int), "SELECT 1")
+	if err != nil {
+		return 0, err
+	}
+	if c.maxLag == 0 {
+		return 0, nil
+	}
+	lag, err := c.replicationLag(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if lag > c.maxLag {
+		return lag, fmt.Errorf("replica lags by %s, more than %s", lag, c.maxLag)
+	}
+	return lag, nil
+}
+
+// replicationLag returns the current lag of the replica.
+func (c *Checker) replicationLag(ctx context.Context) (time.Duration, error) {
+	rows, err := c.db.QueryContext(ctx, c.lagQuery)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("reading replica status columns: %w", err)
+	}
+	if !rows.Next() {
+		err = rows.Err()
+		if err != nil {
+			return 0, fmt.Errorf("reading replica status: %w", err)
+		}
+		return 0, errors.New("database is not a replica")
+	}
+	// The status has dozens of columns, which vary by version, so scan them
+	// all and pick out the one we need.
+	var seconds sql.NullInt64
+	dest := make([]interface{}, len(columns))
+	found := false
+	for i, column := range columns {
+		if column == "Seconds_Behind_Source" || column == "Seconds_Behind_Master" {
+			dest[i] = &seconds
+			found = true
+		} else {
+			dest[i] = new(sql.RawBytes)
+		}
+	}
+	if !found {
+		return 0, errors.New("replica status has no Seconds_Behind_Source column")
+	}
+	err = rows.Scan(dest...)
+	if err != nil {
+		return 0, fmt.Errorf("reading replica status: %w", err)
+	}
+	if !seconds.Valid {
+		return 0, errors.New("replication is not running")
+	}
+	return time.Duration(seconds.Int64) * time.Second, nil
+}
+
+// Health returns the error of the most recent check, if any, or an error if
+// the database hasn't been checked for more than three intervals, e.g.
+// because checks are hanging. It implements the grpc.checker interface.
+func (c *Checker) Health(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil && c.clk.Since(c.checkedAt) > 3*c.interval {
+		return fmt.Errorf("database was last checked at %s", c.checkedAt)
+	}
+	return c.err
+}
+
+// Lag returns the replication lag found by the most recent check.
+func (c *Checker) Lag() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastLag
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+
+	_, err := NewChecker(dbMap, 0, 0, nil)
+	test.AssertError(t, err, "zero interval should be rejected")
+
+	// A primary is healthy if it responds.
+	primary, err := NewChecker(dbMap, time.Second, 0, prometheus.NewRegistry())
+	test.AssertNotError(t, err, "creating checker")
+	test.AssertError(t, primary.Health(ctx), "unchecked database should be unhealthy")
+	primary.Start()
+	defer primary.Stop()
+	test.AssertNotError(t, primary.Health(ctx), "checking primary")
+	test.AssertMetricWithLabelsEquals(t, primary.up, prometheus.Labels{}, 1)
+
+	// A replica is unhealthy if it lags too far, or isn't replicating.
+	replica, err := NewChecker(dbMap, time.Second, 10*time.Second, prometheus.NewRegistry())
+	test.AssertNotError(t, err, "creating checker")
+	clk := clock.NewFake()
+	replica.clk = clk
+	for _, tc := range []struct {
+		lagQuery string
+		lag      time.Duration
+		healthy  bool
+	}{
+		{"SELECT 'Waiting for source to send event' AS Replica_IO_State, 7 AS Seconds_Behind_Source", 7 * time.Second, true},
+		{"SELECT 3 AS Seconds_Behind_Master", 3 * time.Second, true},
+		{"SELECT 11 AS Seconds_Behind_Source", 11 * time.Second, false},
+		{"SELECT NULL AS Seconds_Behind_Source", 0, false},
+		{"SELECT 1 AS Seconds_Behind_Source WHERE 1 = 0", 0, false},
+		{"SELECT 1 AS Replica_IO_State", 0, false},
+	} {
+		replica.lagQuery = tc.lagQuery
+		replica.runCheck()
+		err = replica.Health(ctx)
+		test.AssertEquals(t, err == nil, tc.healthy)
+		test.AssertEquals(t, replica.Lag(), tc.lag)
+		test.AssertMetricWithLabelsEquals(t, replica.lag, prometheus.Labels{}, tc.lag.Seconds())
+		if tc.healthy {
+			test.AssertMetricWithLabelsEquals(t, replica.up, prometheus.Labels{}, 1)
+		} else {
+			test.AssertMetricWithLabelsEquals(t, replica.up, prometheus.Labels{}, 0)
+		}
+	}
+
+	// A database whose checks stall is unhealthy.
+	replica.lagQuery = "SELECT 0 AS Seconds_Behind_Source"
+	replica.runCheck()
+	test.AssertNotError(t, replica.Health(ctx), "checking replica")
+	clk.Add(4 * time.Second)
+	test.AssertError(t, replica.Health(ctx), "stale check should be unhealthy")
+}
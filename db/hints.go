@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// selectKeywordRegexp matches the SELECT keyword which begins a query, after
+// which MySQL requires optimizer hints to be placed.
+var selectKeywordRegexp = regexp.MustCompile(`(?i)^\s*select\b`)
+
+// hintExecutionTime returns query with a MAX_EXECUTION_TIME optimizer hint
+// added, limiting its execution on the server to the time remaining until the
+// deadline of ctx, if query is a SELECT and ctx has a deadline. The server then
+// aborts a runaway read even if the cancellation of ctx can't reach it, e.g.
+// because the driver is blocked or the connection to it was lost. Queries
+// which already have the hint are unchanged.
+func (i instrumentation) hintExecutionTime(ctx context.Context, query string) string {
+	if !i.executionTimeHints {
+		return query
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return query
+	}
+	loc := selectKeywordRegexp.FindStringIndex(query)
+	if loc == nil || strings.Contains(strings.ToUpper(query), "MAX_EXECUTION_TIME") {
+		return query
+	}
+	// The hint is in whole milliseconds, and zero would mean no limit. Round
+	// up so that the server doesn't abort a query the client would still
+	// wait for.
+	ms := (time.Until(deadline) + time.Millisecond - 1).Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return fmt.Sprintf("%s /*+ MAX_EXECUTION_TIME(%d) */%s", query[:loc[1]], ms, query[loc[1]:])
+}
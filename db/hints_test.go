@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// selectRecorder records the queries passed to SelectStr.
+type selectRecorder struct {
+	MockSqlExecutor
+	queries []string
+}
+
+func (r *selectRecorder) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
+	r.queries = append(r.queries, query)
+	return "", nil
+}
+
+var hintRegexp = regexp.MustCompile(`MAX_EXECUTION_TIME\((\d+)\)`)
+
+// assertHinted checks that got is want with a MAX_EXECUTION_TIME hint of at
+// most max milliseconds, allowing for the time taken by the test, replaced by
+// {hint}.
+func assertHinted(t *testing.T, got, want string, max int) {
+	t.Helper()
+	matches := hintRegexp.FindStringSubmatch(got)
+	test.Assert(t, matches != nil, "expected hint in "+got)
+	ms, err := strconv.Atoi(matches[1])
+	test.AssertNotError(t, err, "parsing hint")
+	test.Assert(t, ms <= max && ms > max-100, fmt.Sprintf("hint of %dms, expected about %dms", ms, max))
+	test.AssertEquals(t, strings.Replace(got, matches[0], "MAX_EXECUTION_TIME({hint})", 1), want)
+}
+
+func TestHintExecutionTime(t *testing.T) {
+	ctx := context.Background()
+	deadlineCtx, cancel := context.WithDeadline(ctx, time.Now().Add(1500*time.Millisecond))
+	defer cancel()
+	const query = "SELECT Name FROM widgets WHERE ID = ?"
+
+	var disabled instrumentation
+	test.AssertEquals(t, disabled.hintExecutionTime(deadlineCtx, query), query)
+
+	i := instrumentation{executionTimeHints: true}
+	test.AssertEquals(t, i.hintExecutionTime(ctx, query), query)
+	assertHinted(t, i.hintExecutionTime(deadlineCtx, query), "SELECT /*+ MAX_EXECUTION_TIME({hint}) */ Name FROM widgets WHERE ID = ?", 1500)
+	assertHinted(t, i.hintExecutionTime(deadlineCtx, "  select COUNT(*) FROM widgets"), "  select /*+ MAX_EXECUTION_TIME({hint}) */ COUNT(*) FROM widgets", 1500)
+
+	// Only SELECTs without a hint are hinted.
+	for _, unhinted := range []string{
+		"UPDATE widgets SET Name = ?",
+		"SELECTED",
+		"SELECT /*+ MAX_EXECUTION_TIME(10) */ Name FROM widgets",
+	} {
+		test.AssertEquals(t, i.hintExecutionTime(deadlineCtx, unhinted), unhinted)
+	}
+
+	// A passed deadline still limits execution.
+	pastCtx, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+	test.AssertEquals(t, i.hintExecutionTime(pastCtx, query), "SELECT /*+ MAX_EXECUTION_TIME(1) */ Name FROM widgets WHERE ID = ?")
+
+	// The hint reflects the default timeout of the operation's class.
+	recorder := &selectRecorder{}
+	timeouts, err := newQueryTimeouts(QueryTimeouts{Read: 2 * time.Second}, nil)
+	test.AssertNotError(t, err, "creating timeouts")
+	we := WrappedExecutor{sqlExecutor: recorder, instrumentation: instrumentation{timeouts: timeouts, executionTimeHints: true}}
+	_, err = we.SelectStr(ctx, query, 1)
+	test.AssertNotError(t, err, "selecting")
+	test.AssertEquals(t, len(recorder.queries), 1)
+	assertHinted(t, recorder.queries[0], "SELECT /*+ MAX_EXECUTION_TIME({hint}) */ Name FROM widgets WHERE ID = ?", 2000)
+
+	// Hinted queries are still valid SQL.
+	dbMap := testSQLiteMap(t)
+	dbMap.SetMaxExecutionTimeHints()
+	_, err = dbMap.SelectNullInt(deadlineCtx, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "selecting with hint")
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// InsertReturningID inserts holder, a struct whose table's auto-increment key
+// is its ID field, as is the case for Boulder's models, and returns the ID the
+// database assigned it. borp sets the ID of holder using the result of the
+// INSERT, so no follow-up query is needed. Errors are wrapped in ErrDatabaseOp,
+// if inserter hasn't already, including when holder has no integer ID field or
+// its ID remains unset because the table's key isn't auto-increment.
+func InsertReturningID[T any](ctx context.Context, inserter Inserter, holder *T) (int64, error) {
+	if holder == nil {
+		return 0, errForOp("insert", errors.New("nil holder"), []interface{}{holder})
+	}
+	var idField reflect.Value
+	v := reflect.ValueOf(holder).Elem()
+	if v.Kind() == reflect.Struct {
+		idField = v.FieldByName("ID")
+	}
+	var id func() int64
+	switch idField.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		id = idField.Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		id = func() int64 { return int64(idField.Uint()) }
+	default:
+		return 0, errForOp("insert", fmt.Errorf("%T has no integer ID field", holder), []interface{}{holder})
+	}
+
+	err := inserter.Insert(ctx, holder)
+	if err != nil {
+		var dbOpErr ErrDatabaseOp
+		if errors.As(err, &dbOpErr) {
+			return 0, err
+		}
+		return 0, errForOp("insert", err, []interface{}{holder})
+	}
+	if id() == 0 {
+		return 0, errForOp("insert", fmt.Errorf("no ID was assigned to %T: is its key auto-increment?", holder), []interface{}{holder})
+	}
+	return id(), nil
+}
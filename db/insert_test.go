@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/letsencrypt/borp"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// failingInserter fails every Insert with an unwrapped error.
+type failingInserter struct{}
+
+func (failingInserter) Insert(context.Context, ...interface{}) error {
+	return errors.New("oops")
+}
+
+func TestInsertReturningID(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+
+	id, err := InsertReturningID(ctx, dbMap, &sqliteTestModel{Name: "widget"})
+	test.AssertNotError(t, err, "inserting widget")
+	test.AssertEquals(t, id, int64(1))
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		id, err = InsertReturningID(ctx, tx, &sqliteTestModel{Name: "gadget"})
+		return err
+	})
+	test.AssertNotError(t, err, "inserting gadget in transaction")
+	test.AssertEquals(t, id, int64(2))
+
+	// Errors are wrapped.
+	var dbOpErr ErrDatabaseOp
+	_, err = InsertReturningID(ctx, dbMap, &sqliteTestModel{Name: "widget"})
+	test.Assert(t, IsDuplicate(err), "expected duplicate error")
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	_, err = InsertReturningID(ctx, failingInserter{}, &sqliteTestModel{Name: "widget"})
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Op, "insert")
+
+	// Holders without an auto-increment ID are an error.
+	_, err = InsertReturningID(ctx, dbMap, (*sqliteTestModel)(nil))
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	type noID struct{ Name string }
+	_, err = InsertReturningID(ctx, dbMap, &noID{Name: "widget"})
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertContains(t, err.Error(), "no integer ID field")
+	notStruct := int64(0)
+	_, err = InsertReturningID(ctx, dbMap, &notStruct)
+	test.AssertContains(t, err.Error(), "no integer ID field")
+
+	manual, err := NewSQLiteMap(ctx, ":memory:", func(dbMap *borp.DbMap) {
+		dbMap.AddTableWithName(sqliteTestModel{}, "widgets").SetKeys(false, "ID")
+	})
+	test.AssertNotError(t, err, "creating SQLite map")
+	defer func() { _ = manual.Close() }()
+	_, err = InsertReturningID(ctx, manual, &sqliteTestModel{Name: "widget"})
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertContains(t, err.Error(), "auto-increment")
+}
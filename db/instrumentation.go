@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/letsencrypt/borp"
+)
+
+// instrumentation is shared by a WrappedMap and the WrappedTransactions and
+// WrappedExecutors derived from it. Any of its fields may be nil, or false, in
+// which case that instrumentation is skipped.
+type instrumentation struct {
+	// tables is used to find the tables mapped to the holders of borp
+	// operations.
+	tables *borp.DbMap
+
+	timeouts  *queryTimeouts
+	metrics   *queryMetrics
+	tracer    *queryTracer
+	commenter *queryCommenter
+	explainer *queryExplainer
+	faults    *faultInjector
+	breaker   *circuitBreaker
+	limiter   *concurrencyLimiter
+
+	gtidCapture *gtidCapturer
+	gtidWait    *gtidWaiter
+
+	// executionTimeHints, if true, adds MAX_EXECUTION_TIME hints to SELECTs.
+	executionTimeHints bool
+}
+
+type queryNameKey struct{}
+
+// WithQueryName returns a copy of ctx carrying name, a logical name for the
+// operations performed with it, e.g. "GetOrderByID". It labels their metrics
+// and names their spans, so that they can be grouped by intent rather than by
+// their table alone. Since every name is a label value, names must come from
+// a small, fixed set, never from a request.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+// queryName returns the name attached to ctx by WithQueryName, or "" if none.
+func queryName(ctx context.Context) string {
+	name, _ := ctx.Value(queryNameKey{}).(string)
+	return name
+}
+
+// begin applies the default timeout of class to ctx and starts a span for the
+// named operation on table. The returned function must be called with the
+// result of the operation once it completes, and the number of rows it
+// affected or -1 if it isn't a write.
+func (i instrumentation) begin(ctx context.Context, op string, class queryClass, table string) (context.Context, func(rows int64, err error)) {
+	name := queryName(ctx)
+	ctx, span := i.tracer.start(ctx, op, table, name)
+	ctx, done := i.timeouts.apply(ctx, class)
+	start := time.Now()
+	return ctx, func(rows int64, err error) {
+		done(err)
+		i.metrics.observe(op, table, name, start, err)
+		if err == nil && rows >= 0 {
+			i.metrics.observeRows(op, table, name, rows)
+		}
+		i.tracer.end(span, rows, err)
+	}
+}
+
+// labelsTables returns true if the table of each operation is needed to label
+// its metrics or span.
+func (i instrumentation) labelsTables() bool {
+	return i.metrics != nil || i.tracer != nil
+}
+
+// tableForHolder returns the name of the table mapped to the type of holder, or
+// "unknown" if there is none.
+func (i instrumentation) tableForHolder(holder interface{}) string {
+	if !i.labelsTables() {
+		return ""
+	}
+	t := reflect.TypeOf(holder)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || i.tables == nil {
+		return "unknown"
+	}
+	table, err := i.tables.TableFor(t, false)
+	if err != nil {
+		return "unknown"
+	}
+	return table.TableName
+}
+
+// tableForList returns the name of the table mapped to the type of the first
+// element of list, or "unknown" if there is none.
+func (i instrumentation) tableForList(list []interface{}) string {
+	if len(list) == 0 {
+		return i.tableForHolder(nil)
+	}
+	return i.tableForHolder(list[0])
+}
+
+// tableForQuery returns the name of the table of query, or "unknown" if it
+// can't be determined.
+func (i instrumentation) tableForQuery(query string) string {
+	if !i.labelsTables() {
+		return ""
+	}
+	table := tableFromQuery(query)
+	if table == "" {
+		return "unknown"
+	}
+	return table
+}
@@ -11,10 +11,10 @@ import (
 
 // These interfaces exist to aid in mocking database operations for unit tests.
 //
-// By convention, any function that takes a OneSelector, Selector,
-// Inserter, Execer, or SelectExecer as as an argument expects
-// that a context has already been applied to the relevant DbMap or
-// Transaction object.
+// Every method of these interfaces takes the context of the operation as its
+// first argument, so there is no separate step to bind a context to a DbMap or
+// Transaction object, and no way to perform an operation without one. A
+// WrappedMap applies its default QueryTimeouts to contexts without a deadline.
 
 // A OneSelector is anything that provides a `SelectOne` function.
 type OneSelector interface {
@@ -31,6 +31,16 @@ type Inserter interface {
 	Insert(context.Context, ...interface{}) error
 }
 
+// A Updater is anything that provides an `Update` function
+type Updater interface {
+	Update(context.Context, ...interface{}) (int64, error)
+}
+
+// A Deleter is anything that provides a `Delete` function
+type Deleter interface {
+	Delete(context.Context, ...interface{}) (int64, error)
+}
+
 // A Execer is anything that provides an `ExecContext` function
 type Execer interface {
 	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
@@ -72,11 +82,15 @@ type Queryer interface {
 	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
 }
 
-// Transaction extends an Executor and adds Rollback and Commit
+// Transaction extends an Executor and adds Rollback and Commit. Its BeginTx
+// begins a nested transaction, so a Transaction is also a DatabaseMap, and
+// functions which need a transaction of their own, e.g. by calling
+// WithTransaction, can be passed either.
 type Transaction interface {
 	Executor
 	Rollback() error
 	Commit() error
+	BeginTx(context.Context) (Transaction, error)
 }
 
 // MappedExecutor is anything that can map types to tables
@@ -103,13 +117,10 @@ type Rows[T any] interface {
 
 // MockSqlExecutor implement SqlExecutor by returning errors from every call.
 //
-// TODO: To mock out WithContext, we needed to be able to return objects that satisfy
-// borp.SqlExecutor. That's a pretty big interface, so we specify one no-op mock
-// that we can embed everywhere we need to satisfy it.
-// Note: MockSqlExecutor does *not* implement WithContext. The expectation is
-// that structs that embed MockSqlExecutor will define their own WithContext
-// that returns a reference to themselves. That makes it easy for those structs
-// to override the specific methods they need to implement (e.g. SelectOne).
+// borp.SqlExecutor is a pretty big interface, so we specify one no-op mock that
+// we can embed everywhere we need to satisfy it. That makes it easy for the
+// structs which embed it to override the specific methods they need to
+// implement (e.g. SelectOne).
 type MockSqlExecutor struct{}
 
 func (mse MockSqlExecutor) Get(ctx context.Context, i interface{}, keys ...interface{}) (interface{}, error) {
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/letsencrypt/borp"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/semaphore"
+)
+
+// errConcurrencyWait is wrapped, along with the context's error, by the error
+// returned when an operation's context ends while it waits for a slot.
+var errConcurrencyWait = errors.New("gave up waiting for a database slot")
+
+// concurrencyLimiter bounds the number of operations in progress at once. An
+// operation beyond the limit waits for one to finish, or for its context to
+// end. A nil *concurrencyLimiter imposes no limit.
+type concurrencyLimiter struct {
+	sem *semaphore.Weighted
+
+	waiting prometheus.Gauge
+	wait    prometheus.Histogram
+}
+
+func newConcurrencyLimiter(limit int, stats prometheus.Registerer) (*concurrencyLimiter, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("concurrency limit must be positive, got %d", limit)
+	}
+	waiting := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "db_concurrency_waiting",
+		Help: "Number of database operations waiting for a slot under the concurrency limit",
+	})
+	wait := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "db_concurrency_wait_seconds",
+		Help:    "Time database operations waited for a slot under the concurrency limit",
+		Buckets: []float64{.0001, .001, .005, .01, .05, .1, .5, 1, 5},
+	})
+	if stats != nil {
+		for _, c := range []prometheus.Collector{waiting, wait} {
+			err := stats.Register(c)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &concurrencyLimiter{
+		sem:     semaphore.NewWeighted(int64(limit)),
+		waiting: waiting,
+		wait:    wait,
+	}, nil
+}
+
+// acquire waits for a slot, returning a function which releases it, and which
+// may be called more than once.
+func (l *concurrencyLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	start := time.Now()
+	l.waiting.Inc()
+	err := l.sem.Acquire(ctx, 1)
+	l.waiting.Dec()
+	l.wait.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errConcurrencyWait, err)
+	}
+	var once sync.Once
+	return func() { once.Do(func() { l.sem.Release(1) }) }, nil
+}
+
+// wrap returns executor, with each of its operations holding a slot while it
+// runs. A slot is released once QueryContext returns, not once its rows are
+// closed.
+func (l *concurrencyLimiter) wrap(executor borp.SqlExecutor) borp.SqlExecutor {
+	if l == nil {
+		return executor
+	}
+	return guardedExecutor{SqlExecutor: executor, guard: func(ctx context.Context) (func(error), error) {
+		release, err := l.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return func(error) { release() }, nil
+	}}
+}
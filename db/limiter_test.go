@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/borp"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	ctx := context.Background()
+	// Abandoning a transaction discards its connection, and with it a
+	// ":memory:" database, so use a file.
+	dbMap, err := NewSQLiteMap(ctx, filepath.Join(t.TempDir(), "widgets.db"), func(dbMap *borp.DbMap) {
+		dbMap.AddTableWithName(sqliteTestModel{}, "widgets").SetKeys(true, "ID")
+	})
+	test.AssertNotError(t, err, "creating SQLite map")
+	defer func() { _ = dbMap.Close() }()
+	err = dbMap.SetConcurrencyLimit(0, nil)
+	test.AssertError(t, err, "zero limit should be rejected")
+	err = dbMap.SetConcurrencyLimit(1, prometheus.NewRegistry())
+	test.AssertNotError(t, err, "setting concurrency limit")
+
+	// countWithin counts the widgets, waiting at most timeout for a slot.
+	countWithin := func(timeout time.Duration) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		_, err := dbMap.SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+		return err
+	}
+	test.AssertNotError(t, countWithin(time.Second), "counting widgets")
+
+	// A transaction holds its slot, for all of its statements, until it ends.
+	tx, err := dbMap.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning transaction")
+	err = tx.Insert(ctx, &sqliteTestModel{Name: "widget"})
+	test.AssertNotError(t, err, "inserting widget")
+	_, err = tx.Select(ctx, &[]sqliteTestModel{}, "SELECT * FROM widgets")
+	test.AssertNotError(t, err, "selecting widgets")
+	err = countWithin(10 * time.Millisecond)
+	test.AssertErrorIs(t, err, errConcurrencyWait)
+	test.AssertErrorIs(t, err, context.DeadlineExceeded)
+	var dbOpErr ErrDatabaseOp
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertMetricWithLabelsEquals(t, dbMap.limiter.waiting, prometheus.Labels{}, 0)
+	test.AssertMetricWithLabelsEquals(t, dbMap.limiter.wait, prometheus.Labels{}, 3)
+	test.AssertNotError(t, tx.Commit(), "committing transaction")
+	test.AssertNotError(t, countWithin(time.Second), "counting widgets")
+
+	// A waiting statement proceeds once the slot is released.
+	tx, err = dbMap.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning transaction")
+	counted := make(chan error)
+	go func() { counted <- countWithin(time.Minute) }()
+	time.Sleep(10 * time.Millisecond)
+	test.AssertNotError(t, tx.Rollback(), "rolling back transaction")
+	test.AssertNotError(t, <-counted, "counting widgets after waiting")
+
+	// So does one waiting on a transaction whose context ends without it
+	// having ended.
+	txCtx, cancel := context.WithCancel(ctx)
+	_, err = dbMap.BeginTx(txCtx)
+	test.AssertNotError(t, err, "beginning transaction")
+	cancel()
+	test.AssertNotError(t, countWithin(time.Second), "counting widgets after abandoning transaction")
+
+	// A nested transaction doesn't need a slot of its own.
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		return WithTransaction(ctx, tx.(Transaction), func(nested Executor) error {
+			return nested.Insert(ctx, &sqliteTestModel{Name: "nested"})
+		})
+	})
+	test.AssertNotError(t, err, "inserting in nested transaction")
+}
@@ -7,9 +7,10 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"time"
 
-	"github.com/go-sql-driver/mysql"
 	"github.com/letsencrypt/borp"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // ErrDatabaseOp wraps an underlying err with a description of the operation
@@ -52,20 +53,177 @@ func IsNoRows(err error) bool {
 
 // IsDuplicate is a utility function for determining if an error wrap MySQL's
 // Error 1062: Duplicate entry. This error is returned when inserting a row
-// would violate a unique key constraint.
+// would violate a unique key constraint. The equivalent SQLite errors, returned
+// by a WrappedMap from dbtest.NewSQLiteMap, are also recognized.
 func IsDuplicate(err error) bool {
-	var dbErr *mysql.MySQLError
-	return errors.As(err, &dbErr) && dbErr.Number == 1062
+	return ClassifyError(err) == Duplicate
 }
 
+// Compile-time checks that the wrappers implement the context-first
+// interfaces.
+var (
+	_ DatabaseMap    = (*WrappedMap)(nil)
+	_ Executor       = (*WrappedMap)(nil)
+	_ MappedExecutor = (*WrappedMap)(nil)
+	_ Transaction    = WrappedTransaction{}
+	_ DatabaseMap    = WrappedTransaction{}
+	_ Executor       = WrappedExecutor{}
+)
+
 // WrappedMap wraps a *borp.DbMap such that its major functions wrap error
 // results in ErrDatabaseOp instances before returning them to the caller.
 type WrappedMap struct {
 	dbMap *borp.DbMap
+	// maxTransactionDuration, if non-zero, bounds the life of each transaction
+	// begun by the map. See SetMaxTransactionDuration.
+	maxTransactionDuration time.Duration
+	instrumentation
 }
 
+// NewWrappedMap returns a *WrappedMap over dbMap. It emits a span for each
+// operation, using the globally configured TracerProvider.
 func NewWrappedMap(dbMap *borp.DbMap) *WrappedMap {
-	return &WrappedMap{dbMap: dbMap}
+	return &WrappedMap{
+		dbMap: dbMap,
+		instrumentation: instrumentation{
+			tables: dbMap,
+			tracer: newQueryTracer(dbMap),
+		},
+	}
+}
+
+// NewInstrumentedWrappedMap is like NewWrappedMap, but the returned map, and
+// the transactions it begins, apply the provided default timeouts. If stats is
+// non-nil, the latency, errors, and rows affected of each operation, by
+// operation and table, and the number of operations which exceed their
+// timeout, are exported.
+func NewInstrumentedWrappedMap(dbMap *borp.DbMap, timeouts QueryTimeouts, stats prometheus.Registerer) (*WrappedMap, error) {
+	t, err := newQueryTimeouts(timeouts, stats)
+	if err != nil {
+		return nil, err
+	}
+	m := NewWrappedMap(dbMap)
+	m.timeouts = t
+	if stats != nil {
+		m.metrics, err = newQueryMetrics(stats)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// SetQueryComments causes a comment attributing each statement to component,
+// and to the trace and request ID (see WithRequestID) of its context, to be
+// appended to the statements executed by the map and the transactions it
+// subsequently begins. Only the statements passed to Select, SelectOne,
+// SelectNullInt, SelectStr, QueryContext, QueryRowContext, and ExecContext are
+// commented, not those generated by borp for Get, Insert, Update, and Delete.
+// It must be called before the map is used.
+func (m *WrappedMap) SetQueryComments(component string) {
+	m.commenter = &queryCommenter{component: component}
+}
+
+// SetExplainChecks causes each SELECT executed by the map, and the
+// transactions it subsequently begins, to first be explained, and report to be
+// called for each problem found with the plan on any of largeTables: a full
+// table scan that no index could have avoided, or a filesort. Tables are named
+// as in the plan, so must be referred to by name rather than alias in queries.
+// Only MySQL and SQLite plans are checked. It is intended for tests, to catch
+// queries missing an index before they reach production, and must be called
+// before the map is used.
+func (m *WrappedMap) SetExplainChecks(largeTables []string, report func(QueryPlanProblem)) {
+	tables := make(map[string]bool, len(largeTables))
+	for _, table := range largeTables {
+		tables[table] = true
+	}
+	m.explainer = &queryExplainer{tables: tables, report: report}
+}
+
+// SetGTIDCapture causes the map to record the executed GTID set of the
+// database after each write, or transaction commit, made with a context from
+// WithReadYourWrites, for reads made by a map with SetGTIDWait to wait for.
+// It should be called on the map of the primary, which must be MySQL with GTIDs
+// enabled, before the map is used.
+func (m *WrappedMap) SetGTIDCapture() {
+	m.gtidCapture = &gtidCapturer{primary: m.dbMap}
+}
+
+// SetGTIDWait causes each read made by the map, or the transactions it
+// subsequently begins, with a context from WithReadYourWrites to first wait up
+// to timeout for the database to execute the GTID set recorded by the latest
+// write made with that context. Reads which time out fail, rather than
+// returning stale results. QueryRowContext, which can't return an error of its
+// own, doesn't wait. It should be called on the map of a MySQL replica before
+// the map is used.
+func (m *WrappedMap) SetGTIDWait(timeout time.Duration) {
+	m.gtidWait = &gtidWaiter{timeout: timeout}
+}
+
+// SetMaxTransactionDuration bounds the life of each transaction the map
+// subsequently begins. A transaction is tied to the context it began with: once
+// that context is canceled or times out, the transaction is rolled back and its
+// connection returned to the pool, even if it is never committed or rolled
+// back, and its Commit returns an error. This gives each transaction a deadline
+// of d, if its context doesn't have an earlier one, so that transactions
+// abandoned with a context which is never canceled can't hold a connection,
+// and their locks, indefinitely. It must be called before the map is used.
+func (m *WrappedMap) SetMaxTransactionDuration(d time.Duration) {
+	m.maxTransactionDuration = d
+}
+
+// SetMaxExecutionTimeHints causes each SELECT executed by the map, and the
+// transactions it subsequently begins, with a deadline, including one applied
+// by its default QueryTimeouts, to carry a MAX_EXECUTION_TIME optimizer hint
+// for the time remaining, so that MySQL aborts it once the deadline passes
+// even if the context's cancellation doesn't reach the server. MariaDB ignores
+// the hint. It must be called before the map is used.
+func (m *WrappedMap) SetMaxExecutionTimeHints() {
+	m.executionTimeHints = true
+}
+
+// SetCircuitBreaker causes the map, and the transactions it subsequently
+// begins, to stop sending operations to the database once threshold
+// consecutive operations have failed because it was unreachable, or timed out,
+// so that during an outage callers shed load immediately rather than queueing
+// for connections. Instead, until an operation succeeds, they fail with an
+// error wrapping ErrCircuitOpen, except for a single trial operation allowed
+// through each cooldown. If stats is non-nil, whether the breaker is open and
+// the number of operations it has failed are exported. It must be called
+// before the map is used.
+func (m *WrappedMap) SetCircuitBreaker(threshold int, cooldown time.Duration, stats prometheus.Registerer) error {
+	b, err := newCircuitBreaker(threshold, cooldown, stats)
+	if err != nil {
+		return err
+	}
+	m.breaker = b
+	return nil
+}
+
+// SetConcurrencyLimit bounds the number of statements the map executes at once
+// to limit, so that a surge of requests, e.g. retries after an upstream
+// failure, queues in the caller rather than overwhelming the database. A
+// statement beyond the limit waits for a slot, or fails once its context,
+// which includes its default timeout, ends. Each transaction the map
+// subsequently begins holds one slot until it ends, rather than one per
+// statement. If stats is non-nil, the number of statements waiting and the
+// time they wait are exported. It must be called before the map is used.
+func (m *WrappedMap) SetConcurrencyLimit(limit int, stats prometheus.Registerer) error {
+	l, err := newConcurrencyLimiter(limit, stats)
+	if err != nil {
+		return err
+	}
+	m.limiter = l
+	return nil
+}
+
+// Close closes the underlying database connection pool.
+func (m *WrappedMap) Close() error {
+	return m.dbMap.Db.Close()
+}
+
+func (m *WrappedMap) executor() WrappedExecutor {
+	return WrappedExecutor{sqlExecutor: m.breaker.wrap(m.limiter.wrap(m.faults.wrap(m.dbMap))), instrumentation: m.instrumentation}
 }
 
 func (m *WrappedMap) TableFor(t reflect.Type, checkPK bool) (*borp.TableMap, error) {
@@ -73,107 +231,289 @@ func (m *WrappedMap) TableFor(t reflect.Type, checkPK bool) (*borp.TableMap, err
 }
 
 func (m *WrappedMap) Get(ctx context.Context, holder interface{}, keys ...interface{}) (interface{}, error) {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.Get(ctx, holder, keys...)
+	return m.executor().Get(ctx, holder, keys...)
 }
 
 func (m *WrappedMap) Insert(ctx context.Context, list ...interface{}) error {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.Insert(ctx, list...)
+	return m.executor().Insert(ctx, list...)
 }
 
 func (m *WrappedMap) Update(ctx context.Context, list ...interface{}) (int64, error) {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.Update(ctx, list...)
+	return m.executor().Update(ctx, list...)
 }
 
 func (m *WrappedMap) Delete(ctx context.Context, list ...interface{}) (int64, error) {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.Delete(ctx, list...)
+	return m.executor().Delete(ctx, list...)
 }
 
 func (m *WrappedMap) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.Select(ctx, holder, query, args...)
+	return m.executor().Select(ctx, holder, query, args...)
 }
 
 func (m *WrappedMap) SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.SelectOne(ctx, holder, query, args...)
+	return m.executor().SelectOne(ctx, holder, query, args...)
 }
 
 func (m *WrappedMap) SelectNullInt(ctx context.Context, query string, args ...interface{}) (sql.NullInt64, error) {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.SelectNullInt(ctx, query, args...)
+	return m.executor().SelectNullInt(ctx, query, args...)
 }
 
 func (m *WrappedMap) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.QueryContext(ctx, query, args...)
+	return m.executor().QueryContext(ctx, query, args...)
+}
+
+// Query is like QueryContext, but returns *WrappedRows, whose errors identify
+// the query's table.
+func (m *WrappedMap) Query(ctx context.Context, query string, args ...interface{}) (*WrappedRows, error) {
+	return m.executor().Query(ctx, query, args...)
 }
 
 func (m *WrappedMap) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.QueryRowContext(ctx, query, args...)
+	return m.executor().QueryRowContext(ctx, query, args...)
 }
 
 func (m *WrappedMap) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.SelectStr(ctx, query, args...)
+	return m.executor().SelectStr(ctx, query, args...)
 }
 
 func (m *WrappedMap) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return WrappedExecutor{sqlExecutor: m.dbMap}.ExecContext(ctx, query, args...)
+	return m.executor().ExecContext(ctx, query, args...)
 }
 
+// BeginTx begins a transaction, which is rolled back when ctx is canceled or
+// times out if it hasn't already been committed. See
+// SetMaxTransactionDuration.
 func (m *WrappedMap) BeginTx(ctx context.Context) (Transaction, error) {
-	tx, err := m.dbMap.BeginTx(ctx)
+	cancel := context.CancelFunc(func() {})
+	if m.maxTransactionDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, m.maxTransactionDuration)
+	}
+	tx, release, err := m.beginTx(ctx)
 	if err != nil {
-		return tx, ErrDatabaseOp{
+		cancel()
+		return nil, ErrDatabaseOp{
 			Op:  "begin transaction",
 			Err: err,
 		}
 	}
+	// The transaction's statements run in the slot it holds under the
+	// concurrency limit, if any, until it ends or its context does.
+	instrumentation := m.instrumentation
+	if instrumentation.limiter != nil {
+		instrumentation.limiter = nil
+		stop := context.AfterFunc(ctx, release)
+		end := cancel
+		cancel = func() {
+			stop()
+			release()
+			end()
+		}
+	}
 	return WrappedTransaction{
-		transaction: tx,
-	}, err
+		transaction:     tx,
+		ctx:             ctx,
+		cancel:          cancel,
+		instrumentation: instrumentation,
+	}, nil
+}
+
+// beginTx begins a transaction once the circuit breaker and concurrency limit,
+// if any, allow it, returning a function which releases its slot under the
+// limit.
+func (m *WrappedMap) beginTx(ctx context.Context) (*borp.Transaction, func(), error) {
+	done, err := m.breaker.allow()
+	if err != nil {
+		return nil, nil, err
+	}
+	release, err := m.limiter.acquire(ctx)
+	if err != nil {
+		done(err)
+		return nil, nil, err
+	}
+	err = m.faults.fail("begin transaction")
+	var tx *borp.Transaction
+	if err == nil {
+		tx, err = m.dbMap.BeginTx(ctx)
+	}
+	done(err)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return tx, release, nil
+}
+
+// BeginReadOnly begins a read-only transaction whose reads all see the same
+// consistent snapshot of the database, as of when it began, without taking any
+// locks. It suits jobs which run several queries that must agree with one
+// another, like reports. To keep such load off the primary, call it on the map
+// of a replica when one is configured, e.g. the SA's read-only database.
+//
+// On SQLite, which has no read-only transactions, it is equivalent to BeginTx.
+func (m *WrappedMap) BeginReadOnly(ctx context.Context) (Transaction, error) {
+	tx, err := m.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, ok := m.dbMap.Dialect.(borp.MySQLDialect)
+	if !ok {
+		return tx, nil
+	}
+	borpTx := tx.(WrappedTransaction).transaction
+	// borp can't begin a transaction with options, but the transaction's
+	// connection is reserved for it. End the empty transaction begun by BeginTx
+	// and replace it with one having the characteristics we want, which the
+	// eventual Commit or Rollback will end instead.
+	for _, query := range []string{
+		"COMMIT",
+		"SET TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION READ ONLY, WITH CONSISTENT SNAPSHOT",
+	} {
+		_, err = borpTx.ExecContext(ctx, query)
+		if err != nil {
+			_ = borpTx.Rollback()
+			return nil, ErrDatabaseOp{
+				Op:  "begin read-only transaction",
+				Err: err,
+			}
+		}
+	}
+	return tx, nil
 }
 
 // WrappedTransaction wraps a *borp.Transaction such that its major functions
 // wrap error results in ErrDatabaseOp instances before returning them to the
 // caller.
+//
+// A WrappedTransaction returned by the BeginTx method of another is nested
+// within it, using a savepoint: its Commit releases the savepoint, leaving the
+// changes made since it to be committed or rolled back with the enclosing
+// transaction, and its Rollback rolls back only those changes.
 type WrappedTransaction struct {
 	transaction *borp.Transaction
+	// savepoint is the name of the savepoint a nested transaction began with,
+	// and is empty for a top-level transaction. ctx is the context the
+	// transaction began with, which the savepoint statements of a nested
+	// transaction's Commit and Rollback, and the GTID capture of a top-level
+	// transaction's Commit, use.
+	savepoint string
+	ctx       context.Context
+	depth     int
+	// cancel releases the resources of a top-level transaction's ctx once it
+	// has been committed or rolled back.
+	cancel context.CancelFunc
+	instrumentation
+}
+
+func (tx WrappedTransaction) executor() WrappedExecutor {
+	// A transaction's writes are captured once, when it commits.
+	instrumentation := tx.instrumentation
+	instrumentation.gtidCapture = nil
+	return WrappedExecutor{sqlExecutor: tx.breaker.wrap(tx.faults.wrap(tx.transaction)), instrumentation: instrumentation}
 }
 
 func (tx WrappedTransaction) Commit() error {
-	return tx.transaction.Commit()
+	if tx.savepoint != "" {
+		return tx.transaction.ReleaseSavepoint(tx.ctx, tx.savepoint)
+	}
+	err := tx.contextEnded(tx.transaction.Commit())
+	tx.end()
+	if err != nil {
+		return err
+	}
+	tx.gtidCapture.capture(tx.ctx)
+	return nil
 }
 
 func (tx WrappedTransaction) Rollback() error {
-	return tx.transaction.Rollback()
+	if tx.savepoint != "" {
+		err := tx.transaction.RollbackToSavepoint(tx.ctx, tx.savepoint)
+		if err != nil {
+			return err
+		}
+		return tx.transaction.ReleaseSavepoint(tx.ctx, tx.savepoint)
+	}
+	err := tx.contextEnded(tx.transaction.Rollback())
+	tx.end()
+	return err
+}
+
+// end releases the resources of a top-level transaction's context.
+func (tx WrappedTransaction) end() {
+	if tx.cancel != nil {
+		tx.cancel()
+	}
+}
+
+// contextEnded explains err, returned by committing or rolling back, if it is
+// because the transaction was already rolled back when its context ended. It
+// must be called before end.
+func (tx WrappedTransaction) contextEnded(err error) error {
+	if err == nil || tx.ctx == nil || tx.ctx.Err() == nil {
+		return err
+	}
+	ctxErr := tx.ctx.Err()
+	if errors.Is(err, ctxErr) {
+		return fmt.Errorf("transaction rolled back because its context ended: %w", err)
+	}
+	if errors.Is(err, sql.ErrTxDone) {
+		return fmt.Errorf("transaction rolled back because its context ended: %w: %w", ctxErr, err)
+	}
+	return err
+}
+
+// BeginTx begins a transaction nested within tx, by creating a savepoint.
+func (tx WrappedTransaction) BeginTx(ctx context.Context) (Transaction, error) {
+	nested := tx
+	nested.depth++
+	nested.savepoint = fmt.Sprintf("boulder_savepoint_%d", nested.depth)
+	nested.ctx = ctx
+	err := tx.transaction.Savepoint(ctx, nested.savepoint)
+	if err != nil {
+		return nil, ErrDatabaseOp{
+			Op:  "begin nested transaction",
+			Err: err,
+		}
+	}
+	return nested, nil
 }
 
 func (tx WrappedTransaction) Get(ctx context.Context, holder interface{}, keys ...interface{}) (interface{}, error) {
-	return (WrappedExecutor{sqlExecutor: tx.transaction}).Get(ctx, holder, keys...)
+	return tx.executor().Get(ctx, holder, keys...)
 }
 
 func (tx WrappedTransaction) Insert(ctx context.Context, list ...interface{}) error {
-	return (WrappedExecutor{sqlExecutor: tx.transaction}).Insert(ctx, list...)
+	return tx.executor().Insert(ctx, list...)
 }
 
 func (tx WrappedTransaction) Update(ctx context.Context, list ...interface{}) (int64, error) {
-	return (WrappedExecutor{sqlExecutor: tx.transaction}).Update(ctx, list...)
+	return tx.executor().Update(ctx, list...)
 }
 
 func (tx WrappedTransaction) Delete(ctx context.Context, list ...interface{}) (int64, error) {
-	return (WrappedExecutor{sqlExecutor: tx.transaction}).Delete(ctx, list...)
+	return tx.executor().Delete(ctx, list...)
 }
 
 func (tx WrappedTransaction) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
-	return (WrappedExecutor{sqlExecutor: tx.transaction}).Select(ctx, holder, query, args...)
+	return tx.executor().Select(ctx, holder, query, args...)
 }
 
 func (tx WrappedTransaction) SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
-	return (WrappedExecutor{sqlExecutor: tx.transaction}).SelectOne(ctx, holder, query, args...)
+	return tx.executor().SelectOne(ctx, holder, query, args...)
 }
 
 func (tx WrappedTransaction) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	return (WrappedExecutor{sqlExecutor: tx.transaction}).QueryContext(ctx, query, args...)
+	return tx.executor().QueryContext(ctx, query, args...)
+}
+
+// Query is like QueryContext, but returns *WrappedRows, whose errors identify
+// the query's table.
+func (tx WrappedTransaction) Query(ctx context.Context, query string, args ...interface{}) (*WrappedRows, error) {
+	return tx.executor().Query(ctx, query, args...)
 }
 
 func (tx WrappedTransaction) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return (WrappedExecutor{sqlExecutor: tx.transaction}).ExecContext(ctx, query, args...)
+	return tx.executor().ExecContext(ctx, query, args...)
 }
 
 // WrappedExecutor wraps a borp.SqlExecutor such that its major functions
@@ -181,6 +521,7 @@ func (tx WrappedTransaction) ExecContext(ctx context.Context, query string, args
 // caller.
 type WrappedExecutor struct {
 	sqlExecutor borp.SqlExecutor
+	instrumentation
 }
 
 func errForOp(operation string, err error, list []interface{}) ErrDatabaseOp {
@@ -217,7 +558,13 @@ func errForQuery(query, operation string, err error, list []interface{}) ErrData
 }
 
 func (we WrappedExecutor) Get(ctx context.Context, holder interface{}, keys ...interface{}) (interface{}, error) {
+	err := we.gtidWait.wait(ctx, we.sqlExecutor)
+	if err != nil {
+		return nil, err
+	}
+	ctx, done := we.begin(ctx, "get", readQuery, we.tableForHolder(holder))
 	res, err := we.sqlExecutor.Get(ctx, holder, keys...)
+	done(-1, err)
 	if err != nil {
 		return res, errForOp("get", err, []interface{}{holder})
 	}
@@ -225,31 +572,47 @@ func (we WrappedExecutor) Get(ctx context.Context, holder interface{}, keys ...i
 }
 
 func (we WrappedExecutor) Insert(ctx context.Context, list ...interface{}) error {
-	err := we.sqlExecutor.Insert(ctx, list...)
+	opCtx, done := we.begin(ctx, "insert", writeQuery, we.tableForList(list))
+	err := we.sqlExecutor.Insert(opCtx, list...)
+	done(int64(len(list)), err)
 	if err != nil {
 		return errForOp("insert", err, list)
 	}
+	we.gtidCapture.capture(ctx)
 	return nil
 }
 
 func (we WrappedExecutor) Update(ctx context.Context, list ...interface{}) (int64, error) {
-	updatedRows, err := we.sqlExecutor.Update(ctx, list...)
+	opCtx, done := we.begin(ctx, "update", writeQuery, we.tableForList(list))
+	updatedRows, err := we.sqlExecutor.Update(opCtx, list...)
+	done(updatedRows, err)
 	if err != nil {
 		return updatedRows, errForOp("update", err, list)
 	}
+	we.gtidCapture.capture(ctx)
 	return updatedRows, err
 }
 
 func (we WrappedExecutor) Delete(ctx context.Context, list ...interface{}) (int64, error) {
-	deletedRows, err := we.sqlExecutor.Delete(ctx, list...)
+	opCtx, done := we.begin(ctx, "delete", writeQuery, we.tableForList(list))
+	deletedRows, err := we.sqlExecutor.Delete(opCtx, list...)
+	done(deletedRows, err)
 	if err != nil {
 		return deletedRows, errForOp("delete", err, list)
 	}
+	we.gtidCapture.capture(ctx)
 	return deletedRows, err
 }
 
 func (we WrappedExecutor) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
-	result, err := we.sqlExecutor.Select(ctx, holder, query, args...)
+	err := we.gtidWait.wait(ctx, we.sqlExecutor)
+	if err != nil {
+		return nil, err
+	}
+	ctx, done := we.begin(ctx, "select", readQuery, we.tableForQuery(query))
+	we.explainer.check(ctx, we.sqlExecutor, we.tables, query, args)
+	result, err := we.sqlExecutor.Select(ctx, holder, we.commenter.comment(ctx, we.hintExecutionTime(ctx, query)), args...)
+	done(-1, err)
 	if err != nil {
 		return result, errForQuery(query, "select", err, []interface{}{holder})
 	}
@@ -257,7 +620,14 @@ func (we WrappedExecutor) Select(ctx context.Context, holder interface{}, query
 }
 
 func (we WrappedExecutor) SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
-	err := we.sqlExecutor.SelectOne(ctx, holder, query, args...)
+	err := we.gtidWait.wait(ctx, we.sqlExecutor)
+	if err != nil {
+		return err
+	}
+	ctx, done := we.begin(ctx, "select one", readQuery, we.tableForQuery(query))
+	we.explainer.check(ctx, we.sqlExecutor, we.tables, query, args)
+	err = we.sqlExecutor.SelectOne(ctx, holder, we.commenter.comment(ctx, we.hintExecutionTime(ctx, query)), args...)
+	done(-1, err)
 	if err != nil {
 		return errForQuery(query, "select one", err, []interface{}{holder})
 	}
@@ -265,7 +635,14 @@ func (we WrappedExecutor) SelectOne(ctx context.Context, holder interface{}, que
 }
 
 func (we WrappedExecutor) SelectNullInt(ctx context.Context, query string, args ...interface{}) (sql.NullInt64, error) {
-	rows, err := we.sqlExecutor.SelectNullInt(ctx, query, args...)
+	err := we.gtidWait.wait(ctx, we.sqlExecutor)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	ctx, done := we.begin(ctx, "select", readQuery, we.tableForQuery(query))
+	we.explainer.check(ctx, we.sqlExecutor, we.tables, query, args)
+	rows, err := we.sqlExecutor.SelectNullInt(ctx, we.commenter.comment(ctx, we.hintExecutionTime(ctx, query)), args...)
+	done(-1, err)
 	if err != nil {
 		return sql.NullInt64{}, errForQuery(query, "select", err, nil)
 	}
@@ -275,11 +652,18 @@ func (we WrappedExecutor) SelectNullInt(ctx context.Context, query string, args
 func (we WrappedExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	// Note: we can't do error wrapping here because the error is passed via the `*sql.Row`
 	// object, and we can't produce a `*sql.Row` object with a custom error because it is unexported.
-	return we.sqlExecutor.QueryRowContext(ctx, query, args...)
+	return we.sqlExecutor.QueryRowContext(ctx, we.commenter.comment(ctx, we.hintExecutionTime(ctx, query)), args...)
 }
 
 func (we WrappedExecutor) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
-	str, err := we.sqlExecutor.SelectStr(ctx, query, args...)
+	err := we.gtidWait.wait(ctx, we.sqlExecutor)
+	if err != nil {
+		return "", err
+	}
+	ctx, done := we.begin(ctx, "select", readQuery, we.tableForQuery(query))
+	we.explainer.check(ctx, we.sqlExecutor, we.tables, query, args)
+	str, err := we.sqlExecutor.SelectStr(ctx, we.commenter.comment(ctx, we.hintExecutionTime(ctx, query)), args...)
+	done(-1, err)
 	if err != nil {
 		return "", errForQuery(query, "select", err, nil)
 	}
@@ -287,13 +671,30 @@ func (we WrappedExecutor) SelectStr(ctx context.Context, query string, args ...i
 }
 
 func (we WrappedExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	rows, err := we.sqlExecutor.QueryContext(ctx, query, args...)
+	err := we.gtidWait.wait(ctx, we.sqlExecutor)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	we.explainer.check(ctx, we.sqlExecutor, we.tables, query, args)
+	rows, err := we.sqlExecutor.QueryContext(ctx, we.commenter.comment(ctx, we.hintExecutionTime(ctx, query)), args...)
+	we.metrics.observe("select", we.tableForQuery(query), queryName(ctx), start, err)
 	if err != nil {
 		return nil, errForQuery(query, "select", err, nil)
 	}
 	return rows, nil
 }
 
+// Query is like QueryContext, but returns *WrappedRows, whose errors identify
+// the query's table.
+func (we WrappedExecutor) Query(ctx context.Context, query string, args ...interface{}) (*WrappedRows, error) {
+	rows, err := we.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &WrappedRows{rows: rows, query: query}, nil
+}
+
 var (
 	// selectTableRegexp matches the table name from an SQL select statement
 	selectTableRegexp = regexp.MustCompile(`(?i)^\s*select\s+[a-z\d:\.\(\), \_\*` + "`" + `]+\s+from\s+([a-z\d\_,` + "`" + `]+)`)
@@ -331,9 +732,19 @@ func tableFromQuery(query string) string {
 }
 
 func (we WrappedExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	res, err := we.sqlExecutor.ExecContext(ctx, query, args...)
+	opCtx, done := we.begin(ctx, "exec", writeQuery, we.tableForQuery(query))
+	res, err := we.sqlExecutor.ExecContext(opCtx, we.commenter.comment(opCtx, query), args...)
+	rows := int64(-1)
+	if err == nil {
+		affected, rowsErr := res.RowsAffected()
+		if rowsErr == nil {
+			rows = affected
+		}
+	}
+	done(rows, err)
 	if err != nil {
 		return res, errForQuery(query, "exec", err, args)
 	}
+	we.gtidCapture.capture(ctx)
 	return res, nil
 }
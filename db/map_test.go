@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/letsencrypt/borp"
+	"github.com/mattn/go-sqlite3"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/letsencrypt/boulder/core"
@@ -105,6 +106,24 @@ func TestIsDuplicate(t *testing.T) {
 			},
 			expectDuplicate: false,
 		},
+		{
+			name: "underlying err is a SQLite unique constraint violation",
+			err: ErrDatabaseOp{
+				Op:    "test",
+				Table: "testTable",
+				Err:   fmt.Errorf("some wrapper around %w", sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}),
+			},
+			expectDuplicate: true,
+		},
+		{
+			name: "underlying err is a SQLite not null constraint violation",
+			err: ErrDatabaseOp{
+				Op:    "test",
+				Table: "testTable",
+				Err:   fmt.Errorf("some wrapper around %w", sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintNotNull}),
+			},
+			expectDuplicate: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -240,7 +259,7 @@ func testDbMap(t *testing.T) *WrappedMap {
 	// NOTE(@cpu): We avoid giving a sa.BoulderTypeConverter to the DbMap field to
 	// avoid the cyclic dep. We don't need to convert any types in the db tests.
 	dbMap := &borp.DbMap{Db: dbConn, Dialect: dialect, TypeConverter: nil}
-	return &WrappedMap{dbMap: dbMap}
+	return NewWrappedMap(dbMap)
 }
 
 func TestWrappedMap(t *testing.T) {
@@ -343,3 +362,19 @@ func TestWrappedMap(t *testing.T) {
 	test.AssertNotError(t, err, "unexpected error beginning transaction")
 	testWrapper(tx)
 }
+
+func TestBeginReadOnly(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testDbMap(t)
+
+	tx, err := dbMap.BeginReadOnly(ctx)
+	test.AssertNotError(t, err, "beginning read-only transaction")
+	defer func() { _ = tx.Rollback() }()
+
+	var count int64
+	err = tx.SelectOne(ctx, &count, "SELECT COUNT(*) FROM registrations")
+	test.AssertNotError(t, err, "reading in read-only transaction")
+
+	_, err = tx.ExecContext(ctx, "UPDATE registrations SET contact = contact WHERE id = -1")
+	test.Assert(t, IsReadOnly(err), fmt.Sprintf("expected a read-only error, got %v", err))
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// queryMetrics records the latency, errors, and rows affected of the operations
+// of a WrappedExecutor, by operation, table, and the name attached to their
+// context by WithQueryName, which is empty for unnamed operations. A nil *queryMetrics records
+// nothing.
+type queryMetrics struct {
+	latency      *prometheus.HistogramVec
+	errors       *prometheus.CounterVec
+	rowsAffected *prometheus.SummaryVec
+}
+
+func newQueryMetrics(stats prometheus.Registerer) (*queryMetrics, error) {
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_latency_seconds",
+		Help:    "Latency of database operations, by operation, table, and query name",
+		Buckets: []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10},
+	}, []string{"op", "table", "query"})
+	errs := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Number of database operations which returned an error, by operation, table, and query name",
+	}, []string{"op", "table", "query"})
+	rowsAffected := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Name: "db_rows_affected",
+		Help: "Number of rows affected by database writes, by operation, table, and query name",
+	}, []string{"op", "table", "query"})
+	for _, c := range []prometheus.Collector{latency, errs, rowsAffected} {
+		err := stats.Register(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &queryMetrics{
+		latency:      latency,
+		errors:       errs,
+		rowsAffected: rowsAffected,
+	}, nil
+}
+
+// observe records the latency and outcome of an operation which began at start.
+func (m *queryMetrics) observe(op, table, name string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.latency.WithLabelValues(op, table, name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.errors.WithLabelValues(op, table, name).Inc()
+	}
+}
+
+// observeRows records the number of rows affected by a write.
+func (m *queryMetrics) observeRows(op, table, name string, rows int64) {
+	if m == nil {
+		return
+	}
+	m.rowsAffected.WithLabelValues(op, table, name).Observe(float64(rows))
+}
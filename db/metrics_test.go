@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestQueryMetrics(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	var err error
+	dbMap.metrics, err = newQueryMetrics(prometheus.NewRegistry())
+	test.AssertNotError(t, err, "creating query metrics")
+	m := dbMap.metrics
+
+	rowsAffected := func(op, table string) float64 {
+		t.Helper()
+		var iom io_prometheus_client.Metric
+		err := m.rowsAffected.WithLabelValues(op, table, "").(prometheus.Metric).Write(&iom)
+		test.AssertNotError(t, err, "writing rows affected")
+		return iom.Summary.GetSampleSum()
+	}
+
+	// borp operations are labeled by the table mapped to their holder.
+	w := &sqliteTestModel{Name: "widget"}
+	err = dbMap.Insert(ctx, w)
+	test.AssertNotError(t, err, "inserting widget")
+	_, err = dbMap.Get(ctx, sqliteTestModel{}, w.ID)
+	test.AssertNotError(t, err, "getting widget")
+	test.AssertMetricWithLabelsEquals(t, m.latency, prometheus.Labels{"op": "insert", "table": "widgets"}, 1)
+	test.AssertMetricWithLabelsEquals(t, m.latency, prometheus.Labels{"op": "get", "table": "widgets"}, 1)
+	test.AssertEquals(t, rowsAffected("insert", "widgets"), float64(1))
+
+	// Operations in a transaction are recorded too.
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		w.Name = "gadget"
+		_, err := tx.Update(ctx, w)
+		return err
+	})
+	test.AssertNotError(t, err, "updating widget")
+	test.AssertEquals(t, rowsAffected("update", "widgets"), float64(1))
+
+	// Queries are labeled by the table they name.
+	var selected []sqliteTestModel
+	_, err = dbMap.Select(ctx, &selected, "SELECT * FROM widgets WHERE ID > ?", 0)
+	test.AssertNotError(t, err, "selecting widgets")
+	test.AssertMetricWithLabelsEquals(t, m.latency, prometheus.Labels{"op": "select", "table": "widgets"}, 1)
+
+	_, err = dbMap.ExecContext(ctx, "INSERT INTO widgets (Name) VALUES (?), (?)", "gizmo", "doohickey")
+	test.AssertNotError(t, err, "exec-ing insert")
+	test.AssertEquals(t, rowsAffected("exec", "widgets"), float64(2))
+
+	_, err = dbMap.ExecContext(ctx, "DELETE FROM widgets WHERE ID > ?", 0)
+	test.AssertNotError(t, err, "exec-ing delete")
+	test.AssertEquals(t, rowsAffected("exec", "widgets"), float64(5))
+
+	// Errors are counted.
+	test.AssertMetricWithLabelsEquals(t, m.errors, prometheus.Labels{}, 0)
+	err = dbMap.SelectOne(ctx, &sqliteTestModel{}, "SELECT * FROM doesNotExist WHERE ID = 1")
+	test.AssertError(t, err, "selecting from missing table")
+	test.AssertMetricWithLabelsEquals(t, m.errors, prometheus.Labels{"op": "select one", "table": "doesNotExist"}, 1)
+	_, err = dbMap.SelectStr(ctx, "blah")
+	test.AssertError(t, err, "bogus query")
+	test.AssertMetricWithLabelsEquals(t, m.errors, prometheus.Labels{"op": "select", "table": "unknown"}, 1)
+
+	// Named operations are labeled by their name.
+	namedCtx := WithQueryName(ctx, "CountWidgets")
+	_, err = dbMap.SelectNullInt(namedCtx, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "counting widgets")
+	test.AssertMetricWithLabelsEquals(t, m.latency, prometheus.Labels{"op": "select", "table": "widgets", "query": "CountWidgets"}, 1)
+	_, err = dbMap.QueryContext(namedCtx, "SELECT * FROM doesNotExist")
+	test.AssertError(t, err, "querying missing table")
+	test.AssertMetricWithLabelsEquals(t, m.errors, prometheus.Labels{"query": "CountWidgets"}, 1)
+}
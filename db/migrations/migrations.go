@@ -0,0 +1,287 @@
+// Package migrations applies versioned schema migrations to a database, and
+// records which have been applied.
+//
+// Migrations are read from SQL files in the format used by
+// https://github.com/rubenv/sql-migrate, such as those in sa/db, and applied
+// migrations are recorded in the same gorp_migrations table, so that databases
+// already migrated by the sql-migrate tool can be managed by this package.
+package migrations
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/db"
+)
+
+// Migration is a schema change, identified by the name of the file it was read
+// from, e.g. 20230519000000_CrlShards.sql. Migrations are applied in order of
+// their IDs.
+type Migration struct {
+	ID string
+	// Up are the statements which apply the migration, and Down those which
+	// roll it back.
+	Up   []string
+	Down []string
+}
+
+const (
+	directiveUp             = "-- +migrate Up"
+	directiveDown           = "-- +migrate Down"
+	directiveStatementBegin = "-- +migrate StatementBegin"
+	directiveStatementEnd   = "-- +migrate StatementEnd"
+)
+
+// parse reads the statements of a migration file. Statements end with a
+// semicolon at the end of a line, unless they are enclosed by StatementBegin
+// and StatementEnd directives, which allow statements like stored procedures
+// to contain semicolons.
+func parse(id string, content []byte) (Migration, error) {
+	migration := Migration{ID: id}
+	var current *[]string
+	var statement strings.Builder
+	var inStatementBlock bool
+
+	endStatement := func() {
+		s := strings.TrimSpace(statement.String())
+		if s != "" {
+			*current = append(*current, s)
+		}
+		statement.Reset()
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, directiveUp):
+			current = &migration.Up
+			continue
+		case strings.HasPrefix(trimmed, directiveDown):
+			current = &migration.Down
+			continue
+		case strings.HasPrefix(trimmed, directiveStatementBegin):
+			inStatementBlock = true
+			continue
+		case strings.HasPrefix(trimmed, directiveStatementEnd):
+			if current != nil {
+				endStatement()
+			}
+			inStatementBlock = false
+			continue
+		case !inStatementBlock && (trimmed == "" || strings.HasPrefix(trimmed, "--")):
+			continue
+		}
+		if current == nil {
+			return Migration{}, fmt.Errorf("parsing migration %q: statement before %q or %q", id, directiveUp, directiveDown)
+		}
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if !inStatementBlock && strings.HasSuffix(trimmed, ";") {
+			endStatement()
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return Migration{}, fmt.Errorf("reading migration %q: %w", id, err)
+	}
+	if inStatementBlock {
+		return Migration{}, fmt.Errorf("parsing migration %q: missing %q", id, directiveStatementEnd)
+	}
+	if strings.TrimSpace(statement.String()) != "" {
+		return Migration{}, fmt.Errorf("parsing migration %q: statement missing a terminating semicolon", id)
+	}
+	return migration, nil
+}
+
+// Load reads the migrations from the .sql files in dir of fsys, ordered by ID.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, err := parse(entry.Name(), content)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].ID < migrations[j].ID })
+	return migrations, nil
+}
+
+// migrationsTable is the table in which applied migrations are recorded, and
+// the name sql-migrate uses for it.
+const migrationsTable = "gorp_migrations"
+
+// appliedMigration is a row of migrationsTable.
+type appliedMigration struct {
+	ID        string    `db:"id"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Status is whether a migration has been applied, and when.
+type Status struct {
+	ID string
+	// AppliedAt is zero if the migration is pending.
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back migrations, in order, using a database map.
+type Migrator struct {
+	dbMap      db.DatabaseMap
+	migrations []Migration
+	clk        clock.Clock
+}
+
+// New returns a *Migrator which manages migrations, which must be ordered by
+// ID, as returned by Load.
+func New(dbMap db.DatabaseMap, migrations []Migration, clk clock.Clock) *Migrator {
+	return &Migrator{dbMap: dbMap, migrations: migrations, clk: clk}
+}
+
+// applied returns the applied migrations, by ID, creating migrationsTable if it
+// doesn't already exist. It is an error for a migration to have been applied
+// which the Migrator doesn't know of.
+func (m *Migrator) applied(ctx context.Context) (map[string]time.Time, error) {
+	_, err := m.dbMap.ExecContext(ctx,
+		"CREATE TABLE IF NOT EXISTS "+migrationsTable+" (id VARCHAR(255) NOT NULL PRIMARY KEY, applied_at DATETIME NULL)")
+	if err != nil {
+		return nil, fmt.Errorf("creating %s: %w", migrationsTable, err)
+	}
+	var rows []appliedMigration
+	_, err = m.dbMap.Select(ctx, &rows, "SELECT id, applied_at FROM "+migrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("selecting applied migrations: %w", err)
+	}
+	known := make(map[string]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.ID] = true
+	}
+	applied := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		if !known[row.ID] {
+			return nil, fmt.Errorf("applied migration %q is unknown", row.ID)
+		}
+		applied[row.ID] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// Status returns the status of each migration, in order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{ID: migration.ID, AppliedAt: applied[migration.ID]}
+	}
+	return statuses, nil
+}
+
+// PlanUp returns the migrations which Up would apply, in order, without
+// applying them.
+func (m *Migrator) PlanUp(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		_, ok := applied[migration.ID]
+		if !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// PlanDown returns the migrations which Down would roll back, in order,
+// without rolling them back.
+func (m *Migrator) PlanDown(ctx context.Context, count int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rollback []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(rollback) < count; i-- {
+		_, ok := applied[m.migrations[i].ID]
+		if ok {
+			rollback = append(rollback, m.migrations[i])
+		}
+	}
+	return rollback, nil
+}
+
+// Up applies each pending migration, in order, and returns those it applied.
+// If a migration fails, those before it remain applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	pending, err := m.PlanUp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, migration := range pending {
+		err = db.WithTransaction(ctx, m.dbMap, func(tx db.Executor) error {
+			for _, statement := range migration.Up {
+				_, err := tx.ExecContext(ctx, statement)
+				if err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO "+migrationsTable+" (id, applied_at) VALUES (?, ?)", migration.ID, m.clk.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("applying migration %q: %w", migration.ID, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down rolls back the count most recently applied migrations, in reverse
+// order, and returns those it rolled back. If a migration fails, those before
+// it remain rolled back.
+func (m *Migrator) Down(ctx context.Context, count int) ([]Migration, error) {
+	rollback, err := m.PlanDown(ctx, count)
+	if err != nil {
+		return nil, err
+	}
+	for i, migration := range rollback {
+		err = db.WithTransaction(ctx, m.dbMap, func(tx db.Executor) error {
+			for _, statement := range migration.Down {
+				_, err := tx.ExecContext(ctx, statement)
+				if err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx, "DELETE FROM "+migrationsTable+" WHERE id = ?", migration.ID)
+			return err
+		})
+		if err != nil {
+			return rollback[:i], fmt.Errorf("rolling back migration %q: %w", migration.ID, err)
+		}
+	}
+	return rollback, nil
+}
@@ -0,0 +1,158 @@
+package migrations
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/db/dbtest"
+	"github.com/letsencrypt/boulder/test"
+)
+
+var testMigrations = fstest.MapFS{
+	"migrations/20230101000000_Widgets.sql": {Data: []byte(`-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE widgets (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL
+);
+CREATE INDEX name_idx ON widgets (name);
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE widgets;
+`)},
+	"migrations/20230201000000_Trigger.sql": {Data: []byte(`-- +migrate Up
+-- +migrate StatementBegin
+CREATE TRIGGER widgets_name AFTER INSERT ON widgets
+BEGIN
+  UPDATE widgets SET name = upper(name) WHERE id = NEW.id;
+END;
+-- +migrate StatementEnd
+
+-- +migrate Down
+DROP TRIGGER widgets_name;
+`)},
+	"migrations/README.md": {Data: []byte("Not a migration.")},
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testMigrations, "migrations")
+	test.AssertNotError(t, err, "loading migrations")
+	test.AssertDeepEquals(t, migrations, []Migration{
+		{
+			ID: "20230101000000_Widgets.sql",
+			Up: []string{
+				"CREATE TABLE widgets (\n  id INTEGER PRIMARY KEY,\n  name TEXT NOT NULL\n);",
+				"CREATE INDEX name_idx ON widgets (name);",
+			},
+			Down: []string{"DROP TABLE widgets;"},
+		},
+		{
+			ID:   "20230201000000_Trigger.sql",
+			Up:   []string{"CREATE TRIGGER widgets_name AFTER INSERT ON widgets\nBEGIN\n  UPDATE widgets SET name = upper(name) WHERE id = NEW.id;\nEND;"},
+			Down: []string{"DROP TRIGGER widgets_name;"},
+		},
+	})
+
+	_, err = parse("bad.sql", []byte("CREATE TABLE widgets (id INTEGER);\n"))
+	test.AssertError(t, err, "expected error for statement before Up")
+	_, err = parse("bad.sql", []byte("-- +migrate Up\nCREATE TABLE widgets (id INTEGER)\n"))
+	test.AssertError(t, err, "expected error for unterminated statement")
+	_, err = parse("bad.sql", []byte("-- +migrate Up\n-- +migrate StatementBegin\nSELECT 1;\n"))
+	test.AssertError(t, err, "expected error for unterminated statement block")
+}
+
+func TestLoadBoulderMigrations(t *testing.T) {
+	for _, dir := range []string{"db/boulder_sa", "db/incidents_sa", "db-next/boulder_sa", "db-next/incidents_sa"} {
+		migrations, err := Load(os.DirFS("../../sa"), dir)
+		test.AssertNotError(t, err, "loading "+dir)
+		for _, migration := range migrations {
+			test.Assert(t, len(migration.Up) > 0, migration.ID+" should have statements")
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	dbMap, err := dbtest.NewSQLiteMap(ctx, ":memory:", nil)
+	test.AssertNotError(t, err, "creating SQLite map")
+	defer func() { _ = dbMap.Close() }()
+	migrations, err := Load(testMigrations, "migrations")
+	test.AssertNotError(t, err, "loading migrations")
+	clk := clock.NewFake()
+	clk.Set(time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC))
+	m := New(dbMap, migrations, clk)
+
+	statuses, err := m.Status(ctx)
+	test.AssertNotError(t, err, "getting status")
+	test.AssertDeepEquals(t, statuses, []Status{
+		{ID: "20230101000000_Widgets.sql"},
+		{ID: "20230201000000_Trigger.sql"},
+	})
+
+	// Planning doesn't apply anything.
+	plan, err := m.PlanUp(ctx)
+	test.AssertNotError(t, err, "planning up")
+	test.AssertDeepEquals(t, plan, migrations)
+	_, err = dbMap.ExecContext(ctx, "INSERT INTO widgets (name) VALUES ('gadget')")
+	test.AssertError(t, err, "widgets shouldn't exist before migrating")
+
+	applied, err := m.Up(ctx)
+	test.AssertNotError(t, err, "migrating up")
+	test.AssertDeepEquals(t, applied, migrations)
+	_, err = dbMap.ExecContext(ctx, "INSERT INTO widgets (name) VALUES ('gadget')")
+	test.AssertNotError(t, err, "inserting widget")
+	name, err := dbMap.SelectStr(ctx, "SELECT name FROM widgets")
+	test.AssertNotError(t, err, "selecting widget")
+	test.AssertEquals(t, name, "GADGET")
+	statuses, err = m.Status(ctx)
+	test.AssertNotError(t, err, "getting status")
+	for _, status := range statuses {
+		test.AssertEquals(t, status.AppliedAt, clk.Now())
+	}
+
+	// Applying again is a no-op.
+	applied, err = m.Up(ctx)
+	test.AssertNotError(t, err, "migrating up again")
+	test.AssertEquals(t, len(applied), 0)
+
+	// Rolling back starts from the most recently applied.
+	plan, err = m.PlanDown(ctx, 1)
+	test.AssertNotError(t, err, "planning down")
+	test.AssertDeepEquals(t, plan, migrations[1:])
+	rolledBack, err := m.Down(ctx, 1)
+	test.AssertNotError(t, err, "migrating down")
+	test.AssertDeepEquals(t, rolledBack, migrations[1:])
+	statuses, err = m.Status(ctx)
+	test.AssertNotError(t, err, "getting status")
+	test.AssertEquals(t, statuses[0].AppliedAt, clk.Now())
+	test.Assert(t, statuses[1].AppliedAt.IsZero(), "trigger migration should be pending")
+
+	rolledBack, err = m.Down(ctx, 5)
+	test.AssertNotError(t, err, "migrating down")
+	test.AssertDeepEquals(t, rolledBack, migrations[:1])
+	_, err = dbMap.ExecContext(ctx, "INSERT INTO widgets (name) VALUES ('gadget')")
+	test.AssertError(t, err, "widgets shouldn't exist after rolling back")
+
+	// A failing migration is rolled back, leaving those before it applied.
+	broken := append(migrations[:1:1], Migration{ID: "20230301000000_Broken.sql", Up: []string{"CREATE TABLE oops (;"}})
+	m = New(dbMap, broken, clk)
+	applied, err = m.Up(ctx)
+	test.AssertError(t, err, "expected broken migration to fail")
+	test.AssertDeepEquals(t, applied, migrations[:1])
+	plan, err = m.PlanUp(ctx)
+	test.AssertNotError(t, err, "planning up")
+	test.AssertDeepEquals(t, plan, broken[1:])
+
+	// Migrations which were applied, but are unknown, are an error.
+	m = New(dbMap, nil, clk)
+	_, err = m.Status(ctx)
+	test.AssertError(t, err, "expected error for unknown applied migration")
+}
@@ -3,13 +3,14 @@ package db
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 )
 
 // MultiInserter makes it easy to construct a
 // `INSERT INTO table (...) VALUES ... RETURNING id;`
 // query which inserts multiple rows into the same table. It can also execute
-// the resulting query.
+// the resulting query, optionally split into chunks of a maximum number of rows.
 type MultiInserter struct {
 	// These are validated by the constructor as containing only characters
 	// that are allowed in an unquoted identifier.
@@ -18,6 +19,10 @@ type MultiInserter struct {
 	fields          []string
 	returningColumn string
 
+	// maxRows is the maximum number of rows inserted by each statement. If
+	// zero, all rows are inserted by a single statement.
+	maxRows int
+
 	values [][]interface{}
 }
 
@@ -68,13 +73,95 @@ func (mi *MultiInserter) Add(row []interface{}) error {
 	return nil
 }
 
+// AddStruct registers another row to be included in the Insert query, whose
+// values are the fields of v, a struct or pointer to a struct, which map to
+// each of the inserter's fields. Like borp, a struct field maps to the column
+// named by its `db` tag, if any, or otherwise by its name. Column names are
+// matched case-insensitively, as MariaDB does.
+func (mi *MultiInserter) AddStruct(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("expected a struct or pointer to a struct, got %T", v)
+	}
+	row := make([]interface{}, len(mi.fields))
+	for i, field := range mi.fields {
+		fv, ok := structFieldForColumn(rv, field)
+		if !ok {
+			return fmt.Errorf("%T has no field for column %q", v, field)
+		}
+		row[i] = fv.Interface()
+	}
+	return mi.Add(row)
+}
+
+// structFieldForColumn returns the exported field of the struct rv, including
+// the fields of embedded structs, which maps to the named column.
+func structFieldForColumn(rv reflect.Value, column string) (reflect.Value, bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("db"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			// The exported fields of an embedded struct are promoted, even if
+			// the struct's type is unexported.
+			fv, ok := structFieldForColumn(rv.Field(i), column)
+			if ok {
+				return fv, true
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, column) {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// SetMaxRowsPerStatement limits the number of rows inserted by each statement
+// executed by Insert, so that large batches don't exceed the maximum size of a
+// query. If n is zero, the default, all rows are inserted by a single
+// statement.
+func (mi *MultiInserter) SetMaxRowsPerStatement(n int) {
+	mi.maxRows = n
+}
+
+// chunks returns the registered rows split into chunks of at most maxRows
+// rows.
+func (mi *MultiInserter) chunks() [][][]interface{} {
+	if mi.maxRows <= 0 || len(mi.values) <= mi.maxRows {
+		return [][][]interface{}{mi.values}
+	}
+	var chunks [][][]interface{}
+	for start := 0; start < len(mi.values); start += mi.maxRows {
+		end := min(start+mi.maxRows, len(mi.values))
+		chunks = append(chunks, mi.values[start:end])
+	}
+	return chunks
+}
+
 // query returns the formatted query string, and the slice of arguments for
-// for borp to use in place of the query's question marks. Currently only
-// used by .Insert(), below.
+// borp to use in place of the query's question marks, which insert all of the
+// registered rows.
 func (mi *MultiInserter) query() (string, []interface{}) {
+	return mi.queryFor(mi.values)
+}
+
+// queryFor returns the formatted query string, and the slice of arguments for
+// borp to use in place of the query's question marks, which insert the
+// provided rows. Used by .Insert(), below.
+func (mi *MultiInserter) queryFor(values [][]interface{}) (string, []interface{}) {
 	var questionsBuf strings.Builder
 	var queryArgs []interface{}
-	for _, row := range mi.values {
+	for _, row := range values {
 		// Safety: We are interpolating a string that will be used in a SQL
 		// query, but we constructed that string in this function and know it
 		// consists only of question marks joined with commas.
@@ -103,15 +190,31 @@ func (mi *MultiInserter) query() (string, []interface{}) {
 
 // Insert inserts all the collected rows into the database represented by
 // `queryer`. If a non-empty returningColumn was provided, then it returns
-// the list of values from that column returned by the query.
+// the list of values from that column returned by the query. If a maximum
+// number of rows per statement was set the rows are inserted by multiple
+// statements, in which case `queryer` should be a Transaction so that they are
+// inserted atomically.
 func (mi *MultiInserter) Insert(ctx context.Context, queryer Queryer) ([]int64, error) {
-	query, queryArgs := mi.query()
+	ids := make([]int64, 0, len(mi.values))
+	for _, chunk := range mi.chunks() {
+		var err error
+		ids, err = mi.insert(ctx, queryer, chunk, ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// insert inserts the provided rows with a single statement, appending the
+// values of the returningColumn, if any, to ids.
+func (mi *MultiInserter) insert(ctx context.Context, queryer Queryer, values [][]interface{}, ids []int64) ([]int64, error) {
+	query, queryArgs := mi.queryFor(values)
 	rows, err := queryer.QueryContext(ctx, query, queryArgs...)
 	if err != nil {
 		return nil, err
 	}
 
-	ids := make([]int64, 0, len(mi.values))
 	if mi.returningColumn != "" {
 		for rows.Next() {
 			var id int64
@@ -129,6 +232,17 @@ func (mi *MultiInserter) Insert(ctx context.Context, queryer Queryer) ([]int64,
 	// on it will panic— but here we choose to treat it like an empty list,
 	// and skip calling `Close()` to avoid the panic.
 	if rows != nil {
+		// Some drivers, e.g. SQLite, don't execute a statement until its
+		// results are read, and report any error it returns via rows.Err().
+		if mi.returningColumn == "" {
+			for rows.Next() {
+			}
+		}
+		err = rows.Err()
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
 		err = rows.Close()
 		if err != nil {
 			return nil, err
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"testing"
 
 	"github.com/letsencrypt/boulder/test"
@@ -79,3 +80,98 @@ func TestMultiQuery(t *testing.T) {
 	test.AssertEquals(t, query, "INSERT INTO table (a,b,c) VALUES (?,?,?),(?,?,?) RETURNING id")
 	test.AssertDeepEquals(t, queryArgs, []interface{}{"one", "two", "three", "egy", "kettö", "három"})
 }
+
+func TestMultiAddStruct(t *testing.T) {
+	type embedded struct {
+		C string `db:"colC"`
+	}
+	type row struct {
+		A       string
+		B       int    `db:"colB"`
+		Ignored string `db:"-"`
+		embedded
+	}
+
+	mi, err := NewMultiInserter("table", []string{"a", "colB", "colC"}, "")
+	test.AssertNotError(t, err, "Failed to create test MultiInserter")
+
+	err = mi.AddStruct(row{A: "one", B: 2, embedded: embedded{C: "three"}})
+	test.AssertNotError(t, err, "Adding struct shouldn't fail")
+	err = mi.AddStruct(&row{A: "egy", B: 2, embedded: embedded{C: "három"}})
+	test.AssertNotError(t, err, "Adding pointer to struct shouldn't fail")
+	test.AssertDeepEquals(t, mi.values, [][]interface{}{{"one", 2, "three"}, {"egy", 2, "három"}})
+
+	err = mi.AddStruct("not a struct")
+	test.AssertError(t, err, "Adding a non-struct should fail")
+
+	mi, err = NewMultiInserter("table", []string{"a", "Ignored"}, "")
+	test.AssertNotError(t, err, "Failed to create test MultiInserter")
+	err = mi.AddStruct(row{})
+	test.AssertError(t, err, "Adding a struct without a field for each column should fail")
+	test.AssertEquals(t, len(mi.values), 0)
+}
+
+func TestMultiChunks(t *testing.T) {
+	mi, err := NewMultiInserter("table", []string{"a"}, "")
+	test.AssertNotError(t, err, "Failed to create test MultiInserter")
+	for i := 0; i < 5; i++ {
+		err = mi.Add([]interface{}{i})
+		test.AssertNotError(t, err, "Failed to insert test row")
+	}
+
+	test.AssertEquals(t, len(mi.chunks()), 1)
+
+	mi.SetMaxRowsPerStatement(2)
+	chunks := mi.chunks()
+	test.AssertEquals(t, len(chunks), 3)
+	test.AssertDeepEquals(t, chunks[2], [][]interface{}{{4}})
+	query, queryArgs := mi.queryFor(chunks[0])
+	test.AssertEquals(t, query, "INSERT INTO table (a) VALUES (?),(?)")
+	test.AssertDeepEquals(t, queryArgs, []interface{}{0, 1})
+
+	mi.SetMaxRowsPerStatement(5)
+	test.AssertEquals(t, len(mi.chunks()), 1)
+}
+
+func TestMultiInsert(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+
+	mi, err := NewMultiInserter("widgets", []string{"Name", "Value"}, "ID")
+	test.AssertNotError(t, err, "Failed to create test MultiInserter")
+	mi.SetMaxRowsPerStatement(2)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		err = mi.AddStruct(sqliteTestModel{Name: name, Value: []byte(name)})
+		test.AssertNotError(t, err, "Failed to add test row")
+	}
+
+	var ids []int64
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		var err error
+		ids, err = mi.Insert(ctx, tx)
+		return err
+	})
+	test.AssertNotError(t, err, "Failed to insert rows")
+	test.AssertDeepEquals(t, ids, []int64{1, 2, 3, 4, 5})
+	count, err := dbMap.SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "counting widgets")
+	test.AssertEquals(t, count.Int64, int64(5))
+
+	// A failed chunk fails the whole insert, and the transaction is rolled
+	// back.
+	mi, err = NewMultiInserter("widgets", []string{"Name"}, "")
+	test.AssertNotError(t, err, "Failed to create test MultiInserter")
+	mi.SetMaxRowsPerStatement(1)
+	for _, name := range []string{"f", "a"} {
+		err = mi.Add([]interface{}{name})
+		test.AssertNotError(t, err, "Failed to add test row")
+	}
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		_, err := mi.Insert(ctx, tx)
+		return err
+	})
+	test.Assert(t, IsDuplicate(err), "expected a duplicate error")
+	count, err = dbMap.SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "counting widgets")
+	test.AssertEquals(t, count.Int64, int64(5))
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/letsencrypt/borp"
+)
+
+// ErrConflict is returned by UpdateVersioned and CompareAndSwap when the row
+// being updated was changed, or deleted, since it was read, so that the update
+// would have overwritten another writer's changes. The caller should re-read
+// the row and decide whether to try again.
+type ErrConflict struct {
+	Table string
+	// RowExists is true if the row still exists, but with a different
+	// version, and false if it has been deleted or whether it exists isn't
+	// known.
+	RowExists bool
+}
+
+func (e ErrConflict) Error() string {
+	if e.RowExists {
+		return fmt.Sprintf("conflicting update to %s: the row was changed since it was read", e.Table)
+	}
+	return fmt.Sprintf("conflicting update to %s: the row was changed or deleted since it was read", e.Table)
+}
+
+// IsConflict returns true if err wraps an ErrConflict.
+func IsConflict(err error) bool {
+	return errors.As(err, &ErrConflict{})
+}
+
+// UpdateVersioned updates the row of holder, whose table must have a version
+// column, registered with borp's SetVersionCol, only if the row's version is
+// still that of holder. On success, holder's version is incremented, along
+// with the row's; otherwise an ErrConflict is returned.
+func UpdateVersioned(ctx context.Context, updater Updater, holder interface{}) error {
+	_, err := updater.Update(ctx, holder)
+	var lockErr borp.OptimisticLockError
+	if errors.As(err, &lockErr) {
+		return ErrConflict{Table: lockErr.TableName, RowExists: lockErr.RowExists}
+	}
+	return err
+}
+
+// CompareAndSwap executes query, an UPDATE of a single row whose WHERE clause
+// requires the row to be as it was read, e.g. by its version or updatedAt
+// column, which the UPDATE also advances:
+//
+//	UPDATE orders SET status = ?, version = version + 1 WHERE id = ? AND version = ?
+//
+// If no row matches, because the row has changed or been deleted, an
+// ErrConflict is returned. Since the SA's connections set clientFoundRows, a
+// matching row counts even if the update changes none of its values.
+func CompareAndSwap(ctx context.Context, execer Execer, query string, args ...interface{}) error {
+	err := ExecExpectingRows(ctx, execer, 1, query, args...)
+	var rowsErr ErrUnexpectedRows
+	if errors.As(err, &rowsErr) && rowsErr.Affected == 0 {
+		return ErrConflict{Table: rowsErr.Table}
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/letsencrypt/borp"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// versionedTestModel is a row with a version column, for optimistic locking.
+type versionedTestModel struct {
+	ID      int64
+	Name    string
+	Version int64
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	ctx := context.Background()
+	dbMap, err := NewSQLiteMap(ctx, ":memory:", func(dbMap *borp.DbMap) {
+		dbMap.AddTableWithName(versionedTestModel{}, "versioned").SetKeys(true, "ID").SetVersionCol("Version")
+	})
+	test.AssertNotError(t, err, "creating SQLite map")
+	defer func() { _ = dbMap.Close() }()
+
+	w := &versionedTestModel{Name: "widget"}
+	test.AssertNotError(t, dbMap.Insert(ctx, w), "inserting widget")
+	stale := *w
+
+	// An update of the current version succeeds, advancing it.
+	w.Name = "gadget"
+	test.AssertNotError(t, UpdateVersioned(ctx, dbMap, w), "updating widget")
+	test.AssertEquals(t, w.Version, stale.Version+1)
+
+	// An update of an older version conflicts, and changes nothing.
+	stale.Name = "gizmo"
+	err = UpdateVersioned(ctx, dbMap, &stale)
+	test.Assert(t, IsConflict(err), "expected conflict")
+	var conflict ErrConflict
+	test.AssertErrorWraps(t, err, &conflict)
+	test.AssertDeepEquals(t, conflict, ErrConflict{Table: "versioned", RowExists: true})
+	name, err := dbMap.SelectStr(ctx, "SELECT Name FROM versioned WHERE ID = ?", w.ID)
+	test.AssertNotError(t, err, "selecting name")
+	test.AssertEquals(t, name, "gadget")
+
+	// As does an update of a deleted row.
+	_, err = dbMap.Delete(ctx, w)
+	test.AssertNotError(t, err, "deleting widget")
+	err = UpdateVersioned(ctx, dbMap, w)
+	test.AssertErrorWraps(t, err, &conflict)
+	test.Assert(t, !conflict.RowExists, "deleted row shouldn't exist")
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	w := &sqliteTestModel{Name: "widget"}
+	test.AssertNotError(t, dbMap.Insert(ctx, w), "inserting widget")
+
+	const swap = "UPDATE widgets SET Name = ? WHERE ID = ? AND Name = ?"
+	test.AssertNotError(t, CompareAndSwap(ctx, dbMap, swap, "gadget", w.ID, "widget"), "swapping name")
+	err := CompareAndSwap(ctx, dbMap, swap, "gizmo", w.ID, "widget")
+	test.Assert(t, IsConflict(err), "expected conflict")
+	test.AssertEquals(t, err.Error(), "conflicting update to widgets: the row was changed or deleted since it was read")
+
+	// Failed updates return their own errors.
+	err = CompareAndSwap(ctx, dbMap, "UPDATE doesNotExist SET Name = ? WHERE ID = ?", "gizmo", w.ID)
+	test.AssertError(t, err, "expected error for missing table")
+	test.Assert(t, !IsConflict(err), "failed update shouldn't conflict")
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// KeysetPager iterates over the rows of the table mapped to T in pages ordered
+// by an integer ID column, using keyset pagination: each page is selected with
+// `WHERE id > ? ORDER BY id LIMIT ?`, where the cursor is the ID of the last
+// row of the previous page. Unlike OFFSET pagination, the cost of selecting a
+// page doesn't grow with the number of rows which precede it.
+type KeysetPager[T any] struct {
+	selector MappedSelector[T]
+
+	// idColumn is validated by the constructor as containing only characters
+	// that are allowed in an unquoted identifier.
+	idColumn string
+	id       func(*T) int64
+	pageSize int
+}
+
+// NewKeysetPager returns a KeysetPager which selects pages of at most pageSize
+// rows of the table mapped to T, ordered by idColumn. The id function returns
+// the value of idColumn for a row, and is used to advance the cursor.
+//
+// Safety: `idColumn` must be known at compile time. It must not contain a
+// user-controlled string.
+func NewKeysetPager[T any](executor MappedExecutor, idColumn string, id func(*T) int64, pageSize int) (*KeysetPager[T], error) {
+	err := validMariaDBUnquotedIdentifier(idColumn)
+	if err != nil {
+		return nil, err
+	}
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive, got %d", pageSize)
+	}
+	selector, err := NewMappedSelector[T](executor)
+	if err != nil {
+		return nil, err
+	}
+	return &KeysetPager[T]{
+		selector: selector,
+		idColumn: idColumn,
+		id:       id,
+		pageSize: pageSize,
+	}, nil
+}
+
+// ForEachPage selects the rows whose ID is greater than after, and which match
+// the optional conditions in clauses (e.g. "status = ?", with the
+// corresponding args), and calls f with each page of them in order. It stops
+// at the first page shorter than the page size, or at the first error returned
+// by f, which is returned. It returns the cursor: the ID of the last row of the
+// last page for which f succeeded, or after if there were none, from which a
+// subsequent call can resume.
+//
+// The caller is responsible for ensuring that the clauses argument does not
+// contain any user-influenced input.
+func (p *KeysetPager[T]) ForEachPage(ctx context.Context, after int64, clauses string, args []interface{}, f func(page []*T) error) (int64, error) {
+	where := fmt.Sprintf("WHERE %s > ?", p.idColumn)
+	if clauses != "" {
+		where = fmt.Sprintf("WHERE (%s) AND %s > ?", clauses, p.idColumn)
+	}
+	// Safety: we are interpolating `p.idColumn` into an SQL query. We know it
+	// is a valid unquoted identifier in MariaDB because we verified that in
+	// the constructor.
+	query := fmt.Sprintf("%s ORDER BY %s LIMIT ?", where, p.idColumn)
+
+	cursor := after
+	for {
+		page, err := p.page(ctx, query, append(args[:len(args):len(args)], cursor, p.pageSize))
+		if err != nil {
+			return cursor, err
+		}
+		if len(page) == 0 {
+			return cursor, nil
+		}
+		err = f(page)
+		if err != nil {
+			return cursor, err
+		}
+		cursor = p.id(page[len(page)-1])
+		if len(page) < p.pageSize {
+			return cursor, nil
+		}
+	}
+}
+
+// page selects a single page of rows.
+func (p *KeysetPager[T]) page(ctx context.Context, query string, args []interface{}) ([]*T, error) {
+	rows, err := p.selector.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := make([]*T, 0, p.pageSize)
+	for rows.Next() {
+		row, err := rows.Get()
+		if err != nil {
+			return nil, err
+		}
+		page = append(page, row)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("reading db rows: %w", err)
+	}
+	return page, nil
+}
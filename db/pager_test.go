@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewKeysetPager(t *testing.T) {
+	dbMap := testSQLiteMap(t)
+	id := func(w *sqliteTestModel) int64 { return w.ID }
+
+	_, err := NewKeysetPager(dbMap, "foo\"bar", id, 10)
+	test.AssertError(t, err, "expected error for invalid ID column name")
+	_, err = NewKeysetPager(dbMap, "id", id, 0)
+	test.AssertError(t, err, "expected error for zero page size")
+	_, err = NewKeysetPager(dbMap, "id", id, 10)
+	test.AssertNotError(t, err, "creating pager")
+}
+
+func TestKeysetPager(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	for i := 0; i < 7; i++ {
+		err := dbMap.Insert(ctx, &sqliteTestModel{Name: fmt.Sprintf("widget%d", i), Value: []byte{byte(i % 2)}})
+		test.AssertNotError(t, err, "inserting widget")
+	}
+
+	pager, err := NewKeysetPager(dbMap, "id", func(w *sqliteTestModel) int64 { return w.ID }, 3)
+	test.AssertNotError(t, err, "creating pager")
+
+	// Every row, in order, in pages of at most three rows.
+	var pages [][]int64
+	collect := func(page []*sqliteTestModel) error {
+		var ids []int64
+		for _, w := range page {
+			ids = append(ids, w.ID)
+		}
+		pages = append(pages, ids)
+		return nil
+	}
+	cursor, err := pager.ForEachPage(ctx, 0, "", nil, collect)
+	test.AssertNotError(t, err, "paging widgets")
+	test.AssertDeepEquals(t, pages, [][]int64{{1, 2, 3}, {4, 5, 6}, {7}})
+	test.AssertEquals(t, cursor, int64(7))
+
+	// Resuming from the cursor selects nothing new.
+	pages = nil
+	cursor, err = pager.ForEachPage(ctx, cursor, "", nil, collect)
+	test.AssertNotError(t, err, "paging widgets")
+	test.AssertEquals(t, len(pages), 0)
+	test.AssertEquals(t, cursor, int64(7))
+
+	// Additional conditions are combined with the cursor.
+	pages = nil
+	cursor, err = pager.ForEachPage(ctx, 1, "value = ? OR name = ?", []interface{}{[]byte{0}, "widget1"}, collect)
+	test.AssertNotError(t, err, "paging widgets")
+	test.AssertDeepEquals(t, pages, [][]int64{{2, 3, 5}, {7}})
+	test.AssertEquals(t, cursor, int64(7))
+
+	// An error from the callback stops paging, and the cursor is left at the
+	// last page which succeeded.
+	oops := errors.New("oops")
+	var calls int
+	cursor, err = pager.ForEachPage(ctx, 0, "", nil, func(page []*sqliteTestModel) error {
+		calls++
+		if calls == 2 {
+			return oops
+		}
+		return nil
+	})
+	test.AssertErrorIs(t, err, oops)
+	test.AssertEquals(t, calls, 2)
+	test.AssertEquals(t, cursor, int64(3))
+}
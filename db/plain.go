@@ -0,0 +1,366 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Compile-time checks that the plain wrappers implement the context-first
+// interfaces.
+var (
+	_ DatabaseMap = (*PlainMap)(nil)
+	_ Executor    = (*PlainMap)(nil)
+	_ Transaction = PlainTransaction{}
+)
+
+// errNeedsMapping is returned by the operations of a PlainMap which require
+// borp to map a type to a table.
+var errNeedsMapping = errors.New("not supported without a borp table mapping; use a WrappedMap")
+
+// PlainMap is like a WrappedMap, with the same instrumentation and error
+// wrapping, but it operates directly on a *sql.DB, without borp. It suits
+// components which only run hand-written SQL, avoiding the reflection of
+// borp's table mapping.
+//
+// Without a mapping, Get, Insert, Update, and Delete always fail, and Select
+// and SelectOne support only holders of a single column's value: a pointer to
+// a slice of values, or to a value, respectively, where a value is anything
+// database/sql can scan into and not a struct, other than a time.Time or an
+// sql.Scanner. QueryContext, Query, and ExecContext are unrestricted.
+type PlainMap struct {
+	db *sql.DB
+	instrumentation
+}
+
+// NewPlainMap returns a *PlainMap over db which, like a WrappedMap from
+// NewInstrumentedWrappedMap, applies the provided default timeouts and, if
+// stats is non-nil, exports the metrics of its operations.
+func NewPlainMap(db *sql.DB, timeouts QueryTimeouts, stats prometheus.Registerer) (*PlainMap, error) {
+	t, err := newQueryTimeouts(timeouts, stats)
+	if err != nil {
+		return nil, err
+	}
+	m := &PlainMap{
+		db: db,
+		instrumentation: instrumentation{
+			timeouts: t,
+			tracer:   newPlainQueryTracer(db),
+		},
+	}
+	if stats != nil {
+		m.metrics, err = newQueryMetrics(stats)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Close closes the underlying database connection pool.
+func (m *PlainMap) Close() error {
+	return m.db.Close()
+}
+
+func (m *PlainMap) executor() WrappedExecutor {
+	return WrappedExecutor{sqlExecutor: plainExecutor{conn: m.db}, instrumentation: m.instrumentation}
+}
+
+func (m *PlainMap) Get(ctx context.Context, holder interface{}, keys ...interface{}) (interface{}, error) {
+	return m.executor().Get(ctx, holder, keys...)
+}
+
+func (m *PlainMap) Insert(ctx context.Context, list ...interface{}) error {
+	return m.executor().Insert(ctx, list...)
+}
+
+func (m *PlainMap) Update(ctx context.Context, list ...interface{}) (int64, error) {
+	return m.executor().Update(ctx, list...)
+}
+
+func (m *PlainMap) Delete(ctx context.Context, list ...interface{}) (int64, error) {
+	return m.executor().Delete(ctx, list...)
+}
+
+func (m *PlainMap) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	return m.executor().Select(ctx, holder, query, args...)
+}
+
+func (m *PlainMap) SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
+	return m.executor().SelectOne(ctx, holder, query, args...)
+}
+
+func (m *PlainMap) SelectNullInt(ctx context.Context, query string, args ...interface{}) (sql.NullInt64, error) {
+	return m.executor().SelectNullInt(ctx, query, args...)
+}
+
+func (m *PlainMap) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
+	return m.executor().SelectStr(ctx, query, args...)
+}
+
+func (m *PlainMap) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return m.executor().QueryContext(ctx, query, args...)
+}
+
+// Query is like QueryContext, but returns *WrappedRows, whose errors identify
+// the query's table.
+func (m *PlainMap) Query(ctx context.Context, query string, args ...interface{}) (*WrappedRows, error) {
+	return m.executor().Query(ctx, query, args...)
+}
+
+func (m *PlainMap) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return m.executor().QueryRowContext(ctx, query, args...)
+}
+
+func (m *PlainMap) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return m.executor().ExecContext(ctx, query, args...)
+}
+
+func (m *PlainMap) BeginTx(ctx context.Context) (Transaction, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ErrDatabaseOp{
+			Op:  "begin transaction",
+			Err: err,
+		}
+	}
+	return PlainTransaction{tx: tx, instrumentation: m.instrumentation}, nil
+}
+
+// PlainTransaction is the transaction of a PlainMap, operating directly on a
+// *sql.Tx, with the same restrictions. Like a WrappedTransaction, one returned
+// by the BeginTx method of another is nested within it, using a savepoint.
+type PlainTransaction struct {
+	tx *sql.Tx
+	// savepoint is the name of the savepoint a nested transaction began with,
+	// and ctx the context it began with. Both are zero for a top-level
+	// transaction.
+	savepoint string
+	ctx       context.Context
+	depth     int
+	instrumentation
+}
+
+func (tx PlainTransaction) executor() WrappedExecutor {
+	return WrappedExecutor{sqlExecutor: plainExecutor{conn: tx.tx}, instrumentation: tx.instrumentation}
+}
+
+func (tx PlainTransaction) Commit() error {
+	if tx.savepoint != "" {
+		_, err := tx.tx.ExecContext(tx.ctx, "RELEASE SAVEPOINT "+tx.savepoint)
+		return err
+	}
+	return tx.tx.Commit()
+}
+
+func (tx PlainTransaction) Rollback() error {
+	if tx.savepoint != "" {
+		_, err := tx.tx.ExecContext(tx.ctx, "ROLLBACK TO SAVEPOINT "+tx.savepoint)
+		if err != nil {
+			return err
+		}
+		_, err = tx.tx.ExecContext(tx.ctx, "RELEASE SAVEPOINT "+tx.savepoint)
+		return err
+	}
+	return tx.tx.Rollback()
+}
+
+// BeginTx begins a transaction nested within tx, by creating a savepoint.
+func (tx PlainTransaction) BeginTx(ctx context.Context) (Transaction, error) {
+	nested := tx
+	nested.depth++
+	nested.savepoint = fmt.Sprintf("boulder_savepoint_%d", nested.depth)
+	nested.ctx = ctx
+	_, err := tx.tx.ExecContext(ctx, "SAVEPOINT "+nested.savepoint)
+	if err != nil {
+		return nil, ErrDatabaseOp{
+			Op:  "begin nested transaction",
+			Err: err,
+		}
+	}
+	return nested, nil
+}
+
+func (tx PlainTransaction) Get(ctx context.Context, holder interface{}, keys ...interface{}) (interface{}, error) {
+	return tx.executor().Get(ctx, holder, keys...)
+}
+
+func (tx PlainTransaction) Insert(ctx context.Context, list ...interface{}) error {
+	return tx.executor().Insert(ctx, list...)
+}
+
+func (tx PlainTransaction) Update(ctx context.Context, list ...interface{}) (int64, error) {
+	return tx.executor().Update(ctx, list...)
+}
+
+func (tx PlainTransaction) Delete(ctx context.Context, list ...interface{}) (int64, error) {
+	return tx.executor().Delete(ctx, list...)
+}
+
+func (tx PlainTransaction) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	return tx.executor().Select(ctx, holder, query, args...)
+}
+
+func (tx PlainTransaction) SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
+	return tx.executor().SelectOne(ctx, holder, query, args...)
+}
+
+func (tx PlainTransaction) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.executor().QueryContext(ctx, query, args...)
+}
+
+// Query is like QueryContext, but returns *WrappedRows, whose errors identify
+// the query's table.
+func (tx PlainTransaction) Query(ctx context.Context, query string, args ...interface{}) (*WrappedRows, error) {
+	return tx.executor().Query(ctx, query, args...)
+}
+
+func (tx PlainTransaction) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.executor().ExecContext(ctx, query, args...)
+}
+
+// sqlConn is implemented by both *sql.DB and *sql.Tx.
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// plainExecutor implements borp.SqlExecutor directly on a *sql.DB or *sql.Tx,
+// so that a WrappedExecutor can instrument it, for the operations which don't
+// require a table mapping.
+type plainExecutor struct {
+	conn sqlConn
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// isValueType returns true if database/sql can scan a single column into a
+// value of type t, as far as a PlainMap supports.
+func isValueType(t reflect.Type) bool {
+	return t.Kind() != reflect.Struct || t == timeType || reflect.PointerTo(t).Implements(scannerType)
+}
+
+func (pe plainExecutor) Get(ctx context.Context, holder interface{}, keys ...interface{}) (interface{}, error) {
+	return nil, errNeedsMapping
+}
+
+func (pe plainExecutor) Insert(ctx context.Context, list ...interface{}) error {
+	return errNeedsMapping
+}
+
+func (pe plainExecutor) Update(ctx context.Context, list ...interface{}) (int64, error) {
+	return 0, errNeedsMapping
+}
+
+func (pe plainExecutor) Delete(ctx context.Context, list ...interface{}) (int64, error) {
+	return 0, errNeedsMapping
+}
+
+// Select appends the value of the single column of each row to holder, which
+// must be a pointer to a slice of values. Nothing is returned, as when borp
+// selects into a slice.
+func (pe plainExecutor) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(holder)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Slice || !isValueType(v.Elem().Type().Elem()) {
+		return nil, fmt.Errorf("selecting into %T: %w", holder, errNeedsMapping)
+	}
+	rows, err := pe.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	slice := v.Elem()
+	for rows.Next() {
+		elem := reflect.New(slice.Type().Elem())
+		err = rows.Scan(elem.Interface())
+		if err != nil {
+			return nil, err
+		}
+		slice = reflect.Append(slice, elem.Elem())
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	v.Elem().Set(slice)
+	return nil, nil
+}
+
+// SelectOne scans the single column of the single row returned by query into
+// holder, which must be a pointer to a value. Like borp, it returns
+// sql.ErrNoRows if there is no row.
+func (pe plainExecutor) SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
+	v := reflect.ValueOf(holder)
+	if v.Kind() != reflect.Pointer || !isValueType(v.Elem().Type()) {
+		return fmt.Errorf("selecting into %T: %w", holder, errNeedsMapping)
+	}
+	return pe.conn.QueryRowContext(ctx, query, args...).Scan(holder)
+}
+
+// selectVal scans the first column of the first row returned by query into
+// holder. Like borp's Select functions, it leaves holder unchanged if there
+// are no rows.
+func (pe plainExecutor) selectVal(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
+	err := pe.conn.QueryRowContext(ctx, query, args...).Scan(holder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+func (pe plainExecutor) SelectInt(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	var i int64
+	err := pe.selectVal(ctx, &i, query, args...)
+	return i, err
+}
+
+func (pe plainExecutor) SelectNullInt(ctx context.Context, query string, args ...interface{}) (sql.NullInt64, error) {
+	var i sql.NullInt64
+	err := pe.selectVal(ctx, &i, query, args...)
+	return i, err
+}
+
+func (pe plainExecutor) SelectFloat(ctx context.Context, query string, args ...interface{}) (float64, error) {
+	var f float64
+	err := pe.selectVal(ctx, &f, query, args...)
+	return f, err
+}
+
+func (pe plainExecutor) SelectNullFloat(ctx context.Context, query string, args ...interface{}) (sql.NullFloat64, error) {
+	var f sql.NullFloat64
+	err := pe.selectVal(ctx, &f, query, args...)
+	return f, err
+}
+
+func (pe plainExecutor) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
+	var s string
+	err := pe.selectVal(ctx, &s, query, args...)
+	return s, err
+}
+
+func (pe plainExecutor) SelectNullStr(ctx context.Context, query string, args ...interface{}) (sql.NullString, error) {
+	var s sql.NullString
+	err := pe.selectVal(ctx, &s, query, args...)
+	return s, err
+}
+
+func (pe plainExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return pe.conn.ExecContext(ctx, query, args...)
+}
+
+func (pe plainExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return pe.conn.QueryContext(ctx, query, args...)
+}
+
+func (pe plainExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return pe.conn.QueryRowContext(ctx, query, args...)
+}
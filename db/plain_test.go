@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func testPlainMap(t *testing.T) *PlainMap {
+	t.Helper()
+	dbConn, err := sql.Open("sqlite3", ":memory:")
+	test.AssertNotError(t, err, "opening SQLite database")
+	dbConn.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = dbConn.Close() })
+	_, err = dbConn.Exec("CREATE TABLE widgets (ID INTEGER PRIMARY KEY AUTOINCREMENT, Name TEXT UNIQUE, Value BLOB, Created DATETIME)")
+	test.AssertNotError(t, err, "creating table")
+
+	m, err := NewPlainMap(dbConn, QueryTimeouts{Read: time.Second}, prometheus.NewRegistry())
+	test.AssertNotError(t, err, "creating PlainMap")
+	return m
+}
+
+func TestPlainMap(t *testing.T) {
+	ctx := context.Background()
+	m := testPlainMap(t)
+	test.AssertEquals(t, m.tracer.system, semconv.DBSystemSqlite)
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, name := range []string{"gadget", "gizmo"} {
+		_, err := m.ExecContext(ctx, "INSERT INTO widgets (Name, Created) VALUES (?, ?)", name, created)
+		test.AssertNotError(t, err, "inserting widget")
+	}
+	_, err := m.ExecContext(ctx, "INSERT INTO widgets (Name) VALUES (?)", "gadget")
+	test.Assert(t, IsDuplicate(err), "expected duplicate error")
+
+	var names []string
+	_, err = m.Select(ctx, &names, "SELECT Name FROM widgets ORDER BY Name")
+	test.AssertNotError(t, err, "selecting names")
+	test.AssertDeepEquals(t, names, []string{"gadget", "gizmo"})
+
+	var id int64
+	err = m.SelectOne(ctx, &id, "SELECT ID FROM widgets WHERE Name = ?", "gizmo")
+	test.AssertNotError(t, err, "selecting ID")
+	test.AssertEquals(t, id, int64(2))
+
+	var gotCreated time.Time
+	err = m.SelectOne(ctx, &gotCreated, "SELECT Created FROM widgets WHERE ID = ?", id)
+	test.AssertNotError(t, err, "selecting time")
+	test.Assert(t, gotCreated.Equal(created), "wrong created time")
+
+	var value sql.NullString
+	err = m.SelectOne(ctx, &value, "SELECT Value FROM widgets WHERE ID = ?", id)
+	test.AssertNotError(t, err, "selecting scanner")
+	test.Assert(t, !value.Valid, "value should be NULL")
+
+	err = m.SelectOne(ctx, &id, "SELECT ID FROM widgets WHERE Name = ?", "doohickey")
+	test.Assert(t, IsNoRows(err), "expected no rows")
+
+	name, err := m.SelectStr(ctx, "SELECT Name FROM widgets WHERE ID = ?", 1)
+	test.AssertNotError(t, err, "selecting string")
+	test.AssertEquals(t, name, "gadget")
+	name, err = m.SelectStr(ctx, "SELECT Name FROM widgets WHERE ID = ?", 3)
+	test.AssertNotError(t, err, "selecting missing string")
+	test.AssertEquals(t, name, "")
+
+	count, err := m.SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "counting")
+	test.AssertEquals(t, count.Int64, int64(2))
+
+	rows, err := m.Query(ctx, "SELECT Name FROM widgets")
+	test.AssertNotError(t, err, "querying")
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	test.AssertNotError(t, rows.Err(), "iterating rows")
+	test.AssertNotError(t, rows.Close(), "closing rows")
+	test.AssertEquals(t, n, 2)
+
+	// Operations needing a table mapping fail, with a wrapped error.
+	err = m.Insert(ctx, &sqliteTestModel{Name: "doohickey"})
+	test.AssertErrorIs(t, err, errNeedsMapping)
+	var dbOpErr ErrDatabaseOp
+	test.Assert(t, errors.As(err, &dbOpErr), "expected ErrDatabaseOp")
+	_, err = m.Get(ctx, sqliteTestModel{}, 1)
+	test.AssertErrorIs(t, err, errNeedsMapping)
+	_, err = m.Update(ctx, &sqliteTestModel{ID: 1})
+	test.AssertErrorIs(t, err, errNeedsMapping)
+	_, err = m.Delete(ctx, &sqliteTestModel{ID: 1})
+	test.AssertErrorIs(t, err, errNeedsMapping)
+	var widgets []sqliteTestModel
+	_, err = m.Select(ctx, &widgets, "SELECT * FROM widgets")
+	test.AssertErrorIs(t, err, errNeedsMapping)
+	var widget sqliteTestModel
+	err = m.SelectOne(ctx, &widget, "SELECT * FROM widgets WHERE ID = 1")
+	test.AssertErrorIs(t, err, errNeedsMapping)
+}
+
+func TestPlainTransaction(t *testing.T) {
+	ctx := context.Background()
+	m := testPlainMap(t)
+
+	count := func() int64 {
+		t.Helper()
+		n, err := m.SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+		test.AssertNotError(t, err, "counting")
+		return n.Int64
+	}
+
+	tx, err := m.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning transaction")
+	_, err = tx.ExecContext(ctx, "INSERT INTO widgets (Name) VALUES (?)", "gadget")
+	test.AssertNotError(t, err, "inserting in transaction")
+
+	// A nested transaction's rollback undoes only its own writes.
+	nested, err := tx.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning nested transaction")
+	_, err = nested.ExecContext(ctx, "INSERT INTO widgets (Name) VALUES (?)", "gizmo")
+	test.AssertNotError(t, err, "inserting in nested transaction")
+	test.AssertNotError(t, nested.Rollback(), "rolling back nested transaction")
+
+	nested, err = tx.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning nested transaction")
+	_, err = nested.ExecContext(ctx, "INSERT INTO widgets (Name) VALUES (?)", "doohickey")
+	test.AssertNotError(t, err, "inserting in nested transaction")
+	test.AssertNotError(t, nested.Commit(), "committing nested transaction")
+
+	var names []string
+	_, err = tx.Select(ctx, &names, "SELECT Name FROM widgets ORDER BY Name")
+	test.AssertNotError(t, err, "selecting in transaction")
+	test.AssertDeepEquals(t, names, []string{"doohickey", "gadget"})
+	test.AssertNotError(t, tx.Commit(), "committing transaction")
+	test.AssertEquals(t, count(), int64(2))
+
+	tx, err = m.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning transaction")
+	_, err = tx.ExecContext(ctx, "DELETE FROM widgets")
+	test.AssertNotError(t, err, "deleting in transaction")
+	test.AssertNotError(t, tx.Rollback(), "rolling back transaction")
+	test.AssertEquals(t, count(), int64(2))
+
+	// WithTransaction works with a PlainMap, as with any DatabaseMap.
+	err = WithTransaction(ctx, m, func(tx Executor) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM widgets WHERE Name = ?", "gadget")
+		return err
+	})
+	test.AssertNotError(t, err, "deleting with WithTransaction")
+	test.AssertEquals(t, count(), int64(1))
+}
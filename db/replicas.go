@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Compile-time checks that ReplicaRoutedMap implements the context-first
+// interfaces.
+var (
+	_ DatabaseMap = (*ReplicaRoutedMap)(nil)
+	_ Executor    = (*ReplicaRoutedMap)(nil)
+)
+
+// ReplicaRoutedMap routes reads to replicas of a primary and everything else,
+// including transactions, to the primary. Each replica is health checked,
+// and reads skip any which is unhealthy or lags its primary by more than a
+// staleness threshold, so that they aren't served data old enough to cause
+// duplicate work. Healthy replicas are read from in turn, and if none is
+// healthy reads go to the primary.
+type ReplicaRoutedMap struct {
+	primary  *WrappedMap
+	replicas []routedReplica
+	next     atomic.Uint64
+
+	exclusions *prometheus.CounterVec
+	fallbacks  prometheus.Counter
+}
+
+type routedReplica struct {
+	*WrappedMap
+	checker *Checker
+}
+
+// NewReplicaRoutedMap returns a *ReplicaRoutedMap over primary and replicas,
+// each of which is checked every checkInterval, once started, and excluded
+// from reads while its check fails or finds it lagging by more than maxLag.
+// If stats is non-nil, the results of each replica's checks, labeled by its
+// index in replicas, the number of reads that skipped each, and the number
+// that went to the primary instead, are exported.
+func NewReplicaRoutedMap(primary *WrappedMap, replicas []*WrappedMap, checkInterval, maxLag time.Duration, stats prometheus.Registerer) (*ReplicaRoutedMap, error) {
+	if len(replicas) == 0 {
+		return nil, errors.New("no replicas provided")
+	}
+	if maxLag <= 0 {
+		return nil, errors.New("replica staleness threshold must be positive")
+	}
+	exclusions := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_replica_exclusions_total",
+		Help: "Number of reads which skipped a replica because it was unhealthy or lagging, by replica",
+	}, []string{"replica"})
+	fallbacks := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "db_replica_fallbacks_total",
+		Help: "Number of reads sent to the primary because no replica was healthy",
+	})
+	if stats != nil {
+		for _, c := range []prometheus.Collector{exclusions, fallbacks} {
+			err := stats.Register(c)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	m := &ReplicaRoutedMap{
+		primary:    primary,
+		exclusions: exclusions,
+		fallbacks:  fallbacks,
+	}
+	for i, replica := range replicas {
+		var checkerStats prometheus.Registerer
+		if stats != nil {
+			checkerStats = prometheus.WrapRegistererWith(prometheus.Labels{"replica": strconv.Itoa(i)}, stats)
+		}
+		checker, err := NewChecker(replica, checkInterval, maxLag, checkerStats)
+		if err != nil {
+			return nil, err
+		}
+		m.replicas = append(m.replicas, routedReplica{WrappedMap: replica, checker: checker})
+	}
+	return m, nil
+}
+
+// Start starts checking the replicas. Each is checked once before it returns,
+// so that reads can be routed to the healthy replicas immediately.
+func (m *ReplicaRoutedMap) Start() {
+	for _, replica := range m.replicas {
+		replica.checker.Start()
+	}
+}
+
+// Stop stops checking the replicas. Reads then continue to be routed by the
+// results of their final checks.
+func (m *ReplicaRoutedMap) Stop() {
+	for _, replica := range m.replicas {
+		replica.checker.Stop()
+	}
+}
+
+// reader returns the next healthy replica, or the primary if none is healthy.
+func (m *ReplicaRoutedMap) reader(ctx context.Context) *WrappedMap {
+	start := m.next.Add(1)
+	for i := 0; i < len(m.replicas); i++ {
+		idx := (start + uint64(i)) % uint64(len(m.replicas))
+		replica := m.replicas[idx]
+		if replica.checker.Health(ctx) == nil {
+			return replica.WrappedMap
+		}
+		m.exclusions.WithLabelValues(strconv.FormatUint(idx, 10)).Inc()
+	}
+	m.fallbacks.Inc()
+	return m.primary
+}
+
+func (m *ReplicaRoutedMap) Get(ctx context.Context, holder interface{}, keys ...interface{}) (interface{}, error) {
+	return m.reader(ctx).Get(ctx, holder, keys...)
+}
+
+func (m *ReplicaRoutedMap) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	return m.reader(ctx).Select(ctx, holder, query, args...)
+}
+
+func (m *ReplicaRoutedMap) SelectOne(ctx context.Context, holder interface{}, query string, args ...interface{}) error {
+	return m.reader(ctx).SelectOne(ctx, holder, query, args...)
+}
+
+func (m *ReplicaRoutedMap) SelectNullInt(ctx context.Context, query string, args ...interface{}) (sql.NullInt64, error) {
+	return m.reader(ctx).SelectNullInt(ctx, query, args...)
+}
+
+func (m *ReplicaRoutedMap) SelectStr(ctx context.Context, query string, args ...interface{}) (string, error) {
+	return m.reader(ctx).SelectStr(ctx, query, args...)
+}
+
+// QueryContext is routed as a read, so query must not write.
+func (m *ReplicaRoutedMap) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return m.reader(ctx).QueryContext(ctx, query, args...)
+}
+
+func (m *ReplicaRoutedMap) Insert(ctx context.Context, list ...interface{}) error {
+	return m.primary.Insert(ctx, list...)
+}
+
+func (m *ReplicaRoutedMap) Update(ctx context.Context, list ...interface{}) (int64, error) {
+	return m.primary.Update(ctx, list...)
+}
+
+func (m *ReplicaRoutedMap) Delete(ctx context.Context, list ...interface{}) (int64, error) {
+	return m.primary.Delete(ctx, list...)
+}
+
+func (m *ReplicaRoutedMap) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return m.primary.ExecContext(ctx, query, args...)
+}
+
+func (m *ReplicaRoutedMap) BeginTx(ctx context.Context) (Transaction, error) {
+	return m.primary.BeginTx(ctx)
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestReplicaRoutedMap(t *testing.T) {
+	ctx := context.Background()
+	// Each database holds a widget named for it, so that reads show which was
+	// routed to.
+	maps := make(map[string]*WrappedMap)
+	for _, name := range []string{"primary", "replica0", "replica1"} {
+		m := testSQLiteMap(t)
+		test.AssertNotError(t, m.Insert(ctx, &sqliteTestModel{Name: name}), "inserting widget")
+		maps[name] = m
+	}
+	_, err := NewReplicaRoutedMap(maps["primary"], nil, time.Second, time.Second, nil)
+	test.AssertError(t, err, "no replicas should be rejected")
+	stats := prometheus.NewRegistry()
+	m, err := NewReplicaRoutedMap(maps["primary"], []*WrappedMap{maps["replica0"], maps["replica1"]}, time.Second, 10*time.Second, stats)
+	test.AssertNotError(t, err, "creating replica routed map")
+
+	setLag := func(lags ...string) {
+		t.Helper()
+		for i, lag := range lags {
+			m.replicas[i].checker.lagQuery = "SELECT " + lag + " AS Seconds_Behind_Source"
+			m.replicas[i].checker.runCheck()
+		}
+	}
+	read := func() string {
+		t.Helper()
+		name, err := m.SelectStr(ctx, "SELECT Name FROM widgets")
+		test.AssertNotError(t, err, "reading widget")
+		return name
+	}
+
+	// Healthy replicas are read from in turn.
+	setLag("0", "5")
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		seen[read()]++
+	}
+	test.AssertDeepEquals(t, seen, map[string]int{"replica0": 2, "replica1": 2})
+
+	// Lagging or stopped replicas are excluded.
+	setLag("11", "5")
+	test.AssertEquals(t, read(), "replica1")
+	test.AssertEquals(t, read(), "replica1")
+	// Only the read whose turn it was to go to replica0 skipped it.
+	test.AssertMetricWithLabelsEquals(t, m.exclusions, prometheus.Labels{"replica": "0"}, 1)
+	setLag("11", "NULL")
+	test.AssertEquals(t, read(), "primary")
+	test.AssertMetricWithLabelsEquals(t, m.fallbacks, prometheus.Labels{}, 1)
+	test.AssertMetricWithLabelsEquals(t, m.replicas[1].checker.up, prometheus.Labels{}, 0)
+
+	// Writes and transactions always go to the primary.
+	setLag("0", "0")
+	test.AssertNotError(t, m.Insert(ctx, &sqliteTestModel{Name: "written"}), "inserting widget")
+	err = WithTransaction(ctx, m, func(tx Executor) error {
+		_, err := tx.ExecContext(ctx, "UPDATE widgets SET Name = ? WHERE Name = ?", "updated", "written")
+		return err
+	})
+	test.AssertNotError(t, err, "updating widget")
+	n, err := maps["primary"].SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets WHERE Name = ?", "updated")
+	test.AssertNotError(t, err, "counting widgets")
+	test.AssertEquals(t, n.Int64, int64(1))
+	for _, replica := range []string{"replica0", "replica1"} {
+		n, err = maps[replica].SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+		test.AssertNotError(t, err, "counting widgets")
+		test.AssertEquals(t, n.Int64, int64(1))
+	}
+
+	m.Start()
+	m.Stop()
+}
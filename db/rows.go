@@ -0,0 +1,57 @@
+package db
+
+import (
+	"database/sql"
+)
+
+// WrappedRows wraps the *sql.Rows of a query such that the errors of Scan,
+// Err, and Close are wrapped in ErrDatabaseOp instances, identifying the table
+// of the query which produced them, before being returned to the caller.
+type WrappedRows struct {
+	rows  *sql.Rows
+	query string
+}
+
+// Next prepares the next row for Scan, returning false once there are no more
+// rows or an error occurred, which Err then returns.
+func (r *WrappedRows) Next() bool {
+	return r.rows.Next()
+}
+
+// Columns returns the names of the columns of the rows.
+func (r *WrappedRows) Columns() ([]string, error) {
+	columns, err := r.rows.Columns()
+	if err != nil {
+		return nil, errForQuery(r.query, "read columns of", err, nil)
+	}
+	return columns, nil
+}
+
+// Scan copies the columns of the current row into dest, as sql.Rows.Scan does.
+func (r *WrappedRows) Scan(dest ...interface{}) error {
+	err := r.rows.Scan(dest...)
+	if err != nil {
+		return errForQuery(r.query, "scan", err, nil)
+	}
+	return nil
+}
+
+// Err returns the error, if any, which ended iteration over the rows. It
+// should be checked once Next returns false.
+func (r *WrappedRows) Err() error {
+	err := r.rows.Err()
+	if err != nil {
+		return errForQuery(r.query, "read rows of", err, nil)
+	}
+	return nil
+}
+
+// Close closes the rows, returning their connection. It must be called when
+// the caller is done reading rows, regardless of success or error.
+func (r *WrappedRows) Close() error {
+	err := r.rows.Close()
+	if err != nil {
+		return errForQuery(r.query, "close rows of", err, nil)
+	}
+	return nil
+}
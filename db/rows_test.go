@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestWrappedRows(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	err := dbMap.Insert(ctx, &sqliteTestModel{Name: "widget"})
+	test.AssertNotError(t, err, "inserting widget")
+
+	rows, err := dbMap.Query(ctx, "SELECT ID, Name FROM widgets")
+	test.AssertNotError(t, err, "querying widgets")
+	columns, err := rows.Columns()
+	test.AssertNotError(t, err, "reading columns")
+	test.AssertDeepEquals(t, columns, []string{"ID", "Name"})
+	var names []string
+	for rows.Next() {
+		var id int64
+		var name string
+		err = rows.Scan(&id, &name)
+		test.AssertNotError(t, err, "scanning widget")
+		names = append(names, name)
+	}
+	test.AssertNotError(t, rows.Err(), "iterating widgets")
+	test.AssertNotError(t, rows.Close(), "closing rows")
+	test.AssertDeepEquals(t, names, []string{"widget"})
+
+	// Scan errors identify the query's table.
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		rows, err := tx.(WrappedTransaction).Query(ctx, "SELECT ID, Name FROM widgets")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rows.Close() }()
+		test.Assert(t, rows.Next(), "expected a row")
+		var id int64
+		return rows.Scan(&id)
+	})
+	var dbOpErr ErrDatabaseOp
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Op, "scan")
+	test.AssertEquals(t, dbOpErr.Table, "widgets")
+
+	_, err = dbMap.Query(ctx, "SELECT ID FROM doesNotExist")
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Table, "doesNotExist")
+}
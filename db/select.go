@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// SelectAll runs query, returning each of its rows as a T. T is either a
+// struct mapped to the query's table, whose columns are selected, or the type
+// of its single column, e.g. string. Errors are wrapped in ErrDatabaseOp, if
+// selector hasn't already.
+func SelectAll[T any](ctx context.Context, selector Selector, query string, args ...interface{}) ([]T, error) {
+	var holder []T
+	_, err := selector.Select(ctx, &holder, query, args...)
+	if err != nil {
+		return nil, wrapSelectErr(query, "select", err, &holder)
+	}
+	return holder, nil
+}
+
+// SelectOneT runs query, which must return a single row, returning it as a
+// *T, where T is as for SelectAll. As with SelectOne, the error wraps
+// sql.ErrNoRows if there is no row, and is wrapped in ErrDatabaseOp, if
+// selector hasn't already.
+func SelectOneT[T any](ctx context.Context, selector OneSelector, query string, args ...interface{}) (*T, error) {
+	var holder T
+	err := selector.SelectOne(ctx, &holder, query, args...)
+	if err != nil {
+		return nil, wrapSelectErr(query, "select one", err, &holder)
+	}
+	return &holder, nil
+}
+
+// wrapSelectErr wraps err, returned by a select into holder, in ErrDatabaseOp
+// unless it already is one.
+func wrapSelectErr(query, operation string, err error, holder interface{}) error {
+	var dbOpErr ErrDatabaseOp
+	if errors.As(err, &dbOpErr) {
+		return err
+	}
+	return errForQuery(query, operation, err, []interface{}{holder})
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// failingSelector fails every select with an unwrapped error.
+type failingSelector struct{}
+
+func (failingSelector) Select(context.Context, interface{}, string, ...interface{}) ([]interface{}, error) {
+	return nil, errors.New("oops")
+}
+
+func (failingSelector) SelectOne(context.Context, interface{}, string, ...interface{}) error {
+	return errors.New("oops")
+}
+
+func TestSelectAll(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	test.AssertNotError(t, dbMap.Insert(ctx, &sqliteTestModel{Name: "gadget"}), "inserting widget")
+	test.AssertNotError(t, dbMap.Insert(ctx, &sqliteTestModel{Name: "gizmo"}), "inserting widget")
+
+	widgets, err := SelectAll[sqliteTestModel](ctx, dbMap, "SELECT * FROM widgets ORDER BY ID")
+	test.AssertNotError(t, err, "selecting widgets")
+	test.AssertEquals(t, len(widgets), 2)
+	test.AssertEquals(t, widgets[0].Name, "gadget")
+	test.AssertEquals(t, widgets[1].Name, "gizmo")
+
+	names, err := SelectAll[string](ctx, dbMap, "SELECT Name FROM widgets WHERE Name != ?", "gadget")
+	test.AssertNotError(t, err, "selecting names")
+	test.AssertDeepEquals(t, names, []string{"gizmo"})
+
+	none, err := SelectAll[sqliteTestModel](ctx, dbMap, "SELECT * FROM widgets WHERE Name = ?", "doohickey")
+	test.AssertNotError(t, err, "selecting no widgets")
+	test.AssertEquals(t, len(none), 0)
+
+	// Errors are wrapped, whether or not the selector wraps them.
+	var dbOpErr ErrDatabaseOp
+	_, err = SelectAll[sqliteTestModel](ctx, dbMap, "SELECT * FROM nonexistent")
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	_, err = SelectAll[sqliteTestModel](ctx, failingSelector{}, "SELECT * FROM widgets")
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Table, "widgets")
+}
+
+func TestSelectOneT(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	test.AssertNotError(t, dbMap.Insert(ctx, &sqliteTestModel{Name: "gadget"}), "inserting widget")
+
+	widget, err := SelectOneT[sqliteTestModel](ctx, dbMap, "SELECT * FROM widgets WHERE Name = ?", "gadget")
+	test.AssertNotError(t, err, "selecting widget")
+	test.AssertEquals(t, widget.Name, "gadget")
+
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		var id *int64
+		id, err = SelectOneT[int64](ctx, tx, "SELECT ID FROM widgets WHERE Name = ?", "gadget")
+		if err != nil {
+			return err
+		}
+		test.AssertEquals(t, *id, widget.ID)
+		return nil
+	})
+	test.AssertNotError(t, err, "selecting ID in transaction")
+
+	_, err = SelectOneT[sqliteTestModel](ctx, dbMap, "SELECT * FROM widgets WHERE Name = ?", "doohickey")
+	test.Assert(t, IsNoRows(err), "expected no rows")
+
+	var dbOpErr ErrDatabaseOp
+	_, err = SelectOneT[sqliteTestModel](ctx, failingSelector{}, "SELECT * FROM widgets WHERE ID = 1")
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Op, "select one")
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ShardedMap routes operations to one of several databases, its shards, by a
+// shard key, so that the rows of the largest tables can be partitioned across
+// them horizontally. Each row must be stored on the shard its key routes to:
+// operations on a row, including transactions, are performed on the
+// *WrappedMap returned by Shard or ShardForID. Queries which aren't by a shard
+// key, e.g. for all of an account's certificates when they're sharded by
+// serial, are fanned out to every shard by Select.
+//
+// The number and order of shards determines which keys route to each, so
+// neither can change without moving the rows whose keys route elsewhere.
+type ShardedMap struct {
+	shards []*WrappedMap
+}
+
+// NewShardedMap returns a *ShardedMap which routes to shards, in order.
+func NewShardedMap(shards []*WrappedMap) (*ShardedMap, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("no shards")
+	}
+	for i, shard := range shards {
+		if shard == nil {
+			return nil, fmt.Errorf("shard %d is nil", i)
+		}
+	}
+	return &ShardedMap{shards: shards}, nil
+}
+
+// Shards returns every shard, in order.
+func (s *ShardedMap) Shards() []*WrappedMap {
+	return s.shards
+}
+
+// Shard returns the shard which the string key, e.g. a certificate serial,
+// routes to: key's 64-bit FNV-1a hash modulo the number of shards.
+func (s *ShardedMap) Shard(key string) *WrappedMap {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return s.shards[h.Sum64()%uint64(len(s.shards))]
+}
+
+// ShardForID returns the shard which the numeric key, e.g. a registration ID,
+// routes to: id modulo the number of shards. Sequential IDs are therefore
+// spread evenly across shards.
+func (s *ShardedMap) ShardForID(id int64) *WrappedMap {
+	return s.shards[uint64(id)%uint64(len(s.shards))]
+}
+
+// Select runs query on every shard concurrently, and merges their results, in
+// shard order. Like borp's Select, if holder is a pointer to a slice the rows
+// are appended to it, and otherwise they're returned. Callers needing the
+// merged rows in a particular order, or limited in number, must sort or
+// truncate them, since each shard orders and limits only its own rows. If any
+// shard fails, the remaining queries are canceled and the first error is
+// returned, with no rows.
+//
+// Select makes a *ShardedMap a Selector, so functions which take one can query
+// every shard.
+func (s *ShardedMap) Select(ctx context.Context, holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	holderValue := reflect.ValueOf(holder)
+	intoSlice := holderValue.Kind() == reflect.Pointer && holderValue.Elem().Kind() == reflect.Slice
+
+	results := make([][]interface{}, len(s.shards))
+	var holders []reflect.Value
+	if intoSlice {
+		holders = make([]reflect.Value, len(s.shards))
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for i, shard := range s.shards {
+		i, shard := i, shard
+		shardHolder := holder
+		if intoSlice {
+			holders[i] = reflect.New(holderValue.Elem().Type())
+			shardHolder = holders[i].Interface()
+		}
+		g.Go(func() error {
+			var err error
+			results[i], err = shard.Select(ctx, shardHolder, query, args...)
+			if err != nil {
+				return fmt.Errorf("selecting from shard %d: %w", i, err)
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	if intoSlice {
+		merged := holderValue.Elem()
+		for _, h := range holders {
+			merged = reflect.AppendSlice(merged, h.Elem())
+		}
+		holderValue.Elem().Set(merged)
+		return nil, nil
+	}
+	var merged []interface{}
+	for _, result := range results {
+		merged = append(merged, result...)
+	}
+	return merged, nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestShardedMap(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewShardedMap(nil)
+	test.AssertError(t, err, "expected error for no shards")
+
+	shards := []*WrappedMap{testSQLiteMap(t), testSQLiteMap(t), testSQLiteMap(t)}
+	s, err := NewShardedMap(shards)
+	test.AssertNotError(t, err, "creating sharded map")
+	var _ Selector = s
+
+	// Routing is deterministic, and numeric keys are spread evenly.
+	test.Assert(t, s.Shard("serial") == s.Shard("serial"), "same key should route to the same shard")
+	test.Assert(t, s.ShardForID(4) == shards[1], "ID 4 should route to shard 1")
+	test.Assert(t, s.ShardForID(5) == shards[2], "ID 5 should route to shard 2")
+	test.Assert(t, s.ShardForID(6) == shards[0], "ID 6 should route to shard 0")
+
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	used := make(map[*WrappedMap]bool)
+	for _, name := range names {
+		shard := s.Shard(name)
+		used[shard] = true
+		err := shard.Insert(ctx, &sqliteTestModel{Name: name})
+		test.AssertNotError(t, err, "inserting widget")
+	}
+	test.Assert(t, len(used) > 1, "keys should route to more than one shard")
+
+	// Select fans out to every shard and merges the rows.
+	var widgets []sqliteTestModel
+	_, err = s.Select(ctx, &widgets, "SELECT * FROM widgets WHERE Name > ?", "b")
+	test.AssertNotError(t, err, "selecting widgets into slice")
+	got := make(map[string]bool)
+	for _, w := range widgets {
+		got[w.Name] = true
+	}
+	test.AssertEquals(t, len(widgets), 6)
+	test.AssertEquals(t, len(got), 6)
+
+	rows, err := s.Select(ctx, sqliteTestModel{}, "SELECT * FROM widgets")
+	test.AssertNotError(t, err, "selecting widgets")
+	test.AssertEquals(t, len(rows), len(names))
+
+	// A failing shard fails the whole query.
+	_, err = shards[1].ExecContext(ctx, "DROP TABLE widgets")
+	test.AssertNotError(t, err, "dropping table")
+	widgets = nil
+	_, err = s.Select(ctx, &widgets, "SELECT * FROM widgets")
+	test.AssertError(t, err, "expected error from missing table")
+	test.AssertContains(t, err.Error(), "shard 1")
+	test.AssertEquals(t, len(widgets), 0)
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/letsencrypt/borp"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// The tests of this package can't import db/dbtest, which imports this
+// package, so the following duplicate its SQLite support. Keep them in sync.
+
+func init() {
+	RegisterDriver(&sqlite3.SQLiteDriver{}, "sqlite", classifySQLiteError)
+}
+
+// NewSQLiteMap is dbtest.NewSQLiteMap.
+func NewSQLiteMap(ctx context.Context, dsn string, setup func(*borp.DbMap)) (*WrappedMap, error) {
+	dbConn, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening SQLite database: %w", err)
+	}
+	dbConn.SetMaxOpenConns(1)
+
+	dbMap := &borp.DbMap{Db: dbConn, Dialect: borp.SqliteDialect{}}
+	if setup != nil {
+		setup(dbMap)
+	}
+	err = dbMap.CreateTablesIfNotExists(ctx)
+	if err != nil {
+		_ = dbConn.Close()
+		return nil, fmt.Errorf("creating SQLite tables: %w", err)
+	}
+	return NewWrappedMap(dbMap), nil
+}
+
+// classifySQLiteError is dbtest.ClassifySQLiteError.
+func classifySQLiteError(err error) ErrorClass {
+	var dbErr sqlite3.Error
+	if !errors.As(err, &dbErr) {
+		return Unknown
+	}
+	switch {
+	case dbErr.ExtendedCode == sqlite3.ErrConstraintUnique || dbErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		return Duplicate
+	case dbErr.Code == sqlite3.ErrBusy || dbErr.Code == sqlite3.ErrLocked:
+		return LockTimeout
+	case dbErr.Code == sqlite3.ErrReadonly:
+		return ReadOnly
+	case dbErr.Code == sqlite3.ErrError && strings.Contains(dbErr.Error(), "syntax error"):
+		return Syntax
+	}
+	return Unknown
+}
+
+type sqliteTestModel struct {
+	ID    int64
+	Name  string
+	Value []byte
+}
+
+func testSQLiteMap(t *testing.T) *WrappedMap {
+	t.Helper()
+	dbMap, err := NewSQLiteMap(context.Background(), ":memory:", func(dbMap *borp.DbMap) {
+		table := dbMap.AddTableWithName(sqliteTestModel{}, "widgets").SetKeys(true, "ID")
+		table.ColMap("Name").SetUnique(true)
+	})
+	test.AssertNotError(t, err, "creating SQLite map")
+	t.Cleanup(func() { _ = dbMap.Close() })
+	return dbMap
+}
+
+func TestSQLiteMap(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+
+	w := &sqliteTestModel{Name: "gadget", Value: []byte{1, 2, 3}}
+	err := dbMap.Insert(ctx, w)
+	test.AssertNotError(t, err, "inserting widget")
+	test.Assert(t, w.ID != 0, "insert should set the auto-increment ID")
+
+	got, err := dbMap.Get(ctx, sqliteTestModel{}, w.ID)
+	test.AssertNotError(t, err, "getting widget")
+	test.AssertDeepEquals(t, got, w)
+
+	var selected []sqliteTestModel
+	_, err = dbMap.Select(ctx, &selected, "SELECT * FROM widgets WHERE Name = ?", "gadget")
+	test.AssertNotError(t, err, "selecting widgets")
+	test.AssertEquals(t, len(selected), 1)
+
+	// A unique key violation is recognized as a duplicate.
+	err = dbMap.Insert(ctx, &sqliteTestModel{Name: "gadget"})
+	test.AssertError(t, err, "inserting duplicate widget")
+	test.Assert(t, IsDuplicate(err), "expected a duplicate error")
+	var dbOpErr ErrDatabaseOp
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Op, "insert")
+
+	// Queries against missing tables are wrapped with the table name.
+	err = dbMap.SelectOne(ctx, &sqliteTestModel{}, "SELECT * FROM doesNotExist WHERE ID = 1")
+	test.AssertError(t, err, "selecting from missing table")
+	test.AssertErrorWraps(t, err, &dbOpErr)
+	test.AssertEquals(t, dbOpErr.Table, "doesNotExist")
+	test.Assert(t, !IsDuplicate(err), "a missing table is not a duplicate")
+
+	// Transactions use the same in-memory database, and are rolled back on
+	// error.
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		err := tx.Insert(ctx, &sqliteTestModel{Name: "gizmo"})
+		if err != nil {
+			return err
+		}
+		return errors.New("oops")
+	})
+	test.AssertError(t, err, "transaction should fail")
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		return tx.Insert(ctx, &sqliteTestModel{Name: "doohickey"})
+	})
+	test.AssertNotError(t, err, "transaction should succeed")
+	count, err := dbMap.SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "counting widgets")
+	test.AssertEquals(t, count.Int64, int64(2))
+}
+
+func TestSQLiteBeginReadOnly(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+
+	// There are no read-only transactions in SQLite, so it is an ordinary
+	// transaction.
+	tx, err := dbMap.BeginReadOnly(ctx)
+	test.AssertNotError(t, err, "beginning read-only transaction")
+	var count int64
+	err = tx.SelectOne(ctx, &count, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "reading in read-only transaction")
+	err = tx.Commit()
+	test.AssertNotError(t, err, "committing read-only transaction")
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// QueryTimeouts are the default timeouts, by class of operation, which a
+// WrappedMap applies to operations whose context has no deadline. A zero
+// timeout applies no deadline.
+//
+// QueryContext and QueryRowContext are never subject to a default timeout,
+// because their results are read after they return.
+type QueryTimeouts struct {
+	// Read applies to Get, Select, SelectOne, SelectNullInt, and SelectStr.
+	Read time.Duration
+
+	// Write applies to Insert, Update, Delete, and ExecContext.
+	Write time.Duration
+
+	// LongRunning applies to any operation whose context was returned by
+	// WithLongRunning, in place of Read or Write.
+	LongRunning time.Duration
+}
+
+// queryClass is the class of an operation, used to select its default timeout
+// and to label the timeouts metric.
+type queryClass string
+
+const (
+	readQuery        queryClass = "read"
+	writeQuery       queryClass = "write"
+	longRunningQuery queryClass = "longRunning"
+)
+
+type longRunningKey struct{}
+
+// WithLongRunning returns a copy of ctx which marks the operations performed
+// with it as long-running, e.g. the batch queries of a cron job, so that they
+// are subject to QueryTimeouts.LongRunning instead of Read or Write.
+func WithLongRunning(ctx context.Context) context.Context {
+	return context.WithValue(ctx, longRunningKey{}, true)
+}
+
+// queryTimeouts applies QueryTimeouts and counts the operations which hit
+// them. A nil *queryTimeouts applies no timeouts.
+type queryTimeouts struct {
+	QueryTimeouts
+	timeouts *prometheus.CounterVec
+}
+
+func newQueryTimeouts(timeouts QueryTimeouts, stats prometheus.Registerer) (*queryTimeouts, error) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_timeouts_total",
+		Help: "Number of database operations which exceeded the default timeout of their class, by class",
+	}, []string{"class"})
+	if stats != nil {
+		err := stats.Register(counter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &queryTimeouts{QueryTimeouts: timeouts, timeouts: counter}, nil
+}
+
+// apply returns ctx with the default timeout of the provided class applied, if
+// ctx has no deadline, and a function which must be called with the result of
+// the operation once it completes.
+func (t *queryTimeouts) apply(ctx context.Context, class queryClass) (context.Context, func(error)) {
+	if t == nil {
+		return ctx, func(error) {}
+	}
+	_, ok := ctx.Deadline()
+	if ok {
+		return ctx, func(error) {}
+	}
+	if ctx.Value(longRunningKey{}) != nil {
+		class = longRunningQuery
+	}
+	var timeout time.Duration
+	switch class {
+	case readQuery:
+		timeout = t.Read
+	case writeQuery:
+		timeout = t.Write
+	case longRunningQuery:
+		timeout = t.LongRunning
+	}
+	if timeout <= 0 {
+		return ctx, func(error) {}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func(err error) {
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			t.timeouts.WithLabelValues(string(class)).Inc()
+		}
+		cancel()
+	}
+}
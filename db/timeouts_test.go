@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// slowQuery counts forever, until it is interrupted by its context.
+const slowQuery = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c) SELECT COUNT(*) FROM c"
+
+func TestQueryTimeouts(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	var err error
+	dbMap.timeouts, err = newQueryTimeouts(QueryTimeouts{
+		Read:        10 * time.Millisecond,
+		LongRunning: 50 * time.Millisecond,
+	}, nil)
+	test.AssertNotError(t, err, "creating query timeouts")
+
+	// A read without a deadline is subject to the read timeout.
+	start := time.Now()
+	_, err = dbMap.SelectNullInt(ctx, slowQuery)
+	test.AssertError(t, err, "slow query should time out")
+	test.Assert(t, time.Since(start) < 5*time.Second, "slow query should be interrupted")
+	test.AssertMetricWithLabelsEquals(t, dbMap.timeouts.timeouts, prometheus.Labels{"class": "read"}, 1)
+
+	// So are reads in a transaction.
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		var count int64
+		return tx.SelectOne(ctx, &count, slowQuery)
+	})
+	test.AssertError(t, err, "slow query should time out")
+	test.AssertMetricWithLabelsEquals(t, dbMap.timeouts.timeouts, prometheus.Labels{"class": "read"}, 2)
+
+	// Long-running operations are subject to the long-running timeout.
+	_, err = dbMap.SelectNullInt(WithLongRunning(ctx), slowQuery)
+	test.AssertError(t, err, "slow query should time out")
+	test.AssertMetricWithLabelsEquals(t, dbMap.timeouts.timeouts, prometheus.Labels{"class": "longRunning"}, 1)
+
+	// A deadline supplied by the caller is not replaced, and hitting it is not
+	// counted.
+	deadlineCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = dbMap.SelectNullInt(deadlineCtx, slowQuery)
+	test.AssertError(t, err, "slow query should time out")
+	test.AssertMetricWithLabelsEquals(t, dbMap.timeouts.timeouts, prometheus.Labels{"class": "read"}, 2)
+
+	// Writes have no timeout, and operations which complete in time are not
+	// counted.
+	err = dbMap.Insert(ctx, &sqliteTestModel{Name: "widget"})
+	test.AssertNotError(t, err, "inserting widget")
+	_, err = dbMap.SelectNullInt(ctx, "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "counting widgets")
+	test.AssertMetricWithLabelsEquals(t, dbMap.timeouts.timeouts, prometheus.Labels{"class": "write"}, 0)
+	test.AssertMetricWithLabelsEquals(t, dbMap.timeouts.timeouts, prometheus.Labels{"class": "read"}, 2)
+}
+
+func TestQueryTimeoutsApply(t *testing.T) {
+	ctx := context.Background()
+
+	// A nil *queryTimeouts applies no deadline.
+	var none *queryTimeouts
+	got, done := none.apply(ctx, readQuery)
+	_, ok := got.Deadline()
+	test.Assert(t, !ok, "nil queryTimeouts should not apply a deadline")
+	done(errors.New("oops"))
+
+	timeouts, err := newQueryTimeouts(QueryTimeouts{Read: time.Minute, Write: time.Hour}, nil)
+	test.AssertNotError(t, err, "creating query timeouts")
+	got, done = timeouts.apply(ctx, writeQuery)
+	deadline, ok := got.Deadline()
+	test.Assert(t, ok, "write timeout should be applied")
+	test.Assert(t, time.Until(deadline) > time.Minute, "write timeout should be applied, not read")
+	done(nil)
+	test.AssertErrorIs(t, got.Err(), context.Canceled)
+
+	// An unset class timeout applies no deadline.
+	got, done = timeouts.apply(WithLongRunning(ctx), readQuery)
+	_, ok = got.Deadline()
+	test.Assert(t, !ok, "unset long-running timeout should not apply a deadline")
+	done(nil)
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/letsencrypt/borp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the OpenTelemetry tracer used by this package.
+const tracerName = "github.com/letsencrypt/boulder/db"
+
+// queryTracer emits a span for each operation of a WrappedExecutor. A nil
+// *queryTracer emits nothing.
+type queryTracer struct {
+	tracer trace.Tracer
+
+	// system identifies the database, e.g. MySQL, in each span.
+	system attribute.KeyValue
+}
+
+// newQueryTracer returns a *queryTracer for operations on dbMap, using the
+// globally configured TracerProvider.
+func newQueryTracer(dbMap *borp.DbMap) *queryTracer {
+	system := semconv.DBSystemOtherSQL
+	switch dbMap.Dialect.(type) {
+	case borp.MySQLDialect:
+		system = semconv.DBSystemMySQL
+	case borp.SqliteDialect:
+		system = semconv.DBSystemSqlite
+	}
+	return &queryTracer{tracer: otel.Tracer(tracerName), system: system}
+}
+
+// newPlainQueryTracer is like newQueryTracer, but for operations on db, a
+// database without a borp dialect, whose system is identified by its driver.
+func newPlainQueryTracer(db *sql.DB) *queryTracer {
+	system := semconv.DBSystemOtherSQL
+	if _, ok := db.Driver().(*mysql.MySQLDriver); ok {
+		system = semconv.DBSystemMySQL
+	} else if hooks, ok := registeredDriver(db.Driver()); ok {
+		system = hooks.system
+	}
+	return &queryTracer{tracer: otel.Tracer(tracerName), system: system}
+}
+
+// start starts a span for the operation op on table, as a child of any span in
+// ctx. The span is named for the query name, if any, given by WithQueryName,
+// and otherwise for op.
+func (t *queryTracer) start(ctx context.Context, op, table, name string) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	attrs := []attribute.KeyValue{
+		t.system,
+		semconv.DBOperation(op),
+		semconv.DBSQLTable(table),
+	}
+	spanName := "db/" + op
+	if name != "" {
+		spanName = "db/" + name
+		attrs = append(attrs, attribute.String("db.query_name", name))
+	}
+	return t.tracer.Start(ctx, spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// end records the outcome of an operation on the provided span, which may be
+// nil, and ends it. If rows is not negative it is recorded as the number of
+// rows affected.
+func (t *queryTracer) end(span trace.Span, rows int64, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if rows >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+	}
+	span.End()
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// recordingExporter is a sdktrace.SpanExporter which retains every exported
+// span.
+type recordingExporter struct {
+	sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.Lock()
+	defer e.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error { return nil }
+
+// pop returns the recorded spans and forgets them.
+func (e *recordingExporter) pop() []sdktrace.ReadOnlySpan {
+	e.Lock()
+	defer e.Unlock()
+	spans := e.spans
+	e.spans = nil
+	return spans
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestQueryTracing(t *testing.T) {
+	exporter := &recordingExporter{}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	dbMap := testSQLiteMap(t)
+	dbMap.tracer.tracer = tp.Tracer(tracerName)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	defer parent.End()
+
+	w := &sqliteTestModel{Name: "widget"}
+	err := dbMap.Insert(ctx, w)
+	test.AssertNotError(t, err, "inserting widget")
+	spans := exporter.pop()
+	test.AssertEquals(t, len(spans), 1)
+	test.AssertEquals(t, spans[0].Name(), "db/insert")
+	test.AssertEquals(t, spans[0].Parent().SpanID(), parent.SpanContext().SpanID())
+	attrs := spanAttributes(spans[0])
+	test.AssertEquals(t, attrs[semconv.DBSystemKey].AsString(), "sqlite")
+	test.AssertEquals(t, attrs[semconv.DBOperationKey].AsString(), "insert")
+	test.AssertEquals(t, attrs[semconv.DBSQLTableKey].AsString(), "widgets")
+	test.AssertEquals(t, attrs["db.rows_affected"].AsInt64(), int64(1))
+
+	// Operations in a transaction are children of the caller's span too.
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		var selected []sqliteTestModel
+		_, err := tx.Select(ctx, &selected, "SELECT * FROM widgets WHERE ID = ?", w.ID)
+		return err
+	})
+	test.AssertNotError(t, err, "selecting widgets")
+	spans = exporter.pop()
+	test.AssertEquals(t, len(spans), 1)
+	test.AssertEquals(t, spans[0].Name(), "db/select")
+	test.AssertEquals(t, spans[0].Parent().SpanID(), parent.SpanContext().SpanID())
+	attrs = spanAttributes(spans[0])
+	test.AssertEquals(t, attrs[semconv.DBSQLTableKey].AsString(), "widgets")
+	_, ok := attrs["db.rows_affected"]
+	test.Assert(t, !ok, "reads should not record rows affected")
+
+	// Errors are recorded.
+	_, err = dbMap.ExecContext(ctx, "DELETE FROM doesNotExist WHERE ID = ?", 1)
+	test.AssertError(t, err, "deleting from missing table")
+	spans = exporter.pop()
+	test.AssertEquals(t, len(spans), 1)
+	test.AssertEquals(t, spans[0].Name(), "db/exec")
+	test.AssertEquals(t, spans[0].Status().Code, codes.Error)
+	test.AssertEquals(t, spanAttributes(spans[0])[semconv.DBSQLTableKey].AsString(), "doesNotExist")
+
+	// Named operations are named for their name rather than their operation.
+	_, err = dbMap.Get(WithQueryName(ctx, "GetWidgetByID"), sqliteTestModel{}, w.ID)
+	test.AssertNotError(t, err, "getting widget")
+	spans = exporter.pop()
+	test.AssertEquals(t, len(spans), 1)
+	test.AssertEquals(t, spans[0].Name(), "db/GetWidgetByID")
+	attrs = spanAttributes(spans[0])
+	test.AssertEquals(t, attrs[semconv.DBOperationKey].AsString(), "get")
+	test.AssertEquals(t, attrs["db.query_name"].AsString(), "GetWidgetByID")
+}
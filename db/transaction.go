@@ -1,26 +1,87 @@
 package db
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/letsencrypt/boulder/core"
+)
 
 // txFunc represents a function that does work in the context of a transaction.
-type txFunc func(tx Executor) (interface{}, error)
+type txFunc func(tx Executor) error
 
 // WithTransaction runs the given function in a transaction, rolling back if it
-// returns an error and committing if not. The provided context is also attached
-// to the transaction. WithTransaction also passes through a value returned by
-// `f`, if there is no error.
-func WithTransaction(ctx context.Context, dbMap DatabaseMap, f txFunc) (interface{}, error) {
+// returns an error or panics and committing if not. The provided context is
+// also attached to the transaction. Values computed by `f` should be returned
+// to the caller by assigning them to variables captured by the closure.
+func WithTransaction(ctx context.Context, dbMap DatabaseMap, f txFunc) error {
+	_, err := withTransaction(ctx, dbMap, f)
+	return err
+}
+
+// withTransaction implements WithTransaction, additionally reporting whether
+// the returned error came from the commit, whose outcome may be unknown.
+func withTransaction(ctx context.Context, dbMap DatabaseMap, f txFunc) (committing bool, err error) {
 	tx, err := dbMap.BeginTx(ctx)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	result, err := f(tx)
+	// If f panics, roll back the transaction before re-panicking so that it
+	// isn't leaked.
+	defer func() {
+		r := recover()
+		if r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+	err = f(tx)
 	if err != nil {
-		return nil, rollback(tx, err)
+		return false, rollback(tx, err)
 	}
-	err = tx.Commit()
-	if err != nil {
-		return nil, err
+	return true, tx.Commit()
+}
+
+const (
+	txRetryBase   = 10 * time.Millisecond
+	txRetryMax    = time.Second
+	txRetryFactor = 2.0
+)
+
+// WithRetryingTransaction is like WithTransaction, but if the transaction fails
+// with a retryable error (see ErrorClass.Retryable) it is retried, with
+// backoff, until it has been attempted maxAttempts times. A connection lost
+// while committing is not retried, since the transaction may have been
+// committed. Since `f` may be called more than once it must not have side
+// effects outside of the transaction, other than assigning to the variables it
+// captures.
+func WithRetryingTransaction(ctx context.Context, dbMap DatabaseMap, maxAttempts int, f txFunc) error {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(core.RetryBackoff(attempt, txRetryBase, txRetryMax, txRetryFactor))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("retrying transaction after %q: %w", err, ctx.Err())
+			case <-timer.C:
+			}
+		}
+		var committing bool
+		committing, err = withTransaction(ctx, dbMap, f)
+		if !IsRetryable(err) || (committing && IsConnectionLost(err)) {
+			return err
+		}
 	}
-	return result, nil
+	return err
+}
+
+// IsSerializationFailure returns true if err wraps a MySQL deadlock (Error
+// 1213) or lock wait timeout (Error 1205), or the SQLite equivalent. These
+// errors are returned when a transaction couldn't be serialized with another
+// concurrent transaction, and retrying it may succeed.
+func IsSerializationFailure(err error) bool {
+	class := ClassifyError(err)
+	return class == Deadlock || class == LockTimeout
 }
@@ -0,0 +1,250 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/letsencrypt/borp"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func countSQLiteTestModels(t *testing.T, dbMap *WrappedMap) int64 {
+	t.Helper()
+	count, err := dbMap.SelectNullInt(context.Background(), "SELECT COUNT(*) FROM widgets")
+	test.AssertNotError(t, err, "counting widgets")
+	return count.Int64
+}
+
+func TestWithTransaction(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+
+	// Committed on success.
+	var id int64
+	err := WithTransaction(ctx, dbMap, func(tx Executor) error {
+		w := &sqliteTestModel{Name: "committed"}
+		err := tx.Insert(ctx, w)
+		id = w.ID
+		return err
+	})
+	test.AssertNotError(t, err, "transaction should succeed")
+	test.Assert(t, id != 0, "value assigned by the closure should be visible")
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(1))
+
+	// Rolled back on error, which is returned unwrapped.
+	oops := errors.New("oops")
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		err := tx.Insert(ctx, &sqliteTestModel{Name: "rolled back"})
+		if err != nil {
+			return err
+		}
+		return oops
+	})
+	test.AssertEquals(t, err, oops)
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(1))
+
+	// Rolled back on panic, which is propagated.
+	func() {
+		defer func() {
+			test.AssertEquals(t, recover(), "oh no")
+		}()
+		_ = WithTransaction(ctx, dbMap, func(tx Executor) error {
+			err := tx.Insert(ctx, &sqliteTestModel{Name: "panicked"})
+			if err != nil {
+				return err
+			}
+			panic("oh no")
+		})
+	}()
+	// The single connection of the SQLite map would still be held by a leaked
+	// transaction, blocking this query.
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(1))
+}
+
+// insertWidget inserts a widget in a transaction of its own, which is nested
+// if dbMap is itself a transaction.
+func insertWidget(ctx context.Context, dbMap DatabaseMap, name string, fail error) error {
+	return WithTransaction(ctx, dbMap, func(tx Executor) error {
+		err := tx.Insert(ctx, &sqliteTestModel{Name: name})
+		if err != nil {
+			return err
+		}
+		return fail
+	})
+}
+
+func TestNestedTransaction(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	oops := errors.New("oops")
+
+	// Without an enclosing transaction the helper commits its own.
+	err := insertWidget(ctx, dbMap, "top-level", nil)
+	test.AssertNotError(t, err, "inserting widget")
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(1))
+
+	// Within one, a failed nested transaction rolls back only its own changes.
+	tx, err := dbMap.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning transaction")
+	err = tx.Insert(ctx, &sqliteTestModel{Name: "outer"})
+	test.AssertNotError(t, err, "inserting outer widget")
+	err = insertWidget(ctx, tx, "nested", nil)
+	test.AssertNotError(t, err, "inserting nested widget")
+	err = insertWidget(ctx, tx, "failed", oops)
+	test.AssertEquals(t, err, oops)
+
+	// Nesting composes to any depth.
+	inner, err := tx.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning nested transaction")
+	err = insertWidget(ctx, inner, "doubly nested", oops)
+	test.AssertEquals(t, err, oops)
+	err = insertWidget(ctx, inner, "doubly nested", nil)
+	test.AssertNotError(t, err, "inserting doubly nested widget")
+	err = inner.Rollback()
+	test.AssertNotError(t, err, "rolling back nested transaction")
+
+	err = tx.Commit()
+	test.AssertNotError(t, err, "committing transaction")
+	var names []string
+	_, err = dbMap.Select(ctx, &names, "SELECT Name FROM widgets ORDER BY ID")
+	test.AssertNotError(t, err, "selecting widget names")
+	test.AssertDeepEquals(t, names, []string{"top-level", "outer", "nested"})
+
+	// Committed nested transactions are rolled back with the enclosing one.
+	tx, err = dbMap.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning transaction")
+	err = insertWidget(ctx, tx, "discarded", nil)
+	test.AssertNotError(t, err, "inserting nested widget")
+	err = tx.Rollback()
+	test.AssertNotError(t, err, "rolling back transaction")
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(3))
+}
+
+func TestWithRetryingTransaction(t *testing.T) {
+	ctx := context.Background()
+	dbMap := testSQLiteMap(t)
+	deadlock := fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1213})
+
+	// Serialization failures are retried until the transaction succeeds.
+	var attempts int
+	err := WithRetryingTransaction(ctx, dbMap, 3, func(tx Executor) error {
+		attempts++
+		err := tx.Insert(ctx, &sqliteTestModel{Name: fmt.Sprintf("attempt %d", attempts)})
+		if err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return deadlock
+		}
+		return nil
+	})
+	test.AssertNotError(t, err, "transaction should eventually succeed")
+	test.AssertEquals(t, attempts, 3)
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(1))
+
+	// ...or until maxAttempts is reached.
+	attempts = 0
+	err = WithRetryingTransaction(ctx, dbMap, 2, func(tx Executor) error {
+		attempts++
+		return deadlock
+	})
+	test.Assert(t, IsSerializationFailure(err), "expected the last serialization failure")
+	test.AssertEquals(t, attempts, 2)
+
+	// So are other retryable errors, such as a lost connection.
+	attempts = 0
+	err = WithRetryingTransaction(ctx, dbMap, 2, func(tx Executor) error {
+		attempts++
+		if attempts < 2 {
+			return &mysql.MySQLError{Number: 4031}
+		}
+		return nil
+	})
+	test.AssertNotError(t, err, "transaction should succeed after a lost connection")
+	test.AssertEquals(t, attempts, 2)
+
+	// Fatal errors are not retried.
+	attempts = 0
+	err = WithRetryingTransaction(ctx, dbMap, 3, func(tx Executor) error {
+		attempts++
+		return &mysql.MySQLError{Number: 1290}
+	})
+	test.Assert(t, IsReadOnly(err), "expected a read-only error")
+	test.AssertEquals(t, attempts, 1)
+
+	attempts = 0
+	err = WithRetryingTransaction(ctx, dbMap, 3, func(tx Executor) error {
+		attempts++
+		return &mysql.MySQLError{Number: 1062}
+	})
+	test.Assert(t, IsDuplicate(err), "expected a duplicate error")
+	test.AssertEquals(t, attempts, 1)
+
+	// Retries stop when the context is canceled.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	attempts = 0
+	err = WithRetryingTransaction(cancelCtx, dbMap, 3, func(tx Executor) error {
+		attempts++
+		cancel()
+		return deadlock
+	})
+	test.AssertErrorIs(t, err, context.Canceled)
+	test.AssertEquals(t, attempts, 1)
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+	test.Assert(t, IsSerializationFailure(&mysql.MySQLError{Number: 1213}), "deadlock should be a serialization failure")
+	test.Assert(t, IsSerializationFailure(&mysql.MySQLError{Number: 1205}), "lock wait timeout should be a serialization failure")
+	test.Assert(t, !IsSerializationFailure(&mysql.MySQLError{Number: 1062}), "duplicate should not be a serialization failure")
+	test.Assert(t, !IsSerializationFailure(errors.New("oops")), "other errors should not be serialization failures")
+	test.Assert(t, !IsSerializationFailure(nil), "nil should not be a serialization failure")
+}
+
+func TestTransactionContextEnds(t *testing.T) {
+	ctx := context.Background()
+	// A transaction rolled back by its context ending discards its connection,
+	// and with it a ":memory:" database, so use a file.
+	dbMap, err := NewSQLiteMap(ctx, filepath.Join(t.TempDir(), "widgets.db"), func(dbMap *borp.DbMap) {
+		dbMap.AddTableWithName(sqliteTestModel{}, "widgets").SetKeys(true, "ID")
+	})
+	test.AssertNotError(t, err, "creating SQLite map")
+	defer func() { _ = dbMap.Close() }()
+
+	// Canceling the context a transaction began with rolls it back and
+	// returns its connection, without a Commit or Rollback.
+	txCtx, cancel := context.WithCancel(ctx)
+	tx, err := dbMap.BeginTx(txCtx)
+	test.AssertNotError(t, err, "beginning transaction")
+	err = tx.Insert(ctx, &sqliteTestModel{Name: "abandoned"})
+	test.AssertNotError(t, err, "inserting widget")
+	cancel()
+	// The single connection must be returned for this to proceed.
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(0))
+	err = tx.Commit()
+	test.AssertErrorIs(t, err, context.Canceled)
+	test.AssertContains(t, err.Error(), "rolled back because its context ended")
+
+	// With a maximum duration, so are transactions whose context never ends.
+	dbMap.SetMaxTransactionDuration(10 * time.Millisecond)
+	tx, err = dbMap.BeginTx(ctx)
+	test.AssertNotError(t, err, "beginning transaction")
+	err = tx.Insert(ctx, &sqliteTestModel{Name: "abandoned"})
+	test.AssertNotError(t, err, "inserting widget")
+	time.Sleep(50 * time.Millisecond)
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(0))
+	err = tx.Commit()
+	test.AssertErrorIs(t, err, context.DeadlineExceeded)
+
+	// Transactions which end in time are unaffected.
+	err = WithTransaction(ctx, dbMap, func(tx Executor) error {
+		return tx.Insert(ctx, &sqliteTestModel{Name: "committed"})
+	})
+	test.AssertNotError(t, err, "committing transaction")
+	test.AssertEquals(t, countSQLiteTestModels(t, dbMap), int64(1))
+}
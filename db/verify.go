@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ServerRequirements are properties of a MySQL or MariaDB server, and of the
+// sessions Boulder opens with it, that must hold for Boulder to use it safely,
+// e.g. strict SQL modes, without which values too long for their column are
+// silently truncated rather than rejected. See VerifyServer.
+type ServerRequirements struct {
+	// SQLModes are modes which must all be enabled in each session's
+	// sql_mode, e.g. STRICT_ALL_TABLES.
+	SQLModes []string
+	// Charset, if set, is the required default character set of the
+	// database, e.g. utf8mb4.
+	Charset string
+	// MinVersion, if set, is the oldest acceptable server version, e.g.
+	// "10.5". Versions are compared by their leading numeric components, so
+	// the MySQL and MariaDB version schemes should not be mixed.
+	MinVersion string
+}
+
+// VerifyServer checks that the server m is connected to meets reqs,
+// returning an error describing each requirement it doesn't. It is intended to
+// be called once m is constructed, so that a mis-provisioned server is found
+// at startup rather than by its effects on data. It requires MySQL or
+// MariaDB.
+func (m *WrappedMap) VerifyServer(ctx context.Context, reqs ServerRequirements) error {
+	var sqlMode, charset, version string
+	err := m.dbMap.Db.QueryRowContext(ctx, "SELECT @@SESSION.sql_mode, @@character_set_database, VERSION()").Scan(&sqlMode, &charset, &version)
+	if err != nil {
+		return fmt.Errorf("querying database server properties: %w", err)
+	}
+	return checkServer(reqs, sqlMode, charset, version)
+}
+
+// checkServer returns an error describing each of reqs which a server with the
+// provided properties doesn't meet.
+func checkServer(reqs ServerRequirements, sqlMode, charset, version string) error {
+	var errs []error
+	enabled := make(map[string]bool)
+	for _, mode := range strings.Split(sqlMode, ",") {
+		enabled[strings.ToUpper(strings.TrimSpace(mode))] = true
+	}
+	for _, mode := range reqs.SQLModes {
+		if !enabled[strings.ToUpper(mode)] {
+			errs = append(errs, fmt.Errorf("sql_mode %q lacks %s", sqlMode, mode))
+		}
+	}
+	if reqs.Charset != "" && !strings.EqualFold(charset, reqs.Charset) {
+		errs = append(errs, fmt.Errorf("database character set is %q, not %q", charset, reqs.Charset))
+	}
+	if reqs.MinVersion != "" {
+		older, err := versionOlder(version, reqs.MinVersion)
+		if err != nil {
+			errs = append(errs, err)
+		} else if older {
+			errs = append(errs, fmt.Errorf("version %q is older than %s", version, reqs.MinVersion))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("database server doesn't meet requirements: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// versionOlder returns true if version, as returned by VERSION(), e.g.
+// "10.5.23-MariaDB-1:10.5.23+maria~ubu2004", is older than min, e.g. "10.5".
+func versionOlder(version, min string) (bool, error) {
+	have, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+	want, err := parseVersion(min)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < len(want); i++ {
+		if i >= len(have) || have[i] < want[i] {
+			return true, nil
+		}
+		if have[i] > want[i] {
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+// parseVersion returns the leading dot-separated numeric components of
+// version.
+func parseVersion(version string) ([]int, error) {
+	numeric := version
+	end := strings.IndexFunc(numeric, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+	if end >= 0 {
+		numeric = numeric[:end]
+	}
+	var parts []int
+	for _, part := range strings.Split(strings.TrimSuffix(numeric, "."), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("parsing version %q: %w", version, err)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
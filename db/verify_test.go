@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestCheckServer(t *testing.T) {
+	reqs := ServerRequirements{
+		SQLModes:   []string{"STRICT_ALL_TABLES", "no_engine_substitution"},
+		Charset:    "utf8mb4",
+		MinVersion: "10.5",
+	}
+	const sqlMode = "STRICT_ALL_TABLES,NO_ENGINE_SUBSTITUTION"
+	test.AssertNotError(t, checkServer(reqs, sqlMode, "utf8mb4", "10.5.23-MariaDB-1:10.5.23+maria~ubu2004"), "meets requirements")
+	test.AssertNotError(t, checkServer(reqs, sqlMode, "UTF8MB4", "10.11.6-MariaDB"), "meets requirements")
+	test.AssertNotError(t, checkServer(ServerRequirements{}, "", "latin1", "5.7.44"), "no requirements")
+
+	err := checkServer(reqs, "NO_ENGINE_SUBSTITUTION", "latin1", "10.4.32-MariaDB")
+	test.AssertError(t, err, "mis-provisioned server")
+	test.AssertContains(t, err.Error(), `sql_mode "NO_ENGINE_SUBSTITUTION" lacks STRICT_ALL_TABLES`)
+	test.AssertContains(t, err.Error(), `database character set is "latin1", not "utf8mb4"`)
+	test.AssertContains(t, err.Error(), `version "10.4.32-MariaDB" is older than 10.5`)
+
+	err = checkServer(ServerRequirements{MinVersion: "8.0.22"}, "", "", "8.0")
+	test.AssertError(t, err, "version missing a component")
+	err = checkServer(ServerRequirements{MinVersion: "8.0.22"}, "", "", "unknown")
+	test.AssertContains(t, err.Error(), `parsing version "unknown"`)
+	test.AssertNotError(t, checkServer(ServerRequirements{MinVersion: "8.0.22"}, "", "", "8.4.0"), "newer version")
+}
+
+func TestVerifyServerSQLite(t *testing.T) {
+	// SQLite has no server variables to verify.
+	err := testSQLiteMap(t).VerifyServer(context.Background(), ServerRequirements{})
+	test.AssertError(t, err, "verifying SQLite")
+}
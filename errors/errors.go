@@ -51,6 +51,9 @@ const (
 	UnsupportedContact
 	// The requesteed serial number does not exist in the `serials` table.
 	UnknownSerial
+	// The account is paused for the requested identifier, and must be
+	// unpaused before it can be validated.
+	Paused
 )
 
 func (ErrorType) Error() string {
@@ -127,6 +130,8 @@ func (be *BoulderError) GRPCStatus() *status.Status {
 		c = codes.InvalidArgument
 	case UnsupportedContact:
 		c = codes.InvalidArgument
+	case Paused:
+		c = codes.PermissionDenied
 	default:
 		c = codes.Unknown
 	}
@@ -255,3 +260,7 @@ func BadRevocationReasonError(reason int64) error {
 func UnknownSerialError() error {
 	return New(UnknownSerial, "unknown serial")
 }
+
+func PausedError(msg string, args ...interface{}) error {
+	return New(Paused, msg, args...)
+}
@@ -87,6 +87,32 @@ type Config struct {
 
 	// DOH enables DNS-over-HTTPS queries for validation
 	DOH bool
+
+	// DNSAccount01Enabled allows the dns-account-01 challenge type, from
+	// draft-ietf-acme-dns-account-label, to be offered and validated if it is
+	// also enabled in the PA's challenges. Unlike dns-01, its TXT record's name
+	// is specific to the account, so that several accounts can validate the
+	// same name independently.
+	DNSAccount01Enabled bool
+
+	// UseKvLimitsForNewOrder causes the RA to enforce the NewOrdersPerAccount
+	// and CertificatesPerDomain limits using only its key-value rate limiter,
+	// rather than the legacy limits counted in SQL. It has no effect unless
+	// the RA's Limiter is configured, in which case, while it is false, the
+	// RA compares the two in shadow mode and enforces the legacy decision.
+	UseKvLimitsForNewOrder bool
+
+	// DisableLegacyLimitWrites causes the SA to stop recording issued
+	// certificates and created orders in the certificatesPerName and
+	// newOrdersRL tables, which only the legacy CertificatesPerName and
+	// NewOrdersPerAccount limits read. It should only be enabled once
+	// UseKvLimitsForNewOrder is enabled in every RA.
+	DisableLegacyLimitWrites bool
+
+	// CheckIdentifiersPaused causes the RA to refuse to validate identifiers
+	// which an operator has paused for the requesting account, using the SA's
+	// paused table, which must exist.
+	CheckIdentifiersPaused bool
 }
 
 var fMu = new(sync.RWMutex)
@@ -3,6 +3,7 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/letsencrypt/boulder/cmd"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/ratelimits"
 )
 
 const (
@@ -482,37 +484,172 @@ func (ac *authInterceptor) checkContextAuth(ctx context.Context, fullMethod stri
 		return fmt.Errorf("service %q has no allowed client names", serviceName)
 	}
 
+	clientNames, err := peerClientNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, clientName := range clientNames {
+		_, ok := allowedClientNames[clientName]
+		if ok {
+			return nil
+		}
+	}
+
+	return fmt.Errorf(
+		"client names %v are not authorized for service %q (%v)",
+		clientNames, serviceName, allowedClientNames)
+}
+
+// peerClientNames extracts TLS information from the incoming context and
+// returns the DNS names contained in the verified client mTLS cert.
+func peerClientNames(ctx context.Context) ([]string, error) {
 	p, ok := peer.FromContext(ctx)
 	if !ok {
-		return fmt.Errorf("unable to fetch peer info from grpc context")
+		return nil, fmt.Errorf("unable to fetch peer info from grpc context")
 	}
 
 	if p.AuthInfo == nil {
-		return fmt.Errorf("grpc connection appears to be plaintext")
+		return nil, fmt.Errorf("grpc connection appears to be plaintext")
 	}
 
 	tlsAuth, ok := p.AuthInfo.(credentials.TLSInfo)
 	if !ok {
-		return fmt.Errorf("connection is not TLS authed")
+		return nil, fmt.Errorf("connection is not TLS authed")
 	}
 
 	if len(tlsAuth.State.VerifiedChains) == 0 || len(tlsAuth.State.VerifiedChains[0]) == 0 {
-		return fmt.Errorf("connection auth not verified")
+		return nil, fmt.Errorf("connection auth not verified")
 	}
 
-	cert := tlsAuth.State.VerifiedChains[0][0]
+	return tlsAuth.State.VerifiedChains[0][0].DNSNames, nil
+}
 
-	for _, clientName := range cert.DNSNames {
-		_, ok := allowedClientNames[clientName]
-		if ok {
-			return nil
+// Ensure authInterceptor matches the serverInterceptor interface.
+var _ serverInterceptor = (*authInterceptor)(nil)
+
+// rateLimitInterceptor provides two server interceptors (Unary and Stream) which
+// limit the rate at which each client, named by its mTLS cert, may make RPCs to
+// each gRPC service, for the clients with a limit in the ClientRateLimits of
+// that service. Each RPC spends from the bucket of the first name in the
+// client's cert which has a limit.
+type rateLimitInterceptor struct {
+	limiter *ratelimits.Limiter
+
+	// serviceClientLimits is a map of gRPC service names (e.g.
+	// "sa.StorageAuthority") to client certificate SANs (e.g. "ra.boulder") to
+	// the limit on RPCs to that service from that client.
+	serviceClientLimits map[string]map[string]*ratelimits.InternalLimit
+
+	rateLimited *prometheus.CounterVec
+}
+
+// newRateLimitInterceptor takes a GRPCServerConfig and uses the ClientRateLimits
+// of its Service stanzas to construct a rateLimitInterceptor which enforces
+// them, with buckets stored in memory. It returns nil if no service has any
+// ClientRateLimits.
+func newRateLimitInterceptor(c *cmd.GRPCServerConfig, stats prometheus.Registerer, clk clock.Clock) (*rateLimitInterceptor, error) {
+	limits := make(map[string]map[string]*ratelimits.InternalLimit)
+	for serviceName, service := range c.Services {
+		if len(service.ClientRateLimits) == 0 {
+			continue
+		}
+		limits[serviceName] = make(map[string]*ratelimits.InternalLimit)
+		for clientName, rl := range service.ClientRateLimits {
+			if !slices.Contains(service.ClientNames, clientName) {
+				return nil, fmt.Errorf("rate limited client %q is not in the client names of service %q", clientName, serviceName)
+			}
+			limit, err := ratelimits.NewInternalLimit(ratelimits.GRPCRequestsPerClient, rl.Burst, rl.Count, rl.Period.Duration)
+			if err != nil {
+				return nil, fmt.Errorf("rate limit for client %q of service %q: %w", clientName, serviceName, err)
+			}
+			limits[serviceName][clientName] = limit
 		}
 	}
+	if len(limits) == 0 {
+		return nil, nil
+	}
 
-	return fmt.Errorf(
-		"client names %v are not authorized for service %q (%v)",
-		cert.DNSNames, serviceName, allowedClientNames)
+	// Prefix the Limiter's metrics so that they don't collide with those of
+	// any other Limiter in the same process.
+	limiter, err := ratelimits.NewLimiter(clk, ratelimits.NewInmemSource(), prometheus.WrapRegistererWithPrefix("grpc_server_", stats))
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimited := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_rate_limited",
+		Help: "A counter of RPCs rejected because the client exceeded its rate limit, labelled by service and client",
+	}, []string{"service", "client"})
+	stats.MustRegister(rateLimited)
+
+	return &rateLimitInterceptor{
+		limiter:             limiter,
+		serviceClientLimits: limits,
+		rateLimited:         rateLimited,
+	}, nil
 }
 
-// Ensure authInterceptor matches the serverInterceptor interface.
-var _ serverInterceptor = (*authInterceptor)(nil)
+// Unary is a gRPC unary interceptor.
+func (rli *rateLimitInterceptor) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	err := rli.checkRateLimit(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// Stream is a gRPC stream interceptor. Each stream counts as a single RPC.
+func (rli *rateLimitInterceptor) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := rli.checkRateLimit(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// checkRateLimit spends one RPC from the bucket of the calling client for the
+// service of the given method, returning a RateLimit error if the client has
+// exceeded its limit. It returns nil for clients without a limit.
+func (rli *rateLimitInterceptor) checkRateLimit(ctx context.Context, fullMethod string) error {
+	serviceName, _ := splitMethodName(fullMethod)
+	clientLimits, ok := rli.serviceClientLimits[serviceName]
+	if !ok {
+		return nil
+	}
+
+	clientNames, err := peerClientNames(ctx)
+	if err != nil {
+		// Clients without verified names can't have a limit, and are rejected
+		// by the authInterceptor in any case.
+		return nil
+	}
+
+	for _, clientName := range clientNames {
+		limit, ok := clientLimits[clientName]
+		if !ok {
+			continue
+		}
+		txn, err := limit.Transaction(serviceName+":"+clientName, 1)
+		if err != nil {
+			return err
+		}
+		d, err := rli.limiter.Spend(ctx, txn)
+		if err != nil {
+			return err
+		}
+		if !d.Allowed {
+			rli.rateLimited.WithLabelValues(serviceName, clientName).Inc()
+			return &berrors.BoulderError{
+				Type:       berrors.RateLimit,
+				Detail:     fmt.Sprintf("client %q exceeded its rate limit for service %q, retry after %s", clientName, serviceName, d.RetryIn),
+				RetryAfter: d.RetryIn,
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// Ensure rateLimitInterceptor matches the serverInterceptor interface.
+var _ serverInterceptor = (*rateLimitInterceptor)(nil)
@@ -25,6 +25,9 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
 
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/config"
+	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/grpc/test_proto"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/test"
@@ -468,3 +471,73 @@ func TestServiceAuthChecker(t *testing.T) {
 	err = ac.checkContextAuth(ctx, "/package.ServiceName/Method/")
 	test.AssertNotError(t, err, "checking allowed cert")
 }
+
+func TestRateLimitInterceptor(t *testing.T) {
+	peerCtx := func(names ...string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{
+				State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{&x509.Certificate{DNSNames: names}}},
+				},
+			},
+		})
+	}
+
+	cfg := &cmd.GRPCServerConfig{
+		Services: map[string]cmd.GRPCServiceConfig{
+			"package.ServiceName": {
+				ClientNames: []string{"limited.client", "unlimited.client"},
+				ClientRateLimits: map[string]cmd.GRPCClientRateLimit{
+					"limited.client": {Burst: 2, Count: 1, Period: config.Duration{Duration: time.Second}},
+				},
+			},
+			"package.OtherService": {
+				ClientNames: []string{"limited.client"},
+			},
+		},
+	}
+	clk := clock.NewFake()
+	rli, err := newRateLimitInterceptor(cfg, metrics.NoopRegisterer, clk)
+	test.AssertNotError(t, err, "creating rate limit interceptor")
+
+	// The limited client may make a burst of two RPCs.
+	limited := peerCtx("other.name", "limited.client")
+	for i := 0; i < 2; i++ {
+		err = rli.checkRateLimit(limited, "/package.ServiceName/Method")
+		test.AssertNotError(t, err, "RPC within limit")
+	}
+	err = rli.checkRateLimit(limited, "/package.ServiceName/Method")
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+	var bErr *berrors.BoulderError
+	test.Assert(t, errors.As(err, &bErr), "should be a BoulderError")
+	test.AssertEquals(t, bErr.RetryAfter, time.Second)
+	test.AssertMetricWithLabelsEquals(t, rli.rateLimited, prometheus.Labels{"service": "package.ServiceName", "client": "limited.client"}, 1)
+
+	// Other services, and other clients, aren't limited.
+	err = rli.checkRateLimit(limited, "/package.OtherService/Method")
+	test.AssertNotError(t, err, "RPC to service without limits")
+	for i := 0; i < 3; i++ {
+		err = rli.checkRateLimit(peerCtx("unlimited.client"), "/package.ServiceName/Method")
+		test.AssertNotError(t, err, "RPC from client without a limit")
+	}
+
+	// The bucket refills.
+	clk.Add(time.Second)
+	err = rli.checkRateLimit(limited, "/package.ServiceName/Method")
+	test.AssertNotError(t, err, "RPC after refill")
+
+	// Limits must be for allowed clients.
+	cfg.Services["package.OtherService"] = cmd.GRPCServiceConfig{
+		ClientNames: []string{"limited.client"},
+		ClientRateLimits: map[string]cmd.GRPCClientRateLimit{
+			"unknown.client": {Burst: 1, Count: 1, Period: config.Duration{Duration: time.Second}},
+		},
+	}
+	_, err = newRateLimitInterceptor(cfg, metrics.NoopRegisterer, clk)
+	test.AssertError(t, err, "limit for a client not in clientNames")
+
+	// Without any limits, there's no interceptor.
+	rli, err = newRateLimitInterceptor(&cmd.GRPCServerConfig{}, metrics.NoopRegisterer, clk)
+	test.AssertNotError(t, err, "creating rate limit interceptor")
+	test.Assert(t, rli == nil, "interceptor without limits should be nil")
+}
@@ -15,6 +15,7 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
@@ -53,6 +54,7 @@ type serverBuilder struct {
 	services      map[string]service
 	healthSrv     *health.Server
 	checkInterval time.Duration
+	codec         encoding.Codec
 	logger        blog.Logger
 	err           error
 }
@@ -72,6 +74,15 @@ func (sb *serverBuilder) WithCheckInterval(i time.Duration) *serverBuilder {
 	return sb
 }
 
+// WithCodec forces the server to use the provided codec for every message it
+// sends and receives, including those of the health service, regardless of the
+// content-subtype of requests. It is needed to serve services whose messages
+// don't implement proto.Message.
+func (sb *serverBuilder) WithCodec(c encoding.Codec) *serverBuilder {
+	sb.codec = c
+	return sb
+}
+
 // Add registers a new service (consisting of its description and its
 // implementation) to the set of services which will be exposed by this server.
 // It returns the modified-in-place serverBuilder so that calls can be chained.
@@ -150,10 +161,22 @@ func (sb *serverBuilder) Build(tlsConfig *tls.Config, statsRegistry prometheus.R
 
 	mi := newServerMetadataInterceptor(metrics, clk)
 
+	// The rate limit interceptor runs inside the metadata interceptor, so that
+	// its errors are wrapped for transmission to the client like any other.
+	var li serverInterceptor = &noopServerInterceptor{}
+	rli, err := newRateLimitInterceptor(sb.cfg, statsRegistry, clk)
+	if err != nil {
+		return nil, err
+	}
+	if rli != nil {
+		li = rli
+	}
+
 	unaryInterceptors := []grpc.UnaryServerInterceptor{
 		mi.metrics.grpcMetrics.UnaryServerInterceptor(),
 		ai.Unary,
 		mi.Unary,
+		li.Unary,
 		otelgrpc.UnaryServerInterceptor(otelgrpc.WithInterceptorFilter(filters.Not(filters.HealthCheck()))),
 	}
 
@@ -161,6 +184,7 @@ func (sb *serverBuilder) Build(tlsConfig *tls.Config, statsRegistry prometheus.R
 		mi.metrics.grpcMetrics.StreamServerInterceptor(),
 		ai.Stream,
 		mi.Stream,
+		li.Stream,
 		otelgrpc.StreamServerInterceptor(),
 	}
 
@@ -175,6 +199,9 @@ func (sb *serverBuilder) Build(tlsConfig *tls.Config, statsRegistry prometheus.R
 				MaxConnectionAge: sb.cfg.MaxConnectionAge.Duration,
 			}))
 	}
+	if sb.codec != nil {
+		options = append(options, grpc.ForceServerCodec(sb.codec))
+	}
 
 	// Create the server itself and register all of our services on it.
 	server := grpc.NewServer(options...)
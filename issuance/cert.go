@@ -34,10 +34,28 @@ type ProfileConfig struct {
 	MaxValidityPeriod   config.Duration
 	MaxValidityBackdate config.Duration
 
+	// OmitOCSP and OmitCRLDP, if set, cause the OCSP responder URL and CRL
+	// distribution point, respectively, to be left out of certificates. The
+	// BRs only permit this for short-lived certificates, so either requires a
+	// MaxValidityPeriod of at most MaxShortLivedValidity.
+	//
+	// If OmitOCSP is set, the CA also refuses to sign OCSP responses for any
+	// certificate from the profile's issuers, so it should only be set for
+	// issuers which have no unexpired certificates with an OCSP URL. Short-lived
+	// issuance has no rate limits of its own; see the ratelimits README for
+	// how to configure renewals for it.
+	OmitOCSP  bool
+	OmitCRLDP bool
+
 	// Deprecated: we do not respect this field.
 	Policies []PolicyConfig `validate:"-"`
 }
 
+// MaxShortLivedValidity is the longest validity period of a short-lived
+// subscriber certificate, per the Baseline Requirements, Section 1.6.1. Such
+// certificates need not include an OCSP responder URL or CRL distribution point.
+const MaxShortLivedValidity = 7 * 24 * time.Hour
+
 // PolicyConfig describes a policy
 type PolicyConfig struct {
 	OID string `validate:"required"`
@@ -58,6 +76,9 @@ type Profile struct {
 	crlURL    string
 	issuerURL string
 
+	omitOCSP  bool
+	omitCRLDP bool
+
 	maxBackdate time.Duration
 	maxValidity time.Duration
 }
@@ -71,6 +92,9 @@ func NewProfile(profileConfig ProfileConfig, issuerConfig IssuerConfig) (*Profil
 	if issuerConfig.OCSPURL == "" {
 		return nil, errors.New("OCSP URL is required")
 	}
+	if (profileConfig.OmitOCSP || profileConfig.OmitCRLDP) && profileConfig.MaxValidityPeriod.Duration > MaxShortLivedValidity {
+		return nil, fmt.Errorf("OCSP and CRL URLs may only be omitted for validity periods of at most %s", MaxShortLivedValidity)
+	}
 
 	sp := &Profile{
 		useForRSALeaves:   issuerConfig.UseForRSALeaves,
@@ -82,6 +106,8 @@ func NewProfile(profileConfig ProfileConfig, issuerConfig IssuerConfig) (*Profil
 		issuerURL:         issuerConfig.IssuerURL,
 		crlURL:            issuerConfig.CRLURL,
 		ocspURL:           issuerConfig.OCSPURL,
+		omitOCSP:          profileConfig.OmitOCSP,
+		omitCRLDP:         profileConfig.OmitCRLDP,
 		maxBackdate:       profileConfig.MaxValidityBackdate.Duration,
 		maxValidity:       profileConfig.MaxValidityPeriod.Duration,
 	}
@@ -89,6 +115,18 @@ func NewProfile(profileConfig ProfileConfig, issuerConfig IssuerConfig) (*Profil
 	return sp, nil
 }
 
+// MaxValidity returns the longest validity period of certificates issued with
+// the profile.
+func (p *Profile) MaxValidity() time.Duration {
+	return p.maxValidity
+}
+
+// OmitsOCSP returns true if certificates issued with the profile don't include
+// an OCSP responder URL.
+func (p *Profile) OmitsOCSP() bool {
+	return p.omitOCSP
+}
+
 // requestValid verifies the passed IssuanceRequest against the profile. If the
 // request doesn't match the signing profile an error is returned.
 func (p *Profile) requestValid(clk clock.Clock, req *IssuanceRequest) error {
@@ -165,14 +203,17 @@ func (p *Profile) generateTemplate() *x509.Certificate {
 	template := &x509.Certificate{
 		SignatureAlgorithm:    p.sigAlg,
 		ExtKeyUsage:           defaultEKU,
-		OCSPServer:            []string{p.ocspURL},
 		IssuingCertificateURL: []string{p.issuerURL},
 		BasicConstraintsValid: true,
 		// Baseline Requirements, Section 7.1.6.1: domain-validated
 		PolicyIdentifiers: []asn1.ObjectIdentifier{{2, 23, 140, 1, 2, 1}},
 	}
 
-	if p.crlURL != "" {
+	if !p.omitOCSP {
+		template.OCSPServer = []string{p.ocspURL}
+	}
+
+	if p.crlURL != "" && !p.omitCRLDP {
 		template.CRLDistributionPoints = []string{p.crlURL}
 	}
 
@@ -17,6 +17,7 @@ import (
 	ct "github.com/google/certificate-transparency-go"
 	"github.com/jmhodges/clock"
 
+	"github.com/letsencrypt/boulder/config"
 	"github.com/letsencrypt/boulder/ctpolicy/loglist"
 	"github.com/letsencrypt/boulder/linter"
 	"github.com/letsencrypt/boulder/test"
@@ -43,6 +44,21 @@ func TestNewProfileNoOCSPURL(t *testing.T) {
 	test.AssertEquals(t, err.Error(), "OCSP URL is required")
 }
 
+func TestNewProfileOmitRevocationURLs(t *testing.T) {
+	pc := defaultProfileConfig()
+	pc.OmitOCSP = true
+	pc.OmitCRLDP = true
+	pc.MaxValidityPeriod = config.Duration{Duration: MaxShortLivedValidity}
+	p, err := NewProfile(pc, defaultIssuerConfig())
+	test.AssertNotError(t, err, "NewProfile failed for a short-lived profile")
+	test.Assert(t, p.OmitsOCSP(), "profile should omit OCSP")
+
+	pc.MaxValidityPeriod = config.Duration{Duration: MaxShortLivedValidity + time.Second}
+	_, err = NewProfile(pc, defaultIssuerConfig())
+	test.AssertError(t, err, "NewProfile didn't fail omitting OCSP and CRL URLs for long-lived certificates")
+	test.AssertContains(t, err.Error(), "may only be omitted")
+}
+
 func TestRequestValid(t *testing.T) {
 	fc := clock.NewFake()
 	fc.Add(time.Hour * 24)
@@ -304,6 +320,23 @@ func TestGenerateTemplate(t *testing.T) {
 				PolicyIdentifiers:     []asn1.ObjectIdentifier{{2, 23, 140, 1, 2, 1}},
 			},
 		},
+		{
+			name: "omitted ocsp and crl urls",
+			profile: &Profile{
+				ocspURL:   "ocsp-url",
+				crlURL:    "crl-url",
+				sigAlg:    x509.SHA256WithRSA,
+				omitOCSP:  true,
+				omitCRLDP: true,
+			},
+			expectedTemplate: &x509.Certificate{
+				BasicConstraintsValid: true,
+				SignatureAlgorithm:    x509.SHA256WithRSA,
+				ExtKeyUsage:           defaultEKU,
+				IssuingCertificateURL: []string{""},
+				PolicyIdentifiers:     []asn1.ObjectIdentifier{{2, 23, 140, 1, 2, 1}},
+			},
+		},
 	}
 
 	for _, tc := range tests {
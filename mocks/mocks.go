@@ -607,6 +607,41 @@ func (sa *StorageAuthority) UpdateCRLShard(ctx context.Context, req *sapb.Update
 	return nil, errors.New("unimplemented")
 }
 
+// CheckIdentifiersPaused is a mock.
+func (sa *StorageAuthorityReadOnly) CheckIdentifiersPaused(ctx context.Context, req *sapb.PauseRequest, _ ...grpc.CallOption) (*sapb.Identifiers, error) {
+	return &sapb.Identifiers{}, nil
+}
+
+// PauseIdentifiers is a mock.
+func (sa *StorageAuthority) PauseIdentifiers(ctx context.Context, req *sapb.PauseRequest, _ ...grpc.CallOption) (*sapb.PauseIdentifiersResponse, error) {
+	return &sapb.PauseIdentifiersResponse{}, nil
+}
+
+// UnpauseAccount is a mock.
+func (sa *StorageAuthority) UnpauseAccount(ctx context.Context, req *sapb.RegistrationID, _ ...grpc.CallOption) (*sapb.Count, error) {
+	return &sapb.Count{}, nil
+}
+
+// GetApprovedOverrideRequests is a mock.
+func (sa *StorageAuthorityReadOnly) GetApprovedOverrideRequests(ctx context.Context, _ *emptypb.Empty, _ ...grpc.CallOption) (sapb.StorageAuthorityReadOnly_GetApprovedOverrideRequestsClient, error) {
+	return nil, nil
+}
+
+// GetApprovedOverrideRequests is a mock.
+func (sa *StorageAuthority) GetApprovedOverrideRequests(ctx context.Context, _ *emptypb.Empty, _ ...grpc.CallOption) (sapb.StorageAuthority_GetApprovedOverrideRequestsClient, error) {
+	return nil, nil
+}
+
+// AddOverrideRequest is a mock.
+func (sa *StorageAuthority) AddOverrideRequest(ctx context.Context, req *sapb.OverrideRequest, _ ...grpc.CallOption) (*sapb.OverrideRequest, error) {
+	return req, nil
+}
+
+// UpdateOverrideRequestState is a mock.
+func (sa *StorageAuthority) UpdateOverrideRequestState(ctx context.Context, req *sapb.UpdateOverrideRequestStateRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
 // PublisherClient is a mock
 type PublisherClient struct {
 	// empty
@@ -115,24 +115,13 @@ func NewNonceService(stats prometheus.Registerer, maxUsed int, prefix string) (*
 	//
 	// TODO(#6610): Update this comment once we've moved to eight character
 	// prefixes by default.
-	if prefix != "" {
-		// TODO(#6610): Refactor once we've moved to derivable prefixes by
-		// default.
-		if len(prefix) != PrefixLen && len(prefix) != DeprecatedPrefixLen {
-			return nil, fmt.Errorf(
-				"'noncePrefix' must be %d or %d characters, not %d",
-				PrefixLen,
-				DeprecatedPrefixLen,
-				len(prefix),
-			)
-		}
-		if _, err := base64.RawURLEncoding.DecodeString(prefix); err != nil {
-			return nil, errors.New("nonce prefix must be valid base64url")
-		}
+	err := validatePrefix(prefix)
+	if err != nil {
+		return nil, err
 	}
 
 	key := make([]byte, 16)
-	if _, err := rand.Read(key); err != nil {
+	if _, err = rand.Read(key); err != nil {
 		return nil, err
 	}
 
@@ -188,6 +177,28 @@ func NewNonceService(stats prometheus.Registerer, maxUsed int, prefix string) (*
 	}, nil
 }
 
+// validatePrefix returns an error if prefix is neither empty nor a valid nonce
+// prefix.
+func validatePrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	// TODO(#6610): Refactor once we've moved to derivable prefixes by
+	// default.
+	if len(prefix) != PrefixLen && len(prefix) != DeprecatedPrefixLen {
+		return fmt.Errorf(
+			"'noncePrefix' must be %d or %d characters, not %d",
+			PrefixLen,
+			DeprecatedPrefixLen,
+			len(prefix),
+		)
+	}
+	if _, err := base64.RawURLEncoding.DecodeString(prefix); err != nil {
+		return errors.New("nonce prefix must be valid base64url")
+	}
+	return nil
+}
+
 func (ns *NonceService) encrypt(counter int64) (string, error) {
 	// Generate a nonce with upper 4 bytes zero
 	nonce := make([]byte, 12)
@@ -342,25 +353,55 @@ func RemoteRedeem(ctx context.Context, noncePrefixMap map[string]Redeemer, nonce
 	return resp.Valid, nil
 }
 
+// backend generates and redeems nonces for a Server. It is implemented by
+// *RedisNonceService, and by memoryBackend for a *NonceService.
+type backend interface {
+	Nonce(ctx context.Context) (string, error)
+	Valid(ctx context.Context, nonce string) (bool, error)
+}
+
+// memoryBackend adapts a NonceService, which never blocks or fails to redeem,
+// to the backend interface.
+type memoryBackend struct {
+	ns *NonceService
+}
+
+func (b memoryBackend) Nonce(context.Context) (string, error) {
+	return b.ns.Nonce()
+}
+
+func (b memoryBackend) Valid(_ context.Context, nonce string) (bool, error) {
+	return b.ns.Valid(nonce), nil
+}
+
 // NewServer returns a new Server, wrapping a NonceService.
 func NewServer(inner *NonceService) *Server {
+	return &Server{inner: memoryBackend{inner}}
+}
+
+// NewRedisServer returns a new Server, wrapping a RedisNonceService.
+func NewRedisServer(inner *RedisNonceService) *Server {
 	return &Server{inner: inner}
 }
 
 // Server implements the gRPC nonce service.
 type Server struct {
 	noncepb.UnimplementedNonceServiceServer
-	inner *NonceService
+	inner backend
 }
 
 // Redeem accepts a nonce from a gRPC client and redeems it using the inner nonce service.
 func (ns *Server) Redeem(ctx context.Context, msg *noncepb.NonceMessage) (*noncepb.ValidMessage, error) {
-	return &noncepb.ValidMessage{Valid: ns.inner.Valid(msg.Nonce)}, nil
+	valid, err := ns.inner.Valid(ctx, msg.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &noncepb.ValidMessage{Valid: valid}, nil
 }
 
 // Nonce generates a nonce and sends it to a gRPC client.
-func (ns *Server) Nonce(_ context.Context, _ *emptypb.Empty) (*noncepb.NonceMessage, error) {
-	nonce, err := ns.inner.Nonce()
+func (ns *Server) Nonce(ctx context.Context, _ *emptypb.Empty) (*noncepb.NonceMessage, error) {
+	nonce, err := ns.inner.Nonce(ctx)
 	if err != nil {
 		return nil, err
 	}
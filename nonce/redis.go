@@ -0,0 +1,112 @@
+package nonce
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisNonceTTL is how long a nonce stored in Redis can be redeemed for
+// if no other lifetime is configured. Every ACME response carries a fresh
+// nonce, most of which are never redeemed, so this bounds the space they take.
+const DefaultRedisNonceTTL = 15 * time.Minute
+
+// redisNonceKeyPrefix namespaces nonce keys within a Redis ring which may be
+// shared with other services.
+const redisNonceKeyPrefix = "nonce:"
+
+// RedisNonceService generates nonces and stores them in Redis, from which each
+// can be redeemed once, before it expires, by any RedisNonceService sharing
+// the same Redis ring. This lets any nonce service instance, and so any WFE,
+// accept a nonce issued by any other.
+type RedisNonceService struct {
+	client       *redis.Ring
+	ttl          time.Duration
+	prefix       string
+	nonceCreates prometheus.Counter
+	nonceRedeems *prometheus.CounterVec
+}
+
+// NewRedisNonceService constructs a RedisNonceService storing nonces in client
+// for ttl, or DefaultRedisNonceTTL if ttl isn't positive. Nonces are prefixed
+// by prefix, which must be empty or meet the same requirements as for
+// NewNonceService.
+func NewRedisNonceService(client *redis.Ring, stats prometheus.Registerer, ttl time.Duration, prefix string) (*RedisNonceService, error) {
+	err := validatePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultRedisNonceTTL
+	}
+
+	nonceCreates := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nonce_creates",
+		Help: "A counter of nonces generated",
+	})
+	stats.MustRegister(nonceCreates)
+	nonceRedeems := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "nonce_redeems",
+		Help: "A counter of nonce validations labelled by result",
+	}, []string{"result", "error"})
+	stats.MustRegister(nonceRedeems)
+
+	return &RedisNonceService{
+		client:       client,
+		ttl:          ttl,
+		prefix:       prefix,
+		nonceCreates: nonceCreates,
+		nonceRedeems: nonceRedeems,
+	}, nil
+}
+
+// Nonce generates a random nonce and stores it in Redis until it expires.
+func (ns *RedisNonceService) Nonce(ctx context.Context) (string, error) {
+	// NonceLen base64url characters encode three quarters as many bytes.
+	body := make([]byte, NonceLen*3/4)
+	_, err := rand.Read(body)
+	if err != nil {
+		return "", err
+	}
+	nonce := ns.prefix + base64.RawURLEncoding.EncodeToString(body)
+
+	stored, err := ns.client.SetNX(ctx, redisNonceKeyPrefix+nonce, "", ns.ttl).Result()
+	if err != nil {
+		return "", fmt.Errorf("storing nonce: %w", err)
+	}
+	if !stored {
+		// This should never happen, short of a broken random number generator.
+		return "", errors.New("generated nonce already exists")
+	}
+	ns.nonceCreates.Inc()
+	return nonce, nil
+}
+
+// Valid redeems the provided nonce, returning true if it was stored and has
+// neither expired nor been redeemed before. A nonce is deleted as it's
+// redeemed, so of several concurrent redemptions at most one succeeds.
+func (ns *RedisNonceService) Valid(ctx context.Context, nonce string) (bool, error) {
+	if len(nonce) != len(ns.prefix)+NonceLen {
+		ns.nonceRedeems.WithLabelValues("invalid", "length").Inc()
+		return false, nil
+	}
+
+	deleted, err := ns.client.Del(ctx, redisNonceKeyPrefix+nonce).Result()
+	if err != nil {
+		ns.nonceRedeems.WithLabelValues("error", "redis").Inc()
+		return false, fmt.Errorf("redeeming nonce: %w", err)
+	}
+	if deleted == 0 {
+		ns.nonceRedeems.WithLabelValues("invalid", "unknown").Inc()
+		return false, nil
+	}
+	ns.nonceRedeems.WithLabelValues("valid", "").Inc()
+	return true, nil
+}
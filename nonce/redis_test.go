@@ -0,0 +1,80 @@
+package nonce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/letsencrypt/boulder/metrics"
+	noncepb "github.com/letsencrypt/boulder/nonce/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// newTestRedisNonceServices returns two RedisNonceServices, with different
+// prefixes, sharing a miniredis server, along with the server.
+func newTestRedisNonceServices(t *testing.T) (*RedisNonceService, *RedisNonceService, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	ring := redis.NewRing(&redis.RingOptions{
+		Addrs: map[string]string{"shard1": server.Addr()},
+	})
+	t.Cleanup(func() { _ = ring.Close() })
+	a, err := NewRedisNonceService(ring, metrics.NoopRegisterer, time.Minute, "aaaaaaaa")
+	test.AssertNotError(t, err, "Could not create nonce service")
+	b, err := NewRedisNonceService(ring, metrics.NoopRegisterer, time.Minute, "bbbbbbbb")
+	test.AssertNotError(t, err, "Could not create nonce service")
+	return a, b, server
+}
+
+func TestRedisNonce(t *testing.T) {
+	ctx := context.Background()
+	a, b, server := newTestRedisNonceServices(t)
+
+	n, err := a.Nonce(ctx)
+	test.AssertNotError(t, err, "Could not create nonce")
+	test.AssertEquals(t, len(n), PrefixLen+NonceLen)
+	test.AssertEquals(t, n[:PrefixLen], "aaaaaaaa")
+
+	// Any service sharing the ring can redeem the nonce, but only once.
+	valid, err := b.Valid(ctx, n)
+	test.AssertNotError(t, err, "Could not redeem nonce")
+	test.Assert(t, valid, "Did not recognize fresh nonce")
+	valid, err = a.Valid(ctx, n)
+	test.AssertNotError(t, err, "Could not redeem nonce")
+	test.Assert(t, !valid, "Recognized the same nonce twice")
+
+	// Nonces expire.
+	n, err = a.Nonce(ctx)
+	test.AssertNotError(t, err, "Could not create nonce")
+	server.FastForward(time.Minute)
+	valid, err = a.Valid(ctx, n)
+	test.AssertNotError(t, err, "Could not redeem nonce")
+	test.Assert(t, !valid, "Recognized an expired nonce")
+
+	valid, err = a.Valid(ctx, "aaaaaaaa-malformed")
+	test.AssertNotError(t, err, "Could not redeem nonce")
+	test.Assert(t, !valid, "Recognized a malformed nonce")
+
+	_, err = NewRedisNonceService(nil, metrics.NoopRegisterer, 0, "bad")
+	test.AssertError(t, err, "Accepted an invalid prefix")
+}
+
+func TestRedisServer(t *testing.T) {
+	ctx := context.Background()
+	a, _, server := newTestRedisNonceServices(t)
+	s := NewRedisServer(a)
+
+	msg, err := s.Nonce(ctx, nil)
+	test.AssertNotError(t, err, "Could not create nonce")
+	resp, err := s.Redeem(ctx, msg)
+	test.AssertNotError(t, err, "Could not redeem nonce")
+	test.Assert(t, resp.Valid, "Did not recognize fresh nonce")
+
+	// Unlike an invalid nonce, a failure to reach Redis is an error.
+	server.Close()
+	_, err = s.Redeem(ctx, &noncepb.NonceMessage{Nonce: msg.Nonce})
+	test.AssertError(t, err, "Redeemed a nonce without Redis")
+}
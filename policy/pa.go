@@ -19,6 +19,7 @@ import (
 
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/iana"
 	"github.com/letsencrypt/boulder/identifier"
 	blog "github.com/letsencrypt/boulder/log"
@@ -526,8 +527,12 @@ func (pa *AuthorityImpl) challengeTypesFor(identifier identifier.ACMEIdentifier)
 				"Challenges requested for wildcard identifier but DNS-01 " +
 					"challenge type is not enabled")
 		}
-		// Only provide a DNS-01-Wildcard challenge
+		// Only provide DNS-based challenges: DNS-01 and, if enabled,
+		// DNS-ACCOUNT-01.
 		challenges = []core.AcmeChallenge{core.ChallengeTypeDNS01}
+		if pa.dnsAccount01Enabled() {
+			challenges = append(challenges, core.ChallengeTypeDNSAccount01)
+		}
 	} else {
 		// Otherwise we collect up challenges based on what is enabled.
 		if pa.ChallengeTypeEnabled(core.ChallengeTypeHTTP01) {
@@ -541,6 +546,10 @@ func (pa *AuthorityImpl) challengeTypesFor(identifier identifier.ACMEIdentifier)
 		if pa.ChallengeTypeEnabled(core.ChallengeTypeDNS01) {
 			challenges = append(challenges, core.ChallengeTypeDNS01)
 		}
+
+		if pa.dnsAccount01Enabled() {
+			challenges = append(challenges, core.ChallengeTypeDNSAccount01)
+		}
 	}
 
 	return challenges, nil
@@ -589,6 +598,13 @@ func (pa *AuthorityImpl) ChallengeTypeEnabled(t core.AcmeChallenge) bool {
 	return pa.enabledChallenges[t]
 }
 
+// dnsAccount01Enabled returns whether the DNS-ACCOUNT-01 challenge type is
+// enabled, which requires both the DNSAccount01Enabled feature flag and its
+// inclusion in the PA's challenges.
+func (pa *AuthorityImpl) dnsAccount01Enabled() bool {
+	return features.Get().DNSAccount01Enabled && pa.ChallengeTypeEnabled(core.ChallengeTypeDNSAccount01)
+}
+
 // CheckAuthz determines that an authorization was fulfilled by a challenge
 // that was appropriate for the kind of identifier in the authorization.
 func (pa *AuthorityImpl) CheckAuthz(authz *core.Authorization) error {
@@ -418,6 +418,33 @@ func TestChallengesForWildcard(t *testing.T) {
 	test.AssertEquals(t, challenges[0].Type, core.ChallengeTypeDNS01)
 }
 
+func TestChallengesForDNSAccount01(t *testing.T) {
+	pa := must.Do(New(map[core.AcmeChallenge]bool{
+		core.ChallengeTypeHTTP01:       true,
+		core.ChallengeTypeDNS01:        true,
+		core.ChallengeTypeDNSAccount01: true,
+	}, blog.NewMock()))
+	ident := identifier.DNSIdentifier("zombo.com")
+	wildcardIdent := identifier.DNSIdentifier("*.zombo.com")
+
+	// DNS-ACCOUNT-01 isn't offered without its feature flag.
+	challTypes, err := pa.challengeTypesFor(ident)
+	test.AssertNotError(t, err, "challengeTypesFor failed")
+	test.AssertDeepEquals(t, challTypes, []core.AcmeChallenge{core.ChallengeTypeHTTP01, core.ChallengeTypeDNS01})
+
+	features.Set(features.Config{DNSAccount01Enabled: true})
+	defer features.Reset()
+
+	challTypes, err = pa.challengeTypesFor(ident)
+	test.AssertNotError(t, err, "challengeTypesFor failed")
+	test.AssertDeepEquals(t, challTypes, []core.AcmeChallenge{core.ChallengeTypeHTTP01, core.ChallengeTypeDNS01, core.ChallengeTypeDNSAccount01})
+
+	// It's offered alongside DNS-01 for wildcards.
+	challTypes, err = pa.challengeTypesFor(wildcardIdent)
+	test.AssertNotError(t, err, "challengeTypesFor failed")
+	test.AssertDeepEquals(t, challTypes, []core.AcmeChallenge{core.ChallengeTypeDNS01, core.ChallengeTypeDNSAccount01})
+}
+
 // TestMalformedExactBlocklist tests that loading a YAML policy file with an
 // invalid exact blocklist entry will fail as expected.
 func TestMalformedExactBlocklist(t *testing.T) {
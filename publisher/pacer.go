@@ -0,0 +1,252 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/ctpolicy/ctconfig"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/ratelimits"
+)
+
+// logPacer paces and caps the submissions made to a single CT log, as
+// configured by a ctconfig.LogLimit.
+type logPacer struct {
+	uri string
+
+	// limit is nil if submissions to the log are not paced.
+	limit *ratelimits.InternalLimit
+
+	// slots holds one entry per submission in flight to the log. It is nil if
+	// in flight submissions are not limited.
+	slots chan struct{}
+
+	sync.Mutex
+	queued    int
+	maxQueued int
+}
+
+// enqueue counts a submission as waiting for its turn, returning false if
+// maxQueued submissions are already waiting.
+func (lp *logPacer) enqueue() bool {
+	lp.Lock()
+	defer lp.Unlock()
+	if lp.queued >= lp.maxQueued {
+		return false
+	}
+	lp.queued++
+	return true
+}
+
+func (lp *logPacer) dequeue() {
+	lp.Lock()
+	defer lp.Unlock()
+	lp.queued--
+}
+
+// pacer paces and caps the submissions made to each CT log with a configured
+// ctconfig.LogLimit. Submissions which can't be made right away wait their
+// turn, up to the deadline of their context, unless too many submissions are
+// already waiting, in which case they fail immediately so that the caller can
+// move on to another log.
+type pacer struct {
+	limiter *ratelimits.Limiter
+	clk     clock.Clock
+	logs    map[string]*logPacer
+
+	queueWait *prometheus.HistogramVec
+	queued    *prometheus.GaugeVec
+	inFlight  *prometheus.GaugeVec
+	rejected  *prometheus.CounterVec
+}
+
+// normalizeLogURI returns the provided log URL as it's keyed in the logCache,
+// without a trailing slash.
+func normalizeLogURI(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String(), nil
+}
+
+// newPacer returns a *pacer for the provided limits, keyed by log URL. It
+// returns nil if no limits are provided.
+func newPacer(logLimits map[string]ctconfig.LogLimit, clk clock.Clock, stats prometheus.Registerer) (*pacer, error) {
+	if len(logLimits) == 0 {
+		return nil, nil
+	}
+
+	logs := make(map[string]*logPacer)
+	for logURL, ll := range logLimits {
+		uri, err := normalizeLogURI(logURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing log URL %q: %w", logURL, err)
+		}
+		lp := &logPacer{uri: uri, maxQueued: ll.MaxQueued}
+		if ll.Count != 0 {
+			lp.limit, err = ratelimits.NewInternalLimit(ratelimits.CTSubmissionsPerLog, ll.Burst, ll.Count, ll.Period.Duration)
+			if err != nil {
+				return nil, fmt.Errorf("limit for log %q: %w", logURL, err)
+			}
+			_, err = lp.limit.Transaction(uri, 1)
+			if err != nil {
+				return nil, fmt.Errorf("limit for log %q: %w", logURL, err)
+			}
+		}
+		if ll.MaxConcurrent != 0 {
+			lp.slots = make(chan struct{}, ll.MaxConcurrent)
+		}
+		logs[uri] = lp
+	}
+
+	// Prefix the Limiter's metrics so that they don't collide with those of
+	// any other Limiter in the same process.
+	limiter, err := ratelimits.NewLimiter(clk, ratelimits.NewInmemSource(), prometheus.WrapRegistererWithPrefix("publisher_", stats))
+	if err != nil {
+		return nil, err
+	}
+
+	queueWait := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ct_submission_queue_wait_seconds",
+		Help:    "Time submissions to a paced CT log waited for their turn, labelled by log",
+		Buckets: metrics.InternetFacingBuckets,
+	}, []string{"log"})
+	stats.MustRegister(queueWait)
+
+	queued := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ct_submissions_queued",
+		Help: "Number of submissions waiting for their turn to be submitted to a paced CT log, labelled by log",
+	}, []string{"log"})
+	stats.MustRegister(queued)
+
+	inFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ct_submissions_in_flight",
+		Help: "Number of submissions in flight to a paced CT log, labelled by log",
+	}, []string{"log"})
+	stats.MustRegister(inFlight)
+
+	rejected := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ct_submissions_rejected",
+		Help: "Count of submissions to a paced CT log which failed without being submitted, labelled by log and reason",
+	}, []string{"log", "reason"})
+	stats.MustRegister(rejected)
+
+	return &pacer{
+		limiter:   limiter,
+		clk:       clk,
+		logs:      logs,
+		queueWait: queueWait,
+		queued:    queued,
+		inFlight:  inFlight,
+		rejected:  rejected,
+	}, nil
+}
+
+// wait blocks until a submission may be made to the log at the provided URL.
+// On success it returns a function which must be called once the submission
+// is complete. Submissions to logs without a configured limit never wait.
+func (p *pacer) wait(ctx context.Context, uri string) (func(), error) {
+	lp, ok := p.logs[uri]
+	if !ok {
+		return func() {}, nil
+	}
+
+	start := p.clk.Now()
+	var queued bool
+	enqueue := func() error {
+		if queued {
+			return nil
+		}
+		if !lp.enqueue() {
+			p.rejected.WithLabelValues(uri, "queue_full").Inc()
+			return fmt.Errorf("too many submissions waiting for CT log %q", uri)
+		}
+		queued = true
+		p.queued.WithLabelValues(uri).Inc()
+		return nil
+	}
+	defer func() {
+		if queued {
+			lp.dequeue()
+			p.queued.WithLabelValues(uri).Dec()
+		}
+	}()
+	fail := func(err error) (func(), error) {
+		if ctx.Err() != nil {
+			p.rejected.WithLabelValues(uri, "canceled").Inc()
+		}
+		return nil, err
+	}
+
+	// Take a slot before spending from the log's bucket, so that a submission
+	// which gives up waiting for a slot hasn't spent a turn it never used.
+	if lp.slots != nil {
+		select {
+		case lp.slots <- struct{}{}:
+		default:
+			err := enqueue()
+			if err != nil {
+				return nil, err
+			}
+			select {
+			case lp.slots <- struct{}{}:
+			case <-ctx.Done():
+				return fail(ctx.Err())
+			}
+		}
+	}
+	release := func() {
+		if lp.slots != nil {
+			<-lp.slots
+		}
+	}
+
+	for lp.limit != nil {
+		txn, err := lp.limit.Transaction(uri, 1)
+		if err != nil {
+			release()
+			return fail(err)
+		}
+		d, err := p.limiter.Spend(ctx, txn)
+		if err != nil {
+			release()
+			return fail(err)
+		}
+		if d.Allowed {
+			break
+		}
+		err = enqueue()
+		if err != nil {
+			release()
+			return nil, err
+		}
+		deadline, ok := ctx.Deadline()
+		if ok && p.clk.Now().Add(d.RetryIn).After(deadline) {
+			// Don't wait for a turn that comes too late.
+			release()
+			p.rejected.WithLabelValues(uri, "deadline").Inc()
+			return nil, fmt.Errorf("next submission to CT log %q is allowed in %s, after the deadline", uri, d.RetryIn)
+		}
+		select {
+		case <-ctx.Done():
+			release()
+			return fail(ctx.Err())
+		case <-p.clk.After(d.RetryIn):
+		}
+	}
+
+	p.queueWait.WithLabelValues(uri).Observe(p.clk.Since(start).Seconds())
+	p.inFlight.WithLabelValues(uri).Inc()
+	return func() {
+		p.inFlight.WithLabelValues(uri).Dec()
+		release()
+	}, nil
+}
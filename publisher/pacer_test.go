@@ -0,0 +1,164 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/config"
+	"github.com/letsencrypt/boulder/ctpolicy/ctconfig"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestPacer(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	clk := clock.NewFake()
+	p, err := newPacer(map[string]ctconfig.LogLimit{
+		"https://paced.example.com/": {
+			Burst:  1,
+			Count:  1,
+			Period: config.Duration{Duration: time.Minute},
+		},
+		"https://capped.example.com": {
+			MaxConcurrent: 1,
+			MaxQueued:     1,
+		},
+	}, clk, metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "creating pacer")
+
+	// Submissions to logs without a limit never wait.
+	release, err := p.wait(ctx, "https://other.example.com")
+	test.AssertNotError(t, err, "unlimited log")
+	release()
+
+	// The trailing slash of a configured log URL is ignored. Once the burst is
+	// spent, and with no submissions allowed to wait, the next fails.
+	release, err = p.wait(ctx, "https://paced.example.com")
+	test.AssertNotError(t, err, "first paced submission")
+	release()
+	_, err = p.wait(ctx, "https://paced.example.com")
+	test.AssertError(t, err, "paced submission with no room to wait")
+	test.AssertMetricWithLabelsEquals(t, p.rejected, prometheus.Labels{"log": "https://paced.example.com", "reason": "queue_full"}, 1)
+	clk.Add(time.Minute)
+	release, err = p.wait(ctx, "https://paced.example.com")
+	test.AssertNotError(t, err, "paced submission after refill")
+	release()
+
+	// A submission waits for an in flight submission to finish.
+	release, err = p.wait(ctx, "https://capped.example.com")
+	test.AssertNotError(t, err, "first capped submission")
+	test.AssertMetricWithLabelsEquals(t, p.inFlight, prometheus.Labels{"log": "https://capped.example.com"}, 1)
+	done := make(chan error)
+	go func() {
+		release, err := p.wait(ctx, "https://capped.example.com")
+		if err == nil {
+			release()
+		}
+		done <- err
+	}()
+	for {
+		p.logs["https://capped.example.com"].Lock()
+		queued := p.logs["https://capped.example.com"].queued
+		p.logs["https://capped.example.com"].Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Only one submission may wait.
+	_, err = p.wait(ctx, "https://capped.example.com")
+	test.AssertError(t, err, "capped submission with no room to wait")
+	release()
+	test.AssertNotError(t, <-done, "waiting capped submission")
+	test.AssertMetricWithLabelsEquals(t, p.inFlight, prometheus.Labels{"log": "https://capped.example.com"}, 0)
+	test.AssertMetricWithLabelsEquals(t, p.queued, prometheus.Labels{"log": "https://capped.example.com"}, 0)
+
+	// A canceled submission stops waiting.
+	release, err = p.wait(ctx, "https://capped.example.com")
+	test.AssertNotError(t, err, "capped submission")
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = p.wait(cancelCtx, "https://capped.example.com")
+	test.AssertErrorIs(t, err, context.Canceled)
+	test.AssertMetricWithLabelsEquals(t, p.rejected, prometheus.Labels{"log": "https://capped.example.com", "reason": "canceled"}, 1)
+	release()
+}
+
+func TestPacerDeadline(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	p, err := newPacer(map[string]ctconfig.LogLimit{
+		"https://paced.example.com": {
+			Burst:     1,
+			Count:     1,
+			Period:    config.Duration{Duration: time.Minute},
+			MaxQueued: 10,
+		},
+	}, clk, metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "creating pacer")
+
+	release, err := p.wait(context.Background(), "https://paced.example.com")
+	test.AssertNotError(t, err, "first paced submission")
+	release()
+
+	// The next turn is a minute away, after the deadline.
+	ctx, cancel := context.WithDeadline(context.Background(), clk.Now().Add(time.Second))
+	defer cancel()
+	_, err = p.wait(ctx, "https://paced.example.com")
+	test.AssertError(t, err, "paced submission past its deadline")
+	test.AssertMetricWithLabelsEquals(t, p.rejected, prometheus.Labels{"log": "https://paced.example.com", "reason": "deadline"}, 1)
+}
+
+func TestNewPacer(t *testing.T) {
+	t.Parallel()
+	p, err := newPacer(nil, clock.NewFake(), metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "no limits")
+	test.Assert(t, p == nil, "pacer should be nil without limits")
+
+	_, err = newPacer(map[string]ctconfig.LogLimit{
+		"https://paced.example.com": {Burst: 1, Count: 1},
+	}, clock.NewFake(), metrics.NoopRegisterer)
+	test.AssertError(t, err, "limit without a period")
+
+	_, err = newPacer(map[string]ctconfig.LogLimit{
+		"paced.example.com": {Burst: 1, Count: 1, Period: config.Duration{Duration: time.Second}},
+	}, clock.NewFake(), metrics.NoopRegisterer)
+	test.AssertError(t, err, "relative log URL")
+}
+
+func TestPacerSlotBeforeSpend(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	p, err := newPacer(map[string]ctconfig.LogLimit{
+		"https://paced.example.com": {
+			Burst:         2,
+			Count:         1,
+			Period:        config.Duration{Duration: time.Minute},
+			MaxConcurrent: 1,
+			MaxQueued:     1,
+		},
+	}, clk, metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "creating pacer")
+
+	release, err := p.wait(context.Background(), "https://paced.example.com")
+	test.AssertNotError(t, err, "first submission")
+
+	// A submission canceled while waiting for a slot doesn't spend a turn.
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.wait(canceledCtx, "https://paced.example.com")
+	test.AssertErrorIs(t, err, context.Canceled)
+	release()
+
+	ctx, cancel := context.WithDeadline(context.Background(), clk.Now().Add(time.Second))
+	defer cancel()
+	release, err = p.wait(ctx, "https://paced.example.com")
+	test.AssertNotError(t, err, "the second turn should remain")
+	release()
+}
@@ -23,10 +23,12 @@ import (
 	ctClient "github.com/google/certificate-transparency-go/client"
 	"github.com/google/certificate-transparency-go/jsonclient"
 	cttls "github.com/google/certificate-transparency-go/tls"
+	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/letsencrypt/boulder/canceled"
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/ctpolicy/ctconfig"
 	"github.com/letsencrypt/boulder/issuance"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
@@ -199,16 +201,26 @@ type Impl struct {
 	issuerBundles map[issuance.NameID][]ct.ASN1Cert
 	ctLogsCache   logCache
 	metrics       *pubMetrics
+
+	// pacer is nil if no logLimits are configured.
+	pacer *pacer
 }
 
 // New creates a Publisher that will submit certificates
-// to requested CT logs
+// to requested CT logs, pacing its submissions to the logs
+// with a limit in logLimits, keyed by log URL.
 func New(
 	bundles map[issuance.NameID][]ct.ASN1Cert,
 	userAgent string,
+	logLimits map[string]ctconfig.LogLimit,
+	clk clock.Clock,
 	logger blog.Logger,
 	stats prometheus.Registerer,
-) *Impl {
+) (*Impl, error) {
+	pacer, err := newPacer(logLimits, clk, stats)
+	if err != nil {
+		return nil, err
+	}
 	return &Impl{
 		issuerBundles: bundles,
 		userAgent:     userAgent,
@@ -217,7 +229,8 @@ func New(
 		},
 		log:     logger,
 		metrics: initMetrics(stats),
-	}
+		pacer:   pacer,
+	}, nil
 }
 
 // SubmitToSingleCTWithResult will submit the certificate represented by certDER
@@ -253,6 +266,15 @@ func (pub *Impl) SubmitToSingleCTWithResult(ctx context.Context, req *pubpb.Requ
 		return nil, err
 	}
 
+	if pub.pacer != nil {
+		release, err := pub.pacer.wait(ctx, ctLog.uri)
+		if err != nil {
+			pub.log.Warningf("Failed to submit certificate to CT log at %s: %s", ctLog.uri, err)
+			return nil, err
+		}
+		defer release()
+	}
+
 	sct, err := pub.singleLogSubmit(ctx, chain, req.Kind, ctLog)
 	if err != nil {
 		if canceled.Is(err) {
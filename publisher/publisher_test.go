@@ -23,6 +23,7 @@ import (
 	"time"
 
 	ct "github.com/google/certificate-transparency-go"
+	"github.com/jmhodges/clock"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/letsencrypt/boulder/core"
@@ -150,11 +151,14 @@ func setup(t *testing.T) (*Impl, *x509.Certificate, *ecdsa.PrivateKey) {
 		chain2[0].NameID(): GetCTBundleForChain(chain2),
 		chain3[0].NameID(): GetCTBundleForChain(chain3),
 	}
-	pub := New(
+	pub, err := New(
 		issuerBundles,
 		"test-user-agent/1.0",
+		nil,
+		clock.NewFake(),
 		log,
 		metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "failed to create publisher")
 
 	// Load leaf certificate
 	leaf, err := core.LoadCert("../test/hierarchy/ee-r3.cert.pem")
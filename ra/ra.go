@@ -106,6 +106,13 @@ type RegistrationAuthorityImpl struct {
 
 	ctpolicy *ctpolicy.CTPolicy
 
+	// limiter and txnBuilder, if set, evaluate the NewOrdersPerAccount and
+	// CertificatesPerDomain limits using the key-value rate limiter, either in
+	// shadow mode, by comparator, or authoritatively.
+	limiter    ratelimits.TransactionLimiter
+	txnBuilder *ratelimits.TransactionBuilder
+	comparator *ratelimits.Comparator
+
 	ctpolicyResults             *prometheus.HistogramVec
 	revocationReasonCounter     *prometheus.CounterVec
 	namesPerCert                *prometheus.HistogramVec
@@ -137,6 +144,8 @@ func NewRegistrationAuthorityImpl(
 	ctp *ctpolicy.CTPolicy,
 	purger akamaipb.AkamaiPurgerClient,
 	issuers []*issuance.Certificate,
+	limiter ratelimits.TransactionLimiter,
+	txnBuilder *ratelimits.TransactionBuilder,
 ) *RegistrationAuthorityImpl {
 	ctpolicyResults := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -238,6 +247,11 @@ func NewRegistrationAuthorityImpl(
 		issuersByNameID[issuer.NameID()] = issuer
 	}
 
+	var comparator *ratelimits.Comparator
+	if limiter != nil && txnBuilder != nil {
+		comparator = ratelimits.NewComparator(limiter, stats, logger)
+	}
+
 	ra := &RegistrationAuthorityImpl{
 		clk:                          clk,
 		log:                          logger,
@@ -266,6 +280,9 @@ func NewRegistrationAuthorityImpl(
 		authzAges:                    authzAges,
 		orderAges:                    orderAges,
 		inflightFinalizes:            inflightFinalizes,
+		limiter:                      limiter,
+		txnBuilder:                   txnBuilder,
+		comparator:                   comparator,
 	}
 	return ra
 }
@@ -1420,18 +1437,34 @@ func (ra *RegistrationAuthorityImpl) enforceNameCounts(ctx context.Context, name
 	return badNames, response.Earliest.AsTime(), nil
 }
 
+// isRenewal returns true if a certificate has already been issued for exactly
+// the provided names, in which case a new one is exempt from the
+// CertificatesPerName limit.
+func (ra *RegistrationAuthorityImpl) isRenewal(ctx context.Context, names []string) (bool, error) {
+	exists, err := ra.SA.FQDNSetExists(ctx, &sapb.FQDNSetExistsRequest{Domains: names})
+	if err != nil {
+		return false, fmt.Errorf("checking renewal exemption for %q: %s", names, err)
+	}
+	return exists.Exists, nil
+}
+
 func (ra *RegistrationAuthorityImpl) checkCertificatesPerNameLimit(ctx context.Context, names []string, limit ratelimit.RateLimitPolicy, regID int64) error {
 	// check if there is already an existing certificate for
 	// the exact name set we are issuing for. If so bypass the
 	// the certificatesPerName limit.
-	exists, err := ra.SA.FQDNSetExists(ctx, &sapb.FQDNSetExistsRequest{Domains: names})
+	renewal, err := ra.isRenewal(ctx, names)
 	if err != nil {
-		return fmt.Errorf("checking renewal exemption for %q: %s", names, err)
+		return err
 	}
-	if exists.Exists {
+	if renewal {
 		return nil
 	}
+	return ra.enforceCertificatesPerNameLimit(ctx, names, limit, regID)
+}
 
+// enforceCertificatesPerNameLimit is checkCertificatesPerNameLimit for names
+// which are known not to be a renewal.
+func (ra *RegistrationAuthorityImpl) enforceCertificatesPerNameLimit(ctx context.Context, names []string, limit ratelimit.RateLimitPolicy, regID int64) error {
 	tldNames := ratelimits.DomainsForRateLimiting(names)
 	namesOutOfLimit, earliest, err := ra.enforceNameCounts(ctx, tldNames, limit, regID)
 	if err != nil {
@@ -1521,33 +1554,159 @@ func (ra *RegistrationAuthorityImpl) checkCertificatesPerFQDNSetLimit(ctx contex
 	}
 }
 
-func (ra *RegistrationAuthorityImpl) checkNewOrderLimits(ctx context.Context, names []string, regID int64) error {
+// checkLimiterNewOrderLimits enforces the NewOrdersPerAccount and
+// CertificatesPerDomain limits using only the key-value rate limiter, spending
+// from each of their buckets if, and only if, all of them have capacity. It is
+// used in place of the legacy NewOrdersPerAccount and CertificatesPerName
+// limits when the UseKvLimitsForNewOrder feature is enabled. If they are
+// spent from, the Transactions spent are returned, so that they can be
+// refunded if the order is not stored.
+func (ra *RegistrationAuthorityImpl) checkLimiterNewOrderLimits(ctx context.Context, names []string, regID int64) ([]ratelimits.Transaction, error) {
+	renewal, err := ra.isRenewal(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	orderTxn, err := ra.txnBuilder.OrdersPerAccountTransaction(regID)
+	if err != nil {
+		return nil, fmt.Errorf("building %s transaction: %w", ratelimits.NewOrdersPerAccount, err)
+	}
+	domainTxns, err := ra.certificatesPerDomainTransactions(regID, names, renewal)
+	if err != nil {
+		return nil, err
+	}
+
+	txns := append(domainTxns, orderTxn)
+	d, err := ra.limiter.BatchSpend(ctx, txns)
+	if err != nil {
+		return nil, fmt.Errorf("checking new order limits: %w", err)
+	}
+	if !d.Allowed {
+		retryAt := ra.clk.Now().Add(d.RetryIn)
+		ra.log.Infof("Rate limit exceeded, %s, regID: %d, domains: %s", d, regID, strings.Join(names, ", "))
+		return nil, berrors.RateLimitError(d.RetryIn, "too many new orders or certificates already issued for these names. Retry after %s", retryAt.Format(time.RFC3339))
+	}
+	return txns, nil
+}
+
+// refundNewOrderLimits refunds the Transactions spent by
+// checkLimiterNewOrderLimits for an order which was not stored. Failures are
+// logged rather than returned, so that the error which prevented the order
+// from being stored is the one returned to the client.
+func (ra *RegistrationAuthorityImpl) refundNewOrderLimits(ctx context.Context, txns []ratelimits.Transaction) {
+	if len(txns) == 0 {
+		return
+	}
+	// Remove cancellation from the request context, which may be why the
+	// order wasn't stored, so that the refund is still made.
+	_, err := ra.limiter.BatchRefund(context.WithoutCancel(ctx), txns)
+	if err != nil {
+		ra.log.Warningf("refunding new order limits: %s", err)
+	}
+}
+
+// certificatesPerDomainTransactions returns the CertificatesPerDomain
+// Transactions for a new order. If renewal is true, they're charged the
+// renewalCost of the limit, if it configures one, which should be 0 for parity
+// with the legacy CertificatesPerName limit's renewal exemption.
+func (ra *RegistrationAuthorityImpl) certificatesPerDomainTransactions(regID int64, names []string, renewal bool) ([]ratelimits.Transaction, error) {
+	txns, err := ra.txnBuilder.CertificatesPerDomainTransactions(regID, names)
+	if err != nil {
+		return nil, fmt.Errorf("building %s transactions: %w", ratelimits.CertificatesPerDomain, err)
+	}
+	if renewal {
+		for i := range txns {
+			txns[i] = txns[i].AsRenewal()
+		}
+	}
+	return txns, nil
+}
+
+// shadowLimit returns the result of legacy, the check of a legacy rate limit.
+// If the key-value rate limiter is configured, txns, built by the provided
+// function, are also spent from it and its decision compared with that of
+// legacy by the comparator, in shadow mode. If legacy allows the request, the
+// comparison is returned as compare rather than made, so that the caller can
+// make it only once every other limit has allowed the request too; otherwise
+// the Limiter would be charged for requests which the legacy limits never
+// count. If legacy denies the request, the comparison is made right away.
+func (ra *RegistrationAuthorityImpl) shadowLimit(ctx context.Context, name ratelimits.Name, txns func() ([]ratelimits.Transaction, error), legacy func() error) (compare func(), err error) {
+	legacyErr := legacy()
+	if ra.comparator == nil {
+		return nil, legacyErr
+	}
+	t, err := txns()
+	if err != nil {
+		ra.log.Warningf("building %s transactions for comparison: %s", name, err)
+		return nil, legacyErr
+	}
+	legacyResult := func() error { return legacyErr }
+	if legacyErr != nil {
+		return nil, ra.comparator.Compare(ctx, name, t, legacyResult)
+	}
+	return func() {
+		_ = ra.comparator.Compare(ctx, name, t, legacyResult)
+	}, nil
+}
+
+// checkNewOrderLimits checks every rate limit which a new order for the
+// provided names and ACME registration Id is subject to. If the key-value rate
+// limiter is authoritative, the Transactions spent from it are returned; see
+// checkLimiterNewOrderLimits.
+func (ra *RegistrationAuthorityImpl) checkNewOrderLimits(ctx context.Context, names []string, regID int64) ([]ratelimits.Transaction, error) {
+	limiterAuthoritative := ra.limiter != nil && ra.txnBuilder != nil && features.Get().UseKvLimitsForNewOrder
+
+	// comparisons are the shadow mode comparisons of the legacy limits which
+	// allowed the order, made only once every limit has allowed it.
+	var comparisons []func()
+
 	newOrdersPerAccountLimits := ra.rlPolicies.NewOrdersPerAccount()
-	if newOrdersPerAccountLimits.Enabled() {
+	if newOrdersPerAccountLimits.Enabled() && !limiterAuthoritative {
 		started := ra.clk.Now()
-		err := ra.checkNewOrdersPerAccountLimit(ctx, regID, newOrdersPerAccountLimits)
+		compare, err := ra.shadowLimit(ctx, ratelimits.NewOrdersPerAccount, func() ([]ratelimits.Transaction, error) {
+			txn, err := ra.txnBuilder.OrdersPerAccountTransaction(regID)
+			return []ratelimits.Transaction{txn}, err
+		}, func() error {
+			return ra.checkNewOrdersPerAccountLimit(ctx, regID, newOrdersPerAccountLimits)
+		})
 		elapsed := ra.clk.Since(started)
 		if err != nil {
 			if errors.Is(err, berrors.RateLimit) {
 				ra.rlCheckLatency.WithLabelValues(ratelimit.NewOrdersPerAccount, ratelimits.Denied).Observe(elapsed.Seconds())
 			}
-			return err
+			return nil, err
 		}
 		ra.rlCheckLatency.WithLabelValues(ratelimit.NewOrdersPerAccount, ratelimits.Allowed).Observe(elapsed.Seconds())
+		if compare != nil {
+			comparisons = append(comparisons, compare)
+		}
 	}
 
 	certNameLimits := ra.rlPolicies.CertificatesPerName()
-	if certNameLimits.Enabled() {
+	if certNameLimits.Enabled() && !limiterAuthoritative {
 		started := ra.clk.Now()
-		err := ra.checkCertificatesPerNameLimit(ctx, names, certNameLimits, regID)
+		renewal, err := ra.isRenewal(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		compare, err := ra.shadowLimit(ctx, ratelimits.CertificatesPerDomain, func() ([]ratelimits.Transaction, error) {
+			return ra.certificatesPerDomainTransactions(regID, names, renewal)
+		}, func() error {
+			if renewal {
+				return nil
+			}
+			return ra.enforceCertificatesPerNameLimit(ctx, names, certNameLimits, regID)
+		})
 		elapsed := ra.clk.Since(started)
 		if err != nil {
 			if errors.Is(err, berrors.RateLimit) {
 				ra.rlCheckLatency.WithLabelValues(ratelimit.CertificatesPerName, ratelimits.Denied).Observe(elapsed.Seconds())
 			}
-			return err
+			return nil, err
 		}
 		ra.rlCheckLatency.WithLabelValues(ratelimit.CertificatesPerName, ratelimits.Allowed).Observe(elapsed.Seconds())
+		if compare != nil {
+			comparisons = append(comparisons, compare)
+		}
 	}
 
 	fqdnLimitsFast := ra.rlPolicies.CertificatesPerFQDNSetFast()
@@ -1559,7 +1718,7 @@ func (ra *RegistrationAuthorityImpl) checkNewOrderLimits(ctx context.Context, na
 			if errors.Is(err, berrors.RateLimit) {
 				ra.rlCheckLatency.WithLabelValues(ratelimit.CertificatesPerFQDNSetFast, ratelimits.Denied).Observe(elapsed.Seconds())
 			}
-			return err
+			return nil, err
 		}
 		ra.rlCheckLatency.WithLabelValues(ratelimit.CertificatesPerFQDNSetFast, ratelimits.Allowed).Observe(elapsed.Seconds())
 	}
@@ -1573,7 +1732,7 @@ func (ra *RegistrationAuthorityImpl) checkNewOrderLimits(ctx context.Context, na
 			if errors.Is(err, berrors.RateLimit) {
 				ra.rlCheckLatency.WithLabelValues(ratelimit.CertificatesPerFQDNSet, ratelimits.Denied).Observe(elapsed.Seconds())
 			}
-			return err
+			return nil, err
 		}
 		ra.rlCheckLatency.WithLabelValues(ratelimit.CertificatesPerFQDNSet, ratelimits.Allowed).Observe(elapsed.Seconds())
 	}
@@ -1587,12 +1746,20 @@ func (ra *RegistrationAuthorityImpl) checkNewOrderLimits(ctx context.Context, na
 			if errors.Is(err, berrors.RateLimit) {
 				ra.rlCheckLatency.WithLabelValues(ratelimit.InvalidAuthorizationsPerAccount, ratelimits.Denied).Observe(elapsed.Seconds())
 			}
-			return err
+			return nil, err
 		}
 		ra.rlCheckLatency.WithLabelValues(ratelimit.InvalidAuthorizationsPerAccount, ratelimits.Allowed).Observe(elapsed.Seconds())
 	}
 
-	return nil
+	if limiterAuthoritative {
+		// Checked last, so that nothing is spent for an order which another
+		// limit denies.
+		return ra.checkLimiterNewOrderLimits(ctx, names, regID)
+	}
+	for _, compare := range comparisons {
+		compare()
+	}
+	return nil, nil
 }
 
 // UpdateRegistration updates an existing Registration with new values. Caller
@@ -1746,6 +1913,23 @@ func (ra *RegistrationAuthorityImpl) recordValidation(ctx context.Context, authI
 	return err
 }
 
+// checkIdentifierPaused returns a Paused error if ident has been paused for the
+// account regID, which may not validate it again until an operator unpauses
+// the account.
+func (ra *RegistrationAuthorityImpl) checkIdentifierPaused(ctx context.Context, regID int64, ident identifier.ACMEIdentifier) error {
+	resp, err := ra.SA.CheckIdentifiersPaused(ctx, &sapb.PauseRequest{
+		RegistrationID: regID,
+		Identifiers:    []*sapb.Identifier{{Type: string(ident.Type), Value: ident.Value}},
+	})
+	if err != nil {
+		return fmt.Errorf("checking whether identifier is paused: %w", err)
+	}
+	if len(resp.Identifiers) > 0 {
+		return berrors.PausedError("%q may not be validated by this account until it is unpaused", ident.Value)
+	}
+	return nil
+}
+
 // PerformValidation initiates validation for a specific challenge associated
 // with the given base authorization. The authorization and challenge are
 // updated based on the results.
@@ -1796,6 +1980,13 @@ func (ra *RegistrationAuthorityImpl) PerformValidation(
 		return nil, berrors.MalformedError("authorization must be pending")
 	}
 
+	if features.Get().CheckIdentifiersPaused {
+		err = ra.checkIdentifierPaused(ctx, authz.RegistrationID, authz.Identifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Look up the account key for this authorization
 	regPB, err := ra.SA.GetRegistration(ctx, &sapb.RegistrationID{Id: authz.RegistrationID})
 	if err != nil {
@@ -2365,7 +2556,7 @@ func (ra *RegistrationAuthorityImpl) GenerateOCSP(ctx context.Context, req *rapb
 }
 
 // NewOrder creates a new order object
-func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.NewOrderRequest) (*corepb.Order, error) {
+func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.NewOrderRequest) (_ *corepb.Order, err error) {
 	if req == nil || req.RegistrationID == 0 {
 		return nil, errIncompleteGRPCRequest
 	}
@@ -2381,7 +2572,7 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 	}
 
 	// Validate that our policy allows issuing for each of the names in the order
-	err := ra.PA.WillingToIssue(newOrder.Names)
+	err = ra.PA.WillingToIssue(newOrder.Names)
 	if err != nil {
 		return nil, err
 	}
@@ -2417,10 +2608,18 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 	}
 
 	// Check if there is rate limit space for issuing a certificate.
-	err = ra.checkNewOrderLimits(ctx, newOrder.Names, newOrder.RegistrationID)
+	spent, err := ra.checkNewOrderLimits(ctx, newOrder.Names, newOrder.RegistrationID)
 	if err != nil {
 		return nil, err
 	}
+	// If the order isn't stored, nothing should have been spent for it. The
+	// named err result is set by every return below, even those of nested
+	// scopes which re-declare err.
+	defer func() {
+		if err != nil {
+			ra.refundNewOrderLimits(ctx, spent)
+		}
+	}()
 
 	// An order's lifetime is effectively bound by the shortest remaining lifetime
 	// of its associated authorizations. For that reason it would be Uncool if
@@ -2460,13 +2659,13 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 		}
 		authz := nameToExistingAuthz[name]
 		authzAge := (ra.authorizationLifetime - authz.Expires.AsTime().Sub(ra.clk.Now())).Seconds()
-		// If the identifier is a wildcard and the existing authz only has one
-		// DNS-01 type challenge we can reuse it. In theory we will
+		// If the identifier is a wildcard and the existing authz only has
+		// DNS-based challenges we can reuse it. In theory we will
 		// never get back an authorization for a domain with a wildcard prefix
 		// that doesn't meet this criteria from SA.GetAuthorizations but we verify
 		// again to be safe.
 		if strings.HasPrefix(name, "*.") &&
-			len(authz.Challenges) == 1 && core.AcmeChallenge(authz.Challenges[0].Type) == core.ChallengeTypeDNS01 {
+			onlyDNSChallenges(authz.Challenges) {
 			authzID, err := strconv.ParseInt(authz.Id, 10, 64)
 			if err != nil {
 				return nil, err
@@ -2614,6 +2813,22 @@ func (ra *RegistrationAuthorityImpl) createPendingAuthz(reg int64, identifier id
 	return authz, nil
 }
 
+// onlyDNSChallenges returns true if challenges is non-empty and contains only
+// DNS-01 and DNS-ACCOUNT-01 challenges, the only ones offered for wildcards.
+func onlyDNSChallenges(challenges []*corepb.Challenge) bool {
+	if len(challenges) == 0 {
+		return false
+	}
+	for _, chall := range challenges {
+		switch core.AcmeChallenge(chall.Type) {
+		case core.ChallengeTypeDNS01, core.ChallengeTypeDNSAccount01:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // wildcardOverlap takes a slice of domain names and returns an error if any of
 // them is a non-wildcard FQDN that overlaps with a wildcard domain in the map.
 func wildcardOverlap(dnsNames []string) error {
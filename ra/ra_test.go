@@ -17,6 +17,8 @@ import (
 	"math/big"
 	mrand "math/rand"
 	"net"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -44,6 +46,7 @@ import (
 	"github.com/letsencrypt/boulder/ctpolicy"
 	"github.com/letsencrypt/boulder/ctpolicy/loglist"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/goodkey"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/identifier"
@@ -368,7 +371,7 @@ func initAuthorities(t *testing.T) (*DummyValidationAuthority, sapb.StorageAutho
 		300*24*time.Hour, 7*24*time.Hour,
 		nil, noopCAA{},
 		0, 5*time.Minute,
-		ctp, nil, nil)
+		ctp, nil, nil, nil, nil)
 	ra.SA = sa
 	ra.VA = va
 	ra.CA = ca
@@ -840,6 +843,61 @@ func TestPerformValidationAlreadyValid(t *testing.T) {
 	test.AssertNotError(t, err, "Error was not nil, but should have been nil")
 }
 
+// mockSAPaused is a mock SA for which only paused.example.com is paused.
+type mockSAPaused struct {
+	mocks.StorageAuthority
+}
+
+func (sa *mockSAPaused) CheckIdentifiersPaused(_ context.Context, req *sapb.PauseRequest, _ ...grpc.CallOption) (*sapb.Identifiers, error) {
+	resp := &sapb.Identifiers{}
+	for _, ident := range req.Identifiers {
+		if ident.Value == "paused.example.com" {
+			resp.Identifiers = append(resp.Identifiers, ident)
+		}
+	}
+	return resp, nil
+}
+
+func TestPerformValidationPaused(t *testing.T) {
+	_, _, ra, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+	ra.SA = &mockSAPaused{*mocks.NewStorageAuthority(fc)}
+
+	features.Set(features.Config{CheckIdentifiersPaused: true})
+	defer features.Reset()
+
+	performValidation := func(name string) error {
+		exp := ra.clk.Now().Add(24 * time.Hour)
+		authzPB, err := bgrpc.AuthzToPB(core.Authorization{
+			ID:             "1337",
+			Identifier:     identifier.DNSIdentifier(name),
+			RegistrationID: 1,
+			Status:         core.StatusPending,
+			Expires:        &exp,
+			Challenges: []core.Challenge{
+				{
+					Token:  core.NewToken(),
+					Type:   core.ChallengeTypeHTTP01,
+					Status: core.StatusPending,
+				},
+			},
+		})
+		test.AssertNotError(t, err, "bgrpc.AuthzToPB failed")
+		_, err = ra.PerformValidation(ctx, &rapb.PerformValidationRequest{
+			Authz:          authzPB,
+			ChallengeIndex: 0,
+		})
+		return err
+	}
+
+	err := performValidation("paused.example.com")
+	test.AssertErrorIs(t, err, berrors.Paused)
+	test.AssertContains(t, err.Error(), "paused.example.com")
+
+	err = performValidation("not-paused.example.com")
+	test.AssertNotError(t, err, "validating an identifier which isn't paused")
+}
+
 func TestPerformValidationSuccess(t *testing.T) {
 	va, sa, ra, fc, cleanUp := initAuthorities(t)
 	defer cleanUp()
@@ -1134,6 +1192,155 @@ func (m mockSAWithNameCounts) CountCertificatesByNames(ctx context.Context, req
 	return &sapb.CountByNames{Counts: counts}, nil
 }
 
+func TestCheckNewOrderLimitsWithLimiter(t *testing.T) {
+	_, _, ra, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+	ra.SA = mocks.NewStorageAuthority(fc)
+
+	// The legacy limit always allows, since the mock SA counts no orders.
+	ra.rlPolicies = &dummyRateLimitConfig{
+		NewOrdersPerAccountPolicy: ratelimit.RateLimitPolicy{
+			Threshold: 1,
+			Window:    config.Duration{Duration: time.Hour},
+		},
+	}
+
+	defaults := filepath.Join(t.TempDir(), "defaults.yml")
+	err := os.WriteFile(defaults, []byte("NewOrdersPerAccount: { burst: 1, count: 1, period: 1h }\n"), 0600)
+	test.AssertNotError(t, err, "writing defaults")
+	limiter, err := ratelimits.NewLimiter(fc, ratelimits.NewInmemSource(), metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "making limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(defaults, "", "", "")
+	test.AssertNotError(t, err, "making transaction builder")
+	ra.limiter = limiter
+	ra.txnBuilder = txnBuilder
+	ra.comparator = ratelimits.NewComparator(limiter, metrics.NoopRegisterer, blog.NewMock())
+
+	// In shadow mode, the Limiter is spent from, but the legacy decision is
+	// enforced.
+	names := []string{"example.com"}
+	for i := 0; i < 2; i++ {
+		_, err = ra.checkNewOrderLimits(ctx, names, Registration.Id)
+		test.AssertNotError(t, err, "shadow mode should enforce the legacy decision")
+	}
+	txn, err := txnBuilder.OrdersPerAccountTransaction(Registration.Id)
+	test.AssertNotError(t, err, "building transaction")
+	d, err := limiter.Check(ctx, txn)
+	test.AssertNotError(t, err, "checking")
+	test.Assert(t, !d.Allowed, "shadow mode should have spent from the Limiter")
+
+	// With UseKvLimitsForNewOrder, only the Limiter's decision is enforced.
+	features.Set(features.Config{UseKvLimitsForNewOrder: true})
+	defer features.Reset()
+	_, err = ra.checkNewOrderLimits(ctx, names, Registration.Id)
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+
+	fc.Add(time.Hour)
+	_, err = ra.checkNewOrderLimits(ctx, names, Registration.Id)
+	test.AssertNotError(t, err, "the Limiter should have refilled")
+	_, err = ra.checkNewOrderLimits(ctx, names, Registration.Id)
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+}
+
+// mockSAFailingNewOrder fails to store every new order.
+type mockSAFailingNewOrder struct {
+	*mocks.StorageAuthority
+}
+
+func (mockSAFailingNewOrder) NewOrderAndAuthzs(_ context.Context, _ *sapb.NewOrderAndAuthzsRequest, _ ...grpc.CallOption) (*corepb.Order, error) {
+	return nil, errors.New("database is down")
+}
+
+func TestNewOrderRefundsLimiterIfNotStored(t *testing.T) {
+	_, _, ra, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+	ra.SA = mockSAFailingNewOrder{mocks.NewStorageAuthority(fc)}
+	ra.rlPolicies = &dummyRateLimitConfig{}
+
+	defaults := filepath.Join(t.TempDir(), "defaults.yml")
+	err := os.WriteFile(defaults, []byte(`
+NewOrdersPerAccount: { burst: 2, count: 2, period: 1h }
+CertificatesPerDomain: { burst: 2, count: 2, period: 1h }
+`), 0600)
+	test.AssertNotError(t, err, "writing defaults")
+	limiter, err := ratelimits.NewLimiter(fc, ratelimits.NewInmemSource(), metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "making limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(defaults, "", "", "")
+	test.AssertNotError(t, err, "making transaction builder")
+	ra.limiter = limiter
+	ra.txnBuilder = txnBuilder
+	features.Set(features.Config{UseKvLimitsForNewOrder: true})
+	defer features.Reset()
+
+	names := []string{"zombo.com"}
+	_, err = ra.NewOrder(ctx, &rapb.NewOrderRequest{RegistrationID: Registration.Id, Names: names})
+	test.AssertError(t, err, "storing the order should fail")
+
+	// Everything spent for the order which wasn't stored has been refunded.
+	orderTxn, err := txnBuilder.OrdersPerAccountTransaction(Registration.Id)
+	test.AssertNotError(t, err, "building transaction")
+	domainTxns, err := txnBuilder.CertificatesPerDomainTransactions(Registration.Id, names)
+	test.AssertNotError(t, err, "building transactions")
+	decisions, err := limiter.BatchCheck(ctx, append(domainTxns, orderTxn))
+	test.AssertNotError(t, err, "checking")
+	for _, d := range decisions {
+		test.AssertEquals(t, d.Remaining, int64(1))
+	}
+}
+
+// mockSAWithFQDNSetTimestamps returns the same timestamps for every FQDN set.
+type mockSAWithFQDNSetTimestamps struct {
+	*mocks.StorageAuthority
+	timestamps *sapb.Timestamps
+}
+
+func (m mockSAWithFQDNSetTimestamps) FQDNSetTimestampsForWindow(_ context.Context, _ *sapb.CountFQDNSetsRequest, _ ...grpc.CallOption) (*sapb.Timestamps, error) {
+	return m.timestamps, nil
+}
+
+func TestCheckNewOrderLimitsShadowOnlySpendsAllowedOrders(t *testing.T) {
+	_, _, ra, fc, cleanUp := initAuthorities(t)
+	defer cleanUp()
+
+	// The legacy NewOrdersPerAccount limit always allows, since the mock SA
+	// counts no orders, but the legacy CertificatesPerFQDNSet limit denies.
+	ra.SA = mockSAWithFQDNSetTimestamps{
+		StorageAuthority: mocks.NewStorageAuthority(fc),
+		timestamps:       &sapb.Timestamps{Timestamps: []*timestamppb.Timestamp{timestamppb.New(fc.Now())}},
+	}
+	ra.rlPolicies = &dummyRateLimitConfig{
+		NewOrdersPerAccountPolicy: ratelimit.RateLimitPolicy{
+			Threshold: 1,
+			Window:    config.Duration{Duration: time.Hour},
+		},
+		CertificatesPerFQDNSetPolicy: ratelimit.RateLimitPolicy{
+			Threshold: 1,
+			Window:    config.Duration{Duration: time.Hour},
+		},
+	}
+
+	defaults := filepath.Join(t.TempDir(), "defaults.yml")
+	err := os.WriteFile(defaults, []byte("NewOrdersPerAccount: { burst: 1, count: 1, period: 1h }\n"), 0600)
+	test.AssertNotError(t, err, "writing defaults")
+	limiter, err := ratelimits.NewLimiter(fc, ratelimits.NewInmemSource(), metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "making limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(defaults, "", "", "")
+	test.AssertNotError(t, err, "making transaction builder")
+	ra.limiter = limiter
+	ra.txnBuilder = txnBuilder
+	ra.comparator = ratelimits.NewComparator(limiter, metrics.NoopRegisterer, blog.NewMock())
+
+	// The order is denied by a later legacy limit, so nothing is spent from
+	// the Limiter for NewOrdersPerAccount.
+	_, err = ra.checkNewOrderLimits(ctx, []string{"example.com"}, Registration.Id)
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+	txn, err := txnBuilder.OrdersPerAccountTransaction(Registration.Id)
+	test.AssertNotError(t, err, "building transaction")
+	d, err := limiter.Check(ctx, txn)
+	test.AssertNotError(t, err, "checking")
+	test.Assert(t, d.Allowed, "an order denied by a legacy limit shouldn't be spent from the Limiter")
+}
+
 func TestCheckCertificatesPerNameLimit(t *testing.T) {
 	_, _, ra, fc, cleanUp := initAuthorities(t)
 	defer cleanUp()
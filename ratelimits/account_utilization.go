@@ -0,0 +1,107 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accountUtilizationBatchSize is the approximate number of buckets read from
+// the source by each batch of ScanAccountUtilization.
+const accountUtilizationBatchSize = 500
+
+// accountLimits are the limits whose bucket keys begin with the registration
+// ID of an ACME account.
+var accountLimits = []Name{
+	NewOrdersPerAccount,
+	FailedAuthorizationsPerAccount,
+	CertificatesPerDomainPerAccount,
+	RevocationsPerAccount,
+	AccountUpdatesPerAccount,
+}
+
+// AccountUtilization is the utilization of a bucket of a limit keyed by ACME
+// account, as of the time it was scanned.
+type AccountUtilization struct {
+	// Limit is the name of the limit.
+	Limit Name
+
+	// RegID is the registration ID of the account.
+	RegID int64
+
+	// BucketKey is the key of the bucket. For CertificatesPerDomainPerAccount
+	// it includes the domain name, so an account may have several buckets of
+	// the same limit.
+	BucketKey string
+
+	// Override is true if the bucket is governed by an override, false if by
+	// the default limit.
+	Override bool
+	Burst    int64
+
+	// Utilization is the proportion of the burst which was used, between 0
+	// and 1.
+	Utilization float64
+}
+
+// ScanAccountUtilization calls fn with the utilization of each stored bucket,
+// of each limit keyed by ACME account, whose utilization of its effective
+// limit, the matching override or otherwise the default, is at least
+// threshold, a proportion between 0 and 1. Buckets of disabled limits are
+// skipped. Since the utilization of a bucket falls as it refills, only
+// buckets which are highly utilized at the time they're scanned are found;
+// callers interested in peaks should scan repeatedly.
+func (l *Limiter) ScanAccountUtilization(ctx context.Context, builder *TransactionBuilder, threshold float64, fn func(AccountUtilization)) error {
+	if threshold <= 0 || threshold > 1 {
+		return fmt.Errorf("invalid threshold %f, must be > 0 and <= 1", threshold)
+	}
+	if l.scanner == nil {
+		return errors.New("source does not support scanning buckets")
+	}
+
+	for _, name := range accountLimits {
+		prefix := joinWithColon(name.EnumString(), "")
+		err := l.scanner.scanBuckets(ctx, prefix, accountUtilizationBatchSize, 0, func(buckets map[string]time.Time) error {
+			for bucketKey, tat := range buckets {
+				// The registration ID is the first component of every id of
+				// these limits.
+				regIdStr, _, _ := strings.Cut(strings.TrimPrefix(bucketKey, prefix), ":")
+				regId, err := strconv.ParseInt(regIdStr, 10, 64)
+				if err != nil {
+					// Not a bucket of this limit.
+					continue
+				}
+				overrideKey := joinWithColon(name.EnumString(), regIdStr)
+				rl, err := builder.getLimit(name, overrideKey)
+				if err != nil {
+					if errors.Is(err, errLimitDisabled) {
+						continue
+					}
+					return err
+				}
+
+				d := maybeSpend(l.clk, rl, tat, 0)
+				utilization := float64(rl.Burst-d.Remaining) / float64(rl.Burst)
+				if utilization < threshold {
+					continue
+				}
+				fn(AccountUtilization{
+					Limit:       name,
+					RegID:       regId,
+					BucketKey:   bucketKey,
+					Override:    rl.isOverride,
+					Burst:       rl.Burst,
+					Utilization: utilization,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("scanning buckets of %s: %w", name, err)
+		}
+	}
+	return nil
+}
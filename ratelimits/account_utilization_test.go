@@ -0,0 +1,72 @@
+package ratelimits
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestScanAccountUtilization(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder, err := NewTransactionBuilder("testdata/working_defaults_new_order.yml", "", "testdata/working_override_new_order.yml", "")
+	test.AssertNotError(t, err, "should not error")
+	ctx := context.Background()
+
+	spend := func(regId int64, cost int64) {
+		t.Helper()
+		txn, err := txnBuilder.OrdersPerAccountTransaction(regId)
+		test.AssertNotError(t, err, "txn should be valid")
+		txn, err = newTransaction(txn.limit, txn.bucketKey, cost)
+		test.AssertNotError(t, err, "txn should be valid")
+		_, err = l.Spend(ctx, txn)
+		test.AssertNotError(t, err, "should not error")
+	}
+	scan := func(threshold float64) map[int64]AccountUtilization {
+		t.Helper()
+		found := make(map[int64]AccountUtilization)
+		err := l.ScanAccountUtilization(ctx, txnBuilder, threshold, func(u AccountUtilization) {
+			found[u.RegID] = u
+		})
+		test.AssertNotError(t, err, "should not error")
+		return found
+	}
+
+	// Account 1 uses 18 of its default burst of 20, and account 13371338 the
+	// same 18 of its overridden burst of 40.
+	spend(1, 18)
+	spend(13371338, 18)
+
+	// Buckets of limits not keyed by account are ignored.
+	ipTxn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "txn should be valid")
+	_, err = l.Spend(ctx, ipTxn)
+	test.AssertNotError(t, err, "should not error")
+
+	found := scan(0.8)
+	test.AssertEquals(t, len(found), 1)
+	test.AssertEquals(t, found[1].Limit, NewOrdersPerAccount)
+	test.AssertEquals(t, found[1].BucketKey, joinWithColon(NewOrdersPerAccount.EnumString(), "1"))
+	test.Assert(t, !found[1].Override, "default limit should apply")
+	test.AssertEquals(t, found[1].Burst, int64(20))
+	test.AssertEquals(t, found[1].Utilization, 0.9)
+
+	found = scan(0.4)
+	test.AssertEquals(t, len(found), 2)
+	test.Assert(t, found[13371338].Override, "override should apply")
+	test.AssertEquals(t, found[13371338].Burst, int64(40))
+	test.AssertEquals(t, found[13371338].Utilization, 0.45)
+
+	// Utilization falls as buckets refill.
+	clk.Add(time.Second)
+	test.AssertEquals(t, len(scan(0.1)), 0)
+
+	err = l.ScanAccountUtilization(ctx, txnBuilder, 0, func(AccountUtilization) {})
+	test.AssertError(t, err, "threshold of 0 should be rejected")
+}
@@ -0,0 +1,73 @@
+package ratelimits
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// bucketAdminPath is the path at which the handler returned by NewAdminHandler
+// serves buckets.
+const bucketAdminPath = "/ratelimits/bucket"
+
+// bucketReset is the JSON response to a successful reset.
+type bucketReset struct {
+	Name      string `json:"name"`
+	Id        string `json:"id"`
+	BucketKey string `json:"bucketKey"`
+}
+
+// NewAdminHandler returns an http.Handler for inspecting and resetting
+// buckets, intended to be served to support staff on an admin listener. Every
+// request must present the provided token as 'Authorization: Bearer <token>'.
+// The bucket is identified by the 'name' and 'id' query parameters, formatted
+// as they would be in an overrides file, of requests to /ratelimits/bucket:
+//   - GET responds with the same JSON object as the handler returned by
+//     NewDebugHandler, including the bucket's TAT.
+//   - DELETE resets the bucket to full capacity. Each reset is audit logged.
+func NewAdminHandler(limiter *Limiter, builder *TransactionBuilder, token string, logger blog.Logger) (http.Handler, error) {
+	if token == "" {
+		return nil, errors.New("admin token must not be empty")
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(bucketAdminPath, func(w http.ResponseWriter, r *http.Request) {
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		e, code, err := lookupBucket(r.Context(), limiter, builder, r.URL.Query().Get("name"), r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		var resp any = e
+		if r.Method == http.MethodDelete {
+			err = limiter.Reset(r.Context(), e.BucketKey)
+			if err != nil {
+				logger.AuditErrf("Failed to reset rate limit bucket %q from %s: %s", e.BucketKey, r.RemoteAddr, err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			logger.AuditInfof("Reset rate limit bucket %q for %s %q from %s", e.BucketKey, e.Name, e.Id, r.RemoteAddr)
+			resp = bucketReset{Name: e.Name, Id: e.Id, BucketKey: e.BucketKey}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux, nil
+}
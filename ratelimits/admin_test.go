@@ -0,0 +1,77 @@
+package ratelimits
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestAdminHandler(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	log := blog.NewMock()
+
+	_, err := NewAdminHandler(l, txnBuilder, "", log)
+	test.AssertError(t, err, "empty token should be rejected")
+
+	handler, err := NewAdminHandler(l, txnBuilder, "hunter2", log)
+	test.AssertNotError(t, err, "should not error")
+
+	do := func(method, token, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, bucketAdminPath+"?"+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+		return rw
+	}
+	query := "name=NewRegistrationsPerIPAddress&id=" + tenZeroZeroTwo
+
+	// Missing or incorrect token.
+	test.AssertEquals(t, do(http.MethodGet, "", query).Code, http.StatusUnauthorized)
+	test.AssertEquals(t, do(http.MethodGet, "hunter3", query).Code, http.StatusUnauthorized)
+	test.AssertEquals(t, do(http.MethodPost, "hunter2", query).Code, http.StatusMethodNotAllowed)
+
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(tenZeroZeroTwo))
+	test.AssertNotError(t, err, "should not error")
+	_, err = l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+
+	rw := do(http.MethodGet, "hunter2", query)
+	test.AssertEquals(t, rw.Code, http.StatusOK)
+	var resp BucketState
+	err = json.Unmarshal(rw.Body.Bytes(), &resp)
+	test.AssertNotError(t, err, "unmarshalling response")
+	test.Assert(t, resp.Exists, "bucket should exist")
+	test.AssertEquals(t, resp.Remaining, int64(39))
+	test.Assert(t, resp.TAT != nil, "TAT should be set")
+	test.AssertEquals(t, resp.TAT.UnixNano(), clk.Now().Add(25*time.Millisecond).UnixNano())
+
+	rw = do(http.MethodDelete, "hunter2", query)
+	test.AssertEquals(t, rw.Code, http.StatusOK)
+	test.AssertEquals(t, len(log.GetAllMatching("Reset rate limit bucket")), 1)
+
+	rw = do(http.MethodGet, "hunter2", query)
+	test.AssertEquals(t, rw.Code, http.StatusOK)
+	resp = BucketState{}
+	err = json.Unmarshal(rw.Body.Bytes(), &resp)
+	test.AssertNotError(t, err, "unmarshalling response")
+	test.Assert(t, !resp.Exists, "bucket should not exist")
+	test.AssertEquals(t, resp.Remaining, int64(40))
+
+	// Invalid id.
+	test.AssertEquals(t, do(http.MethodDelete, "hunter2", "name=NewRegistrationsPerIPAddress&id=lol").Code, http.StatusBadRequest)
+}
@@ -0,0 +1,235 @@
+package ratelimits
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/strictyaml"
+)
+
+// ParseArrivals reads request timestamps from r, one RFC 3339 timestamp per
+// line, and returns them sorted in ascending order. Blank lines and lines
+// beginning with '#' are ignored.
+func ParseArrivals(r io.Reader) ([]time.Time, error) {
+	var arrivals []time.Time
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, fmt.Errorf("parsing timestamp on line %d: %w", line, err)
+		}
+		arrivals = append(arrivals, t)
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(arrivals, func(a, b time.Time) int { return a.Compare(b) })
+	return arrivals, nil
+}
+
+// windowCounts returns, for each of the provided arrivals, the number of
+// arrivals which fall within the window of length period which begins at that
+// arrival. Arrivals must be sorted in ascending order.
+func windowCounts(arrivals []time.Time, period time.Duration) []int64 {
+	counts := make([]int64, len(arrivals))
+	end := 0
+	for i, start := range arrivals {
+		for end < len(arrivals) && arrivals[end].Sub(start) < period {
+			end++
+		}
+		counts[i] = int64(end - i)
+	}
+	return counts
+}
+
+// quantile returns the q-th quantile, 0 < q <= 1, of the provided values using
+// the nearest-rank method. It returns 0 if values is empty.
+func quantile(values []int64, q float64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// replay spends a cost of 1 against a single bucket governed by the provided
+// limit for each of the provided arrivals, in order, and returns the number of
+// requests which would have been allowed and denied. Arrivals must be sorted in
+// ascending order.
+func replay(rl limit, arrivals []time.Time) (int64, int64) {
+	var allowed, denied int64
+	if len(arrivals) == 0 {
+		return 0, 0
+	}
+	clk := clock.NewFake()
+	clk.Set(arrivals[0])
+	tat := arrivals[0]
+	for _, arrival := range arrivals {
+		clk.Set(arrival)
+		d := maybeSpend(clk, rl, tat, 1)
+		if d.Allowed {
+			allowed++
+			tat = d.newTAT
+		} else {
+			denied++
+		}
+	}
+	return allowed, denied
+}
+
+// SuggestOverride recommends an override for the limit specified by name and
+// the provided id, based on the historical request arrivals for that id. The
+// suggested count and burst allow the q-th quantile, 0 < q <= 1, of the
+// number of requests observed in any period of the default limit loaded from
+// the defaults file at the provided path, and are never lower than those of the
+// default. The result is a ready-to-merge override stanza, formatted for the
+// overrides file, preceded by a comment describing how the suggested override
+// would have performed against the provided arrivals.
+func SuggestOverride(defaults string, nameStr string, id string, arrivals []time.Time, q float64) (string, error) {
+	if q <= 0 || q > 1 {
+		return "", fmt.Errorf("invalid quantile %f, must be > 0 and <= 1", q)
+	}
+	if len(arrivals) == 0 {
+		return "", fmt.Errorf("no arrivals provided")
+	}
+	name, ok := stringToName[nameStr]
+	if !ok || name == Unknown {
+		return "", fmt.Errorf("unrecognized name %q, must be one of %v", nameStr, limitNames)
+	}
+	err := validateIdForName(name, id)
+	if err != nil {
+		return "", err
+	}
+	registry, err := loadAndParseDefaultLimits(defaults, "")
+	if err != nil {
+		return "", err
+	}
+	dl, ok := registry[name.EnumString()]
+	if !ok {
+		return "", fmt.Errorf("no default limit configured for %s", name)
+	}
+
+	observed := quantile(windowCounts(arrivals, dl.Period.Duration), q)
+	suggested := dl
+	suggested.Count = max(dl.Count, observed)
+	suggested.Burst = max(dl.Burst, observed)
+	suggested = precomputeLimit(suggested)
+
+	_, defaultDenied := replay(dl, arrivals)
+	_, suggestedDenied := replay(suggested, arrivals)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# p%g of %d requests per %s, denied %d/%d with the default, %d/%d with this override\n",
+		q*100, observed, dl.Period.Duration, defaultDenied, len(arrivals), suggestedDenied, len(arrivals))
+	fmt.Fprintf(&b, "- %s:\n", name)
+	fmt.Fprintf(&b, "    burst: %d\n", suggested.Burst)
+	fmt.Fprintf(&b, "    count: %d\n", suggested.Count)
+	fmt.Fprintf(&b, "    period: %s\n", suggested.Period.Duration)
+	fmt.Fprintf(&b, "    ids: [%q]\n", id)
+	return b.String(), nil
+}
+
+// ParseBucketArrivals reads request timestamps for many buckets from r, one
+// request per line, formatted as an id followed by whitespace and an RFC 3339
+// timestamp. It returns the arrivals for each id, sorted in ascending order.
+// Blank lines and lines beginning with '#' are ignored.
+func ParseBucketArrivals(r io.Reader) (map[string][]time.Time, error) {
+	arrivals := make(map[string][]time.Time)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed line %d, must be formatted 'id timestamp'", line)
+		}
+		t, err := time.Parse(time.RFC3339Nano, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("parsing timestamp on line %d: %w", line, err)
+		}
+		arrivals[fields[0]] = append(arrivals[fields[0]], t)
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range arrivals {
+		slices.SortFunc(a, func(a, b time.Time) int { return a.Compare(b) })
+	}
+	return arrivals, nil
+}
+
+// loadCandidates loads a YAML file containing a list of candidate limits, each
+// specifying burst, count, and period, and validates them.
+func loadCandidates(path string) ([]limit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []limit
+	err = strictyaml.Unmarshal(data, &candidates)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range candidates {
+		err = validateLimit(c)
+		if err != nil {
+			return nil, fmt.Errorf("validating candidate %d: %w", i+1, err)
+		}
+		candidates[i] = precomputeLimit(c)
+	}
+	return candidates, nil
+}
+
+// EvaluateCandidates replays the provided per-bucket arrivals against each of
+// the candidate limits in the YAML file at the provided path, as if each were
+// the default limit, and writes a report of the denial rate each candidate
+// would have produced to w. The candidates file must be formatted as a list of
+// maps, each containing the burst, count, and period of a candidate.
+func EvaluateCandidates(path string, arrivals map[string][]time.Time, w io.Writer) error {
+	candidates, err := loadCandidates(path)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("no candidates found in %q", path)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "burst\tcount\tperiod\trequests\tdenied\tdenial rate\tbuckets denied")
+	for _, c := range candidates {
+		var requests, denied, bucketsDenied int64
+		for _, a := range arrivals {
+			_, d := replay(c, a)
+			requests += int64(len(a))
+			denied += d
+			if d > 0 {
+				bucketsDenied++
+			}
+		}
+		var rate float64
+		if requests > 0 {
+			rate = float64(denied) / float64(requests)
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%d\t%d\t%.4f%%\t%d/%d\n",
+			c.Burst, c.Count, c.Period.Duration, requests, denied, rate*100, bucketsDenied, len(arrivals))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,136 @@
+package ratelimits
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/config"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// evenArrivals returns n arrivals, starting at start, spaced interval apart.
+func evenArrivals(start time.Time, n int, interval time.Duration) []time.Time {
+	arrivals := make([]time.Time, n)
+	for i := range arrivals {
+		arrivals[i] = start.Add(time.Duration(i) * interval)
+	}
+	return arrivals
+}
+
+func TestParseArrivals(t *testing.T) {
+	arrivals, err := ParseArrivals(strings.NewReader(`
+# comment
+2024-01-01T00:00:01Z
+2024-01-01T00:00:00.5Z
+
+2024-01-01T00:00:00Z
+`))
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(arrivals), 3)
+	test.Assert(t, arrivals[0].Before(arrivals[1]), "arrivals should be sorted")
+	test.Assert(t, arrivals[1].Before(arrivals[2]), "arrivals should be sorted")
+
+	_, err = ParseArrivals(strings.NewReader("2024-01-01T00:00:00Z\nlol\n"))
+	test.AssertError(t, err, "malformed timestamp")
+	test.AssertContains(t, err.Error(), "line 2")
+}
+
+func TestWindowCountsAndQuantile(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 10 requests, 100ms apart, followed by a single request 10s later.
+	arrivals := append(evenArrivals(start, 10, 100*time.Millisecond), start.Add(10*time.Second))
+	counts := windowCounts(arrivals, time.Second)
+	test.AssertDeepEquals(t, counts, []int64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 1})
+	test.AssertEquals(t, quantile(counts, 1), int64(10))
+	test.AssertEquals(t, quantile(counts, 0.5), int64(5))
+	test.AssertEquals(t, quantile(nil, 0.5), int64(0))
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rl := precomputeLimit(limit{Burst: 5, Count: 5, Period: config.Duration{Duration: time.Second}})
+
+	// A burst of 10 simultaneous requests, only 5 fit.
+	allowed, denied := replay(rl, evenArrivals(start, 10, 0))
+	test.AssertEquals(t, allowed, int64(5))
+	test.AssertEquals(t, denied, int64(5))
+
+	// 10 requests at the steady-state rate are all allowed.
+	allowed, denied = replay(rl, evenArrivals(start, 10, 200*time.Millisecond))
+	test.AssertEquals(t, allowed, int64(10))
+	test.AssertEquals(t, denied, int64(0))
+}
+
+func TestSuggestOverride(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// The default for NewRegistrationsPerIPAddress allows 20 per second, this
+	// is 40 requests in one second.
+	arrivals := evenArrivals(start, 40, 25*time.Millisecond)
+
+	stanza, err := SuggestOverride("testdata/working_default.yml", "NewRegistrationsPerIPAddress", "10.0.0.1", arrivals, 1)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertContains(t, stanza, "- NewRegistrationsPerIPAddress:\n    burst: 40\n    count: 40\n    period: 1s\n    ids: [\"10.0.0.1\"]\n")
+	test.AssertContains(t, stanza, "0/40 with this override")
+
+	// The suggestion must be loadable as an overrides file.
+	path := t.TempDir() + "/overrides.yml"
+	err = os.WriteFile(path, []byte(stanza), 0600)
+	test.AssertNotError(t, err, "writing overrides file")
+	_, err = loadAndParseOverrideLimits(path)
+	test.AssertNotError(t, err, "suggested override should be valid")
+
+	// Suggestions are never lower than the default.
+	stanza, err = SuggestOverride("testdata/working_default.yml", "NewRegistrationsPerIPAddress", "10.0.0.1", arrivals[:2], 1)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertContains(t, stanza, "burst: 20\n    count: 20\n")
+
+	_, err = SuggestOverride("testdata/working_default.yml", "NewRegistrationsPerIPAddress", "lol", arrivals, 1)
+	test.AssertError(t, err, "invalid id")
+	_, err = SuggestOverride("testdata/working_default.yml", "NewOrdersPerAccount", "1234", arrivals, 1)
+	test.AssertError(t, err, "no default configured")
+	_, err = SuggestOverride("testdata/working_default.yml", "NewRegistrationsPerIPAddress", "10.0.0.1", arrivals, 0)
+	test.AssertError(t, err, "invalid quantile")
+	_, err = SuggestOverride("testdata/working_default.yml", "NewRegistrationsPerIPAddress", "10.0.0.1", nil, 1)
+	test.AssertError(t, err, "no arrivals")
+}
+
+func TestParseBucketArrivals(t *testing.T) {
+	arrivals, err := ParseBucketArrivals(strings.NewReader(`
+# comment
+10.0.0.1 2024-01-01T00:00:01Z
+10.0.0.2 2024-01-01T00:00:00Z
+10.0.0.1 2024-01-01T00:00:00Z
+`))
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(arrivals), 2)
+	test.AssertEquals(t, len(arrivals["10.0.0.1"]), 2)
+	test.Assert(t, arrivals["10.0.0.1"][0].Before(arrivals["10.0.0.1"][1]), "arrivals should be sorted")
+
+	_, err = ParseBucketArrivals(strings.NewReader("2024-01-01T00:00:00Z\n"))
+	test.AssertError(t, err, "missing id")
+}
+
+func TestEvaluateCandidates(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	arrivals := map[string][]time.Time{
+		// 10 simultaneous requests.
+		"10.0.0.1": evenArrivals(start, 10, 0),
+		// 2 simultaneous requests.
+		"10.0.0.2": evenArrivals(start, 2, 0),
+	}
+
+	var out strings.Builder
+	err := EvaluateCandidates("testdata/working_candidates.yml", arrivals, &out)
+	test.AssertNotError(t, err, "should not error")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	test.AssertEquals(t, len(lines), 3)
+	// 5 of 12 requests are denied, all from a single bucket.
+	test.AssertEquals(t, strings.Join(strings.Fields(lines[1]), " "), "5 5 1s 12 5 41.6667% 1/2")
+	// Nothing is denied.
+	test.AssertEquals(t, strings.Join(strings.Fields(lines[2]), " "), "10 10 1s 12 0 0.0000% 0/2")
+
+	err = EvaluateCandidates("testdata/busted_default_burst_0.yml", arrivals, &out)
+	test.AssertError(t, err, "malformed candidates file")
+}
@@ -0,0 +1,38 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Backfill records, in the bucket of txn, at least used requests against its
+// limit, e.g. as counted by a legacy SQL-based rate limit over the limit's
+// period. The requests are treated as though they were all made just now,
+// which can only overstate their effect. A bucket which already reflects at
+// least as much usage, e.g. because it was spent from in shadow mode, is left
+// unchanged, so backfilling the same counts more than once is harmless. It
+// returns true if the bucket was changed. Usage beyond the limit's burst is
+// not recorded, and nothing is recorded for allow-only Transactions.
+func (l *Limiter) Backfill(ctx context.Context, txn Transaction, used int64) (bool, error) {
+	if txn.allowOnly() || used <= 0 {
+		return false, nil
+	}
+	if used > txn.limit.Burst {
+		used = txn.limit.Burst
+	}
+	want := l.clk.Now().Add(time.Duration(txn.limit.emissionInterval * used))
+
+	tat, err := l.source.Get(ctx, txn.bucketKey)
+	if err != nil && !errors.Is(err, ErrBucketNotFound) {
+		return false, err
+	}
+	if !tat.Before(want) {
+		return false, nil
+	}
+	err = l.source.BatchSet(ctx, map[string]time.Time{txn.bucketKey: want})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,48 @@
+package ratelimits
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestLimiter_Backfill(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	ctx := context.Background()
+
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.4.1"))
+	test.AssertNotError(t, err, "txn should be valid")
+
+	// A missing bucket is created reflecting the backfilled usage.
+	changed, err := l.Backfill(ctx, txn, 5)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, changed, "bucket should have changed")
+	d, err := l.Check(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(14))
+
+	// Backfilling the same or less usage changes nothing.
+	changed, err = l.Backfill(ctx, txn, 5)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !changed, "bucket shouldn't have changed")
+	changed, err = l.Backfill(ctx, txn, 0)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !changed, "bucket shouldn't have changed")
+
+	// Usage beyond the burst exhausts the bucket.
+	changed, err = l.Backfill(ctx, txn, 100)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, changed, "bucket should have changed")
+	d, err = l.Check(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+	test.AssertEquals(t, d.ResetIn, time.Second)
+}
@@ -0,0 +1,160 @@
+package ratelimits
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxPooledBatchSize is the largest batch whose buffers are returned to
+// batchBuffersPool. Buffers grown by unusually large batches are left for the
+// garbage collector, so that a single large batch doesn't pin its memory for
+// the lifetime of the process.
+const maxPooledBatchSize = 1024
+
+var batchBuffersPool = sync.Pool{
+	New: func() any {
+		return &batchBuffers{
+			seen:    make(map[string]struct{}),
+			newTATs: make(map[string]time.Time),
+		}
+	},
+}
+
+// batchBuffers holds the scratch space used to evaluate a single batch of
+// Transactions. They are pooled so that the batch paths, which run for every
+// new-order request, don't allocate them anew on every call. Call
+// getBatchBuffers to obtain one and release to return it to the pool; nothing
+// held by a batchBuffers may be used after it is released.
+type batchBuffers struct {
+	// txns and bucketKeys are the Transactions of the batch which are not
+	// allow-only, and their bucket keys, in order.
+	txns       []Transaction
+	bucketKeys []string
+
+	// seen is the set of bucketKeys, used to detect duplicate buckets.
+	seen map[string]struct{}
+
+	// decisions and results are indexed like txns.
+	decisions []*Decision
+	results   []string
+
+	// newTATs are the bucket states to persist and created are the
+	// Transactions whose buckets they create.
+	newTATs map[string]time.Time
+	created []Transaction
+}
+
+func getBatchBuffers() *batchBuffers {
+	return batchBuffersPool.Get().(*batchBuffers)
+}
+
+// release clears b and returns it to the pool.
+func (b *batchBuffers) release() {
+	if cap(b.txns) > maxPooledBatchSize {
+		return
+	}
+	// Zero the slices before truncating them, so that pooled buffers don't
+	// keep Transactions and Decisions reachable.
+	clear(b.txns)
+	clear(b.bucketKeys)
+	clear(b.decisions)
+	clear(b.results)
+	clear(b.created)
+	b.txns = b.txns[:0]
+	b.bucketKeys = b.bucketKeys[:0]
+	b.decisions = b.decisions[:0]
+	b.results = b.results[:0]
+	b.created = b.created[:0]
+	clear(b.seen)
+	clear(b.newTATs)
+	batchBuffersPool.Put(b)
+}
+
+// prepare fills b.txns and b.bucketKeys with the provided Transactions which
+// are not allow-only. It returns an error if two of them share a bucket.
+func (b *batchBuffers) prepare(txns []Transaction) error {
+	b.txns = slices.Grow(b.txns, len(txns))
+	b.bucketKeys = slices.Grow(b.bucketKeys, len(txns))
+	for _, txn := range txns {
+		if txn.allowOnly() {
+			// Ignore allow-only transactions.
+			continue
+		}
+		_, ok := b.seen[txn.bucketKey]
+		if ok {
+			return fmt.Errorf("found duplicate bucket %q in batch", txn.bucketKey)
+		}
+		b.seen[txn.bucketKey] = struct{}{}
+		b.bucketKeys = append(b.bucketKeys, txn.bucketKey)
+		b.txns = append(b.txns, txn)
+	}
+	return nil
+}
+
+// counterCache memoizes the children of a *prometheus.CounterVec by key, so
+// that counting on the hot path doesn't allocate and hash label values for
+// every Transaction. It is safe for concurrent use.
+type counterCache[K comparable] struct {
+	vec *prometheus.CounterVec
+
+	// labelValues returns the label values of the child for a key. It is only
+	// called the first time each key is seen.
+	labelValues func(K) []string
+
+	sync.RWMutex
+	children map[K]prometheus.Counter
+}
+
+func newCounterCache[K comparable](vec *prometheus.CounterVec, labelValues func(K) []string) *counterCache[K] {
+	return &counterCache[K]{
+		vec:         vec,
+		labelValues: labelValues,
+		children:    make(map[K]prometheus.Counter),
+	}
+}
+
+// get returns the child of the CounterVec for the provided key.
+func (c *counterCache[K]) get(k K) prometheus.Counter {
+	c.RLock()
+	counter, ok := c.children[k]
+	c.RUnlock()
+	if ok {
+		return counter
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	counter, ok = c.children[k]
+	if !ok {
+		counter = c.vec.WithLabelValues(c.labelValues(k)...)
+		c.children[k] = counter
+	}
+	return counter
+}
+
+// decisionLabels are the labels of the decisions metric.
+type decisionLabels struct {
+	name     Name
+	decision string
+	reason   string
+}
+
+// resultLabels are the labels of metrics labeled by limit name and a result,
+// e.g. the refunds and bucket churn metrics.
+type resultLabels struct {
+	name   Name
+	result string
+}
+
+func (k resultLabels) values() []string {
+	return []string{k.name.String(), k.result}
+}
+
+// labelValues returns the label values of metrics labeled only by limit name.
+func (n Name) labelValues() []string {
+	return []string{n.String()}
+}
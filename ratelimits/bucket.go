@@ -16,9 +16,15 @@ var ErrInvalidCost = fmt.Errorf("invalid cost, must be >= 0")
 // ErrInvalidCostOverLimit indicates that the cost specified was > limit.Burst.
 var ErrInvalidCostOverLimit = fmt.Errorf("invalid cost, must be <= limit.Burst")
 
-// newIPAddressBucketKey validates and returns a bucketKey for limits that use
-// the 'enum:ipAddress' bucket key format.
-func newIPAddressBucketKey(name Name, ip net.IP) (string, error) { //nolint: unparam
+// The BucketFor* functions validate their inputs and return the bucket key,
+// formatted 'enum:id', of the bucket for the provided limit. Callers which need
+// a bucket key, e.g. to Reset a bucket, should use them rather than formatting
+// keys by hand. An error is returned if the inputs are invalid or if the limit
+// does not use the bucket key format of the function.
+
+// BucketForIP returns the bucket key for limits which use the 'enum:ipAddress'
+// bucket key format, e.g. NewRegistrationsPerIPAddress.
+func BucketForIP(name Name, ip net.IP) (string, error) {
 	id := ip.String()
 	err := validateIdForName(name, id)
 	if err != nil {
@@ -27,15 +33,14 @@ func newIPAddressBucketKey(name Name, ip net.IP) (string, error) { //nolint: unp
 	return joinWithColon(name.EnumString(), id), nil
 }
 
-// newIPv6RangeCIDRBucketKey validates and returns a bucketKey for limits that
-// use the 'enum:ipv6RangeCIDR' bucket key format.
-func newIPv6RangeCIDRBucketKey(name Name, ip net.IP) (string, error) {
+// BucketForIPv6Range returns the bucket key for limits which use the
+// 'enum:ipv6RangeCIDR' bucket key format, e.g. NewRegistrationsPerIPv6Range,
+// for the /48 range which contains the provided IPv6 address.
+func BucketForIPv6Range(name Name, ip net.IP) (string, error) {
 	if ip.To4() != nil {
 		return "", fmt.Errorf("invalid IPv6 address, %q must be an IPv6 address", ip.String())
 	}
-	ipMask := net.CIDRMask(48, 128)
-	ipNet := &net.IPNet{IP: ip.Mask(ipMask), Mask: ipMask}
-	id := ipNet.String()
+	id := ipv6Range(ip)
 	err := validateIdForName(name, id)
 	if err != nil {
 		return "", err
@@ -43,9 +48,9 @@ func newIPv6RangeCIDRBucketKey(name Name, ip net.IP) (string, error) {
 	return joinWithColon(name.EnumString(), id), nil
 }
 
-// newRegIdBucketKey validates ands returns a bucketKey for limits that use the
-// 'enum:regId' bucket key format.
-func newRegIdBucketKey(name Name, regId int64) (string, error) {
+// BucketForRegistration returns the bucket key for limits which use the
+// 'enum:regId' bucket key format, e.g. NewOrdersPerAccount.
+func BucketForRegistration(name Name, regId int64) (string, error) {
 	id := strconv.FormatInt(regId, 10)
 	err := validateIdForName(name, id)
 	if err != nil {
@@ -54,38 +59,53 @@ func newRegIdBucketKey(name Name, regId int64) (string, error) {
 	return joinWithColon(name.EnumString(), id), nil
 }
 
-// newDomainBucketKey validates and returns a bucketKey for limits that use the
-// 'enum:domain' bucket key format.
-func newDomainBucketKey(name Name, orderName string) (string, error) {
-	err := validateIdForName(name, orderName)
+// BucketForDomain returns the bucket key for limits which use the
+// 'enum:domain' bucket key format, e.g. CertificatesPerDomain.
+func BucketForDomain(name Name, domain string) (string, error) {
+	id := normalizeIdForName(name, domain)
+	err := validateIdForName(name, id)
 	if err != nil {
 		return "", err
 	}
-	return joinWithColon(name.EnumString(), orderName), nil
+	return joinWithColon(name.EnumString(), id), nil
 }
 
-// newRegIdDomainBucketKey validates and returns a bucketKey for limits that use
-// the 'enum:regId:domain' bucket key format.
-func newRegIdDomainBucketKey(name Name, regId int64, orderName string) (string, error) {
-	regIdStr := strconv.FormatInt(regId, 10)
-	err := validateIdForName(name, joinWithColon(regIdStr, orderName))
+// BucketForRegistrationDomain returns the bucket key for limits which use the
+// 'enum:regId:domain' bucket key format, e.g. CertificatesPerDomainPerAccount.
+func BucketForRegistrationDomain(name Name, regId int64, domain string) (string, error) {
+	id := normalizeIdForName(name, joinWithColon(strconv.FormatInt(regId, 10), domain))
+	err := validateIdForName(name, id)
 	if err != nil {
 		return "", err
 	}
-	return joinWithColon(name.EnumString(), regIdStr, orderName), nil
+	return joinWithColon(name.EnumString(), id), nil
 }
 
-// newFQDNSetBucketKey validates and returns a bucketKey for limits that use the
-// 'enum:fqdnSet' bucket key format.
-func newFQDNSetBucketKey(name Name, orderNames []string) (string, error) { //nolint: unparam
-	err := validateIdForName(name, strings.Join(orderNames, ","))
+// BucketForFQDNSet returns the bucket key for limits which use the
+// 'enum:fqdnSet' bucket key format, e.g. CertificatesPerFQDNSet, for the set of
+// provided domain names.
+func BucketForFQDNSet(name Name, domains []string) (string, error) {
+	fqdnSet := normalizeIdForName(name, strings.Join(domains, ","))
+	err := validateIdForName(name, fqdnSet)
 	if err != nil {
 		return "", err
 	}
-	id := fmt.Sprintf("%x", core.HashNames(orderNames))
+	id := fmt.Sprintf("%x", core.HashNames(strings.Split(fqdnSet, ",")))
 	return joinWithColon(name.EnumString(), id), nil
 }
 
+// BucketPrefix returns the prefix shared by the bucket keys of every bucket of
+// the provided limit whose id begins with idPrefix, for use with
+// Limiter.ResetPrefix, which only matches whole segments of it, and
+// Limiter.ExportBuckets. If idPrefix is empty, every bucket of the limit shares
+// the returned prefix.
+func BucketPrefix(name Name, idPrefix string) (string, error) {
+	if !name.isValid() {
+		return "", fmt.Errorf("invalid limit name %d", name)
+	}
+	return joinWithColon(name.EnumString(), idPrefix), nil
+}
+
 // Transaction represents a single rate limit operation. It includes a
 // bucketKey, which combines the specific rate limit enum with a unique
 // identifier to form the key where the state of the "bucket" can be referenced
@@ -107,6 +127,41 @@ type Transaction struct {
 	cost      int64
 	check     bool
 	spend     bool
+
+	// exempt is true if the requester is exempt from the limit. Exempt
+	// Transactions are always allow-only.
+	exempt bool
+
+	// renewal is true if the Transaction was returned by AsRenewal and its
+	// limit exempts renewals. Such Transactions are always allow-only.
+	renewal bool
+
+	// remoteRenewal is true if the Transaction was built by a remote
+	// TransactionBuilder and returned by AsRenewal. The renewalCost of its
+	// limit, if any, is charged by the Server it's sent to.
+	remoteRenewal bool
+
+	// regId is the ACME registration Id of the requester, for the
+	// CertificatesPerDomain Transactions of a remote TransactionBuilder, whose
+	// bucket keys don't include it. The Server uses it to apply exemptions and
+	// CertificatesPerDomainPerAccount overrides.
+	regId int64
+}
+
+// Name returns the name of the limit of the Transaction.
+func (txn Transaction) Name() Name {
+	return txn.limit.name
+}
+
+// BucketKey returns the key of the bucket of the Transaction, formatted as
+// 'enum:id'. It is empty for allow-only Transactions of disabled limits.
+func (txn Transaction) BucketKey() string {
+	return txn.bucketKey
+}
+
+// Cost returns the cost of the Transaction.
+func (txn Transaction) Cost() int64 {
+	return txn.cost
 }
 
 func (txn Transaction) checkOnly() bool {
@@ -159,9 +214,48 @@ func newSpendOnlyTransaction(limit limit, bucketKey string, cost int64) (Transac
 	})
 }
 
-func newAllowOnlyTransaction() (Transaction, error) {
-	// Zero values are sufficient.
-	return validateTransaction(Transaction{})
+// newAllowOnlyTransaction returns an allow-only Transaction for the disabled
+// limit specified by name.
+func newAllowOnlyTransaction(name Name) (Transaction, error) {
+	return validateTransaction(Transaction{limit: limit{name: name}})
+}
+
+// newExemptTransaction returns an allow-only Transaction for a requester who is
+// exempt from the limit specified by name. Unlike other allow-only
+// Transactions, the Limiter counts each exempt Transaction it processes.
+func newExemptTransaction(name Name, bucketKey string) (Transaction, error) {
+	return validateTransaction(Transaction{
+		bucketKey: bucketKey,
+		limit:     limit{name: name},
+		exempt:    true,
+	})
+}
+
+// AsRenewal returns the Transaction for a request which renews an existing
+// certificate, e.g. one identified by an ARI 'replaces' field or with the same
+// FQDN set as the new order. If the limit of the Transaction configures a
+// renewalCost, the returned Transaction is charged that cost in place of its
+// own, or, if the renewalCost is 0, is allow-only. Otherwise, the Transaction is
+// returned unchanged.
+func (txn Transaction) AsRenewal() Transaction {
+	if txn.limit.remote {
+		txn.remoteRenewal = true
+		return txn
+	}
+	if txn.allowOnly() || txn.limit.RenewalCost == nil {
+		return txn
+	}
+	if *txn.limit.RenewalCost == 0 {
+		return Transaction{
+			bucketKey: txn.bucketKey,
+			limit:     limit{name: nameForBucketKey(txn.bucketKey)},
+			renewal:   true,
+		}
+	}
+	// The renewalCost was validated against the burst when the limit was
+	// loaded.
+	txn.cost = *txn.limit.RenewalCost
+	return txn
 }
 
 // TransactionBuilder is used to build Transactions for various rate limits.
@@ -171,29 +265,52 @@ type TransactionBuilder struct {
 	*limitRegistry
 }
 
+// NewRemoteTransactionBuilder returns a new *TransactionBuilder which loads no
+// limits, for callers which make every request of a Server, in ratelimitd,
+// using a Client. The Transactions it builds identify only a limit, a bucket,
+// and a cost: which limits are disabled or overridden, and which requesters
+// are exempt, is decided by the Server's own TransactionBuilder, so that they
+// are configured only in ratelimitd. They must not be passed to a Limiter.
+func NewRemoteTransactionBuilder() *TransactionBuilder {
+	return &TransactionBuilder{&limitRegistry{remote: true}}
+}
+
 // NewTransactionBuilder returns a new *TransactionBuilder. The provided
-// defaults and overrides paths are expected to be paths to YAML files that
-// contain the default and override limits, respectively. Overrides is optional,
+// defaults, overrides, and exemptions paths are expected to be paths to YAML
+// files that contain the default limits, override limits, and ACME registration
+// Ids exempt from specific limits, respectively. The provided environment
+// selects the section of the defaults file, if any, which applies in addition
+// to its top-level limits. Environment, overrides, and exemptions are optional,
 // defaults is required.
-func NewTransactionBuilder(defaults, overrides string) (*TransactionBuilder, error) {
-	registry, err := newLimitRegistry(defaults, overrides)
+func NewTransactionBuilder(defaults, environment, overrides, exemptions string) (*TransactionBuilder, error) {
+	registry, err := newLimitRegistry(defaults, environment, overrides, exemptions)
 	if err != nil {
 		return nil, err
 	}
 	return &TransactionBuilder{registry}, nil
 }
 
+// Reload reloads the default limits, overrides, and exemptions from the paths
+// provided to NewTransactionBuilder. The reloaded limits are swapped in
+// atomically, so it is safe to call Reload while Transactions are being built.
+// Transactions already built keep the limits they were built with. If the
+// reloaded limits are invalid, an error is returned and the limits in use are
+// left unchanged.
+func (builder *TransactionBuilder) Reload() error {
+	return builder.reload()
+}
+
 // RegistrationsPerIPAddressTransaction returns a Transaction for the
 // NewRegistrationsPerIPAddress limit for the provided IP address.
 func (builder *TransactionBuilder) RegistrationsPerIPAddressTransaction(ip net.IP) (Transaction, error) {
-	bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, ip)
+	bucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, ip)
 	if err != nil {
 		return Transaction{}, err
 	}
 	limit, err := builder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
 	if err != nil {
 		if errors.Is(err, errLimitDisabled) {
-			return newAllowOnlyTransaction()
+			return newAllowOnlyTransaction(NewRegistrationsPerIPAddress)
 		}
 		return Transaction{}, err
 	}
@@ -204,14 +321,14 @@ func (builder *TransactionBuilder) RegistrationsPerIPAddressTransaction(ip net.I
 // NewRegistrationsPerIPv6Range limit for the /48 IPv6 range which contains the
 // provided IPv6 address.
 func (builder *TransactionBuilder) RegistrationsPerIPv6RangeTransaction(ip net.IP) (Transaction, error) {
-	bucketKey, err := newIPv6RangeCIDRBucketKey(NewRegistrationsPerIPv6Range, ip)
+	bucketKey, err := BucketForIPv6Range(NewRegistrationsPerIPv6Range, ip)
 	if err != nil {
 		return Transaction{}, err
 	}
-	limit, err := builder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	limit, err := builder.getLimit(NewRegistrationsPerIPv6Range, bucketKey)
 	if err != nil {
 		if errors.Is(err, errLimitDisabled) {
-			return newAllowOnlyTransaction()
+			return newAllowOnlyTransaction(NewRegistrationsPerIPv6Range)
 		}
 		return Transaction{}, err
 	}
@@ -221,14 +338,17 @@ func (builder *TransactionBuilder) RegistrationsPerIPv6RangeTransaction(ip net.I
 // OrdersPerAccountTransaction returns a Transaction for the NewOrdersPerAccount
 // limit for the provided ACME registration Id.
 func (builder *TransactionBuilder) OrdersPerAccountTransaction(regId int64) (Transaction, error) {
-	bucketKey, err := newRegIdBucketKey(NewOrdersPerAccount, regId)
+	bucketKey, err := BucketForRegistration(NewOrdersPerAccount, regId)
 	if err != nil {
 		return Transaction{}, err
 	}
-	limit, err := builder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	if builder.isExempt(NewOrdersPerAccount, regId) {
+		return newExemptTransaction(NewOrdersPerAccount, bucketKey)
+	}
+	limit, err := builder.getLimit(NewOrdersPerAccount, bucketKey)
 	if err != nil {
 		if errors.Is(err, errLimitDisabled) {
-			return newAllowOnlyTransaction()
+			return newAllowOnlyTransaction(NewOrdersPerAccount)
 		}
 		return Transaction{}, err
 	}
@@ -239,14 +359,17 @@ func (builder *TransactionBuilder) OrdersPerAccountTransaction(regId int64) (Tra
 // Transaction for the provided ACME registration Id for the
 // FailedAuthorizationsPerAccount limit.
 func (builder *TransactionBuilder) FailedAuthorizationsPerAccountCheckOnlyTransaction(regId int64) (Transaction, error) {
-	bucketKey, err := newRegIdBucketKey(FailedAuthorizationsPerAccount, regId)
+	bucketKey, err := BucketForRegistration(FailedAuthorizationsPerAccount, regId)
 	if err != nil {
 		return Transaction{}, err
 	}
-	limit, err := builder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	if builder.isExempt(FailedAuthorizationsPerAccount, regId) {
+		return newExemptTransaction(FailedAuthorizationsPerAccount, bucketKey)
+	}
+	limit, err := builder.getLimit(FailedAuthorizationsPerAccount, bucketKey)
 	if err != nil {
 		if errors.Is(err, errLimitDisabled) {
-			return newAllowOnlyTransaction()
+			return newAllowOnlyTransaction(FailedAuthorizationsPerAccount)
 		}
 		return Transaction{}, err
 	}
@@ -256,14 +379,79 @@ func (builder *TransactionBuilder) FailedAuthorizationsPerAccountCheckOnlyTransa
 // FailedAuthorizationsPerAccountTransaction returns a Transaction for the
 // FailedAuthorizationsPerAccount limit for the provided ACME registration Id.
 func (builder *TransactionBuilder) FailedAuthorizationsPerAccountTransaction(regId int64) (Transaction, error) {
-	bucketKey, err := newRegIdBucketKey(FailedAuthorizationsPerAccount, regId)
+	bucketKey, err := BucketForRegistration(FailedAuthorizationsPerAccount, regId)
 	if err != nil {
 		return Transaction{}, err
 	}
-	limit, err := builder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	if builder.isExempt(FailedAuthorizationsPerAccount, regId) {
+		return newExemptTransaction(FailedAuthorizationsPerAccount, bucketKey)
+	}
+	limit, err := builder.getLimit(FailedAuthorizationsPerAccount, bucketKey)
 	if err != nil {
 		if errors.Is(err, errLimitDisabled) {
-			return newAllowOnlyTransaction()
+			return newAllowOnlyTransaction(FailedAuthorizationsPerAccount)
+		}
+		return Transaction{}, err
+	}
+	return newTransaction(limit, bucketKey, 1)
+}
+
+// RevocationsPerAccountTransaction returns a Transaction for the
+// RevocationsPerAccount limit for the provided ACME registration Id.
+func (builder *TransactionBuilder) RevocationsPerAccountTransaction(regId int64) (Transaction, error) {
+	return builder.regIdTransaction(RevocationsPerAccount, regId)
+}
+
+// RevocationsPerIPAddressTransaction returns a Transaction for the
+// RevocationsPerIPAddress limit for the provided IP address.
+func (builder *TransactionBuilder) RevocationsPerIPAddressTransaction(ip net.IP) (Transaction, error) {
+	return builder.ipAddressTransaction(RevocationsPerIPAddress, ip)
+}
+
+// AccountUpdatesPerAccountTransaction returns a Transaction for the
+// AccountUpdatesPerAccount limit for the provided ACME registration Id.
+func (builder *TransactionBuilder) AccountUpdatesPerAccountTransaction(regId int64) (Transaction, error) {
+	return builder.regIdTransaction(AccountUpdatesPerAccount, regId)
+}
+
+// AccountUpdatesPerIPAddressTransaction returns a Transaction for the
+// AccountUpdatesPerIPAddress limit for the provided IP address.
+func (builder *TransactionBuilder) AccountUpdatesPerIPAddressTransaction(ip net.IP) (Transaction, error) {
+	return builder.ipAddressTransaction(AccountUpdatesPerIPAddress, ip)
+}
+
+// regIdTransaction returns a Transaction with a cost of 1 for the provided
+// limit, which must use the 'enum:regId' bucket key format, and ACME
+// registration Id.
+func (builder *TransactionBuilder) regIdTransaction(name Name, regId int64) (Transaction, error) {
+	bucketKey, err := BucketForRegistration(name, regId)
+	if err != nil {
+		return Transaction{}, err
+	}
+	if builder.isExempt(name, regId) {
+		return newExemptTransaction(name, bucketKey)
+	}
+	limit, err := builder.getLimit(name, bucketKey)
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return newAllowOnlyTransaction(name)
+		}
+		return Transaction{}, err
+	}
+	return newTransaction(limit, bucketKey, 1)
+}
+
+// ipAddressTransaction returns a Transaction with a cost of 1 for the provided
+// limit, which must use the 'enum:ipAddress' bucket key format, and IP address.
+func (builder *TransactionBuilder) ipAddressTransaction(name Name, ip net.IP) (Transaction, error) {
+	bucketKey, err := BucketForIP(name, ip)
+	if err != nil {
+		return Transaction{}, err
+	}
+	limit, err := builder.getLimit(name, bucketKey)
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return newAllowOnlyTransaction(name)
 		}
 		return Transaction{}, err
 	}
@@ -281,9 +469,11 @@ func (builder *TransactionBuilder) FailedAuthorizationsPerAccountTransaction(reg
 //     satisfy the cost.
 //
 // When a CertificatesPerDomainPerAccount override is not configured, a check-
-// and-spend Transaction is returned for each per domain bucket.
+// and-spend Transaction is returned for each per domain bucket. If the account
+// is exempt from either limit, an exempt Transaction is returned in place of
+// the Transactions for that limit.
 func (builder *TransactionBuilder) CertificatesPerDomainTransactions(regId int64, orderDomains []string) ([]Transaction, error) {
-	perAccountLimitBucketKey, err := newRegIdBucketKey(CertificatesPerDomainPerAccount, regId)
+	perAccountLimitBucketKey, err := BucketForRegistration(CertificatesPerDomainPerAccount, regId)
 	if err != nil {
 		return nil, err
 	}
@@ -294,28 +484,48 @@ func (builder *TransactionBuilder) CertificatesPerDomainTransactions(regId int64
 
 	var txns []Transaction
 	for _, name := range DomainsForRateLimiting(orderDomains) {
-		perDomainBucketKey, err := newDomainBucketKey(CertificatesPerDomain, name)
+		perDomainBucketKey, err := BucketForDomain(CertificatesPerDomain, name)
 		if err != nil {
 			return nil, err
 		}
+		perDomainExempt := builder.isExempt(CertificatesPerDomain, regId)
 		if perAccountLimit.isOverride {
 			// An override is configured for the CertificatesPerDomainPerAccount
 			// limit.
-			perAccountPerDomainKey, err := newRegIdDomainBucketKey(CertificatesPerDomainPerAccount, regId, name)
+			perAccountPerDomainKey, err := BucketForRegistrationDomain(CertificatesPerDomainPerAccount, regId, name)
 			if err != nil {
 				return nil, err
 			}
-			// Add a check-and-spend transaction for each per account per domain
-			// bucket.
-			txn, err := newTransaction(perAccountLimit, perAccountPerDomainKey, 1)
+			var txn Transaction
+			if builder.isExempt(CertificatesPerDomainPerAccount, regId) {
+				txn, err = newExemptTransaction(CertificatesPerDomainPerAccount, perAccountPerDomainKey)
+			} else {
+				// Add a check-and-spend transaction for each per account per
+				// domain bucket.
+				txn, err = newTransaction(perAccountLimit, perAccountPerDomainKey, 1)
+			}
 			if err != nil {
 				return nil, err
 			}
 			txns = append(txns, txn)
 
+			if perDomainExempt {
+				txn, err = newExemptTransaction(CertificatesPerDomain, perDomainBucketKey)
+				if err != nil {
+					return nil, err
+				}
+				txns = append(txns, txn)
+				continue
+			}
 			perDomainLimit, err := builder.getLimit(CertificatesPerDomain, perDomainBucketKey)
 			if errors.Is(err, errLimitDisabled) {
-				// Skip disabled limit.
+				// Add an allow-only transaction for the disabled limit so that
+				// the Limiter can count it.
+				txn, err = newAllowOnlyTransaction(CertificatesPerDomain)
+				if err != nil {
+					return nil, err
+				}
+				txns = append(txns, txn)
 				continue
 			}
 			if err != nil {
@@ -329,9 +539,23 @@ func (builder *TransactionBuilder) CertificatesPerDomainTransactions(regId int64
 			}
 			txns = append(txns, txn)
 		} else {
+			if perDomainExempt {
+				txn, err := newExemptTransaction(CertificatesPerDomain, perDomainBucketKey)
+				if err != nil {
+					return nil, err
+				}
+				txns = append(txns, txn)
+				continue
+			}
 			perDomainLimit, err := builder.getLimit(CertificatesPerDomain, perDomainBucketKey)
 			if errors.Is(err, errLimitDisabled) {
-				// Skip disabled limit.
+				// Add an allow-only transaction for the disabled limit so that
+				// the Limiter can count it.
+				txn, err := newAllowOnlyTransaction(CertificatesPerDomain)
+				if err != nil {
+					return nil, err
+				}
+				txns = append(txns, txn)
 				continue
 			}
 			if err != nil {
@@ -342,6 +566,11 @@ func (builder *TransactionBuilder) CertificatesPerDomainTransactions(regId int64
 			if err != nil {
 				return nil, err
 			}
+			if perDomainLimit.remote {
+				// The Server decides whether an override of, or exemption
+				// from, either limit applies to the account.
+				txn.regId = regId
+			}
 			txns = append(txns, txn)
 		}
 	}
@@ -351,16 +580,118 @@ func (builder *TransactionBuilder) CertificatesPerDomainTransactions(regId int64
 // CertificatesPerFQDNSetTransaction returns a Transaction for the provided
 // order domain names.
 func (builder *TransactionBuilder) CertificatesPerFQDNSetTransaction(orderNames []string) (Transaction, error) {
-	bucketKey, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, orderNames)
+	bucketKey, err := BucketForFQDNSet(CertificatesPerFQDNSet, orderNames)
 	if err != nil {
 		return Transaction{}, err
 	}
-	limit, err := builder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	limit, err := builder.getLimit(CertificatesPerFQDNSet, bucketKey)
 	if err != nil {
 		if errors.Is(err, errLimitDisabled) {
-			return newAllowOnlyTransaction()
+			return newAllowOnlyTransaction(CertificatesPerFQDNSet)
 		}
 		return Transaction{}, err
 	}
 	return newTransaction(limit, bucketKey, 1)
 }
+
+// NewOrderTransactions returns every Transaction which a new order for the
+// provided ACME registration Id and order domain names would be subject to:
+// NewOrdersPerAccount, a check-only FailedAuthorizationsPerAccount,
+// CertificatesPerDomain (and CertificatesPerDomainPerAccount, if an override is
+// configured), and CertificatesPerFQDNSet. If isRenewal is true, each
+// Transaction is returned as its AsRenewal equivalent.
+func (builder *TransactionBuilder) NewOrderTransactions(regId int64, orderNames []string, isRenewal bool) ([]Transaction, error) {
+	var txns []Transaction
+	txn, err := builder.OrdersPerAccountTransaction(regId)
+	if err != nil {
+		return nil, err
+	}
+	txns = append(txns, txn)
+
+	txn, err = builder.FailedAuthorizationsPerAccountCheckOnlyTransaction(regId)
+	if err != nil {
+		return nil, err
+	}
+	txns = append(txns, txn)
+
+	perDomainTxns, err := builder.CertificatesPerDomainTransactions(regId, orderNames)
+	if err != nil {
+		return nil, err
+	}
+	txns = append(txns, perDomainTxns...)
+
+	txn, err = builder.CertificatesPerFQDNSetTransaction(orderNames)
+	if err != nil {
+		return nil, err
+	}
+	txns = append(txns, txn)
+
+	if isRenewal {
+		for i := range txns {
+			txns[i] = txns[i].AsRenewal()
+		}
+	}
+	return txns, nil
+}
+
+// TransactionForId returns a Transaction for the limit specified by name and
+// the provided id, for callers which identify buckets by id rather than by the
+// request attributes accepted by the other methods of TransactionBuilder. The
+// id is formatted as it would be in an overrides file, e.g. an IP address for
+// NewRegistrationsPerIPAddress or a regId for NewOrdersPerAccount.
+func (builder *TransactionBuilder) TransactionForId(name Name, id string, cost int64) (Transaction, error) {
+	bucketKey, overrideKey, err := bucketKeysForId(name, id)
+	if err != nil {
+		return Transaction{}, err
+	}
+	switch name {
+	case NewOrdersPerAccount, FailedAuthorizationsPerAccount, RevocationsPerAccount, AccountUpdatesPerAccount:
+		regId, err := strconv.ParseInt(id, 10, 64)
+		if err == nil && builder.isExempt(name, regId) {
+			return newExemptTransaction(name, bucketKey)
+		}
+	}
+	limit, err := builder.getLimit(name, overrideKey)
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return newAllowOnlyTransaction(name)
+		}
+		return Transaction{}, err
+	}
+	return newTransaction(limit, bucketKey, cost)
+}
+
+// transactionForBucketKey returns a Transaction for the limit specified by name
+// and the bucket with the provided key, formatted as returned by
+// Transaction.BucketKey. It is used by the Server to rebuild the Transactions
+// of a Client, whose bucket keys, unlike ids, can't always be reversed, e.g.
+// those of CertificatesPerFQDNSet are hashes. Exemptions are applied to the
+// limits whose bucket keys include the requester's ACME registration Id.
+func (builder *TransactionBuilder) transactionForBucketKey(name Name, bucketKey string, cost int64) (Transaction, error) {
+	if nameForBucketKey(bucketKey) != name {
+		return Transaction{}, fmt.Errorf("bucket key %q is not a bucket key of %s", bucketKey, name)
+	}
+	_, id, _ := strings.Cut(bucketKey, ":")
+	overrideKey := bucketKey
+	regIdStr := id
+	if name == CertificatesPerDomainPerAccount {
+		// Overrides of this limit are keyed by regId, not by regId and domain.
+		regIdStr, _, _ = strings.Cut(id, ":")
+		overrideKey = joinWithColon(name.EnumString(), regIdStr)
+	}
+	switch name {
+	case NewOrdersPerAccount, FailedAuthorizationsPerAccount, RevocationsPerAccount, AccountUpdatesPerAccount, CertificatesPerDomainPerAccount:
+		regId, err := strconv.ParseInt(regIdStr, 10, 64)
+		if err == nil && builder.isExempt(name, regId) {
+			return newExemptTransaction(name, bucketKey)
+		}
+	}
+	limit, err := builder.getLimit(name, overrideKey)
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return newAllowOnlyTransaction(name)
+		}
+		return Transaction{}, err
+	}
+	return newTransaction(limit, bucketKey, cost)
+}
@@ -1,16 +1,206 @@
 package ratelimits
 
 import (
+	"context"
+	"net"
 	"testing"
 
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/letsencrypt/boulder/test"
 )
 
 func TestNewTransactionBuilder_WithBadLimitsPath(t *testing.T) {
 	t.Parallel()
-	_, err := NewTransactionBuilder("testdata/does-not-exist.yml", "")
+	_, err := NewTransactionBuilder("testdata/does-not-exist.yml", "", "", "")
+	test.AssertError(t, err, "should error")
+
+	_, err = NewTransactionBuilder("testdata/defaults.yml", "", "testdata/does-not-exist.yml", "")
+	test.AssertError(t, err, "should error")
+}
+
+func TestNewTransactionBuilder_WithExemptions(t *testing.T) {
+	t.Parallel()
+	_, err := NewTransactionBuilder("testdata/working_default.yml", "", "", "testdata/does-not-exist.yml")
 	test.AssertError(t, err, "should error")
 
-	_, err = NewTransactionBuilder("testdata/defaults.yml", "testdata/does-not-exist.yml")
+	_, err = NewTransactionBuilder("testdata/working_default.yml", "", "", "testdata/busted_exemptions_invalid_name.yml")
 	test.AssertError(t, err, "should error")
+
+	b, err := NewTransactionBuilder("testdata/working_defaults_per_domain.yml", "", "", "testdata/working_exemptions.yml")
+	test.AssertNotError(t, err, "should not error")
+
+	// Exempt account.
+	txn, err := b.OrdersPerAccountTransaction(1337)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, txn.exempt, "should be exempt")
+	test.Assert(t, txn.allowOnly(), "should be allow-only")
+
+	// Account which is not exempt.
+	txn, err = b.OrdersPerAccountTransaction(4242)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !txn.exempt, "should not be exempt")
+
+	// Exempt from CertificatesPerDomain.
+	txns, err := b.CertificatesPerDomainTransactions(4242, []string{"example.com", "example.org"})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(txns), 2)
+	for _, txn := range txns {
+		test.Assert(t, txn.exempt, "should be exempt")
+	}
+
+	// Not exempt from CertificatesPerDomain.
+	txns, err = b.CertificatesPerDomainTransactions(1234, []string{"example.com", "example.org"})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(txns), 2)
+	for _, txn := range txns {
+		test.Assert(t, !txn.exempt, "should not be exempt")
+		test.Assert(t, txn.check && txn.spend, "should be check-and-spend")
+	}
+}
+
+func TestTransactionForId(t *testing.T) {
+	t.Parallel()
+	b, err := NewTransactionBuilder("testdata/working_defaults.yml", "", "", "testdata/working_exemptions.yml")
+	test.AssertNotError(t, err, "should not error")
+
+	txn, err := b.TransactionForId(NewRegistrationsPerIPAddress, "10.0.0.1", 2)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, txn.bucketKey, "1:10.0.0.1")
+	test.AssertEquals(t, txn.cost, int64(2))
+	test.Assert(t, txn.check && txn.spend, "should be check-and-spend")
+
+	// Exempt account.
+	txn, err = b.TransactionForId(NewOrdersPerAccount, "1337", 1)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, txn.exempt, "should be exempt")
+
+	// Disabled limit.
+	txn, err = b.TransactionForId(NewOrdersPerAccount, "4242", 1)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !txn.exempt, "should not be exempt")
+	test.Assert(t, txn.allowOnly(), "should be allow-only")
+
+	_, err = b.TransactionForId(NewOrdersPerAccount, "not-a-regId", 1)
+	test.AssertError(t, err, "invalid id should error")
+}
+
+func TestBucketConstructors(t *testing.T) {
+	t.Parallel()
+
+	key, err := BucketForIP(NewRegistrationsPerIPAddress, net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, key, "1:10.0.0.1")
+	_, err = BucketForIP(NewRegistrationsPerIPAddress, nil)
+	test.AssertError(t, err, "nil IP should be rejected")
+	_, err = BucketForIP(NewOrdersPerAccount, net.ParseIP("10.0.0.1"))
+	test.AssertError(t, err, "limit using another bucket key format should be rejected")
+
+	key, err = BucketForIPv6Range(NewRegistrationsPerIPv6Range, net.ParseIP("2001:db8:1:2::1"))
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, key, "2:2001:db8:1::/48")
+	_, err = BucketForIPv6Range(NewRegistrationsPerIPv6Range, net.ParseIP("10.0.0.1"))
+	test.AssertError(t, err, "IPv4 address should be rejected")
+
+	key, err = BucketForRegistration(NewOrdersPerAccount, 1234)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, key, "3:1234")
+	_, err = BucketForRegistration(NewOrdersPerAccount, -1)
+	test.AssertError(t, err, "negative regId should be rejected")
+
+	key, err = BucketForDomain(CertificatesPerDomain, "Example.com")
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, key, "5:example.com")
+	_, err = BucketForDomain(CertificatesPerDomain, "example")
+	test.AssertError(t, err, "invalid domain should be rejected")
+
+	key, err = BucketForRegistrationDomain(CertificatesPerDomainPerAccount, 1234, "example.com")
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, key, "6:1234:example.com")
+	_, err = BucketForRegistrationDomain(CertificatesPerDomain, 1234, "example.com")
+	test.AssertError(t, err, "limit using another bucket key format should be rejected")
+
+	key, err = BucketForFQDNSet(CertificatesPerFQDNSet, []string{"example.com", "example.net"})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, nameForBucketKey(key), CertificatesPerFQDNSet)
+	_, err = BucketForFQDNSet(CertificatesPerFQDNSet, nil)
+	test.AssertError(t, err, "empty fqdnSet should be rejected")
+
+	prefix, err := BucketPrefix(CertificatesPerDomainPerAccount, "1234:")
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, prefix, "6:1234:")
+	_, err = BucketPrefix(Unknown, "")
+	test.AssertError(t, err, "invalid limit should be rejected")
+}
+
+func TestRevocationAndAccountUpdateTransactions(t *testing.T) {
+	t.Parallel()
+	b, err := NewTransactionBuilder("testdata/working_defaults_revocations_account_updates.yml", "", "", "testdata/working_exemptions_revocations.yml")
+	test.AssertNotError(t, err, "should not error")
+
+	txn, err := b.RevocationsPerAccountTransaction(4242)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, txn.bucketKey, joinWithColon(RevocationsPerAccount.EnumString(), "4242"))
+	test.AssertEquals(t, txn.limit.Burst, int64(100))
+	test.Assert(t, txn.check && txn.spend, "should be check-and-spend")
+
+	// Exempt account.
+	txn, err = b.RevocationsPerAccountTransaction(1337)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, txn.exempt, "should be exempt")
+
+	txn, err = b.RevocationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, txn.bucketKey, joinWithColon(RevocationsPerIPAddress.EnumString(), "10.0.0.1"))
+	test.AssertEquals(t, txn.limit.Burst, int64(200))
+
+	txn, err = b.AccountUpdatesPerAccountTransaction(4242)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, txn.bucketKey, joinWithColon(AccountUpdatesPerAccount.EnumString(), "4242"))
+	test.AssertEquals(t, txn.limit.Burst, int64(10))
+
+	// AccountUpdatesPerIPAddress has no default, so it is disabled.
+	txn, err = b.AccountUpdatesPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, txn.allowOnly(), "should be allow-only")
+
+	_, err = b.RevocationsPerIPAddressTransaction(nil)
+	test.AssertError(t, err, "nil IP should be rejected")
+}
+
+func TestRenewalTransactions(t *testing.T) {
+	t.Parallel()
+	b, err := NewTransactionBuilder("testdata/working_defaults_renewals.yml", "", "", "")
+	test.AssertNotError(t, err, "should not error")
+
+	// NewOrdersPerAccount charges renewals a reduced cost.
+	txn, err := b.TransactionForId(NewOrdersPerAccount, "5678", 5)
+	test.AssertNotError(t, err, "should not error")
+	renewal := txn.AsRenewal()
+	test.AssertEquals(t, renewal.cost, int64(2))
+	test.AssertEquals(t, renewal.bucketKey, txn.bucketKey)
+	test.Assert(t, renewal.check && renewal.spend, "should be check-and-spend")
+
+	// CertificatesPerDomain exempts renewals.
+	txns, err := b.NewOrderTransactions(5678, []string{"example.com"}, true)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(txns), 4)
+	test.Assert(t, txns[2].allowOnly() && txns[2].renewal, "should be an allow-only renewal")
+	test.AssertEquals(t, txns[2].limit.name, CertificatesPerDomain)
+
+	// Limits without a renewalCost are unchanged.
+	test.AssertEquals(t, txns[3].cost, int64(1))
+	test.Assert(t, !txns[3].renewal, "should not be a renewal")
+
+	txns, err = b.NewOrderTransactions(5678, []string{"example.com"}, false)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !txns[2].allowOnly(), "should not be allow-only")
+
+	// Renewals are counted separately from disabled limits.
+	l := newInmemTestLimiter(t, clock.NewFake())
+	_, err = l.Spend(context.Background(), txns[2].AsRenewal())
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.decisions, prometheus.Labels{"limit": CertificatesPerDomain.String(), "reason": reasonRenewal}, 1)
+	test.AssertMetricWithLabelsEquals(t, l.disabledLimits, prometheus.Labels{"limit": CertificatesPerDomain.String()}, 0)
 }
@@ -0,0 +1,188 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+
+	rlpb "github.com/letsencrypt/boulder/ratelimits/proto"
+)
+
+var errIncompleteResponse = errors.New("incomplete gRPC response message")
+
+// Client is a TransactionLimiter which checks, spends from, and refunds the
+// buckets of a remote Limiter, exposed by a Server in ratelimitd, over gRPC.
+// Transactions should be built by a TransactionBuilder returned by
+// NewRemoteTransactionBuilder, so that only the limit name, bucket, and cost of
+// each are sent: the Server's TransactionBuilder decides which limits are
+// disabled or overridden and which requesters are exempt, and the Decisions
+// returned describe the Server's limits.
+type Client struct {
+	rlpb rlpb.RateLimitsClient
+}
+
+var _ TransactionLimiter = (*Client)(nil)
+
+// NewClient returns a new *Client which makes requests of the provided
+// RateLimitsClient.
+func NewClient(client rlpb.RateLimitsClient) *Client {
+	return &Client{rlpb: client}
+}
+
+func transactionToPB(txn Transaction) *rlpb.Transaction {
+	pb := &rlpb.Transaction{
+		Limit:     txn.limit.name.String(),
+		BucketKey: txn.bucketKey,
+		Cost:      txn.cost,
+		Renewal:   txn.remoteRenewal,
+		RegId:     txn.regId,
+	}
+	if txn.regId == 0 {
+		// The check and spend of Transactions with a regId are decided by the
+		// Server.
+		pb.CheckOnly = txn.checkOnly()
+		pb.SpendOnly = txn.spendOnly()
+	}
+	return pb
+}
+
+// transactionsToPB returns the protobuf Transactions for those of txns which
+// aren't allow-only, or nil if all of them are. Transactions built by a remote
+// TransactionBuilder are never allow-only.
+func transactionsToPB(txns []Transaction) *rlpb.Transactions {
+	var pbs []*rlpb.Transaction
+	for _, txn := range txns {
+		if txn.allowOnly() {
+			continue
+		}
+		pbs = append(pbs, transactionToPB(txn))
+	}
+	if len(pbs) == 0 {
+		return nil
+	}
+	return &rlpb.Transactions{Transactions: pbs}
+}
+
+// decisionFromPB returns the Decision for the provided protobuf Decision,
+// made for the bucket of one of txns.
+func decisionFromPB(pb *rlpb.Decision, txns []Transaction) (*Decision, error) {
+	if pb == nil || pb.RetryIn == nil || pb.ResetIn == nil {
+		return nil, errIncompleteResponse
+	}
+	d := &Decision{
+		Allowed:   pb.Allowed,
+		Remaining: pb.Remaining,
+		RetryIn:   pb.RetryIn.AsDuration(),
+		ResetIn:   pb.ResetIn.AsDuration(),
+	}
+	if pb.BucketKey == "" {
+		return d, nil
+	}
+	// The bucket may not be one of txns, e.g. if the Server applied a
+	// CertificatesPerDomainPerAccount override, and its limit is that of the
+	// Server, so describe it as the Server did.
+	d.transaction = Transaction{
+		bucketKey: pb.BucketKey,
+		limit:     limit{name: nameForBucketKey(pb.BucketKey), Burst: pb.Burst},
+	}
+	for _, txn := range txns {
+		if txn.bucketKey == pb.BucketKey {
+			d.transaction.cost = txn.cost
+			break
+		}
+	}
+	return d, nil
+}
+
+func (c *Client) Check(ctx context.Context, txn Transaction) (*Decision, error) {
+	if txn.allowOnly() {
+		return allowedDecision, nil
+	}
+	pb, err := c.rlpb.Check(ctx, transactionToPB(txn))
+	if err != nil {
+		return nil, err
+	}
+	return decisionFromPB(pb, []Transaction{txn})
+}
+
+func (c *Client) BatchCheck(ctx context.Context, txns []Transaction) ([]*Decision, error) {
+	decisions := make([]*Decision, len(txns))
+	req := transactionsToPB(txns)
+	if req == nil {
+		for i := range decisions {
+			decisions[i] = allowedDecision
+		}
+		return decisions, nil
+	}
+	resp, err := c.rlpb.BatchCheck(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Decisions) != len(req.Transactions) {
+		return nil, errIncompleteResponse
+	}
+	// The Server decided the Transactions which aren't allow-only, in order.
+	var next int
+	for i, txn := range txns {
+		if txn.allowOnly() {
+			decisions[i] = allowedDecision
+			continue
+		}
+		decisions[i], err = decisionFromPB(resp.Decisions[next], []Transaction{txn})
+		if err != nil {
+			return nil, err
+		}
+		next++
+	}
+	return decisions, nil
+}
+
+func (c *Client) Spend(ctx context.Context, txn Transaction) (*Decision, error) {
+	if txn.allowOnly() {
+		return allowedDecision, nil
+	}
+	pb, err := c.rlpb.Spend(ctx, transactionToPB(txn))
+	if err != nil {
+		return nil, err
+	}
+	return decisionFromPB(pb, []Transaction{txn})
+}
+
+func (c *Client) BatchSpend(ctx context.Context, txns []Transaction) (*Decision, error) {
+	req := transactionsToPB(txns)
+	if req == nil {
+		return allowedDecision, nil
+	}
+	pb, err := c.rlpb.BatchSpend(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return decisionFromPB(pb, txns)
+}
+
+func (c *Client) Refund(ctx context.Context, txn Transaction) (*Decision, error) {
+	if txn.allowOnly() {
+		return allowedDecision, nil
+	}
+	pb, err := c.rlpb.Refund(ctx, transactionToPB(txn))
+	if err != nil {
+		return nil, err
+	}
+	return decisionFromPB(pb, []Transaction{txn})
+}
+
+func (c *Client) BatchRefund(ctx context.Context, txns []Transaction) (*Decision, error) {
+	req := transactionsToPB(txns)
+	if req == nil {
+		return allowedDecision, nil
+	}
+	pb, err := c.rlpb.BatchRefund(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return decisionFromPB(pb, txns)
+}
+
+func (c *Client) Reset(ctx context.Context, bucketKey string) error {
+	_, err := c.rlpb.Reset(ctx, &rlpb.ResetRequest{BucketKey: bucketKey})
+	return err
+}
@@ -0,0 +1,185 @@
+package ratelimits
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmhodges/clock"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	rlpb "github.com/letsencrypt/boulder/ratelimits/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// serverClient is an rlpb.RateLimitsClient which calls a Server in-process.
+type serverClient struct {
+	s        *Server
+	requests int
+}
+
+func (c *serverClient) Check(ctx context.Context, in *rlpb.Transaction, _ ...grpc.CallOption) (*rlpb.Decision, error) {
+	c.requests++
+	return c.s.Check(ctx, in)
+}
+
+func (c *serverClient) BatchCheck(ctx context.Context, in *rlpb.Transactions, _ ...grpc.CallOption) (*rlpb.Decisions, error) {
+	c.requests++
+	return c.s.BatchCheck(ctx, in)
+}
+
+func (c *serverClient) Spend(ctx context.Context, in *rlpb.Transaction, _ ...grpc.CallOption) (*rlpb.Decision, error) {
+	c.requests++
+	return c.s.Spend(ctx, in)
+}
+
+func (c *serverClient) BatchSpend(ctx context.Context, in *rlpb.Transactions, _ ...grpc.CallOption) (*rlpb.Decision, error) {
+	c.requests++
+	return c.s.BatchSpend(ctx, in)
+}
+
+func (c *serverClient) Refund(ctx context.Context, in *rlpb.Transaction, _ ...grpc.CallOption) (*rlpb.Decision, error) {
+	c.requests++
+	return c.s.Refund(ctx, in)
+}
+
+func (c *serverClient) BatchRefund(ctx context.Context, in *rlpb.Transactions, _ ...grpc.CallOption) (*rlpb.Decision, error) {
+	c.requests++
+	return c.s.BatchRefund(ctx, in)
+}
+
+func (c *serverClient) Reset(ctx context.Context, in *rlpb.ResetRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	c.requests++
+	return c.s.Reset(ctx, in)
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	defaults := filepath.Join(dir, "defaults.yml")
+	err := os.WriteFile(defaults, []byte(`
+NewRegistrationsPerIPAddress: { burst: 20, count: 20, period: 1s }
+NewOrdersPerAccount: { burst: 2, count: 2, period: 1s }
+CertificatesPerDomain: { burst: 2, count: 2, period: 1s }
+CertificatesPerDomainPerAccount: { burst: 3, count: 3, period: 1s }
+CertificatesPerFQDNSet: { burst: 5, count: 5, period: 1s }
+`), 0600)
+	test.AssertNotError(t, err, "writing defaults")
+	overrides := filepath.Join(dir, "overrides.yml")
+	err = os.WriteFile(overrides, []byte(`
+- CertificatesPerDomainPerAccount: { burst: 4, count: 4, period: 1s, ids: [1] }
+- CertificatesPerFQDNSet: { burst: 6, count: 6, period: 1s, ids: ["example.com,example.net"] }
+`), 0600)
+	test.AssertNotError(t, err, "writing overrides")
+	exemptions := filepath.Join(dir, "exemptions.yml")
+	err = os.WriteFile(exemptions, []byte(`
+- NewOrdersPerAccount: { ids: [2] }
+- CertificatesPerDomain: { ids: [2] }
+`), 0600)
+	test.AssertNotError(t, err, "writing exemptions")
+	serverBuilder, err := NewTransactionBuilder(defaults, "", overrides, exemptions)
+	test.AssertNotError(t, err, "making transaction builder")
+
+	clk := clock.NewFake()
+	limiter := newInmemTestLimiter(t, clk)
+	rpc := &serverClient{s: NewServer(limiter, serverBuilder)}
+	c := NewClient(rpc)
+	builder := NewRemoteTransactionBuilder()
+	ctx := context.Background()
+
+	// The bucket keys of CertificatesPerFQDNSet are hashes, which the Server
+	// can't reverse into ids. The override and the burst are the Server's.
+	fqdnSetTxn, err := builder.CertificatesPerFQDNSetTransaction([]string{"example.com", "example.net"})
+	test.AssertNotError(t, err, "building transaction")
+	d, err := c.Spend(ctx, fqdnSetTxn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, d.Remaining, int64(5))
+	test.AssertEquals(t, d.Burst(), int64(6))
+	test.AssertEquals(t, d.transaction.bucketKey, fqdnSetTxn.bucketKey)
+
+	// The Client and the Limiter agree about the state of the bucket.
+	serverTxn, err := serverBuilder.CertificatesPerFQDNSetTransaction([]string{"example.com", "example.net"})
+	test.AssertNotError(t, err, "building transaction")
+	want, err := limiter.Check(ctx, serverTxn)
+	test.AssertNotError(t, err, "should not error")
+	d, err = c.Check(ctx, fqdnSetTxn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, want.Remaining)
+	test.AssertEquals(t, d.ResetIn, want.ResetIn)
+
+	// The CertificatesPerDomainPerAccount override of account 1 is applied by
+	// the Server, which only spends from the per domain bucket, so a batch is
+	// allowed even once it's exhausted.
+	domainTxns, err := builder.CertificatesPerDomainTransactions(1, []string{"example.com"})
+	test.AssertNotError(t, err, "building transactions")
+	test.AssertEquals(t, len(domainTxns), 1)
+	for i := 0; i < 3; i++ {
+		d, err = c.BatchSpend(ctx, domainTxns)
+		test.AssertNotError(t, err, "should not error")
+		test.Assert(t, d.Allowed, "should be allowed")
+	}
+	test.AssertEquals(t, d.Remaining, int64(1))
+	test.AssertEquals(t, d.Burst(), int64(4))
+
+	decisions, err := c.BatchCheck(ctx, domainTxns)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(decisions), 1)
+	test.Assert(t, decisions[0].Allowed, "should be allowed")
+	test.AssertEquals(t, decisions[0].Remaining, int64(0))
+
+	// The per domain bucket, which had been over-spent, has the least
+	// remaining capacity after the refund.
+	d, err = c.BatchRefund(ctx, domainTxns)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(1))
+	test.AssertEquals(t, d.Burst(), int64(2))
+
+	// Without an override, account 3 spends from the per domain bucket, which
+	// is exhausted.
+	domainTxns, err = builder.CertificatesPerDomainTransactions(3, []string{"example.com"})
+	test.AssertNotError(t, err, "building transactions")
+	d, err = c.BatchSpend(ctx, domainTxns)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	d, err = c.BatchSpend(ctx, domainTxns)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+	test.AssertEquals(t, d.Burst(), int64(2))
+
+	// Account 2 is exempt from both limits on the Server.
+	domainTxns, err = builder.CertificatesPerDomainTransactions(2, []string{"example.com"})
+	test.AssertNotError(t, err, "building transactions")
+	d, err = c.BatchSpend(ctx, domainTxns)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	exemptTxn, err := builder.OrdersPerAccountTransaction(2)
+	test.AssertNotError(t, err, "building transaction")
+	ordersTxn, err := builder.OrdersPerAccountTransaction(3)
+	test.AssertNotError(t, err, "building transaction")
+	for i := 0; i < 3; i++ {
+		d, err = c.Spend(ctx, exemptTxn)
+		test.AssertNotError(t, err, "should not error")
+		test.Assert(t, d.Allowed, "should be allowed")
+		d, err = c.Spend(ctx, ordersTxn)
+		test.AssertNotError(t, err, "should not error")
+	}
+	test.Assert(t, !d.Allowed, "should be denied")
+
+	// Limits which are disabled on the Server are always allowed.
+	disabledTxn, err := builder.FailedAuthorizationsPerAccountCheckOnlyTransaction(1)
+	test.AssertNotError(t, err, "building transaction")
+	requests := rpc.requests
+	d, err = c.Check(ctx, disabledTxn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, rpc.requests, requests+1)
+
+	err = c.Reset(ctx, fqdnSetTxn.bucketKey)
+	test.AssertNotError(t, err, "should not error")
+	d, err = c.Check(ctx, fqdnSetTxn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(5))
+}
@@ -0,0 +1,186 @@
+package ratelimits
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/letsencrypt/boulder/core"
+)
+
+// BucketState describes the effective limit for a bucket and the current
+// state of that bucket. It is returned by InspectBucket and, as JSON, by the
+// handler returned by NewDebugHandler.
+type BucketState struct {
+	// Name and Id are the limit name and id specified in the request.
+	Name string `json:"name"`
+	Id   string `json:"id"`
+
+	// BucketKey is the key of the bucket, formatted as 'enum:id'.
+	BucketKey string `json:"bucketKey"`
+
+	// Override is true if an override limit matched the provided id, false if
+	// the default limit applies.
+	Override bool   `json:"override"`
+	Burst    int64  `json:"burst"`
+	Count    int64  `json:"count"`
+	Period   string `json:"period"`
+
+	// Exists is false if no bucket is currently stored for BucketKey, which is
+	// equivalent to a full bucket.
+	Exists    bool   `json:"exists"`
+	Remaining int64  `json:"remaining"`
+	ResetIn   string `json:"resetIn"`
+
+	// TAT is the theoretical arrival time stored for the bucket. It is nil if
+	// the bucket does not exist.
+	TAT *time.Time `json:"tat,omitempty"`
+}
+
+// bucketKeysForId returns the bucketKey of the bucket for the provided name and
+// id, and the bucketKey used to lookup an override for that bucket. These only
+// differ for CertificatesPerDomainPerAccount, where overrides are configured
+// per account but buckets are stored per account per domain.
+func bucketKeysForId(name Name, id string) (string, string, error) {
+	id = normalizeIdForName(name, id)
+	err := validateIdForName(name, id)
+	if err != nil {
+		return "", "", err
+	}
+	switch name {
+	case CertificatesPerFQDNSet:
+		// Follow the same convention as an overrides file, where the fqdnSet
+		// is specified as a comma-separated list of domain names.
+		key := joinWithColon(name.EnumString(), fmt.Sprintf("%x", core.HashNames(strings.Split(id, ","))))
+		return key, key, nil
+
+	case CertificatesPerDomainPerAccount:
+		regId, _, _ := strings.Cut(id, ":")
+		return joinWithColon(name.EnumString(), id), joinWithColon(name.EnumString(), regId), nil
+
+	default:
+		key := joinWithColon(name.EnumString(), id)
+		return key, key, nil
+	}
+}
+
+// lookupBucket returns the BucketState for the bucket of the limit with the
+// provided name and id. On error, it also returns the HTTP status code which
+// should be used to respond.
+func lookupBucket(ctx context.Context, limiter *Limiter, builder *TransactionBuilder, nameStr, id string) (BucketState, int, error) {
+	name, ok := stringToName[nameStr]
+	if !ok || name == Unknown {
+		return BucketState{}, http.StatusBadRequest, fmt.Errorf("unrecognized name %q, must be one of %v", nameStr, limitNames)
+	}
+	bucketKey, overrideKey, err := bucketKeysForId(name, id)
+	if err != nil {
+		return BucketState{}, http.StatusBadRequest, err
+	}
+	l, err := builder.getLimit(name, overrideKey)
+	if err != nil {
+		if errors.Is(err, errLimitDisabled) {
+			return BucketState{}, http.StatusNotFound, fmt.Errorf("limit %s is disabled", name)
+		}
+		return BucketState{}, http.StatusInternalServerError, err
+	}
+
+	exists := true
+	tat, err := limiter.source.Get(ctx, bucketKey)
+	if err != nil {
+		if !errors.Is(err, ErrBucketNotFound) {
+			return BucketState{}, http.StatusInternalServerError, fmt.Errorf("getting bucket %q: %s", bucketKey, err)
+		}
+		// A TAT of "now" is equivalent to a full bucket.
+		exists = false
+		tat = limiter.clk.Now()
+	}
+	d := maybeSpend(limiter.clk, l, tat, 0)
+
+	e := BucketState{
+		Name:      name.String(),
+		Id:        id,
+		BucketKey: bucketKey,
+		Override:  l.isOverride,
+		Burst:     l.Burst,
+		Count:     l.Count,
+		Period:    l.Period.Duration.String(),
+		Exists:    exists,
+		Remaining: d.Remaining,
+		ResetIn:   d.ResetIn.String(),
+	}
+	if exists {
+		e.TAT = &tat
+	}
+	return e, http.StatusOK, nil
+}
+
+// InspectBucket returns the effective limit, the default or the matching
+// override, and the current state of the bucket for the limit with the
+// provided name and id, formatted as they would be in an overrides file.
+func InspectBucket(ctx context.Context, limiter *Limiter, builder *TransactionBuilder, name, id string) (BucketState, error) {
+	e, _, err := lookupBucket(ctx, limiter, builder, name, id)
+	return e, err
+}
+
+// NewDebugHandler returns an http.Handler which, given the 'name' and 'id'
+// query parameters, responds with a JSON object describing the effective limit
+// for that bucket (the default or the matching override) and the current state
+// of the bucket. It is intended to be served by the debug server only.
+func NewDebugHandler(limiter *Limiter, builder *TransactionBuilder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		e, code, err := lookupBucket(r.Context(), limiter, builder, r.URL.Query().Get("name"), r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(e)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// defaultTopDenied is the number of bucket keys returned by the handler
+// returned by NewTopDeniedHandler when the 'n' query parameter is not set.
+const defaultTopDenied = 10
+
+// NewTopDeniedHandler returns an http.Handler which responds with a JSON array
+// of the bucket keys with the most over-limit denials, as returned by
+// Limiter.TopDenied. The optional 'n' query parameter sets the number of bucket
+// keys returned, up to 100. It is intended to be served by the debug server
+// only.
+func NewTopDeniedHandler(limiter *Limiter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		n := defaultTopDenied
+		nStr := r.URL.Query().Get("n")
+		if nStr != "" {
+			var err error
+			n, err = strconv.Atoi(nStr)
+			if err != nil || n <= 0 || n > heavyHittersSize {
+				http.Error(w, fmt.Sprintf("invalid n %q, must be between 1 and %d", nStr, heavyHittersSize), http.StatusBadRequest)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(limiter.TopDenied(n))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package ratelimits
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestDebugHandler(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	handler := NewDebugHandler(l, txnBuilder)
+
+	get := func(query string) (int, BucketState) {
+		t.Helper()
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/ratelimits?"+query, nil))
+		var resp BucketState
+		if rw.Code == http.StatusOK {
+			err := json.Unmarshal(rw.Body.Bytes(), &resp)
+			test.AssertNotError(t, err, "unmarshalling response")
+		}
+		return rw.Code, resp
+	}
+
+	// Default limit, no bucket.
+	code, resp := get("name=NewRegistrationsPerIPAddress&id=10.0.0.1")
+	test.AssertEquals(t, code, http.StatusOK)
+	test.AssertEquals(t, resp.BucketKey, joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.1"))
+	test.Assert(t, !resp.Override, "should not be an override")
+	test.Assert(t, !resp.Exists, "bucket should not exist")
+	test.AssertEquals(t, resp.Burst, int64(20))
+	test.AssertEquals(t, resp.Remaining, int64(20))
+
+	// Override limit, after spending.
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(tenZeroZeroTwo))
+	test.AssertNotError(t, err, "should not error")
+	_, err = l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	code, resp = get("name=NewRegistrationsPerIPAddress&id=" + tenZeroZeroTwo)
+	test.AssertEquals(t, code, http.StatusOK)
+	test.Assert(t, resp.Override, "should be an override")
+	test.Assert(t, resp.Exists, "bucket should exist")
+	test.AssertEquals(t, resp.Burst, int64(40))
+	test.AssertEquals(t, resp.Remaining, int64(39))
+
+	// Unknown name.
+	code, _ = get("name=lol&id=10.0.0.1")
+	test.AssertEquals(t, code, http.StatusBadRequest)
+
+	// Invalid id.
+	code, _ = get("name=NewRegistrationsPerIPAddress&id=lol")
+	test.AssertEquals(t, code, http.StatusBadRequest)
+
+	// Disabled limit.
+	code, _ = get("name=CertificatesPerDomain&id=example.com")
+	test.AssertEquals(t, code, http.StatusNotFound)
+}
+
+func TestTopDeniedHandler(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	handler := NewTopDeniedHandler(l)
+
+	// Exhaust the bucket and then get denied twice.
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.10"))
+	test.AssertNotError(t, err, "should not error")
+	for i := 0; i < 22; i++ {
+		_, err = l.Spend(context.Background(), txn)
+		test.AssertNotError(t, err, "should not error")
+	}
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/ratelimits/top?n=5", nil))
+	test.AssertEquals(t, rw.Code, http.StatusOK)
+	var resp []HeavyHitter
+	err = json.Unmarshal(rw.Body.Bytes(), &resp)
+	test.AssertNotError(t, err, "unmarshalling response")
+	test.AssertEquals(t, len(resp), 1)
+	test.AssertDeepEquals(t, resp[0], HeavyHitter{
+		Limit:     NewRegistrationsPerIPAddress.String(),
+		BucketKey: joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.10"),
+		Denials:   2,
+	})
+
+	rw = httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/ratelimits/top?n=lol", nil))
+	test.AssertEquals(t, rw.Code, http.StatusBadRequest)
+}
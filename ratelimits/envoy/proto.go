@@ -0,0 +1,398 @@
+package envoy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// The messages in this file are the subset of the envoy.service.ratelimit.v3
+// protocol used by the Server. They are hand-written, rather than generated,
+// to avoid depending on the Envoy API and its transitive dependencies. Each is
+// encoded and decoded using the field numbers from:
+//   - envoy/service/ratelimit/v3/rls.proto
+//   - envoy/extensions/common/ratelimit/v3/ratelimit.proto
+//
+// Fields which aren't used by the Server are skipped when decoding and never
+// encoded.
+
+// Code is the envoy.service.ratelimit.v3.RateLimitResponse.Code enum.
+type Code int32
+
+const (
+	CodeUnknown   Code = 0
+	CodeOK        Code = 1
+	CodeOverLimit Code = 2
+)
+
+// Unit is the envoy.service.ratelimit.v3.RateLimitResponse.RateLimit.Unit enum.
+type Unit int32
+
+const (
+	UnitUnknown Unit = 0
+	UnitSecond  Unit = 1
+	UnitMinute  Unit = 2
+	UnitHour    Unit = 3
+	UnitDay     Unit = 4
+)
+
+// Entry is a single key/value pair of a RateLimitDescriptor.
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// RateLimitDescriptor is a list of entries which together identify a single
+// rate limit bucket.
+type RateLimitDescriptor struct {
+	Entries []Entry
+
+	// HitsAddend, if non-zero, overrides the HitsAddend of the request for
+	// this descriptor.
+	HitsAddend uint64
+}
+
+// RateLimitRequest is the request of the ShouldRateLimit method.
+type RateLimitRequest struct {
+	Domain      string
+	Descriptors []RateLimitDescriptor
+
+	// HitsAddend is the cost of the request for each descriptor. If zero, a
+	// cost of 1 is assumed.
+	HitsAddend uint32
+}
+
+// RateLimit describes the limit which applies to a descriptor.
+type RateLimit struct {
+	Name            string
+	RequestsPerUnit uint32
+	Unit            Unit
+}
+
+// DescriptorStatus is the status of a single descriptor of a RateLimitRequest.
+type DescriptorStatus struct {
+	Code               Code
+	CurrentLimit       *RateLimit
+	LimitRemaining     uint32
+	DurationUntilReset time.Duration
+}
+
+// RateLimitResponse is the response of the ShouldRateLimit method. Statuses
+// are in the same order as the descriptors of the request.
+type RateLimitResponse struct {
+	OverallCode Code
+	Statuses    []DescriptorStatus
+}
+
+var errInvalidMessage = errors.New("invalid protobuf message")
+
+// consumeFields calls fn for each field of the encoded message b. The value
+// passed to fn is the field's bytes for the bytes wire type, or the decoded
+// varint for the varint wire type. Fields of any other wire type are skipped.
+func consumeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return errInvalidMessage
+		}
+		b = b[l:]
+		var err error
+		switch typ {
+		case protowire.BytesType:
+			v, l := protowire.ConsumeBytes(b)
+			if l < 0 {
+				return errInvalidMessage
+			}
+			err = fn(num, typ, v, 0)
+			b = b[l:]
+		case protowire.VarintType:
+			v, l := protowire.ConsumeVarint(b)
+			if l < 0 {
+				return errInvalidMessage
+			}
+			err = fn(num, typ, nil, v)
+			b = b[l:]
+		default:
+			l := protowire.ConsumeFieldValue(num, typ, b)
+			if l < 0 {
+				return errInvalidMessage
+			}
+			b = b[l:]
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Entry) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			e.Key = string(v)
+		case num == 2 && typ == protowire.BytesType:
+			e.Value = string(v)
+		}
+		return nil
+	})
+}
+
+func (d *RateLimitDescriptor) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var e Entry
+			err := e.unmarshal(v)
+			if err != nil {
+				return err
+			}
+			d.Entries = append(d.Entries, e)
+		case num == 3 && typ == protowire.BytesType:
+			// google.protobuf.UInt64Value
+			return consumeFields(v, func(num protowire.Number, typ protowire.Type, _ []byte, n uint64) error {
+				if num == 1 && typ == protowire.VarintType {
+					d.HitsAddend = n
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+// Unmarshal decodes the wire-format RateLimitRequest b into r.
+func (r *RateLimitRequest) Unmarshal(b []byte) error {
+	*r = RateLimitRequest{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			r.Domain = string(v)
+		case num == 2 && typ == protowire.BytesType:
+			var d RateLimitDescriptor
+			err := d.unmarshal(v)
+			if err != nil {
+				return err
+			}
+			r.Descriptors = append(r.Descriptors, d)
+		case num == 3 && typ == protowire.VarintType:
+			r.HitsAddend = uint32(n)
+		}
+		return nil
+	})
+}
+
+// Marshal returns the wire-format encoding of r.
+func (r *RateLimitRequest) Marshal() []byte {
+	var b []byte
+	if r.Domain != "" {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, r.Domain)
+	}
+	for _, d := range r.Descriptors {
+		var db []byte
+		for _, e := range d.Entries {
+			var eb []byte
+			eb = protowire.AppendTag(eb, 1, protowire.BytesType)
+			eb = protowire.AppendString(eb, e.Key)
+			eb = protowire.AppendTag(eb, 2, protowire.BytesType)
+			eb = protowire.AppendString(eb, e.Value)
+			db = protowire.AppendTag(db, 1, protowire.BytesType)
+			db = protowire.AppendBytes(db, eb)
+		}
+		if d.HitsAddend != 0 {
+			var hb []byte
+			hb = protowire.AppendTag(hb, 1, protowire.VarintType)
+			hb = protowire.AppendVarint(hb, d.HitsAddend)
+			db = protowire.AppendTag(db, 3, protowire.BytesType)
+			db = protowire.AppendBytes(db, hb)
+		}
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, db)
+	}
+	if r.HitsAddend != 0 {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(r.HitsAddend))
+	}
+	return b
+}
+
+func (l *RateLimit) marshal() []byte {
+	var b []byte
+	if l.RequestsPerUnit != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(l.RequestsPerUnit))
+	}
+	if l.Unit != UnitUnknown {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(l.Unit))
+	}
+	if l.Name != "" {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendString(b, l.Name)
+	}
+	return b
+}
+
+func (l *RateLimit) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			l.RequestsPerUnit = uint32(n)
+		case num == 2 && typ == protowire.VarintType:
+			l.Unit = Unit(n)
+		case num == 3 && typ == protowire.BytesType:
+			l.Name = string(v)
+		}
+		return nil
+	})
+}
+
+// marshalDuration returns the wire-format encoding of d as a
+// google.protobuf.Duration.
+func marshalDuration(d time.Duration) []byte {
+	var b []byte
+	seconds := int64(d / time.Second)
+	nanos := int64(d % time.Second)
+	if seconds != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(seconds))
+	}
+	if nanos != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(nanos))
+	}
+	return b
+}
+
+func unmarshalDuration(b []byte) (time.Duration, error) {
+	var d time.Duration
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, _ []byte, n uint64) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			d += time.Duration(int64(n)) * time.Second
+		case num == 2 && typ == protowire.VarintType:
+			d += time.Duration(int32(n))
+		}
+		return nil
+	})
+	return d, err
+}
+
+func (s *DescriptorStatus) marshal() []byte {
+	var b []byte
+	if s.Code != CodeUnknown {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(s.Code))
+	}
+	if s.CurrentLimit != nil {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, s.CurrentLimit.marshal())
+	}
+	if s.LimitRemaining != 0 {
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(s.LimitRemaining))
+	}
+	if s.DurationUntilReset != 0 {
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, marshalDuration(s.DurationUntilReset))
+	}
+	return b
+}
+
+func (s *DescriptorStatus) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		var err error
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			s.Code = Code(n)
+		case num == 2 && typ == protowire.BytesType:
+			s.CurrentLimit = &RateLimit{}
+			err = s.CurrentLimit.unmarshal(v)
+		case num == 3 && typ == protowire.VarintType:
+			s.LimitRemaining = uint32(n)
+		case num == 4 && typ == protowire.BytesType:
+			s.DurationUntilReset, err = unmarshalDuration(v)
+		}
+		return err
+	})
+}
+
+// Marshal returns the wire-format encoding of r.
+func (r *RateLimitResponse) Marshal() []byte {
+	var b []byte
+	if r.OverallCode != CodeUnknown {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(r.OverallCode))
+	}
+	for _, s := range r.Statuses {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, s.marshal())
+	}
+	return b
+}
+
+// Unmarshal decodes the wire-format RateLimitResponse b into r.
+func (r *RateLimitResponse) Unmarshal(b []byte) error {
+	*r = RateLimitResponse{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			r.OverallCode = Code(n)
+		case num == 2 && typ == protowire.BytesType:
+			var s DescriptorStatus
+			err := s.unmarshal(v)
+			if err != nil {
+				return err
+			}
+			r.Statuses = append(r.Statuses, s)
+		}
+		return nil
+	})
+}
+
+// message is implemented by every message which can be sent or received by
+// Codec.
+type message interface {
+	Marshal() []byte
+	Unmarshal([]byte) error
+}
+
+// Codec is a grpc encoding.Codec for the messages in this package. It must be
+// installed on the gRPC server using grpc.ForceServerCodec, or on the client
+// using grpc.ForceCodec, because the messages don't implement proto.Message.
+// Messages which do implement proto.Message, such as those of the health
+// service, are encoded as usual, so that Codec can be used for every service
+// of a server.
+type Codec struct{}
+
+// Name returns "proto" so that the content-subtype of requests and responses
+// matches that of clients using the generated Envoy messages.
+func (Codec) Name() string {
+	return "proto"
+}
+
+// Marshal implements encoding.Codec.
+func (Codec) Marshal(v any) ([]byte, error) {
+	switch m := v.(type) {
+	case message:
+		return m.Marshal(), nil
+	case proto.Message:
+		return proto.Marshal(m)
+	}
+	return nil, fmt.Errorf("cannot marshal %T, not a message from this package or a proto.Message", v)
+}
+
+// Unmarshal implements encoding.Codec.
+func (Codec) Unmarshal(data []byte, v any) error {
+	switch m := v.(type) {
+	case message:
+		return m.Unmarshal(data)
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	}
+	return fmt.Errorf("cannot unmarshal into %T, not a message from this package or a proto.Message", v)
+}
@@ -0,0 +1,198 @@
+// Package envoy implements the Envoy rate limit service protocol
+// (envoy.service.ratelimit.v3) on top of a ratelimits.Limiter, so that
+// services other than boulder, such as an edge proxy, can consult the same
+// buckets and policies.
+package envoy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/letsencrypt/boulder/ratelimits"
+)
+
+// rateLimitServiceServer is the server API for the
+// envoy.service.ratelimit.v3.RateLimitService service.
+type rateLimitServiceServer interface {
+	ShouldRateLimit(context.Context, *RateLimitRequest) (*RateLimitResponse, error)
+}
+
+// ServiceDesc is the grpc.ServiceDesc for the
+// envoy.service.ratelimit.v3.RateLimitService service. The server it is
+// registered with must be created with grpc.ForceServerCodec(Codec{}).
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "envoy.service.ratelimit.v3.RateLimitService",
+	HandlerType: (*rateLimitServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ShouldRateLimit",
+			Handler:    shouldRateLimitHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "envoy/service/ratelimit/v3/rls.proto",
+}
+
+// ShouldRateLimitFullMethodName is the full name of the ShouldRateLimit method.
+const ShouldRateLimitFullMethodName = "/envoy.service.ratelimit.v3.RateLimitService/ShouldRateLimit"
+
+func shouldRateLimitHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateLimitRequest)
+	err := dec(in)
+	if err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(rateLimitServiceServer).ShouldRateLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShouldRateLimitFullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(rateLimitServiceServer).ShouldRateLimit(ctx, req.(*RateLimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Server implements the envoy.service.ratelimit.v3.RateLimitService service.
+// Each descriptor of a request is mapped to a bucket by the first of its
+// entries whose key is configured: the key selects the limit and the value is
+// the id of the bucket, formatted as it would be in an overrides file.
+// Descriptors with no configured entry are always allowed, as they are by
+// Envoy's reference implementation.
+type Server struct {
+	limiter     ratelimits.TransactionLimiter
+	builder     *ratelimits.TransactionBuilder
+	domain      string
+	descriptors map[string]ratelimits.Name
+}
+
+var _ rateLimitServiceServer = (*Server)(nil)
+
+// NewServer returns a new *Server. The provided descriptors map descriptor
+// entry keys to the names of the limits they select, e.g. "remote_address" to
+// "NewRegistrationsPerIPAddress". If domain is not empty, requests for any
+// other domain are rejected.
+func NewServer(limiter ratelimits.TransactionLimiter, builder *ratelimits.TransactionBuilder, domain string, descriptors map[string]string) (*Server, error) {
+	if len(descriptors) == 0 {
+		return nil, errors.New("at least one descriptor must be configured")
+	}
+	names := make(map[string]ratelimits.Name, len(descriptors))
+	for key, nameStr := range descriptors {
+		name, err := ratelimits.NameFromString(nameStr)
+		if err != nil {
+			return nil, fmt.Errorf("descriptor %q: %w", key, err)
+		}
+		names[key] = name
+	}
+	return &Server{
+		limiter:     limiter,
+		builder:     builder,
+		domain:      domain,
+		descriptors: names,
+	}, nil
+}
+
+// ShouldRateLimit spends the cost of the request from the bucket of each
+// descriptor. The overall code is OVER_LIMIT if any descriptor is over its
+// limit, in which case the cost spent from the buckets of the other
+// descriptors is refunded, so that a denied request consumes no capacity.
+func (s *Server) ShouldRateLimit(ctx context.Context, req *RateLimitRequest) (*RateLimitResponse, error) {
+	if s.domain != "" && req.Domain != s.domain {
+		return nil, status.Errorf(codes.InvalidArgument, "unrecognized domain %q", req.Domain)
+	}
+	if len(req.Descriptors) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no descriptors provided")
+	}
+
+	resp := &RateLimitResponse{OverallCode: CodeOK}
+	var spent []ratelimits.Transaction
+	for _, descriptor := range req.Descriptors {
+		descStatus, txn, err := s.spendDescriptor(ctx, req, descriptor)
+		if err != nil {
+			s.refund(ctx, spent)
+			return nil, err
+		}
+		if descStatus.Code == CodeOverLimit {
+			resp.OverallCode = CodeOverLimit
+		} else if txn != nil {
+			spent = append(spent, *txn)
+		}
+		resp.Statuses = append(resp.Statuses, descStatus)
+	}
+	if resp.OverallCode == CodeOverLimit {
+		s.refund(ctx, spent)
+	}
+	return resp, nil
+}
+
+// refund makes a best-effort attempt to refund the provided Transactions,
+// which were spent for a request that won't be served. An error is ignored,
+// as the request has already been decided and the buckets will refill on
+// their own.
+func (s *Server) refund(ctx context.Context, txns []ratelimits.Transaction) {
+	if len(txns) == 0 {
+		return
+	}
+	_, _ = s.limiter.BatchRefund(ctx, txns)
+}
+
+// spendDescriptor spends the cost of the request from the bucket of the
+// provided descriptor and returns its status. If the cost was spent, the
+// Transaction which spent it is also returned, so that it can be refunded.
+func (s *Server) spendDescriptor(ctx context.Context, req *RateLimitRequest, descriptor RateLimitDescriptor) (DescriptorStatus, *ratelimits.Transaction, error) {
+	var name ratelimits.Name
+	var id string
+	for _, entry := range descriptor.Entries {
+		n, ok := s.descriptors[entry.Key]
+		if ok {
+			name, id = n, entry.Value
+			break
+		}
+	}
+	if name == ratelimits.Unknown {
+		return DescriptorStatus{Code: CodeOK}, nil, nil
+	}
+
+	cost := int64(1)
+	if descriptor.HitsAddend != 0 {
+		cost = int64(min(descriptor.HitsAddend, math.MaxInt64))
+	} else if req.HitsAddend != 0 {
+		cost = int64(req.HitsAddend)
+	}
+
+	txn, err := s.builder.TransactionForId(name, id, cost)
+	if err != nil {
+		if errors.Is(err, ratelimits.ErrInvalidCostOverLimit) {
+			// The cost can never be satisfied.
+			return DescriptorStatus{Code: CodeOverLimit}, nil, nil
+		}
+		return DescriptorStatus{}, nil, status.Errorf(codes.InvalidArgument, "%s: %s", name, err)
+	}
+	d, err := s.limiter.Spend(ctx, txn)
+	if err != nil {
+		return DescriptorStatus{}, nil, status.Errorf(codes.Unavailable, "%s: %s", name, err)
+	}
+
+	// Disabled limits have unlimited capacity, so remaining is clamped.
+	remaining := uint32(min(max(d.Remaining, 0), math.MaxUint32))
+	if !d.Allowed {
+		return DescriptorStatus{
+			Code:               CodeOverLimit,
+			LimitRemaining:     remaining,
+			DurationUntilReset: d.RetryIn,
+		}, nil, nil
+	}
+	return DescriptorStatus{
+		Code:               CodeOK,
+		LimitRemaining:     remaining,
+		DurationUntilReset: d.ResetIn,
+	}, &txn, nil
+}
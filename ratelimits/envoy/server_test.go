@@ -0,0 +1,212 @@
+package envoy
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/letsencrypt/boulder/ratelimits"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func newTestServer(t *testing.T, domain string) *Server {
+	t.Helper()
+	limiter, err := ratelimits.NewLimiter(clock.NewFake(), ratelimits.NewInmemSource(), prometheus.NewRegistry())
+	test.AssertNotError(t, err, "creating limiter")
+	builder, err := ratelimits.NewTransactionBuilder("../testdata/working_default.yml", "", "../testdata/working_override.yml", "")
+	test.AssertNotError(t, err, "creating transaction builder")
+	s, err := NewServer(limiter, builder, domain, map[string]string{
+		"remote_address": "NewRegistrationsPerIPAddress",
+		"account":        "NewOrdersPerAccount",
+	})
+	test.AssertNotError(t, err, "creating server")
+	return s
+}
+
+func ipDescriptor(ip string) RateLimitDescriptor {
+	return RateLimitDescriptor{Entries: []Entry{{Key: "remote_address", Value: ip}}}
+}
+
+func TestNewServer(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewServer(nil, nil, "", nil)
+	test.AssertError(t, err, "no descriptors should be rejected")
+
+	_, err = NewServer(nil, nil, "", map[string]string{"remote_address": "NotALimit"})
+	test.AssertError(t, err, "unknown limit name should be rejected")
+}
+
+func TestShouldRateLimit(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t, "boulder")
+	ctx := context.Background()
+
+	// The request domain must match.
+	_, err := s.ShouldRateLimit(ctx, &RateLimitRequest{Domain: "other", Descriptors: []RateLimitDescriptor{ipDescriptor("10.0.0.1")}})
+	test.AssertEquals(t, status.Code(err), codes.InvalidArgument)
+
+	// Spend 19 of the 20 requests allowed by the default limit.
+	resp, err := s.ShouldRateLimit(ctx, &RateLimitRequest{
+		Domain:      "boulder",
+		Descriptors: []RateLimitDescriptor{ipDescriptor("10.0.0.1")},
+		HitsAddend:  19,
+	})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, resp.OverallCode, CodeOK)
+	test.AssertEquals(t, len(resp.Statuses), 1)
+	test.AssertEquals(t, resp.Statuses[0].LimitRemaining, uint32(1))
+	test.AssertEquals(t, resp.Statuses[0].DurationUntilReset, 950*time.Millisecond)
+
+	// The descriptor's own hits addend takes precedence over the request's.
+	// The unconfigured descriptor is allowed.
+	over := ipDescriptor("10.0.0.1")
+	over.HitsAddend = 2
+	resp, err = s.ShouldRateLimit(ctx, &RateLimitRequest{
+		Domain: "boulder",
+		Descriptors: []RateLimitDescriptor{
+			{Entries: []Entry{{Key: "unconfigured", Value: "foo"}}},
+			over,
+		},
+		HitsAddend: 1,
+	})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, resp.OverallCode, CodeOverLimit)
+	test.AssertEquals(t, resp.Statuses[0].Code, CodeOK)
+	test.AssertEquals(t, resp.Statuses[1].Code, CodeOverLimit)
+	test.AssertEquals(t, resp.Statuses[1].LimitRemaining, uint32(1))
+	test.AssertEquals(t, resp.Statuses[1].DurationUntilReset, 50*time.Millisecond)
+
+	// The override for 10.0.0.2 has a burst of 40.
+	resp, err = s.ShouldRateLimit(ctx, &RateLimitRequest{
+		Domain:      "boulder",
+		Descriptors: []RateLimitDescriptor{ipDescriptor("10.0.0.2")},
+		HitsAddend:  30,
+	})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, resp.OverallCode, CodeOK)
+	test.AssertEquals(t, resp.Statuses[0].LimitRemaining, uint32(10))
+
+	// A cost above the burst can never be satisfied.
+	resp, err = s.ShouldRateLimit(ctx, &RateLimitRequest{
+		Domain:      "boulder",
+		Descriptors: []RateLimitDescriptor{ipDescriptor("10.0.0.3")},
+		HitsAddend:  21,
+	})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, resp.OverallCode, CodeOverLimit)
+
+	// When any descriptor is over its limit, the cost spent from the buckets
+	// of the others is refunded.
+	resp, err = s.ShouldRateLimit(ctx, &RateLimitRequest{
+		Domain:      "boulder",
+		Descriptors: []RateLimitDescriptor{ipDescriptor("10.0.0.4"), ipDescriptor("10.0.0.1")},
+		HitsAddend:  5,
+	})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, resp.OverallCode, CodeOverLimit)
+	test.AssertEquals(t, resp.Statuses[0].Code, CodeOK)
+	test.AssertEquals(t, resp.Statuses[0].LimitRemaining, uint32(15))
+	test.AssertEquals(t, resp.Statuses[1].Code, CodeOverLimit)
+	resp, err = s.ShouldRateLimit(ctx, &RateLimitRequest{
+		Domain:      "boulder",
+		Descriptors: []RateLimitDescriptor{ipDescriptor("10.0.0.4")},
+		HitsAddend:  20,
+	})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, resp.OverallCode, CodeOK)
+	test.AssertEquals(t, resp.Statuses[0].LimitRemaining, uint32(0))
+
+	// NewOrdersPerAccount has no default, so it's disabled.
+	resp, err = s.ShouldRateLimit(ctx, &RateLimitRequest{
+		Domain:      "boulder",
+		Descriptors: []RateLimitDescriptor{{Entries: []Entry{{Key: "account", Value: "1234"}}}},
+	})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, resp.OverallCode, CodeOK)
+
+	// Invalid ids are rejected.
+	_, err = s.ShouldRateLimit(ctx, &RateLimitRequest{
+		Domain:      "boulder",
+		Descriptors: []RateLimitDescriptor{ipDescriptor("not-an-ip")},
+	})
+	test.AssertEquals(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	req := &RateLimitRequest{
+		Domain: "boulder",
+		Descriptors: []RateLimitDescriptor{
+			{Entries: []Entry{{Key: "remote_address", Value: "10.0.0.1"}, {Key: "path", Value: "/acme"}}},
+			{Entries: []Entry{{Key: "account", Value: "1234"}}, HitsAddend: 5},
+		},
+		HitsAddend: 2,
+	}
+	var gotReq RateLimitRequest
+	err := gotReq.Unmarshal(req.Marshal())
+	test.AssertNotError(t, err, "unmarshalling request")
+	test.AssertDeepEquals(t, &gotReq, req)
+
+	resp := &RateLimitResponse{
+		OverallCode: CodeOverLimit,
+		Statuses: []DescriptorStatus{
+			{Code: CodeOK, LimitRemaining: 7, DurationUntilReset: 1500 * time.Millisecond},
+			{Code: CodeOverLimit, CurrentLimit: &RateLimit{Name: "limit", RequestsPerUnit: 20, Unit: UnitSecond}},
+		},
+	}
+	var gotResp RateLimitResponse
+	err = gotResp.Unmarshal(resp.Marshal())
+	test.AssertNotError(t, err, "unmarshalling response")
+	test.AssertDeepEquals(t, &gotResp, resp)
+
+	err = gotReq.Unmarshal([]byte{0x0a, 0x05, 'a'})
+	test.AssertError(t, err, "truncated message should not unmarshal")
+
+	_, err = Codec{}.Marshal("not a message")
+	test.AssertError(t, err, "non-message should not marshal")
+}
+
+func TestServiceDesc(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	test.AssertNotError(t, err, "listening")
+	server := grpc.NewServer(grpc.ForceServerCodec(Codec{}))
+	server.RegisterService(&ServiceDesc, newTestServer(t, ""))
+	// Codec must also serve the health service, whose messages are protos.
+	server.RegisterService(&healthpb.Health_ServiceDesc, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(Codec{})),
+	)
+	test.AssertNotError(t, err, "dialing")
+	defer conn.Close()
+
+	var resp RateLimitResponse
+	err = conn.Invoke(context.Background(), ShouldRateLimitFullMethodName, &RateLimitRequest{
+		Domain:      "any",
+		Descriptors: []RateLimitDescriptor{ipDescriptor("10.0.0.1")},
+	}, &resp)
+	test.AssertNotError(t, err, "invoking ShouldRateLimit")
+	test.AssertEquals(t, resp.OverallCode, CodeOK)
+	test.AssertEquals(t, resp.Statuses[0].LimitRemaining, uint32(19))
+
+	healthResp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	test.AssertNotError(t, err, "checking health")
+	test.AssertEquals(t, healthResp.Status, healthpb.HealthCheckResponse_SERVING)
+}
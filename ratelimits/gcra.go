@@ -1,9 +1,12 @@
 package ratelimits
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/config"
 )
 
 // maybeSpend uses the GCRA algorithm to decide whether to allow a request. It
@@ -108,3 +111,214 @@ func maybeRefund(clk clock.Clock, rl limit, tat time.Time, cost int64) *Decision
 		newTAT:    time.Unix(0, newTAT).UTC(),
 	}
 }
+
+// GCRAParams are the parameters of a limit, as they would appear in a defaults
+// or overrides file. They are used by the exported GCRA functions below, which
+// allow implementations of Source that evaluate the GCRA server-side, e.g. in a
+// script, to verify that they match this reference implementation.
+type GCRAParams struct {
+	Burst  int64
+	Count  int64
+	Period time.Duration
+}
+
+// limit validates the GCRAParams and returns them as a precomputed limit.
+func (p GCRAParams) limit() (limit, error) {
+	l := limit{Burst: p.Burst, Count: p.Count, Period: config.Duration{Duration: p.Period}}
+	err := validateLimit(l)
+	if err != nil {
+		return limit{}, err
+	}
+	return precomputeLimit(l), nil
+}
+
+// validateGCRACost returns an error if the cost is outside of [0, burst].
+func validateGCRACost(rl limit, cost int64) error {
+	if cost < 0 {
+		return ErrInvalidCost
+	}
+	if cost > rl.Burst {
+		return ErrInvalidCostOverLimit
+	}
+	return nil
+}
+
+// GCRAResult is the result of GCRASpend or GCRARefund. Results are comparable
+// with ==, so a Source may compare its own result directly against the
+// reference.
+type GCRAResult struct {
+	Allowed   bool
+	Remaining int64
+	RetryIn   time.Duration
+	ResetIn   time.Duration
+
+	// TAT is the theoretical arrival time which the bucket should be stored
+	// with, in UTC. It should only be stored if Allowed is true.
+	TAT time.Time
+}
+
+func resultFromDecision(d *Decision) GCRAResult {
+	return GCRAResult{
+		Allowed:   d.Allowed,
+		Remaining: d.Remaining,
+		RetryIn:   d.RetryIn,
+		ResetIn:   d.ResetIn,
+		TAT:       d.newTAT,
+	}
+}
+
+// GCRASpend returns the result of attempting to spend cost from a bucket with
+// the provided TAT at time now. It is the same computation used by the Check
+// and Spend methods of Limiter. The cost must be 0 or greater and <= the burst
+// of the limit.
+func GCRASpend(p GCRAParams, now, tat time.Time, cost int64) (GCRAResult, error) {
+	rl, err := p.limit()
+	if err != nil {
+		return GCRAResult{}, err
+	}
+	err = validateGCRACost(rl, cost)
+	if err != nil {
+		return GCRAResult{}, err
+	}
+	clk := clock.NewFake()
+	clk.Set(now)
+	return resultFromDecision(maybeSpend(clk, rl, tat, cost)), nil
+}
+
+// GCRARefund returns the result of attempting to refund cost to a bucket with
+// the provided TAT at time now. It is the same computation used by the Refund
+// method of Limiter. The cost must be 0 or greater and <= the burst of the
+// limit.
+func GCRARefund(p GCRAParams, now, tat time.Time, cost int64) (GCRAResult, error) {
+	rl, err := p.limit()
+	if err != nil {
+		return GCRAResult{}, err
+	}
+	err = validateGCRACost(rl, cost)
+	if err != nil {
+		return GCRAResult{}, err
+	}
+	clk := clock.NewFake()
+	clk.Set(now)
+	return resultFromDecision(maybeRefund(clk, rl, tat, cost)), nil
+}
+
+// CheckTATBounds returns an error if the provided TAT could not have been
+// produced by spending from a bucket of the limit, i.e. if it is later than now
+// plus the time it takes for an empty bucket to refill.
+func CheckTATBounds(p GCRAParams, now, tat time.Time) error {
+	rl, err := p.limit()
+	if err != nil {
+		return err
+	}
+	maxTAT := now.UnixNano() + rl.burstOffset
+	if tat.UnixNano() > maxTAT {
+		return fmt.Errorf("TAT %s exceeds the maximum TAT %s", tat.UTC(), time.Unix(0, maxTAT).UTC())
+	}
+	return nil
+}
+
+// checkResultBounds returns an error if the Remaining of the provided result
+// is outside of [0, burst] or, if allowed, the TAT of the result is earlier
+// than now or violates CheckTATBounds.
+func checkResultBounds(p GCRAParams, now time.Time, r GCRAResult) error {
+	if r.Remaining < 0 || r.Remaining > p.Burst {
+		return fmt.Errorf("remaining %d is outside of [0, %d]", r.Remaining, p.Burst)
+	}
+	if !r.Allowed {
+		return nil
+	}
+	if r.TAT.Before(now) {
+		return fmt.Errorf("TAT %s is earlier than now %s", r.TAT, now.UTC())
+	}
+	return CheckTATBounds(p, now, r.TAT)
+}
+
+// CheckRefundWithinCapacity returns an error if refunding cost to a bucket with
+// the provided TAT at time now would leave the bucket with more than its burst
+// capacity.
+func CheckRefundWithinCapacity(p GCRAParams, now, tat time.Time, cost int64) error {
+	r, err := GCRARefund(p, now, tat, cost)
+	if err != nil {
+		return err
+	}
+	err = checkResultBounds(p, now, r)
+	if err != nil {
+		return fmt.Errorf("refunding %d: %w", cost, err)
+	}
+	return nil
+}
+
+// CheckSpendRefundIdentity returns an error if spending cost from a bucket with
+// the provided TAT at time now, and then immediately refunding the same cost,
+// doesn't return the bucket to the capacity it had before the spend. If the
+// spend is denied, nothing is spent and no error is returned.
+func CheckSpendRefundIdentity(p GCRAParams, now, tat time.Time, cost int64) error {
+	spent, err := GCRASpend(p, now, tat, cost)
+	if err != nil {
+		return err
+	}
+	if !spent.Allowed {
+		return nil
+	}
+	refunded, err := GCRARefund(p, now, spent.TAT, cost)
+	if err != nil {
+		return err
+	}
+	// A TAT in the past is equivalent to a full bucket, which is represented
+	// by a TAT of now.
+	want := tat.UTC()
+	if want.Before(now) {
+		want = now.UTC()
+	}
+	if !refunded.TAT.Equal(want) {
+		return fmt.Errorf("spending and refunding %d produced TAT %s, expected %s", cost, refunded.TAT, want)
+	}
+	return nil
+}
+
+// CheckGCRAInvariants returns an error if any of the invariants of the GCRA
+// are violated for spending or refunding cost from a bucket with the provided
+// TAT at time now. The TAT must satisfy CheckTATBounds. The invariants are:
+//   - the Remaining of a spend or refund is within [0, burst],
+//   - an allowed spend or refund produces a TAT within the bounds of
+//     CheckTATBounds,
+//   - an allowed spend deducts exactly the cost, and a denied spend leaves the
+//     bucket unchanged,
+//   - the invariants of CheckRefundWithinCapacity and CheckSpendRefundIdentity.
+func CheckGCRAInvariants(p GCRAParams, now, tat time.Time, cost int64) error {
+	err := CheckTATBounds(p, now, tat)
+	if err != nil {
+		return err
+	}
+	rl, err := p.limit()
+	if err != nil {
+		return err
+	}
+
+	spent, err := GCRASpend(p, now, tat, cost)
+	if err != nil {
+		return err
+	}
+	err = checkResultBounds(p, now, spent)
+	if err != nil {
+		return fmt.Errorf("spending %d: %w", cost, err)
+	}
+	start := tat.UnixNano()
+	if start < now.UnixNano() {
+		start = now.UnixNano()
+	}
+	want := start
+	if spent.Allowed {
+		want += rl.emissionInterval * cost
+	}
+	if spent.TAT.UnixNano() != want {
+		return fmt.Errorf("spending %d (allowed: %t) produced TAT %s, expected %s", cost, spent.Allowed, spent.TAT, time.Unix(0, want).UTC())
+	}
+
+	err = CheckRefundWithinCapacity(p, now, tat, cost)
+	if err != nil {
+		return err
+	}
+	return CheckSpendRefundIdentity(p, now, tat, cost)
+}
@@ -1,6 +1,8 @@
 package ratelimits
 
 import (
+	"fmt"
+	"math/rand"
 	"testing"
 	"time"
 
@@ -225,3 +227,63 @@ func TestMaybeRefund(t *testing.T) {
 	test.AssertEquals(t, d.RetryIn, time.Duration(0))
 	test.AssertEquals(t, d.ResetIn, time.Duration(0))
 }
+
+func TestGCRASpendAndRefund(t *testing.T) {
+	p := GCRAParams{Burst: 10, Count: 1, Period: time.Second}
+	now := time.Unix(0, 0).UTC()
+
+	r, err := GCRASpend(p, now, now, 10)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, r, GCRAResult{Allowed: true, RetryIn: time.Second * 10, ResetIn: time.Second * 10, TAT: now.Add(time.Second * 10)})
+
+	r, err = GCRARefund(p, now, r.TAT, 3)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, r, GCRAResult{Allowed: true, Remaining: 3, ResetIn: time.Second * 7, TAT: now.Add(time.Second * 7)})
+
+	_, err = GCRASpend(p, now, now, 11)
+	test.AssertErrorIs(t, err, ErrInvalidCostOverLimit)
+	_, err = GCRARefund(p, now, now, -1)
+	test.AssertErrorIs(t, err, ErrInvalidCost)
+	_, err = GCRASpend(GCRAParams{Burst: 10, Count: 0, Period: time.Second}, now, now, 1)
+	test.AssertError(t, err, "count of 0 should error")
+	_, err = GCRASpend(GCRAParams{Burst: 10, Count: 10, Period: time.Nanosecond}, now, now, 1)
+	test.AssertError(t, err, "emission interval of 0 should error")
+}
+
+func TestCheckTATBounds(t *testing.T) {
+	p := GCRAParams{Burst: 10, Count: 1, Period: time.Second}
+	now := time.Unix(0, 0).UTC()
+
+	test.AssertNotError(t, CheckTATBounds(p, now, now.Add(-time.Hour)), "TAT in the past should be valid")
+	test.AssertNotError(t, CheckTATBounds(p, now, now.Add(time.Second*10)), "TAT of an empty bucket should be valid")
+	test.AssertError(t, CheckTATBounds(p, now, now.Add(time.Second*10+1)), "TAT beyond an empty bucket should be invalid")
+}
+
+func TestCheckGCRAInvariants(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0).UTC()
+	paramsList := []GCRAParams{
+		{Burst: 1, Count: 1, Period: time.Second},
+		{Burst: 10, Count: 1, Period: time.Second},
+		{Burst: 20, Count: 20, Period: time.Second},
+		{Burst: 3, Count: 7, Period: time.Hour},
+		{Burst: 300, Count: 300, Period: time.Hour * 3},
+	}
+
+	// Exercise each limit with TATs in the past, at now, and throughout the
+	// bucket's capacity, and costs throughout its burst.
+	rng := rand.New(rand.NewSource(1))
+	for _, p := range paramsList {
+		burstOffset := int64(p.Period) / p.Count * p.Burst
+		for i := 0; i < 500; i++ {
+			tat := now.Add(time.Duration(rng.Int63n(2*burstOffset+1) - burstOffset))
+			cost := rng.Int63n(p.Burst + 1)
+			err := CheckGCRAInvariants(p, now, tat, cost)
+			test.AssertNotError(t, err, fmt.Sprintf("params %+v, TAT %s, cost %d", p, tat, cost))
+		}
+	}
+
+	// A TAT beyond the bounds of the limit is rejected.
+	p := GCRAParams{Burst: 10, Count: 1, Period: time.Second}
+	err := CheckGCRAInvariants(p, now, now.Add(time.Minute), 1)
+	test.AssertError(t, err, "out of bounds TAT should error")
+}
@@ -0,0 +1,262 @@
+package ratelimits
+
+import (
+	"cmp"
+	"hash/maphash"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmhodges/clock"
+)
+
+const (
+	// sketchDepth and sketchWidth are the number of rows and the number of
+	// counters per row of the count-min sketches used by heavyHitters. The
+	// estimated count of a key exceeds its true count by at most
+	// 2/sketchWidth of all denials with a probability of 1-(1/2)^sketchDepth.
+	sketchDepth = 4
+	sketchWidth = 2048
+
+	// heavyHittersSize is the number of candidate bucket keys retained by
+	// heavyHitters.
+	heavyHittersSize = 100
+
+	// heavyHittersShards is the number of independently locked shards the
+	// candidate bucket keys of heavyHitters are spread across.
+	heavyHittersShards = 16
+
+	// heavyHittersWindow is the period over which heavyHitters counts
+	// denials. Denials are counted for the current and the previous window, so
+	// they age out after between one and two windows.
+	heavyHittersWindow = time.Hour
+)
+
+// HeavyHitter is a bucket key and its estimated number of denials.
+type HeavyHitter struct {
+	// Limit is the name of the limit of the bucket.
+	Limit string `json:"limit"`
+
+	// BucketKey is the key of the bucket, formatted as 'enum:id'.
+	BucketKey string `json:"bucketKey"`
+
+	// Denials is the estimated number of denials for the bucket in the current
+	// and previous heavyHittersWindow. It is never lower than the true number
+	// of denials in that time.
+	Denials uint64 `json:"denials"`
+}
+
+// sketchWindow is a count-min sketch of the denials in a single
+// heavyHittersWindow, identified by its epoch.
+type sketchWindow struct {
+	epoch    atomic.Int64
+	counters [sketchDepth][sketchWidth]atomic.Uint64
+}
+
+// heavyHittersShard is a bounded set of candidate bucket keys.
+type heavyHittersShard struct {
+	sync.Mutex
+
+	// top maps each candidate bucket key to its estimated number of denials
+	// as of the epoch it was last updated in. It contains at most size
+	// entries.
+	top  map[string]heavyHitter
+	size int
+}
+
+type heavyHitter struct {
+	estimate uint64
+	epoch    int64
+}
+
+// heavyHitters approximately tracks the bucket keys with the most recent
+// denials using count-min sketches, which estimate the number of denials for
+// every bucket key in constant space, and a bounded set of the bucket keys with
+// the highest estimates. Sketch counters are updated atomically and candidates
+// are spread across shards, so concurrent denials of different buckets rarely
+// contend. It is safe for concurrent use.
+type heavyHitters struct {
+	clk   clock.Clock
+	seeds [sketchDepth]maphash.Seed
+
+	// windows are the sketches of the current and the previous window,
+	// indexed by the parity of their epoch. rotateMu is held while a stale
+	// window is reset for a new epoch.
+	windows  [2]sketchWindow
+	rotateMu sync.Mutex
+
+	shards []heavyHittersShard
+}
+
+// newHeavyHitters returns a *heavyHitters which retains up to size candidate
+// bucket keys, spread across the provided number of shards.
+func newHeavyHitters(clk clock.Clock, size, shards int) *heavyHitters {
+	h := &heavyHitters{clk: clk, shards: make([]heavyHittersShard, shards)}
+	for i := range h.seeds {
+		h.seeds[i] = maphash.MakeSeed()
+	}
+	for i := range h.shards {
+		// Round up, so that the shards hold at least size candidates.
+		shardSize := (size + shards - 1) / shards
+		h.shards[i] = heavyHittersShard{top: make(map[string]heavyHitter, shardSize), size: shardSize}
+	}
+	// Windows start out belonging to no epoch.
+	for i := range h.windows {
+		h.windows[i].epoch.Store(-1)
+	}
+	return h
+}
+
+func (h *heavyHitters) epoch() int64 {
+	return h.clk.Now().UnixNano() / int64(heavyHittersWindow)
+}
+
+// window returns the sketch for the provided epoch, resetting it first if it
+// holds the counts of an earlier epoch. It returns nil if it holds the counts
+// of a later one, as it would after the clock went backwards.
+func (h *heavyHitters) window(epoch int64) *sketchWindow {
+	w := &h.windows[epoch%2]
+	if w.epoch.Load() == epoch {
+		return w
+	}
+	h.rotateMu.Lock()
+	defer h.rotateMu.Unlock()
+	current := w.epoch.Load()
+	if current > epoch {
+		return nil
+	}
+	if current < epoch {
+		// Denials added concurrently by callers which still see the old epoch
+		// may be lost or carried over, which the estimates tolerate.
+		for i := range w.counters {
+			for j := range w.counters[i] {
+				w.counters[i][j].Store(0)
+			}
+		}
+		w.epoch.Store(epoch)
+	}
+	return w
+}
+
+// indexes returns the counter of each sketch row for the provided bucket key.
+func (h *heavyHitters) indexes(bucketKey string) [sketchDepth]uint64 {
+	var idx [sketchDepth]uint64
+	for i := range idx {
+		idx[i] = maphash.String(h.seeds[i], bucketKey) % sketchWidth
+	}
+	return idx
+}
+
+// previousCount returns the count of the provided counter in the window before
+// the provided epoch, or 0 if that window has since been reset.
+func (h *heavyHitters) previousCount(epoch int64, row int, idx uint64) uint64 {
+	w := &h.windows[(epoch+1)%2]
+	if w.epoch.Load() != epoch-1 {
+		return 0
+	}
+	return w.counters[row][idx].Load()
+}
+
+// estimate returns the estimated number of denials for the provided bucket key
+// in the provided epoch and the one before it.
+func (h *heavyHitters) estimate(bucketKey string, epoch int64) uint64 {
+	cur := &h.windows[epoch%2]
+	curValid := cur.epoch.Load() == epoch
+	var estimate uint64
+	for i, idx := range h.indexes(bucketKey) {
+		count := h.previousCount(epoch, i, idx)
+		if curValid {
+			count += cur.counters[i][idx].Load()
+		}
+		if i == 0 || count < estimate {
+			estimate = count
+		}
+	}
+	return estimate
+}
+
+// add records a denial for the provided bucket key.
+func (h *heavyHitters) add(bucketKey string) {
+	epoch := h.epoch()
+	w := h.window(epoch)
+	if w == nil {
+		return
+	}
+
+	var estimate uint64
+	for i, idx := range h.indexes(bucketKey) {
+		count := w.counters[i][idx].Add(1) + h.previousCount(epoch, i, idx)
+		if i == 0 || count < estimate {
+			estimate = count
+		}
+	}
+
+	s := &h.shards[maphash.String(h.seeds[0], bucketKey)/sketchWidth%uint64(len(h.shards))]
+	s.Lock()
+	defer s.Unlock()
+
+	_, ok := s.top[bucketKey]
+	if ok || len(s.top) < s.size {
+		s.top[bucketKey] = heavyHitter{estimate: estimate, epoch: epoch}
+		return
+	}
+
+	// Evict the candidate with the lowest estimate if it is lower than the
+	// estimate for this bucket key. The estimates of candidates last updated
+	// in an earlier epoch include denials which may since have aged out, so
+	// they're refreshed first.
+	var minKey string
+	var minEstimate uint64
+	for k, v := range s.top {
+		if v.epoch != epoch {
+			v = heavyHitter{estimate: h.estimate(k, epoch), epoch: epoch}
+			s.top[k] = v
+		}
+		if minKey == "" || v.estimate < minEstimate {
+			minKey, minEstimate = k, v.estimate
+		}
+	}
+	if estimate > minEstimate {
+		delete(s.top, minKey)
+		s.top[bucketKey] = heavyHitter{estimate: estimate, epoch: epoch}
+	}
+}
+
+// topN returns up to n bucket keys with the highest estimated number of
+// denials in the current and previous window, in descending order.
+func (h *heavyHitters) topN(n int) []HeavyHitter {
+	var keys []string
+	for i := range h.shards {
+		s := &h.shards[i]
+		s.Lock()
+		for k := range s.top {
+			keys = append(keys, k)
+		}
+		s.Unlock()
+	}
+
+	epoch := h.epoch()
+	hitters := make([]HeavyHitter, 0, len(keys))
+	for _, k := range keys {
+		estimate := h.estimate(k, epoch)
+		if estimate == 0 {
+			// All of its denials have aged out.
+			continue
+		}
+		hitters = append(hitters, HeavyHitter{Limit: nameForBucketKey(k).String(), BucketKey: k, Denials: estimate})
+	}
+
+	slices.SortFunc(hitters, func(a, b HeavyHitter) int {
+		c := cmp.Compare(b.Denials, a.Denials)
+		if c != 0 {
+			return c
+		}
+		return strings.Compare(a.BucketKey, b.BucketKey)
+	})
+	if len(hitters) > n {
+		hitters = hitters[:n]
+	}
+	return hitters
+}
@@ -0,0 +1,121 @@
+package ratelimits
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func heavyHittersTestKey(i int) string {
+	return joinWithColon(NewRegistrationsPerIPAddress.EnumString(), fmt.Sprintf("10.0.0.%d", i))
+}
+
+func TestHeavyHitters(t *testing.T) {
+	t.Parallel()
+	h := newHeavyHitters(clock.NewFake(), 3, 1)
+	key := heavyHittersTestKey
+
+	// Bucket key i is denied i times.
+	for i := 1; i <= 5; i++ {
+		for j := 0; j < i; j++ {
+			h.add(key(i))
+		}
+	}
+
+	top := h.topN(10)
+	test.AssertEquals(t, len(top), 3)
+	test.AssertDeepEquals(t, top[0], HeavyHitter{Limit: NewRegistrationsPerIPAddress.String(), BucketKey: key(5), Denials: 5})
+	test.AssertEquals(t, top[1].BucketKey, key(4))
+	test.AssertEquals(t, top[2].BucketKey, key(3))
+
+	top = h.topN(1)
+	test.AssertEquals(t, len(top), 1)
+	test.AssertEquals(t, top[0].BucketKey, key(5))
+
+	// A bucket key which overtakes a candidate replaces it.
+	for j := 0; j < 10; j++ {
+		h.add(key(1))
+	}
+	top = h.topN(10)
+	test.AssertEquals(t, top[0].BucketKey, key(1))
+	test.AssertEquals(t, top[0].Denials, uint64(11))
+}
+
+func TestHeavyHittersDecay(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	h := newHeavyHitters(clk, 2, 1)
+	key := heavyHittersTestKey
+
+	for j := 0; j < 10; j++ {
+		h.add(key(1))
+	}
+	h.add(key(2))
+
+	// Denials in the previous window still count.
+	clk.Add(heavyHittersWindow)
+	h.add(key(1))
+	top := h.topN(10)
+	test.AssertEquals(t, len(top), 2)
+	test.AssertEquals(t, top[0].BucketKey, key(1))
+	test.AssertEquals(t, top[0].Denials, uint64(11))
+
+	// Denials from two windows ago have aged out, so a bucket key with fewer
+	// denials overall, but more recent ones, displaces a stale candidate.
+	clk.Add(heavyHittersWindow)
+	h.add(key(3))
+	h.add(key(3))
+	top = h.topN(10)
+	test.AssertEquals(t, len(top), 2)
+	test.AssertDeepEquals(t, top[0], HeavyHitter{Limit: NewRegistrationsPerIPAddress.String(), BucketKey: key(3), Denials: 2})
+	test.AssertEquals(t, top[1].BucketKey, key(1))
+	test.AssertEquals(t, top[1].Denials, uint64(1))
+
+	// Once every denial has aged out, nothing is returned.
+	clk.Add(2 * heavyHittersWindow)
+	test.AssertEquals(t, len(h.topN(10)), 0)
+}
+
+func TestHeavyHittersConcurrent(t *testing.T) {
+	t.Parallel()
+	h := newHeavyHitters(clock.NewFake(), heavyHittersSize, heavyHittersShards)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				h.add(heavyHittersTestKey(j % 10))
+			}
+		}()
+	}
+	wg.Wait()
+
+	top := h.topN(10)
+	test.AssertEquals(t, len(top), 10)
+	for _, hitter := range top {
+		// Estimates never undercount.
+		test.Assert(t, hitter.Denials >= 800, fmt.Sprintf("%s has %d denials, want at least 800", hitter.BucketKey, hitter.Denials))
+	}
+}
+
+func BenchmarkHeavyHittersAdd(b *testing.B) {
+	h := newHeavyHitters(clock.New(), heavyHittersSize, heavyHittersShards)
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = heavyHittersTestKey(i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			h.add(keys[i%len(keys)])
+			i++
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package ratelimits
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/letsencrypt/boulder/config"
+)
+
+// InternalLimit is a limit which a Boulder component configures in code, from
+// its own configuration, to pace its requests to another service, rather than
+// being loaded from a defaults or overrides file. Transactions for it are
+// spent with a Limiter like those of any other limit. Call NewInternalLimit to
+// create a new *InternalLimit.
+type InternalLimit struct {
+	limit limit
+}
+
+// NewInternalLimit returns a new *InternalLimit for the provided internal limit
+// name, allowing count requests per period with the provided burst. The burst,
+// count, and period must meet the same requirements as those of a default
+// limit.
+func NewInternalLimit(name Name, burst, count int64, period time.Duration) (*InternalLimit, error) {
+	if !name.isValid() || !name.isInternal() {
+		return nil, fmt.Errorf("%s is not an internal limit", name)
+	}
+	l := limit{
+		Burst:  burst,
+		Count:  count,
+		Period: config.Duration{Duration: period},
+		name:   name,
+	}
+	err := validateLimit(l)
+	if err != nil {
+		return nil, fmt.Errorf("validating internal limit %s: %w", name, err)
+	}
+	return &InternalLimit{limit: precomputeLimit(l)}, nil
+}
+
+// Transaction returns a Transaction for the bucket of the limit identified by
+// the provided id, e.g. an endpoint for AkamaiPurgeURLsPerEndpoint, with the
+// provided cost.
+func (il *InternalLimit) Transaction(id string, cost int64) (Transaction, error) {
+	err := validateIdForName(il.limit.name, id)
+	if err != nil {
+		return Transaction{}, err
+	}
+	return newTransaction(il.limit, joinWithColon(il.limit.name.EnumString(), id), cost)
+}
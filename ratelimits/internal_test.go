@@ -0,0 +1,53 @@
+package ratelimits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewInternalLimit(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewInternalLimit(NewOrdersPerAccount, 10, 10, time.Second)
+	test.AssertError(t, err, "non-internal limit should be rejected")
+
+	_, err = NewInternalLimit(AkamaiPurgeURLsPerEndpoint, 0, 10, time.Second)
+	test.AssertError(t, err, "burst of 0 should be rejected")
+
+	il, err := NewInternalLimit(AkamaiPurgeURLsPerEndpoint, 10, 10, time.Second)
+	test.AssertNotError(t, err, "should not error")
+
+	_, err = il.Transaction("", 1)
+	test.AssertError(t, err, "empty endpoint should be rejected")
+	_, err = il.Transaction("akamai.example.com", 11)
+	test.AssertErrorIs(t, err, ErrInvalidCostOverLimit)
+
+	txn, err := il.Transaction("akamai.example.com", 6)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, txn.Name(), AkamaiPurgeURLsPerEndpoint)
+	test.AssertEquals(t, txn.BucketKey(), joinWithColon(AkamaiPurgeURLsPerEndpoint.EnumString(), "akamai.example.com"))
+
+	// Transactions for an internal limit are spent like any other.
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	d, err := l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, d.Remaining, int64(4))
+	d, err = l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+	test.AssertEquals(t, d.RetryIn, 200*time.Millisecond)
+
+	// Each endpoint has its own bucket.
+	other, err := il.Transaction("localhost:6789", 6)
+	test.AssertNotError(t, err, "should not error")
+	d, err = l.Spend(context.Background(), other)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+}
@@ -3,12 +3,18 @@ package ratelimits
 import (
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/letsencrypt/boulder/config"
 	"github.com/letsencrypt/boulder/core"
 	"github.com/letsencrypt/boulder/strictyaml"
+	"gopkg.in/yaml.v3"
 )
 
 // errLimitDisabled indicates that the limit name specified is valid but is not
@@ -28,6 +34,11 @@ type limit struct {
 	// allowed. It must be greater than zero.
 	Period config.Duration
 
+	// RenewalCost, if set, is the cost charged for a request which renews an
+	// existing certificate in place of the usual cost. A RenewalCost of 0
+	// exempts renewals from the limit. It must be between 0 and burst.
+	RenewalCost *int64 `yaml:"renewalCost"`
+
 	// name is the name of the limit. It must be one of the Name enums defined
 	// in this package.
 	name Name
@@ -47,6 +58,10 @@ type limit struct {
 	// isOverride is true if this limit is an override limit, false if it is a
 	// default limit.
 	isOverride bool
+
+	// remote is true if this limit is a placeholder, returned by a remote
+	// TransactionBuilder, for a limit which is resolved by a Server.
+	remote bool
 }
 
 func precomputeLimit(l limit) limit {
@@ -65,6 +80,16 @@ func validateLimit(l limit) error {
 	if l.Period.Duration <= 0 {
 		return fmt.Errorf("invalid period '%s', must be > 0", l.Period)
 	}
+	emissionInterval := l.Period.Nanoseconds() / l.Count
+	if emissionInterval == 0 {
+		return fmt.Errorf("invalid period '%s', must be >= count '%d' nanoseconds", l.Period, l.Count)
+	}
+	if l.Burst > math.MaxInt64/emissionInterval {
+		return fmt.Errorf("invalid burst '%d', burst * period / count must not exceed %s", l.Burst, time.Duration(math.MaxInt64))
+	}
+	if l.RenewalCost != nil && (*l.RenewalCost < 0 || *l.RenewalCost > l.Burst) {
+		return fmt.Errorf("invalid renewalCost '%d', must be >= 0 and <= burst", *l.RenewalCost)
+	}
 	return nil
 }
 
@@ -84,10 +109,167 @@ func loadDefaults(path string) (limits, error) {
 	return lm, nil
 }
 
+// environmentPrefix is the prefix of keys in the defaults file which define a
+// section of default limits, formatted as 'Environment:name', which only apply
+// in the named environment.
+const environmentPrefix = "Environment:"
+
+// loadDefaultsForEnvironment marshals the defaults YAML file at path into a map
+// of limits. Top-level limits apply in every environment. If environment is not
+// empty, the limits in the 'Environment:environment' section, which must exist,
+// replace any top-level limits with the same name. Sections for other
+// environments are ignored.
+func loadDefaultsForEnvironment(path, environment string) (limits, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Split the file into top-level limits and environment sections, then
+	// strictly unmarshal only the parts which apply.
+	var sections map[string]yaml.Node
+	err = strictyaml.Unmarshal(data, &sections)
+	if err != nil {
+		return nil, err
+	}
+	shared := make(map[string]yaml.Node)
+	var selected *yaml.Node
+	for k, v := range sections {
+		if !strings.HasPrefix(k, environmentPrefix) {
+			shared[k] = v
+			continue
+		}
+		if strings.TrimPrefix(k, environmentPrefix) == "" {
+			return nil, fmt.Errorf("empty name in environment %q, must be formatted 'Environment:name'", k)
+		}
+		if environment != "" && k == environmentPrefix+environment {
+			node := v
+			selected = &node
+		}
+	}
+	if environment != "" && selected == nil {
+		return nil, fmt.Errorf("no section %q found in defaults file %q", environmentPrefix+environment, path)
+	}
+
+	lm, err := strictUnmarshalLimits(shared)
+	if err != nil {
+		return nil, err
+	}
+	if selected != nil {
+		envLimits, err := strictUnmarshalLimits(selected)
+		if err != nil {
+			return nil, fmt.Errorf("parsing section %q: %w", environmentPrefix+environment, err)
+		}
+		for k, v := range envLimits {
+			lm[k] = v
+		}
+	}
+	return lm, nil
+}
+
+// strictUnmarshalLimits re-marshals the provided YAML and strictly unmarshals
+// it into a map of limits.
+func strictUnmarshalLimits(in interface{}) (limits, error) {
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	lm := make(limits)
+	err = strictyaml.Unmarshal(data, &lm)
+	if err != nil {
+		return nil, err
+	}
+	return lm, nil
+}
+
 type overrideYAML struct {
 	limit `yaml:",inline"`
 	// Ids is a list of ids that this override applies to.
 	Ids []string
+
+	// Template is the optional name of a template, defined elsewhere in the
+	// same file, to use as the starting point for this override. Any of Burst,
+	// Count, Period, or RenewalCost which are set explicitly replace the
+	// template's value.
+	Template string
+
+	// BurstMultiplier, if non-zero, multiplies the Burst of the template (or
+	// explicitly set Burst) by the specified value.
+	BurstMultiplier int64 `yaml:"burstMultiplier"`
+
+	// CountMultiplier, if non-zero, multiplies the Count of the template (or
+	// explicitly set Count) by the specified value.
+	CountMultiplier int64 `yaml:"countMultiplier"`
+}
+
+// templatePrefix is the prefix of keys in the overrides file which define a
+// named template, formatted as 'Template:name', rather than an override.
+const templatePrefix = "Template:"
+
+// parseTemplates extracts the templates defined in the overrides file into a
+// map of limits keyed by template name. Templates are only used as the starting
+// point for overrides, so they are not validated until they are applied.
+func parseTemplates(fromFile overridesYAML) (map[string]limit, error) {
+	templates := make(map[string]limit)
+	for _, ov := range fromFile {
+		for k, v := range ov {
+			if !strings.HasPrefix(k, templatePrefix) {
+				continue
+			}
+			name := strings.TrimPrefix(k, templatePrefix)
+			if name == "" {
+				return nil, fmt.Errorf("empty name in template %q, must be formatted 'Template:name'", k)
+			}
+			if len(v.Ids) != 0 || v.Template != "" || v.BurstMultiplier != 0 || v.CountMultiplier != 0 {
+				return nil, fmt.Errorf("template %q may only specify burst, count, and period", k)
+			}
+			_, ok := templates[name]
+			if ok {
+				return nil, fmt.Errorf("duplicate template %q", k)
+			}
+			templates[name] = v.limit
+		}
+	}
+	return templates, nil
+}
+
+// applyTemplate returns the limit described by the provided override, with
+// any referenced template and multipliers applied.
+func applyTemplate(templates map[string]limit, ov overrideYAML) (limit, error) {
+	l := ov.limit
+	if ov.Template != "" {
+		tmpl, ok := templates[ov.Template]
+		if !ok {
+			return limit{}, fmt.Errorf("unknown template %q", ov.Template)
+		}
+		if l.Burst == 0 {
+			l.Burst = tmpl.Burst
+		}
+		if l.Count == 0 {
+			l.Count = tmpl.Count
+		}
+		if l.Period.Duration == 0 {
+			l.Period = tmpl.Period
+		}
+		if l.RenewalCost == nil {
+			l.RenewalCost = tmpl.RenewalCost
+		}
+	}
+	if ov.BurstMultiplier < 0 || ov.CountMultiplier < 0 {
+		return limit{}, fmt.Errorf("invalid multiplier, must be >= 0")
+	}
+	if ov.BurstMultiplier != 0 {
+		if l.Burst > math.MaxInt64/ov.BurstMultiplier {
+			return limit{}, fmt.Errorf("invalid burstMultiplier '%d', burst '%d' would overflow", ov.BurstMultiplier, l.Burst)
+		}
+		l.Burst *= ov.BurstMultiplier
+	}
+	if ov.CountMultiplier != 0 {
+		if l.Count > math.MaxInt64/ov.CountMultiplier {
+			return limit{}, fmt.Errorf("invalid countMultiplier '%d', count '%d' would overflow", ov.CountMultiplier, l.Count)
+		}
+		l.Count *= ov.CountMultiplier
+	}
+	return l, nil
 }
 
 type overridesYAML []map[string]overrideYAML
@@ -122,6 +304,9 @@ func parseOverrideNameId(key string) (Name, string, error) {
 	if !ok {
 		return Unknown, "", fmt.Errorf("unrecognized name %q in override limit %q, must be one of %v", nameStr, key, limitNames)
 	}
+	if name.isInternal() {
+		return Unknown, "", fmt.Errorf("internal limit %q in override limit %q can't be overridden", nameStr, key)
+	}
 	id := nameAndId[1]
 	if id == "" {
 		return Unknown, "", fmt.Errorf("empty id in override %q, must be formatted 'name:id'", key)
@@ -129,6 +314,25 @@ func parseOverrideNameId(key string) (Name, string, error) {
 	return name, id, nil
 }
 
+// overrideKey normalizes and validates the provided override id for the limit
+// specified by name, and returns the key of the bucket it overrides. Ids which
+// differ only in their normalization, or, for CertificatesPerFQDNSet, in the
+// order of their names, return the same key.
+func overrideKey(name Name, id string) (string, error) {
+	id = normalizeIdForName(name, id)
+	err := validateIdForName(name, id)
+	if err != nil {
+		return "", fmt.Errorf("validating name %s and id %q: %w", name, id, err)
+	}
+	if name == CertificatesPerFQDNSet {
+		// FQDNSet hashes are not a nice thing to ask for in a config file, so
+		// we allow the user to specify a comma-separated list of FQDNs and
+		// compute the hash here.
+		id = fmt.Sprintf("%x", core.HashNames(strings.Split(id, ",")))
+	}
+	return joinWithColon(name.EnumString(), id), nil
+}
+
 // loadAndParseOverrideLimitsDeprecated loads override limits from YAML,
 // validates them, and parses them into a map of limits keyed by 'Name:id'.
 //
@@ -149,20 +353,17 @@ func loadAndParseOverrideLimitsDeprecated(path string) (limits, error) {
 		if err != nil {
 			return nil, fmt.Errorf("parsing override limit %q: %w", k, err)
 		}
-		err = validateIdForName(name, id)
+		key, err := overrideKey(name, id)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"validating name %s and id %q for override limit %q: %w", name, id, k, err)
-		}
-		if name == CertificatesPerFQDNSet {
-			// FQDNSet hashes are not a nice thing to ask for in a config file,
-			// so we allow the user to specify a comma-separated list of FQDNs
-			// and compute the hash here.
-			id = fmt.Sprintf("%x", core.HashNames(strings.Split(id, ",")))
+			return nil, fmt.Errorf("override limit %q: %w", k, err)
 		}
 		v.name = name
 		v.isOverride = true
-		parsed[joinWithColon(name.EnumString(), id)] = precomputeLimit(v)
+		_, ok := parsed[key]
+		if ok {
+			return nil, fmt.Errorf("duplicate id %q for override limit %q", id, k)
+		}
+		parsed[key] = precomputeLimit(v)
 	}
 	return parsed, nil
 }
@@ -171,16 +372,29 @@ func loadAndParseOverrideLimitsDeprecated(path string) (limits, error) {
 // must be formatted as a list of maps, where each map has a single key
 // representing the limit name and a value that is a map containing the limit
 // fields and an additional 'ids' field that is a list of ids that this override
-// applies to.
+// applies to. A map with a key formatted as 'Template:name' defines a named
+// template which overrides can reference using the 'template' field.
 func loadAndParseOverrideLimits(path string) (limits, error) {
 	fromFile, err := loadOverrides(path)
 	if err != nil {
 		return nil, err
 	}
+	templates, err := parseTemplates(fromFile)
+	if err != nil {
+		return nil, err
+	}
 	parsed := make(limits)
 
 	for _, ov := range fromFile {
 		for k, v := range ov {
+			if strings.HasPrefix(k, templatePrefix) {
+				// Templates are not overrides.
+				continue
+			}
+			v.limit, err = applyTemplate(templates, v)
+			if err != nil {
+				return nil, fmt.Errorf("applying template to override limit %q: %w", k, err)
+			}
 			err = validateLimit(v.limit)
 			if err != nil {
 				return nil, fmt.Errorf("validating override limit %q: %w", k, err)
@@ -189,31 +403,32 @@ func loadAndParseOverrideLimits(path string) (limits, error) {
 			if !ok {
 				return nil, fmt.Errorf("unrecognized name %q in override limit, must be one of %v", k, limitNames)
 			}
+			if name.isInternal() {
+				return nil, fmt.Errorf("internal limit %q can't be overridden", k)
+			}
 			v.limit.name = name
 			v.limit.isOverride = true
 			for _, id := range v.Ids {
-				err = validateIdForName(name, id)
+				key, err := overrideKey(name, id)
 				if err != nil {
-					return nil, fmt.Errorf(
-						"validating name %s and id %q for override limit %q: %w", name, id, k, err)
+					return nil, fmt.Errorf("override limit %q: %w", k, err)
 				}
-				if name == CertificatesPerFQDNSet {
-					// FQDNSet hashes are not a nice thing to ask for in a
-					// config file, so we allow the user to specify a
-					// comma-separated list of FQDNs and compute the hash here.
-					id = fmt.Sprintf("%x", core.HashNames(strings.Split(id, ",")))
+				_, ok := parsed[key]
+				if ok {
+					return nil, fmt.Errorf("duplicate id %q for override limit %q", id, k)
 				}
-				parsed[joinWithColon(name.EnumString(), id)] = precomputeLimit(v.limit)
+				parsed[key] = precomputeLimit(v.limit)
 			}
 		}
 	}
 	return parsed, nil
 }
 
-// loadAndParseDefaultLimits loads default limits from YAML, validates them, and
-// parses them into a map of limits keyed by 'Name'.
-func loadAndParseDefaultLimits(path string) (limits, error) {
-	fromFile, err := loadDefaults(path)
+// loadAndParseDefaultLimits loads default limits for the provided environment
+// from YAML, validates them, and parses them into a map of limits keyed by
+// 'Name'. Environment is optional.
+func loadAndParseDefaultLimits(path, environment string) (limits, error) {
+	fromFile, err := loadDefaultsForEnvironment(path, environment)
 	if err != nil {
 		return nil, err
 	}
@@ -228,46 +443,177 @@ func loadAndParseDefaultLimits(path string) (limits, error) {
 		if !ok {
 			return nil, fmt.Errorf("unrecognized name %q in default limit, must be one of %v", k, limitNames)
 		}
+		if name.isInternal() {
+			return nil, fmt.Errorf("internal limit %q can't be configured in a defaults file", k)
+		}
 		v.name = name
 		parsed[name.EnumString()] = precomputeLimit(v)
 	}
 	return parsed, nil
 }
 
-type limitRegistry struct {
-	// defaults stores default limits by 'name'.
+// exemptionsYAML is a list of maps, where each map has a single key
+// representing the limit name and a value containing the list of ACME
+// registration Ids which are exempt from that limit.
+type exemptionsYAML []map[string]struct {
+	// Ids is a list of ACME registration Ids exempt from this limit.
+	Ids []int64
+}
+
+// exemptNames are the limits which may be specified in an exemptions file.
+// Exemptions are by ACME registration Id, so only limits whose Transactions are
+// constructed with a registration Id can be exempted.
+var exemptNames = []Name{
+	NewOrdersPerAccount,
+	FailedAuthorizationsPerAccount,
+	CertificatesPerDomain,
+	CertificatesPerDomainPerAccount,
+	RevocationsPerAccount,
+	AccountUpdatesPerAccount,
+}
+
+// exemption is an ACME registration Id which is exempt from the limit specified
+// by name.
+type exemption struct {
+	name  Name
+	regId int64
+}
+
+// loadAndParseExemptions loads exemptions from YAML, validates them, and parses
+// them into a set.
+func loadAndParseExemptions(path string) (map[exemption]struct{}, error) {
+	fromFile := exemptionsYAML{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = strictyaml.Unmarshal(data, &fromFile)
+	if err != nil {
+		return nil, err
+	}
+	parsed := make(map[exemption]struct{})
+
+	for _, ex := range fromFile {
+		for k, v := range ex {
+			name, ok := stringToName[k]
+			if !ok || !slices.Contains(exemptNames, name) {
+				return nil, fmt.Errorf("invalid name %q in exemption, must be one of %v", k, exemptNames)
+			}
+			for _, regId := range v.Ids {
+				if regId <= 0 {
+					return nil, fmt.Errorf("invalid regId %d for exemption %q, must be > 0", regId, k)
+				}
+				parsed[exemption{name, regId}] = struct{}{}
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// limitTable is the compiled form of the default limits, overrides, and
+// exemptions used by a limitRegistry. Lookups against it take no locks and do
+// no string formatting. A limitTable is never modified once compiled, the
+// limits are reloaded by compiling a new limitTable and swapping it in.
+type limitTable struct {
+	// defaults stores default limits by 'enum'.
 	defaults limits
 
-	// overrides stores override limits by 'name:id'.
+	// overrides stores override limits by 'enum:id'.
 	overrides limits
+
+	// byName stores the default limit for each Name, indexed by Name. The
+	// entry for a Name without a default limit is the zero limit.
+	byName []limit
+
+	// exemptions is the set of ACME registration Ids which are exempt from
+	// each limit.
+	exemptions map[exemption]struct{}
 }
 
-func newLimitRegistry(defaults, overrides string) (*limitRegistry, error) {
+// loadLimitTable loads, validates, and compiles the default limits for the
+// provided environment, and the override limits and exemptions at the provided
+// paths. Overrides and exemptions are optional, defaults is required.
+func loadLimitTable(defaults, environment, overrides, exemptions string) (*limitTable, error) {
 	var err error
-	registry := &limitRegistry{}
-	registry.defaults, err = loadAndParseDefaultLimits(defaults)
+	table := &limitTable{
+		overrides:  make(limits),
+		byName:     make([]limit, len(nameToString)),
+		exemptions: make(map[exemption]struct{}),
+	}
+	table.defaults, err = loadAndParseDefaultLimits(defaults, environment)
 	if err != nil {
 		return nil, err
 	}
-
-	if overrides == "" {
-		// No overrides specified, initialize an empty map.
-		registry.overrides = make(limits)
-		return registry, nil
+	for _, dl := range table.defaults {
+		table.byName[dl.name] = dl
 	}
 
-	registry.overrides, err = loadAndParseOverrideLimitsDeprecated(overrides)
-	if err != nil {
-		// TODO(#7198): Leave this, remove the call above.
-		registry.overrides, err = loadAndParseOverrideLimits(overrides)
+	if exemptions != "" {
+		table.exemptions, err = loadAndParseExemptions(exemptions)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if overrides != "" {
+		table.overrides, err = loadAndParseOverrideLimitsDeprecated(overrides)
+		if err != nil {
+			// TODO(#7198): Leave this, remove the call above.
+			table.overrides, err = loadAndParseOverrideLimits(overrides)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return table, nil
+}
+
+type limitRegistry struct {
+	// The following are the paths, and environment, the limits are loaded
+	// from. See NewTransactionBuilder.
+	defaultsPath   string
+	environment    string
+	overridesPath  string
+	exemptionsPath string
+
+	// remote is true if the registry holds no limits because the Transactions
+	// built with it are sent to a Server, whose own limits apply. See
+	// NewRemoteTransactionBuilder.
+	remote bool
+
+	// table holds the limits currently in use.
+	table atomic.Pointer[limitTable]
+}
+
+func newLimitRegistry(defaults, environment, overrides, exemptions string) (*limitRegistry, error) {
+	registry := &limitRegistry{
+		defaultsPath:   defaults,
+		environment:    environment,
+		overridesPath:  overrides,
+		exemptionsPath: exemptions,
+	}
+	err := registry.reload()
+	if err != nil {
+		return nil, err
+	}
 	return registry, nil
 }
 
+// reload loads the limits from the paths the registry was created with and, if
+// they are valid, swaps them in for the limits currently in use. If they are
+// not, an error is returned and the limits in use are left unchanged.
+func (l *limitRegistry) reload() error {
+	if l.remote {
+		return nil
+	}
+	table, err := loadLimitTable(l.defaultsPath, l.environment, l.overridesPath, l.exemptionsPath)
+	if err != nil {
+		return err
+	}
+	l.table.Store(table)
+	return nil
+}
+
 // getLimit returns the limit for the specified by name and bucketKey, name is
 // required, bucketKey is optional. If bucketkey is empty, the default for the
 // limit specified by name is returned. If no default limit exists for the
@@ -278,16 +624,76 @@ func (l *limitRegistry) getLimit(name Name, bucketKey string) (limit, error) {
 		// Name enums defined in this package.
 		return limit{}, fmt.Errorf("specified name enum %q, is invalid", name)
 	}
+	if l.remote {
+		// The Server resolves the limit. Only the cost is validated against
+		// it here.
+		return limit{name: name, Burst: math.MaxInt64, remote: true}, nil
+	}
+	table := l.table.Load()
 	if bucketKey != "" {
 		// Check for override.
-		ol, ok := l.overrides[bucketKey]
+		ol, ok := table.overrides[bucketKey]
 		if ok {
 			return ol, nil
 		}
 	}
-	dl, ok := l.defaults[name.EnumString()]
-	if ok {
+	dl := table.byName[name]
+	if dl.name == name {
 		return dl, nil
 	}
 	return limit{}, errLimitDisabled
 }
+
+// ValidateLimits loads and validates the default limits for the provided
+// environment and the override limits at the provided paths in the same way
+// NewTransactionBuilder does, with additional
+// checks for overrides that would load successfully but never match. If the
+// limits are valid, a normalized view of the effective limits, one per line
+// and sorted by name, is written to w. Overrides is optional, defaults is
+// required. Exemptions is optional, if provided it is validated but not
+// included in the normalized view.
+func ValidateLimits(defaults, environment, overrides, exemptions string, w io.Writer) error {
+	table, err := loadLimitTable(defaults, environment, overrides, exemptions)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for _, l := range table.defaults {
+		lines = append(lines, fmt.Sprintf("%s: burst=%d count=%d period=%s%s",
+			l.name, l.Burst, l.Count, l.Period.Duration, renewalCostString(l)))
+	}
+	for k, l := range table.overrides {
+		id := strings.TrimPrefix(k, joinWithColon(l.name.EnumString(), ""))
+		lines = append(lines, fmt.Sprintf("%s: burst=%d count=%d period=%s%s (override)",
+			joinWithColon(l.name.String(), id), l.Burst, l.Count, l.Period.Duration, renewalCostString(l)))
+	}
+	slices.Sort(lines)
+
+	for _, line := range lines {
+		_, err = fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renewalCostString returns the renewalCost of the provided limit formatted for
+// the normalized view written by ValidateLimits, or the empty string if it is
+// not set.
+func renewalCostString(l limit) string {
+	if l.RenewalCost == nil {
+		return ""
+	}
+	return fmt.Sprintf(" renewalCost=%d", *l.RenewalCost)
+}
+
+// isExempt returns true if the provided ACME registration Id is exempt from the
+// limit specified by name.
+func (l *limitRegistry) isExempt(name Name, regId int64) bool {
+	if l.remote {
+		return false
+	}
+	_, ok := l.table.Load().exemptions[exemption{name, regId}]
+	return ok
+}
@@ -1,7 +1,12 @@
 package ratelimits
 
 import (
+	"bytes"
+	"math"
+	"net"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -39,6 +44,10 @@ func TestParseOverrideNameId(t *testing.T) {
 	// Invalid enum.
 	_, _, err = parseOverrideNameId("lol:noexist")
 	test.AssertError(t, err, "invalid enum")
+
+	// Internal limits cannot be overridden.
+	_, _, err = parseOverrideNameId(AkamaiPurgeURLsPerEndpoint.String() + ":localhost")
+	test.AssertError(t, err, "internal limit")
 }
 
 func TestValidateLimit(t *testing.T) {
@@ -50,6 +59,10 @@ func TestValidateLimit(t *testing.T) {
 		{Burst: 0, Count: 1, Period: config.Duration{Duration: time.Second}},
 		{Burst: 1, Count: 0, Period: config.Duration{Duration: time.Second}},
 		{Burst: 1, Count: 1, Period: config.Duration{Duration: 0}},
+		// Emission interval of 0.
+		{Burst: 1, Count: 10, Period: config.Duration{Duration: 5}},
+		// Burst offset overflows.
+		{Burst: math.MaxInt64, Count: 1, Period: config.Duration{Duration: time.Second}},
 	} {
 		err = validateLimit(l)
 		test.AssertError(t, err, "limit should be invalid")
@@ -131,6 +144,26 @@ func TestValidateIdForName(t *testing.T) {
 	// Invalid domain, empty.
 	err = validateIdForName(CertificatesPerDomain, "")
 	test.AssertError(t, err, "valid regId with empty domain")
+
+	// 'enum:service:client'
+	err = validateIdForName(GRPCRequestsPerClient, "sa.StorageAuthority:ra.boulder")
+	test.AssertNotError(t, err, "valid service:client")
+
+	// Missing client.
+	err = validateIdForName(GRPCRequestsPerClient, "sa.StorageAuthority:")
+	test.AssertError(t, err, "service:client with empty client")
+
+	// Missing colon.
+	err = validateIdForName(GRPCRequestsPerClient, "sa.StorageAuthority")
+	test.AssertError(t, err, "service:client without a colon")
+
+	// 'enum:logURL'
+	err = validateIdForName(CTSubmissionsPerLog, "https://ct.example.com/2025h1")
+	test.AssertNotError(t, err, "valid logURL")
+
+	// Relative URL.
+	err = validateIdForName(CTSubmissionsPerLog, "ct.example.com/2025h1")
+	test.AssertError(t, err, "relative logURL")
 }
 
 // TODO(#7198): Remove this.
@@ -158,7 +191,8 @@ func TestLoadAndParseOverrideLimitsDeprecated(t *testing.T) {
 	test.AssertEquals(t, l[expectKey1].Burst, int64(40))
 	test.AssertEquals(t, l[expectKey1].Count, int64(40))
 	test.AssertEquals(t, l[expectKey1].Period.Duration, time.Second)
-	expectKey2 := joinWithColon(NewRegistrationsPerIPv6Range.EnumString(), "2001:0db8:0000::/48")
+	// The IPv6 range is normalized from '2001:0db8:0000::/48'.
+	expectKey2 := joinWithColon(NewRegistrationsPerIPv6Range.EnumString(), "2001:db8::/48")
 	test.AssertEquals(t, l[expectKey2].Burst, int64(50))
 	test.AssertEquals(t, l[expectKey2].Count, int64(50))
 	test.AssertEquals(t, l[expectKey2].Period.Duration, time.Second*2)
@@ -167,11 +201,11 @@ func TestLoadAndParseOverrideLimitsDeprecated(t *testing.T) {
 	//   - CertificatesPerFQDNSet:example.com
 	//   - CertificatesPerFQDNSet:example.com,example.net
 	//   - CertificatesPerFQDNSet:example.com,example.net,example.org
-	firstEntryKey, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, []string{"example.com"})
+	firstEntryKey, err := BucketForFQDNSet(CertificatesPerFQDNSet, []string{"example.com"})
 	test.AssertNotError(t, err, "valid fqdnSet with one domain should not fail")
-	secondEntryKey, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, []string{"example.com", "example.net"})
+	secondEntryKey, err := BucketForFQDNSet(CertificatesPerFQDNSet, []string{"example.com", "example.net"})
 	test.AssertNotError(t, err, "valid fqdnSet with two domains should not fail")
-	thirdEntryKey, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, []string{"example.com", "example.net", "example.org"})
+	thirdEntryKey, err := BucketForFQDNSet(CertificatesPerFQDNSet, []string{"example.com", "example.net", "example.org"})
 	test.AssertNotError(t, err, "valid fqdnSet with three domains should not fail")
 	l, err = loadAndParseOverrideLimitsDeprecated("testdata/working_overrides_regid_fqdnset_deprecated.yml")
 	test.AssertNotError(t, err, "multiple valid override limits with 'fqdnSet' Ids")
@@ -250,7 +284,8 @@ func TestLoadAndParseOverrideLimits(t *testing.T) {
 	test.AssertEquals(t, l[expectKey1].Burst, int64(40))
 	test.AssertEquals(t, l[expectKey1].Count, int64(40))
 	test.AssertEquals(t, l[expectKey1].Period.Duration, time.Second)
-	expectKey2 := joinWithColon(NewRegistrationsPerIPv6Range.EnumString(), "2001:0db8:0000::/48")
+	// The IPv6 range is normalized from '2001:0db8:0000::/48'.
+	expectKey2 := joinWithColon(NewRegistrationsPerIPv6Range.EnumString(), "2001:db8::/48")
 	test.AssertEquals(t, l[expectKey2].Burst, int64(50))
 	test.AssertEquals(t, l[expectKey2].Count, int64(50))
 	test.AssertEquals(t, l[expectKey2].Period.Duration, time.Second*2)
@@ -259,11 +294,11 @@ func TestLoadAndParseOverrideLimits(t *testing.T) {
 	//   - CertificatesPerFQDNSet:example.com
 	//   - CertificatesPerFQDNSet:example.com,example.net
 	//   - CertificatesPerFQDNSet:example.com,example.net,example.org
-	firstEntryKey, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, []string{"example.com"})
+	firstEntryKey, err := BucketForFQDNSet(CertificatesPerFQDNSet, []string{"example.com"})
 	test.AssertNotError(t, err, "valid fqdnSet with one domain should not fail")
-	secondEntryKey, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, []string{"example.com", "example.net"})
+	secondEntryKey, err := BucketForFQDNSet(CertificatesPerFQDNSet, []string{"example.com", "example.net"})
 	test.AssertNotError(t, err, "valid fqdnSet with two domains should not fail")
-	thirdEntryKey, err := newFQDNSetBucketKey(CertificatesPerFQDNSet, []string{"example.com", "example.net", "example.org"})
+	thirdEntryKey, err := BucketForFQDNSet(CertificatesPerFQDNSet, []string{"example.com", "example.net", "example.org"})
 	test.AssertNotError(t, err, "valid fqdnSet with three domains should not fail")
 	l, err = loadAndParseOverrideLimits("testdata/working_overrides_regid_fqdnset.yml")
 	test.AssertNotError(t, err, "multiple valid override limits with 'fqdnSet' Ids")
@@ -277,6 +312,28 @@ func TestLoadAndParseOverrideLimits(t *testing.T) {
 	test.AssertEquals(t, l[thirdEntryKey].Count, int64(60))
 	test.AssertEquals(t, l[thirdEntryKey].Period.Duration, time.Second*3)
 
+	// Load multiple valid override limits which reference a template, as
+	// follows:
+	//   - NewRegistrationsPerIPAddress:10.0.0.2 uses the template as-is,
+	//   - NewRegistrationsPerIPAddress:10.0.0.3 doubles the template burst, and
+	//   - NewRegistrationsPerIPv6Range:2001:0db8:0000::/48 replaces the
+	//     template period and triples the template count.
+	l, err = loadAndParseOverrideLimits("testdata/working_overrides_templates.yml")
+	test.AssertNotError(t, err, "multiple valid override limits referencing a template")
+	test.AssertEquals(t, len(l), 3)
+	expectKey1 = joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.2")
+	test.AssertEquals(t, l[expectKey1].Burst, int64(40))
+	test.AssertEquals(t, l[expectKey1].Count, int64(40))
+	test.AssertEquals(t, l[expectKey1].Period.Duration, time.Second)
+	expectKey2 = joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.3")
+	test.AssertEquals(t, l[expectKey2].Burst, int64(80))
+	test.AssertEquals(t, l[expectKey2].Count, int64(40))
+	test.AssertEquals(t, l[expectKey2].Period.Duration, time.Second)
+	expectKey3 := joinWithColon(NewRegistrationsPerIPv6Range.EnumString(), "2001:db8::/48")
+	test.AssertEquals(t, l[expectKey3].Burst, int64(40))
+	test.AssertEquals(t, l[expectKey3].Count, int64(120))
+	test.AssertEquals(t, l[expectKey3].Period.Duration, time.Second*2)
+
 	// Path is empty string.
 	_, err = loadAndParseOverrideLimits("")
 	test.AssertError(t, err, "path is empty string")
@@ -316,18 +373,33 @@ func TestLoadAndParseOverrideLimits(t *testing.T) {
 	_, err = loadAndParseOverrideLimits("testdata/busted_overrides_third_entry_bad_id.yml")
 	test.AssertError(t, err, "multiple override limits, third entry has bad Id value")
 	test.Assert(t, !os.IsNotExist(err), "test file should exist")
+
+	// Template referenced by an override does not exist.
+	_, err = loadAndParseOverrideLimits("testdata/busted_override_unknown_template.yml")
+	test.AssertError(t, err, "override limit referencing an unknown template")
+	test.Assert(t, !os.IsNotExist(err), "test file should exist")
+
+	// Template cannot specify ids.
+	_, err = loadAndParseOverrideLimits("testdata/busted_override_template_with_ids.yml")
+	test.AssertError(t, err, "template with ids")
+	test.Assert(t, !os.IsNotExist(err), "test file should exist")
+
+	// Multiple entries, the same id appears in two of them.
+	_, err = loadAndParseOverrideLimits("testdata/busted_overrides_duplicate_id.yml")
+	test.AssertError(t, err, "multiple override limits with duplicate Id")
+	test.Assert(t, !os.IsNotExist(err), "test file should exist")
 }
 
 func TestLoadAndParseDefaultLimits(t *testing.T) {
 	// Load a single valid default limit.
-	l, err := loadAndParseDefaultLimits("testdata/working_default.yml")
+	l, err := loadAndParseDefaultLimits("testdata/working_default.yml", "")
 	test.AssertNotError(t, err, "valid single default limit")
 	test.AssertEquals(t, l[NewRegistrationsPerIPAddress.EnumString()].Burst, int64(20))
 	test.AssertEquals(t, l[NewRegistrationsPerIPAddress.EnumString()].Count, int64(20))
 	test.AssertEquals(t, l[NewRegistrationsPerIPAddress.EnumString()].Period.Duration, time.Second)
 
 	// Load multiple valid default limits.
-	l, err = loadAndParseDefaultLimits("testdata/working_defaults.yml")
+	l, err = loadAndParseDefaultLimits("testdata/working_defaults.yml", "")
 	test.AssertNotError(t, err, "multiple valid default limits")
 	test.AssertEquals(t, l[NewRegistrationsPerIPAddress.EnumString()].Burst, int64(20))
 	test.AssertEquals(t, l[NewRegistrationsPerIPAddress.EnumString()].Count, int64(20))
@@ -337,32 +409,322 @@ func TestLoadAndParseDefaultLimits(t *testing.T) {
 	test.AssertEquals(t, l[NewRegistrationsPerIPv6Range.EnumString()].Period.Duration, time.Second*2)
 
 	// Path is empty string.
-	_, err = loadAndParseDefaultLimits("")
+	_, err = loadAndParseDefaultLimits("", "")
 	test.AssertError(t, err, "path is empty string")
 	test.Assert(t, os.IsNotExist(err), "path is empty string")
 
 	// Path to file which does not exist.
-	_, err = loadAndParseDefaultLimits("testdata/file_does_not_exist.yml")
+	_, err = loadAndParseDefaultLimits("testdata/file_does_not_exist.yml", "")
 	test.AssertError(t, err, "a file that does not exist")
 	test.Assert(t, os.IsNotExist(err), "test file should not exist")
 
 	// Burst cannot be 0.
-	_, err = loadAndParseDefaultLimits("testdata/busted_default_burst_0.yml")
+	_, err = loadAndParseDefaultLimits("testdata/busted_default_burst_0.yml", "")
 	test.AssertError(t, err, "single default limit with burst=0")
 	test.Assert(t, !os.IsNotExist(err), "test file should exist")
 
 	// Name cannot be empty.
-	_, err = loadAndParseDefaultLimits("testdata/busted_default_empty_name.yml")
+	_, err = loadAndParseDefaultLimits("testdata/busted_default_empty_name.yml", "")
 	test.AssertError(t, err, "single default limit with empty name")
 	test.Assert(t, !os.IsNotExist(err), "test file should exist")
 
 	// Name must be a string representation of a valid Name enumeration.
-	_, err = loadAndParseDefaultLimits("testdata/busted_default_invalid_name.yml")
+	_, err = loadAndParseDefaultLimits("testdata/busted_default_invalid_name.yml", "")
 	test.AssertError(t, err, "single default limit with invalid name")
 	test.Assert(t, !os.IsNotExist(err), "test file should exist")
 
+	// Internal limits cannot be configured in a defaults file.
+	_, err = loadAndParseDefaultLimits("testdata/busted_default_internal_name.yml", "")
+	test.AssertError(t, err, "single default limit with internal name")
+	test.AssertContains(t, err.Error(), "internal limit")
+
 	// Multiple entries, second entry has a bad name.
-	_, err = loadAndParseDefaultLimits("testdata/busted_defaults_second_entry_bad_name.yml")
+	_, err = loadAndParseDefaultLimits("testdata/busted_defaults_second_entry_bad_name.yml", "")
 	test.AssertError(t, err, "multiple default limits, one is bad")
 	test.Assert(t, !os.IsNotExist(err), "test file should exist")
+
+	// RenewalCost is optional.
+	l, err = loadAndParseDefaultLimits("testdata/working_defaults_renewals.yml", "")
+	test.AssertNotError(t, err, "valid default limits with renewalCost")
+	test.Assert(t, l[NewRegistrationsPerIPAddress.EnumString()].RenewalCost == nil, "renewalCost should not be set")
+	test.AssertEquals(t, *l[NewOrdersPerAccount.EnumString()].RenewalCost, int64(2))
+	test.AssertEquals(t, *l[CertificatesPerDomain.EnumString()].RenewalCost, int64(0))
+
+	// RenewalCost cannot exceed burst.
+	_, err = loadAndParseDefaultLimits("testdata/busted_default_renewal_cost_over_burst.yml", "")
+	test.AssertError(t, err, "single default limit with renewalCost > burst")
+	test.AssertContains(t, err.Error(), "invalid renewalCost")
+}
+
+func TestValidateLimits(t *testing.T) {
+	// Valid defaults and overrides.
+	var out bytes.Buffer
+	err := ValidateLimits("testdata/working_defaults.yml", "", "testdata/working_overrides.yml", "", &out)
+	test.AssertNotError(t, err, "valid defaults and overrides")
+	test.AssertEquals(t, out.String(), `NewRegistrationsPerIPAddress: burst=20 count=20 period=1s
+NewRegistrationsPerIPAddress:10.0.0.2: burst=40 count=40 period=1s (override)
+NewRegistrationsPerIPv6Range: burst=30 count=30 period=2s
+NewRegistrationsPerIPv6Range:2001:db8::/48: burst=50 count=50 period=2s (override)
+`)
+
+	// Valid defaults, no overrides.
+	out.Reset()
+	err = ValidateLimits("testdata/working_default.yml", "", "", "", &out)
+	test.AssertNotError(t, err, "valid defaults without overrides")
+	test.AssertEquals(t, out.String(), "NewRegistrationsPerIPAddress: burst=20 count=20 period=1s\n")
+
+	// Invalid defaults.
+	err = ValidateLimits("testdata/busted_default_burst_0.yml", "", "", "", &out)
+	test.AssertError(t, err, "default limit with burst=0")
+
+	// Invalid overrides.
+	err = ValidateLimits("testdata/working_defaults.yml", "", "testdata/busted_overrides_third_entry_bad_id.yml", "", &out)
+	test.AssertError(t, err, "override limit with bad Id value")
+
+	// The same IPv6 range written in two ways.
+	err = ValidateLimits("testdata/working_defaults.yml", "", "testdata/busted_overrides_overlapping_ranges.yml", "", &out)
+	test.AssertError(t, err, "override limits with overlapping IPv6 ranges")
+	test.AssertContains(t, err.Error(), "duplicate id")
+}
+
+func TestLimitRegistryGetLimit(t *testing.T) {
+	registry, err := newLimitRegistry("testdata/working_default.yml", "", "testdata/working_override.yml", "testdata/working_exemptions.yml")
+	test.AssertNotError(t, err, "should not error")
+
+	l, err := registry.getLimit(NewRegistrationsPerIPAddress, joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.2"))
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, l.isOverride, "should be an override")
+	test.AssertEquals(t, l.Burst, int64(40))
+
+	l, err = registry.getLimit(NewRegistrationsPerIPAddress, joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !l.isOverride, "should not be an override")
+	test.AssertEquals(t, l.Burst, int64(20))
+
+	l, err = registry.getLimit(NewRegistrationsPerIPAddress, "")
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, l.Burst, int64(20))
+
+	_, err = registry.getLimit(NewOrdersPerAccount, "")
+	test.AssertErrorIs(t, err, errLimitDisabled)
+	_, err = registry.getLimit(Unknown, "")
+	test.AssertError(t, err, "invalid name should error")
+
+	test.Assert(t, registry.isExempt(CertificatesPerDomain, 4242), "4242 should be exempt")
+	test.Assert(t, !registry.isExempt(NewOrdersPerAccount, 4242), "4242 should not be exempt")
+}
+
+func TestLimitRegistryReload(t *testing.T) {
+	dir := t.TempDir()
+	defaults := filepath.Join(dir, "defaults.yml")
+	overrides := filepath.Join(dir, "overrides.yml")
+	copyFile := func(src, dst string) {
+		t.Helper()
+		data, err := os.ReadFile(src)
+		test.AssertNotError(t, err, "reading file")
+		test.AssertNotError(t, os.WriteFile(dst, data, 0644), "writing file")
+	}
+	copyFile("testdata/working_default.yml", defaults)
+	copyFile("testdata/working_override.yml", overrides)
+
+	builder, err := NewTransactionBuilder(defaults, "", overrides, "")
+	test.AssertNotError(t, err, "should not error")
+	overridden, err := builder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.2"))
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, overridden.limit.Burst, int64(40))
+
+	// Concurrent lookups see either the old or the new limits.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			l, err := builder.getLimit(NewRegistrationsPerIPv6Range, "")
+			if err == nil && l.Burst != 30 {
+				t.Errorf("got burst %d, expected 30", l.Burst)
+			}
+		}
+	}()
+
+	// Reloading picks up a new default and replaces the overrides.
+	copyFile("testdata/working_defaults.yml", defaults)
+	copyFile("testdata/working_override_regid_domain.yml", overrides)
+	err = builder.Reload()
+	test.AssertNotError(t, err, "should not error")
+	wg.Wait()
+
+	txn, err := builder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.2"))
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !txn.limit.isOverride, "override should have been replaced")
+	test.AssertEquals(t, txn.limit.Burst, int64(20))
+	l, err := builder.getLimit(NewRegistrationsPerIPv6Range, "")
+	test.AssertNotError(t, err, "new default should be loaded")
+	test.AssertEquals(t, l.Burst, int64(30))
+
+	// Transactions already built keep their limits.
+	test.AssertEquals(t, overridden.limit.Burst, int64(40))
+
+	// A failed reload leaves the limits in use unchanged.
+	copyFile("testdata/busted_default_burst_0.yml", defaults)
+	err = builder.Reload()
+	test.AssertError(t, err, "invalid defaults should error")
+	l, err = builder.getLimit(NewRegistrationsPerIPv6Range, "")
+	test.AssertNotError(t, err, "limits should be unchanged")
+	test.AssertEquals(t, l.Burst, int64(30))
+}
+
+func TestLoadAndParseDefaultLimitsForEnvironment(t *testing.T) {
+	// No environment, only the top-level limits apply.
+	l, err := loadAndParseDefaultLimits("testdata/working_defaults_environments.yml", "")
+	test.AssertNotError(t, err, "valid defaults with environment sections")
+	test.AssertEquals(t, len(l), 2)
+	test.AssertEquals(t, l[NewRegistrationsPerIPAddress.EnumString()].Burst, int64(20))
+	test.AssertEquals(t, l[NewRegistrationsPerIPv6Range.EnumString()].Burst, int64(30))
+
+	// The staging section replaces a top-level limit.
+	l, err = loadAndParseDefaultLimits("testdata/working_defaults_environments.yml", "staging")
+	test.AssertNotError(t, err, "valid defaults for staging")
+	test.AssertEquals(t, len(l), 2)
+	test.AssertEquals(t, l[NewRegistrationsPerIPAddress.EnumString()].Burst, int64(200))
+	test.AssertEquals(t, l[NewRegistrationsPerIPv6Range.EnumString()].Burst, int64(30))
+
+	// The production section adds a limit.
+	l, err = loadAndParseDefaultLimits("testdata/working_defaults_environments.yml", "production")
+	test.AssertNotError(t, err, "valid defaults for production")
+	test.AssertEquals(t, len(l), 3)
+	test.AssertEquals(t, l[NewRegistrationsPerIPAddress.EnumString()].Burst, int64(20))
+	test.AssertEquals(t, l[NewOrdersPerAccount.EnumString()].Burst, int64(300))
+
+	// The environment section must exist.
+	_, err = loadAndParseDefaultLimits("testdata/working_defaults_environments.yml", "lol")
+	test.AssertError(t, err, "environment section does not exist")
+
+	// An environment must be specified to use environment sections in a file
+	// without them.
+	_, err = loadAndParseDefaultLimits("testdata/working_defaults.yml", "staging")
+	test.AssertError(t, err, "environment section does not exist")
+
+	// Limits in the selected section are validated.
+	_, err = loadAndParseDefaultLimits("testdata/busted_defaults_environment_bad_name.yml", "staging")
+	test.AssertError(t, err, "environment section with a bad name")
+
+	// Limits in sections which are not selected are not parsed.
+	_, err = loadAndParseDefaultLimits("testdata/busted_defaults_environment_bad_name.yml", "")
+	test.AssertNotError(t, err, "unselected environment section with a bad name")
+}
+
+// addLimitsFuzzSeeds adds every testdata file matching pattern to the corpus
+// of f.
+func addLimitsFuzzSeeds(f *testing.F, pattern string) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+// writeFuzzInput writes data to a file in a temporary directory and returns
+// its path.
+func writeFuzzInput(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "limits.yml")
+	err := os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// assertLimitsUsable fails the test if any of the provided limits would cause
+// the GCRA to divide by zero or overflow.
+func assertLimitsUsable(t *testing.T, lm limits) {
+	for k, l := range lm {
+		if l.emissionInterval <= 0 || l.burstOffset <= 0 {
+			t.Fatalf("limit %q has emissionInterval %d and burstOffset %d, both must be > 0", k, l.emissionInterval, l.burstOffset)
+		}
+		if l.burstOffset/l.emissionInterval != l.Burst {
+			t.Fatalf("limit %q has burstOffset %d, which overflowed", k, l.burstOffset)
+		}
+	}
+}
+
+func FuzzLoadAndParseDefaultLimits(f *testing.F) {
+	addLimitsFuzzSeeds(f, "testdata/*default*.yml")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := writeFuzzInput(t, data)
+		lm, err := loadAndParseDefaultLimits(path, "")
+		if err == nil {
+			assertLimitsUsable(t, lm)
+		}
+		lm, err = loadAndParseDefaultLimits(path, "staging")
+		if err == nil {
+			assertLimitsUsable(t, lm)
+		}
+	})
+}
+
+func FuzzLoadAndParseOverrideLimits(f *testing.F) {
+	addLimitsFuzzSeeds(f, "testdata/*override*.yml")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := writeFuzzInput(t, data)
+		lm, err := loadAndParseOverrideLimits(path)
+		if err == nil {
+			assertLimitsUsable(t, lm)
+		}
+		lm, err = loadAndParseOverrideLimitsDeprecated(path)
+		if err == nil {
+			assertLimitsUsable(t, lm)
+		}
+	})
+}
+
+func FuzzParseOverrideNameId(f *testing.F) {
+	f.Add(NewRegistrationsPerIPAddress.String() + ":10.0.0.1")
+	f.Add(NewRegistrationsPerIPv6Range.String() + ":2001:0db8:0000::/48")
+	f.Add(CertificatesPerDomainPerAccount.String() + ":12345:example.com")
+	f.Add(CertificatesPerFQDNSet.String() + ":example.com,example.org")
+	f.Add(":")
+	f.Fuzz(func(t *testing.T, key string) {
+		name, id, err := parseOverrideNameId(key)
+		if err != nil {
+			return
+		}
+		if !name.isValid() || id == "" {
+			t.Fatalf("parseOverrideNameId(%q) returned name %d and id %q without error", key, name, id)
+		}
+		// Validation of the id must never panic.
+		_ = validateIdForName(name, normalizeIdForName(name, id))
+	})
+}
+
+func BenchmarkLimitRegistry_getLimit(b *testing.B) {
+	registry, err := newLimitRegistry("testdata/working_default.yml", "", "testdata/working_override.yml", "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name      string
+		bucketKey string
+	}{
+		{"override", joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.2")},
+		{"default", joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.1")},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, err := registry.getLimit(NewRegistrationsPerIPAddress, tc.bucketKey)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
 }
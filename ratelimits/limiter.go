@@ -2,14 +2,17 @@ package ratelimits
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
-	"slices"
+	"strings"
 	"time"
 
 	"github.com/jmhodges/clock"
+	blog "github.com/letsencrypt/boulder/log"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -22,42 +25,383 @@ const (
 	Denied = "denied"
 )
 
+const (
+	// The following are the values of the 'reason' label of the decisions
+	// metric.
+
+	// reasonWithinLimit is used when the bucket had enough capacity to satisfy
+	// the cost.
+	reasonWithinLimit = "within-limit"
+
+	// reasonOverLimit is used when the bucket lacked the capacity to satisfy
+	// the cost.
+	reasonOverLimit = "over-limit"
+
+	// reasonCostOverBurst is used when the cost exceeded the burst of the
+	// limit, meaning the request could never be satisfied.
+	reasonCostOverBurst = "cost-over-burst"
+
+	// reasonDisabled is used when the limit was disabled.
+	reasonDisabled = "disabled"
+
+	// reasonExempt is used when the requester was exempt from the limit.
+	reasonExempt = "exempt"
+
+	// reasonRenewal is used when the request renewed an existing certificate
+	// and the limit exempts renewals.
+	reasonRenewal = "renewal"
+
+	// reasonSourceErrorFailClosed is used when the request was denied because
+	// the source could not be read or written.
+	reasonSourceErrorFailClosed = "source-error-fail-closed"
+)
+
+const (
+	// The following are the values of the 'result' label of the refunds
+	// metric.
+
+	// refundFull is used when the entire cost was refunded.
+	refundFull = "full"
+
+	// refundPartial is used when only part of the cost was refunded because
+	// the bucket would otherwise have exceeded its burst.
+	refundPartial = "partial"
+
+	// refundNoop is used when nothing was refunded, e.g. because the bucket
+	// did not exist, was already full, or the Transaction was check-only.
+	refundNoop = "noop"
+
+	// refundFailed is used when the refund could not be completed because the
+	// source returned an error.
+	refundFailed = "failed"
+)
+
 // allowedDecision is an "allowed" *Decision that should be returned when a
 // checked limit is found to be disabled.
 var allowedDecision = &Decision{Allowed: true, Remaining: math.MaxInt64}
 
+// TransactionLimiter is the interface through which rate limits are checked,
+// spent, refunded, and reset. *Limiter is the canonical implementation;
+// consumers should depend on this interface so that alternative
+// implementations, such as a client of ratelimitd, or fakes can be substituted.
+type TransactionLimiter interface {
+	Check(ctx context.Context, txn Transaction) (*Decision, error)
+	BatchCheck(ctx context.Context, txns []Transaction) ([]*Decision, error)
+	Spend(ctx context.Context, txn Transaction) (*Decision, error)
+	BatchSpend(ctx context.Context, txns []Transaction) (*Decision, error)
+	Refund(ctx context.Context, txn Transaction) (*Decision, error)
+	BatchRefund(ctx context.Context, txns []Transaction) (*Decision, error)
+	Reset(ctx context.Context, bucketKey string) error
+}
+
+// Compile-time check that *Limiter implements the TransactionLimiter
+// interface.
+var _ TransactionLimiter = (*Limiter)(nil)
+
 // Limiter provides a high-level interface for rate limiting requests by
 // utilizing a leaky bucket-style approach.
 type Limiter struct {
 	// source is used to store buckets. It must be safe for concurrent use.
 	source source
 	clk    clock.Clock
+	tracer trace.Tracer
+
+	// estimator, if non-nil, is used to estimate the number of buckets per
+	// limit stored by the source.
+	estimator bucketEstimator
+
+	// prefixDeleter is nil if the source cannot delete buckets by prefix.
+	prefixDeleter prefixDeleter
+
+	// scanner is nil if the source cannot enumerate its buckets.
+	scanner bucketScanner
+
+	// pinger is nil if the source cannot check its connectivity.
+	pinger pinger
 
 	spendLatency       *prometheus.HistogramVec
+	checkLatency       *prometheus.HistogramVec
 	overrideUsageGauge *prometheus.GaugeVec
+	exemptions         *prometheus.CounterVec
+	decisions          *prometheus.CounterVec
+	activeBuckets      *prometheus.GaugeVec
+	batchSize          *prometheus.HistogramVec
+	disabledLimits     *prometheus.CounterVec
+	refunds            *prometheus.CounterVec
+	refundLatency      *prometheus.HistogramVec
+	bucketChurn        *prometheus.CounterVec
+	sourceTimeouts     *prometheus.CounterVec
+
+	// The following cache the children of the counters which are incremented
+	// for every Transaction.
+	decisionCounters  *counterCache[decisionLabels]
+	refundCounters    *counterCache[resultLabels]
+	churnCounters     *counterCache[resultLabels]
+	exemptionCounters *counterCache[Name]
+	disabledCounters  *counterCache[Name]
+
+	// denialHook, if non-nil, is called for every denied Transaction.
+	denialHook DenialHook
+
+	// hooks are called before and after every Check, Spend, BatchSpend,
+	// Refund, and BatchRefund, in the order they were added.
+	hooks []Hook
+
+	// heavyHitters tracks the bucket keys with the most over-limit denials.
+	heavyHitters *heavyHitters
+
+	// overrideAlerter, if non-nil, is notified of the utilization of each
+	// override spent from.
+	overrideAlerter *overrideAlerter
+
+	// usage accumulates the data reported by UtilizationReport.
+	usage *usageStats
 }
 
 // NewLimiter returns a new *Limiter. The provided source must be safe for
 // concurrent use.
 func NewLimiter(clk clock.Clock, source source, stats prometheus.Registerer) (*Limiter, error) {
-	limiter := &Limiter{source: source, clk: clk}
+	tracer := newTracer()
+	limiter := &Limiter{
+		source:       newTracedSource(source, tracer),
+		clk:          clk,
+		tracer:       tracer,
+		heavyHitters: newHeavyHitters(clk, heavyHittersSize, heavyHittersShards),
+		usage:        newUsageStats(clk.Now()),
+	}
+	if e, ok := source.(bucketEstimator); ok {
+		limiter.estimator = e
+	}
+	if d, ok := source.(prefixDeleter); ok {
+		limiter.prefixDeleter = d
+	}
+	if s, ok := source.(bucketScanner); ok {
+		limiter.scanner = s
+	}
+	if p, ok := source.(pinger); ok {
+		limiter.pinger = p
+	}
 	limiter.spendLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name: "ratelimits_spend_latency",
-		Help: fmt.Sprintf("Latency of ratelimit checks labeled by limit=[name] and decision=[%s|%s], in seconds", Allowed, Denied),
+		Help: fmt.Sprintf("Latency of ratelimit spends labeled by limit=[batch] and decision=[%s|%s], in seconds. Per-limit decisions are counted by ratelimits_decisions_total", Allowed, Denied),
 		// Exponential buckets ranging from 0.0005s to 3s.
 		Buckets: prometheus.ExponentialBuckets(0.0005, 3, 8),
 	}, []string{"limit", "decision"})
 	stats.MustRegister(limiter.spendLatency)
 
+	limiter.checkLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "ratelimits_check_latency",
+		Help: fmt.Sprintf("Latency of ratelimit checks, including reading the bucket, labeled by limit=[name] and decision=[%s|%s], in seconds", Allowed, Denied),
+		// Exponential buckets ranging from 0.0005s to 3s.
+		Buckets: prometheus.ExponentialBuckets(0.0005, 3, 8),
+	}, []string{"limit", "decision"})
+	stats.MustRegister(limiter.checkLatency)
+
 	limiter.overrideUsageGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "ratelimits_override_usage",
 		Help: "Proportion of override limit used, by limit name and bucket key.",
 	}, []string{"limit", "bucket_key"})
 	stats.MustRegister(limiter.overrideUsageGauge)
 
+	limiter.exemptions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_exemptions",
+		Help: "Number of checks or spends skipped because the requester is exempt, by limit name.",
+	}, []string{"limit"})
+	stats.MustRegister(limiter.exemptions)
+
+	limiter.decisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_decisions_total",
+		Help: fmt.Sprintf("Number of ratelimit decisions labeled by limit=[name], decision=[%s|%s], and reason=[%s|%s|%s|%s|%s|%s|%s]",
+			Allowed, Denied, reasonWithinLimit, reasonOverLimit, reasonCostOverBurst, reasonDisabled, reasonExempt, reasonRenewal, reasonSourceErrorFailClosed),
+	}, []string{"limit", "decision", "reason"})
+	stats.MustRegister(limiter.decisions)
+
+	limiter.activeBuckets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ratelimits_active_buckets",
+		Help: "Estimated number of buckets stored by the source, by limit name.",
+	}, []string{"limit"})
+	stats.MustRegister(limiter.activeBuckets)
+
+	limiter.batchSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "ratelimits_batch_size",
+		Help: "Number of buckets per batch call labeled by op=[spend|refund], excluding allow-only transactions",
+		// Exponential buckets ranging from 1 to 256.
+		Buckets: prometheus.ExponentialBuckets(1, 2, 9),
+	}, []string{"op"})
+	stats.MustRegister(limiter.batchSize)
+
+	limiter.disabledLimits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_disabled_limit_hits_total",
+		Help: "Number of checks or spends allowed without evaluation because no default limit is configured, by limit name",
+	}, []string{"limit"})
+	stats.MustRegister(limiter.disabledLimits)
+
+	limiter.refunds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_refunds_total",
+		Help: fmt.Sprintf("Number of attempted refunds labeled by limit=[name] and result=[%s|%s|%s|%s]", refundFull, refundPartial, refundNoop, refundFailed),
+	}, []string{"limit", "result"})
+	stats.MustRegister(limiter.refunds)
+
+	limiter.refundLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "ratelimits_refund_latency",
+		Help: "Latency of ratelimit refunds, including reading and writing buckets, labeled by result=[success|error], in seconds",
+		// Exponential buckets ranging from 0.0005s to 3s.
+		Buckets: prometheus.ExponentialBuckets(0.0005, 3, 8),
+	}, []string{"result"})
+	stats.MustRegister(limiter.refundLatency)
+
+	limiter.bucketChurn = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_bucket_churn_total",
+		Help: "Number of buckets created by a spend or deleted by a reset, labeled by limit=[name] and event=[created|deleted]",
+	}, []string{"limit", "event"})
+	stats.MustRegister(limiter.bucketChurn)
+
+	limiter.sourceTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_source_timeouts_total",
+		Help: "Number of source calls which exceeded their configured deadline, labeled by call=[get|delete|batchget|batchset|estimatebuckets|deleteprefix|scanbuckets|ping]",
+	}, []string{"call"})
+	stats.MustRegister(limiter.sourceTimeouts)
+
+	limiter.decisionCounters = newCounterCache(limiter.decisions, func(k decisionLabels) []string {
+		return []string{k.name.String(), k.decision, k.reason}
+	})
+	limiter.refundCounters = newCounterCache(limiter.refunds, resultLabels.values)
+	limiter.churnCounters = newCounterCache(limiter.bucketChurn, resultLabels.values)
+	limiter.exemptionCounters = newCounterCache(limiter.exemptions, Name.labelValues)
+	limiter.disabledCounters = newCounterCache(limiter.disabledLimits, Name.labelValues)
+
 	return limiter, nil
 }
 
+// Denial describes a single denied Transaction.
+type Denial struct {
+	// Limit is the name of the limit which denied the Transaction.
+	Limit string
+
+	// BucketKey is the key of the bucket which lacked capacity.
+	BucketKey string
+
+	// Cost is the cost of the denied Transaction.
+	Cost int64
+
+	// Reason is the reason the Transaction was denied, one of the 'reason'
+	// label values of the ratelimits_decisions_total metric.
+	Reason string
+
+	// Remaining and RetryIn are those of the *Decision for the Transaction.
+	// They are zero if the Transaction was denied without evaluating the
+	// bucket, e.g. because the source returned an error.
+	Remaining int64
+	RetryIn   time.Duration
+}
+
+// DenialHook is called by the Limiter, synchronously, for every denied
+// Transaction. It must be safe for concurrent use and should return quickly.
+type DenialHook func(Denial)
+
+// SetSourceTimeouts configures the Limiter to apply a deadline to each call to
+// its source: single to each call which reads or writes one bucket, or pings
+// the datastore, and batch to each call which reads or writes many buckets, or
+// estimates their number. Calls which scan or delete buckets by prefix apply
+// batch to each of their round trips. A timeout of 0 applies no deadline. It
+// must be called before the Limiter is used.
+func (l *Limiter) SetSourceTimeouts(single, batch time.Duration) {
+	t := &timeoutSource{
+		source:        l.source,
+		estimator:     l.estimator,
+		prefixDeleter: l.prefixDeleter,
+		scanner:       l.scanner,
+		pinger:        l.pinger,
+		single:        single,
+		batch:         batch,
+		timeouts:      l.sourceTimeouts,
+	}
+	l.source = t
+	// Only replace the optional interfaces the source implements, since the
+	// Limiter treats the others being nil as unsupported.
+	if l.estimator != nil {
+		l.estimator = t
+	}
+	if l.prefixDeleter != nil {
+		l.prefixDeleter = t
+	}
+	if l.scanner != nil {
+		l.scanner = t
+	}
+	if l.pinger != nil {
+		l.pinger = t
+	}
+}
+
+// SetDenialHook configures the Limiter to call the provided DenialHook for
+// every denied Transaction. It must be called before the Limiter is used.
+func (l *Limiter) SetDenialHook(hook DenialHook) {
+	l.denialHook = hook
+}
+
+// Hook is called by the Limiter before and after every Check, Spend,
+// BatchSpend, Refund, and BatchRefund, so that deployments can layer custom
+// logic, such as extra logging, denial webhooks, or experimental policies, on
+// top of the Limiter. The op is the name of the Limiter method, e.g.
+// "BatchSpend". Hooks must be safe for concurrent use, must not modify txns,
+// and should return quickly.
+type Hook interface {
+	// Before is called with the Transactions before they are evaluated. If it
+	// returns an error, the Transactions are not evaluated and the call fails
+	// with that error.
+	Before(ctx context.Context, op string, txns []Transaction) error
+
+	// After is called with the Transactions and the resulting Decision and
+	// error, which may be nil, once they have been evaluated. It is only
+	// called if Before returned nil.
+	After(ctx context.Context, op string, txns []Transaction, d *Decision, err error)
+}
+
+// AddHook configures the Limiter to call the provided Hook before and after
+// every Check, Spend, BatchSpend, Refund, and BatchRefund. Hooks are called in
+// the order they were added. It must be called before the Limiter is used.
+func (l *Limiter) AddHook(hook Hook) {
+	l.hooks = append(l.hooks, hook)
+}
+
+// withHooks calls the Before method of each Hook, then fn, then the After
+// method of each Hook whose Before method returned nil.
+func (l *Limiter) withHooks(ctx context.Context, op string, txns []Transaction, fn func(context.Context, []Transaction) (*Decision, error)) (*Decision, error) {
+	if len(l.hooks) == 0 {
+		return fn(ctx, txns)
+	}
+	for i, hook := range l.hooks {
+		err := hook.Before(ctx, op, txns)
+		if err != nil {
+			for _, h := range l.hooks[:i] {
+				h.After(ctx, op, txns, nil, err)
+			}
+			return nil, err
+		}
+	}
+	d, err := fn(ctx, txns)
+	for _, hook := range l.hooks {
+		hook.After(ctx, op, txns, d, err)
+	}
+	return d, err
+}
+
+// TopDenied returns up to n of the bucket keys with the most recent over-limit
+// denials, in descending order of their estimated number of denials. Denials
+// age out after between one and two hours. Estimates are approximate and may
+// overcount, but never undercount, the true number of recent denials.
+func (l *Limiter) TopDenied(n int) []HeavyHitter {
+	return l.heavyHitters.topN(n)
+}
+
+// LogDenials returns a DenialHook which logs each Denial, as JSON, to the
+// provided logger.
+func LogDenials(logger blog.Logger) DenialHook {
+	return func(d Denial) {
+		logger.InfoObject("Rate limit denied", d)
+	}
+}
+
 type Decision struct {
 	// Allowed is true if the bucket possessed enough capacity to allow the
 	// request given the cost.
@@ -79,6 +423,75 @@ type Decision struct {
 	// theoretical arrival time (TAT) of next request. It must be no more than
 	// (burst * (period / count)) in the future at any single point in time.
 	newTAT time.Time
+
+	// transaction is the Transaction which produced the Decision. For a
+	// Decision merged from a batch, it is the first Transaction which was
+	// denied or, if none were, the one with the least remaining capacity. It
+	// is the zero value if no bucket was evaluated, e.g. for allow-only
+	// Transactions.
+	transaction Transaction
+}
+
+// decisionJSON is the JSON representation of a Decision.
+type decisionJSON struct {
+	Allowed   bool   `json:"allowed"`
+	Remaining int64  `json:"remaining"`
+	RetryIn   string `json:"retryIn"`
+	ResetIn   string `json:"resetIn"`
+
+	// ResetAt is the time at which the bucket will be full, omitted if no
+	// bucket was evaluated.
+	ResetAt *time.Time `json:"resetAt,omitempty"`
+
+	// Limit and BucketKey identify the bucket which produced the Decision,
+	// omitted if no bucket was evaluated.
+	Limit     string `json:"limit,omitempty"`
+	BucketKey string `json:"bucketKey,omitempty"`
+}
+
+// Burst returns the maximum capacity of the bucket which produced the
+// Decision, or 0 if no bucket was evaluated, e.g. for allow-only Transactions.
+func (d *Decision) Burst() int64 {
+	if d.transaction.bucketKey == "" {
+		return 0
+	}
+	return d.transaction.limit.Burst
+}
+
+// MarshalJSON implements json.Marshaler. RetryIn and ResetIn are formatted as
+// durations, e.g. "1m30s".
+func (d *Decision) MarshalJSON() ([]byte, error) {
+	out := decisionJSON{
+		Allowed:   d.Allowed,
+		Remaining: d.Remaining,
+		RetryIn:   d.RetryIn.String(),
+		ResetIn:   d.ResetIn.String(),
+		BucketKey: d.transaction.bucketKey,
+	}
+	if !d.newTAT.IsZero() {
+		resetAt := d.newTAT.UTC()
+		out.ResetAt = &resetAt
+	}
+	if d.transaction.bucketKey != "" {
+		out.Limit = d.transaction.limit.name.String()
+	}
+	return json.Marshal(out)
+}
+
+// String returns a human-readable summary of the Decision, e.g.
+// "denied: remaining=0 retryIn=1s resetIn=20s limit=NewOrdersPerAccount
+// bucketKey=3:12345678". The limit and bucketKey are omitted if no bucket was
+// evaluated.
+func (d *Decision) String() string {
+	decision := Allowed
+	if !d.Allowed {
+		decision = Denied
+	}
+	out := fmt.Sprintf("%s: remaining=%d retryIn=%s resetIn=%s", decision, d.Remaining, d.RetryIn, d.ResetIn)
+	if d.transaction.bucketKey != "" {
+		out += fmt.Sprintf(" limit=%s bucketKey=%s", d.transaction.limit.name, d.transaction.bucketKey)
+	}
+	return out
 }
 
 // Check DOES NOT deduct the cost of the request from the provided bucket's
@@ -87,23 +500,103 @@ type Decision struct {
 // cost WERE to be deducted. If no bucket exists it will NOT be created. No
 // state is persisted to the underlying datastore.
 func (l *Limiter) Check(ctx context.Context, txn Transaction) (*Decision, error) {
+	txns := []Transaction{txn}
+	ctx, span := l.startSpan(ctx, "Check", txns)
+	d, err := l.withHooks(ctx, "Check", txns, func(ctx context.Context, txns []Transaction) (*Decision, error) {
+		return l.check(ctx, txns[0])
+	})
+	endSpan(span, d, err)
+	return d, err
+}
+
+func (l *Limiter) check(ctx context.Context, txn Transaction) (*Decision, error) {
 	if txn.allowOnly() {
+		l.recordAllowOnly([]Transaction{txn})
 		return allowedDecision, nil
 	}
+	if txn.cost > txn.limit.Burst {
+		l.recordDenial(txn, nil, reasonCostOverBurst)
+		return nil, ErrInvalidCostOverLimit
+	}
 	// Remove cancellation from the request context so that transactions are not
 	// interrupted by a client disconnect.
 	ctx = context.WithoutCancel(ctx)
+	start := l.clk.Now()
 	tat, err := l.source.Get(ctx, txn.bucketKey)
 	if err != nil {
 		if !errors.Is(err, ErrBucketNotFound) {
+			l.recordDenial(txn, nil, reasonSourceErrorFailClosed)
 			return nil, err
 		}
 		// First request from this client. No need to initialize the bucket
 		// because this is a check, not a spend. A TAT of "now" is equivalent to
 		// a full bucket.
-		return maybeSpend(l.clk, txn.limit, l.clk.Now(), txn.cost), nil
+		tat = l.clk.Now()
+	}
+	d := maybeSpend(l.clk, txn.limit, tat, txn.cost)
+	d.transaction = txn
+	l.recordSpendDecision(txn, d)
+	decision := Allowed
+	if !d.Allowed {
+		decision = Denied
+	}
+	observeLatency(ctx, l.checkLatency.WithLabelValues(txn.limit.name.String(), decision), l.clk.Since(start).Seconds())
+	return d, nil
+}
+
+// BatchCheck DOES NOT deduct the costs of the provided Transactions from their
+// buckets' capacities. It returns a *Decision for each Transaction, in the same
+// order, representing the hypothetical state of each bucket IF its cost WERE to
+// be deducted. Allow-only Transactions are always allowed. Buckets which do not
+// exist are NOT created and no state is persisted to the underlying datastore.
+// Unlike Check, BatchCheck is intended for introspection: its Decisions are not
+// counted by the ratelimits_decisions_total metric or passed to the DenialHook.
+func (l *Limiter) BatchCheck(ctx context.Context, txns []Transaction) ([]*Decision, error) {
+	ctx, span := l.startSpan(ctx, "BatchCheck", txns)
+	decisions, err := l.batchCheck(ctx, txns)
+	endSpan(span, nil, err)
+	return decisions, err
+}
+
+func (l *Limiter) batchCheck(ctx context.Context, txns []Transaction) ([]*Decision, error) {
+	buf := getBatchBuffers()
+	defer buf.release()
+	err := buf.prepare(txns)
+	if err != nil {
+		return nil, err
+	}
+	for _, txn := range buf.txns {
+		if txn.cost > txn.limit.Burst {
+			return nil, ErrInvalidCostOverLimit
+		}
+	}
+
+	var tats map[string]time.Time
+	if len(buf.txns) > 0 {
+		// Remove cancellation from the request context so that transactions
+		// are not interrupted by a client disconnect.
+		ctx = context.WithoutCancel(ctx)
+		tats, err = l.source.BatchGet(ctx, buf.bucketKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	decisions := make([]*Decision, len(txns))
+	for i, txn := range txns {
+		if txn.allowOnly() {
+			decisions[i] = allowedDecision
+			continue
+		}
+		tat, exists := tats[txn.bucketKey]
+		if !exists {
+			// A TAT of "now" is equivalent to a full bucket.
+			tat = l.clk.Now()
+		}
+		decisions[i] = maybeSpend(l.clk, txn.limit, tat, txn.cost)
+		decisions[i].transaction = txn
 	}
-	return maybeSpend(l.clk, txn.limit, tat, txn.cost), nil
+	return decisions, nil
 }
 
 // Spend attempts to deduct the cost from the provided bucket's capacity. The
@@ -113,24 +606,84 @@ func (l *Limiter) Check(ctx context.Context, txn Transaction) (*Decision, error)
 // state is persisted to the underlying datastore, if applicable, before
 // returning.
 func (l *Limiter) Spend(ctx context.Context, txn Transaction) (*Decision, error) {
-	return l.BatchSpend(ctx, []Transaction{txn})
+	txns := []Transaction{txn}
+	ctx, span := l.startSpan(ctx, "Spend", txns)
+	d, err := l.withHooks(ctx, "Spend", txns, l.batchSpend)
+	endSpan(span, d, err)
+	return d, err
+}
+
+// recordDecision increments the decisions counter for the limit of the
+// provided Transaction.
+func (l *Limiter) recordDecision(txn Transaction, decision, reason string) {
+	l.decisionCounters.get(decisionLabels{txn.limit.name, decision, reason}).Inc()
+}
+
+// recordDenial increments the decisions counter for the limit of the provided
+// denied Transaction and, if configured, calls the DenialHook. The provided
+// *Decision may be nil if the Transaction was denied before the bucket was
+// evaluated.
+func (l *Limiter) recordDenial(txn Transaction, d *Decision, reason string) {
+	l.recordDecision(txn, Denied, reason)
+	if reason == reasonOverLimit {
+		l.heavyHitters.add(txn.bucketKey)
+	}
+	if l.denialHook == nil {
+		return
+	}
+	denial := Denial{
+		Limit:     txn.limit.name.String(),
+		BucketKey: txn.bucketKey,
+		Cost:      txn.cost,
+		Reason:    reason,
+	}
+	if d != nil {
+		denial.Remaining = d.Remaining
+		denial.RetryIn = d.RetryIn
+	}
+	l.denialHook(denial)
+}
+
+// recordSpendDecision increments the decisions counter for the limit of the
+// provided Transaction based on the provided *Decision.
+func (l *Limiter) recordSpendDecision(txn Transaction, d *Decision) {
+	if d.Allowed {
+		l.recordDecision(txn, Allowed, reasonWithinLimit)
+	} else {
+		l.recordDenial(txn, d, reasonOverLimit)
+	}
 }
 
-func prepareBatch(txns []Transaction) ([]Transaction, []string, error) {
-	var bucketKeys []string
-	var transactions []Transaction
+// recordAllowOnly increments the decisions counter for each allow-only
+// Transaction in txns, the exemptions counter for each exempt Transaction in
+// txns, and the disabled limits counter for each allow-only Transaction in txns
+// which is neither exempt nor a renewal.
+func (l *Limiter) recordAllowOnly(txns []Transaction) {
 	for _, txn := range txns {
-		if txn.allowOnly() {
-			// Ignore allow-only transactions.
+		if !txn.allowOnly() {
 			continue
 		}
-		if slices.Contains(bucketKeys, txn.bucketKey) {
-			return nil, nil, fmt.Errorf("found duplicate bucket %q in batch", txn.bucketKey)
+		if txn.exempt {
+			l.exemptionCounters.get(txn.limit.name).Inc()
+			l.recordDecision(txn, Allowed, reasonExempt)
+		} else if txn.renewal {
+			l.recordDecision(txn, Allowed, reasonRenewal)
+		} else {
+			l.disabledCounters.get(txn.limit.name).Inc()
+			l.recordDecision(txn, Allowed, reasonDisabled)
+		}
+	}
+}
+
+// recordSourceError increments the decisions counter for each Transaction in
+// txns which was denied because the source returned an error.
+func (l *Limiter) recordSourceError(txns []Transaction) {
+	for _, txn := range txns {
+		if txn.spendOnly() {
+			continue
 		}
-		bucketKeys = append(bucketKeys, txn.bucketKey)
-		transactions = append(transactions, txn)
+		l.recordDenial(txn, nil, reasonSourceErrorFailClosed)
 	}
-	return transactions, bucketKeys, nil
 }
 
 type batchDecision struct {
@@ -147,6 +700,11 @@ func newBatchDecision() *batchDecision {
 }
 
 func (d *batchDecision) merge(in *Decision) {
+	if d.Allowed && (!in.Allowed || in.Remaining < d.Remaining) {
+		// Keep the first denied Transaction or, until one is denied, the one
+		// with the least remaining capacity.
+		d.transaction = in.transaction
+	}
 	d.Allowed = d.Allowed && in.Allowed
 	d.Remaining = min(d.Remaining, in.Remaining)
 	d.RetryIn = max(d.RetryIn, in.RetryIn)
@@ -166,26 +724,44 @@ func (d *batchDecision) merge(in *Decision) {
 //   - Remaining is the smallest value of each across all Decisions, and
 //   - Decisions resulting from spend-only Transactions are never merged.
 func (l *Limiter) BatchSpend(ctx context.Context, txns []Transaction) (*Decision, error) {
-	batch, bucketKeys, err := prepareBatch(txns)
+	ctx, span := l.startSpan(ctx, "BatchSpend", txns)
+	d, err := l.withHooks(ctx, "BatchSpend", txns, l.batchSpend)
+	endSpan(span, d, err)
+	return d, err
+}
+
+func (l *Limiter) batchSpend(ctx context.Context, txns []Transaction) (*Decision, error) {
+	buf := getBatchBuffers()
+	defer buf.release()
+	err := buf.prepare(txns)
 	if err != nil {
 		return nil, err
 	}
+	batch := buf.txns
+	l.recordAllowOnly(txns)
+	l.batchSize.WithLabelValues("spend").Observe(float64(len(batch)))
 	if len(batch) == 0 {
 		// All Transactions were allow-only.
 		return allowedDecision, nil
 	}
+	for _, txn := range batch {
+		if txn.cost > txn.limit.Burst {
+			l.recordDenial(txn, nil, reasonCostOverBurst)
+			return nil, ErrInvalidCostOverLimit
+		}
+	}
 
 	// Remove cancellation from the request context so that transactions are not
 	// interrupted by a client disconnect.
 	ctx = context.WithoutCancel(ctx)
-	tats, err := l.source.BatchGet(ctx, bucketKeys)
+	tats, err := l.source.BatchGet(ctx, buf.bucketKeys)
 	if err != nil {
+		l.recordSourceError(batch)
 		return nil, err
 	}
 
 	start := l.clk.Now()
 	batchDecision := newBatchDecision()
-	newTATs := make(map[string]time.Time)
 
 	for _, txn := range batch {
 		tat, exists := tats[txn.bucketKey]
@@ -195,15 +771,24 @@ func (l *Limiter) BatchSpend(ctx context.Context, txns []Transaction) (*Decision
 		}
 
 		d := maybeSpend(l.clk, txn.limit, tat, txn.cost)
+		d.transaction = txn
+		buf.decisions = append(buf.decisions, d)
 
 		if txn.limit.isOverride {
 			utilization := float64(txn.limit.Burst-d.Remaining) / float64(txn.limit.Burst)
 			l.overrideUsageGauge.WithLabelValues(txn.limit.name.String(), txn.bucketKey).Set(utilization)
+			l.usage.setOverrideUtilization(l.clk.Now(), txn, utilization)
+			if l.overrideAlerter != nil {
+				l.overrideAlerter.observe(l.clk.Now(), txn, utilization)
+			}
 		}
 
 		if d.Allowed && (tat != d.newTAT) && txn.spend {
 			// New bucket state should be persisted.
-			newTATs[txn.bucketKey] = d.newTAT
+			buf.newTATs[txn.bucketKey] = d.newTAT
+			if !exists {
+				buf.created = append(buf.created, txn)
+			}
 		}
 
 		if !txn.spendOnly() {
@@ -212,13 +797,29 @@ func (l *Limiter) BatchSpend(ctx context.Context, txns []Transaction) (*Decision
 	}
 
 	if batchDecision.Allowed {
-		err = l.source.BatchSet(ctx, newTATs)
+		err = l.source.BatchSet(ctx, buf.newTATs)
 		if err != nil {
+			l.recordSourceError(batch)
 			return nil, err
 		}
-		l.spendLatency.WithLabelValues("batch", Allowed).Observe(l.clk.Since(start).Seconds())
+		for _, txn := range buf.created {
+			l.churnCounters.get(resultLabels{txn.limit.name, "created"}).Inc()
+		}
+		for i, txn := range batch {
+			if txn.spend && buf.decisions[i].Allowed {
+				l.usage.addSpend(txn)
+			}
+		}
+		observeLatency(ctx, l.spendLatency.WithLabelValues("batch", Allowed), l.clk.Since(start).Seconds())
 	} else {
-		l.spendLatency.WithLabelValues("batch", Denied).Observe(l.clk.Since(start).Seconds())
+		observeLatency(ctx, l.spendLatency.WithLabelValues("batch", Denied), l.clk.Since(start).Seconds())
+	}
+	for i, txn := range batch {
+		if txn.spendOnly() {
+			// Spend-only Transactions are never denied.
+			continue
+		}
+		l.recordSpendDecision(txn, buf.decisions[i])
 	}
 	return batchDecision.Decision, nil
 }
@@ -236,7 +837,11 @@ func (l *Limiter) BatchSpend(ctx context.Context, txns []Transaction) (*Decision
 // requests remaining, a refund request of 7 will result in the bucket reaching
 // its maximum capacity of 10, not 12.
 func (l *Limiter) Refund(ctx context.Context, txn Transaction) (*Decision, error) {
-	return l.BatchRefund(ctx, []Transaction{txn})
+	txns := []Transaction{txn}
+	ctx, span := l.startSpan(ctx, "Refund", txns)
+	d, err := l.withHooks(ctx, "Refund", txns, l.batchRefund)
+	endSpan(span, d, err)
+	return d, err
 }
 
 // BatchRefund attempts to refund all or some of the costs to the provided
@@ -250,10 +855,21 @@ func (l *Limiter) Refund(ctx context.Context, txn Transaction) (*Decision, error
 //   - Remaining is the smallest value of each across all Decisions, and
 //   - Decisions resulting from spend-only Transactions are never merged.
 func (l *Limiter) BatchRefund(ctx context.Context, txns []Transaction) (*Decision, error) {
-	batch, bucketKeys, err := prepareBatch(txns)
+	ctx, span := l.startSpan(ctx, "BatchRefund", txns)
+	d, err := l.withHooks(ctx, "BatchRefund", txns, l.batchRefund)
+	endSpan(span, d, err)
+	return d, err
+}
+
+func (l *Limiter) batchRefund(ctx context.Context, txns []Transaction) (*Decision, error) {
+	buf := getBatchBuffers()
+	defer buf.release()
+	err := buf.prepare(txns)
 	if err != nil {
 		return nil, err
 	}
+	batch := buf.txns
+	l.batchSize.WithLabelValues("refund").Observe(float64(len(batch)))
 	if len(batch) == 0 {
 		// All Transactions were allow-only.
 		return allowedDecision, nil
@@ -262,15 +878,17 @@ func (l *Limiter) BatchRefund(ctx context.Context, txns []Transaction) (*Decisio
 	// Remove cancellation from the request context so that transactions are not
 	// interrupted by a client disconnect.
 	ctx = context.WithoutCancel(ctx)
-	tats, err := l.source.BatchGet(ctx, bucketKeys)
+	start := l.clk.Now()
+	tats, err := l.source.BatchGet(ctx, buf.bucketKeys)
 	if err != nil {
+		l.recordRefundError(ctx, batch, start)
 		return nil, err
 	}
 
 	batchDecision := newBatchDecision()
-	newTATs := make(map[string]time.Time)
 
-	for _, txn := range batch {
+	for i, txn := range batch {
+		buf.results = append(buf.results, refundNoop)
 		tat, exists := tats[txn.bucketKey]
 		if !exists {
 			// Ignore non-existent bucket.
@@ -282,27 +900,167 @@ func (l *Limiter) BatchRefund(ctx context.Context, txns []Transaction) (*Decisio
 			cost = txn.cost
 		}
 		d := maybeRefund(l.clk, txn.limit, tat, cost)
+		d.transaction = txn
 		batchDecision.merge(d)
 		if d.Allowed && tat != d.newTAT {
 			// New bucket state should be persisted.
-			newTATs[txn.bucketKey] = d.newTAT
+			buf.newTATs[txn.bucketKey] = d.newTAT
+			buf.results[i] = refundPartial
+			if tat.Sub(d.newTAT) == time.Duration(txn.limit.emissionInterval*cost) {
+				buf.results[i] = refundFull
+			}
 		}
 	}
 
-	if len(newTATs) > 0 {
-		err = l.source.BatchSet(ctx, newTATs)
+	if len(buf.newTATs) > 0 {
+		err = l.source.BatchSet(ctx, buf.newTATs)
 		if err != nil {
+			l.recordRefundError(ctx, batch, start)
 			return nil, err
 		}
 	}
+	for i, txn := range batch {
+		l.refundCounters.get(resultLabels{txn.limit.name, buf.results[i]}).Inc()
+	}
+	observeLatency(ctx, l.refundLatency.WithLabelValues("success"), l.clk.Since(start).Seconds())
 	return batchDecision.Decision, nil
 }
 
+// recordRefundError increments the refunds counter for each Transaction in txns
+// and observes the refund latency since start when the source returned an
+// error.
+func (l *Limiter) recordRefundError(ctx context.Context, txns []Transaction, start time.Time) {
+	for _, txn := range txns {
+		l.refundCounters.get(resultLabels{txn.limit.name, refundFailed}).Inc()
+	}
+	observeLatency(ctx, l.refundLatency.WithLabelValues("error"), l.clk.Since(start).Seconds())
+}
+
+// updateActiveBuckets sets the active buckets gauge for each limit to the
+// number of buckets estimated by the source.
+func (l *Limiter) updateActiveBuckets(ctx context.Context) error {
+	if l.estimator == nil {
+		return errors.New("source does not support estimating buckets")
+	}
+	estimates, err := l.estimator.estimateBuckets(ctx)
+	if err != nil {
+		return err
+	}
+	for name, str := range nameToString {
+		if name == Unknown {
+			continue
+		}
+		l.activeBuckets.WithLabelValues(str).Set(float64(estimates[name]))
+	}
+	return nil
+}
+
+// Health returns an error if the Limiter's source is unreachable and nil
+// otherwise. Sources which cannot check their connectivity, such as the
+// in-memory source, are always considered reachable.
+func (l *Limiter) Health(ctx context.Context) error {
+	if l.pinger == nil {
+		return nil
+	}
+	return l.pinger.Ping(ctx)
+}
+
+// ReportActiveBuckets estimates the number of buckets stored by the source for
+// each limit, every interval, and exports the estimates as the
+// ratelimits_active_buckets gauge. Errors are logged to the provided logger.
+// It blocks until the provided context is canceled.
+func (l *Limiter) ReportActiveBuckets(ctx context.Context, interval time.Duration, logger blog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := l.updateActiveBuckets(ctx)
+		if err != nil {
+			logger.Warningf("estimating active rate limit buckets: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // Reset resets the specified bucket to its maximum capacity. The new bucket
 // state is persisted to the underlying datastore before returning.
 func (l *Limiter) Reset(ctx context.Context, bucketKey string) error {
 	// Remove cancellation from the request context so that transactions are not
 	// interrupted by a client disconnect.
 	ctx = context.WithoutCancel(ctx)
-	return l.source.Delete(ctx, bucketKey)
+	err := l.source.Delete(ctx, bucketKey)
+	if err != nil {
+		return err
+	}
+	l.churnCounters.get(resultLabels{nameForBucketKey(bucketKey), "deleted"}).Inc()
+	return nil
+}
+
+// resetPrefixBatchSize is the approximate number of buckets deleted by each
+// batch of a ResetPrefix.
+const resetPrefixBatchSize = 500
+
+// ResetPrefix resets every bucket whose key begins with the provided prefix to
+// its maximum capacity and returns the number of buckets reset. The prefix must
+// begin with the '<enum>:' of a valid limit, and only ever matches whole
+// segments of a bucket key: '3:' matches every bucket of NewOrdersPerAccount,
+// while '3:1234' matches the bucket of registration ID 1234 and those whose
+// keys continue with another ':'-separated segment, e.g. '3:1234:example.com',
+// but not that of registration ID 12345. Buckets are deleted in batches, and,
+// if progress is non-nil, it is called with the running total after each
+// batch. If an error is returned, buckets deleted before the error remain
+// deleted.
+func (l *Limiter) ResetPrefix(ctx context.Context, prefix string, progress func(reset int64)) (int64, error) {
+	if nameForBucketKey(prefix) == Unknown {
+		return 0, fmt.Errorf("prefix %q does not begin with the '<enum>:' of a valid limit", prefix)
+	}
+	if l.prefixDeleter == nil {
+		return 0, errors.New("source does not support resetting buckets by prefix")
+	}
+
+	// Remove cancellation from the request context so that a reset is not
+	// interrupted by a client disconnect.
+	ctx = context.WithoutCancel(ctx)
+	if strings.HasSuffix(prefix, ":") {
+		return l.resetPrefix(ctx, prefix, 0, progress)
+	}
+
+	// The prefix is a whole bucket key, which is reset before the buckets whose
+	// keys extend it.
+	var reset int64
+	_, err := l.source.Get(ctx, prefix)
+	if err == nil {
+		err = l.source.Delete(ctx, prefix)
+		if err != nil {
+			return 0, err
+		}
+		l.churnCounters.get(resultLabels{nameForBucketKey(prefix), "deleted"}).Inc()
+		reset = 1
+	} else if !errors.Is(err, ErrBucketNotFound) {
+		return 0, err
+	}
+	return l.resetPrefix(ctx, prefix+":", reset, progress)
+}
+
+// resetPrefix resets every bucket whose key begins with the provided prefix,
+// which, unlike that of ResetPrefix, may end part way through a segment. The
+// running total passed to progress begins at the provided number of buckets
+// already reset, which is included in the returned total.
+func (l *Limiter) resetPrefix(ctx context.Context, prefix string, total int64, progress func(reset int64)) (int64, error) {
+	if total > 0 && progress != nil {
+		progress(total)
+	}
+	err := l.prefixDeleter.deletePrefix(ctx, prefix, resetPrefixBatchSize, 0, func(bucketKeys []string) {
+		for _, bucketKey := range bucketKeys {
+			l.churnCounters.get(resultLabels{nameForBucketKey(bucketKey), "deleted"}).Inc()
+		}
+		total += int64(len(bucketKeys))
+		if progress != nil {
+			progress(total)
+		}
+	})
+	return total, err
 }
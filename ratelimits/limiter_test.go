@@ -2,12 +2,19 @@ package ratelimits
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"net"
 	"testing"
 	"time"
 
 	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/config"
+	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/test"
 	"github.com/prometheus/client_golang/prometheus"
@@ -29,7 +36,7 @@ func newTestLimiter(t *testing.T, s source, clk clock.FakeClock) *Limiter {
 //   - 'NewRegistrationsPerIPAddress' burst: 20 count: 20 period: 1s
 //   - 'NewRegistrationsPerIPAddress:10.0.0.2' burst: 40 count: 40 period: 1s
 func newTestTransactionBuilder(t *testing.T) *TransactionBuilder {
-	c, err := NewTransactionBuilder("testdata/working_default.yml", "testdata/working_override.yml")
+	c, err := NewTransactionBuilder("testdata/working_default.yml", "", "testdata/working_override.yml", "")
 	test.AssertNotError(t, err, "should not error")
 	return c
 }
@@ -63,7 +70,7 @@ func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 				"limit":      NewRegistrationsPerIPAddress.String(),
 				"bucket_key": joinWithColon(NewRegistrationsPerIPAddress.EnumString(), tenZeroZeroTwo)}, 0)
 
-			overriddenBucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(tenZeroZeroTwo))
+			overriddenBucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, net.ParseIP(tenZeroZeroTwo))
 			test.AssertNotError(t, err, "should not error")
 			overriddenLimit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, overriddenBucketKey)
 			test.AssertNotError(t, err, "should not error")
@@ -126,7 +133,7 @@ func TestLimiter_CheckWithLimitOverrides(t *testing.T) {
 			clk.Add(d.ResetIn)
 
 			testIP := net.ParseIP(testIP)
-			normalBucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, testIP)
+			normalBucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, testIP)
 			test.AssertNotError(t, err, "should not error")
 			normalLimit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, normalBucketKey)
 			test.AssertNotError(t, err, "should not error")
@@ -257,7 +264,7 @@ func TestLimiter_InitializationViaCheckAndSpend(t *testing.T) {
 	testCtx, limiters, txnBuilder, _, testIP := setup(t)
 	for name, l := range limiters {
 		t.Run(name, func(t *testing.T) {
-			bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			bucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
 			test.AssertNotError(t, err, "should not error")
 			limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
 			test.AssertNotError(t, err, "should not error")
@@ -320,7 +327,7 @@ func TestLimiter_DefaultLimits(t *testing.T) {
 	testCtx, limiters, txnBuilder, clk, testIP := setup(t)
 	for name, l := range limiters {
 		t.Run(name, func(t *testing.T) {
-			bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			bucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
 			test.AssertNotError(t, err, "should not error")
 			limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
 			test.AssertNotError(t, err, "should not error")
@@ -383,7 +390,7 @@ func TestLimiter_RefundAndReset(t *testing.T) {
 	testCtx, limiters, txnBuilder, clk, testIP := setup(t)
 	for name, l := range limiters {
 		t.Run(name, func(t *testing.T) {
-			bucketKey, err := newIPAddressBucketKey(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
+			bucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, net.ParseIP(testIP))
 			test.AssertNotError(t, err, "should not error")
 			limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
 			test.AssertNotError(t, err, "should not error")
@@ -456,3 +463,720 @@ func TestLimiter_RefundAndReset(t *testing.T) {
 		})
 	}
 }
+
+func TestLimiter_Exemptions(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	b, err := NewTransactionBuilder("testdata/working_defaults_per_domain.yml", "", "", "testdata/working_exemptions.yml")
+	test.AssertNotError(t, err, "should not error")
+
+	txns, err := b.CertificatesPerDomainTransactions(1337, []string{"example.com", "example.org"})
+	test.AssertNotError(t, err, "should not error")
+
+	// Exempt Transactions are always allowed and never create buckets.
+	for i := 0; i < 5; i++ {
+		d, err := l.BatchSpend(context.Background(), txns)
+		test.AssertNotError(t, err, "should not error")
+		test.Assert(t, d.Allowed, "should be allowed")
+	}
+	test.AssertMetricWithLabelsEquals(t, l.exemptions, prometheus.Labels{"limit": CertificatesPerDomain.String()}, 10)
+
+	d, err := l.Check(context.Background(), txns[0])
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertMetricWithLabelsEquals(t, l.exemptions, prometheus.Labels{"limit": CertificatesPerDomain.String()}, 11)
+
+	_, err = l.source.Get(context.Background(), txns[0].bucketKey)
+	test.AssertErrorIs(t, err, ErrBucketNotFound)
+}
+
+func TestLimiter_BatchCheck(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+
+	// 10.0.0.1 has the default burst of 20, 10.0.0.2 is overridden to 40.
+	txn1, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	txn2, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(tenZeroZeroTwo))
+	test.AssertNotError(t, err, "should not error")
+	allowOnly, err := newAllowOnlyTransaction(CertificatesPerDomain)
+	test.AssertNotError(t, err, "should not error")
+
+	_, err = l.Spend(context.Background(), txn2)
+	test.AssertNotError(t, err, "should not error")
+
+	for i := 0; i < 2; i++ {
+		ds, err := l.BatchCheck(context.Background(), []Transaction{txn1, txn2, allowOnly})
+		test.AssertNotError(t, err, "should not error")
+		test.AssertEquals(t, len(ds), 3)
+		test.Assert(t, ds[0].Allowed, "should be allowed")
+		test.AssertEquals(t, ds[0].Remaining, int64(19))
+		test.Assert(t, ds[1].Allowed, "should be allowed")
+		test.AssertEquals(t, ds[1].Remaining, int64(38))
+		test.Assert(t, ds[2].Allowed, "should be allowed")
+	}
+
+	// BatchCheck does not create buckets or count decisions.
+	_, err = l.source.Get(context.Background(), txn1.bucketKey)
+	test.AssertErrorIs(t, err, ErrBucketNotFound)
+	test.AssertMetricWithLabelsEquals(t, l.decisions, prometheus.Labels{"limit": NewRegistrationsPerIPAddress.String()}, 1)
+
+	_, err = newTestLimiter(t, erroringSource{}, clk).BatchCheck(context.Background(), []Transaction{txn1})
+	test.AssertErrorIs(t, err, errSourceUnavailable)
+}
+
+func TestDecision_MarshalJSONAndString(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	d, err := l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+
+	out, err := json.Marshal(d)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertUnmarshaledEquals(t, string(out), `{"allowed": true, "remaining": 19, "retryIn": "0s", "resetIn": "50ms",
+		"resetAt": "`+clk.Now().Add(50*time.Millisecond).UTC().Format(time.RFC3339Nano)+`",
+		"limit": "NewRegistrationsPerIPAddress", "bucketKey": "1:10.0.0.1"}`)
+	test.AssertEquals(t, d.String(), "allowed: remaining=19 retryIn=0s resetIn=50ms limit=NewRegistrationsPerIPAddress bucketKey=1:10.0.0.1")
+	test.AssertEquals(t, d.Burst(), int64(20))
+
+	// The bucket for 10.0.0.2 has more capacity, so the batch decision
+	// identifies the bucket for 10.0.0.1.
+	txn2, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(tenZeroZeroTwo))
+	test.AssertNotError(t, err, "should not error")
+	d, err = l.BatchSpend(context.Background(), []Transaction{txn2, txn})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.String(), "allowed: remaining=18 retryIn=0s resetIn=100ms limit=NewRegistrationsPerIPAddress bucketKey=1:10.0.0.1")
+
+	// Deny 10.0.0.2, the batch decision identifies it despite 10.0.0.1 having
+	// less remaining capacity.
+	txn2, err = newTransaction(txn2.limit, txn2.bucketKey, 40)
+	test.AssertNotError(t, err, "should not error")
+	d, err = l.BatchSpend(context.Background(), []Transaction{txn, txn2})
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+	test.AssertContains(t, d.String(), "bucketKey=1:10.0.0.2")
+
+	// Allow-only Decisions have no bucket.
+	test.AssertEquals(t, allowedDecision.String(), fmt.Sprintf("allowed: remaining=%d retryIn=0s resetIn=0s", int64(math.MaxInt64)))
+	out, err = json.Marshal(allowedDecision)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertNotContains(t, string(out), "bucketKey")
+	test.AssertNotContains(t, string(out), "resetAt")
+	test.AssertEquals(t, allowedDecision.Burst(), int64(0))
+}
+
+// recordingHook is a Hook which records each call and, if err is set, fails
+// every call to Before.
+type recordingHook struct {
+	calls []string
+	err   error
+}
+
+func (h *recordingHook) Before(_ context.Context, op string, txns []Transaction) error {
+	h.calls = append(h.calls, fmt.Sprintf("before %s %s", op, txns[0].BucketKey()))
+	return h.err
+}
+
+func (h *recordingHook) After(_ context.Context, op string, _ []Transaction, d *Decision, err error) {
+	h.calls = append(h.calls, fmt.Sprintf("after %s %t %v", op, d != nil && d.Allowed, err))
+}
+
+func TestLimiter_Hooks(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	first := &recordingHook{}
+	second := &recordingHook{}
+	l.AddHook(first)
+	l.AddHook(second)
+
+	txn, err := newTestTransactionBuilder(t).RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	_, err = l.Check(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	_, err = l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	_, err = l.BatchRefund(context.Background(), []Transaction{txn})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertDeepEquals(t, first.calls, []string{
+		"before Check 1:10.0.0.1", "after Check true <nil>",
+		"before Spend 1:10.0.0.1", "after Spend true <nil>",
+		"before BatchRefund 1:10.0.0.1", "after BatchRefund true <nil>",
+	})
+	test.AssertDeepEquals(t, second.calls, first.calls)
+
+	// A Hook which fails Before prevents evaluation, and only the Hooks
+	// before it are called After.
+	err = l.Reset(context.Background(), txn.bucketKey)
+	test.AssertNotError(t, err, "should not error")
+	first.calls, second.calls = nil, nil
+	errPolicy := errors.New("denied by policy")
+	second.err = errPolicy
+	third := &recordingHook{}
+	l.AddHook(third)
+	_, err = l.Spend(context.Background(), txn)
+	test.AssertErrorIs(t, err, errPolicy)
+	test.AssertDeepEquals(t, first.calls, []string{"before Spend 1:10.0.0.1", "after Spend false denied by policy"})
+	test.AssertDeepEquals(t, second.calls, []string{"before Spend 1:10.0.0.1"})
+	test.AssertEquals(t, len(third.calls), 0)
+	_, err = l.source.Get(context.Background(), txn.bucketKey)
+	test.AssertErrorIs(t, err, ErrBucketNotFound)
+}
+
+// erroringSource is a source which fails every call.
+type erroringSource struct{}
+
+var errSourceUnavailable = errors.New("source unavailable")
+
+func (erroringSource) BatchSet(context.Context, map[string]time.Time) error {
+	return errSourceUnavailable
+}
+
+func (erroringSource) Get(context.Context, string) (time.Time, error) {
+	return time.Time{}, errSourceUnavailable
+}
+
+func (erroringSource) BatchGet(context.Context, []string) (map[string]time.Time, error) {
+	return nil, errSourceUnavailable
+}
+
+func (erroringSource) Delete(context.Context, string) error {
+	return errSourceUnavailable
+}
+
+func (erroringSource) Ping(context.Context) error {
+	return errSourceUnavailable
+}
+
+func TestLimiter_Health(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+
+	err := newInmemTestLimiter(t, clk).Health(context.Background())
+	test.AssertNotError(t, err, "in-memory source should always be healthy")
+
+	err = newTestLimiter(t, erroringSource{}, clk).Health(context.Background())
+	test.AssertErrorIs(t, err, errSourceUnavailable)
+}
+
+func TestLimiter_Decisions(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	limitLabel := NewRegistrationsPerIPAddress.String()
+
+	bucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, net.ParseIP("10.0.0.5"))
+	test.AssertNotError(t, err, "should not error")
+	limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	test.AssertNotError(t, err, "should not error")
+
+	// Spend the entire bucket and then exceed it.
+	txn20, err := newTransaction(limit, bucketKey, 20)
+	test.AssertNotError(t, err, "txn should be valid")
+	d, err := l.Spend(context.Background(), txn20)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertMetricWithLabelsEquals(t, l.decisions, prometheus.Labels{"limit": limitLabel, "decision": Allowed, "reason": reasonWithinLimit}, 1)
+
+	txn1, err := newTransaction(limit, bucketKey, 1)
+	test.AssertNotError(t, err, "txn should be valid")
+	d, err = l.Check(context.Background(), txn1)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+	d, err = l.Spend(context.Background(), txn1)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+	test.AssertMetricWithLabelsEquals(t, l.decisions, prometheus.Labels{"limit": limitLabel, "decision": Denied, "reason": reasonOverLimit}, 2)
+
+	// A cost over the burst can never be satisfied.
+	txnOverBurst := txn1
+	txnOverBurst.cost = limit.Burst + 1
+	_, err = l.Spend(context.Background(), txnOverBurst)
+	test.AssertErrorIs(t, err, ErrInvalidCostOverLimit)
+	_, err = l.Check(context.Background(), txnOverBurst)
+	test.AssertErrorIs(t, err, ErrInvalidCostOverLimit)
+	test.AssertMetricWithLabelsEquals(t, l.decisions, prometheus.Labels{"limit": limitLabel, "decision": Denied, "reason": reasonCostOverBurst}, 2)
+
+	// Disabled limits are always allowed.
+	disabled, err := newAllowOnlyTransaction(NewOrdersPerAccount)
+	test.AssertNotError(t, err, "txn should be valid")
+	d, err = l.Spend(context.Background(), disabled)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertMetricWithLabelsEquals(t, l.decisions, prometheus.Labels{"limit": NewOrdersPerAccount.String(), "decision": Allowed, "reason": reasonDisabled}, 1)
+
+	// Errors from the source fail closed.
+	l = newTestLimiter(t, erroringSource{}, clk)
+	_, err = l.Check(context.Background(), txn1)
+	test.AssertErrorIs(t, err, errSourceUnavailable)
+	_, err = l.Spend(context.Background(), txn1)
+	test.AssertErrorIs(t, err, errSourceUnavailable)
+	test.AssertMetricWithLabelsEquals(t, l.decisions, prometheus.Labels{"limit": limitLabel, "decision": Denied, "reason": reasonSourceErrorFailClosed}, 2)
+}
+
+func TestLimiter_DenialHook(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	var denials []Denial
+	l.SetDenialHook(func(d Denial) { denials = append(denials, d) })
+	txnBuilder := newTestTransactionBuilder(t)
+
+	bucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, net.ParseIP("10.0.0.6"))
+	test.AssertNotError(t, err, "should not error")
+	limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	test.AssertNotError(t, err, "should not error")
+	txn20, err := newTransaction(limit, bucketKey, 20)
+	test.AssertNotError(t, err, "txn should be valid")
+
+	// Allowed Transactions are not reported.
+	_, err = l.Spend(context.Background(), txn20)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(denials), 0)
+
+	d, err := l.Spend(context.Background(), txn20)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+	test.AssertEquals(t, len(denials), 1)
+	test.AssertDeepEquals(t, denials[0], Denial{
+		Limit:     NewRegistrationsPerIPAddress.String(),
+		BucketKey: bucketKey,
+		Cost:      20,
+		Reason:    reasonOverLimit,
+		Remaining: d.Remaining,
+		RetryIn:   d.RetryIn,
+	})
+
+	log := blog.NewMock()
+	l.SetDenialHook(LogDenials(log))
+	_, err = l.Check(context.Background(), txn20)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(log.GetAllMatching(`Rate limit denied JSON=.*"BucketKey":"`+bucketKey+`"`)), 1)
+}
+
+func TestLimiter_ActiveBuckets(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+
+	for _, ip := range []string{"10.0.0.7", "10.0.0.8", "10.0.0.9"} {
+		txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(ip))
+		test.AssertNotError(t, err, "txn should be valid")
+		_, err = l.Spend(context.Background(), txn)
+		test.AssertNotError(t, err, "should not error")
+	}
+
+	err := l.updateActiveBuckets(context.Background())
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.activeBuckets, prometheus.Labels{"limit": NewRegistrationsPerIPAddress.String()}, 3)
+	test.AssertMetricWithLabelsEquals(t, l.activeBuckets, prometheus.Labels{"limit": NewOrdersPerAccount.String()}, 0)
+
+	// Sources which cannot estimate buckets are reported as an error.
+	l = newTestLimiter(t, erroringSource{}, clk)
+	err = l.updateActiveBuckets(context.Background())
+	test.AssertError(t, err, "should error")
+}
+
+func TestLimiter_ResetPrefix(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+
+	for _, ip := range []string{"10.0.1.1", "10.0.1.2", "10.0.2.1"} {
+		txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(ip))
+		test.AssertNotError(t, err, "txn should be valid")
+		_, err = l.Spend(context.Background(), txn)
+		test.AssertNotError(t, err, "should not error")
+	}
+
+	_, err := l.ResetPrefix(context.Background(), "10.0.1.", nil)
+	test.AssertError(t, err, "prefix without a limit enum should be rejected")
+
+	// Prefixes only match whole segments of bucket keys, so a partial IP
+	// address matches nothing.
+	var progress []int64
+	reset, err := l.ResetPrefix(context.Background(), joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.1."), func(n int64) {
+		progress = append(progress, n)
+	})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, reset, int64(0))
+	test.AssertEquals(t, len(progress), 0)
+
+	reset, err = l.ResetPrefix(context.Background(), joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.1.1"), func(n int64) {
+		progress = append(progress, n)
+	})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, reset, int64(1))
+	test.AssertDeepEquals(t, progress, []int64{1})
+	test.AssertMetricWithLabelsEquals(t, l.bucketChurn, prometheus.Labels{"limit": NewRegistrationsPerIPAddress.String(), "event": "deleted"}, 1)
+
+	err = l.updateActiveBuckets(context.Background())
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.activeBuckets, prometheus.Labels{"limit": NewRegistrationsPerIPAddress.String()}, 2)
+
+	// Resetting regId 1234 resets its bucket, but leaves that of regId 12345.
+	for _, id := range []string{"1234", "12345"} {
+		err = l.source.BatchSet(context.Background(), map[string]time.Time{joinWithColon(NewOrdersPerAccount.EnumString(), id): clk.Now()})
+		test.AssertNotError(t, err, "should not error")
+	}
+	reset, err = l.ResetPrefix(context.Background(), joinWithColon(NewOrdersPerAccount.EnumString(), "1234"), nil)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, reset, int64(1))
+
+	// Likewise for the per domain buckets of each regId.
+	for _, id := range []string{"1234:example.com", "1234:example.net", "12345:example.com"} {
+		err = l.source.BatchSet(context.Background(), map[string]time.Time{joinWithColon(CertificatesPerDomainPerAccount.EnumString(), id): clk.Now()})
+		test.AssertNotError(t, err, "should not error")
+	}
+	reset, err = l.ResetPrefix(context.Background(), joinWithColon(CertificatesPerDomainPerAccount.EnumString(), "1234"), nil)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, reset, int64(2))
+
+	for key, exists := range map[string]bool{
+		joinWithColon(NewOrdersPerAccount.EnumString(), "1234"):                          false,
+		joinWithColon(NewOrdersPerAccount.EnumString(), "12345"):                         true,
+		joinWithColon(CertificatesPerDomainPerAccount.EnumString(), "1234:example.com"):  false,
+		joinWithColon(CertificatesPerDomainPerAccount.EnumString(), "12345:example.com"): true,
+	} {
+		_, err = l.source.Get(context.Background(), key)
+		if exists {
+			test.AssertNotError(t, err, fmt.Sprintf("bucket %q should survive", key))
+		} else {
+			test.AssertErrorIs(t, err, ErrBucketNotFound)
+		}
+	}
+
+	// A prefix ending in ':' matches every bucket key beginning with it.
+	reset, err = l.ResetPrefix(context.Background(), NewRegistrationsPerIPAddress.EnumString()+":", nil)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, reset, int64(2))
+
+	// Sources which cannot delete by prefix are reported as an error.
+	l = newTestLimiter(t, erroringSource{}, clk)
+	_, err = l.ResetPrefix(context.Background(), NewRegistrationsPerIPAddress.EnumString()+":", nil)
+	test.AssertError(t, err, "should error")
+}
+
+func TestLimiter_BatchSize(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+
+	var txns []Transaction
+	for _, ip := range []string{"10.0.0.11", "10.0.0.12", "10.0.0.13"} {
+		txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(ip))
+		test.AssertNotError(t, err, "txn should be valid")
+		txns = append(txns, txn)
+	}
+	_, err := l.BatchSpend(context.Background(), txns)
+	test.AssertNotError(t, err, "should not error")
+	_, err = l.Spend(context.Background(), txns[0])
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.batchSize, prometheus.Labels{"op": "spend"}, 2)
+
+	_, err = l.BatchRefund(context.Background(), txns)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.batchSize, prometheus.Labels{"op": "refund"}, 1)
+}
+
+func TestLimiter_CheckLatency(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.14"))
+	test.AssertNotError(t, err, "txn should be valid")
+	_, err = l.Check(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.checkLatency, prometheus.Labels{"limit": NewRegistrationsPerIPAddress.String(), "decision": Allowed}, 1)
+	test.AssertMetricWithLabelsEquals(t, l.checkLatency, prometheus.Labels{"decision": Denied}, 0)
+}
+
+func TestLimiter_BatchSpendMetricsPerLimit(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+
+	ipTxn, err := newTestTransactionBuilder(t).RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.15"))
+	test.AssertNotError(t, err, "txn should be valid")
+	b, err := NewTransactionBuilder("testdata/working_defaults_per_domain.yml", "", "", "")
+	test.AssertNotError(t, err, "should not error")
+	domainTxns, err := b.CertificatesPerDomainTransactions(1337, []string{"example.com", "example.org"})
+	test.AssertNotError(t, err, "should not error")
+
+	txns := append([]Transaction{ipTxn}, domainTxns...)
+	_, err = l.BatchSpend(context.Background(), txns)
+	test.AssertNotError(t, err, "should not error")
+
+	// Decisions are counted for each limit individually.
+	test.AssertMetricWithLabelsEquals(t, l.decisions, prometheus.Labels{"limit": NewRegistrationsPerIPAddress.String(), "decision": Allowed}, 1)
+	test.AssertMetricWithLabelsEquals(t, l.decisions, prometheus.Labels{"limit": CertificatesPerDomain.String(), "decision": Allowed}, 2)
+
+	// Latency is observed once per batch, under a single label.
+	test.AssertMetricWithLabelsEquals(t, l.spendLatency, prometheus.Labels{"limit": "batch"}, 1)
+	test.AssertMetricWithLabelsEquals(t, l.spendLatency, prometheus.Labels{}, 1)
+}
+
+func TestLimiter_DisabledLimits(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+
+	// No CertificatesPerDomain default is configured.
+	txnBuilder := newTestTransactionBuilder(t)
+	txns, err := txnBuilder.CertificatesPerDomainTransactions(1337, []string{"example.com", "example.org"})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(txns), 2)
+	for _, txn := range txns {
+		test.Assert(t, txn.allowOnly(), "should be allow-only")
+	}
+	d, err := l.BatchSpend(context.Background(), txns)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertMetricWithLabelsEquals(t, l.disabledLimits, prometheus.Labels{"limit": CertificatesPerDomain.String()}, 2)
+
+	txn, err := newAllowOnlyTransaction(NewOrdersPerAccount)
+	test.AssertNotError(t, err, "should not error")
+	_, err = l.Check(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.disabledLimits, prometheus.Labels{"limit": NewOrdersPerAccount.String()}, 1)
+}
+
+func TestLimiter_RefundMetrics(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	limitLabel := NewRegistrationsPerIPAddress.String()
+
+	bucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, net.ParseIP("10.0.0.16"))
+	test.AssertNotError(t, err, "should not error")
+	limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	test.AssertNotError(t, err, "should not error")
+	txn10, err := newTransaction(limit, bucketKey, 10)
+	test.AssertNotError(t, err, "txn should be valid")
+	txn5, err := newTransaction(limit, bucketKey, 5)
+	test.AssertNotError(t, err, "txn should be valid")
+
+	_, err = l.Spend(context.Background(), txn10)
+	test.AssertNotError(t, err, "should not error")
+
+	// 10 spent, refund 5.
+	_, err = l.Refund(context.Background(), txn5)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.refunds, prometheus.Labels{"limit": limitLabel, "result": refundFull}, 1)
+
+	// 5 spent, refund 10.
+	_, err = l.Refund(context.Background(), txn10)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.refunds, prometheus.Labels{"limit": limitLabel, "result": refundPartial}, 1)
+
+	// Nothing spent, refund 5.
+	_, err = l.Refund(context.Background(), txn5)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.refunds, prometheus.Labels{"limit": limitLabel, "result": refundNoop}, 1)
+	test.AssertMetricWithLabelsEquals(t, l.refundLatency, prometheus.Labels{"result": "success"}, 3)
+
+	l = newTestLimiter(t, erroringSource{}, clk)
+	_, err = l.Refund(context.Background(), txn5)
+	test.AssertErrorIs(t, err, errSourceUnavailable)
+	test.AssertMetricWithLabelsEquals(t, l.refunds, prometheus.Labels{"limit": limitLabel, "result": refundFailed}, 1)
+	test.AssertMetricWithLabelsEquals(t, l.refundLatency, prometheus.Labels{"result": "error"}, 1)
+}
+
+func TestLimiter_BucketChurn(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	limitLabel := NewRegistrationsPerIPAddress.String()
+
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.17"))
+	test.AssertNotError(t, err, "txn should be valid")
+
+	// Only the first spend creates the bucket.
+	for i := 0; i < 3; i++ {
+		_, err = l.Spend(context.Background(), txn)
+		test.AssertNotError(t, err, "should not error")
+	}
+	test.AssertMetricWithLabelsEquals(t, l.bucketChurn, prometheus.Labels{"limit": limitLabel, "event": "created"}, 1)
+
+	err = l.Reset(context.Background(), txn.bucketKey)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.bucketChurn, prometheus.Labels{"limit": limitLabel, "event": "deleted"}, 1)
+
+	_, err = l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, l.bucketChurn, prometheus.Labels{"limit": limitLabel, "event": "created"}, 2)
+}
+
+// blockingSource is a source whose calls block until their context is done.
+type blockingSource struct{}
+
+func (blockingSource) BatchSet(ctx context.Context, _ map[string]time.Time) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingSource) Get(ctx context.Context, _ string) (time.Time, error) {
+	<-ctx.Done()
+	return time.Time{}, ctx.Err()
+}
+
+func (blockingSource) BatchGet(ctx context.Context, _ []string) (map[string]time.Time, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingSource) Delete(ctx context.Context, _ string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingSource) estimateBuckets(ctx context.Context) (map[Name]int64, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingSource) deletePrefix(ctx context.Context, _ string, _ int64, timeout time.Duration, _ func([]string)) error {
+	return roundTrip(ctx, timeout, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+}
+
+func (blockingSource) scanBuckets(ctx context.Context, _ string, _ int64, timeout time.Duration, _ func(map[string]time.Time) error) error {
+	return roundTrip(ctx, timeout, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+}
+
+func (blockingSource) Ping(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestLimiter_SourceTimeouts(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newTestLimiter(t, blockingSource{}, clk)
+	l.SetSourceTimeouts(10*time.Millisecond, 20*time.Millisecond)
+	txnBuilder := newTestTransactionBuilder(t)
+
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.18"))
+	test.AssertNotError(t, err, "txn should be valid")
+
+	// Cancellation of the request context is ignored, but deadlines are not.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.Check(ctx, txn)
+	test.AssertErrorIs(t, err, context.DeadlineExceeded)
+	test.AssertMetricWithLabelsEquals(t, l.sourceTimeouts, prometheus.Labels{"call": "get"}, 1)
+
+	_, err = l.Spend(context.Background(), txn)
+	test.AssertErrorIs(t, err, context.DeadlineExceeded)
+	test.AssertMetricWithLabelsEquals(t, l.sourceTimeouts, prometheus.Labels{"call": "batchget"}, 1)
+
+	err = l.Reset(context.Background(), txn.bucketKey)
+	test.AssertErrorIs(t, err, context.DeadlineExceeded)
+	test.AssertMetricWithLabelsEquals(t, l.sourceTimeouts, prometheus.Labels{"call": "delete"}, 1)
+
+	// The optional interfaces of the source are subject to the same deadlines.
+	err = l.updateActiveBuckets(context.Background())
+	test.AssertErrorIs(t, err, context.DeadlineExceeded)
+	test.AssertMetricWithLabelsEquals(t, l.sourceTimeouts, prometheus.Labels{"call": "estimatebuckets"}, 1)
+
+	_, err = l.ResetPrefix(context.Background(), NewRegistrationsPerIPAddress.EnumString()+":", nil)
+	test.AssertErrorIs(t, err, context.DeadlineExceeded)
+	test.AssertMetricWithLabelsEquals(t, l.sourceTimeouts, prometheus.Labels{"call": "deleteprefix"}, 1)
+
+	_, err = l.ExportBuckets(context.Background(), NewRegistrationsPerIPAddress.EnumString()+":", io.Discard)
+	test.AssertErrorIs(t, err, context.DeadlineExceeded)
+	test.AssertMetricWithLabelsEquals(t, l.sourceTimeouts, prometheus.Labels{"call": "scanbuckets"}, 1)
+
+	err = l.Health(context.Background())
+	test.AssertErrorIs(t, err, context.DeadlineExceeded)
+	test.AssertMetricWithLabelsEquals(t, l.sourceTimeouts, prometheus.Labels{"call": "ping"}, 1)
+}
+
+func TestLimiter_BatchBuffersAreReset(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	ctx := context.Background()
+
+	var txns []Transaction
+	for _, ip := range []string{"10.0.0.21", "10.0.0.22", "10.0.0.23"} {
+		txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(ip))
+		test.AssertNotError(t, err, "txn should be valid")
+		txns = append(txns, txn)
+	}
+
+	// A batch which fails part way through preparation must not leave its
+	// buckets behind for the next batch.
+	_, err := l.BatchSpend(ctx, []Transaction{txns[0], txns[1], txns[0]})
+	test.AssertError(t, err, "duplicate bucket should error")
+	test.AssertContains(t, err.Error(), "duplicate bucket")
+
+	for i := 0; i < 3; i++ {
+		d, err := l.BatchSpend(ctx, txns)
+		test.AssertNotError(t, err, "should not error")
+		test.AssertEquals(t, d.Remaining, int64(19-2*i))
+
+		// A smaller batch must only spend from its own buckets.
+		d, err = l.Spend(ctx, txns[2])
+		test.AssertNotError(t, err, "should not error")
+		test.AssertEquals(t, d.Remaining, int64(18-2*i))
+	}
+
+	d, err := l.BatchRefund(ctx, txns[:2])
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(18))
+	test.AssertMetricWithLabelsEquals(t, l.refunds, prometheus.Labels{"result": refundFull}, 2)
+	d, err = l.Check(ctx, txns[2])
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(13))
+}
+
+func BenchmarkLimiter_BatchSpendAndRefund(b *testing.B) {
+	clk := clock.NewFake()
+	l, err := NewLimiter(clk, NewInmemSource(), metrics.NoopRegisterer)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rl := precomputeLimit(limit{Burst: 1000000, Count: 1000000, Period: config.Duration{Duration: time.Second}, name: CertificatesPerDomain})
+	var txns []Transaction
+	for i := 0; i < 100; i++ {
+		bucketKey := joinWithColon(CertificatesPerDomain.EnumString(), fmt.Sprintf("%d.example.com", i))
+		txns = append(txns, Transaction{bucketKey: bucketKey, limit: rl, cost: 1, check: true, spend: true})
+	}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := l.BatchSpend(ctx, txns)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, err = l.BatchRefund(ctx, txns)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
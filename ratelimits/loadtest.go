@@ -0,0 +1,328 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/letsencrypt/boulder/config"
+)
+
+const (
+	// The following are the operations which may be included in the mix of a
+	// load test.
+	loadTestCheck      = "check"
+	loadTestSpend      = "spend"
+	loadTestBatchCheck = "batch-check"
+	loadTestBatchSpend = "batch-spend"
+)
+
+var loadTestOps = []string{loadTestCheck, loadTestSpend, loadTestBatchCheck, loadTestBatchSpend}
+
+// loadTestBucketPrefix is prepended to the id of every bucket used by a load
+// test, so that they can never collide with buckets used by real requests.
+const loadTestBucketPrefix = "loadtest-"
+
+// LoadTestConfig configures RunLoadTest.
+type LoadTestConfig struct {
+	// Name is the limit whose enum is used in the keys of the load test's
+	// buckets. The configured limit itself is not used, each bucket instead
+	// allows a burst of 100 requests and 100 requests per second, so that busy
+	// buckets exercise both allowed and denied decisions.
+	Name Name
+
+	// Buckets is the number of distinct buckets requests are spread across.
+	Buckets int
+
+	// BatchSize is the number of Transactions in each batch-check and
+	// batch-spend request.
+	BatchSize int
+
+	// Concurrency is the number of concurrent workers making requests.
+	Concurrency int
+
+	// Requests is the total number of requests to make. If it is 0, requests
+	// are made until the context is done.
+	Requests int64
+
+	// Mix is the relative weight of each operation, keyed by "check",
+	// "spend", "batch-check", or "batch-spend". See ParseLoadTestMix.
+	Mix map[string]int
+}
+
+// ParseLoadTestMix parses a mix of operations formatted as comma-separated
+// 'operation=weight' pairs, e.g. "spend=70,check=20,batch-spend=10".
+func ParseLoadTestMix(s string) (map[string]int, error) {
+	mix := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		op, weightStr, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed mix entry %q, must be formatted 'operation=weight'", pair)
+		}
+		if !slices.Contains(loadTestOps, op) {
+			return nil, fmt.Errorf("unrecognized operation %q, must be one of %v", op, loadTestOps)
+		}
+		weight, err := strconv.Atoi(weightStr)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q for %s, must be an integer >= 0", weightStr, op)
+		}
+		mix[op] = weight
+	}
+	return mix, nil
+}
+
+func (c LoadTestConfig) validate() error {
+	if !c.Name.isValid() {
+		return fmt.Errorf("invalid limit name %q", c.Name)
+	}
+	if c.Buckets <= 0 {
+		return fmt.Errorf("invalid buckets %d, must be > 0", c.Buckets)
+	}
+	if c.BatchSize <= 0 || c.BatchSize > c.Buckets {
+		return fmt.Errorf("invalid batch size %d, must be > 0 and <= buckets", c.BatchSize)
+	}
+	if c.Concurrency <= 0 {
+		return fmt.Errorf("invalid concurrency %d, must be > 0", c.Concurrency)
+	}
+	var total int
+	for _, weight := range c.Mix {
+		total += weight
+	}
+	if total == 0 {
+		return errors.New("mix must include at least one operation with a weight > 0")
+	}
+	return nil
+}
+
+// LoadTestOpResult is the outcome of a load test for a single operation.
+type LoadTestOpResult struct {
+	Op string
+
+	// Requests is the number of requests made, Errors is the number which
+	// returned an error, and Denied is the number which returned a denied
+	// Decision.
+	Requests int64
+	Errors   int64
+	Denied   int64
+
+	// P50, P90, P99, and Max are latency percentiles of the requests,
+	// including those which returned an error.
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// LoadTestReport is the outcome of RunLoadTest.
+type LoadTestReport struct {
+	// Elapsed is the wall time the load test ran for.
+	Elapsed time.Duration
+
+	// Ops contains the outcome of each operation in the mix, in the order
+	// check, spend, batch-check, batch-spend.
+	Ops []LoadTestOpResult
+}
+
+// loadTestSample is the outcome of a single request.
+type loadTestSample struct {
+	latency time.Duration
+	err     bool
+	denied  bool
+}
+
+// RunLoadTest drives the configured mix of operations against the source of
+// the provided Limiter from cfg.Concurrency workers, until cfg.Requests have
+// been made or ctx is done, and returns latency percentiles and error rates for
+// each operation. Every bucket written is reset before RunLoadTest returns. If
+// resetting the buckets fails, the report is returned along with the error.
+func RunLoadTest(ctx context.Context, l *Limiter, cfg LoadTestConfig) (*LoadTestReport, error) {
+	err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	rl := precomputeLimit(limit{
+		Burst:  100,
+		Count:  100,
+		Period: config.Duration{Duration: time.Second},
+		name:   cfg.Name,
+	})
+	bucketKeys := make([]string, cfg.Buckets)
+	for i := range bucketKeys {
+		bucketKeys[i] = joinWithColon(cfg.Name.EnumString(), loadTestBucketPrefix+strconv.Itoa(i))
+	}
+
+	// Expand the mix into a slice from which operations are selected
+	// uniformly.
+	var ops []string
+	for _, op := range loadTestOps {
+		for i := 0; i < cfg.Mix[op]; i++ {
+			ops = append(ops, op)
+		}
+	}
+
+	var mu sync.Mutex
+	samples := make(map[string][]loadTestSample)
+	var issued int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for ctx.Err() == nil {
+				if cfg.Requests > 0 {
+					mu.Lock()
+					if issued >= cfg.Requests {
+						mu.Unlock()
+						return
+					}
+					issued++
+					mu.Unlock()
+				}
+
+				op := ops[rng.Intn(len(ops))]
+				n := 1
+				if op == loadTestBatchCheck || op == loadTestBatchSpend {
+					n = cfg.BatchSize
+				}
+				txns := make([]Transaction, 0, n)
+				picked := make(map[int]bool, n)
+				for len(txns) < n {
+					i := rng.Intn(cfg.Buckets)
+					if picked[i] {
+						continue
+					}
+					picked[i] = true
+					txns = append(txns, Transaction{bucketKey: bucketKeys[i], limit: rl, cost: 1, check: true, spend: true})
+				}
+
+				begin := time.Now()
+				d, err := loadTestDo(ctx, l, op, txns)
+				s := loadTestSample{latency: time.Since(begin), err: err != nil}
+				if err == nil {
+					s.denied = !d.Allowed
+				}
+				mu.Lock()
+				samples[op] = append(samples[op], s)
+				mu.Unlock()
+			}
+		}(time.Now().UnixNano() + int64(w))
+	}
+	wg.Wait()
+	report := &LoadTestReport{Elapsed: time.Since(start)}
+
+	for _, op := range loadTestOps {
+		if cfg.Mix[op] == 0 {
+			continue
+		}
+		report.Ops = append(report.Ops, summarizeLoadTestSamples(op, samples[op]))
+	}
+
+	// Reset every bucket, regardless of whether ctx is done.
+	err = resetLoadTestBuckets(context.WithoutCancel(ctx), l, cfg.Name, bucketKeys)
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// resetLoadTestBuckets resets the provided buckets of a load test, by prefix if
+// the source supports it, otherwise one at a time.
+func resetLoadTestBuckets(ctx context.Context, l *Limiter, name Name, bucketKeys []string) error {
+	if l.prefixDeleter != nil {
+		// The ids of load test buckets share a prefix, not a whole segment.
+		_, err := l.resetPrefix(context.WithoutCancel(ctx), joinWithColon(name.EnumString(), loadTestBucketPrefix), 0, nil)
+		if err != nil {
+			return fmt.Errorf("resetting load test buckets: %w", err)
+		}
+		return nil
+	}
+	for _, bucketKey := range bucketKeys {
+		err := l.Reset(ctx, bucketKey)
+		if err != nil {
+			return fmt.Errorf("resetting load test bucket %q: %w", bucketKey, err)
+		}
+	}
+	return nil
+}
+
+// loadTestDo performs a single operation of a load test.
+func loadTestDo(ctx context.Context, l *Limiter, op string, txns []Transaction) (*Decision, error) {
+	switch op {
+	case loadTestCheck:
+		return l.Check(ctx, txns[0])
+	case loadTestSpend:
+		return l.Spend(ctx, txns[0])
+	case loadTestBatchCheck:
+		decisions, err := l.BatchCheck(ctx, txns)
+		if err != nil {
+			return nil, err
+		}
+		d := newBatchDecision()
+		for _, bd := range decisions {
+			d.merge(bd)
+		}
+		return d.Decision, nil
+	default:
+		return l.BatchSpend(ctx, txns)
+	}
+}
+
+// summarizeLoadTestSamples returns the LoadTestOpResult for the provided
+// samples of a single operation.
+func summarizeLoadTestSamples(op string, samples []loadTestSample) LoadTestOpResult {
+	r := LoadTestOpResult{Op: op, Requests: int64(len(samples))}
+	latencies := make([]int64, 0, len(samples))
+	for _, s := range samples {
+		if s.err {
+			r.Errors++
+		}
+		if s.denied {
+			r.Denied++
+		}
+		latencies = append(latencies, int64(s.latency))
+	}
+	if len(latencies) == 0 {
+		return r
+	}
+	r.P50 = time.Duration(quantile(latencies, 0.50))
+	r.P90 = time.Duration(quantile(latencies, 0.90))
+	r.P99 = time.Duration(quantile(latencies, 0.99))
+	r.Max = time.Duration(slices.Max(latencies))
+	return r
+}
+
+// WriteLoadTestReport writes the provided LoadTestReport to w as a table with
+// one row per operation.
+func WriteLoadTestReport(report *LoadTestReport, w io.Writer) error {
+	var total int64
+	for _, r := range report.Ops {
+		total += r.Requests
+	}
+	var throughput float64
+	if report.Elapsed > 0 {
+		throughput = float64(total) / report.Elapsed.Seconds()
+	}
+	fmt.Fprintf(w, "%d requests in %s (%.1f/s)\n\n", total, report.Elapsed.Round(time.Millisecond), throughput)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "operation\trequests\terrors\terror rate\tdenied\tp50\tp90\tp99\tmax")
+	for _, r := range report.Ops {
+		var rate float64
+		if r.Requests > 0 {
+			rate = float64(r.Errors) / float64(r.Requests) * 100
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.4f%%\t%d\t%s\t%s\t%s\t%s\n",
+			r.Op, r.Requests, r.Errors, rate, r.Denied, r.P50, r.P90, r.P99, r.Max)
+	}
+	return tw.Flush()
+}
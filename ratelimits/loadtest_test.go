@@ -0,0 +1,76 @@
+package ratelimits
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestParseLoadTestMix(t *testing.T) {
+	mix, err := ParseLoadTestMix("spend=70, check=20,batch-spend=10")
+	test.AssertNotError(t, err, "should not error")
+	test.AssertDeepEquals(t, mix, map[string]int{loadTestSpend: 70, loadTestCheck: 20, loadTestBatchSpend: 10})
+
+	_, err = ParseLoadTestMix("refund=1")
+	test.AssertError(t, err, "unknown operation should error")
+	_, err = ParseLoadTestMix("spend")
+	test.AssertError(t, err, "missing weight should error")
+	_, err = ParseLoadTestMix("spend=-1")
+	test.AssertError(t, err, "negative weight should error")
+}
+
+func TestRunLoadTest(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	source := newInmem()
+	l := newTestLimiter(t, source, clk)
+
+	cfg := LoadTestConfig{
+		Name:        NewOrdersPerAccount,
+		Buckets:     3,
+		BatchSize:   3,
+		Concurrency: 4,
+		Requests:    800,
+		Mix:         map[string]int{loadTestCheck: 1, loadTestSpend: 1, loadTestBatchCheck: 1, loadTestBatchSpend: 1},
+	}
+	report, err := RunLoadTest(context.Background(), l, cfg)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(report.Ops), 4)
+	var total, denied int64
+	for _, r := range report.Ops {
+		test.AssertEquals(t, r.Errors, int64(0))
+		test.Assert(t, r.P50 <= r.P99 && r.P99 <= r.Max, "percentiles should be ordered")
+		total += r.Requests
+		denied += r.Denied
+	}
+	test.AssertEquals(t, total, int64(800))
+	// The clock never advances, so each bucket is exhausted after 100 spends,
+	// and each of the 3 buckets receives roughly 250.
+	test.Assert(t, denied > 0, "some requests should be denied")
+
+	// Every bucket was reset.
+	test.AssertEquals(t, len(source.m), 0)
+
+	var out strings.Builder
+	err = WriteLoadTestReport(report, &out)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertContains(t, out.String(), "800 requests")
+	test.AssertContains(t, out.String(), loadTestBatchSpend)
+
+	// Errors from the source are counted, not returned, but resetting the
+	// buckets afterwards fails.
+	cfg.Mix = map[string]int{loadTestSpend: 1}
+	cfg.Requests = 10
+	report, err = RunLoadTest(context.Background(), newTestLimiter(t, erroringSource{}, clk), cfg)
+	test.AssertErrorIs(t, err, errSourceUnavailable)
+	test.AssertEquals(t, len(report.Ops), 1)
+	test.AssertEquals(t, report.Ops[0].Errors, int64(10))
+
+	cfg.BatchSize = 4
+	_, err = RunLoadTest(context.Background(), l, cfg)
+	test.AssertError(t, err, "batch size larger than buckets should error")
+}
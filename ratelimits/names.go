@@ -3,6 +3,7 @@ package ratelimits
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -70,6 +71,43 @@ const (
 	// Note: When this is referenced in an overrides file, the fqdnSet MUST be
 	// passed as a comma-separated list of domain names.
 	CertificatesPerFQDNSet
+
+	// RevocationsPerAccount uses bucket key 'enum:regId', where regId is the
+	// ACME registration Id of the account requesting revocation. It applies to
+	// revocation requests signed by an account key.
+	RevocationsPerAccount
+
+	// RevocationsPerIPAddress uses bucket key 'enum:ipAddress'. It applies to
+	// every revocation request, including those signed by a certificate key.
+	RevocationsPerIPAddress
+
+	// AccountUpdatesPerAccount uses bucket key 'enum:regId', where regId is the
+	// ACME registration Id of the account being updated. It applies to account
+	// updates, deactivations, and key changes.
+	AccountUpdatesPerAccount
+
+	// AccountUpdatesPerIPAddress uses bucket key 'enum:ipAddress'. It applies
+	// to the same requests as AccountUpdatesPerAccount.
+	AccountUpdatesPerIPAddress
+
+	// AkamaiPurgeURLsPerEndpoint uses bucket key 'enum:endpoint', where
+	// endpoint is the host of an Akamai Fast-Purge API. It is an internal
+	// limit, see NewInternalLimit, which paces the URLs the akamai-purger
+	// submits for purging.
+	AkamaiPurgeURLsPerEndpoint
+
+	// GRPCRequestsPerClient uses bucket key 'enum:service:client', where
+	// service is the name of a gRPC service, e.g. sa.StorageAuthority, and
+	// client is a name from the mTLS certificate of a client calling it. It
+	// is an internal limit, see NewInternalLimit, which gRPC servers apply to
+	// the clients configured in their clientRateLimits.
+	GRPCRequestsPerClient
+
+	// CTSubmissionsPerLog uses bucket key 'enum:logURL', where logURL is the
+	// submission URL of a CT log. It is an internal limit, see
+	// NewInternalLimit, which paces the submissions of the publisher to the
+	// logs configured in its logLimits.
+	CTSubmissionsPerLog
 )
 
 // isValid returns true if the Name is a valid rate limit name.
@@ -77,6 +115,17 @@ func (n Name) isValid() bool {
 	return n > Unknown && n < Name(len(nameToString))
 }
 
+// isInternal returns true if the Name is an internal limit, which is
+// configured in code by the Boulder component it paces, rather than in a
+// defaults or overrides file.
+func (n Name) isInternal() bool {
+	switch n {
+	case AkamaiPurgeURLsPerEndpoint, GRPCRequestsPerClient, CTSubmissionsPerLog:
+		return true
+	}
+	return false
+}
+
 // String returns the string representation of the Name. It allows Name to
 // satisfy the fmt.Stringer interface.
 func (n Name) String() string {
@@ -94,6 +143,30 @@ func (n Name) EnumString() string {
 	return strconv.Itoa(int(n))
 }
 
+// NameFromString returns the Name with the provided string representation, as
+// returned by Name.String. It returns an error if no such Name exists.
+func NameFromString(s string) (Name, error) {
+	name, ok := stringToName[s]
+	if !ok || name == Unknown {
+		return Unknown, fmt.Errorf("unrecognized limit name %q, must be one of %v", s, limitNames)
+	}
+	return name, nil
+}
+
+// nameForBucketKey returns the Name encoded in the enum prefix of the provided
+// bucketKey. It returns Unknown if the prefix is not a valid Name.
+func nameForBucketKey(bucketKey string) Name {
+	enum, _, ok := strings.Cut(bucketKey, ":")
+	if !ok {
+		return Unknown
+	}
+	i, err := strconv.Atoi(enum)
+	if err != nil || !Name(i).isValid() {
+		return Unknown
+	}
+	return Name(i)
+}
+
 // nameToString is a map of Name values to string names.
 var nameToString = map[Name]string{
 	Unknown:                         "Unknown",
@@ -104,6 +177,13 @@ var nameToString = map[Name]string{
 	CertificatesPerDomain:           "CertificatesPerDomain",
 	CertificatesPerDomainPerAccount: "CertificatesPerDomainPerAccount",
 	CertificatesPerFQDNSet:          "CertificatesPerFQDNSet",
+	RevocationsPerAccount:           "RevocationsPerAccount",
+	RevocationsPerIPAddress:         "RevocationsPerIPAddress",
+	AccountUpdatesPerAccount:        "AccountUpdatesPerAccount",
+	AccountUpdatesPerIPAddress:      "AccountUpdatesPerIPAddress",
+	AkamaiPurgeURLsPerEndpoint:      "AkamaiPurgeURLsPerEndpoint",
+	GRPCRequestsPerClient:           "GRPCRequestsPerClient",
+	CTSubmissionsPerLog:             "CTSubmissionsPerLog",
 }
 
 // validIPAddress validates that the provided string is a valid IP address.
@@ -192,9 +272,39 @@ func validateFQDNSet(id string) error {
 	return nil
 }
 
+// validateEndpoint validates that the provided string is formatted 'endpoint',
+// where endpoint is the host, and optionally the port, of an API.
+func validateEndpoint(id string) error {
+	if id == "" || strings.ContainsAny(id, "/ ") {
+		return fmt.Errorf("invalid endpoint, %q must be formatted 'host[:port]'", id)
+	}
+	return nil
+}
+
+// validateServiceClient validates that the provided string is formatted
+// 'service:client', where service is the name of a gRPC service and client is
+// the name of a client calling it.
+func validateServiceClient(id string) error {
+	service, client, ok := strings.Cut(id, ":")
+	if !ok || service == "" || client == "" || strings.ContainsAny(id, " ") {
+		return fmt.Errorf("invalid service:client, %q must be formatted 'service:client'", id)
+	}
+	return nil
+}
+
+// validateLogURL validates that the provided string is formatted 'logURL',
+// where logURL is an absolute URL.
+func validateLogURL(id string) error {
+	u, err := url.Parse(id)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid logURL, %q must be an absolute URL", id)
+	}
+	return nil
+}
+
 func validateIdForName(name Name, id string) error {
 	switch name {
-	case NewRegistrationsPerIPAddress:
+	case NewRegistrationsPerIPAddress, RevocationsPerIPAddress, AccountUpdatesPerIPAddress:
 		// 'enum:ipaddress'
 		return validIPAddress(id)
 
@@ -202,7 +312,7 @@ func validateIdForName(name Name, id string) error {
 		// 'enum:ipv6rangeCIDR'
 		return validIPv6RangeCIDR(id)
 
-	case NewOrdersPerAccount, FailedAuthorizationsPerAccount:
+	case NewOrdersPerAccount, FailedAuthorizationsPerAccount, RevocationsPerAccount, AccountUpdatesPerAccount:
 		// 'enum:regId'
 		return validateRegId(id)
 
@@ -223,6 +333,18 @@ func validateIdForName(name Name, id string) error {
 		// 'enum:fqdnSet'
 		return validateFQDNSet(id)
 
+	case AkamaiPurgeURLsPerEndpoint:
+		// 'enum:endpoint'
+		return validateEndpoint(id)
+
+	case GRPCRequestsPerClient:
+		// 'enum:service:client'
+		return validateServiceClient(id)
+
+	case CTSubmissionsPerLog:
+		// 'enum:logURL'
+		return validateLogURL(id)
+
 	case Unknown:
 		fallthrough
 
@@ -27,3 +27,24 @@ func TestNameIsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestNameForBucketKey(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		bucketKey string
+		want      Name
+	}{
+		{bucketKey: "1:10.0.0.1", want: NewRegistrationsPerIPAddress},
+		{bucketKey: "4:12345678", want: FailedAuthorizationsPerAccount},
+		{bucketKey: "0:10.0.0.1", want: Unknown},
+		{bucketKey: "9001:10.0.0.1", want: Unknown},
+		{bucketKey: "lol:10.0.0.1", want: Unknown},
+		{bucketKey: "1", want: Unknown},
+		{bucketKey: "", want: Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bucketKey, func(t *testing.T) {
+			test.AssertEquals(t, nameForBucketKey(tt.bucketKey), tt.want)
+		})
+	}
+}
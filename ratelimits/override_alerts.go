@@ -0,0 +1,119 @@
+package ratelimits
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// OverrideAlert describes an override which has been utilized at or above the
+// configured threshold for at least the configured duration.
+type OverrideAlert struct {
+	// Limit is the name of the overridden limit.
+	Limit string
+
+	// BucketKey is the key of the bucket governed by the override.
+	BucketKey string
+
+	// Utilization is the proportion of the override's burst which was used as
+	// of the most recent spend, between 0 and 1.
+	Utilization float64
+
+	// Since is the time at which the utilization first reached the threshold.
+	Since time.Time
+}
+
+// OverrideAlertHook is called by the Limiter, synchronously, for each
+// OverrideAlert. It must be safe for concurrent use and should return quickly.
+type OverrideAlertHook func(OverrideAlert)
+
+// LogOverrideAlerts returns an OverrideAlertHook which logs each
+// OverrideAlert, as JSON, to the provided logger.
+func LogOverrideAlerts(logger blog.Logger) OverrideAlertHook {
+	return func(a OverrideAlert) {
+		logger.InfoObject("Rate limit override highly utilized", a)
+	}
+}
+
+// overrideAlerter tracks how long each override has been utilized at or above
+// a threshold and calls a hook once per episode of sustained utilization. An
+// episode ends when utilization drops below the threshold. It is safe for
+// concurrent use.
+type overrideAlerter struct {
+	sync.Mutex
+	threshold float64
+	duration  time.Duration
+	hook      OverrideAlertHook
+
+	// above maps the bucket key of each override currently utilized at or
+	// above the threshold to the state of its episode.
+	above map[string]*overrideEpisode
+}
+
+type overrideEpisode struct {
+	since time.Time
+	fired bool
+}
+
+func newOverrideAlerter(threshold float64, duration time.Duration, hook OverrideAlertHook) (*overrideAlerter, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, fmt.Errorf("invalid override alert threshold %f, must be > 0 and <= 1", threshold)
+	}
+	if duration < 0 {
+		return nil, fmt.Errorf("invalid override alert duration %s, must be >= 0", duration)
+	}
+	return &overrideAlerter{
+		threshold: threshold,
+		duration:  duration,
+		hook:      hook,
+		above:     make(map[string]*overrideEpisode),
+	}, nil
+}
+
+// observe records the utilization of the override for the provided
+// Transaction as of now, calling the hook if the override has been utilized at
+// or above the threshold for at least the configured duration.
+func (a *overrideAlerter) observe(now time.Time, txn Transaction, utilization float64) {
+	a.Lock()
+	if utilization < a.threshold {
+		delete(a.above, txn.bucketKey)
+		a.Unlock()
+		return
+	}
+	episode, ok := a.above[txn.bucketKey]
+	if !ok {
+		episode = &overrideEpisode{since: now}
+		a.above[txn.bucketKey] = episode
+	}
+	if episode.fired || now.Sub(episode.since) < a.duration {
+		a.Unlock()
+		return
+	}
+	episode.fired = true
+	since := episode.since
+	a.Unlock()
+
+	a.hook(OverrideAlert{
+		Limit:       txn.limit.name.String(),
+		BucketKey:   txn.bucketKey,
+		Utilization: utilization,
+		Since:       since,
+	})
+}
+
+// SetOverrideAlertHook configures the Limiter to call the provided
+// OverrideAlertHook when an override has been utilized at or above threshold,
+// a proportion of its burst between 0 and 1, for at least duration. The hook is
+// called at most once until utilization drops below threshold again.
+// Utilization is only measured when the override's bucket is spent from. It
+// must be called before the Limiter is used.
+func (l *Limiter) SetOverrideAlertHook(threshold float64, duration time.Duration, hook OverrideAlertHook) error {
+	alerter, err := newOverrideAlerter(threshold, duration, hook)
+	if err != nil {
+		return err
+	}
+	l.overrideAlerter = alerter
+	return nil
+}
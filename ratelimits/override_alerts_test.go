@@ -0,0 +1,83 @@
+package ratelimits
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestOverrideAlerter(t *testing.T) {
+	t.Parallel()
+	_, err := newOverrideAlerter(0, time.Minute, nil)
+	test.AssertError(t, err, "threshold of 0 should error")
+	_, err = newOverrideAlerter(1.1, time.Minute, nil)
+	test.AssertError(t, err, "threshold over 1 should error")
+	_, err = newOverrideAlerter(0.9, -time.Minute, nil)
+	test.AssertError(t, err, "negative duration should error")
+
+	var alerts []OverrideAlert
+	a, err := newOverrideAlerter(0.9, 5*time.Minute, func(oa OverrideAlert) { alerts = append(alerts, oa) })
+	test.AssertNotError(t, err, "should not error")
+
+	txn := Transaction{bucketKey: "1:10.0.0.2", limit: limit{name: NewRegistrationsPerIPAddress}}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Utilization must be sustained for the full duration.
+	a.observe(start, txn, 0.95)
+	a.observe(start.Add(4*time.Minute), txn, 0.95)
+	test.AssertEquals(t, len(alerts), 0)
+	a.observe(start.Add(5*time.Minute), txn, 0.97)
+	test.AssertEquals(t, len(alerts), 1)
+	test.AssertDeepEquals(t, alerts[0], OverrideAlert{
+		Limit:       NewRegistrationsPerIPAddress.String(),
+		BucketKey:   "1:10.0.0.2",
+		Utilization: 0.97,
+		Since:       start,
+	})
+
+	// Only one alert is fired per episode.
+	a.observe(start.Add(10*time.Minute), txn, 0.99)
+	test.AssertEquals(t, len(alerts), 1)
+
+	// Dropping below the threshold ends the episode.
+	a.observe(start.Add(11*time.Minute), txn, 0.5)
+	a.observe(start.Add(12*time.Minute), txn, 0.95)
+	test.AssertEquals(t, len(alerts), 1)
+	a.observe(start.Add(17*time.Minute), txn, 0.95)
+	test.AssertEquals(t, len(alerts), 2)
+	test.AssertEquals(t, alerts[1].Since, start.Add(12*time.Minute))
+}
+
+func TestLimiter_OverrideAlertHook(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	var alerts []OverrideAlert
+	err := l.SetOverrideAlertHook(0.9, 0, func(oa OverrideAlert) { alerts = append(alerts, oa) })
+	test.AssertNotError(t, err, "should not error")
+	txnBuilder := newTestTransactionBuilder(t)
+
+	// 10.0.0.2 is overridden to have a burst of 40.
+	bucketKey, err := BucketForIP(NewRegistrationsPerIPAddress, net.ParseIP(tenZeroZeroTwo))
+	test.AssertNotError(t, err, "should not error")
+	limit, err := txnBuilder.getLimit(NewRegistrationsPerIPAddress, bucketKey)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, limit.isOverride, "should be an override")
+
+	txn, err := newTransaction(limit, bucketKey, 20)
+	test.AssertNotError(t, err, "txn should be valid")
+	_, err = l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(alerts), 0)
+
+	_, err = l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(alerts), 1)
+	test.AssertEquals(t, alerts[0].BucketKey, bucketKey)
+	test.AssertEquals(t, alerts[0].Utilization, float64(1))
+}
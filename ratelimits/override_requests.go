@@ -0,0 +1,232 @@
+package ratelimits
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/letsencrypt/boulder/config"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+// OverrideRequestState is the state of an OverrideRequest.
+type OverrideRequestState string
+
+const (
+	// OverrideRequestPending is the initial state of every OverrideRequest.
+	OverrideRequestPending = OverrideRequestState("pending")
+
+	// OverrideRequestApproved indicates that the override should be honored
+	// until it expires.
+	OverrideRequestApproved = OverrideRequestState("approved")
+
+	// OverrideRequestDenied indicates that the override was reviewed and should
+	// never be honored.
+	OverrideRequestDenied = OverrideRequestState("denied")
+
+	// OverrideRequestExpired indicates that the override was approved but is no
+	// longer honored.
+	OverrideRequestExpired = OverrideRequestState("expired")
+)
+
+// validOverrideRequestTransitions maps each OverrideRequestState to the states
+// it may transition to. Denied and expired are terminal states.
+var validOverrideRequestTransitions = map[OverrideRequestState][]OverrideRequestState{
+	OverrideRequestPending:  {OverrideRequestApproved, OverrideRequestDenied},
+	OverrideRequestApproved: {OverrideRequestExpired},
+}
+
+// ErrInvalidOverrideRequestTransition indicates that an OverrideRequest cannot
+// move from its current state to the requested state.
+var ErrInvalidOverrideRequestTransition = errors.New("invalid override request state transition")
+
+// OverrideRequest is a request, typically filed by an external portal, for an
+// override of a default limit. OverrideRequests are stored by the SA and only
+// approved, unexpired requests are ever honored.
+type OverrideRequest struct {
+	ID int64
+
+	// LimitName is the string representation of the limit Name, e.g.
+	// 'NewOrdersPerAccount'.
+	LimitName string
+
+	// BucketId is the id of the bucket, formatted as it would be in an
+	// overrides file.
+	BucketId string
+
+	Burst  int64
+	Count  int64
+	Period time.Duration
+
+	State OverrideRequestState
+
+	// Requester identifies who filed the request, and Comment contains their
+	// justification for it.
+	Requester string
+	Comment   string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// ExpiresAt is the time after which the override is no longer honored,
+	// even if it was approved.
+	ExpiresAt time.Time
+}
+
+// ValidateOverrideRequest returns an error if the limit described by the
+// provided OverrideRequest would be rejected if it were loaded from an
+// overrides file.
+func ValidateOverrideRequest(req OverrideRequest) error {
+	name, ok := stringToName[req.LimitName]
+	if !ok || name == Unknown {
+		return fmt.Errorf("unrecognized name %q in override request, must be one of %v", req.LimitName, limitNames)
+	}
+	err := validateLimit(limit{
+		Burst:  req.Burst,
+		Count:  req.Count,
+		Period: config.Duration{Duration: req.Period},
+	})
+	if err != nil {
+		return err
+	}
+	err = validateIdForName(name, req.BucketId)
+	if err != nil {
+		return err
+	}
+	if req.ExpiresAt.IsZero() {
+		return fmt.Errorf("override request must have an expiration")
+	}
+	return nil
+}
+
+// ValidateOverrideRequestTransition returns an error wrapping
+// ErrInvalidOverrideRequestTransition if an OverrideRequest may not move from
+// the from state to the to state.
+func ValidateOverrideRequestTransition(from, to OverrideRequestState) error {
+	if !slices.Contains(validOverrideRequestTransitions[from], to) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidOverrideRequestTransition, from, to)
+	}
+	return nil
+}
+
+// OverrideRequestToPB converts the provided OverrideRequest to the form used by
+// the SA's gRPC API.
+func OverrideRequestToPB(req OverrideRequest) *sapb.OverrideRequest {
+	pb := &sapb.OverrideRequest{
+		Id:        req.ID,
+		LimitName: req.LimitName,
+		BucketId:  req.BucketId,
+		Burst:     req.Burst,
+		Count:     req.Count,
+		Period:    durationpb.New(req.Period),
+		State:     string(req.State),
+		Requester: req.Requester,
+		Comment:   req.Comment,
+		ExpiresAt: timestamppb.New(req.ExpiresAt),
+	}
+	if !req.CreatedAt.IsZero() {
+		pb.CreatedAt = timestamppb.New(req.CreatedAt)
+	}
+	if !req.UpdatedAt.IsZero() {
+		pb.UpdatedAt = timestamppb.New(req.UpdatedAt)
+	}
+	return pb
+}
+
+// OverrideRequestFromPB converts the provided OverrideRequest, as returned by
+// the SA's gRPC API, to an OverrideRequest. Timestamps which were not set are
+// left as the zero time.
+func OverrideRequestFromPB(pb *sapb.OverrideRequest) OverrideRequest {
+	req := OverrideRequest{
+		ID:        pb.Id,
+		LimitName: pb.LimitName,
+		BucketId:  pb.BucketId,
+		Burst:     pb.Burst,
+		Count:     pb.Count,
+		Period:    pb.Period.AsDuration(),
+		State:     OverrideRequestState(pb.State),
+		Requester: pb.Requester,
+		Comment:   pb.Comment,
+	}
+	if pb.CreatedAt != nil {
+		req.CreatedAt = pb.CreatedAt.AsTime()
+	}
+	if pb.UpdatedAt != nil {
+		req.UpdatedAt = pb.UpdatedAt.AsTime()
+	}
+	if pb.ExpiresAt != nil {
+		req.ExpiresAt = pb.ExpiresAt.AsTime()
+	}
+	return req
+}
+
+// OverrideRequestKey returns the key of the bucket overridden by the provided
+// OverrideRequest, as it would be keyed if the request were loaded from an
+// overrides file. Requests whose bucket ids differ only in their normalization,
+// or, for CertificatesPerFQDNSet, in the order of their names, return the same
+// key.
+func OverrideRequestKey(req OverrideRequest) (string, error) {
+	name, ok := stringToName[req.LimitName]
+	if !ok || name == Unknown {
+		return "", fmt.Errorf("unrecognized name %q in override request, must be one of %v", req.LimitName, limitNames)
+	}
+	return overrideKey(name, req.BucketId)
+}
+
+// WriteOverrideRequests writes an overrides file to w containing the overrides
+// file at basePath, if one is provided, followed by one stanza per approved
+// OverrideRequest, so that hand-maintained overrides and approved requests can
+// be loaded together by NewTransactionBuilder. Requests which are not approved
+// are skipped. Nothing is written if the base file can't be loaded or if any
+// two overrides, from either, are for the same bucket, since the result
+// couldn't be loaded either.
+func WriteOverrideRequests(basePath string, reqs []OverrideRequest, w io.Writer) error {
+	var out bytes.Buffer
+	seen := make(map[string]string)
+	if basePath != "" {
+		base, err := loadAndParseOverrideLimits(basePath)
+		if err != nil {
+			return fmt.Errorf("loading base overrides file %q: %w", basePath, err)
+		}
+		for key := range base {
+			seen[key] = fmt.Sprintf("base overrides file %q", basePath)
+		}
+		contents, err := os.ReadFile(basePath)
+		if err != nil {
+			return err
+		}
+		out.Write(contents)
+		if len(contents) > 0 && !bytes.HasSuffix(contents, []byte("\n")) {
+			out.WriteByte('\n')
+		}
+	}
+
+	for _, req := range reqs {
+		if req.State != OverrideRequestApproved {
+			continue
+		}
+		key, err := OverrideRequestKey(req)
+		if err != nil {
+			return fmt.Errorf("override request %d: %w", req.ID, err)
+		}
+		other, ok := seen[key]
+		if ok {
+			return fmt.Errorf("override request %d for %s %q is for the same bucket as %s", req.ID, req.LimitName, req.BucketId, other)
+		}
+		seen[key] = fmt.Sprintf("override request %d", req.ID)
+
+		name := stringToName[req.LimitName]
+		fmt.Fprintf(&out, "# Override request %d filed by %q, expires %s\n- %s:\n    burst: %d\n    count: %d\n    period: %s\n    ids: [%q]\n",
+			req.ID, req.Requester, req.ExpiresAt.UTC().Format(time.RFC3339),
+			req.LimitName, req.Burst, req.Count, req.Period, normalizeIdForName(name, req.BucketId))
+	}
+	_, err := w.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,189 @@
+package ratelimits
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func validOverrideRequest(now time.Time) OverrideRequest {
+	return OverrideRequest{
+		LimitName: NewOrdersPerAccount.String(),
+		BucketId:  "12345678",
+		Burst:     600,
+		Count:     600,
+		Period:    3 * time.Hour,
+		Requester: "subscriber@example.com",
+		Comment:   "large hosting provider",
+		ExpiresAt: now.Add(90 * 24 * time.Hour),
+	}
+}
+
+func TestValidateOverrideRequest(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err := ValidateOverrideRequest(validOverrideRequest(now))
+	test.AssertNotError(t, err, "valid override request")
+
+	req := validOverrideRequest(now)
+	req.LimitName = "lol"
+	err = ValidateOverrideRequest(req)
+	test.AssertError(t, err, "unknown limit name")
+
+	req = validOverrideRequest(now)
+	req.BucketId = "lol"
+	err = ValidateOverrideRequest(req)
+	test.AssertError(t, err, "invalid id")
+
+	req = validOverrideRequest(now)
+	req.Burst = 0
+	err = ValidateOverrideRequest(req)
+	test.AssertError(t, err, "burst of 0")
+
+	req = validOverrideRequest(now)
+	req.ExpiresAt = time.Time{}
+	err = ValidateOverrideRequest(req)
+	test.AssertError(t, err, "no expiration")
+}
+
+func TestValidateOverrideRequestTransition(t *testing.T) {
+	err := ValidateOverrideRequestTransition(OverrideRequestPending, OverrideRequestApproved)
+	test.AssertNotError(t, err, "pending to approved")
+	err = ValidateOverrideRequestTransition(OverrideRequestPending, OverrideRequestDenied)
+	test.AssertNotError(t, err, "pending to denied")
+	err = ValidateOverrideRequestTransition(OverrideRequestApproved, OverrideRequestExpired)
+	test.AssertNotError(t, err, "approved to expired")
+
+	for _, tc := range []struct {
+		from, to OverrideRequestState
+	}{
+		{OverrideRequestPending, OverrideRequestExpired},
+		{OverrideRequestApproved, OverrideRequestDenied},
+		{OverrideRequestDenied, OverrideRequestApproved},
+		{OverrideRequestExpired, OverrideRequestApproved},
+	} {
+		err = ValidateOverrideRequestTransition(tc.from, tc.to)
+		test.AssertErrorIs(t, err, ErrInvalidOverrideRequestTransition)
+	}
+}
+
+func TestOverrideRequestPB(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	req := validOverrideRequest(now)
+	req.ID = 1
+	req.State = OverrideRequestApproved
+	req.CreatedAt = now
+	req.UpdatedAt = now.Add(time.Hour)
+
+	test.AssertDeepEquals(t, OverrideRequestFromPB(OverrideRequestToPB(req)), req)
+
+	// Unset timestamps round trip as the zero time.
+	req.CreatedAt = time.Time{}
+	req.UpdatedAt = time.Time{}
+	pb := OverrideRequestToPB(req)
+	test.Assert(t, pb.CreatedAt == nil, "CreatedAt should be unset")
+	test.AssertDeepEquals(t, OverrideRequestFromPB(pb), req)
+}
+
+func TestWriteOverrideRequests(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	approved := validOverrideRequest(now)
+	approved.ID = 1
+	approved.State = OverrideRequestApproved
+	pending := validOverrideRequest(now)
+	pending.ID = 2
+	pending.BucketId = "87654321"
+	pending.State = OverrideRequestPending
+
+	var out strings.Builder
+	err := WriteOverrideRequests("", []OverrideRequest{approved, pending}, &out)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertNotContains(t, out.String(), "87654321")
+
+	// The output must be loadable as an overrides file.
+	path := t.TempDir() + "/overrides.yml"
+	err = os.WriteFile(path, []byte(out.String()), 0600)
+	test.AssertNotError(t, err, "writing overrides file")
+	l, err := loadAndParseOverrideLimits(path)
+	test.AssertNotError(t, err, "written override requests should be valid")
+	test.AssertEquals(t, len(l), 1)
+	ol := l[joinWithColon(NewOrdersPerAccount.EnumString(), "12345678")]
+	test.AssertEquals(t, ol.Burst, int64(600))
+	test.AssertEquals(t, ol.Count, int64(600))
+	test.AssertEquals(t, ol.Period.Duration, 3*time.Hour)
+}
+
+func TestWriteOverrideRequestsWithBase(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	approved := validOverrideRequest(now)
+	approved.ID = 1
+	approved.State = OverrideRequestApproved
+
+	// A hand-maintained overrides file, without a trailing newline.
+	basePath := t.TempDir() + "/base.yml"
+	err := os.WriteFile(basePath, []byte("- NewRegistrationsPerIPAddress:\n    burst: 40\n    count: 40\n    period: 1s\n    ids: [10.0.0.2]"), 0600)
+	test.AssertNotError(t, err, "writing base overrides file")
+
+	var out strings.Builder
+	err = WriteOverrideRequests(basePath, []OverrideRequest{approved}, &out)
+	test.AssertNotError(t, err, "should not error")
+
+	// Both the hand-maintained override and the approved request must be
+	// loaded from the output.
+	path := t.TempDir() + "/overrides.yml"
+	err = os.WriteFile(path, []byte(out.String()), 0600)
+	test.AssertNotError(t, err, "writing overrides file")
+	l, err := loadAndParseOverrideLimits(path)
+	test.AssertNotError(t, err, "merged overrides should be valid")
+	test.AssertEquals(t, len(l), 2)
+	test.AssertEquals(t, l[joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.0.2")].Burst, int64(40))
+	test.AssertEquals(t, l[joinWithColon(NewOrdersPerAccount.EnumString(), "12345678")].Burst, int64(600))
+
+	// An approved request for a bucket overridden by the base file is an
+	// error, and nothing is written.
+	dupe := validOverrideRequest(now)
+	dupe.ID = 2
+	dupe.State = OverrideRequestApproved
+	dupe.LimitName = NewRegistrationsPerIPAddress.String()
+	dupe.BucketId = "10.0.0.2"
+	out.Reset()
+	err = WriteOverrideRequests(basePath, []OverrideRequest{approved, dupe}, &out)
+	test.AssertError(t, err, "duplicate of base override should error")
+	test.AssertContains(t, err.Error(), "base overrides file")
+	test.AssertEquals(t, out.Len(), 0)
+
+	// An unloadable base file is an error.
+	err = WriteOverrideRequests(t.TempDir()+"/missing.yml", []OverrideRequest{approved}, &out)
+	test.AssertError(t, err, "missing base file should error")
+}
+
+func TestWriteOverrideRequestsDuplicates(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newFQDNSetRequest := func(id int64, bucketId string) OverrideRequest {
+		req := validOverrideRequest(now)
+		req.ID = id
+		req.State = OverrideRequestApproved
+		req.LimitName = CertificatesPerFQDNSet.String()
+		req.BucketId = bucketId
+		return req
+	}
+
+	// FQDN sets which differ only in the order and case of their names are
+	// for the same bucket.
+	var out strings.Builder
+	err := WriteOverrideRequests("", []OverrideRequest{
+		newFQDNSetRequest(1, "example.com,example.net"),
+		newFQDNSetRequest(2, "EXAMPLE.net,example.com"),
+	}, &out)
+	test.AssertError(t, err, "duplicate FQDN sets should error")
+	test.AssertContains(t, err.Error(), "override request 1")
+	test.AssertEquals(t, out.Len(), 0)
+
+	// Ids are written normalized.
+	err = WriteOverrideRequests("", []OverrideRequest{newFQDNSetRequest(1, "EXAMPLE.com,example.net")}, &out)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertContains(t, out.String(), `ids: ["example.com,example.net"]`)
+}
@@ -0,0 +1,161 @@
+package ratelimits
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// QuotaEstimate describes how much of a single bucket a request would consume
+// and the current state of that bucket. It is returned by
+// EstimateNewOrderQuota.
+type QuotaEstimate struct {
+	// Name is the name of the limit.
+	Name string `json:"name"`
+
+	// BucketKey is the key of the bucket, formatted as 'enum:id'. It is empty
+	// if the limit is disabled.
+	BucketKey string `json:"bucketKey,omitempty"`
+
+	// Unlimited is true if the limit is disabled, the requester is exempt from
+	// it, or the request is a renewal and the limit exempts renewals. If true, none of the remaining fields are set.
+	Unlimited bool `json:"unlimited"`
+
+	// Cost is the amount of the bucket's capacity the request would consume.
+	// It is zero for check-only limits, which are checked but never spent.
+	Cost  int64 `json:"cost"`
+	Burst int64 `json:"burst,omitempty"`
+
+	// Remaining is the current capacity of the bucket, before the Cost is
+	// deducted.
+	Remaining int64 `json:"remaining"`
+
+	// Allowed is false if the bucket lacks the capacity to satisfy the request,
+	// in which case RetryIn is the duration until it will.
+	Allowed bool   `json:"allowed"`
+	RetryIn string `json:"retryIn,omitempty"`
+}
+
+// EstimateNewOrderQuota returns a QuotaEstimate for each bucket which a new
+// order for the provided ACME registration Id and order domain names would be
+// subject to, without spending from any of them. If isRenewal is true, the
+// estimates reflect the renewalCost of each limit. The estimates are returned in
+// the same order as the Transactions returned by
+// TransactionBuilder.NewOrderTransactions.
+func EstimateNewOrderQuota(ctx context.Context, limiter TransactionLimiter, builder *TransactionBuilder, regId int64, orderNames []string, isRenewal bool) ([]QuotaEstimate, error) {
+	txns, err := builder.NewOrderTransactions(regId, orderNames, isRenewal)
+	if err != nil {
+		return nil, err
+	}
+	decisions, err := limiter.BatchCheck(ctx, txns)
+	if err != nil {
+		return nil, err
+	}
+
+	estimates := make([]QuotaEstimate, 0, len(txns))
+	for i, txn := range txns {
+		name := txn.limit.name
+		if txn.bucketKey != "" {
+			name = nameForBucketKey(txn.bucketKey)
+		}
+		e := QuotaEstimate{
+			Name:      name.String(),
+			BucketKey: txn.bucketKey,
+		}
+		if txn.allowOnly() {
+			e.Unlimited = true
+			e.Allowed = true
+			estimates = append(estimates, e)
+			continue
+		}
+
+		d := decisions[i]
+		e.Burst = txn.limit.Burst
+		e.Remaining = d.Remaining
+		if d.Allowed {
+			// The Decision describes the bucket after the cost is deducted.
+			e.Remaining += txn.cost
+		}
+		if txn.spend {
+			e.Cost = txn.cost
+		}
+		// Spend-only Transactions are never denied.
+		e.Allowed = d.Allowed || txn.spendOnly()
+		if !e.Allowed {
+			e.RetryIn = d.RetryIn.String()
+		}
+		estimates = append(estimates, e)
+	}
+	return estimates, nil
+}
+
+// accountQuotaNames are the limits, keyed by ACME registration Id alone, which
+// are reported by AccountQuotas.
+var accountQuotaNames = []Name{
+	NewOrdersPerAccount,
+	FailedAuthorizationsPerAccount,
+	RevocationsPerAccount,
+	AccountUpdatesPerAccount,
+}
+
+// AccountQuota describes a limit which applies to an ACME account and the
+// current state of the account's bucket for that limit. It is returned by
+// AccountQuotas and is suitable for returning to the subscriber.
+type AccountQuota struct {
+	// Name is the name of the limit.
+	Name string `json:"name"`
+
+	// Unlimited is true if the limit is disabled or the account is exempt
+	// from it. If true, none of the remaining fields are set.
+	Unlimited bool `json:"unlimited,omitempty"`
+
+	Burst  int64  `json:"burst,omitempty"`
+	Count  int64  `json:"count,omitempty"`
+	Period string `json:"period,omitempty"`
+
+	// Remaining is the current capacity of the bucket. ResetAt is the time at
+	// which the bucket will be full again, it is omitted if the bucket is
+	// already full.
+	Remaining int64      `json:"remaining"`
+	ResetAt   *time.Time `json:"resetAt,omitempty"`
+}
+
+// AccountQuotas returns an AccountQuota for each limit keyed by the provided
+// ACME registration Id alone, e.g. NewOrdersPerAccount, without spending from
+// any of them. Limits keyed by domain name or IP address are not included.
+func AccountQuotas(ctx context.Context, limiter TransactionLimiter, builder *TransactionBuilder, regId int64, now time.Time) ([]AccountQuota, error) {
+	txns := make([]Transaction, 0, len(accountQuotaNames))
+	for _, name := range accountQuotaNames {
+		// A cost of 0 reports the current state of the bucket.
+		txn, err := builder.TransactionForId(name, strconv.FormatInt(regId, 10), 0)
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, txn)
+	}
+	decisions, err := limiter.BatchCheck(ctx, txns)
+	if err != nil {
+		return nil, err
+	}
+
+	quotas := make([]AccountQuota, 0, len(txns))
+	for i, txn := range txns {
+		q := AccountQuota{Name: accountQuotaNames[i].String()}
+		if txn.allowOnly() {
+			q.Unlimited = true
+			quotas = append(quotas, q)
+			continue
+		}
+		d := decisions[i]
+		q.Burst = txn.limit.Burst
+		q.Count = txn.limit.Count
+		q.Period = txn.limit.Period.Duration.String()
+		q.Remaining = d.Remaining
+		if d.ResetIn > 0 {
+			resetAt := now.Add(d.ResetIn).UTC()
+			q.ResetAt = &resetAt
+		}
+		quotas = append(quotas, q)
+	}
+	return quotas, nil
+}
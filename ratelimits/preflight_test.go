@@ -0,0 +1,113 @@
+package ratelimits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestEstimateNewOrderQuota(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	b, err := NewTransactionBuilder("testdata/working_defaults_new_order.yml", "", "", "testdata/working_exemptions.yml")
+	test.AssertNotError(t, err, "should not error")
+
+	// Exhaust the CertificatesPerDomain bucket for example.org.
+	txns, err := b.CertificatesPerDomainTransactions(5678, []string{"example.org"})
+	test.AssertNotError(t, err, "should not error")
+	for i := 0; i < 2; i++ {
+		_, err = l.BatchSpend(context.Background(), txns)
+		test.AssertNotError(t, err, "should not error")
+	}
+
+	orderNames := []string{"example.com", "www.example.com", "example.org"}
+	for i := 0; i < 2; i++ {
+		// Estimating must not spend, so repeated estimates are identical.
+		estimates, err := EstimateNewOrderQuota(context.Background(), l, b, 5678, orderNames, false)
+		test.AssertNotError(t, err, "should not error")
+		test.AssertEquals(t, len(estimates), 5)
+
+		test.AssertEquals(t, estimates[0].Name, NewOrdersPerAccount.String())
+		test.AssertEquals(t, estimates[0].Cost, int64(1))
+		test.AssertEquals(t, estimates[0].Remaining, int64(20))
+		test.Assert(t, estimates[0].Allowed, "should be allowed")
+
+		// Check-only, so nothing is consumed.
+		test.AssertEquals(t, estimates[1].Name, FailedAuthorizationsPerAccount.String())
+		test.AssertEquals(t, estimates[1].Cost, int64(0))
+		test.Assert(t, estimates[1].Allowed, "should be allowed")
+
+		// example.com and www.example.com share a bucket.
+		test.AssertEquals(t, estimates[2].Name, CertificatesPerDomain.String())
+		test.AssertEquals(t, estimates[2].BucketKey, joinWithColon(CertificatesPerDomain.EnumString(), "example.com"))
+		test.AssertEquals(t, estimates[2].Remaining, int64(2))
+		test.Assert(t, estimates[2].Allowed, "should be allowed")
+
+		test.AssertEquals(t, estimates[3].BucketKey, joinWithColon(CertificatesPerDomain.EnumString(), "example.org"))
+		test.AssertEquals(t, estimates[3].Remaining, int64(0))
+		test.Assert(t, !estimates[3].Allowed, "should not be allowed")
+		test.AssertEquals(t, estimates[3].RetryIn, "500ms")
+
+		test.AssertEquals(t, estimates[4].Name, CertificatesPerFQDNSet.String())
+		test.Assert(t, estimates[4].Allowed, "should be allowed")
+	}
+
+	// Renewals are charged the renewalCost of each limit which configures one.
+	b, err = NewTransactionBuilder("testdata/working_defaults_renewals.yml", "", "", "")
+	test.AssertNotError(t, err, "should not error")
+	estimates, err := EstimateNewOrderQuota(context.Background(), l, b, 5678, orderNames, true)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, estimates[0].Cost, int64(2))
+	test.Assert(t, estimates[2].Unlimited && estimates[3].Unlimited, "renewals should be unlimited")
+
+	b, err = NewTransactionBuilder("testdata/working_defaults_new_order.yml", "", "", "testdata/working_exemptions.yml")
+	test.AssertNotError(t, err, "should not error")
+
+	// 1337 is exempt from NewOrdersPerAccount and CertificatesPerDomain.
+	estimates, err = EstimateNewOrderQuota(context.Background(), l, b, 1337, orderNames, false)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, estimates[0].Unlimited, "should be unlimited")
+	test.Assert(t, !estimates[1].Unlimited, "should not be unlimited")
+	test.Assert(t, estimates[2].Unlimited && estimates[3].Unlimited, "should be unlimited")
+}
+
+func TestAccountQuotas(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	b, err := NewTransactionBuilder("testdata/working_defaults_new_order.yml", "", "", "testdata/working_exemptions.yml")
+	test.AssertNotError(t, err, "should not error")
+
+	txn, err := b.OrdersPerAccountTransaction(5678)
+	test.AssertNotError(t, err, "should not error")
+	_, err = l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+
+	quotas, err := AccountQuotas(context.Background(), l, b, 5678, clk.Now())
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(quotas), len(accountQuotaNames))
+	test.AssertEquals(t, quotas[0].Name, NewOrdersPerAccount.String())
+	test.AssertEquals(t, quotas[0].Burst, int64(20))
+	test.AssertEquals(t, quotas[0].Remaining, int64(19))
+	test.AssertEquals(t, *quotas[0].ResetAt, clk.Now().Add(50*time.Millisecond).UTC())
+
+	// The FailedAuthorizationsPerAccount bucket is full.
+	test.AssertEquals(t, quotas[1].Remaining, int64(20))
+	test.Assert(t, quotas[1].ResetAt == nil, "full bucket should not have a ResetAt")
+
+	// RevocationsPerAccount is disabled.
+	test.Assert(t, quotas[2].Unlimited, "should be unlimited")
+
+	// 1337 is exempt from NewOrdersPerAccount.
+	quotas, err = AccountQuotas(context.Background(), l, b, 1337, clk.Now())
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, quotas[0].Unlimited, "should be unlimited")
+
+	_, err = AccountQuotas(context.Background(), newTestLimiter(t, erroringSource{}, clk), b, 5678, clk.Now())
+	test.AssertErrorIs(t, err, errSourceUnavailable)
+}
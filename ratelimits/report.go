@@ -0,0 +1,198 @@
+package ratelimits
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// UtilizationReport is a machine-readable snapshot of how the Limiter's
+// buckets are being used, intended for capacity planning.
+type UtilizationReport struct {
+	// GeneratedAt is the time at which the report was generated.
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	// Since is the time from which SpendRates are measured, the time at which
+	// the Limiter was created.
+	Since time.Time `json:"since"`
+
+	// Overrides is the utilization of each override as of its most recent
+	// spend, ordered by bucket key.
+	Overrides []OverrideUtilization `json:"overrides"`
+
+	// TopDenied is up to 10 of the bucket keys with the most over-limit
+	// denials, as returned by Limiter.TopDenied.
+	TopDenied []HeavyHitter `json:"topDenied"`
+
+	// SpendRates is the number of successful spends for each limit, ordered
+	// by limit name.
+	SpendRates []SpendRate `json:"spendRates"`
+}
+
+// OverrideUtilization is the utilization of a single override.
+type OverrideUtilization struct {
+	Limit     string `json:"limit"`
+	BucketKey string `json:"bucketKey"`
+
+	// Utilization is the proportion of the override's burst which was used as
+	// of UpdatedAt, between 0 and 1.
+	Utilization float64   `json:"utilization"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SpendRate is the number of successful spends for a single limit.
+type SpendRate struct {
+	Limit  string `json:"limit"`
+	Spends int64  `json:"spends"`
+
+	// PerSecond is the average number of spends per second since the Limiter
+	// was created.
+	PerSecond float64 `json:"perSecond"`
+}
+
+// reportTopDenied is the number of bucket keys included in the TopDenied field
+// of each UtilizationReport.
+const reportTopDenied = 10
+
+// usageStats accumulates the data reported by UtilizationReport. It is safe
+// for concurrent use.
+type usageStats struct {
+	sync.Mutex
+	since     time.Time
+	spends    map[Name]int64
+	overrides map[string]OverrideUtilization
+}
+
+func newUsageStats(since time.Time) *usageStats {
+	return &usageStats{
+		since:     since,
+		spends:    make(map[Name]int64),
+		overrides: make(map[string]OverrideUtilization),
+	}
+}
+
+// addSpend records a successful spend for the limit of the provided
+// Transaction.
+func (u *usageStats) addSpend(txn Transaction) {
+	u.Lock()
+	defer u.Unlock()
+	u.spends[txn.limit.name]++
+}
+
+// setOverrideUtilization records the utilization of the override for the
+// provided Transaction as of now.
+func (u *usageStats) setOverrideUtilization(now time.Time, txn Transaction, utilization float64) {
+	u.Lock()
+	defer u.Unlock()
+	u.overrides[txn.bucketKey] = OverrideUtilization{
+		Limit:       txn.limit.name.String(),
+		BucketKey:   txn.bucketKey,
+		Utilization: utilization,
+		UpdatedAt:   now,
+	}
+}
+
+// UtilizationReport returns a snapshot of current override utilization, the
+// bucket keys with the most over-limit denials, and the spend rate of each
+// limit.
+func (l *Limiter) UtilizationReport() UtilizationReport {
+	now := l.clk.Now()
+	report := UtilizationReport{
+		GeneratedAt: now,
+		Since:       l.usage.since,
+		Overrides:   []OverrideUtilization{},
+		TopDenied:   l.TopDenied(reportTopDenied),
+		SpendRates:  []SpendRate{},
+	}
+
+	l.usage.Lock()
+	for _, o := range l.usage.overrides {
+		report.Overrides = append(report.Overrides, o)
+	}
+	elapsed := now.Sub(l.usage.since).Seconds()
+	for name, spends := range l.usage.spends {
+		rate := SpendRate{Limit: name.String(), Spends: spends}
+		if elapsed > 0 {
+			rate.PerSecond = float64(spends) / elapsed
+		}
+		report.SpendRates = append(report.SpendRates, rate)
+	}
+	l.usage.Unlock()
+
+	slices.SortFunc(report.Overrides, func(a, b OverrideUtilization) int {
+		return strings.Compare(a.BucketKey, b.BucketKey)
+	})
+	slices.SortFunc(report.SpendRates, func(a, b SpendRate) int {
+		return strings.Compare(a.Limit, b.Limit)
+	})
+	return report
+}
+
+// NewUtilizationReportHandler returns an http.Handler which responds with the
+// Limiter's current UtilizationReport as JSON. It is intended to be served by
+// the debug server only.
+func NewUtilizationReportHandler(limiter *Limiter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(limiter.UtilizationReport())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// writeUtilizationReport writes the Limiter's current UtilizationReport, as
+// JSON, to the file at the provided path. The file is replaced atomically so
+// that readers never observe a partially written report.
+func (l *Limiter) writeUtilizationReport(path string) error {
+	data, err := json.Marshal(l.UtilizationReport())
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteUtilizationReports writes the Limiter's current UtilizationReport, as
+// JSON, to the file at the provided path every interval. Errors are logged to
+// the provided logger. It blocks until the provided context is canceled.
+func (l *Limiter) WriteUtilizationReports(ctx context.Context, interval time.Duration, path string, logger blog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := l.writeUtilizationReport(path)
+			if err != nil {
+				logger.Warningf("writing rate limit utilization report to %q: %s", path, err)
+			}
+		}
+	}
+}
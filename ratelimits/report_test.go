@@ -0,0 +1,73 @@
+package ratelimits
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestUtilizationReport(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	start := clk.Now()
+
+	// 10.0.0.2 is overridden to have a burst of 40.
+	overridden, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(tenZeroZeroTwo))
+	test.AssertNotError(t, err, "txn should be valid")
+	normal, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.19"))
+	test.AssertNotError(t, err, "txn should be valid")
+	for i := 0; i < 10; i++ {
+		_, err = l.Spend(context.Background(), overridden)
+		test.AssertNotError(t, err, "should not error")
+	}
+	_, err = l.Spend(context.Background(), normal)
+	test.AssertNotError(t, err, "should not error")
+	clk.Add(10 * time.Second)
+
+	report := l.UtilizationReport()
+	test.AssertEquals(t, report.Since, start)
+	test.AssertEquals(t, report.GeneratedAt, clk.Now())
+	test.AssertEquals(t, len(report.Overrides), 1)
+	test.AssertDeepEquals(t, report.Overrides[0], OverrideUtilization{
+		Limit:       NewRegistrationsPerIPAddress.String(),
+		BucketKey:   overridden.bucketKey,
+		Utilization: 0.25,
+		UpdatedAt:   start,
+	})
+	test.AssertEquals(t, len(report.TopDenied), 0)
+	test.AssertDeepEquals(t, report.SpendRates, []SpendRate{
+		{Limit: NewRegistrationsPerIPAddress.String(), Spends: 11, PerSecond: 1.1},
+	})
+
+	// The report can be written to a file.
+	path := filepath.Join(t.TempDir(), "report.json")
+	err = l.writeUtilizationReport(path)
+	test.AssertNotError(t, err, "writing report")
+	data, err := os.ReadFile(path)
+	test.AssertNotError(t, err, "reading report")
+	var fromFile UtilizationReport
+	err = json.Unmarshal(data, &fromFile)
+	test.AssertNotError(t, err, "unmarshalling report")
+	test.AssertEquals(t, len(fromFile.SpendRates), 1)
+
+	// And served over HTTP.
+	rw := httptest.NewRecorder()
+	NewUtilizationReportHandler(l).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/ratelimits/report", nil))
+	test.AssertEquals(t, rw.Code, http.StatusOK)
+	var fromHTTP UtilizationReport
+	err = json.Unmarshal(rw.Body.Bytes(), &fromHTTP)
+	test.AssertNotError(t, err, "unmarshalling report")
+	test.AssertEquals(t, len(fromHTTP.Overrides), 1)
+}
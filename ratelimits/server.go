@@ -0,0 +1,296 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	rlpb "github.com/letsencrypt/boulder/ratelimits/proto"
+)
+
+var errIncompleteRequest = errors.New("incomplete gRPC request message")
+
+// Server exposes a Limiter over gRPC, so that many WFE and RA instances can
+// share a single limiter tier rather than each holding their own Redis
+// connections and limit configuration. Buckets are identified by limit name
+// and either id or bucket key, and the Transactions for them are built by the
+// Server's TransactionBuilder, which alone decides which limits are disabled
+// or overridden and which requesters are exempt. See Client.
+type Server struct {
+	rlpb.UnimplementedRateLimitsServer
+	limiter TransactionLimiter
+	builder *TransactionBuilder
+}
+
+var _ rlpb.RateLimitsServer = (*Server)(nil)
+
+// NewServer returns a new *Server which spends from the buckets of the provided
+// TransactionLimiter using Transactions built by the provided
+// TransactionBuilder.
+func NewServer(limiter TransactionLimiter, builder *TransactionBuilder) *Server {
+	return &Server{limiter: limiter, builder: builder}
+}
+
+// Health implements the grpc.checker interface. The Server is unhealthy while
+// its Limiter's source is unreachable, so that clients stop sending it
+// requests which it would fail. TransactionLimiters which cannot check their
+// health are always considered healthy.
+func (s *Server) Health(ctx context.Context) error {
+	checker, ok := s.limiter.(interface{ Health(context.Context) error })
+	if !ok {
+		return nil
+	}
+	return checker.Health(ctx)
+}
+
+// transactionFromPB returns the Transactions for the provided protobuf
+// Transaction. There is one, unless the protobuf Transaction is for the
+// CertificatesPerDomain limit of an account with a
+// CertificatesPerDomainPerAccount override, in which case there are two, as
+// returned by TransactionBuilder.CertificatesPerDomainTransactions.
+func (s *Server) transactionFromPB(req *rlpb.Transaction) ([]Transaction, error) {
+	if req == nil || req.Limit == "" || (req.Id == "") == (req.BucketKey == "") {
+		return nil, errIncompleteRequest
+	}
+	if req.CheckOnly && req.SpendOnly {
+		return nil, berrors.MalformedError("transaction cannot be both check-only and spend-only")
+	}
+	name, err := NameFromString(req.Limit)
+	if err != nil {
+		return nil, berrors.MalformedError("%s", err)
+	}
+	if req.RegId != 0 {
+		return s.perDomainTransactionsFromPB(name, req)
+	}
+	var txn Transaction
+	if req.BucketKey != "" {
+		txn, err = s.builder.transactionForBucketKey(name, req.BucketKey, req.Cost)
+	} else {
+		txn, err = s.builder.TransactionForId(name, req.Id, req.Cost)
+	}
+	if err != nil {
+		return nil, berrors.MalformedError("building transaction for %s: %s", name, err)
+	}
+	if req.Renewal {
+		txn = txn.AsRenewal()
+	}
+	if !txn.allowOnly() {
+		txn.check = !req.SpendOnly
+		txn.spend = !req.CheckOnly
+	}
+	return []Transaction{txn}, nil
+}
+
+// perDomainTransactionsFromPB returns the Transactions for the provided
+// protobuf CertificatesPerDomain Transaction of the account with its regId,
+// applying the account's exemptions and CertificatesPerDomainPerAccount
+// override, if any.
+func (s *Server) perDomainTransactionsFromPB(name Name, req *rlpb.Transaction) ([]Transaction, error) {
+	if name != CertificatesPerDomain {
+		return nil, berrors.MalformedError("regId is only accepted for %s transactions", CertificatesPerDomain)
+	}
+	if req.CheckOnly || req.SpendOnly {
+		return nil, berrors.MalformedError("%s transactions with a regId cannot be check-only or spend-only", name)
+	}
+	if req.Cost != 1 {
+		return nil, berrors.MalformedError("%s transactions with a regId must have a cost of 1", name)
+	}
+	domain := req.Id
+	if req.BucketKey != "" {
+		if nameForBucketKey(req.BucketKey) != name {
+			return nil, berrors.MalformedError("bucket key %q is not a bucket key of %s", req.BucketKey, name)
+		}
+		_, domain, _ = strings.Cut(req.BucketKey, ":")
+	}
+	txns, err := s.builder.CertificatesPerDomainTransactions(req.RegId, []string{domain})
+	if err != nil {
+		return nil, berrors.MalformedError("building transactions for %s: %s", name, err)
+	}
+	if len(txns) == 0 {
+		return nil, berrors.MalformedError("no %s transactions for %q", name, domain)
+	}
+	if req.Renewal {
+		for i := range txns {
+			txns[i] = txns[i].AsRenewal()
+		}
+	}
+	return txns, nil
+}
+
+// transactionsFromPB returns the Transactions for the provided protobuf
+// Transactions, as returned by transactionFromPB for each, in order.
+func (s *Server) transactionsFromPB(req *rlpb.Transactions) ([][]Transaction, error) {
+	if req == nil || len(req.Transactions) == 0 {
+		return nil, errIncompleteRequest
+	}
+	txns := make([][]Transaction, 0, len(req.Transactions))
+	for _, pb := range req.Transactions {
+		t, err := s.transactionFromPB(pb)
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, t)
+	}
+	return txns, nil
+}
+
+// flatten returns the Transactions of each of the provided groups, in order.
+func flatten(groups [][]Transaction) []Transaction {
+	var txns []Transaction
+	for _, group := range groups {
+		txns = append(txns, group...)
+	}
+	return txns
+}
+
+// mergeChecks returns the Decision of a check of the provided Transactions,
+// built from a single protobuf Transaction, from their individual Decisions.
+// As for BatchSpend, spend-only Transactions are never denied.
+func mergeChecks(txns []Transaction, decisions []*Decision) *Decision {
+	if len(decisions) == 1 {
+		return decisions[0]
+	}
+	batch := newBatchDecision()
+	for i, d := range decisions {
+		if txns[i].spendOnly() {
+			continue
+		}
+		batch.merge(d)
+	}
+	return batch.Decision
+}
+
+func decisionToPB(d *Decision) *rlpb.Decision {
+	return &rlpb.Decision{
+		Allowed:   d.Allowed,
+		Remaining: d.Remaining,
+		RetryIn:   durationpb.New(d.RetryIn),
+		ResetIn:   durationpb.New(d.ResetIn),
+		BucketKey: d.transaction.bucketKey,
+		Burst:     d.Burst(),
+	}
+}
+
+// Check implements rlpb.RateLimitsServer.
+func (s *Server) Check(ctx context.Context, req *rlpb.Transaction) (*rlpb.Decision, error) {
+	txns, err := s.transactionFromPB(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(txns) == 1 {
+		d, err := s.limiter.Check(ctx, txns[0])
+		if err != nil {
+			return nil, err
+		}
+		return decisionToPB(d), nil
+	}
+	decisions, err := s.limiter.BatchCheck(ctx, txns)
+	if err != nil {
+		return nil, err
+	}
+	return decisionToPB(mergeChecks(txns, decisions)), nil
+}
+
+// BatchCheck implements rlpb.RateLimitsServer.
+func (s *Server) BatchCheck(ctx context.Context, req *rlpb.Transactions) (*rlpb.Decisions, error) {
+	groups, err := s.transactionsFromPB(req)
+	if err != nil {
+		return nil, err
+	}
+	txns := flatten(groups)
+	decisions, err := s.limiter.BatchCheck(ctx, txns)
+	if err != nil {
+		return nil, err
+	}
+	if len(decisions) != len(txns) {
+		return nil, errors.New("limiter returned the wrong number of decisions")
+	}
+	// Respond with one Decision for each protobuf Transaction.
+	resp := &rlpb.Decisions{Decisions: make([]*rlpb.Decision, 0, len(groups))}
+	for _, txns := range groups {
+		resp.Decisions = append(resp.Decisions, decisionToPB(mergeChecks(txns, decisions[:len(txns)])))
+		decisions = decisions[len(txns):]
+	}
+	return resp, nil
+}
+
+// Spend implements rlpb.RateLimitsServer.
+func (s *Server) Spend(ctx context.Context, req *rlpb.Transaction) (*rlpb.Decision, error) {
+	txns, err := s.transactionFromPB(req)
+	if err != nil {
+		return nil, err
+	}
+	var d *Decision
+	if len(txns) == 1 {
+		d, err = s.limiter.Spend(ctx, txns[0])
+	} else {
+		d, err = s.limiter.BatchSpend(ctx, txns)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decisionToPB(d), nil
+}
+
+// BatchSpend implements rlpb.RateLimitsServer.
+func (s *Server) BatchSpend(ctx context.Context, req *rlpb.Transactions) (*rlpb.Decision, error) {
+	groups, err := s.transactionsFromPB(req)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.limiter.BatchSpend(ctx, flatten(groups))
+	if err != nil {
+		return nil, err
+	}
+	return decisionToPB(d), nil
+}
+
+// Refund implements rlpb.RateLimitsServer.
+func (s *Server) Refund(ctx context.Context, req *rlpb.Transaction) (*rlpb.Decision, error) {
+	txns, err := s.transactionFromPB(req)
+	if err != nil {
+		return nil, err
+	}
+	var d *Decision
+	if len(txns) == 1 {
+		d, err = s.limiter.Refund(ctx, txns[0])
+	} else {
+		d, err = s.limiter.BatchRefund(ctx, txns)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decisionToPB(d), nil
+}
+
+// BatchRefund implements rlpb.RateLimitsServer.
+func (s *Server) BatchRefund(ctx context.Context, req *rlpb.Transactions) (*rlpb.Decision, error) {
+	groups, err := s.transactionsFromPB(req)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.limiter.BatchRefund(ctx, flatten(groups))
+	if err != nil {
+		return nil, err
+	}
+	return decisionToPB(d), nil
+}
+
+// Reset implements rlpb.RateLimitsServer.
+func (s *Server) Reset(ctx context.Context, req *rlpb.ResetRequest) (*emptypb.Empty, error) {
+	if req == nil || req.BucketKey == "" {
+		return nil, errIncompleteRequest
+	}
+	if nameForBucketKey(req.BucketKey) == Unknown {
+		return nil, berrors.MalformedError("unrecognized bucket key %q", req.BucketKey)
+	}
+	err := s.limiter.Reset(ctx, req.BucketKey)
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
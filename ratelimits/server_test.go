@@ -0,0 +1,212 @@
+package ratelimits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	rlpb "github.com/letsencrypt/boulder/ratelimits/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	s := NewServer(newInmemTestLimiter(t, clock.NewFake()), newTestTransactionBuilder(t))
+	ctx := context.Background()
+
+	txn := &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String(), Id: "10.0.0.1", Cost: 10}
+	d, err := s.Check(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, d.Remaining, int64(10))
+
+	d, err = s.Spend(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, d.Remaining, int64(10))
+	test.AssertEquals(t, d.Burst, int64(20))
+	test.AssertEquals(t, d.ResetIn.AsDuration(), 500*time.Millisecond)
+
+	// The override for 10.0.0.2 has a burst of 40.
+	d, err = s.BatchSpend(ctx, &rlpb.Transactions{Transactions: []*rlpb.Transaction{
+		txn,
+		{Limit: NewRegistrationsPerIPAddress.String(), Id: "10.0.0.2", Cost: 10},
+	}})
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+	test.AssertEquals(t, d.Remaining, int64(0))
+
+	d, err = s.Spend(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+	test.AssertEquals(t, d.RetryIn.AsDuration(), 500*time.Millisecond)
+
+	d, err = s.Refund(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(10))
+
+	d, err = s.BatchRefund(ctx, &rlpb.Transactions{Transactions: []*rlpb.Transaction{txn}})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(20))
+
+	// A disabled limit is always allowed.
+	d, err = s.Spend(ctx, &rlpb.Transaction{Limit: NewOrdersPerAccount.String(), Id: "1234", Cost: 1})
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, d.Allowed, "should be allowed")
+}
+
+func TestServer_Renewal(t *testing.T) {
+	t.Parallel()
+	b, err := NewTransactionBuilder("testdata/working_defaults_renewals.yml", "", "", "")
+	test.AssertNotError(t, err, "should not error")
+	s := NewServer(newInmemTestLimiter(t, clock.NewFake()), b)
+	ctx := context.Background()
+
+	// NewOrdersPerAccount charges renewals a cost of 2.
+	d, err := s.Spend(ctx, &rlpb.Transaction{Limit: NewOrdersPerAccount.String(), Id: "1234", Cost: 5, Renewal: true})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(8))
+
+	d, err = s.Spend(ctx, &rlpb.Transaction{Limit: NewOrdersPerAccount.String(), Id: "1234", Cost: 5})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(3))
+}
+
+func TestServer_Health(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+
+	err := NewServer(newInmemTestLimiter(t, clk), newTestTransactionBuilder(t)).Health(context.Background())
+	test.AssertNotError(t, err, "should be healthy")
+
+	err = NewServer(newTestLimiter(t, erroringSource{}, clk), newTestTransactionBuilder(t)).Health(context.Background())
+	test.AssertErrorIs(t, err, errSourceUnavailable)
+}
+
+// denyingLimiter is a TransactionLimiter which denies every Transaction.
+type denyingLimiter struct{}
+
+var deniedDecision = &Decision{Allowed: false, RetryIn: time.Second}
+
+func (denyingLimiter) Check(context.Context, Transaction) (*Decision, error) {
+	return deniedDecision, nil
+}
+
+func (denyingLimiter) BatchCheck(_ context.Context, txns []Transaction) ([]*Decision, error) {
+	decisions := make([]*Decision, len(txns))
+	for i := range txns {
+		decisions[i] = deniedDecision
+	}
+	return decisions, nil
+}
+
+func (denyingLimiter) Spend(context.Context, Transaction) (*Decision, error) {
+	return deniedDecision, nil
+}
+
+func (denyingLimiter) BatchSpend(context.Context, []Transaction) (*Decision, error) {
+	return deniedDecision, nil
+}
+
+func (denyingLimiter) Refund(context.Context, Transaction) (*Decision, error) {
+	return deniedDecision, nil
+}
+
+func (denyingLimiter) BatchRefund(context.Context, []Transaction) (*Decision, error) {
+	return deniedDecision, nil
+}
+
+func (denyingLimiter) Reset(context.Context, string) error {
+	return nil
+}
+
+func TestServer_TransactionLimiter(t *testing.T) {
+	t.Parallel()
+	s := NewServer(denyingLimiter{}, newTestTransactionBuilder(t))
+
+	d, err := s.Spend(context.Background(), &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String(), Id: "10.0.0.1", Cost: 1})
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+	test.AssertEquals(t, d.RetryIn.AsDuration(), time.Second)
+
+	// TransactionLimiters which cannot check their health are healthy.
+	test.AssertNotError(t, s.Health(context.Background()), "should be healthy")
+}
+
+func TestServer_InvalidRequests(t *testing.T) {
+	t.Parallel()
+	s := NewServer(newInmemTestLimiter(t, clock.NewFake()), newTestTransactionBuilder(t))
+	ctx := context.Background()
+
+	_, err := s.Spend(ctx, &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String()})
+	test.AssertErrorIs(t, err, errIncompleteRequest)
+
+	_, err = s.BatchSpend(ctx, &rlpb.Transactions{})
+	test.AssertErrorIs(t, err, errIncompleteRequest)
+
+	_, err = s.Spend(ctx, &rlpb.Transaction{Limit: "NotALimit", Id: "10.0.0.1", Cost: 1})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	_, err = s.Spend(ctx, &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String(), Id: "not-an-ip", Cost: 1})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	_, err = s.Check(ctx, &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String(), Id: "10.0.0.1", Cost: 21})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	// Exactly one of id and bucketKey must be set.
+	_, err = s.Spend(ctx, &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String(), Id: "10.0.0.1", BucketKey: "1:10.0.0.1", Cost: 1})
+	test.AssertErrorIs(t, err, errIncompleteRequest)
+
+	_, err = s.Spend(ctx, &rlpb.Transaction{Limit: NewOrdersPerAccount.String(), BucketKey: "1:10.0.0.1", Cost: 1})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	_, err = s.Spend(ctx, &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String(), BucketKey: "1:10.0.0.1", Cost: 1, CheckOnly: true, SpendOnly: true})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	_, err = s.Spend(ctx, &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String(), Id: "10.0.0.1", Cost: 1, RegId: 1})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	_, err = s.Spend(ctx, &rlpb.Transaction{Limit: CertificatesPerDomain.String(), Id: "example.com", Cost: 1, RegId: 1, CheckOnly: true})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	_, err = s.Spend(ctx, &rlpb.Transaction{Limit: CertificatesPerDomain.String(), Id: "example.com", Cost: 2, RegId: 1})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	_, err = s.Reset(ctx, &rlpb.ResetRequest{BucketKey: "not-a-bucket-key"})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+}
+
+func TestServer_BucketKey(t *testing.T) {
+	t.Parallel()
+	s := NewServer(newInmemTestLimiter(t, clock.NewFake()), newTestTransactionBuilder(t))
+	ctx := context.Background()
+
+	// The override for 10.0.0.2 applies to its bucket key too.
+	txn := &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String(), BucketKey: "1:10.0.0.2", Cost: 10}
+	d, err := s.Spend(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(30))
+	test.AssertEquals(t, d.Burst, int64(40))
+	test.AssertEquals(t, d.BucketKey, "1:10.0.0.2")
+
+	// A check-only Transaction never spends.
+	checkOnly := &rlpb.Transaction{Limit: NewRegistrationsPerIPAddress.String(), BucketKey: "1:10.0.0.2", Cost: 10, CheckOnly: true}
+	d, err = s.BatchSpend(ctx, &rlpb.Transactions{Transactions: []*rlpb.Transaction{checkOnly}})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(20))
+
+	decisions, err := s.BatchCheck(ctx, &rlpb.Transactions{Transactions: []*rlpb.Transaction{txn, {Limit: NewRegistrationsPerIPAddress.String(), Id: "10.0.0.1", Cost: 1}}})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(decisions.Decisions), 2)
+	test.AssertEquals(t, decisions.Decisions[0].Remaining, int64(20))
+	test.AssertEquals(t, decisions.Decisions[1].Remaining, int64(19))
+
+	_, err = s.Reset(ctx, &rlpb.ResetRequest{BucketKey: "1:10.0.0.2"})
+	test.AssertNotError(t, err, "should not error")
+	d, err = s.Check(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(30))
+}
@@ -0,0 +1,139 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+const (
+	// The following are the values of the 'result' label of the shadow
+	// comparisons metric.
+
+	// shadowMatch is used when the legacy check and the Limiter agreed.
+	shadowMatch = "match"
+
+	// shadowLegacyDenied is used when the legacy check denied a request which
+	// the Limiter allowed.
+	shadowLegacyDenied = "legacy-denied"
+
+	// shadowLimiterDenied is used when the Limiter denied a request which the
+	// legacy check allowed.
+	shadowLimiterDenied = "limiter-denied"
+
+	// shadowLegacyError is used when the legacy check returned an error other
+	// than a rate limit error, so no comparison was made.
+	shadowLegacyError = "legacy-error"
+
+	// shadowLimiterError is used when the Limiter returned an error, so no
+	// comparison was made.
+	shadowLimiterError = "limiter-error"
+)
+
+// Divergence describes a request for which the legacy check and the Limiter
+// made different decisions. It is logged, as JSON, by the Comparator.
+type Divergence struct {
+	// Limit is the name of the limit being compared.
+	Limit string
+
+	// BucketKeys are the keys of the buckets of the Transactions spent by the
+	// Limiter.
+	BucketKeys []string
+
+	// LegacyAllowed and LimiterAllowed are the decisions of the legacy check
+	// and the Limiter, respectively.
+	LegacyAllowed  bool
+	LimiterAllowed bool
+
+	// LegacyError is the rate limit error returned by the legacy check, if it
+	// denied the request.
+	LegacyError string `json:",omitempty"`
+}
+
+// Comparator evaluates requests against both the legacy, SQL-based, rate
+// limits and a TransactionLimiter. It counts and logs requests for which they
+// diverge, and always returns the legacy decision. It is intended to gather
+// parity data before the Limiter becomes authoritative. Call NewComparator to
+// create a new *Comparator.
+type Comparator struct {
+	limiter     TransactionLimiter
+	log         blog.Logger
+	comparisons *prometheus.CounterVec
+}
+
+// NewComparator returns a new *Comparator which compares legacy checks against
+// the provided TransactionLimiter.
+func NewComparator(limiter TransactionLimiter, stats prometheus.Registerer, logger blog.Logger) *Comparator {
+	comparisons := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimits_shadow_comparisons_total",
+		Help: fmt.Sprintf("Number of requests evaluated by both the legacy rate limits and the key-value rate limiter, labeled by limit=[name] and result=[%s|%s|%s|%s|%s]",
+			shadowMatch, shadowLegacyDenied, shadowLimiterDenied, shadowLegacyError, shadowLimiterError),
+	}, []string{"limit", "result"})
+	stats.MustRegister(comparisons)
+
+	return &Comparator{
+		limiter:     limiter,
+		log:         logger,
+		comparisons: comparisons,
+	}
+}
+
+// Compare calls legacy, which must return nil if the request is allowed or a
+// berrors.RateLimit error if it is denied, and spends the provided Transactions
+// for the limit specified by name from the Limiter. The result of legacy is
+// always returned, errors from the Limiter are logged but never returned. If
+// legacy denies the request, anything spent from the Limiter is refunded, so
+// the Limiter's buckets only reflect requests the legacy check allowed.
+func (c *Comparator) Compare(ctx context.Context, name Name, txns []Transaction, legacy func() error) error {
+	legacyErr := legacy()
+	if legacyErr != nil && !errors.Is(legacyErr, berrors.RateLimit) {
+		c.comparisons.WithLabelValues(name.String(), shadowLegacyError).Inc()
+		return legacyErr
+	}
+	legacyAllowed := legacyErr == nil
+
+	d, err := c.limiter.BatchSpend(ctx, txns)
+	if err != nil {
+		c.comparisons.WithLabelValues(name.String(), shadowLimiterError).Inc()
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+			c.log.Warningf("comparing %s rate limit: %s", name, err)
+		}
+		return legacyErr
+	}
+
+	if !legacyAllowed && d.Allowed {
+		_, err = c.limiter.BatchRefund(ctx, txns)
+		if err != nil {
+			c.log.Warningf("refunding %s rate limit denied by legacy check: %s", name, err)
+		}
+	}
+
+	if legacyAllowed == d.Allowed {
+		c.comparisons.WithLabelValues(name.String(), shadowMatch).Inc()
+		return legacyErr
+	}
+
+	result := shadowLimiterDenied
+	div := Divergence{
+		Limit:          name.String(),
+		LegacyAllowed:  legacyAllowed,
+		LimiterAllowed: d.Allowed,
+	}
+	if !legacyAllowed {
+		result = shadowLegacyDenied
+		div.LegacyError = legacyErr.Error()
+	}
+	for _, txn := range txns {
+		if txn.bucketKey != "" {
+			div.BucketKeys = append(div.BucketKeys, txn.bucketKey)
+		}
+	}
+	c.comparisons.WithLabelValues(name.String(), result).Inc()
+	c.log.InfoObject("Rate limit decisions diverged", div)
+	return legacyErr
+}
@@ -0,0 +1,80 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestComparator(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	log := blog.NewMock()
+	c := NewComparator(l, metrics.NoopRegisterer, log)
+	ctx := context.Background()
+
+	txn, err := newTestTransactionBuilder(t).RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	txns := []Transaction{txn}
+	allow := func() error { return nil }
+	deny := func() error { return berrors.RateLimitError(time.Second, "too many registrations") }
+	results := func(result string) prometheus.Labels {
+		return prometheus.Labels{"limit": NewRegistrationsPerIPAddress.String(), "result": result}
+	}
+
+	// Both allow.
+	err = c.Compare(ctx, NewRegistrationsPerIPAddress, txns, allow)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, c.comparisons, results(shadowMatch), 1)
+	test.AssertEquals(t, len(log.GetAllMatching("diverged")), 0)
+
+	// The legacy check denies, the Limiter allows. The legacy error is
+	// returned and the spend is refunded.
+	err = c.Compare(ctx, NewRegistrationsPerIPAddress, txns, deny)
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+	test.AssertMetricWithLabelsEquals(t, c.comparisons, results(shadowLegacyDenied), 1)
+	test.AssertEquals(t, len(log.GetAllMatching(`Rate limit decisions diverged JSON=.*"BucketKeys":\["1:10.0.0.1"\].*"LegacyAllowed":false,"LimiterAllowed":true`)), 1)
+	d, err := l.Check(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(18))
+
+	// Exhaust the bucket. The legacy check allows, the Limiter denies. The
+	// legacy decision is returned.
+	log.Clear()
+	txn, err = newTransaction(txn.limit, txn.bucketKey, 19)
+	test.AssertNotError(t, err, "should not error")
+	_, err = l.Spend(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	err = c.Compare(ctx, NewRegistrationsPerIPAddress, txns, allow)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, c.comparisons, results(shadowLimiterDenied), 1)
+	test.AssertEquals(t, len(log.GetAllMatching(`"LegacyAllowed":true,"LimiterAllowed":false`)), 1)
+
+	// Both deny.
+	err = c.Compare(ctx, NewRegistrationsPerIPAddress, txns, deny)
+	test.AssertErrorIs(t, err, berrors.RateLimit)
+	test.AssertMetricWithLabelsEquals(t, c.comparisons, results(shadowMatch), 2)
+
+	// The legacy check fails, so the Limiter is not consulted.
+	errLegacy := errors.New("database unavailable")
+	err = c.Compare(ctx, NewRegistrationsPerIPAddress, txns, func() error { return errLegacy })
+	test.AssertErrorIs(t, err, errLegacy)
+	test.AssertMetricWithLabelsEquals(t, c.comparisons, results(shadowLegacyError), 1)
+
+	// The Limiter fails, the legacy decision is returned.
+	c = NewComparator(newTestLimiter(t, erroringSource{}, clk), metrics.NoopRegisterer, log)
+	err = c.Compare(ctx, NewRegistrationsPerIPAddress, txns, allow)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertMetricWithLabelsEquals(t, c.comparisons, results(shadowLimiterError), 1)
+}
@@ -0,0 +1,184 @@
+package ratelimits
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RecordedRequest is a single new-order request from a request log, as read by
+// ParseRecordedRequests.
+type RecordedRequest struct {
+	Timestamp   time.Time `json:"timestamp"`
+	RegId       int64     `json:"regId"`
+	Identifiers []string  `json:"identifiers"`
+	Renewal     bool      `json:"renewal,omitempty"`
+}
+
+// ParseRecordedRequests reads new-order requests from r, one JSON object per
+// line with the fields of RecordedRequest, and returns them sorted by
+// timestamp in ascending order. Requests with identical timestamps retain
+// their order. Blank lines and lines beginning with '#' are ignored.
+func ParseRecordedRequests(r io.Reader) ([]RecordedRequest, error) {
+	var requests []RecordedRequest
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var req RecordedRequest
+		err := json.Unmarshal([]byte(text), &req)
+		if err != nil {
+			return nil, fmt.Errorf("parsing request on line %d: %w", line, err)
+		}
+		if req.Timestamp.IsZero() || req.RegId == 0 || len(req.Identifiers) == 0 {
+			return nil, fmt.Errorf("malformed request on line %d, timestamp, regId, and identifiers are required", line)
+		}
+		requests = append(requests, req)
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(requests, func(a, b RecordedRequest) int { return a.Timestamp.Compare(b.Timestamp) })
+	return requests, nil
+}
+
+// LimitSimulation is the outcome of a simulation for a single limit.
+type LimitSimulation struct {
+	// Name is the name of the limit.
+	Name string
+
+	// Requests is the number of requests which were subject to the limit,
+	// Denied is the number of those the limit would have denied, and
+	// BucketsDenied is the number of distinct buckets which denied at least
+	// one request.
+	Requests      int64
+	Denied        int64
+	BucketsDenied int64
+}
+
+// SimulationReport is the outcome of SimulateNewOrders.
+type SimulationReport struct {
+	// Requests is the number of requests replayed and Denied is the number
+	// which at least one limit would have denied.
+	Requests int64
+	Denied   int64
+
+	// Limits contains the outcome for each limit which at least one request
+	// was subject to, sorted by name.
+	Limits []LimitSimulation
+}
+
+// SimulateNewOrders replays the provided requests, which must be sorted by
+// timestamp, against the limits of the provided TransactionBuilder using an
+// in-memory source and a clock set to the timestamp of each request. Each
+// request is evaluated exactly as the WFE evaluates a new order: it is denied
+// if any of its limits would deny it, and only allowed requests are spent.
+// Nothing is spent from failed authorization limits, which are only checked at
+// new-order time.
+func SimulateNewOrders(builder *TransactionBuilder, requests []RecordedRequest) (*SimulationReport, error) {
+	if len(requests) == 0 {
+		return &SimulationReport{}, nil
+	}
+	clk := clock.NewFake()
+	clk.Set(requests[0].Timestamp)
+	limiter, err := NewLimiter(clk, NewInmemSource(), prometheus.NewRegistry())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	report := &SimulationReport{}
+	limits := make(map[Name]*LimitSimulation)
+	bucketsDenied := make(map[string]bool)
+	for _, req := range requests {
+		if req.Timestamp.Before(clk.Now()) {
+			return nil, fmt.Errorf("requests must be sorted by timestamp, %s is before %s", req.Timestamp, clk.Now())
+		}
+		clk.Set(req.Timestamp)
+
+		txns, err := builder.NewOrderTransactions(req.RegId, req.Identifiers, req.Renewal)
+		if err != nil {
+			return nil, fmt.Errorf("building transactions for regId %d: %w", req.RegId, err)
+		}
+		decisions, err := limiter.BatchCheck(ctx, txns)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Requests++
+		// Limits with a bucket per domain name are subject to many
+		// Transactions, but are only counted once per request.
+		subjectTo := make(map[Name]bool)
+		deniedBy := make(map[Name]bool)
+		for i, txn := range txns {
+			if txn.allowOnly() {
+				continue
+			}
+			name := nameForBucketKey(txn.bucketKey)
+			ls, ok := limits[name]
+			if !ok {
+				ls = &LimitSimulation{Name: name.String()}
+				limits[name] = ls
+			}
+			if !subjectTo[name] {
+				ls.Requests++
+				subjectTo[name] = true
+			}
+			if decisions[i].Allowed || txn.spendOnly() {
+				continue
+			}
+			if !deniedBy[name] {
+				ls.Denied++
+				deniedBy[name] = true
+			}
+			if !bucketsDenied[txn.bucketKey] {
+				ls.BucketsDenied++
+				bucketsDenied[txn.bucketKey] = true
+			}
+		}
+		if len(deniedBy) > 0 {
+			report.Denied++
+			continue
+		}
+		_, err = limiter.BatchSpend(ctx, txns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ls := range limits {
+		report.Limits = append(report.Limits, *ls)
+	}
+	slices.SortFunc(report.Limits, func(a, b LimitSimulation) int { return strings.Compare(a.Name, b.Name) })
+	return report, nil
+}
+
+// WriteSimulationReport writes the provided SimulationReport to w as a table
+// with one row per limit, followed by a row of totals.
+func WriteSimulationReport(report *SimulationReport, w io.Writer) error {
+	rate := func(denied, requests int64) float64 {
+		if requests == 0 {
+			return 0
+		}
+		return float64(denied) / float64(requests) * 100
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "limit\trequests\tdenied\tdenial rate\tbuckets denied")
+	for _, ls := range report.Limits {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.4f%%\t%d\n", ls.Name, ls.Requests, ls.Denied, rate(ls.Denied, ls.Requests), ls.BucketsDenied)
+	}
+	fmt.Fprintf(tw, "total\t%d\t%d\t%.4f%%\t\n", report.Requests, report.Denied, rate(report.Denied, report.Requests))
+	return tw.Flush()
+}
@@ -0,0 +1,60 @@
+package ratelimits
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestParseRecordedRequests(t *testing.T) {
+	input := `# comment
+{"timestamp": "2024-01-01T00:00:01Z", "regId": 2, "identifiers": ["b.example.com"]}
+
+{"timestamp": "2024-01-01T00:00:00Z", "regId": 1, "identifiers": ["a.example.com"], "renewal": true}
+`
+	requests, err := ParseRecordedRequests(strings.NewReader(input))
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, len(requests), 2)
+	test.AssertEquals(t, requests[0].RegId, int64(1))
+	test.Assert(t, requests[0].Renewal, "should be a renewal")
+	test.AssertEquals(t, requests[1].RegId, int64(2))
+
+	_, err = ParseRecordedRequests(strings.NewReader(`{"timestamp": "2024-01-01T00:00:00Z", "regId": 1}`))
+	test.AssertError(t, err, "missing identifiers should error")
+	_, err = ParseRecordedRequests(strings.NewReader(`not json`))
+	test.AssertError(t, err, "malformed line should error")
+}
+
+func TestSimulateNewOrders(t *testing.T) {
+	builder, err := NewTransactionBuilder("testdata/working_defaults_new_order.yml", "", "", "")
+	test.AssertNotError(t, err, "should not error")
+
+	// CertificatesPerDomain allows 2 per second, so the third order for
+	// example.com is denied, but the order a second later is allowed.
+	input := `{"timestamp": "2024-01-01T00:00:00Z", "regId": 5678, "identifiers": ["example.com"]}
+{"timestamp": "2024-01-01T00:00:00Z", "regId": 5678, "identifiers": ["www.example.com"]}
+{"timestamp": "2024-01-01T00:00:00Z", "regId": 5678, "identifiers": ["example.com", "example.net"]}
+{"timestamp": "2024-01-01T00:00:00Z", "regId": 5678, "identifiers": ["example.org"]}
+{"timestamp": "2024-01-01T00:00:01Z", "regId": 5678, "identifiers": ["example.com"]}
+`
+	requests, err := ParseRecordedRequests(strings.NewReader(input))
+	test.AssertNotError(t, err, "should not error")
+	report, err := SimulateNewOrders(builder, requests)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, report.Requests, int64(5))
+	test.AssertEquals(t, report.Denied, int64(1))
+
+	byName := make(map[string]LimitSimulation)
+	for _, ls := range report.Limits {
+		byName[ls.Name] = ls
+	}
+	test.AssertEquals(t, byName[CertificatesPerDomain.String()], LimitSimulation{Name: CertificatesPerDomain.String(), Requests: 5, Denied: 1, BucketsDenied: 1})
+	test.AssertEquals(t, byName[NewOrdersPerAccount.String()], LimitSimulation{Name: NewOrdersPerAccount.String(), Requests: 5})
+
+	var out strings.Builder
+	err = WriteSimulationReport(report, &out)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertContains(t, out.String(), "CertificatesPerDomain")
+	test.AssertContains(t, out.String(), "20.0000%")
+}
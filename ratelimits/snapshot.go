@@ -0,0 +1,130 @@
+package ratelimits
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot format written by
+// ExportBuckets. ImportBuckets rejects snapshots of any other version.
+const snapshotVersion = 1
+
+// snapshotBatchSize is the approximate number of buckets read from or written
+// to the source by each batch of an export or import.
+const snapshotBatchSize = 500
+
+// snapshotHeader is the first line of a snapshot.
+type snapshotHeader struct {
+	Version int `json:"version"`
+
+	// Prefix is the prefix of the bucket keys included in the snapshot, empty
+	// if the snapshot includes every bucket.
+	Prefix     string    `json:"prefix"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// snapshotBucket is every subsequent line of a snapshot.
+type snapshotBucket struct {
+	Key string `json:"key"`
+
+	// TAT is the bucket's theoretical arrival time, in nanoseconds since the
+	// Unix epoch, exactly as stored by the source.
+	TAT int64 `json:"tat"`
+}
+
+// ExportBuckets writes a snapshot of every bucket whose key begins with the
+// provided prefix to w and returns the number of buckets written. The prefix
+// must be empty, to export every bucket, or begin with the '<enum>:' of a valid
+// limit, e.g. '3:' to export every bucket of NewOrdersPerAccount. The snapshot
+// is newline-delimited JSON: a header containing the format version, followed
+// by one line per bucket. Buckets are read in batches, so a snapshot of a
+// source which is being written to is not a point-in-time copy.
+func (l *Limiter) ExportBuckets(ctx context.Context, prefix string, w io.Writer) (int64, error) {
+	if prefix != "" && nameForBucketKey(prefix) == Unknown {
+		return 0, fmt.Errorf("prefix %q does not begin with the '<enum>:' of a valid limit", prefix)
+	}
+	if l.scanner == nil {
+		return 0, errors.New("source does not support exporting buckets")
+	}
+
+	enc := json.NewEncoder(w)
+	err := enc.Encode(snapshotHeader{Version: snapshotVersion, Prefix: prefix, ExportedAt: l.clk.Now().UTC()})
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	err = l.scanner.scanBuckets(ctx, prefix, snapshotBatchSize, 0, func(buckets map[string]time.Time) error {
+		for bucketKey, tat := range buckets {
+			if nameForBucketKey(bucketKey) == Unknown {
+				// Not a rate limit bucket.
+				continue
+			}
+			err := enc.Encode(snapshotBucket{Key: bucketKey, TAT: tat.UnixNano()})
+			if err != nil {
+				return err
+			}
+			total++
+		}
+		return nil
+	})
+	return total, err
+}
+
+// ImportBuckets reads a snapshot written by ExportBuckets from r, stores every
+// bucket it contains, and returns the number of buckets stored. Existing
+// buckets with the same keys are overwritten. Buckets are written in batches,
+// and, if an error is returned, buckets written before the error remain
+// written.
+func (l *Limiter) ImportBuckets(ctx context.Context, r io.Reader) (int64, error) {
+	dec := json.NewDecoder(r)
+	var header snapshotHeader
+	err := dec.Decode(&header)
+	if err != nil {
+		return 0, fmt.Errorf("reading snapshot header: %w", err)
+	}
+	if header.Version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported snapshot version %d, expected %d", header.Version, snapshotVersion)
+	}
+
+	var total int64
+	batch := make(map[string]time.Time)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := l.source.BatchSet(ctx, batch)
+		if err != nil {
+			return err
+		}
+		total += int64(len(batch))
+		batch = make(map[string]time.Time)
+		return nil
+	}
+
+	for {
+		var bucket snapshotBucket
+		err := dec.Decode(&bucket)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return total, fmt.Errorf("reading snapshot bucket: %w", err)
+		}
+		if nameForBucketKey(bucket.Key) == Unknown {
+			return total, fmt.Errorf("snapshot bucket %q is not a valid bucket key", bucket.Key)
+		}
+		batch[bucket.Key] = time.Unix(0, bucket.TAT).UTC()
+		if len(batch) >= snapshotBatchSize {
+			err = flush()
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, flush()
+}
@@ -0,0 +1,68 @@
+package ratelimits
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestExportImportBuckets(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newInmemTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	ctx := context.Background()
+
+	for _, ip := range []string{"10.0.3.1", "10.0.3.2", tenZeroZeroTwo} {
+		txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(ip))
+		test.AssertNotError(t, err, "txn should be valid")
+		_, err = l.Spend(ctx, txn)
+		test.AssertNotError(t, err, "should not error")
+	}
+
+	_, err := l.ExportBuckets(ctx, "10.0.3.", &bytes.Buffer{})
+	test.AssertError(t, err, "prefix without a limit enum should be rejected")
+
+	var snapshot bytes.Buffer
+	exported, err := l.ExportBuckets(ctx, joinWithColon(NewRegistrationsPerIPAddress.EnumString(), "10.0.3."), &snapshot)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, exported, int64(2))
+	test.AssertEquals(t, strings.Count(snapshot.String(), "\n"), 3)
+
+	var all bytes.Buffer
+	exported, err = l.ExportBuckets(ctx, "", &all)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, exported, int64(3))
+
+	// Import into an empty source and verify the TATs match.
+	imported := newInmemTestLimiter(t, clk)
+	n, err := imported.ImportBuckets(ctx, &snapshot)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, n, int64(2))
+	for _, ip := range []string{"10.0.3.1", "10.0.3.2"} {
+		bucketKey := joinWithColon(NewRegistrationsPerIPAddress.EnumString(), ip)
+		want, err := l.source.Get(ctx, bucketKey)
+		test.AssertNotError(t, err, "should not error")
+		got, err := imported.source.Get(ctx, bucketKey)
+		test.AssertNotError(t, err, "should not error")
+		test.Assert(t, got.Equal(want), "imported TAT should match exported TAT")
+	}
+	_, err = imported.source.Get(ctx, joinWithColon(NewRegistrationsPerIPAddress.EnumString(), tenZeroZeroTwo))
+	test.AssertErrorIs(t, err, ErrBucketNotFound)
+
+	// Unsupported versions and invalid bucket keys are rejected.
+	_, err = imported.ImportBuckets(ctx, strings.NewReader(`{"version":2}`+"\n"))
+	test.AssertError(t, err, "unsupported version should be rejected")
+	_, err = imported.ImportBuckets(ctx, strings.NewReader(`{"version":1}`+"\n"+`{"key":"nonce","tat":1}`+"\n"))
+	test.AssertError(t, err, "invalid bucket key should be rejected")
+
+	// Sources which cannot enumerate buckets are reported as an error.
+	_, err = newTestLimiter(t, erroringSource{}, clk).ExportBuckets(ctx, "", &bytes.Buffer{})
+	test.AssertError(t, err, "should error")
+}
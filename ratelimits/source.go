@@ -2,9 +2,13 @@ package ratelimits
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // ErrBucketNotFound indicates that the bucket was not found.
@@ -18,6 +22,8 @@ type source interface {
 	//   a) applying a deadline or timeout to the context WITHIN the method, or
 	//   b) guaranteeing the operation will not block indefinitely (e.g. via
 	//    the underlying storage client implementation).
+	// The Limiter reuses bucketKeys, so implementations MUST NOT retain it
+	// after returning.
 	BatchSet(ctx context.Context, bucketKeys map[string]time.Time) error
 
 	// Get retrieves the TAT associated with the specified bucketKey (formatted
@@ -45,18 +51,62 @@ type source interface {
 	Delete(ctx context.Context, bucketKey string) error
 }
 
-// inmem is an in-memory implementation of the source interface used for
-// testing.
-type inmem struct {
+// bucketEstimator is implemented by sources which can estimate the number of
+// buckets they currently store for each limit.
+type bucketEstimator interface {
+	// estimateBuckets returns the estimated number of buckets stored for each
+	// limit Name. Limits with no buckets may be omitted.
+	estimateBuckets(ctx context.Context) (map[Name]int64, error)
+}
+
+// prefixDeleter is implemented by sources which can delete every bucket whose
+// key begins with a given prefix.
+type prefixDeleter interface {
+	// deletePrefix deletes, in batches of approximately batchSize, every
+	// bucket whose key begins with prefix. The deleted func is called with the
+	// keys of each batch after it has been deleted. A timeout other than 0 is
+	// applied to each round trip to the datastore, rather than to the whole
+	// call, which may take many.
+	deletePrefix(ctx context.Context, prefix string, batchSize int64, timeout time.Duration, deleted func(bucketKeys []string)) error
+}
+
+// pinger is implemented by sources which can check that their backing
+// datastore is reachable.
+type pinger interface {
+	// Ping returns an error if the datastore is unreachable and nil otherwise.
+	Ping(ctx context.Context) error
+}
+
+// bucketScanner is implemented by sources which can enumerate the buckets they
+// store.
+type bucketScanner interface {
+	// scanBuckets calls fn, in batches of approximately batchSize, with the
+	// TAT of every bucket whose key begins with prefix. If fn returns an
+	// error, scanning stops and that error is returned. A timeout other than 0
+	// is applied as for prefixDeleter.deletePrefix.
+	scanBuckets(ctx context.Context, prefix string, batchSize int64, timeout time.Duration, fn func(buckets map[string]time.Time) error) error
+}
+
+// Compile-time check that InmemSource implements the source interface.
+var _ source = (*InmemSource)(nil)
+
+// InmemSource is an in-memory implementation of the source interface used for
+// testing, and by Limiters whose buckets need not be shared between processes.
+type InmemSource struct {
 	sync.RWMutex
 	m map[string]time.Time
 }
 
-func newInmem() *inmem {
-	return &inmem{m: make(map[string]time.Time)}
+func newInmem() *InmemSource {
+	return &InmemSource{m: make(map[string]time.Time)}
+}
+
+// NewInmemSource returns a new, empty *InmemSource.
+func NewInmemSource() *InmemSource {
+	return newInmem()
 }
 
-func (in *inmem) BatchSet(_ context.Context, bucketKeys map[string]time.Time) error {
+func (in *InmemSource) BatchSet(_ context.Context, bucketKeys map[string]time.Time) error {
 	in.Lock()
 	defer in.Unlock()
 	for k, v := range bucketKeys {
@@ -65,7 +115,7 @@ func (in *inmem) BatchSet(_ context.Context, bucketKeys map[string]time.Time) er
 	return nil
 }
 
-func (in *inmem) Get(_ context.Context, bucketKey string) (time.Time, error) {
+func (in *InmemSource) Get(_ context.Context, bucketKey string) (time.Time, error) {
 	in.RLock()
 	defer in.RUnlock()
 	tat, ok := in.m[bucketKey]
@@ -75,23 +125,201 @@ func (in *inmem) Get(_ context.Context, bucketKey string) (time.Time, error) {
 	return tat, nil
 }
 
-func (in *inmem) BatchGet(_ context.Context, bucketKeys []string) (map[string]time.Time, error) {
+func (in *InmemSource) BatchGet(_ context.Context, bucketKeys []string) (map[string]time.Time, error) {
 	in.RLock()
 	defer in.RUnlock()
 	tats := make(map[string]time.Time, len(bucketKeys))
 	for _, k := range bucketKeys {
 		tat, ok := in.m[k]
 		if !ok {
-			tats[k] = time.Time{}
+			continue
 		}
 		tats[k] = tat
 	}
 	return tats, nil
 }
 
-func (in *inmem) Delete(_ context.Context, bucketKey string) error {
+func (in *InmemSource) Delete(_ context.Context, bucketKey string) error {
 	in.Lock()
 	defer in.Unlock()
 	delete(in.m, bucketKey)
 	return nil
 }
+
+func (in *InmemSource) deletePrefix(_ context.Context, prefix string, batchSize int64, _ time.Duration, deleted func([]string)) error {
+	in.Lock()
+	var keys []string
+	for k := range in.m {
+		if strings.HasPrefix(k, prefix) {
+			delete(in.m, k)
+			keys = append(keys, k)
+		}
+	}
+	in.Unlock()
+
+	for len(keys) > 0 {
+		n := min(int64(len(keys)), batchSize)
+		deleted(keys[:n])
+		keys = keys[n:]
+	}
+	return nil
+}
+
+func (in *InmemSource) scanBuckets(_ context.Context, prefix string, batchSize int64, _ time.Duration, fn func(map[string]time.Time) error) error {
+	in.RLock()
+	buckets := make(map[string]time.Time)
+	for k, tat := range in.m {
+		if strings.HasPrefix(k, prefix) {
+			buckets[k] = tat
+		}
+	}
+	in.RUnlock()
+
+	batch := make(map[string]time.Time)
+	for k, tat := range buckets {
+		batch[k] = tat
+		if int64(len(batch)) >= batchSize {
+			err := fn(batch)
+			if err != nil {
+				return err
+			}
+			batch = make(map[string]time.Time)
+		}
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+func (in *InmemSource) estimateBuckets(_ context.Context) (map[Name]int64, error) {
+	in.RLock()
+	defer in.RUnlock()
+	counts := make(map[Name]int64)
+	for k := range in.m {
+		name := nameForBucketKey(k)
+		if name == Unknown {
+			continue
+		}
+		counts[name]++
+	}
+	return counts, nil
+}
+
+// Compile-time check that timeoutSource implements the source interface.
+var _ source = (*timeoutSource)(nil)
+
+// timeoutSource wraps a source, and those of the optional interfaces it
+// implements, applying a deadline to each call. Because the Limiter removes
+// cancellation from request contexts, these deadlines are the only bound on how
+// long a slow source can hold a request.
+type timeoutSource struct {
+	source source
+
+	// estimator, prefixDeleter, scanner, and pinger are those of the optional
+	// interfaces implemented by the wrapped source, or nil.
+	estimator     bucketEstimator
+	prefixDeleter prefixDeleter
+	scanner       bucketScanner
+	pinger        pinger
+
+	// single is the timeout applied to Get, Delete, and Ping, and batch is the
+	// timeout applied to BatchGet, BatchSet, and estimateBuckets, and to each
+	// round trip of deletePrefix and scanBuckets. A timeout of 0 applies no
+	// deadline.
+	single   time.Duration
+	batch    time.Duration
+	timeouts *prometheus.CounterVec
+}
+
+// withTimeout returns a copy of ctx with the provided timeout applied, unless
+// the timeout is 0.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// countTimeout increments the timeouts counter for the provided call if err
+// resulted from the deadline of ctx being exceeded.
+func (t *timeoutSource) countTimeout(ctx context.Context, call string, err error) {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.timeouts.WithLabelValues(call).Inc()
+	}
+}
+
+// countRoundTripTimeout increments the timeouts counter for the provided call
+// if err resulted from the deadline of one of its round trips, rather than that
+// of ctx, being exceeded.
+func (t *timeoutSource) countRoundTripTimeout(ctx context.Context, call string, err error) {
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		t.timeouts.WithLabelValues(call).Inc()
+	}
+}
+
+func (t *timeoutSource) BatchSet(ctx context.Context, bucketKeys map[string]time.Time) error {
+	ctx, cancel := withTimeout(ctx, t.batch)
+	defer cancel()
+	err := t.source.BatchSet(ctx, bucketKeys)
+	t.countTimeout(ctx, "batchset", err)
+	return err
+}
+
+func (t *timeoutSource) Get(ctx context.Context, bucketKey string) (time.Time, error) {
+	ctx, cancel := withTimeout(ctx, t.single)
+	defer cancel()
+	tat, err := t.source.Get(ctx, bucketKey)
+	t.countTimeout(ctx, "get", err)
+	return tat, err
+}
+
+func (t *timeoutSource) BatchGet(ctx context.Context, bucketKeys []string) (map[string]time.Time, error) {
+	ctx, cancel := withTimeout(ctx, t.batch)
+	defer cancel()
+	tats, err := t.source.BatchGet(ctx, bucketKeys)
+	t.countTimeout(ctx, "batchget", err)
+	return tats, err
+}
+
+func (t *timeoutSource) Delete(ctx context.Context, bucketKey string) error {
+	ctx, cancel := withTimeout(ctx, t.single)
+	defer cancel()
+	err := t.source.Delete(ctx, bucketKey)
+	t.countTimeout(ctx, "delete", err)
+	return err
+}
+
+func (t *timeoutSource) estimateBuckets(ctx context.Context) (map[Name]int64, error) {
+	ctx, cancel := withTimeout(ctx, t.batch)
+	defer cancel()
+	estimates, err := t.estimator.estimateBuckets(ctx)
+	t.countTimeout(ctx, "estimatebuckets", err)
+	return estimates, err
+}
+
+func (t *timeoutSource) deletePrefix(ctx context.Context, prefix string, batchSize int64, timeout time.Duration, deleted func([]string)) error {
+	if timeout <= 0 {
+		timeout = t.batch
+	}
+	err := t.prefixDeleter.deletePrefix(ctx, prefix, batchSize, timeout, deleted)
+	t.countRoundTripTimeout(ctx, "deleteprefix", err)
+	return err
+}
+
+func (t *timeoutSource) scanBuckets(ctx context.Context, prefix string, batchSize int64, timeout time.Duration, fn func(map[string]time.Time) error) error {
+	if timeout <= 0 {
+		timeout = t.batch
+	}
+	err := t.scanner.scanBuckets(ctx, prefix, batchSize, timeout, fn)
+	t.countRoundTripTimeout(ctx, "scanbuckets", err)
+	return err
+}
+
+func (t *timeoutSource) Ping(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, t.single)
+	defer cancel()
+	err := t.pinger.Ping(ctx)
+	t.countTimeout(ctx, "ping", err)
+	return err
+}
@@ -0,0 +1,223 @@
+package ratelimits
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/config"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// benchSource is a source compared by BenchmarkSource.
+type benchSource struct {
+	name string
+
+	// new returns a new, empty, instance of the source. It should call
+	// b.Skip if the source is unavailable.
+	new func(b *testing.B, clk clock.FakeClock) source
+}
+
+// benchSources are the sources compared by BenchmarkSource. New source
+// implementations should be added here so that their performance can be
+// compared against the existing sources in review.
+var benchSources = []benchSource{
+	{
+		name: "inmem",
+		new: func(b *testing.B, clk clock.FakeClock) source {
+			return newInmem()
+		},
+	},
+	{
+		name: "miniredis",
+		new: func(b *testing.B, clk clock.FakeClock) source {
+			s, _ := newMiniredisSource(b, clk)
+			return s
+		},
+	},
+	{
+		// The Redis shards of the integration test environment. Skipped if
+		// they are unreachable.
+		name: "redis",
+		new: func(b *testing.B, clk clock.FakeClock) source {
+			s := newTestRedisSource(clk, map[string]string{
+				"shard1": "10.33.33.4:4218",
+				"shard2": "10.33.33.5:4218",
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			err := s.Ping(ctx)
+			if err != nil {
+				b.Skipf("redis is unavailable: %s", err)
+			}
+			return s
+		},
+	},
+}
+
+// benchBatchSizes are the batch sizes of the BatchGet, BatchSet, and
+// BatchSpend benchmarks. 100 is the maximum number of identifiers in a
+// new-order request.
+var benchBatchSizes = []int{1, 10, 100}
+
+// benchBucketKeys returns n distinct bucket keys, prefixed by the name of the
+// benchmark so that concurrent runs against a shared source don't collide.
+func benchBucketKeys(b *testing.B, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = joinWithColon(CertificatesPerDomain.EnumString(), fmt.Sprintf("%s-%d.example.com", b.Name(), i))
+	}
+	return keys
+}
+
+// setBenchBuckets sets a bucket for each of the provided keys, and deletes them
+// when the benchmark completes.
+func setBenchBuckets(b *testing.B, s source, clk clock.Clock, keys []string) {
+	buckets := make(map[string]time.Time, len(keys))
+	for _, k := range keys {
+		buckets[k] = clk.Now()
+	}
+	err := s.BatchSet(context.Background(), buckets)
+	if err != nil {
+		b.Fatalf("setting buckets: %s", err)
+	}
+	b.Cleanup(func() {
+		for _, k := range keys {
+			_ = s.Delete(context.Background(), k)
+		}
+	})
+}
+
+// BenchmarkSource compares the throughput and allocations of each operation
+// across benchSources. Run it with, e.g.:
+//
+//	go test -run '^$' -bench 'Source/miniredis/BatchGet' ./ratelimits/
+//
+// and compare runs before and after a change with benchstat.
+func BenchmarkSource(b *testing.B) {
+	for _, bs := range benchSources {
+		bs := bs
+		b.Run(bs.name, func(b *testing.B) {
+			b.Run("Get", func(b *testing.B) {
+				clk := clock.NewFake()
+				s := bs.new(b, clk)
+				keys := benchBucketKeys(b, 1)
+				setBenchBuckets(b, s, clk, keys)
+				ctx := context.Background()
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_, err := s.Get(ctx, keys[0])
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+
+			b.Run("GetParallel", func(b *testing.B) {
+				clk := clock.NewFake()
+				s := bs.new(b, clk)
+				keys := benchBucketKeys(b, 100)
+				setBenchBuckets(b, s, clk, keys)
+				ctx := context.Background()
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					var i int
+					for pb.Next() {
+						_, err := s.Get(ctx, keys[i%len(keys)])
+						if err != nil {
+							b.Error(err)
+							return
+						}
+						i++
+					}
+				})
+			})
+
+			for _, n := range benchBatchSizes {
+				b.Run(fmt.Sprintf("BatchGet/batch=%d", n), func(b *testing.B) {
+					clk := clock.NewFake()
+					s := bs.new(b, clk)
+					keys := benchBucketKeys(b, n)
+					setBenchBuckets(b, s, clk, keys)
+					ctx := context.Background()
+
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						_, err := s.BatchGet(ctx, keys)
+						if err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+
+				b.Run(fmt.Sprintf("BatchSet/batch=%d", n), func(b *testing.B) {
+					clk := clock.NewFake()
+					s := bs.new(b, clk)
+					keys := benchBucketKeys(b, n)
+					setBenchBuckets(b, s, clk, keys)
+					buckets := make(map[string]time.Time, n)
+					for _, k := range keys {
+						buckets[k] = clk.Now()
+					}
+					ctx := context.Background()
+
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						err := s.BatchSet(ctx, buckets)
+						if err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+
+				b.Run(fmt.Sprintf("BatchSpend/batch=%d", n), func(b *testing.B) {
+					clk := clock.NewFake()
+					l, err := NewLimiter(clk, bs.new(b, clk), metrics.NoopRegisterer)
+					if err != nil {
+						b.Fatal(err)
+					}
+					keys := benchBucketKeys(b, n)
+					b.Cleanup(func() {
+						for _, k := range keys {
+							_ = l.Reset(context.Background(), k)
+						}
+					})
+					// A limit which is never exhausted, so that every
+					// BatchSpend reads and writes every bucket.
+					rl := precomputeLimit(limit{
+						Burst:  1000000000,
+						Count:  1000000000,
+						Period: config.Duration{Duration: time.Second},
+						name:   CertificatesPerDomain,
+					})
+					txns := make([]Transaction, n)
+					for i, k := range keys {
+						txns[i] = Transaction{bucketKey: k, limit: rl, cost: 1, check: true, spend: true}
+					}
+					ctx := context.Background()
+
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						d, err := l.BatchSpend(ctx, txns)
+						if err != nil {
+							b.Fatal(err)
+						}
+						if !d.Allowed {
+							b.Fatal("BatchSpend should be allowed")
+						}
+					}
+				})
+			}
+		})
+	}
+}
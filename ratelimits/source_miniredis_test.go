@@ -0,0 +1,254 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jmhodges/clock"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// newMiniredisRing starts the provided number of miniredis servers, each of
+// which is closed when the test completes, and returns a *redis.Ring sharded
+// across them along with the servers, in shard order.
+func newMiniredisRing(t testing.TB, shards int) (*redis.Ring, []*miniredis.Miniredis) {
+	t.Helper()
+	servers := make([]*miniredis.Miniredis, shards)
+	addrs := make(map[string]string, shards)
+	for i := range servers {
+		servers[i] = miniredis.RunT(t)
+		addrs[fmt.Sprintf("shard%d", i+1)] = servers[i].Addr()
+	}
+	ring := redis.NewRing(&redis.RingOptions{
+		Addrs: addrs,
+		// Fail fast when a test closes a server.
+		MaxRetries:  -1,
+		DialTimeout: time.Second,
+	})
+	t.Cleanup(func() { _ = ring.Close() })
+	return ring, servers
+}
+
+// newMiniredisSource returns a *RedisSource backed by a ring of two miniredis
+// servers, along with the servers.
+func newMiniredisSource(t testing.TB, clk clock.Clock) (*RedisSource, []*miniredis.Miniredis) {
+	t.Helper()
+	ring, servers := newMiniredisRing(t, 2)
+	return NewRedisSource(ring, clk, metrics.NoopRegisterer), servers
+}
+
+// newMiniredisTestLimiter returns a *Limiter backed by newMiniredisSource.
+func newMiniredisTestLimiter(t *testing.T, clk clock.FakeClock) *Limiter {
+	t.Helper()
+	source, _ := newMiniredisSource(t, clk)
+	return newTestLimiter(t, source, clk)
+}
+
+func TestMiniredisSource_BatchSetAndGet(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	s, servers := newMiniredisSource(t, clk)
+	ctx := context.Background()
+
+	set := make(map[string]time.Time)
+	var keys []string
+	for i := 0; i < 20; i++ {
+		key := joinWithColon(CertificatesPerFQDNSet.EnumString(), fmt.Sprintf("batch%d", i))
+		set[key] = clk.Now().Add(time.Duration(i) * time.Second)
+		keys = append(keys, key)
+	}
+	err := s.BatchSet(ctx, set)
+	test.AssertNotError(t, err, "BatchSet() should not error")
+
+	// The pipeline is split across every shard, and buckets never expire.
+	var stored int
+	for _, server := range servers {
+		test.Assert(t, len(server.Keys()) > 0, "every shard should store some buckets")
+		for _, key := range server.Keys() {
+			test.AssertEquals(t, server.TTL(key), time.Duration(0))
+		}
+		stored += len(server.Keys())
+	}
+	test.AssertEquals(t, stored, len(set))
+
+	got, err := s.BatchGet(ctx, append(keys, "missing"))
+	test.AssertNotError(t, err, "BatchGet() should not error")
+	test.AssertEquals(t, len(got), len(set))
+	for k, v := range set {
+		test.Assert(t, got[k].Equal(v), "BatchGet() should return the values set by BatchSet()")
+	}
+
+	tat, err := s.Get(ctx, keys[0])
+	test.AssertNotError(t, err, "Get() should not error")
+	test.Assert(t, tat.Equal(set[keys[0]]), "Get() should return the value set by BatchSet()")
+
+	_, err = s.Get(ctx, "missing")
+	test.AssertErrorIs(t, err, ErrBucketNotFound)
+
+	err = s.Delete(ctx, keys[0])
+	test.AssertNotError(t, err, "Delete() should not error")
+	_, err = s.Get(ctx, keys[0])
+	test.AssertErrorIs(t, err, ErrBucketNotFound)
+}
+
+func TestMiniredisSource_Errors(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	s, servers := newMiniredisSource(t, clk)
+	ctx := context.Background()
+
+	key := joinWithColon(CertificatesPerFQDNSet.EnumString(), "errors")
+	err := s.BatchSet(ctx, map[string]time.Time{key: clk.Now()})
+	test.AssertNotError(t, err, "BatchSet() should not error")
+
+	// A non-integer TAT is an error, not a missing bucket.
+	for _, server := range servers {
+		if server.Exists(key) {
+			test.AssertNotError(t, server.Set(key, "lol"), "setting value")
+		}
+	}
+	_, err = s.Get(ctx, key)
+	test.AssertError(t, err, "Get() of a malformed TAT should error")
+	test.Assert(t, !errors.Is(err, ErrBucketNotFound), "Get() of a malformed TAT should not be ErrBucketNotFound")
+	_, err = s.BatchGet(ctx, []string{key})
+	test.AssertError(t, err, "BatchGet() of a malformed TAT should error")
+
+	// Every shard returns an error.
+	for _, server := range servers {
+		server.SetError("LOADING Redis is loading the dataset in memory")
+	}
+	_, err = s.Get(ctx, key)
+	test.AssertError(t, err, "Get() should error")
+	test.AssertEquals(t, resultForError(err), "redisError")
+	_, err = s.BatchGet(ctx, []string{key})
+	test.AssertError(t, err, "BatchGet() should error")
+	err = s.BatchSet(ctx, map[string]time.Time{key: clk.Now()})
+	test.AssertError(t, err, "BatchSet() should error")
+	err = s.Ping(ctx)
+	test.AssertError(t, err, "Ping() should error")
+	for _, server := range servers {
+		server.SetError("")
+	}
+	test.AssertNotError(t, s.Ping(ctx), "Ping() should not error")
+
+	// A shard is unreachable.
+	servers[1].Close()
+	err = s.Ping(ctx)
+	test.AssertError(t, err, "Ping() should error when a shard is closed")
+	var netErr net.Error
+	test.Assert(t, errors.As(err, &netErr), "Ping() should return a network error")
+}
+
+func TestResultForError(t *testing.T) {
+	t.Parallel()
+	test.AssertEquals(t, resultForError(redis.Nil), "notFound")
+	test.AssertEquals(t, resultForError(context.DeadlineExceeded), "deadlineExceeded")
+	test.AssertEquals(t, resultForError(fmt.Errorf("wrapped: %w", context.Canceled)), "canceled")
+	test.AssertEquals(t, resultForError(errors.New("oops")), "failed")
+
+	clk := clock.NewFake()
+	s, servers := newMiniredisSource(t, clk)
+	servers[0].SetError("ERR oops")
+	servers[1].SetError("ERR oops")
+	err := s.Ping(context.Background())
+	test.AssertEquals(t, resultForError(err), "redisError")
+}
+
+func TestMiniredisSource_PrefixOperations(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	s, _ := newMiniredisSource(t, clk)
+	ctx := context.Background()
+
+	// Glob characters in the prefix must be matched literally.
+	prefix := joinWithColon(CertificatesPerFQDNSet.EnumString(), "prefix[*")
+	keep := joinWithColon(CertificatesPerFQDNSet.EnumString(), "prefixkeep")
+	set := map[string]time.Time{keep: clk.Now()}
+	for i := 0; i < 10; i++ {
+		set[fmt.Sprintf("%s%d", prefix, i)] = clk.Now().Add(time.Duration(i) * time.Second)
+	}
+	err := s.BatchSet(ctx, set)
+	test.AssertNotError(t, err, "BatchSet() should not error")
+
+	scanned := make(map[string]time.Time)
+	err = s.scanBuckets(ctx, prefix, 3, 0, func(buckets map[string]time.Time) error {
+		for k, v := range buckets {
+			scanned[k] = v
+		}
+		return nil
+	})
+	test.AssertNotError(t, err, "scanBuckets() should not error")
+	test.AssertEquals(t, len(scanned), 10)
+	for k, v := range scanned {
+		test.Assert(t, set[k].Equal(v), "scanBuckets() should return the values set by BatchSet()")
+	}
+
+	estimates, err := s.estimateBuckets(ctx)
+	test.AssertNotError(t, err, "estimateBuckets() should not error")
+	test.AssertEquals(t, estimates[CertificatesPerFQDNSet], int64(11))
+
+	// Unlike Redis, the SCAN cursor of miniredis is an offset into the sorted
+	// keys, so deleting keys between SCANs skips subsequent keys. Delete them
+	// in a single batch.
+	var deleted []string
+	err = s.deletePrefix(ctx, prefix, 100, 0, func(keys []string) {
+		deleted = append(deleted, keys...)
+	})
+	test.AssertNotError(t, err, "deletePrefix() should not error")
+	test.AssertEquals(t, len(deleted), 10)
+	got, err := s.BatchGet(ctx, []string{keep, prefix + "0"})
+	test.AssertNotError(t, err, "BatchGet() should not error")
+	test.AssertEquals(t, len(got), 1)
+	test.Assert(t, !got[keep].IsZero(), "deletePrefix() should not delete other keys")
+}
+
+func TestMiniredisSource_CheckClockSkew(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	s, servers := newMiniredisSource(t, clk)
+
+	servers[0].SetTime(clk.Now().Add(time.Minute))
+	servers[1].SetTime(clk.Now().Add(-time.Second))
+	skews, err := s.checkClockSkew(context.Background())
+	test.AssertNotError(t, err, "checkClockSkew() should not error")
+	test.AssertEquals(t, skews[servers[0].Addr()], time.Minute)
+	test.AssertEquals(t, skews[servers[1].Addr()], -time.Second)
+}
+
+func TestMiniredisLimiter(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	l := newMiniredisTestLimiter(t, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	ctx := context.Background()
+
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	for i := 0; i < 20; i++ {
+		d, err := l.Spend(ctx, txn)
+		test.AssertNotError(t, err, "should not error")
+		test.Assert(t, d.Allowed, "should be allowed")
+		test.AssertEquals(t, d.Remaining, int64(19-i))
+	}
+	d, err := l.Spend(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, !d.Allowed, "should be denied")
+
+	d, err = l.Refund(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(1))
+
+	err = l.Reset(ctx, txn.bucketKey)
+	test.AssertNotError(t, err, "should not error")
+	d, err = l.Check(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, int64(19))
+}
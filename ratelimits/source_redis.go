@@ -3,10 +3,15 @@ package ratelimits
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmhodges/clock"
+	blog "github.com/letsencrypt/boulder/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
 )
@@ -14,11 +19,16 @@ import (
 // Compile-time check that RedisSource implements the source interface.
 var _ source = (*RedisSource)(nil)
 
+// estimateSampleSize is the number of keys sampled from each shard when
+// estimating the number of buckets per limit.
+const estimateSampleSize = 1000
+
 // RedisSource is a ratelimits source backed by sharded Redis.
 type RedisSource struct {
-	client  *redis.Ring
-	clk     clock.Clock
-	latency *prometheus.HistogramVec
+	client    *redis.Ring
+	clk       clock.Clock
+	latency   *prometheus.HistogramVec
+	clockSkew *prometheus.GaugeVec
 }
 
 // NewRedisSource returns a new Redis backed source using the provided
@@ -27,7 +37,7 @@ func NewRedisSource(client *redis.Ring, clk clock.Clock, stats prometheus.Regist
 	latency := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name: "ratelimits_latency",
-			Help: "Histogram of Redis call latencies labeled by call=[set|get|delete|ping] and result=[success|error]",
+			Help: "Histogram of Redis call latencies labeled by call=[set|get|delete|deleteprefix|scan|ping|estimate|time] and result=[success|error]",
 			// Exponential buckets ranging from 0.0005s to 3s.
 			Buckets: prometheus.ExponentialBucketsRange(0.0005, 3, 8),
 		},
@@ -35,10 +45,20 @@ func NewRedisSource(client *redis.Ring, clk clock.Clock, stats prometheus.Regist
 	)
 	stats.MustRegister(latency)
 
+	clockSkew := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ratelimits_redis_clock_skew_seconds",
+			Help: "Difference between the Redis server time and the local time, by shard=[addr], in seconds. Positive values indicate the Redis server is ahead",
+		},
+		[]string{"shard"},
+	)
+	stats.MustRegister(clockSkew)
+
 	return &RedisSource{
-		client:  client,
-		clk:     clk,
-		latency: latency,
+		client:    client,
+		clk:       clk,
+		latency:   latency,
+		clockSkew: clockSkew,
 	}
 }
 
@@ -61,7 +81,7 @@ func resultForError(err error) string {
 		return "timeout"
 	}
 	var redisErr redis.Error
-	if errors.Is(err, redisErr) {
+	if errors.As(err, &redisErr) {
 		// An internal error was returned by the Redis server.
 		return "redisError"
 	}
@@ -80,11 +100,11 @@ func (r *RedisSource) BatchSet(ctx context.Context, buckets map[string]time.Time
 	}
 	_, err := pipeline.Exec(ctx)
 	if err != nil {
-		r.latency.With(prometheus.Labels{"call": "batchset", "result": resultForError(err)}).Observe(time.Since(start).Seconds())
+		observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "batchset", "result": resultForError(err)}), time.Since(start).Seconds())
 		return err
 	}
 
-	r.latency.With(prometheus.Labels{"call": "batchset", "result": "success"}).Observe(time.Since(start).Seconds())
+	observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "batchset", "result": "success"}), time.Since(start).Seconds())
 	return nil
 }
 
@@ -98,14 +118,14 @@ func (r *RedisSource) Get(ctx context.Context, bucketKey string) (time.Time, err
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			// Bucket key does not exist.
-			r.latency.With(prometheus.Labels{"call": "get", "result": "notFound"}).Observe(time.Since(start).Seconds())
+			observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "get", "result": "notFound"}), time.Since(start).Seconds())
 			return time.Time{}, ErrBucketNotFound
 		}
-		r.latency.With(prometheus.Labels{"call": "get", "result": resultForError(err)}).Observe(time.Since(start).Seconds())
+		observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "get", "result": resultForError(err)}), time.Since(start).Seconds())
 		return time.Time{}, err
 	}
 
-	r.latency.With(prometheus.Labels{"call": "get", "result": "success"}).Observe(time.Since(start).Seconds())
+	observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "get", "result": "success"}), time.Since(start).Seconds())
 	return time.Unix(0, tatNano).UTC(), nil
 }
 
@@ -122,7 +142,7 @@ func (r *RedisSource) BatchGet(ctx context.Context, bucketKeys []string) (map[st
 	}
 	results, err := pipeline.Exec(ctx)
 	if err != nil {
-		r.latency.With(prometheus.Labels{"call": "batchget", "result": resultForError(err)}).Observe(time.Since(start).Seconds())
+		observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "batchget", "result": resultForError(err)}), time.Since(start).Seconds())
 		if !errors.Is(err, redis.Nil) {
 			return nil, err
 		}
@@ -136,13 +156,13 @@ func (r *RedisSource) BatchGet(ctx context.Context, bucketKeys []string) (map[st
 				// Bucket key does not exist.
 				continue
 			}
-			r.latency.With(prometheus.Labels{"call": "batchget", "result": resultForError(err)}).Observe(time.Since(start).Seconds())
+			observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "batchget", "result": resultForError(err)}), time.Since(start).Seconds())
 			return nil, err
 		}
 		tats[bucketKeys[i]] = time.Unix(0, tatNano).UTC()
 	}
 
-	r.latency.With(prometheus.Labels{"call": "batchget", "result": "success"}).Observe(time.Since(start).Seconds())
+	observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "batchget", "result": "success"}), time.Since(start).Seconds())
 	return tats, nil
 }
 
@@ -154,11 +174,145 @@ func (r *RedisSource) Delete(ctx context.Context, bucketKey string) error {
 
 	err := r.client.Del(ctx, bucketKey).Err()
 	if err != nil {
-		r.latency.With(prometheus.Labels{"call": "delete", "result": resultForError(err)}).Observe(time.Since(start).Seconds())
+		observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "delete", "result": resultForError(err)}), time.Since(start).Seconds())
 		return err
 	}
 
-	r.latency.With(prometheus.Labels{"call": "delete", "result": "success"}).Observe(time.Since(start).Seconds())
+	observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "delete", "result": "success"}), time.Since(start).Seconds())
+	return nil
+}
+
+// roundTrip calls fn with a copy of ctx with the provided timeout applied,
+// unless it is 0. If fn returns an error because that deadline was exceeded,
+// the error matches context.DeadlineExceeded, even if the Redis client reported
+// it as a network timeout.
+func roundTrip(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	rtCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	err := fn(rtCtx)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) && errors.Is(rtCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return err
+}
+
+// globEscaper escapes the characters which are special in the glob-style
+// patterns accepted by SCAN MATCH.
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// deletePrefix deletes every bucket whose key begins with prefix. Each shard of
+// the *redis.Ring is walked using SCAN, and the keys returned by each SCAN are
+// removed with a single DEL before the next SCAN is issued. The timeout, if not
+// 0, is applied to each SCAN and DEL. The deleted func is never called
+// concurrently.
+func (r *RedisSource) deletePrefix(ctx context.Context, prefix string, batchSize int64, timeout time.Duration, deleted func([]string)) error {
+	start := r.clk.Now()
+
+	var mu sync.Mutex
+	match := globEscaper.Replace(prefix) + "*"
+	err := r.client.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+		var cursor uint64
+		for {
+			var keys []string
+			var next uint64
+			err := roundTrip(ctx, timeout, func(ctx context.Context) error {
+				var err error
+				keys, next, err = shard.Scan(ctx, cursor, match, batchSize).Result()
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				err = roundTrip(ctx, timeout, func(ctx context.Context) error {
+					return shard.Del(ctx, keys...).Err()
+				})
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				deleted(keys)
+				mu.Unlock()
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	})
+	if err != nil {
+		observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "deleteprefix", "result": resultForError(err)}), time.Since(start).Seconds())
+		return err
+	}
+	observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "deleteprefix", "result": "success"}), time.Since(start).Seconds())
+	return nil
+}
+
+// scanBuckets calls fn with the TAT of every bucket whose key begins with
+// prefix. Each shard of the *redis.Ring is walked using SCAN, and the TATs of
+// the keys returned by each SCAN are read with a single MGET. Keys which expire
+// or are deleted between the SCAN and the MGET are omitted. The timeout, if not
+// 0, is applied to each SCAN and MGET. The fn func is never called
+// concurrently.
+func (r *RedisSource) scanBuckets(ctx context.Context, prefix string, batchSize int64, timeout time.Duration, fn func(map[string]time.Time) error) error {
+	start := r.clk.Now()
+
+	var mu sync.Mutex
+	match := globEscaper.Replace(prefix) + "*"
+	err := r.client.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+		var cursor uint64
+		for {
+			var keys []string
+			var next uint64
+			err := roundTrip(ctx, timeout, func(ctx context.Context) error {
+				var err error
+				keys, next, err = shard.Scan(ctx, cursor, match, batchSize).Result()
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				var values []interface{}
+				err = roundTrip(ctx, timeout, func(ctx context.Context) error {
+					var err error
+					values, err = shard.MGet(ctx, keys...).Result()
+					return err
+				})
+				if err != nil {
+					return err
+				}
+				buckets := make(map[string]time.Time, len(keys))
+				for i, value := range values {
+					s, ok := value.(string)
+					if !ok {
+						// Bucket key does not exist.
+						continue
+					}
+					tatNano, err := strconv.ParseInt(s, 10, 64)
+					if err != nil {
+						return fmt.Errorf("parsing TAT of bucket %q: %w", keys[i], err)
+					}
+					buckets[keys[i]] = time.Unix(0, tatNano).UTC()
+				}
+				mu.Lock()
+				err = fn(buckets)
+				mu.Unlock()
+				if err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	})
+	if err != nil {
+		observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "scan", "result": resultForError(err)}), time.Since(start).Seconds())
+		return err
+	}
+	observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "scan", "result": "success"}), time.Since(start).Seconds())
 	return nil
 }
 
@@ -171,9 +325,114 @@ func (r *RedisSource) Ping(ctx context.Context) error {
 		return shard.Ping(ctx).Err()
 	})
 	if err != nil {
-		r.latency.With(prometheus.Labels{"call": "ping", "result": resultForError(err)}).Observe(time.Since(start).Seconds())
+		observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "ping", "result": resultForError(err)}), time.Since(start).Seconds())
 		return err
 	}
-	r.latency.With(prometheus.Labels{"call": "ping", "result": "success"}).Observe(time.Since(start).Seconds())
+	observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "ping", "result": "success"}), time.Since(start).Seconds())
 	return nil
 }
+
+// estimateBuckets estimates the number of buckets stored for each limit. For
+// each shard of the *redis.Ring it samples up to estimateSampleSize keys using
+// a single SCAN and scales the proportion of sampled keys belonging to each
+// limit by the total number of keys on that shard, as reported by DBSIZE.
+func (r *RedisSource) estimateBuckets(ctx context.Context) (map[Name]int64, error) {
+	start := r.clk.Now()
+
+	var mu sync.Mutex
+	estimates := make(map[Name]int64)
+	err := r.client.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+		size, err := shard.DBSize(ctx).Result()
+		if err != nil {
+			return err
+		}
+		keys, _, err := shard.Scan(ctx, 0, "", estimateSampleSize).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		sampled := make(map[Name]int64)
+		for _, key := range keys {
+			name := nameForBucketKey(key)
+			if name == Unknown {
+				continue
+			}
+			sampled[name]++
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for name, count := range sampled {
+			estimates[name] += count * size / int64(len(keys))
+		}
+		return nil
+	})
+	if err != nil {
+		observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "estimate", "result": resultForError(err)}), time.Since(start).Seconds())
+		return nil, err
+	}
+	observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "estimate", "result": "success"}), time.Since(start).Seconds())
+	return estimates, nil
+}
+
+// checkClockSkew compares the local clock with the TIME reported by each shard
+// of the *redis.Ring, exports the difference as a gauge, and returns the
+// difference for each shard keyed by shard address. The local time is taken as
+// the midpoint of each TIME call to account for round-trip latency.
+func (r *RedisSource) checkClockSkew(ctx context.Context) (map[string]time.Duration, error) {
+	start := r.clk.Now()
+
+	var mu sync.Mutex
+	skews := make(map[string]time.Duration)
+	err := r.client.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+		before := r.clk.Now()
+		serverTime, err := shard.Time(ctx).Result()
+		if err != nil {
+			return err
+		}
+		after := r.clk.Now()
+		skew := serverTime.Sub(before.Add(after.Sub(before) / 2))
+
+		addr := shard.Options().Addr
+		r.clockSkew.WithLabelValues(addr).Set(skew.Seconds())
+		mu.Lock()
+		skews[addr] = skew
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "time", "result": resultForError(err)}), time.Since(start).Seconds())
+		return nil, err
+	}
+	observeLatency(ctx, r.latency.With(prometheus.Labels{"call": "time", "result": "success"}), time.Since(start).Seconds())
+	return skews, nil
+}
+
+// MonitorClockSkew compares the local clock with the TIME reported by each
+// shard every interval, exporting the difference as the
+// ratelimits_redis_clock_skew_seconds gauge. A warning is logged to the
+// provided logger for each shard whose clock differs from the local clock by
+// more than threshold, because GCRA decisions degrade silently with skew. It
+// blocks until the provided context is canceled.
+func (r *RedisSource) MonitorClockSkew(ctx context.Context, interval, threshold time.Duration, logger blog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		skews, err := r.checkClockSkew(ctx)
+		if err != nil {
+			logger.Warningf("checking Redis clock skew: %s", err)
+		}
+		for addr, skew := range skews {
+			if skew > threshold || skew < -threshold {
+				logger.Warningf("Redis shard %s clock differs from local clock by %s, exceeding threshold of %s", addr, skew, threshold)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
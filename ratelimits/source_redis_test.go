@@ -103,3 +103,106 @@ func TestRedisSource_BatchSetAndGet(t *testing.T) {
 	test.AssertNotError(t, err, "BatchGet() should not error when a key isn't found")
 	test.Assert(t, got["test4"].IsZero(), "BatchGet() should return a zero time for a key that does not exist")
 }
+
+func TestRedisSource_EstimateBuckets(t *testing.T) {
+	clk := clock.NewFake()
+	s := newTestRedisSource(clk, map[string]string{
+		"shard1": "10.33.33.4:4218",
+		"shard2": "10.33.33.5:4218",
+	})
+
+	set := map[string]time.Time{
+		joinWithColon(CertificatesPerFQDNSet.EnumString(), "estimate1"): clk.Now(),
+		joinWithColon(CertificatesPerFQDNSet.EnumString(), "estimate2"): clk.Now(),
+		joinWithColon(CertificatesPerFQDNSet.EnumString(), "estimate3"): clk.Now(),
+	}
+	err := s.BatchSet(context.Background(), set)
+	test.AssertNotError(t, err, "BatchSet() should not error")
+
+	// Other tests share these shards, so only a lower bound can be asserted.
+	estimates, err := s.estimateBuckets(context.Background())
+	test.AssertNotError(t, err, "estimateBuckets() should not error")
+	test.Assert(t, estimates[CertificatesPerFQDNSet] >= 3, "estimateBuckets() should count the buckets set by BatchSet()")
+}
+
+func TestRedisSource_DeletePrefix(t *testing.T) {
+	clk := clock.NewFake()
+	s := newTestRedisSource(clk, map[string]string{
+		"shard1": "10.33.33.4:4218",
+		"shard2": "10.33.33.5:4218",
+	})
+
+	prefix := joinWithColon(CertificatesPerFQDNSet.EnumString(), "deleteprefix[")
+	set := map[string]time.Time{
+		prefix + "1": clk.Now(),
+		prefix + "2": clk.Now(),
+		prefix + "3": clk.Now(),
+		joinWithColon(CertificatesPerFQDNSet.EnumString(), "deleteprefixkeep"): clk.Now(),
+	}
+	err := s.BatchSet(context.Background(), set)
+	test.AssertNotError(t, err, "BatchSet() should not error")
+
+	var deleted []string
+	err = s.deletePrefix(context.Background(), prefix, 1, 0, func(keys []string) {
+		deleted = append(deleted, keys...)
+	})
+	test.AssertNotError(t, err, "deletePrefix() should not error")
+	test.AssertEquals(t, len(deleted), 3)
+
+	got, err := s.BatchGet(context.Background(), []string{prefix + "1", joinWithColon(CertificatesPerFQDNSet.EnumString(), "deleteprefixkeep")})
+	test.AssertNotError(t, err, "BatchGet() should not error")
+	test.Assert(t, got[prefix+"1"].IsZero(), "deletePrefix() should delete keys matching the prefix")
+	test.Assert(t, !got[joinWithColon(CertificatesPerFQDNSet.EnumString(), "deleteprefixkeep")].IsZero(), "deletePrefix() should not delete other keys")
+}
+
+func TestRedisSource_ScanBuckets(t *testing.T) {
+	clk := clock.NewFake()
+	s := newTestRedisSource(clk, map[string]string{
+		"shard1": "10.33.33.4:4218",
+		"shard2": "10.33.33.5:4218",
+	})
+
+	prefix := joinWithColon(CertificatesPerFQDNSet.EnumString(), "scanbuckets")
+	set := map[string]time.Time{
+		prefix + "1": clk.Now(),
+		prefix + "2": clk.Now().Add(time.Second),
+		prefix + "3": clk.Now().Add(2 * time.Second),
+	}
+	err := s.BatchSet(context.Background(), set)
+	test.AssertNotError(t, err, "BatchSet() should not error")
+
+	got := make(map[string]time.Time)
+	err = s.scanBuckets(context.Background(), prefix, 1, 0, func(buckets map[string]time.Time) error {
+		for k, v := range buckets {
+			got[k] = v
+		}
+		return nil
+	})
+	test.AssertNotError(t, err, "scanBuckets() should not error")
+	test.AssertEquals(t, len(got), 3)
+	for k, v := range set {
+		test.Assert(t, got[k].Equal(v), "scanBuckets() should return the values set by BatchSet()")
+	}
+}
+
+func TestRedisSource_CheckClockSkew(t *testing.T) {
+	clk := clock.NewFake()
+	clk.Set(time.Now())
+	s := newTestRedisSource(clk, map[string]string{
+		"shard1": "10.33.33.4:4218",
+		"shard2": "10.33.33.5:4218",
+	})
+
+	skews, err := s.checkClockSkew(context.Background())
+	test.AssertNotError(t, err, "checkClockSkew() should not error")
+	test.AssertEquals(t, len(skews), 2)
+
+	// Advancing the local clock should be reflected as the Redis server
+	// falling behind.
+	clk.Add(time.Hour)
+	skews, err = s.checkClockSkew(context.Background())
+	test.AssertNotError(t, err, "checkClockSkew() should not error")
+	for addr, skew := range skews {
+		test.Assert(t, skew < -50*time.Minute, "shard "+addr+" should be behind the local clock")
+	}
+}
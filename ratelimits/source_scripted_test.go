@@ -0,0 +1,256 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+const (
+	// The following are the operations of a scriptedSource which may be
+	// scripted. BatchGet and BatchSet are scripted per key, Get and Delete are
+	// scripted for their only key.
+	opGet      = "Get"
+	opBatchGet = "BatchGet"
+	opBatchSet = "BatchSet"
+	opDelete   = "Delete"
+)
+
+// scriptedStep is the scripted behavior of a single call to a scriptedSource
+// for a single key.
+type scriptedStep struct {
+	// latency advances the fake clock of the scriptedSource before the call
+	// returns.
+	latency time.Duration
+
+	// err is returned by the call. For BatchSet, keys whose steps have no err
+	// are still written, so a batch can partially fail.
+	err error
+
+	// notFound causes Get to return ErrBucketNotFound and BatchGet to omit
+	// the key, regardless of whether the bucket exists. BatchSet and Delete
+	// ignore it.
+	notFound bool
+}
+
+// scriptedCall records a single call to a scriptedSource.
+type scriptedCall struct {
+	op         string
+	bucketKeys []string
+}
+
+// scriptedSource is a source whose behavior for each operation and key is
+// scripted as a sequence of steps, one consumed per call. Calls for which no
+// step remains behave as an inmem source. Every call is recorded.
+type scriptedSource struct {
+	sync.Mutex
+	clk     clock.FakeClock
+	backing *InmemSource
+	steps   map[string]map[string][]scriptedStep
+	calls   []scriptedCall
+}
+
+func newScriptedSource(clk clock.FakeClock) *scriptedSource {
+	return &scriptedSource{
+		clk:     clk,
+		backing: newInmem(),
+		steps:   make(map[string]map[string][]scriptedStep),
+	}
+}
+
+// script appends the provided steps to those scripted for op and bucketKey.
+func (s *scriptedSource) script(op, bucketKey string, steps ...scriptedStep) {
+	s.Lock()
+	defer s.Unlock()
+	if s.steps[op] == nil {
+		s.steps[op] = make(map[string][]scriptedStep)
+	}
+	s.steps[op][bucketKey] = append(s.steps[op][bucketKey], steps...)
+}
+
+// next records a call to op and returns the next step scripted for each of the
+// provided bucketKeys, consuming them. Keys without a scripted step get the
+// zero step. The clock is advanced by the largest latency of the steps.
+func (s *scriptedSource) next(op string, bucketKeys ...string) map[string]scriptedStep {
+	s.Lock()
+	defer s.Unlock()
+	s.calls = append(s.calls, scriptedCall{op: op, bucketKeys: bucketKeys})
+	steps := make(map[string]scriptedStep, len(bucketKeys))
+	var latency time.Duration
+	for _, bucketKey := range bucketKeys {
+		var step scriptedStep
+		queue := s.steps[op][bucketKey]
+		if len(queue) > 0 {
+			step = queue[0]
+			s.steps[op][bucketKey] = queue[1:]
+		}
+		steps[bucketKey] = step
+		latency = max(latency, step.latency)
+	}
+	s.clk.Add(latency)
+	return steps
+}
+
+// recorded returns the calls made so far.
+func (s *scriptedSource) recorded() []scriptedCall {
+	s.Lock()
+	defer s.Unlock()
+	return append([]scriptedCall(nil), s.calls...)
+}
+
+func (s *scriptedSource) BatchSet(ctx context.Context, bucketKeys map[string]time.Time) error {
+	keys := make([]string, 0, len(bucketKeys))
+	for k := range bucketKeys {
+		keys = append(keys, k)
+	}
+	var firstErr error
+	write := make(map[string]time.Time)
+	for k, step := range s.next(opBatchSet, keys...) {
+		if step.err != nil {
+			if firstErr == nil {
+				firstErr = step.err
+			}
+			continue
+		}
+		write[k] = bucketKeys[k]
+	}
+	err := s.backing.BatchSet(ctx, write)
+	if err != nil {
+		return err
+	}
+	return firstErr
+}
+
+func (s *scriptedSource) Get(ctx context.Context, bucketKey string) (time.Time, error) {
+	step := s.next(opGet, bucketKey)[bucketKey]
+	if step.err != nil {
+		return time.Time{}, step.err
+	}
+	if step.notFound {
+		return time.Time{}, ErrBucketNotFound
+	}
+	return s.backing.Get(ctx, bucketKey)
+}
+
+func (s *scriptedSource) BatchGet(ctx context.Context, bucketKeys []string) (map[string]time.Time, error) {
+	steps := s.next(opBatchGet, bucketKeys...)
+	for _, k := range bucketKeys {
+		if steps[k].err != nil {
+			return nil, steps[k].err
+		}
+	}
+	tats, err := s.backing.BatchGet(ctx, bucketKeys)
+	if err != nil {
+		return nil, err
+	}
+	for k, step := range steps {
+		if step.notFound {
+			delete(tats, k)
+		}
+	}
+	return tats, nil
+}
+
+func (s *scriptedSource) Delete(ctx context.Context, bucketKey string) error {
+	step := s.next(opDelete, bucketKey)[bucketKey]
+	if step.err != nil {
+		return step.err
+	}
+	return s.backing.Delete(ctx, bucketKey)
+}
+
+func TestScriptedSource_GetNotFoundButSetSucceeds(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	source := newScriptedSource(clk)
+	l := newTestLimiter(t, source, clk)
+	txn, err := newTestTransactionBuilder(t).RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, err = l.Spend(ctx, txn)
+		test.AssertNotError(t, err, "should not error")
+	}
+
+	// A Check which is told the bucket doesn't exist treats it as full.
+	source.script(opGet, txn.bucketKey, scriptedStep{notFound: true})
+	d, err := l.Check(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, txn.limit.Burst-1)
+
+	// A Spend which is told the bucket doesn't exist overwrites it with a new
+	// bucket.
+	source.script(opBatchGet, txn.bucketKey, scriptedStep{notFound: true})
+	d, err = l.Spend(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, txn.limit.Burst-1)
+
+	// The scripted steps have been consumed, so the overwritten bucket is
+	// read back.
+	d, err = l.Check(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.Remaining, txn.limit.Burst-2)
+
+	// Each Spend calls BatchGet and BatchSet, each Check calls Get.
+	calls := source.recorded()
+	test.AssertEquals(t, len(calls), 14)
+	test.AssertEquals(t, calls[10].op, opGet)
+	test.AssertEquals(t, calls[11].op, opBatchGet)
+	test.AssertEquals(t, calls[12].op, opBatchSet)
+	test.AssertEquals(t, calls[13].op, opGet)
+}
+
+func TestScriptedSource_PartialBatchFailure(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	source := newScriptedSource(clk)
+	l := newTestLimiter(t, source, clk)
+	txnBuilder := newTestTransactionBuilder(t)
+	txn1, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+	txn2, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.3"))
+	test.AssertNotError(t, err, "should not error")
+	ctx := context.Background()
+
+	// The write of the second bucket fails, but the first is still written.
+	errWriteFailed := errors.New("write failed")
+	source.script(opBatchSet, txn2.bucketKey, scriptedStep{err: errWriteFailed})
+	_, err = l.BatchSpend(ctx, []Transaction{txn1, txn2})
+	test.AssertErrorIs(t, err, errWriteFailed)
+
+	decisions, err := l.BatchCheck(ctx, []Transaction{txn1, txn2})
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, decisions[0].Remaining, txn1.limit.Burst-2)
+	test.AssertEquals(t, decisions[1].Remaining, txn2.limit.Burst-1)
+
+	// A read of either bucket failing fails the whole batch closed.
+	errReadFailed := errors.New("read failed")
+	source.script(opBatchGet, txn1.bucketKey, scriptedStep{err: errReadFailed})
+	_, err = l.BatchSpend(ctx, []Transaction{txn1, txn2})
+	test.AssertErrorIs(t, err, errReadFailed)
+}
+
+func TestScriptedSource_Latency(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake()
+	source := newScriptedSource(clk)
+	l := newTestLimiter(t, source, clk)
+	txn, err := newTestTransactionBuilder(t).RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.1"))
+	test.AssertNotError(t, err, "should not error")
+
+	// The bucket is evaluated after the read returns, so the new TAT is
+	// relative to the clock after the scripted latency.
+	start := clk.Now()
+	source.script(opBatchGet, txn.bucketKey, scriptedStep{latency: time.Second})
+	d, err := l.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, d.newTAT, start.Add(time.Second).Add(time.Duration(txn.limit.emissionInterval)))
+}
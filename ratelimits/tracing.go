@@ -0,0 +1,137 @@
+package ratelimits
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the OpenTelemetry tracer used by this package.
+const tracerName = "github.com/letsencrypt/boulder/ratelimits"
+
+// newTracer returns a trace.Tracer from the globally configured
+// TracerProvider.
+func newTracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// observeLatency observes the provided latency, in seconds, on the provided
+// histogram. If ctx contains a sampled span, its trace ID is attached to the
+// observation as an exemplar so that slow observations can be linked to the
+// trace which produced them.
+func observeLatency(ctx context.Context, o prometheus.Observer, seconds float64) {
+	sc := trace.SpanContextFromContext(ctx)
+	eo, ok := o.(prometheus.ExemplarObserver)
+	if ok && sc.IsSampled() {
+		eo.ObserveWithExemplar(seconds, prometheus.Labels{"trace_id": sc.TraceID().String()})
+		return
+	}
+	o.Observe(seconds)
+}
+
+// limitNamesForTxns returns the distinct limit names of the provided
+// Transactions, in order of first appearance.
+func limitNamesForTxns(txns []Transaction) []string {
+	var names []string
+	for _, txn := range txns {
+		// Batches span only a handful of limits, so a linear search is cheaper
+		// than allocating a set.
+		name := txn.limit.name.String()
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// startSpan starts a span for the named Limiter operation on the provided
+// Transactions, as a child of any span in ctx.
+func (l *Limiter) startSpan(ctx context.Context, op string, txns []Transaction) (context.Context, trace.Span) {
+	return l.tracer.Start(ctx, "ratelimits.Limiter/"+op, trace.WithAttributes(
+		attribute.StringSlice("ratelimits.limits", limitNamesForTxns(txns)),
+		attribute.Int("ratelimits.batch_size", len(txns)),
+	))
+}
+
+// endSpan records the outcome of a Limiter operation on the provided span and
+// ends it.
+func endSpan(span trace.Span, d *Decision, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if d != nil {
+		span.SetAttributes(
+			attribute.Bool("ratelimits.allowed", d.Allowed),
+			attribute.Int64("ratelimits.remaining", d.Remaining),
+			attribute.String("ratelimits.retry_in", d.RetryIn.String()),
+		)
+	}
+	span.End()
+}
+
+// Compile-time check that tracedSource implements the source interface.
+var _ source = (*tracedSource)(nil)
+
+// tracedSource wraps a source, emitting a span for each call.
+type tracedSource struct {
+	source source
+	tracer trace.Tracer
+}
+
+func newTracedSource(s source, tracer trace.Tracer) *tracedSource {
+	return &tracedSource{source: s, tracer: tracer}
+}
+
+func (t *tracedSource) start(ctx context.Context, call string, size int) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "ratelimits.source/"+call,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int("ratelimits.batch_size", size)),
+	)
+}
+
+// end records err, unless it is ErrBucketNotFound, on the provided span and
+// ends it.
+func (t *tracedSource) end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrBucketNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *tracedSource) BatchSet(ctx context.Context, bucketKeys map[string]time.Time) error {
+	ctx, span := t.start(ctx, "BatchSet", len(bucketKeys))
+	err := t.source.BatchSet(ctx, bucketKeys)
+	t.end(span, err)
+	return err
+}
+
+func (t *tracedSource) Get(ctx context.Context, bucketKey string) (time.Time, error) {
+	ctx, span := t.start(ctx, "Get", 1)
+	tat, err := t.source.Get(ctx, bucketKey)
+	span.SetAttributes(attribute.Bool("ratelimits.found", err == nil))
+	t.end(span, err)
+	return tat, err
+}
+
+func (t *tracedSource) BatchGet(ctx context.Context, bucketKeys []string) (map[string]time.Time, error) {
+	ctx, span := t.start(ctx, "BatchGet", len(bucketKeys))
+	tats, err := t.source.BatchGet(ctx, bucketKeys)
+	span.SetAttributes(attribute.Int("ratelimits.found", len(tats)))
+	t.end(span, err)
+	return tats, err
+}
+
+func (t *tracedSource) Delete(ctx context.Context, bucketKey string) error {
+	ctx, span := t.start(ctx, "Delete", 1)
+	err := t.source.Delete(ctx, bucketKey)
+	t.end(span, err)
+	return err
+}
@@ -0,0 +1,138 @@
+package ratelimits
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// recordingExporter is a sdktrace.SpanExporter which retains every exported
+// span.
+type recordingExporter struct {
+	sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.Lock()
+	defer e.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error { return nil }
+
+func (e *recordingExporter) reset() {
+	e.Lock()
+	defer e.Unlock()
+	e.spans = nil
+}
+
+// byName returns the recorded spans keyed by span name.
+func (e *recordingExporter) byName() map[string]sdktrace.ReadOnlySpan {
+	e.Lock()
+	defer e.Unlock()
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range e.spans {
+		spans[span.Name()] = span
+	}
+	return spans
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestLimiter_Tracing(t *testing.T) {
+	t.Parallel()
+
+	exporter := &recordingExporter{}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	l := newInmemTestLimiter(t, clock.NewFake())
+	l.tracer = tp.Tracer(tracerName)
+	l.source = newTracedSource(newInmem(), l.tracer)
+
+	txnBuilder := newTestTransactionBuilder(t)
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP(tenZeroZeroTwo))
+	test.AssertNotError(t, err, "txn should be valid")
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	_, err = l.Spend(ctx, txn)
+	test.AssertNotError(t, err, "should not error")
+	parent.End()
+
+	spans := exporter.byName()
+	spend, ok := spans["ratelimits.Limiter/Spend"]
+	test.Assert(t, ok, "missing Spend span")
+	test.AssertEquals(t, spend.Parent().SpanID(), parent.SpanContext().SpanID())
+	allowed, ok := spanAttribute(spend, "ratelimits.allowed")
+	test.Assert(t, ok, "missing allowed attribute")
+	test.Assert(t, allowed.AsBool(), "should be allowed")
+	limits, ok := spanAttribute(spend, "ratelimits.limits")
+	test.Assert(t, ok, "missing limits attribute")
+	test.AssertDeepEquals(t, limits.AsStringSlice(), []string{NewRegistrationsPerIPAddress.String()})
+
+	for _, name := range []string{"ratelimits.source/BatchGet", "ratelimits.source/BatchSet"} {
+		span, ok := spans[name]
+		test.Assert(t, ok, "missing span "+name)
+		test.AssertEquals(t, span.Parent().SpanID(), spend.SpanContext().SpanID())
+	}
+
+	// A Check against a bucket which doesn't exist should not record an
+	// error on the source span.
+	exporter.reset()
+	txn, err = txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("10.0.0.3"))
+	test.AssertNotError(t, err, "txn should be valid")
+	_, err = l.Check(context.Background(), txn)
+	test.AssertNotError(t, err, "should not error")
+	spans = exporter.byName()
+	_, ok = spans["ratelimits.Limiter/Check"]
+	test.Assert(t, ok, "missing Check span")
+	get, ok := spans["ratelimits.source/Get"]
+	test.Assert(t, ok, "missing Get span")
+	test.AssertEquals(t, len(get.Events()), 0)
+	found, ok := spanAttribute(get, "ratelimits.found")
+	test.Assert(t, ok, "missing found attribute")
+	test.Assert(t, !found.AsBool(), "bucket should not be found")
+}
+
+func TestObserveLatency_Exemplar(t *testing.T) {
+	t.Parallel()
+	reg := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_latency",
+		Buckets: []float64{1},
+	})
+	reg.MustRegister(histogram)
+
+	// Without a span, no exemplar is attached.
+	observeLatency(context.Background(), histogram, 0.5)
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	observeLatency(ctx, histogram, 0.5)
+	span.End()
+
+	families, err := reg.Gather()
+	test.AssertNotError(t, err, "gathering metrics")
+	test.AssertEquals(t, len(families), 1)
+	h := families[0].GetMetric()[0].GetHistogram()
+	test.AssertEquals(t, h.GetSampleCount(), uint64(2))
+	exemplar := h.GetBucket()[0].GetExemplar()
+	test.Assert(t, exemplar != nil, "missing exemplar")
+	test.AssertEquals(t, exemplar.GetLabel()[0].GetName(), "trace_id")
+	test.AssertEquals(t, exemplar.GetLabel()[0].GetValue(), span.SpanContext().TraceID().String())
+}
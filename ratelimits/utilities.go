@@ -1,10 +1,13 @@
 package ratelimits
 
 import (
+	"fmt"
+	"net"
 	"strings"
 
 	"github.com/letsencrypt/boulder/core"
 	"github.com/weppos/publicsuffix-go/publicsuffix"
+	"golang.org/x/net/idna"
 )
 
 // joinWithColon joins the provided args with a colon.
@@ -31,3 +34,106 @@ func DomainsForRateLimiting(names []string) []string {
 	}
 	return core.UniqueLowerNames(domains)
 }
+
+// NormalizeDomain returns the form of the provided domain name used in bucket
+// keys: lowercase, with any internationalized labels converted to their ASCII
+// (punycode) form and without a trailing dot. A leading wildcard label is
+// preserved. An error is returned if the domain cannot be converted.
+func NormalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(domain, ".")
+	wildcard := strings.HasPrefix(domain, "*.")
+	ascii, err := idna.Lookup.ToASCII(strings.TrimPrefix(domain, "*."))
+	if err != nil {
+		return "", fmt.Errorf("normalizing domain %q: %w", domain, err)
+	}
+	if wildcard {
+		return "*." + ascii, nil
+	}
+	return ascii, nil
+}
+
+// NormalizeIPAddress returns the form of the provided IP address used in
+// bucket keys: dotted decimal for IPv4 addresses, including IPv4-mapped IPv6
+// addresses, and the RFC 5952 form for IPv6 addresses. An error is returned if
+// the provided string is not an IP address.
+func NormalizeIPAddress(ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", fmt.Errorf("invalid IP address, %q must be an IP address", ip)
+	}
+	return parsed.String(), nil
+}
+
+// NormalizeIPv6Range returns the form of an IPv6 range used in bucket keys:
+// the /48 CIDR, in RFC 5952 form, containing the provided IPv6 address or /48
+// CIDR. Any bits of the provided address beyond the first 48 are folded away,
+// so that every address in a range shares a bucket. An error is returned for
+// IPv4 addresses and for CIDRs with any other prefix length.
+func NormalizeIPv6Range(ipOrCIDR string) (string, error) {
+	ip := net.ParseIP(ipOrCIDR)
+	if ip == nil {
+		var ipNet *net.IPNet
+		var err error
+		ip, ipNet, err = net.ParseCIDR(ipOrCIDR)
+		if err != nil {
+			return "", fmt.Errorf("invalid IPv6 range, %q must be an IPv6 address or CIDR", ipOrCIDR)
+		}
+		ones, bits := ipNet.Mask.Size()
+		if ones != 48 || bits != 128 {
+			return "", fmt.Errorf("invalid IPv6 range, %q must be /48", ipOrCIDR)
+		}
+	}
+	if ip.To4() != nil {
+		return "", fmt.Errorf("invalid IPv6 range, %q must be an IPv6 address or CIDR", ipOrCIDR)
+	}
+	return ipv6Range(ip), nil
+}
+
+// ipv6Range returns the /48 CIDR containing the provided IPv6 address.
+func ipv6Range(ip net.IP) string {
+	ipMask := net.CIDRMask(48, 128)
+	ipNet := &net.IPNet{IP: ip.Mask(ipMask), Mask: ipMask}
+	return ipNet.String()
+}
+
+// normalizeIdForName returns the provided id, formatted as it would be in an
+// overrides file, with each of its components normalized for use in a bucket
+// key. Components which cannot be normalized are left unchanged, so that
+// validateIdForName can report them.
+func normalizeIdForName(name Name, id string) string {
+	normalize := func(f func(string) (string, error), s string) string {
+		normalized, err := f(s)
+		if err != nil {
+			return s
+		}
+		return normalized
+	}
+
+	switch name {
+	case NewRegistrationsPerIPAddress, RevocationsPerIPAddress, AccountUpdatesPerIPAddress:
+		return normalize(NormalizeIPAddress, id)
+
+	case NewRegistrationsPerIPv6Range:
+		return normalize(NormalizeIPv6Range, id)
+
+	case CertificatesPerDomain:
+		return normalize(NormalizeDomain, id)
+
+	case CertificatesPerDomainPerAccount:
+		regId, domain, ok := strings.Cut(id, ":")
+		if !ok {
+			return id
+		}
+		return joinWithColon(regId, normalize(NormalizeDomain, domain))
+
+	case CertificatesPerFQDNSet:
+		domains := strings.Split(id, ",")
+		for i, domain := range domains {
+			domains[i] = normalize(NormalizeDomain, domain)
+		}
+		return strings.Join(domains, ",")
+
+	default:
+		return id
+	}
+}
@@ -1,6 +1,7 @@
 package ratelimits
 
 import (
+	"net"
 	"testing"
 
 	"github.com/letsencrypt/boulder/test"
@@ -25,3 +26,81 @@ func TestDomainsForRateLimiting(t *testing.T) {
 	domains = DomainsForRateLimiting([]string{"github.io", "foo.github.io", "bar.github.io"})
 	test.AssertDeepEquals(t, domains, []string{"bar.github.io", "foo.github.io", "github.io"})
 }
+
+func TestNormalizeDomain(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"example.com", "example.com"},
+		{"WWW.Example.COM", "www.example.com"},
+		{"example.com.", "example.com"},
+		{"*.Example.com", "*.example.com"},
+		{"bücher.example", "xn--bcher-kva.example"},
+		{"XN--BCHER-KVA.example", "xn--bcher-kva.example"},
+	} {
+		got, err := NormalizeDomain(tc.in)
+		test.AssertNotError(t, err, tc.in)
+		test.AssertEquals(t, got, tc.want)
+	}
+
+	_, err := NormalizeDomain("under_score.example.com")
+	test.AssertError(t, err, "domain with an underscore should not normalize")
+}
+
+func TestNormalizeIPAddress(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"10.0.0.1", "10.0.0.1"},
+		{"::ffff:10.0.0.1", "10.0.0.1"},
+		{"2001:0DB8:0000:0000:0000:ff00:0042:8329", "2001:db8::ff00:42:8329"},
+		{"2001:db8:0:0:1:0:0:1", "2001:db8::1:0:0:1"},
+	} {
+		got, err := NormalizeIPAddress(tc.in)
+		test.AssertNotError(t, err, tc.in)
+		test.AssertEquals(t, got, tc.want)
+	}
+
+	_, err := NormalizeIPAddress("10.0.0.256")
+	test.AssertError(t, err, "invalid IP address should not normalize")
+}
+
+func TestNormalizeIPv6Range(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"2001:0db8:0000::/48", "2001:db8::/48"},
+		{"2001:db8:1:ffff::/48", "2001:db8:1::/48"},
+		{"2001:db8:1:2:3:4:5:6", "2001:db8:1::/48"},
+	} {
+		got, err := NormalizeIPv6Range(tc.in)
+		test.AssertNotError(t, err, tc.in)
+		test.AssertEquals(t, got, tc.want)
+	}
+
+	for _, in := range []string{"10.0.0.1", "10.0.0.0/8", "2001:db8::/64", "2001:db8::/32", "lol"} {
+		_, err := NormalizeIPv6Range(in)
+		test.AssertError(t, err, in)
+	}
+}
+
+func TestNormalizedBucketKeys(t *testing.T) {
+	// Ids which differ only in their formatting share a bucket.
+	key1, _, err := bucketKeysForId(NewRegistrationsPerIPv6Range, "2001:0db8:0000::/48")
+	test.AssertNotError(t, err, "should not error")
+	key2, err := BucketForIPv6Range(NewRegistrationsPerIPv6Range, net.ParseIP("2001:db8::1"))
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, key1, key2)
+
+	key1, err = BucketForRegistrationDomain(CertificatesPerDomainPerAccount, 1234, "Example.COM")
+	test.AssertNotError(t, err, "should not error")
+	key2, _, err = bucketKeysForId(CertificatesPerDomainPerAccount, "1234:example.com")
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, key1, key2)
+
+	key1, err = BucketForFQDNSet(CertificatesPerFQDNSet, []string{"Example.com", "example.NET."})
+	test.AssertNotError(t, err, "should not error")
+	key2, _, err = bucketKeysForId(CertificatesPerFQDNSet, "example.net,example.com")
+	test.AssertNotError(t, err, "should not error")
+	test.AssertEquals(t, key1, key2)
+}
@@ -0,0 +1,126 @@
+package ratelimits
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/letsencrypt/boulder/strictyaml"
+)
+
+const (
+	// vectorOpSpend and vectorOpRefund are the valid values of the Op field of
+	// a GCRAVector.
+	vectorOpSpend  = "spend"
+	vectorOpRefund = "refund"
+)
+
+// GCRAVectorResult is the expected result of a GCRAVector. All durations are
+// in nanoseconds and all times are in nanoseconds since the Unix epoch.
+type GCRAVectorResult struct {
+	Allowed   bool  `yaml:"allowed"`
+	Remaining int64 `yaml:"remaining"`
+	RetryIn   int64 `yaml:"retryIn"`
+	ResetIn   int64 `yaml:"resetIn"`
+	TAT       int64 `yaml:"tat"`
+}
+
+// GCRAVector is a single canonical input to GCRASpend or GCRARefund and the
+// result the reference implementation produces for it. Vectors are loaded with
+// LoadGCRAVectors, see testdata/gcra_vectors.yml for the canonical set. All
+// durations are in nanoseconds and all times are in nanoseconds since the Unix
+// epoch, so that implementations in other languages can reproduce them
+// exactly.
+type GCRAVector struct {
+	// Name uniquely identifies the vector.
+	Name string `yaml:"name"`
+
+	// Op is either "spend" or "refund".
+	Op string `yaml:"op"`
+
+	Burst  int64 `yaml:"burst"`
+	Count  int64 `yaml:"count"`
+	Period int64 `yaml:"period"`
+
+	Now  int64 `yaml:"now"`
+	TAT  int64 `yaml:"tat"`
+	Cost int64 `yaml:"cost"`
+
+	Expected GCRAVectorResult `yaml:"expected"`
+}
+
+// Params returns the limit parameters of the vector.
+func (v GCRAVector) Params() GCRAParams {
+	return GCRAParams{Burst: v.Burst, Count: v.Count, Period: time.Duration(v.Period)}
+}
+
+// Want returns the expected result of the vector as a GCRAResult.
+func (v GCRAVector) Want() GCRAResult {
+	return GCRAResult{
+		Allowed:   v.Expected.Allowed,
+		Remaining: v.Expected.Remaining,
+		RetryIn:   time.Duration(v.Expected.RetryIn),
+		ResetIn:   time.Duration(v.Expected.ResetIn),
+		TAT:       time.Unix(0, v.Expected.TAT).UTC(),
+	}
+}
+
+// Evaluate returns the result of the vector's Op as computed by the reference
+// implementation, GCRASpend or GCRARefund.
+func (v GCRAVector) Evaluate() (GCRAResult, error) {
+	now := time.Unix(0, v.Now).UTC()
+	tat := time.Unix(0, v.TAT).UTC()
+	switch v.Op {
+	case vectorOpSpend:
+		return GCRASpend(v.Params(), now, tat, v.Cost)
+	case vectorOpRefund:
+		return GCRARefund(v.Params(), now, tat, v.Cost)
+	default:
+		return GCRAResult{}, fmt.Errorf("invalid op %q, must be %q or %q", v.Op, vectorOpSpend, vectorOpRefund)
+	}
+}
+
+// Verify returns an error if the provided result, e.g. as produced by an
+// alternate implementation of the GCRA, differs from the expected result of the
+// vector.
+func (v GCRAVector) Verify(got GCRAResult) error {
+	want := v.Want()
+	if got.Allowed != want.Allowed ||
+		got.Remaining != want.Remaining ||
+		got.RetryIn != want.RetryIn ||
+		got.ResetIn != want.ResetIn ||
+		!got.TAT.Equal(want.TAT) {
+		return fmt.Errorf("vector %q: got %+v, expected %+v", v.Name, got, want)
+	}
+	return nil
+}
+
+// LoadGCRAVectors loads the GCRAVectors in the YAML file at path. An error is
+// returned if any vector is unnamed, shares a name with another vector, or has
+// an invalid Op.
+func LoadGCRAVectors(path string) ([]GCRAVector, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var vectors []GCRAVector
+	err = strictyaml.Unmarshal(data, &vectors)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(vectors))
+	for i, v := range vectors {
+		if v.Name == "" {
+			return nil, fmt.Errorf("vector %d has no name", i)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("duplicate vector %q", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Op != vectorOpSpend && v.Op != vectorOpRefund {
+			return nil, fmt.Errorf("vector %q has invalid op %q, must be %q or %q", v.Name, v.Op, vectorOpSpend, vectorOpRefund)
+		}
+	}
+	return vectors, nil
+}
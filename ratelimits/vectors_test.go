@@ -0,0 +1,40 @@
+package ratelimits
+
+import (
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestGCRAVectors(t *testing.T) {
+	vectors, err := LoadGCRAVectors("testdata/gcra_vectors.yml")
+	test.AssertNotError(t, err, "should not error")
+	test.Assert(t, len(vectors) > 0, "should load vectors")
+
+	for _, v := range vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			got, err := v.Evaluate()
+			test.AssertNotError(t, err, "should not error")
+			test.AssertNotError(t, v.Verify(got), "reference should match vector")
+
+			err = CheckGCRAInvariants(v.Params(), time.Unix(0, v.Now), time.Unix(0, v.TAT), v.Cost)
+			test.AssertNotError(t, err, "vector should satisfy invariants")
+		})
+	}
+
+	// A result differing in any field fails verification.
+	v := vectors[0]
+	got := v.Want()
+	got.Remaining++
+	test.AssertError(t, v.Verify(got), "mismatched result should fail verification")
+}
+
+func TestLoadGCRAVectorsErrors(t *testing.T) {
+	_, err := LoadGCRAVectors("testdata/busted_gcra_vectors_invalid_op.yml")
+	test.AssertError(t, err, "invalid op should error")
+	test.AssertContains(t, err.Error(), "invalid op")
+
+	_, err = LoadGCRAVectors("testdata/does_not_exist.yml")
+	test.AssertError(t, err, "missing file should error")
+}
@@ -1,7 +1,9 @@
 package sa
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"time"
 
@@ -43,6 +45,47 @@ type DbSettings struct {
 	// If d < 0, connections are not closed due to a connection's idle
 	// time.
 	ConnMaxIdleTime time.Duration
+
+	// QueryTimeouts are the default timeouts, by class of operation, applied
+	// to operations whose context has no deadline.
+	QueryTimeouts boulderDB.QueryTimeouts
+
+	// FailbackInterval is how often failover hosts preferred to the current
+	// host are probed. If zero, they are not probed.
+	FailbackInterval time.Duration
+
+	// TLSConfig, if set, is the name of a TLS config registered with
+	// mysql.RegisterTLSConfig, which is used to connect to every host.
+	TLSConfig string
+
+	// TransactionIsolation, if set, is the isolation level of each session,
+	// e.g. READ-COMMITTED, unless the DSN sets transaction_isolation.
+	TransactionIsolation string
+	// QueryComments causes statements to be commented with the name of the
+	// component and the trace which executed them.
+	QueryComments bool
+
+	// MaxTransactionDuration, if non-zero, bounds how long each transaction
+	// may remain open before it is rolled back.
+	MaxTransactionDuration time.Duration
+
+	// MaxExecutionTimeHints causes SELECTs to carry a MAX_EXECUTION_TIME hint
+	// for the time remaining until their deadline.
+	MaxExecutionTimeHints bool
+
+	// CircuitBreakerThreshold, if non-zero, is the number of consecutive
+	// failures to reach the database after which operations fail fast, and
+	// CircuitBreakerCooldown how often one is then attempted.
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+
+	// MaxConcurrentQueries, if non-zero, limits the number of statements,
+	// counting each transaction as one, executed at once.
+	MaxConcurrentQueries int
+
+	// ServerRequirements, if any are set, are verified once the database is
+	// connected to.
+	ServerRequirements boulderDB.ServerRequirements
 }
 
 // InitWrappedDb constructs a wrapped borp mapping object with the provided
@@ -60,14 +103,64 @@ func InitWrappedDb(config cmd.DBConfig, scope prometheus.Registerer, logger blog
 		MaxIdleConns:    config.MaxIdleConns,
 		ConnMaxLifetime: config.ConnMaxLifetime.Duration,
 		ConnMaxIdleTime: config.ConnMaxIdleTime.Duration,
+		QueryTimeouts: boulderDB.QueryTimeouts{
+			Read:        config.ReadTimeout.Duration,
+			Write:       config.WriteTimeout.Duration,
+			LongRunning: config.LongRunningTimeout.Duration,
+		},
+		FailbackInterval:        config.FailbackInterval.Duration,
+		TransactionIsolation:    config.TransactionIsolation,
+		QueryComments:           config.QueryComments,
+		MaxTransactionDuration:  config.MaxTransactionDuration.Duration,
+		MaxExecutionTimeHints:   config.MaxExecutionTimeHints,
+		CircuitBreakerThreshold: config.CircuitBreakerThreshold,
+		CircuitBreakerCooldown:  config.CircuitBreakerCooldown.Duration,
+		MaxConcurrentQueries:    config.MaxConcurrentQueries,
+		ServerRequirements: boulderDB.ServerRequirements{
+			SQLModes:   config.RequiredSQLModes,
+			Charset:    config.RequiredCharset,
+			MinVersion: config.MinServerVersion,
+		},
+	}
+
+	if config.TLS != nil {
+		// Load requires a Registerer for its certificate expiry metrics.
+		tlsScope := scope
+		if tlsScope == nil {
+			tlsScope = prometheus.NewRegistry()
+		}
+		tlsConfig, err := config.TLS.Load(tlsScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load DB TLS config: %s", err)
+		}
+		// The driver looks up TLS configs by name, so name it after the
+		// connect file, which is unique to each database.
+		settings.TLSConfig = "boulder-" + config.DBConnectFile
+		err = mysql.RegisterTLSConfig(settings.TLSConfig, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	mysqlConfig, err := mysql.ParseDSN(url)
+	mysqlConfig, err := boulderDB.ParseDSN(url)
 	if err != nil {
 		return nil, err
 	}
 
-	dbMap, err := newDbMapFromMySQLConfig(mysqlConfig, settings, scope, logger)
+	failoverURLs, err := config.FailoverURLs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load failover DBConnect URLs: %s", err)
+	}
+	var failoverConfigs []*mysql.Config
+	for _, url := range failoverURLs {
+		failoverConfig, err := boulderDB.ParseDSN(url)
+		if err != nil {
+			return nil, err
+		}
+		failoverConfigs = append(failoverConfigs, failoverConfig)
+	}
+
+	dbMap, err := newDbMapFromMySQLConfig(mysqlConfig, failoverConfigs, settings, scope, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -89,12 +182,12 @@ func DBMapForTestWithLog(dbConnect string, log blog.Logger) (*boulderDB.WrappedM
 	var err error
 	var config *mysql.Config
 
-	config, err = mysql.ParseDSN(dbConnect)
+	config, err = boulderDB.ParseDSN(dbConnect)
 	if err != nil {
 		return nil, err
 	}
 
-	return newDbMapFromMySQLConfig(config, DbSettings{}, nil, log)
+	return newDbMapFromMySQLConfig(config, nil, DbSettings{}, nil, log)
 }
 
 // sqlOpen is used in the tests to check that the arguments are properly
@@ -103,6 +196,24 @@ var sqlOpen = func(dbType, connectStr string) (*sql.DB, error) {
 	return sql.Open(dbType, connectStr)
 }
 
+// sqlOpenFailover is used in place of sqlOpen when failover hosts are
+// configured. It is also replaceable for testing.
+var sqlOpenFailover = func(configs []*mysql.Config, failbackInterval time.Duration) (*sql.DB, error) {
+	var connectors []driver.Connector
+	for _, config := range configs {
+		connector, err := mysql.NewConnector(config)
+		if err != nil {
+			return nil, err
+		}
+		connectors = append(connectors, connector)
+	}
+	connector, err := boulderDB.NewFailoverConnector(connectors, failbackInterval)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
 // setMaxOpenConns is also used so that we can replace it for testing.
 var setMaxOpenConns = func(db *sql.DB, maxOpenConns int) {
 	if maxOpenConns != 0 {
@@ -140,15 +251,26 @@ var setConnMaxIdleTime = func(db *sql.DB, connMaxIdleTime time.Duration) {
 //   - wraps the connection in a borp.DbMap so we can use the handy Get/Insert methods borp provides
 //   - wraps that in a db.WrappedMap to get more useful error messages
 //
+// If failover is non-empty, new connections are made to the hosts it
+// configures, in order, when those before them are unreachable.
 // If logger is non-nil, it will receive debug log messages from borp.
 // If scope is non-nil, it will be used to register Prometheus metrics.
-func newDbMapFromMySQLConfig(config *mysql.Config, settings DbSettings, scope prometheus.Registerer, logger blog.Logger) (*boulderDB.WrappedMap, error) {
-	err := adjustMySQLConfig(config)
-	if err != nil {
-		return nil, err
+func newDbMapFromMySQLConfig(config *mysql.Config, failover []*mysql.Config, settings DbSettings, scope prometheus.Registerer, logger blog.Logger) (*boulderDB.WrappedMap, error) {
+	for _, conf := range append([]*mysql.Config{config}, failover...) {
+		applyDbSettings(conf, settings)
+		err := adjustMySQLConfig(conf)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	db, err := sqlOpen("mysql", config.FormatDSN())
+	var db *sql.DB
+	var err error
+	if len(failover) == 0 {
+		db, err = sqlOpen("mysql", config.FormatDSN())
+	} else {
+		db, err = sqlOpenFailover(append([]*mysql.Config{config}, failover...), settings.FailbackInterval)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -175,7 +297,67 @@ func newDbMapFromMySQLConfig(config *mysql.Config, settings DbSettings, scope pr
 	}
 
 	initTables(dbmap)
-	return boulderDB.NewWrappedMap(dbmap), nil
+
+	// Label the query metrics like the connection pool metrics, so that
+	// multiple databases can be registered with the same scope.
+	var queryScope prometheus.Registerer
+	if scope != nil {
+		queryScope = prometheus.WrapRegistererWith(prometheus.Labels{"address": config.Addr, "user": config.User}, scope)
+	}
+	wrappedMap, err := boulderDB.NewInstrumentedWrappedMap(dbmap, settings.QueryTimeouts, queryScope)
+	if err != nil {
+		return nil, err
+	}
+	if settings.QueryComments {
+		wrappedMap.SetQueryComments(core.Command())
+	}
+	wrappedMap.SetMaxTransactionDuration(settings.MaxTransactionDuration)
+	if settings.MaxExecutionTimeHints {
+		wrappedMap.SetMaxExecutionTimeHints()
+	}
+	if settings.CircuitBreakerThreshold > 0 {
+		cooldown := settings.CircuitBreakerCooldown
+		if cooldown == 0 {
+			cooldown = 5 * time.Second
+		}
+		err = wrappedMap.SetCircuitBreaker(settings.CircuitBreakerThreshold, cooldown, queryScope)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if settings.MaxConcurrentQueries > 0 {
+		err = wrappedMap.SetConcurrencyLimit(settings.MaxConcurrentQueries, queryScope)
+		if err != nil {
+			return nil, err
+		}
+	}
+	reqs := settings.ServerRequirements
+	if len(reqs.SQLModes) > 0 || reqs.Charset != "" || reqs.MinVersion != "" {
+		// With failover hosts, only the host currently connected to is
+		// verified.
+		err = wrappedMap.VerifyServer(context.Background(), reqs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return wrappedMap, nil
+}
+
+// applyDbSettings sets the connection parameters configured by settings, which
+// are validated along with the rest by adjustMySQLConfig.
+func applyDbSettings(conf *mysql.Config, settings DbSettings) {
+	if settings.TLSConfig != "" {
+		conf.TLSConfig = settings.TLSConfig
+	}
+	if settings.TransactionIsolation != "" {
+		if conf.Params == nil {
+			conf.Params = make(map[string]string)
+		}
+		_, ok := conf.Params["transaction_isolation"]
+		if !ok {
+			conf.Params["transaction_isolation"] = fmt.Sprintf("'%s'", settings.TransactionIsolation)
+		}
+	}
 }
 
 // adjustMySQLConfig sets certain flags that we want on every connection.
@@ -284,6 +466,8 @@ func initTables(dbMap *borp.DbMap) {
 	dbMap.AddTable(incidentSerialModel{})
 	dbMap.AddTableWithName(crlShardModel{}, "crlShards").SetKeys(true, "ID")
 	dbMap.AddTableWithName(revokedCertModel{}, "revokedCertificates").SetKeys(true, "ID")
+	dbMap.AddTableWithName(pausedModel{}, "paused").SetKeys(false, "RegistrationID", "IdentifierValue", "IdentifierType")
+	dbMap.AddTableWithName(overrideRequestModel{}, "overrideRequests").SetKeys(true, "ID")
 
 	// Read-only maps used for selecting subsets of columns.
 	dbMap.AddTableWithName(CertStatusMetadata{}, "certificateStatus")
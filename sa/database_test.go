@@ -130,6 +130,58 @@ func TestNewDbMap(t *testing.T) {
 
 }
 
+func TestNewDbMapFailover(t *testing.T) {
+	const primaryURL = "policy:password@tcp(primary:3306)/boulder_policy_integration"
+	const secondaryURL = "policy:password@tcp(secondary:3306)/boulder_policy_integration"
+	oldSQLOpenFailover := sqlOpenFailover
+	defer func() {
+		sqlOpenFailover = oldSQLOpenFailover
+	}()
+	sqlOpenFailover = func(configs []*mysql.Config, failbackInterval time.Duration) (*sql.DB, error) {
+		test.AssertEquals(t, len(configs), 2)
+		test.AssertEquals(t, configs[0].Addr, "primary:3306")
+		test.AssertEquals(t, configs[1].Addr, "secondary:3306")
+		for _, config := range configs {
+			test.Assert(t, config.ParseTime, "failover config should be adjusted")
+			test.AssertEquals(t, config.Params["sql_mode"], "'STRICT_ALL_TABLES'")
+		}
+		test.AssertEquals(t, failbackInterval, time.Minute)
+		return nil, errExpected
+	}
+
+	primary, err := mysql.ParseDSN(primaryURL)
+	test.AssertNotError(t, err, "parsing primary DSN")
+	secondary, err := mysql.ParseDSN(secondaryURL)
+	test.AssertNotError(t, err, "parsing secondary DSN")
+	_, err = newDbMapFromMySQLConfig(primary, []*mysql.Config{secondary}, DbSettings{FailbackInterval: time.Minute}, nil, nil)
+	test.AssertEquals(t, err, errExpected)
+}
+
+func TestNewDbMapSettings(t *testing.T) {
+	const mysqlConnectURL = "policy:password@tcp(boulder-proxysql:6033)/boulder_policy_integration"
+	const expected = "policy:password@tcp(boulder-proxysql:6033)/boulder_policy_integration?clientFoundRows=true&parseTime=true&tls=skip-verify&sql_mode=%27STRICT_ALL_TABLES%27&transaction_isolation=%27READ-COMMITTED%27"
+	oldSQLOpen := sqlOpen
+	defer func() {
+		sqlOpen = oldSQLOpen
+	}()
+	sqlOpen = func(dbType, connectString string) (*sql.DB, error) {
+		test.AssertEquals(t, connectString, expected)
+		return nil, errExpected
+	}
+
+	conf, err := mysql.ParseDSN(mysqlConnectURL)
+	test.AssertNotError(t, err, "parsing DSN")
+	settings := DbSettings{TLSConfig: "skip-verify", TransactionIsolation: "READ-COMMITTED"}
+	_, err = newDbMapFromMySQLConfig(conf, nil, settings, nil, nil)
+	test.AssertEquals(t, err, errExpected)
+
+	// An isolation level set by the DSN is not overridden.
+	conf, err = mysql.ParseDSN(mysqlConnectURL + "?transaction_isolation=%27SERIALIZABLE%27")
+	test.AssertNotError(t, err, "parsing DSN")
+	applyDbSettings(conf, settings)
+	test.AssertEquals(t, conf.Params["transaction_isolation"], "'SERIALIZABLE'")
+}
+
 func TestStrictness(t *testing.T) {
 	dbMap, err := DBMapForTest(vars.DBConnSA)
 	if err != nil {
@@ -0,0 +1,24 @@
+-- +migrate Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `overrideRequests` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `limitName` varchar(255) NOT NULL,
+  `bucketId` varchar(255) NOT NULL,
+  `burst` bigint(20) NOT NULL,
+  `count` bigint(20) NOT NULL,
+  `periodNS` bigint(20) NOT NULL,
+  `state` varchar(16) NOT NULL,
+  `requester` varchar(255) NOT NULL,
+  `comment` text NOT NULL,
+  `createdAt` datetime NOT NULL,
+  `updatedAt` datetime NOT NULL,
+  `expiresAt` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `state_expiresAt_idx` (`state`, `expiresAt`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +migrate Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `overrideRequests`;
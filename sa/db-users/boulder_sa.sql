@@ -12,6 +12,7 @@ CREATE USER IF NOT EXISTS 'mailer'@'localhost';
 CREATE USER IF NOT EXISTS 'cert_checker'@'localhost';
 CREATE USER IF NOT EXISTS 'test_setup'@'localhost';
 CREATE USER IF NOT EXISTS 'badkeyrevoker'@'localhost';
+CREATE USER IF NOT EXISTS 'ratelimits_tool'@'localhost';
 CREATE USER IF NOT EXISTS 'proxysql'@'localhost';
 
 -- Storage Authority
@@ -34,6 +35,8 @@ GRANT SELECT,INSERT,UPDATE ON newOrdersRL TO 'sa'@'localhost';
 GRANT SELECT ON incidents TO 'sa'@'localhost';
 GRANT SELECT,INSERT,UPDATE ON crlShards TO 'sa'@'localhost';
 GRANT SELECT,INSERT,UPDATE ON revokedCertificates TO 'sa'@'localhost';
+GRANT SELECT,INSERT,UPDATE ON overrideRequests TO 'sa'@'localhost';
+GRANT SELECT,INSERT,UPDATE ON paused TO 'sa'@'localhost';
 
 GRANT SELECT ON certificates TO 'sa_ro'@'localhost';
 GRANT SELECT ON certificateStatus TO 'sa_ro'@'localhost';
@@ -54,6 +57,8 @@ GRANT SELECT ON newOrdersRL TO 'sa_ro'@'localhost';
 GRANT SELECT ON incidents TO 'sa_ro'@'localhost';
 GRANT SELECT ON crlShards TO 'sa_ro'@'localhost';
 GRANT SELECT ON revokedCertificates TO 'sa_ro'@'localhost';
+GRANT SELECT ON overrideRequests TO 'sa_ro'@'localhost';
+GRANT SELECT ON paused TO 'sa_ro'@'localhost';
 
 -- OCSP Responder
 GRANT SELECT ON certificateStatus TO 'ocsp_resp'@'localhost';
@@ -82,6 +87,10 @@ GRANT SELECT ON certificateStatus TO 'badkeyrevoker'@'localhost';
 GRANT SELECT ON precertificates TO 'badkeyrevoker'@'localhost';
 GRANT SELECT ON registrations TO 'badkeyrevoker'@'localhost';
 
+-- Rate limits tool, backfilling from the legacy rate limit tables
+GRANT SELECT ON certificatesPerName TO 'ratelimits_tool'@'localhost';
+GRANT SELECT ON newOrdersRL TO 'ratelimits_tool'@'localhost';
+
 -- ProxySQL --
 GRANT ALL PRIVILEGES ON monitor TO 'proxysql'@'localhost';
 
@@ -65,6 +65,10 @@ func (dbc dbMetricsCollector) Collect(ch chan<- prometheus.Metric) {
 // stats and DbSettings into Prometheus metrics on the fly. The exported metrics
 // all start with `db_`. The underlying data comes from sql.DBStats:
 // https://pkg.go.dev/database/sql#DBStats
+//
+// Because the stats are read when the registry is scraped, rather than sampled
+// on a ticker, they are never stale. Connection pool exhaustion shows up as
+// db_inuse reaching db_max_open_connections and db_wait_count increasing.
 func initDBMetrics(db *sql.DB, stats prometheus.Registerer, dbSettings DbSettings, address string, user string) error {
 	// Create a dbMetricsCollector and register it
 	dbc := dbMetricsCollector{db: db, dbSettings: dbSettings}
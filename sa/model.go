@@ -25,6 +25,7 @@ import (
 	"github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/identifier"
 	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/ratelimits"
 	"github.com/letsencrypt/boulder/revocation"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 )
@@ -64,15 +65,15 @@ const regFields = "id, jwk, jwk_sha256, contact, agreement, initialIP, createdAt
 // there are multiple email addresses present, it does not modify other ones. If the email
 // address is not present, it does not modify the registration and will return a nil error.
 func ClearEmail(ctx context.Context, dbMap db.DatabaseMap, regID int64, email string) error {
-	_, overallError := db.WithTransaction(ctx, dbMap, func(tx db.Executor) (interface{}, error) {
+	overallError := db.WithTransaction(ctx, dbMap, func(tx db.Executor) error {
 		curr, err := selectRegistration(ctx, tx, "id", regID)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		currPb, err := registrationModelToPb(curr)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// newContacts will be a copy of all emails in currPb.Contact _except_ the one to be removed
@@ -84,16 +85,17 @@ func ClearEmail(ctx context.Context, dbMap db.DatabaseMap, regID int64, email st
 		}
 
 		if slices.Equal(currPb.Contact, newContacts) {
-			return nil, nil
+			return nil
 		}
 
 		currPb.Contact = newContacts
 		newModel, err := registrationPbToModel(currPb)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		return tx.Update(ctx, newModel)
+		_, err = tx.Update(ctx, newModel)
+		return err
 	})
 	if overallError != nil {
 		return overallError
@@ -440,15 +442,17 @@ func modelToOrder(om *orderModel) (*corepb.Order, error) {
 }
 
 var challTypeToUint = map[string]uint8{
-	"http-01":     0,
-	"dns-01":      1,
-	"tls-alpn-01": 2,
+	"http-01":        0,
+	"dns-01":         1,
+	"tls-alpn-01":    2,
+	"dns-account-01": 3,
 }
 
 var uintToChallType = map[uint8]string{
 	0: "http-01",
 	1: "dns-01",
 	2: "tls-alpn-01",
+	3: "dns-account-01",
 }
 
 var identifierTypeToUint = map[string]uint8{
@@ -1188,3 +1192,56 @@ type revokedCertModel struct {
 	RevokedDate   time.Time         `db:"revokedDate"`
 	RevokedReason revocation.Reason `db:"revokedReason"`
 }
+
+// pausedModel represents one row in the paused table. A row whose UnpausedAt
+// is nil means that its account may not validate its identifier until the
+// account is unpaused.
+type pausedModel struct {
+	RegistrationID  int64      `db:"registrationID"`
+	IdentifierType  uint8      `db:"identifierType"`
+	IdentifierValue string     `db:"identifierValue"`
+	PausedAt        time.Time  `db:"pausedAt"`
+	UnpausedAt      *time.Time `db:"unpausedAt"`
+}
+
+// overrideRequestModel represents one row in the overrideRequests table.
+type overrideRequestModel struct {
+	ID        int64     `db:"id"`
+	LimitName string    `db:"limitName"`
+	BucketId  string    `db:"bucketId"`
+	Burst     int64     `db:"burst"`
+	Count     int64     `db:"count"`
+	PeriodNS  int64     `db:"periodNS"`
+	State     string    `db:"state"`
+	Requester string    `db:"requester"`
+	Comment   string    `db:"comment"`
+	CreatedAt time.Time `db:"createdAt"`
+	UpdatedAt time.Time `db:"updatedAt"`
+	ExpiresAt time.Time `db:"expiresAt"`
+}
+
+// overrideRequestModelToRatelimits converts the provided overrideRequestModel
+// to a ratelimits.OverrideRequest.
+// overrideRequestBucket identifies the bucket of an overrideRequestModel.
+type overrideRequestBucket struct {
+	ID        int64
+	LimitName string
+	BucketId  string
+}
+
+func overrideRequestModelToRatelimits(m overrideRequestModel) ratelimits.OverrideRequest {
+	return ratelimits.OverrideRequest{
+		ID:        m.ID,
+		LimitName: m.LimitName,
+		BucketId:  m.BucketId,
+		Burst:     m.Burst,
+		Count:     m.Count,
+		Period:    time.Duration(m.PeriodNS),
+		State:     ratelimits.OverrideRequestState(m.State),
+		Requester: m.Requester,
+		Comment:   m.Comment,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+		ExpiresAt: m.ExpiresAt,
+	}
+}
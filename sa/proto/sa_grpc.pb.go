@@ -53,6 +53,8 @@ type StorageAuthorityReadOnlyClient interface {
 	KeyBlocked(ctx context.Context, in *KeyBlockedRequest, opts ...grpc.CallOption) (*Exists, error)
 	PreviousCertificateExists(ctx context.Context, in *PreviousCertificateExistsRequest, opts ...grpc.CallOption) (*Exists, error)
 	SerialsForIncident(ctx context.Context, in *SerialsForIncidentRequest, opts ...grpc.CallOption) (StorageAuthorityReadOnly_SerialsForIncidentClient, error)
+	CheckIdentifiersPaused(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*Identifiers, error)
+	GetApprovedOverrideRequests(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (StorageAuthorityReadOnly_GetApprovedOverrideRequestsClient, error)
 }
 
 type storageAuthorityReadOnlyClient struct {
@@ -361,6 +363,47 @@ func (x *storageAuthorityReadOnlySerialsForIncidentClient) Recv() (*IncidentSeri
 	return m, nil
 }
 
+func (c *storageAuthorityReadOnlyClient) CheckIdentifiersPaused(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*Identifiers, error) {
+	out := new(Identifiers)
+	err := c.cc.Invoke(ctx, "/sa.StorageAuthorityReadOnly/CheckIdentifiersPaused", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityReadOnlyClient) GetApprovedOverrideRequests(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (StorageAuthorityReadOnly_GetApprovedOverrideRequestsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &StorageAuthorityReadOnly_ServiceDesc.Streams[2], "/sa.StorageAuthorityReadOnly/GetApprovedOverrideRequests", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storageAuthorityReadOnlyGetApprovedOverrideRequestsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type StorageAuthorityReadOnly_GetApprovedOverrideRequestsClient interface {
+	Recv() (*OverrideRequest, error)
+	grpc.ClientStream
+}
+
+type storageAuthorityReadOnlyGetApprovedOverrideRequestsClient struct {
+	grpc.ClientStream
+}
+
+func (x *storageAuthorityReadOnlyGetApprovedOverrideRequestsClient) Recv() (*OverrideRequest, error) {
+	m := new(OverrideRequest)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // StorageAuthorityReadOnlyServer is the server API for StorageAuthorityReadOnly service.
 // All implementations must embed UnimplementedStorageAuthorityReadOnlyServer
 // for forward compatibility
@@ -393,6 +436,8 @@ type StorageAuthorityReadOnlyServer interface {
 	KeyBlocked(context.Context, *KeyBlockedRequest) (*Exists, error)
 	PreviousCertificateExists(context.Context, *PreviousCertificateExistsRequest) (*Exists, error)
 	SerialsForIncident(*SerialsForIncidentRequest, StorageAuthorityReadOnly_SerialsForIncidentServer) error
+	CheckIdentifiersPaused(context.Context, *PauseRequest) (*Identifiers, error)
+	GetApprovedOverrideRequests(*emptypb.Empty, StorageAuthorityReadOnly_GetApprovedOverrideRequestsServer) error
 	mustEmbedUnimplementedStorageAuthorityReadOnlyServer()
 }
 
@@ -484,6 +529,12 @@ func (UnimplementedStorageAuthorityReadOnlyServer) PreviousCertificateExists(con
 func (UnimplementedStorageAuthorityReadOnlyServer) SerialsForIncident(*SerialsForIncidentRequest, StorageAuthorityReadOnly_SerialsForIncidentServer) error {
 	return status.Errorf(codes.Unimplemented, "method SerialsForIncident not implemented")
 }
+func (UnimplementedStorageAuthorityReadOnlyServer) CheckIdentifiersPaused(context.Context, *PauseRequest) (*Identifiers, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckIdentifiersPaused not implemented")
+}
+func (UnimplementedStorageAuthorityReadOnlyServer) GetApprovedOverrideRequests(*emptypb.Empty, StorageAuthorityReadOnly_GetApprovedOverrideRequestsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetApprovedOverrideRequests not implemented")
+}
 func (UnimplementedStorageAuthorityReadOnlyServer) mustEmbedUnimplementedStorageAuthorityReadOnlyServer() {
 }
 
@@ -1008,6 +1059,45 @@ func (x *storageAuthorityReadOnlySerialsForIncidentServer) Send(m *IncidentSeria
 	return x.ServerStream.SendMsg(m)
 }
 
+func _StorageAuthorityReadOnly_CheckIdentifiersPaused_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityReadOnlyServer).CheckIdentifiersPaused(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthorityReadOnly/CheckIdentifiersPaused",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityReadOnlyServer).CheckIdentifiersPaused(ctx, req.(*PauseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthorityReadOnly_GetApprovedOverrideRequests_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StorageAuthorityReadOnlyServer).GetApprovedOverrideRequests(m, &storageAuthorityReadOnlyGetApprovedOverrideRequestsServer{stream})
+}
+
+type StorageAuthorityReadOnly_GetApprovedOverrideRequestsServer interface {
+	Send(*OverrideRequest) error
+	grpc.ServerStream
+}
+
+type storageAuthorityReadOnlyGetApprovedOverrideRequestsServer struct {
+	grpc.ServerStream
+}
+
+func (x *storageAuthorityReadOnlyGetApprovedOverrideRequestsServer) Send(m *OverrideRequest) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // StorageAuthorityReadOnly_ServiceDesc is the grpc.ServiceDesc for StorageAuthorityReadOnly service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -1119,6 +1209,10 @@ var StorageAuthorityReadOnly_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PreviousCertificateExists",
 			Handler:    _StorageAuthorityReadOnly_PreviousCertificateExists_Handler,
 		},
+		{
+			MethodName: "CheckIdentifiersPaused",
+			Handler:    _StorageAuthorityReadOnly_CheckIdentifiersPaused_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -1131,6 +1225,11 @@ var StorageAuthorityReadOnly_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _StorageAuthorityReadOnly_SerialsForIncident_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "GetApprovedOverrideRequests",
+			Handler:       _StorageAuthorityReadOnly_GetApprovedOverrideRequests_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "sa.proto",
 }
@@ -1168,6 +1267,8 @@ type StorageAuthorityClient interface {
 	KeyBlocked(ctx context.Context, in *KeyBlockedRequest, opts ...grpc.CallOption) (*Exists, error)
 	PreviousCertificateExists(ctx context.Context, in *PreviousCertificateExistsRequest, opts ...grpc.CallOption) (*Exists, error)
 	SerialsForIncident(ctx context.Context, in *SerialsForIncidentRequest, opts ...grpc.CallOption) (StorageAuthority_SerialsForIncidentClient, error)
+	CheckIdentifiersPaused(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*Identifiers, error)
+	GetApprovedOverrideRequests(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (StorageAuthority_GetApprovedOverrideRequestsClient, error)
 	// Adders
 	AddBlockedKey(ctx context.Context, in *AddBlockedKeyRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	AddCertificate(ctx context.Context, in *AddCertificateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
@@ -1187,6 +1288,10 @@ type StorageAuthorityClient interface {
 	UpdateRevokedCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	LeaseCRLShard(ctx context.Context, in *LeaseCRLShardRequest, opts ...grpc.CallOption) (*LeaseCRLShardResponse, error)
 	UpdateCRLShard(ctx context.Context, in *UpdateCRLShardRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	PauseIdentifiers(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseIdentifiersResponse, error)
+	UnpauseAccount(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*Count, error)
+	AddOverrideRequest(ctx context.Context, in *OverrideRequest, opts ...grpc.CallOption) (*OverrideRequest, error)
+	UpdateOverrideRequestState(ctx context.Context, in *UpdateOverrideRequestStateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type storageAuthorityClient struct {
@@ -1495,6 +1600,47 @@ func (x *storageAuthoritySerialsForIncidentClient) Recv() (*IncidentSerial, erro
 	return m, nil
 }
 
+func (c *storageAuthorityClient) CheckIdentifiersPaused(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*Identifiers, error) {
+	out := new(Identifiers)
+	err := c.cc.Invoke(ctx, "/sa.StorageAuthority/CheckIdentifiersPaused", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) GetApprovedOverrideRequests(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (StorageAuthority_GetApprovedOverrideRequestsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &StorageAuthority_ServiceDesc.Streams[2], "/sa.StorageAuthority/GetApprovedOverrideRequests", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storageAuthorityGetApprovedOverrideRequestsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type StorageAuthority_GetApprovedOverrideRequestsClient interface {
+	Recv() (*OverrideRequest, error)
+	grpc.ClientStream
+}
+
+type storageAuthorityGetApprovedOverrideRequestsClient struct {
+	grpc.ClientStream
+}
+
+func (x *storageAuthorityGetApprovedOverrideRequestsClient) Recv() (*OverrideRequest, error) {
+	m := new(OverrideRequest)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *storageAuthorityClient) AddBlockedKey(ctx context.Context, in *AddBlockedKeyRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, "/sa.StorageAuthority/AddBlockedKey", in, out, opts...)
@@ -1657,6 +1803,42 @@ func (c *storageAuthorityClient) UpdateCRLShard(ctx context.Context, in *UpdateC
 	return out, nil
 }
 
+func (c *storageAuthorityClient) PauseIdentifiers(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseIdentifiersResponse, error) {
+	out := new(PauseIdentifiersResponse)
+	err := c.cc.Invoke(ctx, "/sa.StorageAuthority/PauseIdentifiers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) UnpauseAccount(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := c.cc.Invoke(ctx, "/sa.StorageAuthority/UnpauseAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) AddOverrideRequest(ctx context.Context, in *OverrideRequest, opts ...grpc.CallOption) (*OverrideRequest, error) {
+	out := new(OverrideRequest)
+	err := c.cc.Invoke(ctx, "/sa.StorageAuthority/AddOverrideRequest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) UpdateOverrideRequestState(ctx context.Context, in *UpdateOverrideRequestStateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/sa.StorageAuthority/UpdateOverrideRequestState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StorageAuthorityServer is the server API for StorageAuthority service.
 // All implementations must embed UnimplementedStorageAuthorityServer
 // for forward compatibility
@@ -1690,6 +1872,8 @@ type StorageAuthorityServer interface {
 	KeyBlocked(context.Context, *KeyBlockedRequest) (*Exists, error)
 	PreviousCertificateExists(context.Context, *PreviousCertificateExistsRequest) (*Exists, error)
 	SerialsForIncident(*SerialsForIncidentRequest, StorageAuthority_SerialsForIncidentServer) error
+	CheckIdentifiersPaused(context.Context, *PauseRequest) (*Identifiers, error)
+	GetApprovedOverrideRequests(*emptypb.Empty, StorageAuthority_GetApprovedOverrideRequestsServer) error
 	// Adders
 	AddBlockedKey(context.Context, *AddBlockedKeyRequest) (*emptypb.Empty, error)
 	AddCertificate(context.Context, *AddCertificateRequest) (*emptypb.Empty, error)
@@ -1709,6 +1893,10 @@ type StorageAuthorityServer interface {
 	UpdateRevokedCertificate(context.Context, *RevokeCertificateRequest) (*emptypb.Empty, error)
 	LeaseCRLShard(context.Context, *LeaseCRLShardRequest) (*LeaseCRLShardResponse, error)
 	UpdateCRLShard(context.Context, *UpdateCRLShardRequest) (*emptypb.Empty, error)
+	PauseIdentifiers(context.Context, *PauseRequest) (*PauseIdentifiersResponse, error)
+	UnpauseAccount(context.Context, *RegistrationID) (*Count, error)
+	AddOverrideRequest(context.Context, *OverrideRequest) (*OverrideRequest, error)
+	UpdateOverrideRequestState(context.Context, *UpdateOverrideRequestStateRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedStorageAuthorityServer()
 }
 
@@ -1800,6 +1988,12 @@ func (UnimplementedStorageAuthorityServer) PreviousCertificateExists(context.Con
 func (UnimplementedStorageAuthorityServer) SerialsForIncident(*SerialsForIncidentRequest, StorageAuthority_SerialsForIncidentServer) error {
 	return status.Errorf(codes.Unimplemented, "method SerialsForIncident not implemented")
 }
+func (UnimplementedStorageAuthorityServer) CheckIdentifiersPaused(context.Context, *PauseRequest) (*Identifiers, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckIdentifiersPaused not implemented")
+}
+func (UnimplementedStorageAuthorityServer) GetApprovedOverrideRequests(*emptypb.Empty, StorageAuthority_GetApprovedOverrideRequestsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetApprovedOverrideRequests not implemented")
+}
 func (UnimplementedStorageAuthorityServer) AddBlockedKey(context.Context, *AddBlockedKeyRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddBlockedKey not implemented")
 }
@@ -1854,6 +2048,18 @@ func (UnimplementedStorageAuthorityServer) LeaseCRLShard(context.Context, *Lease
 func (UnimplementedStorageAuthorityServer) UpdateCRLShard(context.Context, *UpdateCRLShardRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateCRLShard not implemented")
 }
+func (UnimplementedStorageAuthorityServer) PauseIdentifiers(context.Context, *PauseRequest) (*PauseIdentifiersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseIdentifiers not implemented")
+}
+func (UnimplementedStorageAuthorityServer) UnpauseAccount(context.Context, *RegistrationID) (*Count, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnpauseAccount not implemented")
+}
+func (UnimplementedStorageAuthorityServer) AddOverrideRequest(context.Context, *OverrideRequest) (*OverrideRequest, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddOverrideRequest not implemented")
+}
+func (UnimplementedStorageAuthorityServer) UpdateOverrideRequestState(context.Context, *UpdateOverrideRequestStateRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateOverrideRequestState not implemented")
+}
 func (UnimplementedStorageAuthorityServer) mustEmbedUnimplementedStorageAuthorityServer() {}
 
 // UnsafeStorageAuthorityServer may be embedded to opt out of forward compatibility for this service.
@@ -2377,6 +2583,45 @@ func (x *storageAuthoritySerialsForIncidentServer) Send(m *IncidentSerial) error
 	return x.ServerStream.SendMsg(m)
 }
 
+func _StorageAuthority_CheckIdentifiersPaused_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).CheckIdentifiersPaused(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/CheckIdentifiersPaused",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).CheckIdentifiersPaused(ctx, req.(*PauseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_GetApprovedOverrideRequests_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StorageAuthorityServer).GetApprovedOverrideRequests(m, &storageAuthorityGetApprovedOverrideRequestsServer{stream})
+}
+
+type StorageAuthority_GetApprovedOverrideRequestsServer interface {
+	Send(*OverrideRequest) error
+	grpc.ServerStream
+}
+
+type storageAuthorityGetApprovedOverrideRequestsServer struct {
+	grpc.ServerStream
+}
+
+func (x *storageAuthorityGetApprovedOverrideRequestsServer) Send(m *OverrideRequest) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _StorageAuthority_AddBlockedKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AddBlockedKeyRequest)
 	if err := dec(in); err != nil {
@@ -2701,6 +2946,78 @@ func _StorageAuthority_UpdateCRLShard_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_PauseIdentifiers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).PauseIdentifiers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/PauseIdentifiers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).PauseIdentifiers(ctx, req.(*PauseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_UnpauseAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegistrationID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).UnpauseAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/UnpauseAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).UnpauseAccount(ctx, req.(*RegistrationID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_AddOverrideRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).AddOverrideRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/AddOverrideRequest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).AddOverrideRequest(ctx, req.(*OverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_UpdateOverrideRequestState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateOverrideRequestStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).UpdateOverrideRequestState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/UpdateOverrideRequestState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).UpdateOverrideRequestState(ctx, req.(*UpdateOverrideRequestStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // StorageAuthority_ServiceDesc is the grpc.ServiceDesc for StorageAuthority service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -2812,6 +3129,10 @@ var StorageAuthority_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PreviousCertificateExists",
 			Handler:    _StorageAuthority_PreviousCertificateExists_Handler,
 		},
+		{
+			MethodName: "CheckIdentifiersPaused",
+			Handler:    _StorageAuthority_CheckIdentifiersPaused_Handler,
+		},
 		{
 			MethodName: "AddBlockedKey",
 			Handler:    _StorageAuthority_AddBlockedKey_Handler,
@@ -2884,6 +3205,22 @@ var StorageAuthority_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateCRLShard",
 			Handler:    _StorageAuthority_UpdateCRLShard_Handler,
 		},
+		{
+			MethodName: "PauseIdentifiers",
+			Handler:    _StorageAuthority_PauseIdentifiers_Handler,
+		},
+		{
+			MethodName: "UnpauseAccount",
+			Handler:    _StorageAuthority_UnpauseAccount_Handler,
+		},
+		{
+			MethodName: "AddOverrideRequest",
+			Handler:    _StorageAuthority_AddOverrideRequest_Handler,
+		},
+		{
+			MethodName: "UpdateOverrideRequestState",
+			Handler:    _StorageAuthority_UpdateOverrideRequestState_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -2896,6 +3233,11 @@ var StorageAuthority_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _StorageAuthority_SerialsForIncident_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "GetApprovedOverrideRequests",
+			Handler:       _StorageAuthority_GetApprovedOverrideRequests_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "sa.proto",
 }
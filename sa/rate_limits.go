@@ -144,3 +144,53 @@ func countNewOrders(ctx context.Context, dbMap db.Selector, req *sapb.CountOrder
 	}
 	return &sapb.Count{Count: total}, nil
 }
+
+// LegacyRateLimitCount is the total count, over some window, recorded for a
+// single eTLD+1 or account in one of the legacy rate limit tables. It is
+// returned by LegacyCertificatesPerNameCounts and LegacyNewOrdersCounts.
+type LegacyRateLimitCount struct {
+	// ID is an eTLD+1 for certificatesPerName, or a registration ID for
+	// newOrdersRL, formatted as it would be in a rate limit overrides file.
+	ID    string
+	Count int64
+}
+
+// LegacyCertificatesPerNameCounts returns the total count of certificates
+// recorded in the certificatesPerName table for each eTLD+1 since the provided
+// time. It is used to backfill the key-value rate limiter's
+// CertificatesPerDomain buckets before the table is retired.
+func LegacyCertificatesPerNameCounts(ctx context.Context, dbMap db.Selector, since time.Time) ([]LegacyRateLimitCount, error) {
+	var counts []LegacyRateLimitCount
+	_, err := dbMap.Select(
+		ctx,
+		&counts,
+		`SELECT eTLDPlusOne AS ID, SUM(count) AS Count FROM certificatesPerName
+		 WHERE time > :since
+		 GROUP BY eTLDPlusOne`,
+		map[string]interface{}{"since": since},
+	)
+	if err != nil && !db.IsNoRows(err) {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// LegacyNewOrdersCounts returns the total count of orders recorded in the
+// newOrdersRL table for each registration ID since the provided time. It is
+// used to backfill the key-value rate limiter's NewOrdersPerAccount buckets
+// before the table is retired.
+func LegacyNewOrdersCounts(ctx context.Context, dbMap db.Selector, since time.Time) ([]LegacyRateLimitCount, error) {
+	var counts []LegacyRateLimitCount
+	_, err := dbMap.Select(
+		ctx,
+		&counts,
+		`SELECT CAST(regID AS CHAR) AS ID, SUM(count) AS Count FROM newOrdersRL
+		 WHERE time > :since
+		 GROUP BY regID`,
+		map[string]interface{}{"since": since},
+	)
+	if err != nil && !db.IsNoRows(err) {
+		return nil, err
+	}
+	return counts, nil
+}
@@ -138,3 +138,38 @@ func TestNewOrdersRateLimitTable(t *testing.T) {
 	test.AssertNotError(t, err, "countNewOrders failed")
 	test.AssertEquals(t, count.Count, int64(45))
 }
+
+func TestLegacyRateLimitCounts(t *testing.T) {
+	sa, _, cleanUp := initSA(t)
+	defer cleanUp()
+
+	start := time.Now().Truncate(time.Hour)
+	tx, err := sa.dbMap.BeginTx(ctx)
+	test.AssertNotError(t, err, "failed to open tx")
+	for i := 0; i < 3; i++ {
+		at := start.Add(-time.Duration(i) * time.Hour)
+		err = sa.addCertificatesPerName(ctx, tx, []string{"www.example.com", "example.net"}, at)
+		test.AssertNotError(t, err, "addCertificatesPerName failed")
+		err = addNewOrdersRateLimit(ctx, tx, 1, at)
+		test.AssertNotError(t, err, "addNewOrdersRateLimit failed")
+	}
+	test.AssertNotError(t, tx.Commit(), "failed to commit tx")
+
+	// Only the two most recent hours are counted.
+	since := start.Add(-90 * time.Minute)
+	certs, err := LegacyCertificatesPerNameCounts(ctx, sa.dbMap, since)
+	test.AssertNotError(t, err, "LegacyCertificatesPerNameCounts failed")
+	test.AssertEquals(t, len(certs), 2)
+	for _, c := range certs {
+		test.Assert(t, c.ID == "example.com" || c.ID == "example.net", fmt.Sprintf("unexpected eTLD+1 %q", c.ID))
+		test.AssertEquals(t, c.Count, int64(2))
+	}
+
+	orders, err := LegacyNewOrdersCounts(ctx, sa.dbMap, since)
+	test.AssertNotError(t, err, "LegacyNewOrdersCounts failed")
+	test.AssertDeepEquals(t, orders, []LegacyRateLimitCount{{ID: "1", Count: 2}})
+
+	orders, err = LegacyNewOrdersCounts(ctx, sa.dbMap, start.Add(time.Hour))
+	test.AssertNotError(t, err, "LegacyNewOrdersCounts failed")
+	test.AssertEquals(t, len(orders), 0)
+}
@@ -19,8 +19,10 @@ import (
 	corepb "github.com/letsencrypt/boulder/core/proto"
 	"github.com/letsencrypt/boulder/db"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/ratelimits"
 	"github.com/letsencrypt/boulder/revocation"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 )
@@ -223,22 +225,22 @@ func (ssa *SQLStorageAuthority) AddPrecertificate(ctx context.Context, req *sapb
 		Expires:        parsed.NotAfter,
 	}
 
-	_, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		// Select to see if precert exists
 		var row struct {
 			Count int64
 		}
 		err := tx.SelectOne(ctx, &row, "SELECT COUNT(*) as count FROM precertificates WHERE serial=?", serialHex)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if row.Count > 0 {
-			return nil, berrors.DuplicateError("cannot add a duplicate cert")
+			return berrors.DuplicateError("cannot add a duplicate cert")
 		}
 
 		err = tx.Insert(ctx, preCertModel)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		status := core.OCSPStatusGood
@@ -258,7 +260,7 @@ func (ssa *SQLStorageAuthority) AddPrecertificate(ctx context.Context, req *sapb
 		}
 		err = ssa.dbMap.Insert(ctx, cs)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// NOTE(@cpu): When we collect up names to check if an FQDN set exists (e.g.
@@ -272,20 +274,20 @@ func (ssa *SQLStorageAuthority) AddPrecertificate(ctx context.Context, req *sapb
 			tx.SelectOne,
 			parsed.DNSNames)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		err = addIssuedNames(ctx, tx, parsed, isRenewal)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		err = addKeyHash(ctx, tx, parsed)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		return nil, nil
+		return nil
 	})
 	if overallError != nil {
 		return nil, overallError
@@ -317,23 +319,24 @@ func (ssa *SQLStorageAuthority) AddCertificate(ctx context.Context, req *sapb.Ad
 		Expires:        parsedCertificate.NotAfter,
 	}
 
-	isRenewalRaw, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	var isRenewal bool
+	overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		// Select to see if cert exists
 		var row struct {
 			Count int64
 		}
 		err := tx.SelectOne(ctx, &row, "SELECT COUNT(*) as count FROM certificates WHERE serial=?", serial)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if row.Count > 0 {
-			return nil, berrors.DuplicateError("cannot add a duplicate cert")
+			return berrors.DuplicateError("cannot add a duplicate cert")
 		}
 
 		// Save the final certificate
 		err = tx.Insert(ctx, cert)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// NOTE(@cpu): When we collect up names to check if an FQDN set exists (e.g.
@@ -342,43 +345,28 @@ func (ssa *SQLStorageAuthority) AddCertificate(ctx context.Context, req *sapb.Ad
 		// if a certificate we issued were to have a Subj. CN not present as a SAN it
 		// would be a misissuance and miscalculating whether the cert is a renewal or
 		// not for the purpose of rate limiting is the least of our troubles.
-		isRenewal, err := ssa.checkFQDNSetExists(
+		isRenewal, err = ssa.checkFQDNSetExists(
 			ctx,
 			tx.SelectOne,
 			parsedCertificate.DNSNames)
-		if err != nil {
-			return nil, err
-		}
-
-		return isRenewal, err
+		return err
 	})
 	if overallError != nil {
 		return nil, overallError
 	}
 
-	// Recast the interface{} return from db.WithTransaction as a bool, returning
-	// an error if we can't.
-	var isRenewal bool
-	if boolVal, ok := isRenewalRaw.(bool); !ok {
-		return nil, fmt.Errorf(
-			"AddCertificate db.WithTransaction returned %T out var, expected bool",
-			isRenewalRaw)
-	} else {
-		isRenewal = boolVal
-	}
-
 	// In a separate transaction perform the work required to update tables used
 	// for rate limits. Since the effects of failing these writes is slight
 	// miscalculation of rate limits we choose to not fail the AddCertificate
 	// operation if the rate limit update transaction fails.
-	_, rlTransactionErr := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	rlTransactionErr := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		// Add to the rate limit table, but only for new certificates. Renewals
 		// don't count against the certificatesPerName limit.
-		if !isRenewal {
+		if !isRenewal && !features.Get().DisableLegacyLimitWrites {
 			timeToTheHour := parsedCertificate.NotBefore.Round(time.Hour)
 			err := ssa.addCertificatesPerName(ctx, tx, parsedCertificate.DNSNames, timeToTheHour)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 
@@ -393,10 +381,10 @@ func (ssa *SQLStorageAuthority) AddCertificate(ctx context.Context, req *sapb.Ad
 			parsedCertificate.NotAfter,
 		)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		return nil, nil
+		return nil
 	})
 	// If the ratelimit transaction failed increment a stat and log a warning
 	// but don't return an error from AddCertificate.
@@ -456,21 +444,22 @@ func (ssa *SQLStorageAuthority) NewOrderAndAuthzs(ctx context.Context, req *sapb
 		return nil, errIncompleteRequest
 	}
 
-	output, err := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	var newOrder *corepb.Order
+	err := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		// First, insert all of the new authorizations and record their IDs.
 		newAuthzIDs := make([]int64, 0)
 		if len(req.NewAuthzs) != 0 {
 			inserter, err := db.NewMultiInserter("authz2", strings.Split(authzFields, ", "), "id")
 			if err != nil {
-				return nil, err
+				return err
 			}
 			for _, authz := range req.NewAuthzs {
 				if authz.Status != string(core.StatusPending) {
-					return nil, berrors.InternalServerError("authorization must be pending")
+					return berrors.InternalServerError("authorization must be pending")
 				}
 				am, err := authzPBToModel(authz)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				err = inserter.Add([]interface{}{
 					am.ID,
@@ -487,12 +476,12 @@ func (ssa *SQLStorageAuthority) NewOrderAndAuthzs(ctx context.Context, req *sapb
 					nil,
 				})
 				if err != nil {
-					return nil, err
+					return err
 				}
 			}
 			newAuthzIDs, err = inserter.Insert(ctx, tx)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 
@@ -504,51 +493,51 @@ func (ssa *SQLStorageAuthority) NewOrderAndAuthzs(ctx context.Context, req *sapb
 		}
 		err := tx.Insert(ctx, order)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// Third, insert all of the orderToAuthz relations.
 		inserter, err := db.NewMultiInserter("orderToAuthz2", []string{"orderID", "authzID"}, "")
 		if err != nil {
-			return nil, err
+			return err
 		}
 		for _, id := range req.NewOrder.V2Authorizations {
 			err = inserter.Add([]interface{}{order.ID, id})
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 		for _, id := range newAuthzIDs {
 			err = inserter.Add([]interface{}{order.ID, id})
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 		_, err = inserter.Insert(ctx, tx)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// Fourth, insert all of the requestedNames.
 		inserter, err = db.NewMultiInserter("requestedNames", []string{"orderID", "reversedName"}, "")
 		if err != nil {
-			return nil, err
+			return err
 		}
 		for _, name := range req.NewOrder.Names {
 			err = inserter.Add([]interface{}{order.ID, ReverseName(name)})
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 		_, err = inserter.Insert(ctx, tx)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// Fifth, insert the FQDNSet entry for the order.
 		err = addOrderFQDNSet(ctx, tx, req.NewOrder.Names, order.ID, order.RegistrationID, order.Expires)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// Finally, build the overall Order PB.
@@ -570,28 +559,26 @@ func (ssa *SQLStorageAuthority) NewOrderAndAuthzs(ctx context.Context, req *sapb
 		// all valid authorizations the order may be "born" in a ready status.
 		status, err := statusForOrder(ctx, tx, res, ssa.clk.Now())
 		if err != nil {
-			return nil, err
+			return err
 		}
 		res.Status = status
 
-		return res, nil
+		newOrder = res
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	order, ok := output.(*corepb.Order)
-	if !ok {
-		return nil, fmt.Errorf("casting error in NewOrderAndAuthzs")
-	}
-
-	// Increment the order creation count
-	err = addNewOrdersRateLimit(ctx, ssa.dbMap, req.NewOrder.RegistrationID, ssa.clk.Now().Truncate(time.Minute))
-	if err != nil {
-		return nil, err
+	if !features.Get().DisableLegacyLimitWrites {
+		// Increment the order creation count
+		err = addNewOrdersRateLimit(ctx, ssa.dbMap, req.NewOrder.RegistrationID, ssa.clk.Now().Truncate(time.Minute))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return order, nil
+	return newOrder, nil
 }
 
 // SetOrderProcessing updates an order from pending status to processing
@@ -601,7 +588,7 @@ func (ssa *SQLStorageAuthority) SetOrderProcessing(ctx context.Context, req *sap
 	if req.Id == 0 {
 		return nil, errIncompleteRequest
 	}
-	_, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		result, err := tx.ExecContext(ctx, `
 		UPDATE orders
 		SET beganProcessing = ?
@@ -611,15 +598,15 @@ func (ssa *SQLStorageAuthority) SetOrderProcessing(ctx context.Context, req *sap
 			req.Id,
 			false)
 		if err != nil {
-			return nil, berrors.InternalServerError("error updating order to beganProcessing status")
+			return berrors.InternalServerError("error updating order to beganProcessing status")
 		}
 
 		n, err := result.RowsAffected()
 		if err != nil || n == 0 {
-			return nil, berrors.OrderNotReadyError("Order was already processing. This may indicate your client finalized the same order multiple times, possibly due to a client bug.")
+			return berrors.OrderNotReadyError("Order was already processing. This may indicate your client finalized the same order multiple times, possibly due to a client bug.")
 		}
 
-		return nil, nil
+		return nil
 	})
 	if overallError != nil {
 		return nil, overallError
@@ -632,13 +619,13 @@ func (ssa *SQLStorageAuthority) SetOrderError(ctx context.Context, req *sapb.Set
 	if req.Id == 0 || req.Error == nil {
 		return nil, errIncompleteRequest
 	}
-	_, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		om, err := orderToModel(&corepb.Order{
 			Id:    req.Id,
 			Error: req.Error,
 		})
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		result, err := tx.ExecContext(ctx, `
@@ -648,15 +635,15 @@ func (ssa *SQLStorageAuthority) SetOrderError(ctx context.Context, req *sapb.Set
 			om.Error,
 			om.ID)
 		if err != nil {
-			return nil, berrors.InternalServerError("error updating order error field")
+			return berrors.InternalServerError("error updating order error field")
 		}
 
 		n, err := result.RowsAffected()
 		if err != nil || n == 0 {
-			return nil, berrors.InternalServerError("no order updated with new error field")
+			return berrors.InternalServerError("no order updated with new error field")
 		}
 
-		return nil, nil
+		return nil
 	})
 	if overallError != nil {
 		return nil, overallError
@@ -672,7 +659,7 @@ func (ssa *SQLStorageAuthority) FinalizeOrder(ctx context.Context, req *sapb.Fin
 	if req.Id == 0 || req.CertificateSerial == "" {
 		return nil, errIncompleteRequest
 	}
-	_, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		result, err := tx.ExecContext(ctx, `
 		UPDATE orders
 		SET certificateSerial = ?
@@ -681,22 +668,22 @@ func (ssa *SQLStorageAuthority) FinalizeOrder(ctx context.Context, req *sapb.Fin
 			req.CertificateSerial,
 			req.Id)
 		if err != nil {
-			return nil, berrors.InternalServerError("error updating order for finalization")
+			return berrors.InternalServerError("error updating order for finalization")
 		}
 
 		n, err := result.RowsAffected()
 		if err != nil || n == 0 {
-			return nil, berrors.InternalServerError("no order updated for finalization")
+			return berrors.InternalServerError("no order updated for finalization")
 		}
 
 		// Delete the orderFQDNSet row for the order now that it has been finalized.
 		// We use this table for order reuse and should not reuse a finalized order.
 		err = deleteOrderFQDNSet(ctx, tx, req.Id)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		return nil, nil
+		return nil
 	})
 	if overallError != nil {
 		return nil, overallError
@@ -836,7 +823,7 @@ func (ssa *SQLStorageAuthority) RevokeCertificate(ctx context.Context, req *sapb
 		return nil, errIncompleteRequest
 	}
 
-	_, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		revokedDate := req.Date.AsTime()
 
 		res, err := tx.ExecContext(ctx,
@@ -854,24 +841,24 @@ func (ssa *SQLStorageAuthority) RevokeCertificate(ctx context.Context, req *sapb
 			string(core.OCSPStatusRevoked),
 		)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		rows, err := res.RowsAffected()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if rows == 0 {
-			return nil, berrors.AlreadyRevokedError("no certificate with serial %s and status other than %s", req.Serial, string(core.OCSPStatusRevoked))
+			return berrors.AlreadyRevokedError("no certificate with serial %s and status other than %s", req.Serial, string(core.OCSPStatusRevoked))
 		}
 
 		if req.ShardIdx != 0 {
 			err = addRevokedCertificate(ctx, tx, req, revokedDate)
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 
-		return nil, nil
+		return nil
 	})
 	if overallError != nil {
 		return nil, overallError
@@ -893,7 +880,7 @@ func (ssa *SQLStorageAuthority) UpdateRevokedCertificate(ctx context.Context, re
 		return nil, fmt.Errorf("cannot update revocation for any reason other than keyCompromise (1); got: %d", req.Reason)
 	}
 
-	_, overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	overallError := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		thisUpdate := req.Date.AsTime()
 		revokedDate := req.Backdate.AsTime()
 
@@ -910,16 +897,16 @@ func (ssa *SQLStorageAuthority) UpdateRevokedCertificate(ctx context.Context, re
 			revokedDate,
 		)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		rows, err := res.RowsAffected()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if rows == 0 {
 			// InternalServerError because we expected this certificate status to exist,
 			// to already be revoked for a different reason, and to have a matching date.
-			return nil, berrors.InternalServerError("no certificate with serial %s and revoked reason other than keyCompromise", req.Serial)
+			return berrors.InternalServerError("no certificate with serial %s and revoked reason other than keyCompromise", req.Serial)
 		}
 
 		// Only update the revokedCertificates table if the revocation request
@@ -942,21 +929,21 @@ func (ssa *SQLStorageAuthority) UpdateRevokedCertificate(ctx context.Context, re
 				// RevokeCertificateRequest messages.
 				err = addRevokedCertificate(ctx, tx, req, revokedDate)
 				if err != nil {
-					return nil, err
+					return err
 				}
-				return nil, nil
+				return nil
 			} else if err != nil {
-				return nil, fmt.Errorf("retrieving revoked certificate row: %w", err)
+				return fmt.Errorf("retrieving revoked certificate row: %w", err)
 			}
 
 			rcm.RevokedReason = revocation.Reason(ocsp.KeyCompromise)
 			_, err = tx.Update(ctx, &rcm)
 			if err != nil {
-				return nil, fmt.Errorf("updating revoked certificate row: %w", err)
+				return fmt.Errorf("updating revoked certificate row: %w", err)
 			}
 		}
 
-		return nil, nil
+		return nil
 	})
 	if overallError != nil {
 		return nil, overallError
@@ -1039,7 +1026,8 @@ func (ssa *SQLStorageAuthority) LeaseCRLShard(ctx context.Context, req *sapb.Lea
 // leased or are previously-unknown indices are considered older than any other
 // shard. It returns an error if all shards for the issuer are already leased.
 func (ssa *SQLStorageAuthority) leaseOldestCRLShard(ctx context.Context, req *sapb.LeaseCRLShardRequest) (*sapb.LeaseCRLShardResponse, error) {
-	shardIdx, err := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	var shardIdx int
+	err := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		var shards []*crlShardModel
 		_, err := tx.Select(
 			ctx,
@@ -1051,11 +1039,10 @@ func (ssa *SQLStorageAuthority) leaseOldestCRLShard(ctx context.Context, req *sa
 			req.IssuerNameID, req.MinShardIdx, req.MaxShardIdx,
 		)
 		if err != nil {
-			return -1, fmt.Errorf("selecting candidate shards: %w", err)
+			return fmt.Errorf("selecting candidate shards: %w", err)
 		}
 
 		// Determine which shard index we want to lease.
-		var shardIdx int
 		var needToInsert bool
 		if len(shards) < (int(req.MaxShardIdx + 1 - req.MinShardIdx)) {
 			// Some expected shards are missing (i.e. never-before-produced), so we
@@ -1087,7 +1074,7 @@ func (ssa *SQLStorageAuthority) leaseOldestCRLShard(ctx context.Context, req *sa
 				}
 			}
 			if oldest == nil {
-				return -1, fmt.Errorf("issuer %d has no unleased shards in range %d-%d", req.IssuerNameID, req.MinShardIdx, req.MaxShardIdx)
+				return fmt.Errorf("issuer %d has no unleased shards in range %d-%d", req.IssuerNameID, req.MinShardIdx, req.MaxShardIdx)
 			}
 			shardIdx = oldest.Idx
 			needToInsert = false
@@ -1102,7 +1089,7 @@ func (ssa *SQLStorageAuthority) leaseOldestCRLShard(ctx context.Context, req *sa
 				req.Until.AsTime(),
 			)
 			if err != nil {
-				return -1, fmt.Errorf("inserting selected shard: %w", err)
+				return fmt.Errorf("inserting selected shard: %w", err)
 			}
 		} else {
 			_, err = tx.ExecContext(ctx,
@@ -1116,11 +1103,11 @@ func (ssa *SQLStorageAuthority) leaseOldestCRLShard(ctx context.Context, req *sa
 				shardIdx,
 			)
 			if err != nil {
-				return -1, fmt.Errorf("updating selected shard: %w", err)
+				return fmt.Errorf("updating selected shard: %w", err)
 			}
 		}
 
-		return shardIdx, err
+		return err
 	})
 	if err != nil {
 		return nil, fmt.Errorf("leasing oldest shard: %w", err)
@@ -1128,7 +1115,7 @@ func (ssa *SQLStorageAuthority) leaseOldestCRLShard(ctx context.Context, req *sa
 
 	return &sapb.LeaseCRLShardResponse{
 		IssuerNameID: req.IssuerNameID,
-		ShardIdx:     int64(shardIdx.(int)),
+		ShardIdx:     int64(shardIdx),
 	}, nil
 }
 
@@ -1140,7 +1127,7 @@ func (ssa *SQLStorageAuthority) leaseSpecificCRLShard(ctx context.Context, req *
 		return nil, fmt.Errorf("request must identify a single shard index: %d != %d", req.MinShardIdx, req.MaxShardIdx)
 	}
 
-	_, err := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	err := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		needToInsert := false
 		var shardModel crlShardModel
 		err := tx.SelectOne(ctx,
@@ -1156,9 +1143,9 @@ func (ssa *SQLStorageAuthority) leaseSpecificCRLShard(ctx context.Context, req *
 		if db.IsNoRows(err) {
 			needToInsert = true
 		} else if err != nil {
-			return nil, fmt.Errorf("selecting requested shard: %w", err)
+			return fmt.Errorf("selecting requested shard: %w", err)
 		} else if shardModel.LeasedUntil.After(ssa.clk.Now()) {
-			return nil, fmt.Errorf("shard %d for issuer %d already leased", req.MinShardIdx, req.IssuerNameID)
+			return fmt.Errorf("shard %d for issuer %d already leased", req.MinShardIdx, req.IssuerNameID)
 		}
 
 		if needToInsert {
@@ -1170,7 +1157,7 @@ func (ssa *SQLStorageAuthority) leaseSpecificCRLShard(ctx context.Context, req *
 				req.Until.AsTime(),
 			)
 			if err != nil {
-				return nil, fmt.Errorf("inserting selected shard: %w", err)
+				return fmt.Errorf("inserting selected shard: %w", err)
 			}
 		} else {
 			_, err = tx.ExecContext(ctx,
@@ -1184,11 +1171,11 @@ func (ssa *SQLStorageAuthority) leaseSpecificCRLShard(ctx context.Context, req *
 				req.MinShardIdx,
 			)
 			if err != nil {
-				return nil, fmt.Errorf("updating selected shard: %w", err)
+				return fmt.Errorf("updating selected shard: %w", err)
 			}
 		}
 
-		return nil, nil
+		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("leasing specific shard: %w", err)
@@ -1221,7 +1208,7 @@ func (ssa *SQLStorageAuthority) UpdateCRLShard(ctx context.Context, req *sapb.Up
 		nextUpdate = &nut
 	}
 
-	_, err := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) (interface{}, error) {
+	err := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
 		res, err := tx.ExecContext(ctx,
 			`UPDATE crlShards
 				SET thisUpdate = ?, nextUpdate = ?, leasedUntil = ?
@@ -1237,24 +1224,255 @@ func (ssa *SQLStorageAuthority) UpdateCRLShard(ctx context.Context, req *sapb.Up
 			req.ThisUpdate.AsTime(),
 		)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		rowsAffected, err := res.RowsAffected()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if rowsAffected == 0 {
-			return nil, fmt.Errorf("unable to update shard %d for issuer %d", req.ShardIdx, req.IssuerNameID)
+			return fmt.Errorf("unable to update shard %d for issuer %d", req.ShardIdx, req.IssuerNameID)
 		}
 		if rowsAffected != 1 {
-			return nil, errors.New("update affected unexpected number of rows")
+			return errors.New("update affected unexpected number of rows")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// PauseIdentifiers pauses each of the provided identifiers for the provided
+// account, so that the RA will refuse to validate them until the account is
+// unpaused. Identifiers which are already paused are left as they are. It
+// returns the number of identifiers newly paused, and the number which had
+// been paused, and then unpaused, before.
+func (ssa *SQLStorageAuthority) PauseIdentifiers(ctx context.Context, req *sapb.PauseRequest) (*sapb.PauseIdentifiersResponse, error) {
+	if core.IsAnyNilOrZero(req.RegistrationID) || len(req.Identifiers) == 0 {
+		return nil, errIncompleteRequest
+	}
+	for _, ident := range req.Identifiers {
+		if ident == nil || core.IsAnyNilOrZero(ident.Type, ident.Value) {
+			return nil, errIncompleteRequest
+		}
+		_, ok := identifierTypeToUint[ident.Type]
+		if !ok {
+			return nil, fmt.Errorf("unsupported identifier type %q", ident.Type)
+		}
+	}
+
+	resp := &sapb.PauseIdentifiersResponse{}
+	err := db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
+		// Reset the counts, in case the transaction is retried.
+		resp.Paused, resp.Repaused = 0, 0
+		now := ssa.clk.Now()
+		for _, ident := range req.Identifiers {
+			var entry pausedModel
+			err := tx.SelectOne(ctx, &entry, `
+				SELECT * FROM paused
+				WHERE registrationID = ? AND identifierType = ? AND identifierValue = ?`,
+				req.RegistrationID,
+				identifierTypeToUint[ident.Type],
+				ident.Value,
+			)
+			if db.IsNoRows(err) {
+				err = tx.Insert(ctx, &pausedModel{
+					RegistrationID:  req.RegistrationID,
+					IdentifierType:  identifierTypeToUint[ident.Type],
+					IdentifierValue: ident.Value,
+					PausedAt:        now,
+				})
+				if err != nil {
+					return err
+				}
+				resp.Paused++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if entry.UnpausedAt == nil {
+				// Already paused.
+				continue
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				UPDATE paused
+				SET pausedAt = ?, unpausedAt = NULL
+				WHERE registrationID = ? AND identifierType = ? AND identifierValue = ?`,
+				now,
+				entry.RegistrationID,
+				entry.IdentifierType,
+				entry.IdentifierValue,
+			)
+			if err != nil {
+				return err
+			}
+			resp.Repaused++
 		}
-		return nil, nil
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
+	return resp, nil
+}
+
+// UnpauseAccount unpauses every identifier paused for the provided account,
+// returning how many there were.
+func (ssa *SQLStorageAuthority) UnpauseAccount(ctx context.Context, req *sapb.RegistrationID) (*sapb.Count, error) {
+	if core.IsAnyNilOrZero(req.Id) {
+		return nil, errIncompleteRequest
+	}
+
+	res, err := ssa.dbMap.ExecContext(ctx, `
+		UPDATE paused
+		SET unpausedAt = ?
+		WHERE registrationID = ? AND unpausedAt IS NULL`,
+		ssa.clk.Now(),
+		req.Id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	return &sapb.Count{Count: rows}, nil
+}
+
+// AddOverrideRequest validates the provided override request and stores it, in
+// the pending state. The id, state, createdAt, and updatedAt fields of the
+// request are ignored. It returns the stored request.
+func (ssa *SQLStorageAuthority) AddOverrideRequest(ctx context.Context, req *sapb.OverrideRequest) (*sapb.OverrideRequest, error) {
+	if core.IsAnyNilOrZero(req.LimitName, req.BucketId, req.Period, req.Requester, req.ExpiresAt) {
+		return nil, errIncompleteRequest
+	}
+	or := ratelimits.OverrideRequestFromPB(req)
+	err := ratelimits.ValidateOverrideRequest(or)
+	if err != nil {
+		return nil, berrors.MalformedError("invalid override request: %s", err)
+	}
+
+	now := ssa.clk.Now()
+	model := &overrideRequestModel{
+		LimitName: or.LimitName,
+		BucketId:  or.BucketId,
+		Burst:     or.Burst,
+		Count:     or.Count,
+		PeriodNS:  or.Period.Nanoseconds(),
+		State:     string(ratelimits.OverrideRequestPending),
+		Requester: or.Requester,
+		Comment:   or.Comment,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: or.ExpiresAt,
+	}
+	err = ssa.dbMap.Insert(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	return ratelimits.OverrideRequestToPB(overrideRequestModelToRatelimits(*model)), nil
+}
+
+// UpdateOverrideRequestState moves the override request with the provided id
+// from the from state to the to state. It returns a berrors.Malformed error if
+// the transition is not permitted, a berrors.NotFound error if no request with
+// the provided id is currently in the from state, and a berrors.Duplicate error
+// if the request is being approved but another approved, unexpired request is
+// for the same bucket.
+func (ssa *SQLStorageAuthority) UpdateOverrideRequestState(ctx context.Context, req *sapb.UpdateOverrideRequestStateRequest) (*emptypb.Empty, error) {
+	if core.IsAnyNilOrZero(req.Id, req.From, req.To) {
+		return nil, errIncompleteRequest
+	}
+	err := ratelimits.ValidateOverrideRequestTransition(ratelimits.OverrideRequestState(req.From), ratelimits.OverrideRequestState(req.To))
+	if err != nil {
+		return nil, berrors.MalformedError("%s", err)
+	}
 
+	now := ssa.clk.Now()
+	err = db.WithTransaction(ctx, ssa.dbMap, func(tx db.Executor) error {
+		if ratelimits.OverrideRequestState(req.To) == ratelimits.OverrideRequestApproved {
+			err := checkOverrideRequestConflict(ctx, tx, req.Id, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			UPDATE overrideRequests
+			SET state = ?, updatedAt = ?
+			WHERE id = ? AND state = ?`,
+			req.To,
+			now,
+			req.Id,
+			req.From,
+		)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows != 1 {
+			return berrors.NotFoundError("no override request with id %d in state %s", req.Id, req.From)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &emptypb.Empty{}, nil
 }
+
+// checkOverrideRequestConflict returns a berrors.Duplicate error if any other
+// approved, unexpired override request is for the same bucket as the override
+// request with the provided id, once their bucket ids are normalized. Two such
+// requests can't be loaded from the same overrides file. The approved requests
+// for the limit are locked until the transaction ends, so that concurrent
+// approvals can't both succeed.
+func checkOverrideRequestConflict(ctx context.Context, tx db.Executor, id int64, now time.Time) error {
+	var toApprove overrideRequestBucket
+	err := tx.SelectOne(ctx, &toApprove, "SELECT id, limitName, bucketId FROM overrideRequests WHERE id = ?", id)
+	if err != nil {
+		if db.IsNoRows(err) {
+			return berrors.NotFoundError("no override request with id %d", id)
+		}
+		return err
+	}
+	key, err := ratelimits.OverrideRequestKey(ratelimits.OverrideRequest{LimitName: toApprove.LimitName, BucketId: toApprove.BucketId})
+	if err != nil {
+		return berrors.MalformedError("invalid override request %d: %s", id, err)
+	}
+
+	var approved []overrideRequestBucket
+	_, err = tx.Select(ctx, &approved, `
+		SELECT id, limitName, bucketId FROM overrideRequests
+		WHERE limitName = ? AND state = ? AND expiresAt > ? AND id != ?
+		FOR UPDATE`,
+		toApprove.LimitName,
+		string(ratelimits.OverrideRequestApproved),
+		now,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	for _, other := range approved {
+		otherKey, err := ratelimits.OverrideRequestKey(ratelimits.OverrideRequest{LimitName: other.LimitName, BucketId: other.BucketId})
+		if err != nil {
+			// It can't conflict with a request it doesn't share a key with.
+			continue
+		}
+		if otherKey == key {
+			return berrors.DuplicateError("override request %d is for the same bucket as approved override request %d", id, other.ID)
+		}
+	}
+	return nil
+}
@@ -34,6 +34,7 @@ import (
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/ratelimits"
 	"github.com/letsencrypt/boulder/revocation"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 	"github.com/letsencrypt/boulder/test"
@@ -1327,6 +1328,37 @@ func TestNewOrderAndAuthzs(t *testing.T) {
 	test.AssertDeepEquals(t, names, []string{"com.a", "com.b", "com.c", "com.d"})
 }
 
+func TestNewOrderAndAuthzsDisableLegacyLimitWrites(t *testing.T) {
+	sa, fc, cleanup := initSA(t)
+	defer cleanup()
+
+	features.Set(features.Config{DisableLegacyLimitWrites: true})
+	defer features.Reset()
+
+	reg := createWorkingRegistration(t, sa)
+	authzID := createPendingAuthorization(t, sa, "a.com", fc.Now().Add(time.Hour))
+	_, err := sa.NewOrderAndAuthzs(ctx, &sapb.NewOrderAndAuthzsRequest{
+		NewOrder: &sapb.NewOrderRequest{
+			RegistrationID:   reg.Id,
+			Expires:          timestamppb.New(fc.Now().Add(2 * time.Hour)),
+			Names:            []string{"a.com"},
+			V2Authorizations: []int64{authzID},
+		},
+	})
+	test.AssertNotError(t, err, "sa.NewOrderAndAuthzs failed")
+
+	// The order isn't recorded in the newOrdersRL table.
+	count, err := countNewOrders(ctx, sa.dbMap, &sapb.CountOrdersRequest{
+		AccountID: reg.Id,
+		Range: &sapb.Range{
+			Earliest: timestamppb.New(fc.Now().Add(-time.Hour)),
+			Latest:   timestamppb.New(fc.Now().Add(time.Hour)),
+		},
+	})
+	test.AssertNotError(t, err, "countNewOrders failed")
+	test.AssertEquals(t, count.Count, int64(0))
+}
+
 // TestNewOrderAndAuthzs_NonNilInnerOrder verifies that a nil
 // sapb.NewOrderAndAuthzsRequest NewOrder object returns an error.
 func TestNewOrderAndAuthzs_NonNilInnerOrder(t *testing.T) {
@@ -3999,3 +4031,210 @@ func TestUpdateCRLShard(t *testing.T) {
 	)
 	test.AssertError(t, err, "updating an unknown shard")
 }
+
+func TestPauseIdentifiers(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires paused database table")
+	}
+
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+
+	reg := createWorkingRegistration(t, sa)
+	example := &sapb.Identifier{Type: "dns", Value: "example.com"}
+	other := &sapb.Identifier{Type: "dns", Value: "example.net"}
+
+	checkPaused := func(want ...*sapb.Identifier) {
+		t.Helper()
+		resp, err := sa.CheckIdentifiersPaused(ctx, &sapb.PauseRequest{
+			RegistrationID: reg.Id,
+			Identifiers:    []*sapb.Identifier{example, other},
+		})
+		test.AssertNotError(t, err, "checking paused identifiers")
+		test.AssertEquals(t, len(resp.Identifiers), len(want))
+		for i := range want {
+			test.AssertEquals(t, resp.Identifiers[i].Value, want[i].Value)
+		}
+	}
+	checkPaused()
+
+	// Pausing an identifier only pauses it for its account.
+	resp, err := sa.PauseIdentifiers(ctx, &sapb.PauseRequest{
+		RegistrationID: reg.Id,
+		Identifiers:    []*sapb.Identifier{example},
+	})
+	test.AssertNotError(t, err, "pausing identifier")
+	test.AssertEquals(t, resp.Paused, int64(1))
+	test.AssertEquals(t, resp.Repaused, int64(0))
+	checkPaused(example)
+	otherAcct, err := sa.CheckIdentifiersPaused(ctx, &sapb.PauseRequest{
+		RegistrationID: reg.Id + 1,
+		Identifiers:    []*sapb.Identifier{example},
+	})
+	test.AssertNotError(t, err, "checking paused identifiers")
+	test.AssertEquals(t, len(otherAcct.Identifiers), 0)
+
+	// Pausing it again, along with another identifier, pauses only the other.
+	resp, err = sa.PauseIdentifiers(ctx, &sapb.PauseRequest{
+		RegistrationID: reg.Id,
+		Identifiers:    []*sapb.Identifier{example, other},
+	})
+	test.AssertNotError(t, err, "pausing identifiers")
+	test.AssertEquals(t, resp.Paused, int64(1))
+	test.AssertEquals(t, resp.Repaused, int64(0))
+	checkPaused(example, other)
+
+	// Unpausing the account unpauses every identifier, after which they can be
+	// paused again.
+	fc.Add(time.Hour)
+	count, err := sa.UnpauseAccount(ctx, &sapb.RegistrationID{Id: reg.Id})
+	test.AssertNotError(t, err, "unpausing account")
+	test.AssertEquals(t, count.Count, int64(2))
+	checkPaused()
+	resp, err = sa.PauseIdentifiers(ctx, &sapb.PauseRequest{
+		RegistrationID: reg.Id,
+		Identifiers:    []*sapb.Identifier{other},
+	})
+	test.AssertNotError(t, err, "repausing identifier")
+	test.AssertEquals(t, resp.Paused, int64(0))
+	test.AssertEquals(t, resp.Repaused, int64(1))
+	checkPaused(other)
+
+	_, err = sa.PauseIdentifiers(ctx, &sapb.PauseRequest{
+		RegistrationID: reg.Id,
+		Identifiers:    []*sapb.Identifier{{Type: "ip", Value: "10.0.0.1"}},
+	})
+	test.AssertError(t, err, "paused an unsupported identifier type")
+	_, err = sa.PauseIdentifiers(ctx, &sapb.PauseRequest{RegistrationID: reg.Id})
+	test.AssertErrorIs(t, err, errIncompleteRequest)
+}
+
+type mockApprovedOverrideRequestsServerStream struct {
+	grpc.ServerStream
+	sent []*sapb.OverrideRequest
+}
+
+func (s *mockApprovedOverrideRequestsServerStream) Send(req *sapb.OverrideRequest) error {
+	s.sent = append(s.sent, req)
+	return nil
+}
+
+func (s *mockApprovedOverrideRequestsServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestOverrideRequests(t *testing.T) {
+	if os.Getenv("BOULDER_CONFIG_DIR") != "test/config-next" {
+		t.Skip("Test requires overrideRequests database table")
+	}
+
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+
+	newReq := func(bucketId string) *sapb.OverrideRequest {
+		return &sapb.OverrideRequest{
+			LimitName: ratelimits.NewOrdersPerAccount.String(),
+			BucketId:  bucketId,
+			Burst:     600,
+			Count:     600,
+			Period:    durationpb.New(3 * time.Hour),
+			Requester: "subscriber@example.com",
+			Comment:   "large hosting provider",
+			ExpiresAt: timestamppb.New(fc.Now().Add(90 * 24 * time.Hour)),
+		}
+	}
+	approved := func() []*sapb.OverrideRequest {
+		t.Helper()
+		stream := &mockApprovedOverrideRequestsServerStream{}
+		err := sa.GetApprovedOverrideRequests(&emptypb.Empty{}, stream)
+		test.AssertNotError(t, err, "getting approved override requests")
+		return stream.sent
+	}
+
+	first, err := sa.AddOverrideRequest(ctx, newReq("12345678"))
+	test.AssertNotError(t, err, "adding override request")
+	test.AssertEquals(t, first.State, string(ratelimits.OverrideRequestPending))
+	test.Assert(t, first.CreatedAt.AsTime().Equal(fc.Now()), "CreatedAt should be the current time")
+	second, err := sa.AddOverrideRequest(ctx, newReq("87654321"))
+	test.AssertNotError(t, err, "adding override request")
+	test.AssertEquals(t, len(approved()), 0)
+
+	invalid := newReq("lol")
+	_, err = sa.AddOverrideRequest(ctx, invalid)
+	test.AssertErrorIs(t, err, berrors.Malformed)
+	_, err = sa.AddOverrideRequest(ctx, &sapb.OverrideRequest{})
+	test.AssertErrorIs(t, err, errIncompleteRequest)
+
+	// Approve the first request and deny the second.
+	_, err = sa.UpdateOverrideRequestState(ctx, &sapb.UpdateOverrideRequestStateRequest{
+		Id:   first.Id,
+		From: string(ratelimits.OverrideRequestPending),
+		To:   string(ratelimits.OverrideRequestApproved),
+	})
+	test.AssertNotError(t, err, "approving override request")
+	_, err = sa.UpdateOverrideRequestState(ctx, &sapb.UpdateOverrideRequestStateRequest{
+		Id:   second.Id,
+		From: string(ratelimits.OverrideRequestPending),
+		To:   string(ratelimits.OverrideRequestDenied),
+	})
+	test.AssertNotError(t, err, "denying override request")
+	got := approved()
+	test.AssertEquals(t, len(got), 1)
+	test.AssertEquals(t, got[0].Id, first.Id)
+	test.AssertEquals(t, got[0].Period.AsDuration(), 3*time.Hour)
+
+	// The second request is no longer pending, and denied is terminal.
+	_, err = sa.UpdateOverrideRequestState(ctx, &sapb.UpdateOverrideRequestStateRequest{
+		Id:   second.Id,
+		From: string(ratelimits.OverrideRequestPending),
+		To:   string(ratelimits.OverrideRequestApproved),
+	})
+	test.AssertErrorIs(t, err, berrors.NotFound)
+	_, err = sa.UpdateOverrideRequestState(ctx, &sapb.UpdateOverrideRequestStateRequest{
+		Id:   second.Id,
+		From: string(ratelimits.OverrideRequestDenied),
+		To:   string(ratelimits.OverrideRequestApproved),
+	})
+	test.AssertErrorIs(t, err, berrors.Malformed)
+
+	// A request for the same bucket as an approved request can't be approved
+	// too, even if its bucket id is formatted differently.
+	fqdnSet := newReq("example.com,example.net")
+	fqdnSet.LimitName = ratelimits.CertificatesPerFQDNSet.String()
+	third, err := sa.AddOverrideRequest(ctx, fqdnSet)
+	test.AssertNotError(t, err, "adding override request")
+	fqdnSet.BucketId = "EXAMPLE.net,example.com"
+	fourth, err := sa.AddOverrideRequest(ctx, fqdnSet)
+	test.AssertNotError(t, err, "adding override request")
+	_, err = sa.UpdateOverrideRequestState(ctx, &sapb.UpdateOverrideRequestStateRequest{
+		Id:   third.Id,
+		From: string(ratelimits.OverrideRequestPending),
+		To:   string(ratelimits.OverrideRequestApproved),
+	})
+	test.AssertNotError(t, err, "approving override request")
+	_, err = sa.UpdateOverrideRequestState(ctx, &sapb.UpdateOverrideRequestStateRequest{
+		Id:   fourth.Id,
+		From: string(ratelimits.OverrideRequestPending),
+		To:   string(ratelimits.OverrideRequestApproved),
+	})
+	test.AssertErrorIs(t, err, berrors.Duplicate)
+	test.AssertEquals(t, len(approved()), 2)
+
+	// Once the approved request has expired, the other may be approved.
+	_, err = sa.UpdateOverrideRequestState(ctx, &sapb.UpdateOverrideRequestStateRequest{
+		Id:   third.Id,
+		From: string(ratelimits.OverrideRequestApproved),
+		To:   string(ratelimits.OverrideRequestExpired),
+	})
+	test.AssertNotError(t, err, "expiring override request")
+	_, err = sa.UpdateOverrideRequestState(ctx, &sapb.UpdateOverrideRequestStateRequest{
+		Id:   fourth.Id,
+		From: string(ratelimits.OverrideRequestPending),
+		To:   string(ratelimits.OverrideRequestApproved),
+	})
+	test.AssertNotError(t, err, "approving override request")
+
+	// Approved requests are no longer returned once they expire.
+	fc.Add(91 * 24 * time.Hour)
+	test.AssertEquals(t, len(approved()), 0)
+}
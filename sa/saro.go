@@ -24,6 +24,7 @@ import (
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/identifier"
 	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/ratelimits"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 )
 
@@ -670,37 +671,38 @@ func (ssa *SQLStorageAuthorityRO) GetOrder(ctx context.Context, req *sapb.OrderR
 		return nil, errIncompleteRequest
 	}
 
-	txn := func(tx db.Executor) (interface{}, error) {
+	var order *corepb.Order
+	txn := func(tx db.Executor) error {
 		omObj, err := tx.Get(ctx, orderModel{}, req.Id)
 		if err != nil {
 			if db.IsNoRows(err) {
-				return nil, berrors.NotFoundError("no order found for ID %d", req.Id)
+				return berrors.NotFoundError("no order found for ID %d", req.Id)
 			}
-			return nil, err
+			return err
 		}
 		if omObj == nil {
-			return nil, berrors.NotFoundError("no order found for ID %d", req.Id)
+			return berrors.NotFoundError("no order found for ID %d", req.Id)
 		}
 
-		order, err := modelToOrder(omObj.(*orderModel))
+		order, err = modelToOrder(omObj.(*orderModel))
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		orderExp := order.Expires.AsTime()
 		if orderExp.Before(ssa.clk.Now()) {
-			return nil, berrors.NotFoundError("no order found for ID %d", req.Id)
+			return berrors.NotFoundError("no order found for ID %d", req.Id)
 		}
 
 		v2AuthzIDs, err := authzForOrder(ctx, tx, order.Id)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		order.V2Authorizations = v2AuthzIDs
 
 		names, err := namesForOrder(ctx, tx, order.Id)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		// The requested names are stored reversed to improve indexing performance. We
 		// need to reverse the reversed names here before giving them back to the
@@ -714,20 +716,20 @@ func (ssa *SQLStorageAuthorityRO) GetOrder(ctx context.Context, req *sapb.OrderR
 		// Calculate the status for the order
 		status, err := statusForOrder(ctx, tx, order, ssa.clk.Now())
 		if err != nil {
-			return nil, err
+			return err
 		}
 		order.Status = status
 
-		return order, nil
+		return nil
 	}
 
-	output, err := db.WithTransaction(ctx, ssa.dbReadOnlyMap, txn)
+	err := db.WithTransaction(ctx, ssa.dbReadOnlyMap, txn)
 	if (db.IsNoRows(err) || errors.Is(err, berrors.NotFound)) && ssa.lagFactor != 0 {
 		// GetOrder is often called shortly after a new order is created, sometimes
 		// before the order or its associated rows have propagated to the read
 		// replica yet. If we get a NoRows, wait a little bit and retry, once.
 		ssa.clk.Sleep(ssa.lagFactor)
-		output, err = db.WithTransaction(ctx, ssa.dbReadOnlyMap, txn)
+		err = db.WithTransaction(ctx, ssa.dbReadOnlyMap, txn)
 		if err != nil {
 			if db.IsNoRows(err) || errors.Is(err, berrors.NotFound) {
 				ssa.lagFactorCounter.WithLabelValues("GetOrder", "notfound").Inc()
@@ -742,11 +744,6 @@ func (ssa *SQLStorageAuthorityRO) GetOrder(ctx context.Context, req *sapb.OrderR
 		return nil, err
 	}
 
-	order, ok := output.(*corepb.Order)
-	if !ok {
-		return nil, fmt.Errorf("casting error in GetOrder")
-	}
-
 	return order, nil
 }
 
@@ -1222,6 +1219,55 @@ func (ssa *SQLStorageAuthority) IncidentsForSerial(ctx context.Context, req *sap
 	return ssa.SQLStorageAuthorityRO.IncidentsForSerial(ctx, req)
 }
 
+// CheckIdentifiersPaused returns those of the provided identifiers which are
+// currently paused for the provided account. Identifiers of unsupported types
+// can't have been paused, so are never returned.
+func (ssa *SQLStorageAuthorityRO) CheckIdentifiersPaused(ctx context.Context, req *sapb.PauseRequest) (*sapb.Identifiers, error) {
+	if core.IsAnyNilOrZero(req.RegistrationID) || len(req.Identifiers) == 0 {
+		return nil, errIncompleteRequest
+	}
+
+	values := make([]interface{}, 0, len(req.Identifiers)+1)
+	values = append(values, req.RegistrationID)
+	for _, ident := range req.Identifiers {
+		if ident == nil || core.IsAnyNilOrZero(ident.Type, ident.Value) {
+			return nil, errIncompleteRequest
+		}
+		values = append(values, ident.Value)
+	}
+
+	var entries []pausedModel
+	_, err := ssa.dbReadOnlyMap.Select(ctx, &entries, fmt.Sprintf(`
+		SELECT * FROM paused
+		WHERE registrationID = ? AND unpausedAt IS NULL AND identifierValue IN (%s)`,
+		db.QuestionMarks(len(req.Identifiers))),
+		values...,
+	)
+	if err != nil && !db.IsNoRows(err) {
+		return nil, err
+	}
+
+	paused := make(map[uint8]map[string]bool)
+	for _, entry := range entries {
+		if paused[entry.IdentifierType] == nil {
+			paused[entry.IdentifierType] = make(map[string]bool)
+		}
+		paused[entry.IdentifierType][entry.IdentifierValue] = true
+	}
+	resp := &sapb.Identifiers{}
+	for _, ident := range req.Identifiers {
+		typ, ok := identifierTypeToUint[ident.Type]
+		if ok && paused[typ][ident.Value] {
+			resp.Identifiers = append(resp.Identifiers, ident)
+		}
+	}
+	return resp, nil
+}
+
+func (ssa *SQLStorageAuthority) CheckIdentifiersPaused(ctx context.Context, req *sapb.PauseRequest) (*sapb.Identifiers, error) {
+	return ssa.SQLStorageAuthorityRO.CheckIdentifiersPaused(ctx, req)
+}
+
 // SerialsForIncident queries the provided incident table and returns the
 // resulting rows as a stream of `*sapb.IncidentSerial`s. An `io.EOF` error
 // signals that there are no more serials to send. If the incident table in
@@ -1287,6 +1333,51 @@ func (ssa *SQLStorageAuthority) SerialsForIncident(req *sapb.SerialsForIncidentR
 	return ssa.SQLStorageAuthorityRO.SerialsForIncident(req, stream)
 }
 
+// GetApprovedOverrideRequests writes to the output stream every override
+// request which is approved and unexpired, ordered by id. Any row which would
+// be rejected if it were loaded from an overrides file results in an error.
+func (ssa *SQLStorageAuthorityRO) GetApprovedOverrideRequests(_ *emptypb.Empty, stream sapb.StorageAuthorityReadOnly_GetApprovedOverrideRequestsServer) error {
+	selector, err := db.NewMappedSelector[overrideRequestModel](ssa.dbReadOnlyMap)
+	if err != nil {
+		return fmt.Errorf("initializing db map: %w", err)
+	}
+
+	rows, err := selector.QueryContext(stream.Context(), "WHERE state = ? AND expiresAt > ? ORDER BY id",
+		string(ratelimits.OverrideRequestApproved),
+		ssa.clk.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("reading db: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		orm, err := rows.Get()
+		if err != nil {
+			return fmt.Errorf("reading db: %w", err)
+		}
+		or := overrideRequestModelToRatelimits(*orm)
+		err = ratelimits.ValidateOverrideRequest(or)
+		if err != nil {
+			return fmt.Errorf("validating override request %d: %w", or.ID, err)
+		}
+		err = stream.Send(ratelimits.OverrideRequestToPB(or))
+		if err != nil {
+			return fmt.Errorf("sending override request %d: %w", or.ID, err)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("iterating over row results: %w", err)
+	}
+	return nil
+}
+
+func (ssa *SQLStorageAuthority) GetApprovedOverrideRequests(req *emptypb.Empty, stream sapb.StorageAuthority_GetApprovedOverrideRequestsServer) error {
+	return ssa.SQLStorageAuthorityRO.GetApprovedOverrideRequests(req, stream)
+}
+
 // GetRevokedCerts gets a request specifying an issuer and a period of time,
 // and writes to the output stream the set of all certificates issued by that
 // issuer which expire during that period of time and which have been revoked.
@@ -210,7 +210,7 @@ func AssertSliceContains[T comparable](t *testing.T, haystack []T, needle T) {
 // each), takes a set of labels and ignores any metrics which have different
 // label values.
 // Only works for simple metrics (Counters and Gauges), or for the *count*
-// (not value) of data points in a Histogram.
+// (not value) of data points in a Histogram or Summary.
 func AssertMetricWithLabelsEquals(t *testing.T, c prometheus.Collector, l prometheus.Labels, expected float64) {
 	t.Helper()
 	ch := make(chan prometheus.Metric)
@@ -240,11 +240,13 @@ loop:
 					break metric
 				}
 			}
-			// Exactly one of the Counter, Gauge, or Histogram values will be set by
-			// the .Write() operation, so add them all because the others will be 0.
+			// Exactly one of the Counter, Gauge, Histogram, or Summary values will
+			// be set by the .Write() operation, so add them all because the others
+			// will be 0.
 			total += iom.Counter.GetValue()
 			total += iom.Gauge.GetValue()
 			total += float64(iom.Histogram.GetSampleCount())
+			total += float64(iom.Summary.GetSampleCount())
 		}
 	}
 	AssertEquals(t, total, expected)
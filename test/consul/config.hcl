@@ -213,6 +213,14 @@ services {
   tags    = ["tcp"] // Required for SRV RR support in gRPC DNS resolution.
 }
 
+services {
+  id      = "ratelimitd-a"
+  name    = "ratelimitd"
+  address = "10.77.77.77"
+  port    = 9018
+  tags    = ["tcp"] // Required for SRV RR support in gRPC DNS resolution.
+}
+
 services {
   id      = "rva1-a"
   name    = "rva1"
@@ -12,7 +12,7 @@ command -v minica >/dev/null 2>&1 || {
 }
 
 for SERVICE in admin-revoker expiration-mailer ocsp-responder consul \
-  wfe akamai-purger bad-key-revoker crl-updater crl-storer \
+  wfe akamai-purger bad-key-revoker crl-updater crl-storer ratelimitd \
   health-checker; do
   minica -domains "${SERVICE}.boulder"
 done
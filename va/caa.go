@@ -62,7 +62,7 @@ func (va *ValidationAuthorityImpl) checkCAA(
 		return probs.ServerInternal("expected validationMethod or accountURIID not provided to checkCAA")
 	}
 
-	foundAt, valid, response, err := va.checkCAARecords(ctx, identifier, params)
+	foundAt, valid, reason, response, err := va.checkCAARecords(ctx, identifier, params)
 	if err != nil {
 		return probs.DNS(err.Error())
 	}
@@ -70,6 +70,9 @@ func (va *ValidationAuthorityImpl) checkCAA(
 	va.log.AuditInfof("Checked CAA records for %s, [Present: %t, Account ID: %d, Challenge: %s, Valid for issuance: %t, Found at: %q] Response=%q",
 		identifier.Value, foundAt != "", params.accountURIID, params.validationMethod, valid, foundAt, response)
 	if !valid {
+		if reason != "" {
+			return probs.CAA(fmt.Sprintf("CAA record for %s prevents issuance: %s", foundAt, reason))
+		}
 		return probs.CAA(fmt.Sprintf("CAA record for %s prevents issuance", foundAt))
 	}
 	return nil
@@ -197,16 +200,18 @@ func (va *ValidationAuthorityImpl) getCAA(ctx context.Context, hostname string)
 // validates them. If the identifier argument's value has a wildcard prefix then
 // the prefix is stripped and validation will be performed against the base
 // domain, honouring any issueWild CAA records encountered as appropriate.
-// checkCAARecords returns four values: the first is a string indicating at
+// checkCAARecords returns five values: the first is a string indicating at
 // which name (i.e. FQDN or parent thereof) CAA records were found, if any. The
 // second is a bool indicating whether issuance for the identifier is valid. The
+// third explains, if issuance isn't valid only because of the parameters of
+// records naming our issuer domain, which parameter prevents it. The
 // unmodified *dns.CAA records that were processed/filtered are returned as the
-// third argument. Any  errors encountered are returned as the fourth return
+// fourth argument. Any  errors encountered are returned as the fifth return
 // value (or nil).
 func (va *ValidationAuthorityImpl) checkCAARecords(
 	ctx context.Context,
 	identifier identifier.ACMEIdentifier,
-	params *caaParams) (string, bool, string, error) {
+	params *caaParams) (string, bool, string, string, error) {
 	hostname := strings.ToLower(identifier.Value)
 	// If this is a wildcard name, remove the prefix
 	var wildcard bool
@@ -216,14 +221,14 @@ func (va *ValidationAuthorityImpl) checkCAARecords(
 	}
 	caaSet, err := va.getCAA(ctx, hostname)
 	if err != nil {
-		return "", false, "", err
+		return "", false, "", "", err
 	}
 	raw := ""
 	if caaSet != nil {
 		raw = caaSet.dig
 	}
-	valid, foundAt := va.validateCAA(caaSet, wildcard, params)
-	return foundAt, valid, raw, nil
+	valid, foundAt, reason := va.validateCAA(caaSet, wildcard, params)
+	return foundAt, valid, reason, raw, nil
 }
 
 // validateCAA checks a provided *caaResult. When the wildcard argument is true
@@ -231,18 +236,20 @@ func (va *ValidationAuthorityImpl) checkCAARecords(
 // returns a boolean indicating whether issuance is allowed by this set of CAA
 // records, and a string indicating the name at which the CAA records allowing
 // issuance were found (if any -- since finding no records at all allows
-// issuance).
-func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool, params *caaParams) (bool, string) {
+// issuance). If issuance isn't allowed, but a record naming our issuer domain
+// was found, it also returns the reason that record's parameters forbid
+// issuance.
+func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool, params *caaParams) (bool, string, string) {
 	if caaSet == nil {
 		// No CAA records found, can issue
 		va.metrics.caaCounter.WithLabelValues("no records").Inc()
-		return true, ""
+		return true, "", ""
 	}
 
 	if caaSet.criticalUnknown {
 		// Contains unknown critical directives
 		va.metrics.caaCounter.WithLabelValues("record with unknown critical directive").Inc()
-		return false, caaSet.name
+		return false, caaSet.name, ""
 	}
 
 	if len(caaSet.issue) == 0 && !wildcard {
@@ -251,7 +258,7 @@ func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool,
 		// non-wildcard identifier, or there is only an iodef or non-critical unknown
 		// directive.)
 		va.metrics.caaCounter.WithLabelValues("no relevant records").Inc()
-		return true, caaSet.name
+		return true, caaSet.name, ""
 	}
 
 	// Per RFC 8659 Section 5.3:
@@ -273,6 +280,7 @@ func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool,
 	// prevent issuance by any CA under any circumstance.
 	//
 	// Our CAA identity must be found in the chosen checkSet.
+	var reason string
 	for _, caa := range records {
 		parsedDomain, parsedParams, err := parseCAARecord(caa)
 		if err != nil {
@@ -284,20 +292,26 @@ func (va *ValidationAuthorityImpl) validateCAA(caaSet *caaResult, wildcard bool,
 		}
 
 		if !caaAccountURIMatches(parsedParams, va.accountURIPrefixes, params.accountURIID) {
+			if reason == "" {
+				reason = fmt.Sprintf("accounturi %q doesn't match the requesting account", parsedParams["accounturi"])
+			}
 			continue
 		}
 
 		if !caaValidationMethodMatches(parsedParams, params.validationMethod) {
+			if reason == "" {
+				reason = fmt.Sprintf("validationmethods %q doesn't include %s", parsedParams["validationmethods"], params.validationMethod)
+			}
 			continue
 		}
 
 		va.metrics.caaCounter.WithLabelValues("authorized").Inc()
-		return true, caaSet.name
+		return true, caaSet.name, ""
 	}
 
 	// The list of authorized issuers is non-empty, but we are not in it. Fail.
 	va.metrics.caaCounter.WithLabelValues("unauthorized").Inc()
-	return false, caaSet.name
+	return false, caaSet.name, reason
 }
 
 // parseCAARecord extracts the domain and parameters (if any) from a
@@ -420,7 +420,7 @@ func TestCAAChecking(t *testing.T) {
 		mockLog.Clear()
 		t.Run(caaTest.Name, func(t *testing.T) {
 			ident := identifier.DNSIdentifier(caaTest.Domain)
-			foundAt, valid, _, err := va.checkCAARecords(ctx, ident, params)
+			foundAt, valid, _, _, err := va.checkCAARecords(ctx, ident, params)
 			if err != nil {
 				t.Errorf("checkCAARecords error for %s: %s", caaTest.Domain, err)
 			}
@@ -434,6 +434,25 @@ func TestCAAChecking(t *testing.T) {
 	}
 }
 
+func TestCAAParameterMismatchProblem(t *testing.T) {
+	va, _ := setup(nil, 0, "", nil)
+	va.dnsClient = caaMockDNS{}
+	va.accountURIPrefixes = []string{"https://letsencrypt.org/acct/reg/"}
+	params := &caaParams{accountURIID: 123, validationMethod: core.ChallengeTypeHTTP01}
+
+	prob := va.checkCAA(ctx, identifier.DNSIdentifier("present-incorrect-accounturi.com"), params)
+	test.AssertEquals(t, prob.Type, probs.CAAProblem)
+	test.AssertEquals(t, prob.Detail, `CAA record for present-incorrect-accounturi.com prevents issuance: accounturi "https://letsencrypt.org/acct/reg/321" doesn't match the requesting account`)
+
+	prob = va.checkCAA(ctx, identifier.DNSIdentifier("present-dns-only-correct-accounturi.com"), params)
+	test.AssertEquals(t, prob.Type, probs.CAAProblem)
+	test.AssertEquals(t, prob.Detail, `CAA record for present-dns-only-correct-accounturi.com prevents issuance: validationmethods "dns-01" doesn't include http-01`)
+
+	// Records that don't name our issuer domain have no parameters to explain.
+	prob = va.checkCAA(ctx, identifier.DNSIdentifier("reserved.com"), params)
+	test.AssertEquals(t, prob.Detail, "CAA record for reserved.com prevents issuance")
+}
+
 func TestCAALogging(t *testing.T) {
 	va, _ := setup(nil, 0, "", nil)
 	va.dnsClient = caaMockDNS{}
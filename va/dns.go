@@ -4,9 +4,11 @@ import (
 	"context"
 	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base32"
 	"encoding/base64"
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
@@ -54,13 +56,51 @@ func (va *ValidationAuthorityImpl) validateDNS01(ctx context.Context, ident iden
 		return nil, probs.Malformed("Identifier type for DNS was not itself DNS")
 	}
 
+	// Look for the required record in the DNS
+	challengeSubdomain := fmt.Sprintf("%s.%s", core.DNSPrefix, ident.Value)
+	return va.validateTXT(ctx, ident, challengeSubdomain, challenge.ProvidedKeyAuthorization)
+}
+
+// validateDNSAccount01 validates a dns-account-01 challenge, per
+// draft-ietf-acme-dns-account-label, whose TXT record is at a label derived from
+// the account's URL beneath the usual _acme-challenge subdomain. The account's
+// URL is formed from each of the VA's account URI prefixes in turn, since the
+// VA doesn't know which the client was given.
+func (va *ValidationAuthorityImpl) validateDNSAccount01(ctx context.Context, ident identifier.ACMEIdentifier, regid int64, challenge core.Challenge) ([]core.ValidationRecord, *probs.ProblemDetails) {
+	if ident.Type != identifier.DNS {
+		va.log.Infof("Identifier type for DNS challenge was not DNS: %s", ident)
+		return nil, probs.Malformed("Identifier type for DNS was not itself DNS")
+	}
+
+	var prob *probs.ProblemDetails
+	for _, prefix := range va.accountURIPrefixes {
+		accountURL := fmt.Sprintf("%s%d", prefix, regid)
+		challengeSubdomain := fmt.Sprintf("%s.%s.%s", dnsAccountLabel(accountURL), core.DNSPrefix, ident.Value)
+		var records []core.ValidationRecord
+		records, prob = va.validateTXT(ctx, ident, challengeSubdomain, challenge.ProvidedKeyAuthorization)
+		if prob == nil {
+			return records, nil
+		}
+	}
+	return nil, prob
+}
+
+// dnsAccountLabel returns the label, beneath _acme-challenge, of the TXT record
+// for the account with the given URL: an underscore followed by the lowercase,
+// unpadded base32 encoding of the first 10 bytes of the URL's SHA-256 digest.
+func dnsAccountLabel(accountURL string) string {
+	digest := sha256.Sum256([]byte(accountURL))
+	return "_" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(digest[:10]))
+}
+
+// validateTXT checks that one of the TXT records at challengeSubdomain is the
+// digest of keyAuthorization, as required by the DNS-based challenges.
+func (va *ValidationAuthorityImpl) validateTXT(ctx context.Context, ident identifier.ACMEIdentifier, challengeSubdomain, keyAuthorization string) ([]core.ValidationRecord, *probs.ProblemDetails) {
 	// Compute the digest of the key authorization file
 	h := sha256.New()
-	h.Write([]byte(challenge.ProvidedKeyAuthorization))
+	h.Write([]byte(keyAuthorization))
 	authorizedKeysDigest := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
 
-	// Look for the required record in the DNS
-	challengeSubdomain := fmt.Sprintf("%s.%s", core.DNSPrefix, ident.Value)
 	txts, err := va.dnsClient.LookupTXT(ctx, challengeSubdomain)
 	if err != nil {
 		return nil, probs.DNS(err.Error())
@@ -11,6 +11,7 @@ import (
 	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/bdns"
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/identifier"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/probs"
@@ -95,7 +96,7 @@ func TestDNSValidationNotSane(t *testing.T) {
 
 	chall := dnsChallenge()
 	chall.Token = ""
-	_, prob := va.validateChallenge(ctx, dnsi("localhost"), chall)
+	_, prob := va.validateChallenge(ctx, dnsi("localhost"), 1, chall)
 	if prob.Type != probs.MalformedProblem {
 		t.Errorf("Got wrong error type: expected %s, got %s",
 			prob.Type, probs.MalformedProblem)
@@ -105,7 +106,7 @@ func TestDNSValidationNotSane(t *testing.T) {
 	}
 
 	chall.Token = "yfCBb-bRTLz8Wd1C0lTUQK3qlKj3-t2tYGwx5Hj7r_"
-	_, prob = va.validateChallenge(ctx, dnsi("localhost"), chall)
+	_, prob = va.validateChallenge(ctx, dnsi("localhost"), 1, chall)
 	if prob.Type != probs.MalformedProblem {
 		t.Errorf("Got wrong error type: expected %s, got %s",
 			prob.Type, probs.MalformedProblem)
@@ -115,7 +116,7 @@ func TestDNSValidationNotSane(t *testing.T) {
 	}
 
 	chall.ProvidedKeyAuthorization = "a"
-	_, prob = va.validateChallenge(ctx, dnsi("localhost"), chall)
+	_, prob = va.validateChallenge(ctx, dnsi("localhost"), 1, chall)
 	if prob.Type != probs.MalformedProblem {
 		t.Errorf("Got wrong error type: expected %s, got %s",
 			prob.Type, probs.MalformedProblem)
@@ -129,7 +130,7 @@ func TestDNSValidationNotSane(t *testing.T) {
 func TestDNSValidationServFail(t *testing.T) {
 	va, _ := setup(nil, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("servfail.com"), dnsChallenge())
+	_, prob := va.validateChallenge(ctx, dnsi("servfail.com"), 1, dnsChallenge())
 
 	test.AssertEquals(t, prob.Type, probs.DNSProblem)
 }
@@ -148,7 +149,7 @@ func TestDNSValidationNoServer(t *testing.T) {
 		log,
 		nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("localhost"), dnsChallenge())
+	_, prob := va.validateChallenge(ctx, dnsi("localhost"), 1, dnsChallenge())
 
 	test.AssertEquals(t, prob.Type, probs.DNSProblem)
 }
@@ -156,7 +157,7 @@ func TestDNSValidationNoServer(t *testing.T) {
 func TestDNSValidationOK(t *testing.T) {
 	va, _ := setup(nil, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("good-dns01.com"), dnsChallenge())
+	_, prob := va.validateChallenge(ctx, dnsi("good-dns01.com"), 1, dnsChallenge())
 
 	test.Assert(t, prob == nil, "Should be valid.")
 }
@@ -164,11 +165,39 @@ func TestDNSValidationOK(t *testing.T) {
 func TestDNSValidationNoAuthorityOK(t *testing.T) {
 	va, _ := setup(nil, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("no-authority-dns01.com"), dnsChallenge())
+	_, prob := va.validateChallenge(ctx, dnsi("no-authority-dns01.com"), 1, dnsChallenge())
 
 	test.Assert(t, prob == nil, "Should be valid.")
 }
 
+func TestDNSAccountLabel(t *testing.T) {
+	// The example from draft-ietf-acme-dns-account-label.
+	test.AssertEquals(t, dnsAccountLabel("https://example.com/acme/acct/ExampleAccount"), "_ujmmovf2vn55tgye")
+}
+
+func TestDNSAccountValidation(t *testing.T) {
+	va, _ := setup(nil, 0, "", nil)
+	chall := createChallenge(core.ChallengeTypeDNSAccount01)
+
+	// The challenge type isn't recognized while the feature is disabled.
+	_, prob := va.validateChallenge(ctx, dnsi("good-dns-account01.com"), 1, chall)
+	test.AssertEquals(t, prob.Type, probs.MalformedProblem)
+
+	features.Set(features.Config{DNSAccount01Enabled: true})
+	defer features.Reset()
+
+	_, prob = va.validateChallenge(ctx, dnsi("good-dns-account01.com"), 1, chall)
+	test.Assert(t, prob == nil, "Should be valid.")
+
+	// Another account's label has no record.
+	_, prob = va.validateChallenge(ctx, dnsi("good-dns-account01.com"), 2, chall)
+	test.AssertEquals(t, prob.Type, probs.UnauthorizedProblem)
+
+	// Nor does the account's label beneath a name validated with dns-01.
+	_, prob = va.validateChallenge(ctx, dnsi("good-dns01.com"), 1, chall)
+	test.AssertEquals(t, prob.Type, probs.UnauthorizedProblem)
+}
+
 func TestAvailableAddresses(t *testing.T) {
 	v6a := net.ParseIP("::1")
 	v6b := net.ParseIP("2001:db8::2:1") // 2001:DB8 is reserved for docs (RFC 3849)
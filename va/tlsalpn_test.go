@@ -397,7 +397,7 @@ func TestTLSALPN01Success(t *testing.T) {
 
 	va, _ := setup(hs, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("expected"), chall)
+	_, prob := va.validateChallenge(ctx, dnsi("expected"), 1, chall)
 	if prob != nil {
 		t.Errorf("Validation failed: %v", prob)
 	}
@@ -423,7 +423,7 @@ func TestTLSALPN01ObsoleteFailure(t *testing.T) {
 
 	va, _ := setup(hs, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("expected"), chall)
+	_, prob := va.validateChallenge(ctx, dnsi("expected"), 1, chall)
 	test.AssertNotNil(t, prob, "expected validation to fail")
 }
 
@@ -563,7 +563,7 @@ func TestTLSALPN01TLSVersion(t *testing.T) {
 
 		va, _ := setup(hs, 0, "", nil)
 
-		_, prob := va.validateChallenge(ctx, dnsi("expected"), chall)
+		_, prob := va.validateChallenge(ctx, dnsi("expected"), 1, chall)
 		if !tc.expectError {
 			if prob != nil {
 				t.Errorf("expected success, got: %v", prob)
@@ -590,7 +590,7 @@ func TestTLSALPN01WrongName(t *testing.T) {
 
 	va, _ := setup(hs, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("expected"), chall)
+	_, prob := va.validateChallenge(ctx, dnsi("expected"), 1, chall)
 	test.AssertError(t, prob, "validation should have failed")
 }
 
@@ -603,7 +603,7 @@ func TestTLSALPN01ExtraNames(t *testing.T) {
 
 	va, _ := setup(hs, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("expected"), chall)
+	_, prob := va.validateChallenge(ctx, dnsi("expected"), 1, chall)
 	test.AssertError(t, prob, "validation should have failed")
 }
 
@@ -661,7 +661,7 @@ func TestTLSALPN01NotSelfSigned(t *testing.T) {
 
 	va, _ := setup(hs, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("expected"), chall)
+	_, prob := va.validateChallenge(ctx, dnsi("expected"), 1, chall)
 	test.AssertError(t, prob, "validation should have failed")
 	test.AssertContains(t, prob.Detail, "not self-signed")
 }
@@ -708,7 +708,7 @@ func TestTLSALPN01ExtraIdentifiers(t *testing.T) {
 
 	va, _ := setup(hs, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("expected"), chall)
+	_, prob := va.validateChallenge(ctx, dnsi("expected"), 1, chall)
 	test.AssertError(t, prob, "validation should have failed")
 }
 
@@ -768,7 +768,7 @@ func TestTLSALPN01ExtraSANs(t *testing.T) {
 
 	va, _ := setup(hs, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("expected"), chall)
+	_, prob := va.validateChallenge(ctx, dnsi("expected"), 1, chall)
 	test.AssertError(t, prob, "validation should have failed")
 	// In go >= 1.19, the TLS client library detects that the certificate has
 	// a duplicate extension and terminates the connection itself.
@@ -823,7 +823,7 @@ func TestTLSALPN01ExtraAcmeExtensions(t *testing.T) {
 
 	va, _ := setup(hs, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("expected"), chall)
+	_, prob := va.validateChallenge(ctx, dnsi("expected"), 1, chall)
 	test.AssertError(t, prob, "validation should have failed")
 	// In go >= 1.19, the TLS client library detects that the certificate has
 	// a duplicate extension and terminates the connection itself.
@@ -395,7 +395,7 @@ func (va *ValidationAuthorityImpl) validate(
 	}
 
 	// TODO(#1292): send into another goroutine
-	validationRecords, prob := va.validateChallenge(ctx, baseIdentifier, challenge)
+	validationRecords, prob := va.validateChallenge(ctx, baseIdentifier, regid, challenge)
 	if prob != nil {
 		// The ProblemDetails will be serialized through gRPC, which requires UTF-8.
 		// It will also later be serialized in JSON, which defaults to UTF-8. Make
@@ -425,7 +425,7 @@ func (va *ValidationAuthorityImpl) validate(
 	return validationRecords, nil
 }
 
-func (va *ValidationAuthorityImpl) validateChallenge(ctx context.Context, identifier identifier.ACMEIdentifier, challenge core.Challenge) ([]core.ValidationRecord, *probs.ProblemDetails) {
+func (va *ValidationAuthorityImpl) validateChallenge(ctx context.Context, identifier identifier.ACMEIdentifier, regid int64, challenge core.Challenge) ([]core.ValidationRecord, *probs.ProblemDetails) {
 	err := challenge.CheckConsistencyForValidation()
 	if err != nil {
 		return nil, probs.Malformed("Challenge failed consistency check: %s", err)
@@ -437,6 +437,10 @@ func (va *ValidationAuthorityImpl) validateChallenge(ctx context.Context, identi
 		return va.validateDNS01(ctx, identifier, challenge)
 	case core.ChallengeTypeTLSALPN01:
 		return va.validateTLSALPN01(ctx, identifier, challenge)
+	case core.ChallengeTypeDNSAccount01:
+		if features.Get().DNSAccount01Enabled {
+			return va.validateDNSAccount01(ctx, identifier, regid, challenge)
+		}
 	}
 	return nil, probs.Malformed("invalid challenge type %s", challenge.Type)
 }
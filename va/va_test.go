@@ -237,7 +237,7 @@ func (lrva localRemoteVA) PerformValidation(ctx context.Context, req *vapb.Perfo
 func TestValidateMalformedChallenge(t *testing.T) {
 	va, _ := setup(nil, 0, "", nil)
 
-	_, prob := va.validateChallenge(ctx, dnsi("example.com"), createChallenge("fake-type-01"))
+	_, prob := va.validateChallenge(ctx, dnsi("example.com"), 1, createChallenge("fake-type-01"))
 
 	test.AssertEquals(t, prob.Type, probs.MalformedProblem)
 }
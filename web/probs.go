@@ -46,6 +46,10 @@ func problemDetailsForBoulderError(err *berrors.BoulderError, msg string) *probs
 		outProb = probs.BadRevocationReason(fmt.Sprintf("%s :: %s", msg, err))
 	case berrors.UnsupportedContact:
 		outProb = probs.UnsupportedContact(fmt.Sprintf("%s :: %s", msg, err))
+	case berrors.Paused:
+		// There is no ACME problem type for paused accounts, so say so in the
+		// detail of the unauthorized problem.
+		outProb = probs.Unauthorized(fmt.Sprintf("%s :: Account is paused: %s", msg, err))
 	default:
 		// Internal server error messages may include sensitive data, so we do
 		// not include it.
@@ -37,6 +37,7 @@ func TestProblemDetailsFromError(t *testing.T) {
 		{berrors.RateLimitError(0, detailMsg), 429, probs.RateLimitedProblem, fullDetail + ": see https://letsencrypt.org/docs/rate-limits/"},
 		{berrors.InvalidEmailError(detailMsg), 400, probs.InvalidContactProblem, fullDetail},
 		{berrors.RejectedIdentifierError(detailMsg), 400, probs.RejectedIdentifierProblem, fullDetail},
+		{berrors.PausedError(detailMsg), 403, probs.UnauthorizedProblem, fmt.Sprintf("%s :: Account is paused: %s", errMsg, detailMsg)},
 	}
 	for _, c := range testCases {
 		p := ProblemDetailsForError(c.err, errMsg)
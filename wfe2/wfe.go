@@ -66,6 +66,7 @@ const (
 	newOrderPath      = "/acme/new-order"
 	orderPath         = "/acme/order/"
 	finalizeOrderPath = "/acme/finalize/"
+	rateLimitsPath    = "/acme/rate-limits"
 
 	getAPIPrefix     = "/get/"
 	getOrderPath     = getAPIPrefix + "order/"
@@ -82,6 +83,10 @@ const (
 
 const (
 	headerRetryAfter = "Retry-After"
+
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
 	// Our 99th percentile finalize latency is 2.3s. Asking clients to wait 3s
 	// before polling the order to get an updated status means that >99% of
 	// clients will fetch the updated order object exactly once,.
@@ -168,8 +173,17 @@ type WebFrontEndImpl struct {
 	// match the ones used by the RA.
 	authorizationLifetime        time.Duration
 	pendingAuthorizationLifetime time.Duration
-	limiter                      *ratelimits.Limiter
+	limiter                      ratelimits.TransactionLimiter
 	txnBuilder                   *ratelimits.TransactionBuilder
+
+	// RateLimitHeaders, if true, causes new-account responses to carry the
+	// RateLimit-Limit, RateLimit-Remaining, and RateLimit-Reset headers of
+	// draft-ietf-httpapi-ratelimit-headers, describing the requester's bucket
+	// as of the Decision which spent from it, and new-order responses denied
+	// by the RA's rate limits to carry RateLimit-Remaining and RateLimit-Reset.
+	// Spending from new-account limits is synchronous when it is set, which
+	// adds the latency of the spend to every new-account request.
+	RateLimitHeaders bool
 }
 
 // NewWebFrontEndImpl constructs a web service for Boulder
@@ -191,7 +205,7 @@ func NewWebFrontEndImpl(
 	rnc nonce.Redeemer,
 	rncKey string,
 	accountGetter AccountGetter,
-	limiter *ratelimits.Limiter,
+	limiter ratelimits.TransactionLimiter,
 	txnBuilder *ratelimits.TransactionBuilder,
 ) (WebFrontEndImpl, error) {
 	if len(issuerCertificates) == 0 {
@@ -429,6 +443,11 @@ func (wfe *WebFrontEndImpl) Handler(stats prometheus.Registerer, oTelHTTPOptions
 	wfe.HandleFunc(m, authzPath, wfe.Authorization, "GET", "POST")
 	wfe.HandleFunc(m, challengePath, wfe.Challenge, "GET", "POST")
 	wfe.HandleFunc(m, certPath, wfe.Certificate, "GET", "POST")
+	// Boulder-specific POST-as-GETable endpoint for the requesting account's
+	// rate limits, only served when the key-value rate limiter is enabled.
+	if wfe.limiter != nil && wfe.txnBuilder != nil {
+		wfe.HandleFunc(m, rateLimitsPath, wfe.RateLimits, "POST")
+	}
 	// Boulder-specific GET-able resource endpoints
 	wfe.HandleFunc(m, getOrderPath, wfe.GetOrder, "GET")
 	wfe.HandleFunc(m, getAuthzPath, wfe.Authorization, "GET")
@@ -605,7 +624,7 @@ func (wfe *WebFrontEndImpl) sendError(response http.ResponseWriter, logEvent *we
 	if errors.As(ierr, &bErr) {
 		retryAfterSeconds := int(bErr.RetryAfter.Round(time.Second).Seconds())
 		if retryAfterSeconds > 0 {
-			response.Header().Add(headerRetryAfter, strconv.Itoa(retryAfterSeconds))
+			response.Header().Set(headerRetryAfter, strconv.Itoa(retryAfterSeconds))
 			if bErr.Type == berrors.RateLimit {
 				response.Header().Add("Link", link("https://letsencrypt.org/docs/rate-limits", "help"))
 			}
@@ -619,18 +638,58 @@ func link(url, relation string) string {
 	return fmt.Sprintf("<%s>;rel=\"%s\"", url, relation)
 }
 
+// setRateLimitHeaders describes, in the RateLimit headers of response, the
+// requester's bucket as of d, the Decision made when the cost of the current
+// request was spent from it. No headers are set if RateLimitHeaders is false, d
+// is nil, or no bucket was evaluated, e.g. because the requester is exempt.
+//
+// TODO(#5545): The new-account limits aren't yet authoritative, so a request is
+// never refused because d denied it. Retry-After is therefore never set, and
+// the remaining capacity is clamped, so that a successful response never asks
+// the client to wait.
+func (wfe *WebFrontEndImpl) setRateLimitHeaders(response http.ResponseWriter, d *ratelimits.Decision) {
+	if !wfe.RateLimitHeaders || d == nil || d.Burst() == 0 {
+		return
+	}
+	remaining := min(max(d.Remaining, 0), d.Burst())
+	response.Header().Set(headerRateLimitLimit, strconv.FormatInt(d.Burst(), 10))
+	response.Header().Set(headerRateLimitRemaining, strconv.FormatInt(remaining, 10))
+	response.Header().Set(headerRateLimitReset, headerSeconds(max(d.ResetIn, 0)))
+}
+
+// setRateLimitErrorHeaders describes, in the RateLimit headers of response, the
+// bucket which denied a request, if err is a berrors.RateLimit error. Only the
+// RetryAfter of the error is known, so RateLimit-Limit is omitted and
+// RateLimit-Reset is the time until the request may be retried. Retry-After is
+// set by sendError. No headers are set if RateLimitHeaders is false.
+func (wfe *WebFrontEndImpl) setRateLimitErrorHeaders(response http.ResponseWriter, err error) {
+	var bErr *berrors.BoulderError
+	if !wfe.RateLimitHeaders || !errors.As(err, &bErr) || bErr.Type != berrors.RateLimit || bErr.RetryAfter <= 0 {
+		return
+	}
+	response.Header().Set(headerRateLimitRemaining, "0")
+	response.Header().Set(headerRateLimitReset, headerSeconds(bErr.RetryAfter))
+}
+
+// headerSeconds formats d as a whole number of seconds, rounded up, so that a
+// client waiting the advertised number of seconds never retries early.
+func headerSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
 // checkNewAccountLimits checks whether sufficient limit quota exists for the
 // creation of a new account from the given IP address. If so, that quota is
-// spent. If an error is encountered during the check, it is logged but not
-// returned.
+// spent. It returns the Decision of whichever limit has the least remaining
+// capacity, or nil if the limiter is disabled or the check failed. If an error
+// is encountered during the check, it is logged but not returned.
 //
 // TODO(#5545): For now we're simply exercising the new rate limiter codepath.
 // This should eventually return a berrors.RateLimit error containing the retry
 // after duration among other information available in the ratelimits.Decision.
-func (wfe *WebFrontEndImpl) checkNewAccountLimits(ctx context.Context, ip net.IP) {
+func (wfe *WebFrontEndImpl) checkNewAccountLimits(ctx context.Context, ip net.IP) *ratelimits.Decision {
 	if wfe.limiter == nil && wfe.txnBuilder == nil {
 		// Limiter is disabled.
-		return
+		return nil
 	}
 
 	warn := func(err error, limit ratelimits.Name) {
@@ -645,30 +704,35 @@ func (wfe *WebFrontEndImpl) checkNewAccountLimits(ctx context.Context, ip net.IP
 	txn, err := wfe.txnBuilder.RegistrationsPerIPAddressTransaction(ip)
 	if err != nil {
 		warn(err, ratelimits.NewRegistrationsPerIPAddress)
-		return
+		return nil
 	}
 
 	decision, err := wfe.limiter.Spend(ctx, txn)
 	if err != nil {
 		warn(err, ratelimits.NewRegistrationsPerIPAddress)
-		return
+		return nil
 	}
 	if !decision.Allowed || ip.To4() != nil {
 		// This requester is being limited or the request was made from an IPv4
 		// address.
-		return
+		return decision
 	}
 
 	txn, err = wfe.txnBuilder.RegistrationsPerIPv6RangeTransaction(ip)
 	if err != nil {
 		warn(err, ratelimits.NewRegistrationsPerIPv6Range)
-		return
+		return decision
 	}
 
-	_, err = wfe.limiter.Spend(ctx, txn)
+	rangeDecision, err := wfe.limiter.Spend(ctx, txn)
 	if err != nil {
 		warn(err, ratelimits.NewRegistrationsPerIPv6Range)
+		return decision
+	}
+	if !rangeDecision.Allowed || (rangeDecision.Burst() != 0 && rangeDecision.Remaining < decision.Remaining) {
+		return rangeDecision
 	}
+	return decision
 }
 
 // refundNewAccountLimits is typically called when a new account creation fails.
@@ -842,7 +906,13 @@ func (wfe *WebFrontEndImpl) NewAccount(
 
 	// TODO(#5545): Spending and Refunding can be async until these rate limits
 	// are authoritative. This saves us from adding latency to each request.
-	go wfe.checkNewAccountLimits(ctx, ip)
+	// Spending is only synchronous when the RateLimit headers, which describe
+	// its Decision, are requested.
+	if wfe.RateLimitHeaders {
+		wfe.setRateLimitHeaders(response, wfe.checkNewAccountLimits(ctx, ip))
+	} else {
+		go wfe.checkNewAccountLimits(ctx, ip)
+	}
 	var newRegistrationSuccessful bool
 	defer func() {
 		if !newRegistrationSuccessful {
@@ -1435,6 +1505,37 @@ func (wfe *WebFrontEndImpl) Account(
 	}
 }
 
+// RateLimits is used by an account to retrieve its current rate limits and the
+// remaining quota for each limit which is keyed by the account. Requests must
+// be POST-as-GET requests signed by the account.
+func (wfe *WebFrontEndImpl) RateLimits(
+	ctx context.Context,
+	logEvent *web.RequestEvent,
+	response http.ResponseWriter,
+	request *http.Request) {
+	acct, prob := wfe.validPOSTAsGETForAccount(request, ctx, logEvent)
+	addRequesterHeader(response, logEvent.Requester)
+	if prob != nil {
+		// validPOSTAsGETForAccount handles its own setting of logEvent.Errors
+		wfe.sendError(response, logEvent, prob, nil)
+		return
+	}
+
+	quotas, err := ratelimits.AccountQuotas(ctx, wfe.limiter, wfe.txnBuilder, acct.ID, wfe.clk.Now())
+	if err != nil {
+		wfe.sendError(response, logEvent, probs.ServerInternal("Failed to retrieve rate limits"), err)
+		return
+	}
+
+	err = wfe.writeJsonResponse(response, logEvent, http.StatusOK, struct {
+		Limits []ratelimits.AccountQuota `json:"limits"`
+	}{quotas})
+	if err != nil {
+		wfe.sendError(response, logEvent, probs.ServerInternal("Failed to marshal rate limits"), err)
+		return
+	}
+}
+
 // updateAccount unmarshals an account update request from the provided
 // requestBody to update the given registration. Important: It is assumed the
 // request has already been authenticated by the caller. If the request is
@@ -2135,6 +2236,9 @@ func (wfe *WebFrontEndImpl) NewOrder(
 	})
 	// TODO(#7153): Check each value via core.IsAnyNilOrZero
 	if err != nil || order == nil || order.Id == 0 || order.RegistrationID == 0 || len(order.Names) == 0 || core.IsAnyNilOrZero(order.Created, order.Expires) {
+		// New order limits are enforced by the RA, which only reports the
+		// Decision of a limit which denied the order.
+		wfe.setRateLimitErrorHeaders(response, err)
 		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error creating new order"), err)
 		return
 	}
@@ -2521,7 +2625,9 @@ func (wfe *WebFrontEndImpl) RenewalInfo(ctx context.Context, logEvent *web.Reque
 	}
 
 	if len(result.Incidents) > 0 {
-		sendRI(core.RenewalInfoImmediate(wfe.clk.Now()))
+		ri := core.RenewalInfoImmediate(wfe.clk.Now())
+		ri.ExplanationURL = result.Incidents[0].Url
+		sendRI(ri)
 		return
 	}
 
@@ -18,10 +18,12 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -355,7 +357,7 @@ func setupWFE(t *testing.T) (WebFrontEndImpl, clock.FakeClock, requestSigner) {
 	var rnc nonce.Redeemer
 	var rncKey string
 	var inmemNonceService *inmemnonce.Service
-	var limiter *ratelimits.Limiter
+	var limiter ratelimits.TransactionLimiter
 	var txnBuilder *ratelimits.TransactionBuilder
 	if strings.Contains(os.Getenv("BOULDER_CONFIG_DIR"), "test/config-next") {
 		// Use derived nonces.
@@ -392,7 +394,7 @@ func setupWFE(t *testing.T) (WebFrontEndImpl, clock.FakeClock, requestSigner) {
 		test.AssertNotNil(t, source, "source should not be nil")
 		limiter, err = ratelimits.NewLimiter(fc, source, stats)
 		test.AssertNotError(t, err, "making limiter")
-		txnBuilder, err = ratelimits.NewTransactionBuilder("../test/config-next/wfe2-ratelimit-defaults.yml", "")
+		txnBuilder, err = ratelimits.NewTransactionBuilder("../test/config-next/wfe2-ratelimit-defaults.yml", "", "", "")
 		test.AssertNotError(t, err, "making transaction composer")
 	} else {
 		// TODO(#6610): Remove this once we've moved to derived to prefixes.
@@ -1730,6 +1732,137 @@ func contains(s []string, e string) bool {
 	return false
 }
 
+func TestRateLimits(t *testing.T) {
+	wfe, fc, signer := setupWFE(t)
+
+	defaults := filepath.Join(t.TempDir(), "defaults.yml")
+	err := os.WriteFile(defaults, []byte("NewOrdersPerAccount: { burst: 10, count: 10, period: 1h }\n"), 0600)
+	test.AssertNotError(t, err, "writing defaults")
+	limiter, err := ratelimits.NewLimiter(fc, ratelimits.NewInmemSource(), metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "making limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(defaults, "", "", "")
+	test.AssertNotError(t, err, "making transaction builder")
+	wfe.limiter = limiter
+	wfe.txnBuilder = txnBuilder
+
+	txn, err := txnBuilder.OrdersPerAccountTransaction(1)
+	test.AssertNotError(t, err, "building transaction")
+	_, err = limiter.Spend(context.Background(), txn)
+	test.AssertNotError(t, err, "spending")
+
+	// POST-as-GET signed by account 1.
+	responseWriter := httptest.NewRecorder()
+	wfe.RateLimits(ctx, newRequestEvent(), responseWriter,
+		signAndPost(signer, rateLimitsPath, "http://localhost"+rateLimitsPath, ""))
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `{"limits": [
+		{"name": "NewOrdersPerAccount", "burst": 10, "count": 10, "period": "1h0m0s", "remaining": 9, "resetAt": "`+fc.Now().Add(6*time.Minute).UTC().Format(time.RFC3339)+`"},
+		{"name": "FailedAuthorizationsPerAccount", "unlimited": true, "remaining": 0},
+		{"name": "RevocationsPerAccount", "unlimited": true, "remaining": 0},
+		{"name": "AccountUpdatesPerAccount", "unlimited": true, "remaining": 0}
+	]}`)
+
+	// A POST with a payload is rejected.
+	responseWriter = httptest.NewRecorder()
+	wfe.RateLimits(ctx, newRequestEvent(), responseWriter,
+		signAndPost(signer, rateLimitsPath, "http://localhost"+rateLimitsPath, "{}"))
+	test.AssertEquals(t, responseWriter.Code, http.StatusBadRequest)
+	test.AssertContains(t, responseWriter.Body.String(), "POST-as-GET requests must have an empty payload")
+}
+
+func TestNewAccountRateLimitHeaders(t *testing.T) {
+	wfe, fc, signer := setupWFE(t)
+
+	defaults := filepath.Join(t.TempDir(), "defaults.yml")
+	err := os.WriteFile(defaults, []byte("NewRegistrationsPerIPAddress: { burst: 2, count: 2, period: 1h }\n"), 0600)
+	test.AssertNotError(t, err, "writing defaults")
+	limiter, err := ratelimits.NewLimiter(fc, ratelimits.NewInmemSource(), metrics.NoopRegisterer)
+	test.AssertNotError(t, err, "making limiter")
+	txnBuilder, err := ratelimits.NewTransactionBuilder(defaults, "", "", "")
+	test.AssertNotError(t, err, "making transaction builder")
+	wfe.limiter = limiter
+	wfe.txnBuilder = txnBuilder
+	wfe.RateLimitHeaders = true
+
+	key := loadKey(t, []byte(testE2KeyPrivatePEM))
+	newAccount := func() *httptest.ResponseRecorder {
+		_, _, body := signer.embeddedJWK(key, "http://localhost"+newAcctPath, `{"contact":["mailto:person@mail.com"],"termsOfServiceAgreed":true}`)
+		responseWriter := httptest.NewRecorder()
+		wfe.NewAccount(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath(newAcctPath, body))
+		test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
+		return responseWriter
+	}
+
+	// The headers describe the bucket after the cost of the current request
+	// was spent from it.
+	responseWriter := newAccount()
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Limit"), "2")
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Remaining"), "1")
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Reset"), "1800")
+	test.AssertEquals(t, responseWriter.Header().Get("Retry-After"), "")
+
+	// The spend was made, synchronously, exactly once.
+	txn, err := txnBuilder.RegistrationsPerIPAddressTransaction(net.ParseIP("1.1.1.1"))
+	test.AssertNotError(t, err, "building transaction")
+	d, err := limiter.Check(context.Background(), txn)
+	test.AssertNotError(t, err, "checking")
+	test.AssertEquals(t, d.Remaining, int64(0))
+
+	// The new-account limits aren't enforced, so a request whose spend was
+	// denied still succeeds, and isn't asked to retry later.
+	responseWriter = newAccount()
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Limit"), "2")
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Remaining"), "0")
+	test.AssertEquals(t, responseWriter.Header().Get("Retry-After"), "")
+
+	// Without RateLimitHeaders, no headers are set.
+	wfe.RateLimitHeaders = false
+	responseWriter = newAccount()
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Limit"), "")
+}
+
+// rateLimitedMockRA is a MockRegistrationAuthority whose NewOrder is always
+// denied by a rate limit.
+type rateLimitedMockRA struct {
+	MockRegistrationAuthority
+}
+
+func (ra *rateLimitedMockRA) NewOrder(context.Context, *rapb.NewOrderRequest, ...grpc.CallOption) (*corepb.Order, error) {
+	return nil, berrors.RateLimitError(90*time.Minute, "too many new orders")
+}
+
+func TestNewOrderRateLimitHeaders(t *testing.T) {
+	wfe, _, signer := setupWFE(t)
+	wfe.RateLimitHeaders = true
+
+	newOrder := func() *httptest.ResponseRecorder {
+		responseWriter := httptest.NewRecorder()
+		wfe.NewOrder(ctx, newRequestEvent(), responseWriter,
+			signAndPost(signer, "new-order", "http://localhost/new-order", `{"identifiers": [{"type": "dns", "value": "not-example.com"}]}`))
+		return responseWriter
+	}
+
+	// The RA doesn't report the Decisions of limits which allowed the order.
+	responseWriter := newOrder()
+	test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Remaining"), "")
+
+	// A denial is described by the RA's error.
+	wfe.ra = &rateLimitedMockRA{}
+	responseWriter = newOrder()
+	test.AssertEquals(t, responseWriter.Code, http.StatusTooManyRequests)
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Limit"), "")
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Remaining"), "0")
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Reset"), "5400")
+	test.AssertEquals(t, responseWriter.Header().Get("Retry-After"), "5400")
+
+	// Without RateLimitHeaders, only Retry-After is set.
+	wfe.RateLimitHeaders = false
+	responseWriter = newOrder()
+	test.AssertEquals(t, responseWriter.Header().Get("RateLimit-Remaining"), "")
+	test.AssertEquals(t, responseWriter.Header().Get("Retry-After"), "5400")
+}
+
 func TestAccount(t *testing.T) {
 	wfe, _, signer := setupWFE(t)
 	mux := wfe.Handler(metrics.NoopRegisterer)
@@ -3733,6 +3866,8 @@ func TestIncidentARI(t *testing.T) {
 	test.AssertEquals(t, ri.SuggestedWindow.End.After(ri.SuggestedWindow.Start), true)
 	// The end of the window should also be in the past.
 	test.AssertEquals(t, ri.SuggestedWindow.End.Before(wfe.clk.Now()), true)
+	// The incident should be linked to.
+	test.AssertEquals(t, ri.ExplanationURL, agreementURL)
 }
 
 type mockSAWithSerialMetadata struct {